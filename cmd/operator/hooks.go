@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes"
+	clientscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/mitchross/pvc-plumber/internal/controller"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+	"github.com/mitchross/pvc-plumber/internal/v4/runtimeconfig"
+)
+
+// podExecer implements controller.PodExecer over the pods/exec
+// subresource, the way kubectl exec does: WebSocket first, SPDY when the
+// apiserver (or a proxy in front of it) refuses the upgrade.
+type podExecer struct {
+	cfg  *rest.Config
+	rest rest.Interface
+}
+
+func newPodExecer(cfg *rest.Config) (*podExecer, error) {
+	cs, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build clientset for pod exec: %w", err)
+	}
+	return &podExecer{cfg: cfg, rest: cs.CoreV1().RESTClient()}, nil
+}
+
+// Exec implements controller.PodExecer. No stdin, no TTY; a non-zero
+// exit status comes back as the error.
+func (p *podExecer) Exec(ctx context.Context, namespace, pod, container string, command []string) (string, string, error) {
+	req := p.rest.Post().
+		Namespace(namespace).
+		Resource("pods").
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, clientscheme.ParameterCodec)
+
+	ws, err := remotecommand.NewWebSocketExecutor(p.cfg, http.MethodGet, req.URL().String())
+	if err != nil {
+		return "", "", fmt.Errorf("websocket executor: %w", err)
+	}
+	spdy, err := remotecommand.NewSPDYExecutor(p.cfg, http.MethodPost, req.URL())
+	if err != nil {
+		return "", "", fmt.Errorf("spdy executor: %w", err)
+	}
+	exec, err := remotecommand.NewFallbackExecutor(ws, spdy, func(err error) bool {
+		return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("exec executor: %w", err)
+	}
+	var stdout, stderr bytes.Buffer
+	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr})
+	return stdout.String(), stderr.String(), err
+}
+
+// newHookRunner builds the backup hook runner for runManager. It is
+// always registered in v4 modes, like the clone runner: with hooks off
+// (PVC_PLUMBER_BACKUP_HOOKS unset) or in audit mode it only reports the
+// PVCs that carry hook annotations as disabled, so nobody mistakes an
+// annotation for a quiesced backup. The pod exec client is built only
+// when hooks can run, and only then must the hook timeout fit in the
+// pre-hook budget, the lead less controller.HookLeadMargin. Zero
+// durations resolve to the controller package defaults, as in
+// newOrphanReaper.
+func newHookRunner(
+	c client.Client,
+	reader client.Reader,
+	restCfg *rest.Config,
+	store *controller.Store,
+	sysNs map[string]struct{},
+	runtimeCfg runtimeconfig.Config,
+	j *journal.Journal,
+) (*controller.HookRunner, error) {
+	timeout := runtimeCfg.BackupHookTimeout
+	if timeout <= 0 {
+		timeout = controller.DefaultHookTimeout
+	}
+	lead := runtimeCfg.BackupHookLead
+	if lead <= 0 {
+		lead = controller.DefaultHookLead
+	}
+	maxHold := runtimeCfg.BackupHookMaxHold
+	if maxHold <= 0 {
+		maxHold = controller.DefaultHookMaxHold
+	}
+	r := &controller.HookRunner{
+		Client:           c,
+		Reader:           reader,
+		Store:            store,
+		Enabled:          runtimeCfg.BackupHooks && runtimeCfg.WritesAllowed(),
+		Mode:             runtimeCfg.Mode,
+		SystemNamespaces: sysNs,
+		Timeout:          timeout,
+		Lead:             lead,
+		MaxHold:          maxHold,
+		Journal:          j,
+	}
+	if r.Enabled {
+		if lead-controller.HookLeadMargin <= timeout {
+			return nil, fmt.Errorf("%s=%s must be longer than %s=%s plus %s: the pre-hooks must finish before the sync starts",
+				runtimeconfig.EnvBackupHookLead, lead, runtimeconfig.EnvBackupHookTimeout, timeout, controller.HookLeadMargin)
+		}
+		execer, err := newPodExecer(restCfg)
+		if err != nil {
+			return nil, err
+		}
+		r.Exec = execer
+	}
+	return r, nil
+}
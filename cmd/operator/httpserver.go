@@ -1,7 +1,7 @@
 // HTTP server construction for the operator binary.
 //
 // This mirrors cmd/pvc-plumber/main.go's setup so the operator can host the
-// existing read-only `/exists/`, `/healthz`, `/readyz`, `/metrics` surface
+// existing read-only `/exists/`, `/exists:batch`, `/healthz`, `/readyz`, `/metrics` surface
 // alongside the new controller-runtime manager. The two share a single
 // backend + cache instance, so webhook handlers and HTTP callers benefit
 // from one connection to Kopia and one cached decision per (ns, pvc).
@@ -19,21 +19,37 @@ import (
 	"net/http"
 	"time"
 
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
 	"github.com/mitchross/pvc-plumber/internal/cache"
+	"github.com/mitchross/pvc-plumber/internal/composite"
 	"github.com/mitchross/pvc-plumber/internal/config"
+	"github.com/mitchross/pvc-plumber/internal/controller"
 	"github.com/mitchross/pvc-plumber/internal/handler"
+	"github.com/mitchross/pvc-plumber/internal/inventory"
 	"github.com/mitchross/pvc-plumber/internal/kopia"
+	"github.com/mitchross/pvc-plumber/internal/kopia/native"
 	"github.com/mitchross/pvc-plumber/internal/s3"
+	"github.com/mitchross/pvc-plumber/internal/v4/auditclient"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+	"github.com/mitchross/pvc-plumber/internal/v4/runtimeconfig"
 )
 
 // backendBundle groups the constructed backend, the cache wrapper that
-// fronts it, and (when applicable) the typed *kopia.Client. The kopia
-// pointer is only set for BACKEND_TYPE=kopia-s3; callers must nil-check
-// before using it (e.g. for the periodic cache re-warm loop).
+// fronts it, and (when applicable) the kopia reader as a
+// kopia.SourceSummarizer — the CLI-backed *kopia.Client or the native
+// reader, per KOPIA_READER. The kopia field is only set for
+// BACKEND_TYPE=kopia-s3 or kopia-fs; callers must nil-check before using
+// it (e.g. for the periodic cache re-warm loop). credWatcher is set when the kopia
+// reader reconnects on Secret rotation; newHTTPServer exposes its
+// counters on /metrics.
 type backendBundle struct {
-	backend handler.BackendClient
-	cached  *cache.CachedClient
-	kopia   *kopia.Client
+	backend     handler.BackendClient
+	cached      *cache.CachedClient
+	kopia       kopia.SourceSummarizer
+	credWatcher *kopia.CredentialsWatcher
 }
 
 // buildBackend constructs the backend client + cache layer the same way
@@ -44,17 +60,84 @@ type backendBundle struct {
 // On BACKEND_TYPE=s3 the kopia field is nil; the cache layer wraps the S3
 // client directly. The webhook layer doesn't care which backend it's
 // talking to as long as the BackendClient.CheckBackupExists contract holds.
+//
+// With BACKENDS set, every member is built and wrapped in a
+// composite.Client under BACKEND_MERGE_POLICY. The kopia field is then
+// nil: the cache's scan, pre-warm and re-warm paths read one repository,
+// and a scan of one member can't stand in for the merged answer.
 func buildBackend(ctx context.Context, cfg *config.Config, logger *slog.Logger) (*backendBundle, error) {
+	var members []composite.Member
+	var kopiaClient kopia.SourceSummarizer
+	var credWatcher *kopia.CredentialsWatcher
+	for _, typ := range cfg.BackendTypes() {
+		m, err := buildMember(ctx, typ, cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, composite.Member{Name: typ, Backend: m.backend})
+		if m.kopia != nil {
+			kopiaClient = m.kopia
+		}
+		if m.credWatcher != nil {
+			credWatcher = m.credWatcher
+		}
+	}
+
+	var backendClient handler.BackendClient = members[0].Backend
+	if len(members) > 1 {
+		logger.Info("initializing composite backend", "members", cfg.Backends, "policy", cfg.BackendMergePolicy)
+		cc, err := composite.New(members, cfg.BackendMergePolicy, logger)
+		if err != nil {
+			return nil, err
+		}
+		backendClient = cc
+		kopiaClient = nil
+	}
+
+	cachedBackend := cache.New(backendClient, cfg.CacheTTL, logger)
+	cachedBackend.SetNegativeTTL(cfg.CacheNegativeTTL)
+
+	// Pre-warm only on kopia (S3 has its own listing semantics). Failure is
+	// non-fatal — the cache populates on demand.
+	if kopiaClient != nil {
+		cachedBackend.SetScanner(kopiaClient)
+		cachedBackend.SetSummaryBackend(cfg.BackendType)
+		sources, err := kopiaClient.SummarizeAllSources(ctx)
+		if err != nil {
+			logger.Warn("cache pre-warm failed, will populate on demand", "error", err)
+		} else {
+			cachedBackend.PreWarmSummaries(sources)
+		}
+	}
+
+	return &backendBundle{
+		backend:     backendClient,
+		cached:      cachedBackend,
+		kopia:       kopiaClient,
+		credWatcher: credWatcher,
+	}, nil
+}
+
+// buildMember constructs one uncached backend of type typ. The returned
+// bundle's cached field is unset; kopia and credWatcher are set for
+// the kopia backends as buildBackend documents.
+func buildMember(ctx context.Context, typ string, cfg *config.Config, logger *slog.Logger) (*backendBundle, error) {
 	var backendClient handler.BackendClient
-	var kopiaClient *kopia.Client
+	var kopiaClient kopia.SourceSummarizer
+	var credWatcher *kopia.CredentialsWatcher
 
-	switch cfg.BackendType {
+	switch typ {
 	case "s3":
 		logger.Info("initializing s3 backend",
 			"endpoint", cfg.S3Endpoint,
 			"bucket", cfg.S3Bucket,
-			"secure", cfg.S3Secure)
-		s3Client, err := s3.NewClient(cfg.S3Endpoint, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey, cfg.S3Secure)
+			"secure", cfg.S3Secure,
+			"layout", cfg.S3Layout)
+		layout, err := s3.NewLayout(s3.LayoutOptions{Name: cfg.S3Layout, Prefix: cfg.S3Prefix, IndexKey: cfg.S3IndexKey})
+		if err != nil {
+			return nil, err
+		}
+		s3Client, err := s3.NewClient(cfg.S3Endpoint, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey, cfg.S3Secure, s3.Options{Layout: layout})
 		if err != nil {
 			return nil, fmt.Errorf("create s3 client: %w", err)
 		}
@@ -67,6 +150,7 @@ func buildBackend(ctx context.Context, cfg *config.Config, logger *slog.Logger)
 			"disable_tls", cfg.KopiaS3DisableTLS,
 			"credentials_path", cfg.KopiaCredentialsPath,
 			"connect_timeout", cfg.KopiaConnectTimeout,
+			"reader", cfg.KopiaReader,
 		)
 		// v3.1.0: prefer the directory-mounted-Secret credentials source
 		// when KopiaCredentialsPath is set (operator deployment shape).
@@ -79,11 +163,76 @@ func buildBackend(ctx context.Context, cfg *config.Config, logger *slog.Logger)
 		} else {
 			creds = kopia.NewStaticCredentialsSource(cfg.KopiaPassword, cfg.KopiaS3AccessKey, cfg.KopiaS3SecretKey)
 		}
-		kc := kopia.NewClient(kopia.S3Config{
+		s3cfg := kopia.S3Config{
 			Endpoint:   cfg.KopiaS3Endpoint,
 			Bucket:     cfg.KopiaS3Bucket,
 			DisableTLS: cfg.KopiaS3DisableTLS,
-		}, creds, logger, kopia.Options{ConnectTimeout: cfg.KopiaConnectTimeout})
+		}
+		kopts := kopia.Options{ConnectTimeout: cfg.KopiaConnectTimeout}
+		switch cfg.KopiaReader {
+		case config.KopiaReaderNative:
+			nc, err := native.NewS3Client(native.S3Options{
+				Endpoint:   cfg.KopiaS3Endpoint,
+				Bucket:     cfg.KopiaS3Bucket,
+				DisableTLS: cfg.KopiaS3DisableTLS,
+			}, creds, logger, native.Options{ConnectTimeout: cfg.KopiaConnectTimeout})
+			if err != nil {
+				return nil, fmt.Errorf("create native kopia reader: %w", err)
+			}
+			if err := nc.Connect(ctx); err != nil {
+				return nil, fmt.Errorf("connect to kopia repository: %w", err)
+			}
+			kopiaClient = nc
+			backendClient = nc
+			credWatcher = watchCredentials(ctx, creds, nc, logger)
+		case config.KopiaReaderServer:
+			// The managed server lives as long as the manager context.
+			// Credentials are not watched here: `kopia server start`
+			// holds the repository open with the keys it started with,
+			// so a rotation needs a pod restart in this mode.
+			sc, err := kopia.ConnectServer(ctx, kopia.ServerConfig{
+				URL:      cfg.KopiaServerURL,
+				Username: cfg.KopiaServerUsername,
+				Password: cfg.KopiaServerPassword,
+				Timeout:  cfg.KopiaServerTimeout,
+			}, kopia.ManagedServerOptions{Address: cfg.KopiaServerAddress},
+				kopia.NewClient(s3cfg, creds, logger, kopts), logger)
+			if err != nil {
+				return nil, fmt.Errorf("connect to kopia server: %w", err)
+			}
+			kopiaClient = sc
+			backendClient = sc
+		default:
+			kc := kopia.NewClient(s3cfg, creds, logger, kopts)
+			if err := kc.Connect(ctx); err != nil {
+				return nil, fmt.Errorf("connect to kopia repository: %w", err)
+			}
+			kopiaClient = kc
+			backendClient = kc
+			credWatcher = watchCredentials(ctx, creds, kc, logger)
+		}
+
+	case "kopia-fs":
+		logger.Info("initializing kopia-fs backend",
+			"path", cfg.KopiaFSPath,
+			"connect_timeout", cfg.KopiaConnectTimeout,
+			"reader", cfg.KopiaReader,
+		)
+		// Development / CI shape: a local repository and an env-var
+		// password, so there is no Secret mount to watch.
+		creds := kopia.NewPasswordCredentialsSource(cfg.KopiaPassword)
+		if cfg.KopiaReader == config.KopiaReaderNative {
+			nc := native.NewClient(native.DirStore{Dir: cfg.KopiaFSPath}, creds, logger,
+				native.Options{ConnectTimeout: cfg.KopiaConnectTimeout, BackendType: backend.TypeKopiaFS})
+			if err := nc.Connect(ctx); err != nil {
+				return nil, fmt.Errorf("open kopia repository: %w", err)
+			}
+			kopiaClient = nc
+			backendClient = nc
+			break
+		}
+		kc := kopia.NewFilesystemClient(kopia.FilesystemConfig{Path: cfg.KopiaFSPath}, creds, logger,
+			kopia.Options{ConnectTimeout: cfg.KopiaConnectTimeout})
 		if err := kc.Connect(ctx); err != nil {
 			return nil, fmt.Errorf("connect to kopia repository: %w", err)
 		}
@@ -91,30 +240,36 @@ func buildBackend(ctx context.Context, cfg *config.Config, logger *slog.Logger)
 		backendClient = kc
 
 	default:
-		return nil, fmt.Errorf("invalid BACKEND_TYPE: %s", cfg.BackendType)
-	}
-
-	cachedBackend := cache.New(backendClient, cfg.CacheTTL, logger)
-
-	// Pre-warm only on kopia (S3 has its own listing semantics). Failure is
-	// non-fatal — the cache populates on demand.
-	if kopiaClient != nil {
-		sources, err := kopiaClient.ListAllSources(ctx)
-		if err != nil {
-			logger.Warn("cache pre-warm failed, will populate on demand", "error", err)
-		} else {
-			cachedBackend.PreWarm(sources)
-		}
+		return nil, fmt.Errorf("invalid BACKEND_TYPE: %s", typ)
 	}
 
 	return &backendBundle{
-		backend: backendClient,
-		cached:  cachedBackend,
-		kopia:   kopiaClient,
+		backend:     backendClient,
+		kopia:       kopiaClient,
+		credWatcher: credWatcher,
 	}, nil
 }
 
-// newHTTPServer wires the existing /exists, /healthz, /readyz, /metrics
+// watchCredentials starts a CredentialsWatcher for directory-mounted
+// credentials and returns it, or nil for env-var (static) credentials,
+// which cannot change under a running process. The watcher lives as long
+// as ctx; failing to watch is logged, not fatal — the pod then behaves as
+// before and picks up a rotation on restart.
+func watchCredentials(ctx context.Context, creds kopia.CredentialsSource, target kopia.Reconnector, logger *slog.Logger) *kopia.CredentialsWatcher {
+	dir, ok := creds.(*kopia.DirCredentialsSource)
+	if !ok {
+		return nil
+	}
+	w := kopia.NewCredentialsWatcher(dir, target, logger)
+	go func() {
+		if err := w.Run(ctx); err != nil {
+			logger.Warn("kopia credential rotation disabled", "error", err)
+		}
+	}()
+	return w
+}
+
+// newHTTPServer wires the /exists, /exists:batch, /healthz, /readyz, /metrics
 // routes onto a *http.Server bound to cfg.Port. The caller is responsible
 // for ListenAndServe + Shutdown.
 func newHTTPServer(cfg *config.Config, b *backendBundle, logger *slog.Logger) *http.Server {
@@ -124,9 +279,13 @@ func newHTTPServer(cfg *config.Config, b *backendBundle, logger *slog.Logger) *h
 	}
 	h := handler.NewWithHealthChecker(b.cached, healthChecker, logger)
 	h.SetRequestTimeout(cfg.HTTPTimeout)
+	if b.credWatcher != nil {
+		h.SetRotationCounter(b.credWatcher)
+	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/exists/", h.HandleExists)
+	mux.HandleFunc("/exists:batch", h.HandleExistsBatch)
 	mux.HandleFunc("/healthz", h.HandleHealthz)
 	mux.HandleFunc("/readyz", h.HandleReadyz)
 	mux.HandleFunc("/metrics", h.HandleMetrics)
@@ -151,7 +310,7 @@ func newHTTPServer(cfg *config.Config, b *backendBundle, logger *slog.Logger) *h
 // the dual-mode purpose is documented here and at the call site in
 // main.go. A cosmetic rename to newV4HTTPServer is a future cleanup.
 //
-// /exists is deliberately not mounted. v4-routed pods are not in the
+// /exists (and /exists:batch) is deliberately not mounted. v4-routed pods are not in the
 // admission decision path and the binary does not initialize a
 // backend (audit + permissive both skip buildBackend), so surfacing
 // /exists would either crash or return misleading 503s.
@@ -159,11 +318,45 @@ func newHTTPServer(cfg *config.Config, b *backendBundle, logger *slog.Logger) *h
 // /metrics is also not mounted here. The controller-runtime manager
 // exposes its own /metrics on metricsAddr (:8081 by default), which
 // is sufficient for v4-mode observability.
-func newAuditHTTPServer(cfg *config.Config, store handler.ParitySnapshotter, logger *slog.Logger) *http.Server {
+//
+// /journal (the executor write journal) is mounted when journal is
+// non-nil. main() always passes one in v4 modes; the nil case keeps
+// the route-surface tests independent of the journal.
+//
+// /audit/inventory is mounted when inv is non-nil (PVC_PLUMBER_INVENTORY
+// =true). It is the one route here that scans the backup repository
+// per request, so it stays opt-in, and the write timeout grows to cover
+// its scan.
+//
+// /audit/usage and /audit/usage/metrics are mounted when usage is
+// non-nil (PVC_PLUMBER_USAGE=true). Both serve the tracker's last
+// report, so neither affects the write timeout.
+//
+// POST /backup/{namespace}/{pvc} is mounted when backup is non-nil
+// (PVC_PLUMBER_BACKUP_API_TOKEN_FILE set). It is the only write route
+// and the only authenticated one, and it holds the connection for the
+// whole backup, so the write timeout grows to cover backupWriteTimeout.
+func newAuditHTTPServer(cfg *config.Config, store handler.ParitySnapshotter, journal handler.JournalQuerier, inv handler.InventoryReporter, usage handler.UsageSource, backup *backupAPI, logger *slog.Logger) *http.Server {
 	audit := handler.NewAuditHandler(store, logger)
 
 	mux := http.NewServeMux()
 	mux.Handle("/audit", audit)
+	if journal != nil {
+		mux.Handle("/journal", handler.NewJournalHandler(journal, logger))
+	}
+	writeTimeout := 10 * time.Second
+	if inv != nil {
+		mux.Handle("/audit/inventory", handler.NewInventoryHandler(inv, logger))
+		writeTimeout = handler.InventoryScanTimeout + 5*time.Second
+	}
+	if usage != nil {
+		mux.Handle("/audit/usage", handler.NewUsageHandler(usage, logger))
+		mux.Handle("/audit/usage/metrics", handler.NewUsageMetricsHandler(usage))
+	}
+	if backup != nil {
+		mux.Handle(handler.BackupPathPrefix, handler.NewBackupHandler(backup.trigger, backup.tokenFile, logger))
+		writeTimeout = max(writeTimeout, backupWriteTimeout(backup.timeout))
+	}
 	mux.HandleFunc("/healthz", audithealthHandler)
 	mux.HandleFunc("/readyz", audithealthHandler)
 
@@ -171,8 +364,177 @@ func newAuditHTTPServer(cfg *config.Config, store handler.ParitySnapshotter, log
 		Addr:         ":" + cfg.Port,
 		Handler:      mux,
 		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		WriteTimeout: writeTimeout,
+	}
+}
+
+// buildInventory wires the /audit/inventory reporter, or returns nil
+// when PVC_PLUMBER_INVENTORY is off. The reporter pairs an uncached
+// apiserver client for the PVC listing (the manager's cache only runs
+// under OPERATOR_MODE and only for what the reconciler watches) and the
+// native reader for the repository scan. The kopia settings are loaded
+// here, with full validation, because the v4 config load skipped them;
+// a misconfiguration is an error since the inventory was asked for,
+// but nothing connects until the first request.
+func buildInventory(enabled bool, logger *slog.Logger) (handler.InventoryReporter, error) {
+	if !enabled {
+		return nil, nil
+	}
+	kcfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("inventory kopia settings: %w", err)
+	}
+	lineages, err := inventory.NewReader(kcfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	restCfg, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("inventory kubeconfig: %w", err)
+	}
+	c, err := client.New(restCfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("inventory kube client: %w", err)
+	}
+	logger.Info("backup inventory enabled at /audit/inventory", "backend", kcfg.BackendType)
+	return &inventory.Auditor{PVCs: inventory.KubePVCs{Reader: c}, Lineages: lineages}, nil
+}
+
+// usageRefreshTimeout bounds one usage refresh: two apiserver listings
+// and a whole-repository scan, the same work as one /audit/inventory.
+const usageRefreshTimeout = handler.InventoryScanTimeout
+
+// buildUsage wires the backup usage tracker, or returns nil when
+// PVC_PLUMBER_USAGE is off. Same clients and the same full kopia config
+// load as buildInventory; the refresh cadence is RE_WARM_INTERVAL, which
+// must then be non-zero. Each new report's soft-quota notes replace the
+// store's namespace notes, so a namespace that drops under its quota
+// loses the warning on the next refresh.
+func buildUsage(enabled bool, store *controller.Store, logger *slog.Logger) (*inventory.UsageTracker, time.Duration, error) {
+	if !enabled {
+		return nil, 0, nil
 	}
+	kcfg, err := config.Load()
+	if err != nil {
+		return nil, 0, fmt.Errorf("usage kopia settings: %w", err)
+	}
+	if kcfg.ReWarmInterval <= 0 {
+		return nil, 0, fmt.Errorf("%s=true needs a non-zero RE_WARM_INTERVAL", runtimeconfig.EnvUsage)
+	}
+	lineages, err := inventory.NewReader(kcfg, logger)
+	if err != nil {
+		return nil, 0, err
+	}
+	restCfg, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, 0, fmt.Errorf("usage kubeconfig: %w", err)
+	}
+	c, err := client.New(restCfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, 0, fmt.Errorf("usage kube client: %w", err)
+	}
+	logger.Info("backup usage report enabled at /audit/usage", "backend", kcfg.BackendType, "interval", kcfg.ReWarmInterval)
+	return &inventory.UsageTracker{
+		PVCs:     inventory.KubePVCs{Reader: c},
+		Quotas:   inventory.KubeQuotas{Reader: c},
+		Lineages: lineages,
+		OnReport: func(r inventory.UsageReport) { store.SetNamespaceNotes(r.NamespaceNotes()) },
+		Logger:   logger,
+	}, kcfg.ReWarmInterval, nil
+}
+
+// kopiaReader is the reconciler's view of the kopia repository: the
+// lineage listing behind the point-in-time restore preview and the
+// whole-repository listing behind backup freshness (read through a
+// controller.BackupIndex, never per reconcile).
+type kopiaReader interface {
+	controller.LineageLister
+	kopia.SnapshotLister
+}
+
+// buildKopiaReader wires the reconciler's kopia reads (restore preview
+// and freshness), or returns nil when the v4-mode BACKEND_TYPE (read
+// verbatim, unset by default) names no kopia backend — the preview then
+// reports selections as unresolved and freshness comes from the RS
+// status alone. Same native reader and full kopia config load as
+// buildInventory, so enabling it adds no startup dependency on the
+// repository being reachable.
+func buildKopiaReader(backendType string, logger *slog.Logger) (kopiaReader, error) {
+	if backendType != backend.TypeKopiaS3 && backendType != backend.TypeKopiaFS {
+		return nil, nil
+	}
+	kcfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("reconciler kopia settings: %w", err)
+	}
+	reader, err := inventory.NewReader(kcfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("kopia restore preview and backup freshness enabled", "backend", kcfg.BackendType)
+	return reader, nil
+}
+
+// backupAPI is what newAuditHTTPServer needs to mount POST /backup: the
+// trigger, the token file the handler authenticates against, and the
+// wait timeout the server's write timeout must cover.
+type backupAPI struct {
+	trigger   handler.BackupTriggerer
+	tokenFile string
+	timeout   time.Duration
+}
+
+// backupWriteTimeout is the write timeout that covers one on-demand
+// backup: the wait itself, then restoring the RS trigger and looking up
+// the snapshot (30s each at most), plus slack for the response.
+func backupWriteTimeout(wait time.Duration) time.Duration {
+	return wait + 90*time.Second
+}
+
+// buildBackupAPI wires POST /backup, or returns nil when
+// PVC_PLUMBER_BACKUP_API_TOKEN_FILE is unset. The trigger gets its own
+// uncached, mode-gated client — the audit server starts before the
+// manager, and the wait should poll the apiserver's RS status rather
+// than an informer's — and the journal the reconciler writes to. The
+// snapshot lookup reuses buildKopiaReader, so it is on only for
+// kopia backends.
+func buildBackupAPI(runtimeCfg runtimeconfig.Config, backendType string, j *journal.Journal, logger *slog.Logger) (*backupAPI, error) {
+	if runtimeCfg.BackupAPITokenFile == "" {
+		return nil, nil
+	}
+	restCfg, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("backup API kubeconfig: %w", err)
+	}
+	c, err := client.New(restCfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("backup API kube client: %w", err)
+	}
+	var snapshots controller.LineageLister
+	reader, err := buildKopiaReader(backendType, logger)
+	if err != nil {
+		return nil, err
+	}
+	if reader != nil {
+		snapshots = reader
+	}
+	timeout := runtimeCfg.BackupTimeout
+	if timeout <= 0 {
+		timeout = controller.DefaultBackupTimeout
+	}
+	logger.Info("on-demand backup API enabled at POST "+handler.BackupPathPrefix+"{namespace}/{pvc}",
+		"mode", runtimeCfg.Mode.String(), "timeout", timeout.String())
+	return &backupAPI{
+		trigger: &controller.OnDemandBackup{
+			Client:    auditclient.New(c, runtimeCfg.Mode, logger),
+			Mode:      runtimeCfg.Mode,
+			Journal:   j,
+			Snapshots: snapshots,
+			Timeout:   timeout,
+		},
+		tokenFile: runtimeCfg.BackupAPITokenFile,
+		timeout:   timeout,
+	}, nil
 }
 
 // audithealthHandler is a backend-free liveness/readiness probe used by
@@ -191,7 +553,7 @@ func audithealthHandler(w http.ResponseWriter, _ *http.Request) {
 // exists=true within one re-warm cycle. Returns when ctx is canceled.
 func runCacheReWarmLoop(
 	ctx context.Context,
-	kopiaClient *kopia.Client,
+	kopiaClient kopia.SourceSummarizer,
 	cachedBackend *cache.CachedClient,
 	interval time.Duration,
 	logger *slog.Logger,
@@ -212,13 +574,13 @@ func runCacheReWarmLoop(
 			return
 		case <-ticker.C:
 			callCtx, cancel := context.WithTimeout(ctx, callTimeout)
-			sources, err := kopiaClient.ListAllSources(callCtx)
+			sources, err := kopiaClient.SummarizeAllSources(callCtx)
 			cancel()
 			if err != nil {
 				logger.Warn("cache re-warm failed; keeping previous entries", "error", err)
 				continue
 			}
-			cachedBackend.Refresh(sources)
+			cachedBackend.RefreshSummaries(sources)
 		}
 	}
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/auditclient"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+	"github.com/mitchross/pvc-plumber/internal/v4/runtimeconfig"
+)
+
+// buildJournal constructs the executor write journal with the sinks
+// that need no cluster access (file, stdout). The configmap sink needs
+// a kube client and is attached later by attachConfigMapJournalSink
+// from inside runManager. With no sinks configured the journal still
+// keeps its in-memory ring, so /journal is always served in v4 modes.
+//
+// A file sink that cannot be opened is fatal: an operator who asked for
+// a durable journal and silently got none would only find out during
+// the incident review the journal exists for.
+func buildJournal(runtimeCfg runtimeconfig.Config, logger *slog.Logger) (*journal.Journal, error) {
+	j := journal.New(logger)
+	if runtimeCfg.HasJournalSink(runtimeconfig.JournalSinkFile) {
+		fs, err := journal.NewFileSink(runtimeCfg.JournalFile, runtimeCfg.JournalFileMaxBytes, runtimeCfg.JournalFileMaxFiles)
+		if err != nil {
+			return nil, fmt.Errorf("journal file sink: %w", err)
+		}
+		j.AddSink(fs)
+	}
+	if runtimeCfg.HasJournalSink(runtimeconfig.JournalSinkStdout) {
+		j.AddSink(journal.NewStdoutSink())
+	}
+	return j, nil
+}
+
+// attachConfigMapJournalSink adds the configmap sink when configured.
+// It uses a dedicated uncached client (a cached Get would start a
+// cluster-wide ConfigMap informer to read one object) wrapped in
+// auditclient like the reconciler's, so an audit-mode pod cannot write
+// the ConfigMap even if something were ever journaled there.
+func attachConfigMapJournalSink(j *journal.Journal, restCfg *rest.Config, runtimeCfg runtimeconfig.Config, logger *slog.Logger) error {
+	if !runtimeCfg.HasJournalSink(runtimeconfig.JournalSinkConfigMap) {
+		return nil
+	}
+	ns, name, ok := runtimeconfig.SplitNamespacedName(runtimeCfg.JournalConfigMap)
+	if !ok {
+		// Load already drops the sink for a malformed target; defensive.
+		return fmt.Errorf("journal configmap %q is not namespace/name", runtimeCfg.JournalConfigMap)
+	}
+	c, err := client.New(restCfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return fmt.Errorf("journal configmap client: %w", err)
+	}
+	j.AddSink(journal.NewConfigMapSink(auditclient.New(c, runtimeCfg.Mode, logger), ns, name, runtimeCfg.JournalConfigMapMaxEntries))
+	logger.Info("journal configmap sink attached", "namespace", ns, "name", name)
+	return nil
+}
@@ -43,7 +43,9 @@ import (
 
 	"github.com/mitchross/pvc-plumber/internal/config"
 	"github.com/mitchross/pvc-plumber/internal/controller"
+	"github.com/mitchross/pvc-plumber/internal/handler"
 	"github.com/mitchross/pvc-plumber/internal/v4/auditclient"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
 	"github.com/mitchross/pvc-plumber/internal/v4/mode"
 	"github.com/mitchross/pvc-plumber/internal/v4/naming"
 	"github.com/mitchross/pvc-plumber/internal/v4/runtimeconfig"
@@ -350,6 +352,24 @@ func main() {
 		auditStore.SetMaxAge(auditStaleMaxAge)
 	}
 
+	// Executor write journal: shared by the v4 reconciler (writer) and
+	// the /journal endpoint (reader), same as the Store. File/stdout
+	// sinks are attached here; the configmap sink needs the manager's
+	// rest config and is attached in runManager.
+	var writeJournal *journal.Journal
+	if auditStore != nil {
+		writeJournal, err = buildJournal(runtimeCfg, slogger)
+		if err != nil {
+			slogger.Error("journal init failed", "error", err)
+			os.Exit(1)
+		}
+		slogger.Info("executor write journal enabled",
+			"sinks", runtimeCfg.JournalSinks,
+			"file", runtimeCfg.JournalFile,
+			"configmap", runtimeCfg.JournalConfigMap,
+		)
+	}
+
 	// errgroup collects errors from any subsystem. ctx derives from
 	// rootCtx; if any goroutine returns non-nil, ctx cancels and the rest
 	// shut down. mgr.Start respects that ctx; http.Server respects it via
@@ -380,7 +400,7 @@ func main() {
 			return nil
 		})
 
-		// 2. Cache re-warm loop (kopia-s3 only). Identical cadence to the
+		// 2. Cache re-warm loop (kopia backends only). Identical cadence to the
 		//    legacy binary; ctx cancellation stops it within one tick.
 		if bundle.kopia != nil && cfg.ReWarmInterval > 0 {
 			g.Go(func() error {
@@ -401,7 +421,30 @@ func main() {
 	// pod is running in. Mounted whenever runsV4Reconciler(mode) is
 	// true (audit + permissive today).
 	if auditStore != nil {
-		auditSrv := newAuditHTTPServer(cfg, auditStore, slogger)
+		inv, err := buildInventory(runtimeCfg.Inventory, slogger)
+		if err != nil {
+			slogger.Error("inventory init failed", "error", err)
+			os.Exit(1)
+		}
+		usage, usageInterval, err := buildUsage(runtimeCfg.Usage, auditStore, slogger)
+		if err != nil {
+			slogger.Error("usage report init failed", "error", err)
+			os.Exit(1)
+		}
+		var usageSource handler.UsageSource
+		if usage != nil {
+			usageSource = usage
+			g.Go(func() error {
+				usage.Run(gctx, usageInterval, usageRefreshTimeout)
+				return nil
+			})
+		}
+		backup, err := buildBackupAPI(runtimeCfg, cfg.BackendType, writeJournal, slogger)
+		if err != nil {
+			slogger.Error("backup API init failed", "error", err)
+			os.Exit(1)
+		}
+		auditSrv := newAuditHTTPServer(cfg, auditStore, writeJournal, inv, usageSource, backup, slogger)
 		g.Go(func() error {
 			slogger.Info("audit http server starting", "addr", auditSrv.Addr)
 			if err := auditSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -438,6 +481,7 @@ func main() {
 				runtimeCfg,
 				sysNs,
 				auditStore,
+				writeJournal,
 				metricsAddr, probeAddr,
 				webhookPort, webhookCertDir,
 				enableLeaderElection, leaderElectionID,
@@ -477,6 +521,7 @@ func runManager(
 	runtimeCfg runtimeconfig.Config,
 	sysNs map[string]struct{},
 	auditStore *controller.Store,
+	writeJournal *journal.Journal,
 	metricsAddr, probeAddr string,
 	webhookPort int, webhookCertDir string,
 	enableLeaderElection bool, leaderElectionID string,
@@ -500,7 +545,8 @@ func runManager(
 		"leader_election", enableLeaderElection,
 	)
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), manager.Options{
+	restCfg := ctrl.GetConfigOrDie()
+	mgr, err := ctrl.NewManager(restCfg, manager.Options{
 		Scheme: scheme,
 		Metrics: metricsserver.Options{
 			BindAddress: metricsAddr,
@@ -523,6 +569,21 @@ func runManager(
 	// cluster — see internal/v4/auditclient.
 	reconcilerClient := auditclient.New(mgr.GetClient(), runtimeCfg.Mode, slogger)
 
+	// Kopia repository maintenance (KOPIA_MAINTENANCE_*_INTERVAL). Off
+	// unless a cadence is set; leader-elected because every replica
+	// claims it under the same owner (see kopia.Maintainer). Built here
+	// rather than with the backend bundle, which v4 modes never build.
+	maint, err := setupMaintenance(mgr, cfg.BackendType, runtimeCfg.WritesAllowed(), enableLeaderElection, slogger)
+	if err != nil {
+		return err
+	}
+	if maint != nil {
+		slogger.Info("kopia maintenance registered",
+			"status", maintenanceStatusPath,
+			"metrics", maintenanceMetricsPath,
+		)
+	}
+
 	// Reconciler selection. Audit and permissive both run V4AuditReconciler
 	// (the executor's Mode-gated short-circuit keeps audit observe-only) —
 	// the v3 PVCReconciler is not registered for either, so even an
@@ -544,9 +605,91 @@ func runManager(
 		// the fields may be zero / nil and the executor short-circuits
 		// anyway, so the pass-through is harmless.
 		v4rec := newV4Reconciler(reconcilerClient, auditStore, sysNs, runtimeCfg)
+		if err := attachConfigMapJournalSink(writeJournal, restCfg, runtimeCfg, slogger); err != nil {
+			return err
+		}
+		// Queued sink delivery: reconcile workers hand entries off
+		// instead of waiting on the configmap round-trip.
+		if err := mgr.Add(writeJournal); err != nil {
+			return fmt.Errorf("add journal: %w", err)
+		}
+		v4rec.Journal = writeJournal
+		reader, err := buildKopiaReader(cfg.BackendType, slogger)
+		if err != nil {
+			return err
+		}
+		if reader != nil {
+			// Freshness reads a listing refreshed on the re-warm
+			// cadence: the reader's own CheckBackupExists scans the
+			// whole repository, once per reconciled PVC.
+			index := &controller.BackupIndex{Lister: reader, Interval: cfg.ReWarmInterval}
+			if err := mgr.Add(index); err != nil {
+				return fmt.Errorf("add BackupIndex: %w", err)
+			}
+			v4rec.Snapshots = reader
+			v4rec.Backups = index
+		}
 		if err := v4rec.SetupWithManager(mgr); err != nil {
 			return fmt.Errorf("setup V4AuditReconciler: %w", err)
 		}
+		// Orphaned-child sweep: the reconciler forgets a PVC's children
+		// when the PVC is deleted; this runnable finds them again.
+		reaper := newOrphanReaper(reconcilerClient, mgr.GetAPIReader(), auditStore, sysNs, runtimeCfg, writeJournal)
+		if err := mgr.Add(reaper); err != nil {
+			return fmt.Errorf("add OrphanReaper: %w", err)
+		}
+		slogger.Info("orphan reaper registered",
+			"reap_replication_sources", reaper.ReapReplicationSources,
+			"grace", reaper.Grace.String(),
+			"interval", reaper.Interval.String(),
+		)
+		// Restore drills: opt-in via PVC_PLUMBER_DRILL_NAMESPACE, and only
+		// where writes are allowed — a drill is nothing but writes.
+		drill, err := newDrillRunner(reconcilerClient, auditStore, sysNs, runtimeCfg, writeJournal)
+		if err != nil {
+			return err
+		}
+		switch {
+		case drill != nil:
+			if err := mgr.Add(drill); err != nil {
+				return fmt.Errorf("add DrillRunner: %w", err)
+			}
+			slogger.Info("restore drill registered",
+				"sandbox_namespace", drill.SandboxNamespace,
+				"interval", drill.Interval.String(),
+				"timeout", drill.Timeout.String(),
+				"image", drill.Image,
+			)
+		case runtimeCfg.DrillNamespace != "":
+			slogger.Info("restore drill NOT registered: drills write, and this mode does not allow writes",
+				"mode", runtimeCfg.Mode.String())
+		}
+		// Restore-to-clone: always on, like the orphan sweep. In audit
+		// mode the executor skips its writes and /audit shows the clones
+		// as skipped.
+		cloner := newCloneRunner(reconcilerClient, auditStore, sysNs, runtimeCfg, writeJournal)
+		if err := mgr.Add(cloner); err != nil {
+			return fmt.Errorf("add CloneRunner: %w", err)
+		}
+		slogger.Info("restore clone runner registered",
+			"default_ttl", cloner.DefaultTTL.String(),
+			"interval", cloner.Interval.String(),
+		)
+		// Backup hooks: registered whenever v4 runs, so PVCs carrying
+		// hook annotations show up on /audit even when hooks cannot run.
+		hooks, err := newHookRunner(reconcilerClient, mgr.GetAPIReader(), restCfg, auditStore, sysNs, runtimeCfg, writeJournal)
+		if err != nil {
+			return err
+		}
+		if err := mgr.Add(hooks); err != nil {
+			return fmt.Errorf("add HookRunner: %w", err)
+		}
+		slogger.Info("backup hook runner registered",
+			"enabled", hooks.Enabled,
+			"timeout", hooks.Timeout.String(),
+			"lead", hooks.Lead.String(),
+			"max_hold", hooks.MaxHold.String(),
+		)
 		slogger.Info("v4 reconciler registered (v3 reconciler NOT registered)",
 			"mode", runtimeCfg.Mode.String(),
 			"naming_strategy", naming.StrategyBareDst.String(),
@@ -725,6 +868,113 @@ func newV4Reconciler(
 	}
 }
 
+// newOrphanReaper builds the orphaned-child sweep for runManager. Like
+// newV4Reconciler it is a factory so the config mapping is testable
+// without a manager. Zero durations from runtimeconfig resolve to the
+// controller package defaults here so the startup log shows the
+// effective values.
+func newOrphanReaper(
+	c client.Client,
+	reader client.Reader,
+	store *controller.Store,
+	sysNs map[string]struct{},
+	runtimeCfg runtimeconfig.Config,
+	j *journal.Journal,
+) *controller.OrphanReaper {
+	grace := runtimeCfg.OrphanGrace
+	if grace <= 0 {
+		grace = controller.DefaultOrphanGrace
+	}
+	interval := runtimeCfg.OrphanScanInterval
+	if interval <= 0 {
+		interval = controller.DefaultOrphanScanInterval
+	}
+	return &controller.OrphanReaper{
+		Client:                 c,
+		Reader:                 reader,
+		Store:                  store,
+		Mode:                   runtimeCfg.Mode,
+		SystemNamespaces:       sysNs,
+		ReapReplicationSources: runtimeCfg.OrphanReap,
+		Grace:                  grace,
+		Interval:               interval,
+		Journal:                j,
+	}
+}
+
+// newCloneRunner builds the restore-to-clone runner for runManager. Zero
+// durations resolve to the controller package defaults, as in
+// newOrphanReaper.
+func newCloneRunner(
+	c client.Client,
+	store *controller.Store,
+	sysNs map[string]struct{},
+	runtimeCfg runtimeconfig.Config,
+	j *journal.Journal,
+) *controller.CloneRunner {
+	ttl := runtimeCfg.CloneDefaultTTL
+	if ttl <= 0 {
+		ttl = controller.DefaultCloneTTL
+	}
+	interval := runtimeCfg.CloneScanInterval
+	if interval <= 0 {
+		interval = controller.DefaultCloneScanInterval
+	}
+	return &controller.CloneRunner{
+		Client:           c,
+		Store:            store,
+		Mode:             runtimeCfg.Mode,
+		SystemNamespaces: sysNs,
+		DefaultTTL:       ttl,
+		Interval:         interval,
+		Journal:          j,
+	}
+}
+
+// newDrillRunner builds the restore drill for runManager, or returns nil
+// when drills are off (no sandbox namespace) or the mode does not allow
+// writes. A sandbox that is a system namespace is a startup error rather
+// than a silently disabled drill. Zero durations and an empty image
+// resolve to the controller package defaults, as in newOrphanReaper.
+func newDrillRunner(
+	c client.Client,
+	store *controller.Store,
+	sysNs map[string]struct{},
+	runtimeCfg runtimeconfig.Config,
+	j *journal.Journal,
+) (*controller.DrillRunner, error) {
+	if runtimeCfg.DrillNamespace == "" || !runtimeCfg.WritesAllowed() {
+		return nil, nil
+	}
+	if _, isSystem := sysNs[runtimeCfg.DrillNamespace]; isSystem {
+		return nil, fmt.Errorf("%s=%q is a system namespace; restore drills need a dedicated sandbox",
+			runtimeconfig.EnvDrillNamespace, runtimeCfg.DrillNamespace)
+	}
+	interval := runtimeCfg.DrillInterval
+	if interval <= 0 {
+		interval = controller.DefaultDrillInterval
+	}
+	timeout := runtimeCfg.DrillTimeout
+	if timeout <= 0 {
+		timeout = controller.DefaultDrillTimeout
+	}
+	image := runtimeCfg.DrillImage
+	if image == "" {
+		image = controller.DefaultDrillImage
+	}
+	return &controller.DrillRunner{
+		Client:           c,
+		Store:            store,
+		Mode:             runtimeCfg.Mode,
+		SandboxNamespace: runtimeCfg.DrillNamespace,
+		SystemNamespaces: sysNs,
+		Interval:         interval,
+		Timeout:          timeout,
+		Image:            image,
+		Journal:          j,
+	}, nil
+}
+
 // int64OrZero dereferences a *int64, returning 0 when the pointer is
 // nil. Used to bridge runtimeconfig's "explicit zero vs unset"
 // distinction (Patch 6.8a) into the reconciler's plain int64 fields.
@@ -1,16 +1,27 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
 	"github.com/mitchross/pvc-plumber/internal/config"
 	"github.com/mitchross/pvc-plumber/internal/controller"
+	"github.com/mitchross/pvc-plumber/internal/handler"
+	"github.com/mitchross/pvc-plumber/internal/inventory"
+	"github.com/mitchross/pvc-plumber/internal/kopia"
 	"github.com/mitchross/pvc-plumber/internal/v4/mode"
 	"github.com/mitchross/pvc-plumber/internal/v4/naming"
 	"github.com/mitchross/pvc-plumber/internal/v4/runtimeconfig"
@@ -312,7 +323,7 @@ func testCfgPort() *config.Config {
 }
 
 func TestNewAuditHTTPServer_RoutesAuditEndpoint(t *testing.T) {
-	srv := newAuditHTTPServer(testCfgPort(), emptyAuditStore(), slog.New(slog.DiscardHandler))
+	srv := newAuditHTTPServer(testCfgPort(), emptyAuditStore(), nil, nil, nil, nil, slog.New(slog.DiscardHandler))
 
 	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/audit", nil)
 	rr := httptest.NewRecorder()
@@ -340,7 +351,7 @@ func TestNewAuditHTTPServer_RoutesAuditEndpoint(t *testing.T) {
 }
 
 func TestNewAuditHTTPServer_RoutesHealthz(t *testing.T) {
-	srv := newAuditHTTPServer(testCfgPort(), emptyAuditStore(), slog.New(slog.DiscardHandler))
+	srv := newAuditHTTPServer(testCfgPort(), emptyAuditStore(), nil, nil, nil, nil, slog.New(slog.DiscardHandler))
 
 	for _, path := range []string{"/healthz", "/readyz"} {
 		t.Run(path, func(t *testing.T) {
@@ -369,7 +380,7 @@ func TestNewAuditHTTPServer_RoutesHealthz(t *testing.T) {
 // endpoint requires a backend, which audit mode does not initialize;
 // surfacing /exists would either crash or return misleading 503s.
 func TestNewAuditHTTPServer_DoesNotMountLegacyExists(t *testing.T) {
-	srv := newAuditHTTPServer(testCfgPort(), emptyAuditStore(), slog.New(slog.DiscardHandler))
+	srv := newAuditHTTPServer(testCfgPort(), emptyAuditStore(), nil, nil, nil, nil, slog.New(slog.DiscardHandler))
 
 	// http.ServeMux returns 404 for any unmounted path. /exists/ is the
 	// legacy prefix; /exists/<ns>/<pvc> would route through it if
@@ -391,7 +402,7 @@ func TestNewAuditHTTPServer_DoesNotMountLegacyExists(t *testing.T) {
 // metricsAddr. Mounting a second /metrics here would risk Prometheus
 // scrape duplication.
 func TestNewAuditHTTPServer_DoesNotMountMetrics(t *testing.T) {
-	srv := newAuditHTTPServer(testCfgPort(), emptyAuditStore(), slog.New(slog.DiscardHandler))
+	srv := newAuditHTTPServer(testCfgPort(), emptyAuditStore(), nil, nil, nil, nil, slog.New(slog.DiscardHandler))
 
 	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/metrics", nil)
 	rr := httptest.NewRecorder()
@@ -405,7 +416,7 @@ func TestNewAuditHTTPServer_DoesNotMountMetrics(t *testing.T) {
 // mux. The handler-level test covers this directly; this is a sanity
 // check that the mux registration didn't accidentally restrict methods.
 func TestNewAuditHTTPServer_AuditEndpointRejectsPost(t *testing.T) {
-	srv := newAuditHTTPServer(testCfgPort(), emptyAuditStore(), slog.New(slog.DiscardHandler))
+	srv := newAuditHTTPServer(testCfgPort(), emptyAuditStore(), nil, nil, nil, nil, slog.New(slog.DiscardHandler))
 
 	req := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/audit", nil)
 	rr := httptest.NewRecorder()
@@ -423,7 +434,7 @@ func TestNewAuditHTTPServer_AuditEndpointRejectsPost(t *testing.T) {
 // which mode the pod is running in.
 func TestNewAuditHTTPServer_BindsCfgPort(t *testing.T) {
 	cfg := &config.Config{Port: "12345"}
-	srv := newAuditHTTPServer(cfg, emptyAuditStore(), slog.New(slog.DiscardHandler))
+	srv := newAuditHTTPServer(cfg, emptyAuditStore(), nil, nil, nil, nil, slog.New(slog.DiscardHandler))
 
 	if srv.Addr != ":12345" {
 		t.Errorf("audit server Addr: got %q, want :12345 (must follow cfg.Port)", srv.Addr)
@@ -449,7 +460,7 @@ func TestNewAuditHTTPServer_NoBackendNeeded(t *testing.T) {
 		Port: "8080",
 		// All other fields intentionally zero.
 	}
-	srv := newAuditHTTPServer(cfg, emptyAuditStore(), slog.New(slog.DiscardHandler))
+	srv := newAuditHTTPServer(cfg, emptyAuditStore(), nil, nil, nil, nil, slog.New(slog.DiscardHandler))
 
 	if srv == nil {
 		t.Fatal("newAuditHTTPServer returned nil with backend-free config")
@@ -511,7 +522,7 @@ func TestParseSystemNamespaces_DefaultsAreCanonicalNine(t *testing.T) {
 // Store is constructed from runtimeCfg.Mode=permissive (the wiring
 // main() does in Patch 6.7-wire).
 func TestNewV4HTTPServer_PermissiveReportsPermissiveOperatorMode(t *testing.T) {
-	srv := newAuditHTTPServer(testCfgPort(), emptyV4Store(mode.Permissive), slog.New(slog.DiscardHandler))
+	srv := newAuditHTTPServer(testCfgPort(), emptyV4Store(mode.Permissive), nil, nil, nil, nil, slog.New(slog.DiscardHandler))
 
 	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/audit", nil)
 	rr := httptest.NewRecorder()
@@ -543,7 +554,7 @@ func TestNewV4HTTPServer_PermissiveReportsPermissiveOperatorMode(t *testing.T) {
 // initialized so the legacy handler cannot work; mounting it would
 // surface 503s or panics depending on how the handler is constructed.
 func TestNewV4HTTPServer_PermissiveDoesNotMountLegacyExists(t *testing.T) {
-	srv := newAuditHTTPServer(testCfgPort(), emptyV4Store(mode.Permissive), slog.New(slog.DiscardHandler))
+	srv := newAuditHTTPServer(testCfgPort(), emptyV4Store(mode.Permissive), nil, nil, nil, nil, slog.New(slog.DiscardHandler))
 
 	for _, path := range []string{"/exists", "/exists/", "/exists/myapp/data"} {
 		t.Run(path, func(t *testing.T) {
@@ -561,7 +572,7 @@ func TestNewV4HTTPServer_PermissiveDoesNotMountLegacyExists(t *testing.T) {
 // is not double-mounted under permissive (controller-runtime exposes
 // its own /metrics on metricsAddr).
 func TestNewV4HTTPServer_PermissiveDoesNotMountMetrics(t *testing.T) {
-	srv := newAuditHTTPServer(testCfgPort(), emptyV4Store(mode.Permissive), slog.New(slog.DiscardHandler))
+	srv := newAuditHTTPServer(testCfgPort(), emptyV4Store(mode.Permissive), nil, nil, nil, nil, slog.New(slog.DiscardHandler))
 
 	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/metrics", nil)
 	rr := httptest.NewRecorder()
@@ -579,7 +590,7 @@ func TestNewV4HTTPServer_PermissiveDoesNotMountMetrics(t *testing.T) {
 // backend.
 func TestNewV4HTTPServer_PermissiveBackendIndependent(t *testing.T) {
 	cfg := &config.Config{Port: "8080"} // all backend fields zero
-	srv := newAuditHTTPServer(cfg, emptyV4Store(mode.Permissive), slog.New(slog.DiscardHandler))
+	srv := newAuditHTTPServer(cfg, emptyV4Store(mode.Permissive), nil, nil, nil, nil, slog.New(slog.DiscardHandler))
 
 	if srv == nil {
 		t.Fatal("newAuditHTTPServer returned nil with backend-free permissive config")
@@ -595,6 +606,250 @@ func TestNewV4HTTPServer_PermissiveBackendIndependent(t *testing.T) {
 	}
 }
 
+// /journal is mounted alongside /audit when main() passes a journal,
+// and absent when it does not.
+func TestNewAuditHTTPServer_MountsJournalWhenProvided(t *testing.T) {
+	j, err := buildJournal(runtimeconfig.Config{Mode: mode.Permissive}, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("buildJournal: %v", err)
+	}
+	srv := newAuditHTTPServer(testCfgPort(), emptyV4Store(mode.Permissive), j, nil, nil, nil, slog.New(slog.DiscardHandler))
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/journal", nil)
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("/journal status: got %d, want 200", rr.Code)
+	}
+
+	bare := newAuditHTTPServer(testCfgPort(), emptyV4Store(mode.Permissive), nil, nil, nil, nil, slog.New(slog.DiscardHandler))
+	rr = httptest.NewRecorder()
+	bare.Handler.ServeHTTP(rr, httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/journal", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("/journal without journal: got %d, want 404", rr.Code)
+	}
+}
+
+// stubInventory is a canned /audit/inventory reporter.
+type stubInventory struct{}
+
+func (stubInventory) Report(context.Context) (inventory.Report, error) {
+	return inventory.Build(nil, nil, time.Now()), nil
+}
+
+// /audit/inventory is mounted only when main() passes a reporter, and
+// the write timeout then covers its repository scan.
+func TestNewAuditHTTPServer_MountsInventoryWhenProvided(t *testing.T) {
+	srv := newAuditHTTPServer(testCfgPort(), emptyV4Store(mode.Permissive), nil, stubInventory{}, nil, nil, slog.New(slog.DiscardHandler))
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/audit/inventory", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("/audit/inventory status: got %d, want 200", rr.Code)
+	}
+	if srv.WriteTimeout <= handler.InventoryScanTimeout {
+		t.Errorf("WriteTimeout %v does not cover the %v scan", srv.WriteTimeout, handler.InventoryScanTimeout)
+	}
+
+	bare := newAuditHTTPServer(testCfgPort(), emptyV4Store(mode.Permissive), nil, nil, nil, nil, slog.New(slog.DiscardHandler))
+	rr = httptest.NewRecorder()
+	bare.Handler.ServeHTTP(rr, httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/audit/inventory", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("/audit/inventory without inventory: got %d, want 404", rr.Code)
+	}
+}
+
+// stubUsage is a usage source that has not produced a report yet.
+type stubUsage struct{}
+
+func (stubUsage) Status() inventory.UsageStatus { return inventory.UsageStatus{} }
+
+// /audit/usage and its metrics are mounted only when main() passes a
+// usage source; /metrics itself stays the manager's.
+func TestNewAuditHTTPServer_MountsUsageWhenProvided(t *testing.T) {
+	srv := newAuditHTTPServer(testCfgPort(), emptyV4Store(mode.Permissive), nil, nil, stubUsage{}, nil, slog.New(slog.DiscardHandler))
+	for path, want := range map[string]int{
+		"/audit/usage":         http.StatusServiceUnavailable,
+		"/audit/usage/metrics": http.StatusOK,
+		"/metrics":             http.StatusNotFound,
+	} {
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, httptest.NewRequestWithContext(t.Context(), http.MethodGet, path, nil))
+		if rr.Code != want {
+			t.Errorf("%s status: got %d, want %d", path, rr.Code, want)
+		}
+	}
+
+	bare := newAuditHTTPServer(testCfgPort(), emptyV4Store(mode.Permissive), nil, nil, nil, nil, slog.New(slog.DiscardHandler))
+	rr := httptest.NewRecorder()
+	bare.Handler.ServeHTTP(rr, httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/audit/usage", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("/audit/usage without usage: got %d, want 404", rr.Code)
+	}
+}
+
+// stubBackup is a backup trigger that must not be reached by an
+// unauthenticated request.
+type stubBackup struct{ called bool }
+
+func (s *stubBackup) Trigger(context.Context, string, string) (controller.BackupResult, error) {
+	s.called = true
+	return controller.BackupResult{Status: controller.BackupSucceeded}, nil
+}
+
+// POST /backup is mounted only when main() passes a backup API, always
+// behind the token, and the write timeout then covers the backup wait.
+func TestNewAuditHTTPServer_MountsBackupWhenProvided(t *testing.T) {
+	token := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(token, []byte("s3cret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	stub := &stubBackup{}
+	srv := newAuditHTTPServer(testCfgPort(), emptyV4Store(mode.Permissive), nil, nil, nil,
+		&backupAPI{trigger: stub, tokenFile: token, timeout: time.Hour}, slog.New(slog.DiscardHandler))
+
+	rr := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/backup/myapp/data", nil))
+	if rr.Code != http.StatusUnauthorized || stub.called {
+		t.Errorf("unauthenticated POST /backup: got %d (triggered=%v), want 401 untriggered", rr.Code, stub.called)
+	}
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/backup/myapp/data", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rr = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !stub.called {
+		t.Errorf("authenticated POST /backup: got %d (triggered=%v), want 200 triggered", rr.Code, stub.called)
+	}
+	if srv.WriteTimeout <= time.Hour {
+		t.Errorf("WriteTimeout %v does not cover the 1h backup wait", srv.WriteTimeout)
+	}
+
+	bare := newAuditHTTPServer(testCfgPort(), emptyV4Store(mode.Permissive), nil, nil, nil, nil, slog.New(slog.DiscardHandler))
+	rr = httptest.NewRecorder()
+	bare.Handler.ServeHTTP(rr, httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/backup/myapp/data", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("/backup without a token file: got %d, want 404", rr.Code)
+	}
+}
+
+// Without a token file the backup API touches no kube configuration.
+func TestBuildBackupAPI_DisabledIsNil(t *testing.T) {
+	b, err := buildBackupAPI(runtimeconfig.Config{Mode: mode.Permissive}, "", nil, slog.New(slog.DiscardHandler))
+	if b != nil || err != nil {
+		t.Errorf("buildBackupAPI(no token file) = %v, %v", b, err)
+	}
+}
+
+func TestBuildUsage_DisabledIsNil(t *testing.T) {
+	u, _, err := buildUsage(false, emptyV4Store(mode.Permissive), slog.New(slog.DiscardHandler))
+	if u != nil || err != nil {
+		t.Errorf("buildUsage(false) = %v, %v", u, err)
+	}
+}
+
+// A disabled inventory touches no kopia or kube configuration at all.
+func TestBuildInventory_DisabledIsNil(t *testing.T) {
+	inv, err := buildInventory(false, slog.New(slog.DiscardHandler))
+	if inv != nil || err != nil {
+		t.Errorf("buildInventory(false) = %v, %v", inv, err)
+	}
+}
+
+// The reconciler's kopia reader is wired only for kopia backends; an
+// unset or non-kopia BACKEND_TYPE leaves it nil (not a typed nil) and
+// reads no kopia configuration.
+func TestBuildKopiaReader(t *testing.T) {
+	for _, typ := range []string{"", backend.TypeS3} {
+		if l, err := buildKopiaReader(typ, slog.New(slog.DiscardHandler)); l != nil || err != nil {
+			t.Errorf("%q: got %v, %v", typ, l, err)
+		}
+	}
+
+	t.Setenv("BACKEND_TYPE", backend.TypeKopiaFS)
+	t.Setenv("KOPIA_FS_PATH", "../../internal/kopia/native/testdata/repo")
+	t.Setenv("KOPIA_PASSWORD", "pvc-plumber-fixture")
+	l, err := buildKopiaReader(backend.TypeKopiaFS, slog.New(slog.DiscardHandler))
+	if err != nil || l == nil {
+		t.Fatalf("kopia-fs: got %v, %v", l, err)
+	}
+	snaps, err := l.ListSnapshots(t.Context(), kopia.SnapshotSource{Host: "myapp", UserName: "cache-backup", Path: "/data"})
+	if err != nil || len(snaps) == 0 {
+		t.Errorf("fixture lineage cache-backup@myapp:/data: %d snapshots, %v", len(snaps), err)
+	}
+	// Freshness looks the same lineage up through the legacy fallback,
+	// from the index runManager refreshes off the reader.
+	index := &controller.BackupIndex{Lister: l}
+	if err := index.Refresh(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if res := index.CheckBackupExists(t.Context(), backend.NewQuery("myapp", "cache", "")); !res.Authoritative || !res.Exists || res.LatestSnapshotAt.IsZero() {
+		t.Errorf("CheckBackupExists(myapp/cache) = %+v", res)
+	}
+}
+
+// An unopenable journal file is fatal at startup rather than silently
+// degrading to no durable journal.
+func TestBuildJournal_UnwritableFileFails(t *testing.T) {
+	cfg := runtimeconfig.Config{
+		JournalSinks: []string{runtimeconfig.JournalSinkFile},
+		JournalFile:  t.TempDir() + "/missing-dir/journal.jsonl",
+	}
+	if _, err := buildJournal(cfg, slog.New(slog.DiscardHandler)); err == nil {
+		t.Error("buildJournal: want error for unopenable file, got nil")
+	}
+}
+
+// The hook runner is always built; it can exec and scale only when hooks
+// are on and the mode writes, and a lead that leaves no room for the
+// pre-hooks is a startup error.
+func TestNewHookRunner(t *testing.T) {
+	restCfg := &rest.Config{Host: "https://127.0.0.1:6443"}
+	store := emptyV4Store(mode.Permissive)
+
+	r, err := newHookRunner(nil, nil, restCfg, store, nil, runtimeconfig.Config{Mode: mode.Permissive}, nil)
+	if err != nil {
+		t.Fatalf("hooks off: %v", err)
+	}
+	if r.Enabled || r.Exec != nil {
+		t.Errorf("hooks off: enabled=%t exec=%v", r.Enabled, r.Exec)
+	}
+	if r.Timeout != controller.DefaultHookTimeout || r.Lead != controller.DefaultHookLead || r.MaxHold != controller.DefaultHookMaxHold {
+		t.Errorf("defaults: timeout=%s lead=%s max_hold=%s", r.Timeout, r.Lead, r.MaxHold)
+	}
+
+	r, err = newHookRunner(nil, nil, restCfg, store, nil, runtimeconfig.Config{Mode: mode.Audit, BackupHooks: true}, nil)
+	if err != nil || r.Enabled || r.Exec != nil {
+		t.Errorf("audit mode: enabled=%t exec=%v err=%v", r.Enabled, r.Exec, err)
+	}
+
+	r, err = newHookRunner(nil, nil, restCfg, store, nil, runtimeconfig.Config{Mode: mode.Permissive, BackupHooks: true}, nil)
+	if err != nil || !r.Enabled || r.Exec == nil {
+		t.Errorf("permissive: enabled=%t exec=%v err=%v", r.Enabled, r.Exec, err)
+	}
+
+	_, err = newHookRunner(nil, nil, restCfg, store, nil, runtimeconfig.Config{
+		Mode: mode.Permissive, BackupHooks: true, BackupHookTimeout: 5 * time.Minute,
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), runtimeconfig.EnvBackupHookLead) {
+		t.Errorf("timeout above the default lead: want an error naming %s, got %v", runtimeconfig.EnvBackupHookLead, err)
+	}
+
+	// Shorter than the lead, but not by the margin the pre-hooks must
+	// leave before the sync.
+	_, err = newHookRunner(nil, nil, restCfg, store, nil, runtimeconfig.Config{
+		Mode: mode.Permissive, BackupHooks: true, BackupHookTimeout: controller.DefaultHookLead - controller.HookLeadMargin,
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), runtimeconfig.EnvBackupHookLead) {
+		t.Errorf("timeout inside the lead margin: want an error naming %s, got %v", runtimeconfig.EnvBackupHookLead, err)
+	}
+
+	// With hooks off the durations are unused and do not stop startup.
+	r, err = newHookRunner(nil, nil, restCfg, store, nil, runtimeconfig.Config{
+		Mode: mode.Permissive, BackupHookTimeout: 5 * time.Minute,
+	}, nil)
+	if err != nil || r.Enabled {
+		t.Errorf("hooks off, timeout above the lead: enabled=%t err=%v", r.Enabled, err)
+	}
+}
+
 // =============================================================================
 // Patch 6.8a: V4 builder defaults flow from runtimeconfig into the reconciler
 // =============================================================================
@@ -737,6 +992,79 @@ func TestNewV4Reconciler_PartialDefaults_AuditAllowed(t *testing.T) {
 	}
 }
 
+// TestNewOrphanReaper_MapsConfig pins the env → reaper mapping, including
+// the zero-duration → package-default resolution and report-only default.
+func TestNewOrphanReaper_MapsConfig(t *testing.T) {
+	store := emptyV4Store(mode.Permissive)
+	r := newOrphanReaper(nil, nil, store, nil, runtimeconfig.Config{Mode: mode.Permissive}, nil)
+	if r.ReapReplicationSources {
+		t.Error("reaping must default to off")
+	}
+	if r.Grace != controller.DefaultOrphanGrace || r.Interval != controller.DefaultOrphanScanInterval {
+		t.Errorf("defaults: grace=%v interval=%v", r.Grace, r.Interval)
+	}
+	if r.Store != store || r.Mode != mode.Permissive {
+		t.Error("store/mode not passed through")
+	}
+
+	apiReader := fake.NewClientBuilder().Build()
+	r = newOrphanReaper(nil, apiReader, store, nil, runtimeconfig.Config{
+		Mode: mode.Permissive, OrphanReap: true, OrphanGrace: 72 * time.Hour, OrphanScanInterval: time.Minute,
+	}, nil)
+	if !r.ReapReplicationSources || r.Grace != 72*time.Hour || r.Interval != time.Minute {
+		t.Errorf("explicit config: %+v", r)
+	}
+	if r.Reader != apiReader {
+		t.Error("API reader not passed through; the pre-reap PVC read would hit the cache")
+	}
+}
+
+func TestNewCloneRunner_MapsConfig(t *testing.T) {
+	store := emptyV4Store(mode.Permissive)
+	r := newCloneRunner(nil, store, nil, runtimeconfig.Config{Mode: mode.Permissive}, nil)
+	if r.DefaultTTL != controller.DefaultCloneTTL || r.Interval != controller.DefaultCloneScanInterval {
+		t.Errorf("defaults: ttl=%v interval=%v", r.DefaultTTL, r.Interval)
+	}
+	if r.Store != store || r.Mode != mode.Permissive {
+		t.Error("store/mode not passed through")
+	}
+
+	r = newCloneRunner(nil, store, nil, runtimeconfig.Config{
+		Mode: mode.Audit, CloneDefaultTTL: 72 * time.Hour, CloneScanInterval: 30 * time.Second,
+	}, nil)
+	if r.DefaultTTL != 72*time.Hour || r.Interval != 30*time.Second || r.Mode != mode.Audit {
+		t.Errorf("explicit config: %+v", r)
+	}
+}
+
+func TestNewDrillRunner(t *testing.T) {
+	store := controller.NewStore("permissive", "bare-dst", "")
+	sysNs := map[string]struct{}{"kube-system": {}}
+
+	for name, cfg := range map[string]runtimeconfig.Config{
+		"no sandbox": {Mode: mode.Permissive},
+		"audit mode": {Mode: mode.Audit, DrillNamespace: "drill"},
+	} {
+		if r, err := newDrillRunner(nil, store, sysNs, cfg, nil); r != nil || err != nil {
+			t.Errorf("%s: got %v, %v; want nil, nil", name, r, err)
+		}
+	}
+	if _, err := newDrillRunner(nil, store, sysNs, runtimeconfig.Config{Mode: mode.Permissive, DrillNamespace: "kube-system"}, nil); err == nil {
+		t.Error("system namespace accepted as drill sandbox")
+	}
+
+	r, err := newDrillRunner(nil, store, sysNs, runtimeconfig.Config{Mode: mode.Permissive, DrillNamespace: "drill"}, nil)
+	if err != nil || r == nil {
+		t.Fatalf("enabled: %v, %v", r, err)
+	}
+	if r.Interval != controller.DefaultDrillInterval || r.Timeout != controller.DefaultDrillTimeout || r.Image != controller.DefaultDrillImage {
+		t.Errorf("defaults: interval=%v timeout=%v image=%q", r.Interval, r.Timeout, r.Image)
+	}
+	if r.SandboxNamespace != "drill" || r.Store != store || r.Mode != mode.Permissive {
+		t.Errorf("not passed through: %+v", r)
+	}
+}
+
 // =============================================================================
 // Patch 6.8a: RequireV4WriteDefaults integration smoke test
 // =============================================================================
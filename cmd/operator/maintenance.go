@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+	"github.com/mitchross/pvc-plumber/internal/config"
+	"github.com/mitchross/pvc-plumber/internal/handler"
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+)
+
+// volsyncCreatedByLabel is the label VolSync stamps on every mover Job
+// it creates, whichever mover (kopia, restic, rsync) it runs.
+const volsyncCreatedByLabel = "app.kubernetes.io/created-by"
+
+// Routes the maintenance status is served on, on the manager's metrics
+// server (metricsAddr).
+const (
+	maintenanceStatusPath  = "/kopia/maintenance"
+	maintenanceMetricsPath = "/kopia/maintenance/metrics"
+)
+
+// volsyncMovers counts running VolSync mover Jobs cluster-wide
+// (kopia.MoverActivity). Every mover in the cluster counts, not only
+// the kopia ones writing this repository: an rsync mover does not touch
+// it, but telling them apart means reading each Job's owner, and
+// deferring maintenance a little longer costs nothing.
+type volsyncMovers struct {
+	reader client.Reader
+}
+
+// ActiveMovers implements kopia.MoverActivity.
+func (v volsyncMovers) ActiveMovers(ctx context.Context) (int, error) {
+	var jobs batchv1.JobList
+	if err := v.reader.List(ctx, &jobs, client.MatchingLabels{volsyncCreatedByLabel: "volsync"}); err != nil {
+		return 0, fmt.Errorf("list mover jobs: %w", err)
+	}
+	active := 0
+	for i := range jobs.Items {
+		if jobs.Items[i].Status.Active > 0 {
+			active++
+		}
+	}
+	return active, nil
+}
+
+// buildMaintainer returns the kopia maintenance loop, or nil when the
+// v4-mode BACKEND_TYPE (read verbatim, unset by default) names no kopia
+// backend or no maintenance cadence is set. Like buildKopiaReader it
+// loads the kopia settings with full validation, which already refused
+// a cadence on anything but the CLI reader.
+//
+// The loop gets a CLI client of its own, connected in
+// KOPIA_MAINTENANCE_CONFIG_FILE under the maintenance owner, so claiming
+// maintenance never changes the identity another client in the process
+// queries under.
+func buildMaintainer(backendType string, movers kopia.MoverActivity, logger *slog.Logger) (*kopia.Maintainer, error) {
+	if backendType != backend.TypeKopiaS3 && backendType != backend.TypeKopiaFS {
+		return nil, nil
+	}
+	kcfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("kopia maintenance settings: %w", err)
+	}
+	if !kcfg.KopiaMaintenanceEnabled() {
+		return nil, nil
+	}
+	opts := kopia.Options{ConnectTimeout: kcfg.KopiaConnectTimeout, ConfigFile: kcfg.KopiaMaintenanceConfigFile}
+	var cli *kopia.Client
+	if kcfg.BackendType == backend.TypeKopiaFS {
+		cli = kopia.NewFilesystemClient(kopia.FilesystemConfig{Path: kcfg.KopiaFSPath},
+			kopia.NewPasswordCredentialsSource(kcfg.KopiaPassword), logger, opts)
+	} else {
+		var creds kopia.CredentialsSource
+		if kcfg.KopiaCredentialsPath != "" {
+			creds = kopia.NewDirCredentialsSource(kcfg.KopiaCredentialsPath)
+		} else {
+			creds = kopia.NewStaticCredentialsSource(kcfg.KopiaPassword, kcfg.KopiaS3AccessKey, kcfg.KopiaS3SecretKey)
+		}
+		cli = kopia.NewClient(kopia.S3Config{
+			Endpoint:   kcfg.KopiaS3Endpoint,
+			Bucket:     kcfg.KopiaS3Bucket,
+			DisableTLS: kcfg.KopiaS3DisableTLS,
+		}, creds, logger, opts)
+	}
+	return kopia.NewMaintainer(cli, movers, kopia.MaintenanceOptions{
+		QuickInterval: kcfg.KopiaMaintenanceQuickInterval,
+		FullInterval:  kcfg.KopiaMaintenanceFullInterval,
+		Owner:         kcfg.KopiaMaintenanceOwner,
+		RunTimeout:    kcfg.KopiaMaintenanceRunTimeout,
+	}, logger), nil
+}
+
+// setupMaintenance registers the kopia maintenance loop with mgr, as a
+// leader-elected runnable, and serves its status on the metrics server
+// at maintenanceStatusPath and maintenanceMetricsPath. It returns the
+// registered Maintainer, or nil when buildMaintainer built none or the
+// mode does not allow writes — maintenance deletes repository blobs,
+// which audit mode must not. The mover check reads Jobs through the
+// manager's uncached API reader, so the manager needs no Job informer
+// for it.
+func setupMaintenance(mgr manager.Manager, backendType string, writesAllowed, leaderElection bool, logger *slog.Logger) (*kopia.Maintainer, error) {
+	m, err := buildMaintainer(backendType, volsyncMovers{reader: mgr.GetAPIReader()}, logger)
+	if err != nil || m == nil {
+		return nil, err
+	}
+	if !writesAllowed {
+		logger.Info("kopia maintenance NOT registered: maintenance deletes repository blobs, and this mode does not allow writes")
+		return nil, nil
+	}
+	if !leaderElection {
+		logger.Warn("kopia maintenance without leader election: every replica will run it; keep a single replica")
+	}
+	if err := mgr.Add(m); err != nil {
+		return nil, fmt.Errorf("add kopia maintainer: %w", err)
+	}
+	if err := mgr.AddMetricsServerExtraHandler(maintenanceStatusPath, handler.NewMaintenanceHandler(m, logger)); err != nil {
+		return nil, fmt.Errorf("serve kopia maintenance status: %w", err)
+	}
+	if err := mgr.AddMetricsServerExtraHandler(maintenanceMetricsPath, handler.NewMaintenanceMetricsHandler(m)); err != nil {
+		return nil, fmt.Errorf("serve kopia maintenance metrics: %w", err)
+	}
+	return m, nil
+}
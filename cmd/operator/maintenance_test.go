@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+)
+
+func moverJob(name string, labelled bool, active int32) *batchv1.Job {
+	j := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "media"},
+		Status:     batchv1.JobStatus{Active: active},
+	}
+	if labelled {
+		j.Labels = map[string]string{volsyncCreatedByLabel: "volsync"}
+	}
+	return j
+}
+
+// Only running Jobs VolSync created count; a finished mover or an
+// unrelated running Job does not hold maintenance back.
+func TestVolsyncMovers_CountsActiveVolsyncJobs(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		moverJob("volsync-src-immich", true, 1),
+		moverJob("volsync-src-karakeep", true, 0),
+		moverJob("nightly-report", false, 1),
+	).Build()
+	n, err := volsyncMovers{reader: c}.ActiveMovers(context.Background())
+	if err != nil || n != 1 {
+		t.Errorf("ActiveMovers = %d, %v; want 1", n, err)
+	}
+}
+
+// recordingManager is the part of manager.Manager setupMaintenance
+// touches: the API reader, and the runnables and metrics-server handlers
+// it registers.
+type recordingManager struct {
+	manager.Manager
+	added    []manager.Runnable
+	handlers map[string]http.Handler
+}
+
+func (m *recordingManager) GetAPIReader() client.Reader {
+	return fake.NewClientBuilder().WithScheme(scheme).Build()
+}
+
+func (m *recordingManager) Add(r manager.Runnable) error {
+	m.added = append(m.added, r)
+	return nil
+}
+
+func (m *recordingManager) AddMetricsServerExtraHandler(path string, h http.Handler) error {
+	if m.handlers == nil {
+		m.handlers = map[string]http.Handler{}
+	}
+	m.handlers[path] = h
+	return nil
+}
+
+// maintenanceEnv is a kopia-fs configuration with a quick cadence.
+func maintenanceEnv(t *testing.T) {
+	t.Setenv("BACKEND_TYPE", backend.TypeKopiaFS)
+	t.Setenv("KOPIA_FS_PATH", t.TempDir())
+	t.Setenv("KOPIA_PASSWORD", "pvc-plumber-fixture")
+	t.Setenv("KOPIA_MAINTENANCE_QUICK_INTERVAL", "1h")
+}
+
+// No kopia backend, or no cadence, builds no loop and reads no
+// maintenance settings.
+func TestBuildMaintainer_DisabledIsNil(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	for _, typ := range []string{"", backend.TypeS3} {
+		if m, err := buildMaintainer(typ, nil, logger); m != nil || err != nil {
+			t.Errorf("%q: got %v, %v", typ, m, err)
+		}
+	}
+	maintenanceEnv(t)
+	t.Setenv("KOPIA_MAINTENANCE_QUICK_INTERVAL", "")
+	if m, err := buildMaintainer(backend.TypeKopiaFS, nil, logger); m != nil || err != nil {
+		t.Errorf("no cadence: got %v, %v", m, err)
+	}
+}
+
+// A cadence on a reader that cannot run maintenance is a startup error,
+// not a silently idle loop.
+func TestBuildMaintainer_NeedsCLIReader(t *testing.T) {
+	maintenanceEnv(t)
+	t.Setenv("KOPIA_READER", "native")
+	if _, err := buildMaintainer(backend.TypeKopiaFS, nil, slog.New(slog.DiscardHandler)); err == nil {
+		t.Error("buildMaintainer on the native reader succeeded")
+	}
+}
+
+// runManager's maintenance wiring: with a cadence set the loop is a
+// manager runnable and its status is served on the metrics server. A
+// Maintainer nothing registers never runs.
+func TestSetupMaintenance_RegistersRunnable(t *testing.T) {
+	maintenanceEnv(t)
+	logger := slog.New(slog.DiscardHandler)
+
+	mgr := &recordingManager{}
+	m, err := setupMaintenance(mgr, backend.TypeKopiaFS, true, true, logger)
+	if err != nil || m == nil {
+		t.Fatalf("setupMaintenance: %v, %v", m, err)
+	}
+	if len(mgr.added) != 1 || mgr.added[0] != manager.Runnable(m) {
+		t.Errorf("runnables: %v", mgr.added)
+	}
+	if mgr.handlers[maintenanceStatusPath] == nil || mgr.handlers[maintenanceMetricsPath] == nil {
+		t.Errorf("metrics server handlers: %v", mgr.handlers)
+	}
+
+	// Audit mode registers nothing: maintenance deletes blobs.
+	mgr = &recordingManager{}
+	if m, err := setupMaintenance(mgr, backend.TypeKopiaFS, false, false, logger); m != nil || err != nil ||
+		len(mgr.added) != 0 || len(mgr.handlers) != 0 {
+		t.Errorf("audit: got %v, %v; runnables %v", m, err, mgr.added)
+	}
+}
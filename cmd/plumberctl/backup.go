@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/controller"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+	"github.com/mitchross/pvc-plumber/internal/v4/mode"
+	"github.com/mitchross/pvc-plumber/internal/v4/runtimeconfig"
+)
+
+// runBackup handles `pvc-plumber-ctl backup ...`: the CLI counterpart of
+// the operator's POST /backup/{namespace}/{pvc}. It runs the same
+// controller.OnDemandBackup with the caller's kubeconfig, so the same
+// executor ownership check and the same hold annotation apply — a
+// backup started here and one started over HTTP refuse each other. The
+// RS trigger updates are journaled to --configmap when given.
+//
+// Exit codes: 0 succeeded; 2 refused before the trigger was switched,
+// or superseded; 4 failed, timed out, or an apiserver error.
+func runBackup(rt *cliRuntime, args []string) int {
+	fs := flag.NewFlagSet(cmdBackup, flag.ContinueOnError)
+	fs.SetOutput(rt.stderr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(rt.stderr, "Usage: pvc-plumber-ctl backup --namespace <ns> --pvc <name> [--timeout 30m] [--configmap <ns>/<name>] [--snapshot] [--output table|json]")
+		fs.PrintDefaults()
+	}
+
+	var namespace, pvc, configMap, output string
+	var timeout time.Duration
+	var lookup bool
+	fs.StringVar(&namespace, "namespace", "", "PVC namespace (required)")
+	fs.StringVar(&pvc, "pvc", "", "PVC name (required)")
+	fs.DurationVar(&timeout, "timeout", controller.DefaultBackupTimeout, "how long to wait for the sync")
+	fs.StringVar(&configMap, "configmap", "", "journal the RS trigger updates to the operator's configmap sink (namespace/name)")
+	fs.BoolVar(&lookup, "snapshot", false, "look up the new kopia snapshot; kopia settings come from BACKEND_TYPE and KOPIA_* as in inventory")
+	fs.StringVar(&output, "output", outTable, "output format: table|json")
+	fs.StringVar(&kubeconfigPath, "kubeconfig", "", "path to kubeconfig")
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	var err error
+	switch {
+	case namespace == "":
+		err = &usageError{msg: "missing required flag --namespace"}
+	case pvc == "":
+		err = &usageError{msg: "missing required flag --pvc"}
+	case timeout <= 0:
+		err = &usageError{msg: fmt.Sprintf("invalid --timeout %s (must be positive)", timeout)}
+	case configMap != "":
+		if _, _, ok := runtimeconfig.SplitNamespacedName(configMap); !ok {
+			err = &usageError{msg: fmt.Sprintf("invalid --configmap %q (want namespace/name)", configMap)}
+		}
+	}
+	if err == nil {
+		err = validateOutput(output)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		return exitCodeFor(err)
+	}
+
+	c, err := rt.newClient()
+	if err != nil {
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		return exitCodeFor(err)
+	}
+	b := &controller.OnDemandBackup{Client: c, Mode: mode.Permissive, Timeout: timeout}
+	if configMap != "" {
+		ns, name, _ := runtimeconfig.SplitNamespacedName(configMap)
+		b.Journal = journal.New(nil, journal.NewConfigMapSink(c, ns, name, 0))
+	}
+	if lookup {
+		l, err := rt.newLister()
+		if err != nil {
+			_, _ = fmt.Fprintln(rt.stderr, err)
+			return exitCodeFor(err)
+		}
+		snapshots, ok := l.(controller.LineageLister)
+		if !ok {
+			_, _ = fmt.Fprintln(rt.stderr, "the configured kopia reader cannot list one lineage; drop --snapshot")
+			return exitUsage
+		}
+		b.Snapshots = snapshots
+	}
+
+	_, _ = fmt.Fprintf(rt.stderr, "triggering a backup of %s/%s (waiting up to %s)\n", namespace, pvc, timeout)
+	res, err := b.Trigger(context.Background(), namespace, pvc)
+	if err != nil {
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		if errors.Is(err, controller.ErrBackupRefused) || errors.Is(err, controller.ErrBackupNotFound) {
+			return exitRefused
+		}
+		return exitInfra
+	}
+
+	code := exitInfra
+	switch res.Status {
+	case controller.BackupSucceeded:
+		code = exitSuccess
+	case controller.BackupSuperseded:
+		code = exitRefused
+	}
+	if output == outJSON {
+		if rc := encodeJSON(rt, res); rc != exitSuccess {
+			return rc
+		}
+		return code
+	}
+	renderBackup(rt, res)
+	return code
+}
+
+func renderBackup(rt *cliRuntime, res controller.BackupResult) {
+	tw := tabwriter.NewWriter(rt.stdout, 0, 0, 2, ' ', 0)
+	row := func(k, v string) { _, _ = fmt.Fprintf(tw, "%s\t%s\n", k, v) }
+	row("PVC", res.Namespace+"/"+res.PVC)
+	row("STATUS", string(res.Status))
+	row("TRIGGER", res.Trigger)
+	row("REQUESTED", res.RequestedAt.Format(time.RFC3339))
+	row("FINISHED", res.FinishedAt.Format(time.RFC3339))
+	if res.Schedule != "" {
+		row("SCHEDULE", fmt.Sprintf("%s (restored: %t)", res.Schedule, res.ScheduleRestored))
+	}
+	row("SOURCE", res.Source)
+	if res.Mover.MoverResult != "" {
+		row("MOVER", res.Mover.MoverResult)
+	}
+	if res.Snapshot != nil {
+		row("SNAPSHOT", fmt.Sprintf("%s (%s, %d bytes, %d files)",
+			res.Snapshot.ID, res.Snapshot.StartTime.Format(time.RFC3339), res.Snapshot.TotalSize, res.Snapshot.FileCount))
+	}
+	if res.Detail != "" {
+		row("DETAIL", res.Detail)
+	}
+	_ = tw.Flush()
+}
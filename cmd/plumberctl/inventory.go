@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/config"
+	"github.com/mitchross/pvc-plumber/internal/inventory"
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+)
+
+// inventoryTimeout bounds the PVC listing plus the repository scan. It
+// is longer than the operator endpoint's handler.InventoryScanTimeout:
+// the CLI usually runs from a workstation, further from the bucket.
+const inventoryTimeout = 2 * time.Minute
+
+// newInventoryLister is the production cliRuntime.newLister. The kopia
+// settings come from the same environment variables the operator reads
+// (BACKEND_TYPE, KOPIA_*), so `kubectl exec` into the operator pod, or
+// exporting the values from its Secret, is all the setup needed.
+func newInventoryLister() (kopia.SnapshotLister, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, &infraError{err: fmt.Errorf("kopia settings: %w", err)}
+	}
+	l, err := inventory.NewReader(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		return nil, &infraError{err: err}
+	}
+	return l, nil
+}
+
+// runInventory handles `pvc-plumber-ctl inventory ...`. Read-only: it
+// lists PVCs and scans the repository, and never deletes a lineage.
+func runInventory(rt *cliRuntime, args []string) int {
+	fs := flag.NewFlagSet(cmdInventory, flag.ContinueOnError)
+	fs.SetOutput(rt.stderr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(rt.stderr, "Usage: pvc-plumber-ctl inventory [--output table|json]")
+		_, _ = fmt.Fprintln(rt.stderr, "Kopia settings are read from BACKEND_TYPE and KOPIA_* as in the operator.")
+		fs.PrintDefaults()
+	}
+	var output string
+	fs.StringVar(&output, "output", outTable, "output format: table|json")
+	fs.StringVar(&kubeconfigPath, "kubeconfig", "", "path to kubeconfig")
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if err := validateOutput(output); err != nil {
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		return exitCodeFor(err)
+	}
+
+	c, err := rt.newClient()
+	if err != nil {
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		return exitCodeFor(err)
+	}
+	lineages, err := rt.newLister()
+	if err != nil {
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		return exitCodeFor(err)
+	}
+	a := &inventory.Auditor{
+		PVCs:     inventory.KubePVCs{Reader: c},
+		Lineages: lineages,
+		Now:      func() time.Time { return rt.now },
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), inventoryTimeout)
+	defer cancel()
+	report, err := a.Report(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		return exitInfra
+	}
+
+	if output == outJSON {
+		return encodeJSON(rt, report)
+	}
+	renderInventory(rt.stdout, report)
+	return exitSuccess
+}
+
+// renderInventory prints the three lists as tables under a one-line
+// summary. Empty sections keep their heading so "none" is explicit.
+func renderInventory(w io.Writer, r inventory.Report) {
+	s := r.Summary
+	_, _ = fmt.Fprintf(w, "%d lineages, %d PVCs (%d opted in); %d orphaned (%d bytes at latest snapshot), %d PVCs without a lineage, %d suspected renames\n",
+		s.Lineages, s.PVCs, s.OptedInPVCs, s.OrphanedLineages, s.OrphanedBytes, s.PVCsWithoutLineage, s.SuspectedRenames)
+
+	_, _ = fmt.Fprintf(w, "\nORPHANED LINEAGES (%d)\n", len(r.OrphanedLineages))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "SOURCE\tSNAPSHOTS\tLATEST\tSIZE\tFORMER PVC")
+	for _, o := range r.OrphanedLineages {
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\t%d\t%s\n",
+			o.Source, o.SnapshotCount, formatLatest(o.LatestSnapshotAt), o.LatestSnapshotSize, joinOrDash(o.FormerPVCs))
+	}
+	_ = tw.Flush()
+
+	_, _ = fmt.Fprintf(w, "\nPVCS WITHOUT LINEAGE (%d)\n", len(r.PVCsWithoutLineage))
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAMESPACE/PVC\tIDENTITY\tEXPECTED SOURCES")
+	for _, p := range r.PVCsWithoutLineage {
+		_, _ = fmt.Fprintf(tw, "%s/%s\t%s\t%s\n", p.Namespace, p.PVC, p.Identity, joinOrDash(p.ExpectedSources))
+	}
+	_ = tw.Flush()
+
+	_, _ = fmt.Fprintf(w, "\nSUSPECTED RENAMES (%d)\n", len(r.SuspectedRenames))
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "SOURCE\tCONVENTION\tNAMESPACE/PVC\tIDENTITY\tSNAPSHOTS\tLATEST")
+	for _, rn := range r.SuspectedRenames {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s/%s\t%s\t%d\t%s\n",
+			rn.Source, rn.MatchedConvention, rn.Namespace, rn.PVC, rn.Identity, rn.SnapshotCount, formatLatest(rn.LatestSnapshotAt))
+	}
+	_ = tw.Flush()
+}
+
+// formatLatest renders a lineage's newest complete snapshot, or "-" for
+// a lineage that only ever checkpointed.
+func formatLatest(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func joinOrDash(items []string) string {
+	if len(items) == 0 {
+		return "-"
+	}
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/executor"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+	"github.com/mitchross/pvc-plumber/internal/v4/mode"
+	"github.com/mitchross/pvc-plumber/internal/v4/runtimeconfig"
+)
+
+// journalSource is the shared --configmap / --file flag pair.
+type journalSource struct {
+	configMap string
+	file      string
+	maxFiles  int
+}
+
+func (s *journalSource) bind(fs *flag.FlagSet) {
+	fs.StringVar(&s.configMap, "configmap", "", "journal ConfigMap as namespace/name (the operator's configmap sink)")
+	fs.StringVar(&s.file, "file", "", "journal file path (a copy of the operator's file sink)")
+	fs.IntVar(&s.maxFiles, "max-files", journal.DefaultFileMaxFiles, "rotated files to read alongside --file")
+}
+
+func (s *journalSource) validate() error {
+	switch {
+	case s.configMap == "" && s.file == "":
+		return &usageError{msg: "one of --configmap or --file is required"}
+	case s.configMap != "" && s.file != "":
+		return &usageError{msg: "--configmap and --file are mutually exclusive"}
+	case s.configMap != "":
+		if _, _, ok := runtimeconfig.SplitNamespacedName(s.configMap); !ok {
+			return &usageError{msg: fmt.Sprintf("invalid --configmap %q (want namespace/name)", s.configMap)}
+		}
+	}
+	return nil
+}
+
+// open returns the journal reader and, for the ConfigMap source, the
+// sink replays should be journaled to. A file copied out of the pod is
+// a snapshot; appending to it would not reach the operator, so the
+// file source returns a nil sink.
+func (s *journalSource) open(rt *cliRuntime) (journal.Reader, journal.Sink, error) {
+	if s.file != "" {
+		return fileReader{path: s.file, maxFiles: s.maxFiles}, nil, nil
+	}
+	c, err := rt.newClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	ns, name, _ := runtimeconfig.SplitNamespacedName(s.configMap)
+	cm := journal.NewConfigMapSink(c, ns, name, 0)
+	return cm, cm, nil
+}
+
+type fileReader struct {
+	path     string
+	maxFiles int
+}
+
+func (f fileReader) Read(context.Context) ([]journal.Entry, error) {
+	return journal.ReadFiles(f.path, f.maxFiles)
+}
+
+// runJournal handles `pvc-plumber-ctl journal ...`. Read-only.
+func runJournal(rt *cliRuntime, args []string) int {
+	fs := flag.NewFlagSet(cmdJournal, flag.ContinueOnError)
+	fs.SetOutput(rt.stderr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(rt.stderr, "Usage: pvc-plumber-ctl journal (--configmap <ns>/<name> | --file <path>) [filters] [--output table|json]")
+		fs.PrintDefaults()
+	}
+
+	var src journalSource
+	var f journal.Filter
+	var since, output string
+	src.bind(fs)
+	fs.StringVar(&f.Namespace, "namespace", "", "filter: target namespace")
+	fs.StringVar(&f.Name, "name", "", "filter: target name")
+	fs.StringVar(&f.Kind, "kind", "", "filter: ReplicationSource | ReplicationDestination")
+	fs.StringVar(&f.Op, "op", "", "filter: create | update | delete")
+	fs.StringVar(&f.Status, "status", "", "filter: succeeded | refused | failed")
+	fs.StringVar(&since, "since", "", "filter: RFC3339 timestamp or Go duration (e.g. 168h)")
+	fs.IntVar(&f.Limit, "limit", 50, "maximum entries, newest first (0 = all)")
+	fs.StringVar(&output, "output", outTable, "output format: table|json")
+	fs.StringVar(&kubeconfigPath, "kubeconfig", "", "path to kubeconfig")
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	err := src.validate()
+	if err == nil {
+		err = validateOutput(output)
+	}
+	if err == nil && since != "" {
+		f.Since, err = parseSince(since, rt.now)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		return exitCodeFor(err)
+	}
+
+	reader, _, err := src.open(rt)
+	if err != nil {
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		return exitCodeFor(err)
+	}
+	all, err := reader.Read(context.Background())
+	if err != nil {
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		return exitInfra
+	}
+	entries := journal.Select(all, f)
+
+	if output == outJSON {
+		if entries == nil {
+			entries = []journal.Entry{}
+		}
+		return encodeJSON(rt, entries)
+	}
+	renderEntries(rt.stdout, entries)
+	return exitSuccess
+}
+
+// runReplay handles `pvc-plumber-ctl replay ...`.
+func runReplay(rt *cliRuntime, args []string) int {
+	fs := flag.NewFlagSet(cmdReplay, flag.ContinueOnError)
+	fs.SetOutput(rt.stderr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(rt.stderr, "Usage: pvc-plumber-ctl replay (--configmap <ns>/<name> | --file <path>) --kind <Kind> --namespace <ns> --name <name> [--confirm]")
+		fs.PrintDefaults()
+	}
+
+	var src journalSource
+	var target journal.Target
+	var confirm bool
+	src.bind(fs)
+	fs.StringVar(&target.Kind, "kind", "", "ReplicationSource | ReplicationDestination (required)")
+	fs.StringVar(&target.Namespace, "namespace", "", "target namespace (required)")
+	fs.StringVar(&target.Name, "name", "", "target name (required)")
+	fs.BoolVar(&confirm, "confirm", false, "create the object; without it the reconstructed body is printed and nothing is written")
+	fs.StringVar(&kubeconfigPath, "kubeconfig", "", "path to kubeconfig")
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	err := src.validate()
+	if err == nil {
+		err = validateTarget(target)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		return exitCodeFor(err)
+	}
+
+	reader, sink, err := src.open(rt)
+	if err != nil {
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		return exitCodeFor(err)
+	}
+	ctx := context.Background()
+	entries, err := reader.Read(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		return exitInfra
+	}
+
+	obj, from, err := journal.Reconstruct(entries, target)
+	if err != nil {
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		return exitCodeFor(err)
+	}
+	_, _ = fmt.Fprintf(rt.stderr, "reconstructed from journal entry %s %s (spec %s)\n",
+		from.Time.Format(time.RFC3339), from.Op, from.SpecHash)
+
+	if !confirm {
+		_, _ = fmt.Fprintln(rt.stderr, "dry run: re-run with --confirm to create")
+		return encodeJSON(rt, obj.Object)
+	}
+
+	c, err := rt.newClient()
+	if err != nil {
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		return exitCodeFor(err)
+	}
+	var j *journal.Journal
+	if sink != nil {
+		j = journal.New(nil, sink)
+	}
+	out, err := journal.Replay(ctx, c, mode.Permissive, j, entries, target)
+	if err != nil {
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		return exitCodeFor(err)
+	}
+	_, _ = fmt.Fprintf(rt.stdout, "%s %s/%s: %s %s\n", out.GVK, out.Namespace, out.Name, out.Status, out.Reason)
+	switch out.Status {
+	case executor.OpSucceeded:
+		return exitSuccess
+	case executor.OpRefused:
+		return exitRefused
+	default:
+		if out.Err != nil {
+			_, _ = fmt.Fprintln(rt.stderr, out.Err)
+		}
+		return exitInfra
+	}
+}
+
+func validateTarget(t journal.Target) error {
+	switch {
+	case t.Kind == "":
+		return &usageError{msg: "missing required flag --kind"}
+	case t.Kind != "ReplicationSource" && t.Kind != "ReplicationDestination":
+		return &usageError{msg: fmt.Sprintf("invalid --kind %q (want ReplicationSource|ReplicationDestination)", t.Kind)}
+	case t.Namespace == "":
+		return &usageError{msg: "missing required flag --namespace"}
+	case t.Name == "":
+		return &usageError{msg: "missing required flag --name"}
+	}
+	return nil
+}
+
+func validateOutput(output string) error {
+	switch output {
+	case outTable, outJSON:
+		return nil
+	default:
+		return &usageError{msg: fmt.Sprintf("invalid --output %q (want table|json)", output)}
+	}
+}
+
+// parseSince accepts an RFC3339 instant or a duration back from now.
+func parseSince(raw string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		return time.Time{}, &usageError{msg: fmt.Sprintf("invalid --since %q (want RFC3339 or a positive duration)", raw)}
+	}
+	return now.Add(-d), nil
+}
+
+func encodeJSON(rt *cliRuntime, v any) int {
+	enc := json.NewEncoder(rt.stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		return exitInfra
+	}
+	return exitSuccess
+}
+
+func renderEntries(w io.Writer, entries []journal.Entry) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "TIME\tTRIGGER\tOP\tKIND\tNAMESPACE/NAME\tRV\tSTATUS\tREASON\tSPEC")
+	for _, e := range entries {
+		rv := e.BeforeResourceVersion + "→" + e.AfterResourceVersion
+		spec := e.SpecHash
+		if len(spec) > len("sha256:")+12 {
+			spec = spec[:len("sha256:")+12]
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s/%s\t%s\t%s\t%s\t%s\n",
+			e.Time.Format(time.RFC3339), e.Trigger, e.Op, e.Kind(), e.Namespace, e.Name, rv, e.Status, e.Reason, spec)
+	}
+	_ = tw.Flush()
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// kubeconfigPath holds an optional --kubeconfig override shared by the
+// subcommand FlagSets.
+var kubeconfigPath string
+
+// newRuntime builds the production runtime. The client is constructed
+// on first use so read-only file-sourced queries work without cluster
+// credentials.
+func newRuntime(stdout, stderr io.Writer) *cliRuntime {
+	return &cliRuntime{
+		newClient:  newKubeClient,
+		newLister:  newInventoryLister,
+		newDeleter: newPruneRepository,
+		stdout:     stdout,
+		stderr:     stderr,
+		now:        time.Now(),
+	}
+}
+
+// newKubeClient builds an uncached client from --kubeconfig, then the
+// controller-runtime default discovery chain.
+func newKubeClient() (client.Client, error) {
+	cfg, err := loadKubeconfig()
+	if err != nil {
+		return nil, &infraError{err: fmt.Errorf("kubeconfig: %w", err)}
+	}
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, &infraError{err: fmt.Errorf("build client: %w", err)}
+	}
+	return c, nil
+}
+
+func loadKubeconfig() (*rest.Config, error) {
+	if kubeconfigPath != "" {
+		return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfigPath},
+			&clientcmd.ConfigOverrides{},
+		).ClientConfig()
+	}
+	return ctrl.GetConfig()
+}
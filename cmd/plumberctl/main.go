@@ -0,0 +1,194 @@
+// Command plumberctl is the operator-side maintenance CLI for
+// pvc-plumber v4. Where pvc-plumber-adopt (cmd/adopt) only ever touches
+// PVC metadata, plumberctl works on the operator's own artifacts —
+// the executor write journal and the backup repository:
+//
+//	pvc-plumber-ctl journal --configmap pvc-plumber/pvc-plumber-journal --namespace myapp
+//	pvc-plumber-ctl replay  --configmap pvc-plumber/pvc-plumber-journal \
+//	    --kind ReplicationSource --namespace myapp --name data [--confirm]
+//	pvc-plumber-ctl inventory [--output json]
+//	pvc-plumber-ctl prune --source old-backup@myapp:/data --min-age 720h \
+//	    [--confirm --journal /var/lib/pvc-plumber/prune.jsonl]
+//	pvc-plumber-ctl backup --namespace myapp --pvc data [--timeout 30m] \
+//	    [--configmap pvc-plumber/pvc-plumber-journal] [--snapshot]
+//
+// The binary is built to pvc-plumber-ctl via the Makefile's build-ctl
+// target and shipped in the operator image next to pvc-plumber-adopt.
+//
+// Hard boundaries:
+//   - journal and inventory are read-only;
+//   - replay is a dry run unless --confirm is passed, and even then
+//     writes go through executor.Execute, so the RS/RD GVK allow-list
+//     and the no-adoption rule ("exists" refusal) apply exactly as they
+//     do inside the operator;
+//   - prune is a dry run unless --confirm is passed, deletes only
+//     allow-listed sources, and refuses the whole run if any of them is
+//     still referenced or was written to within --min-age;
+//   - backup switches an operator-owned RS's trigger and back through
+//     executor.Execute, exactly as the operator's POST /backup does.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+)
+
+// Exit codes. Same numbering as pvc-plumber-adopt so scripts that wrap
+// both binaries need one table.
+const (
+	exitSuccess = 0 // success / dry run rendered
+	exitUsage   = 1 // unknown subcommand, missing required flag, parse error
+	exitRefused = 2 // replay or prune refused (see printUsage)
+	exitInfra   = 4 // kubeconfig, RBAC, unreadable journal, apiserver failure
+)
+
+// Subcommand and output-format constants.
+const (
+	cmdJournal   = "journal"
+	cmdReplay    = "replay"
+	cmdInventory = "inventory"
+	cmdPrune     = "prune"
+	cmdBackup    = "backup"
+	cmdHelp      = "help"
+	outTable     = "table"
+	outJSON      = "json"
+)
+
+// cliRuntime carries per-invocation dependencies. The kube client is
+// built lazily through newClient because `journal --file` needs none;
+// likewise the repository reader behind newLister, which only
+// `inventory` needs, and the connected kopia CLI behind newDeleter,
+// which only `prune` does. Tests replace all three with fakes.
+type cliRuntime struct {
+	newClient  func() (client.Client, error)
+	newLister  func() (kopia.SnapshotLister, error)
+	newDeleter func(ctx context.Context) (kopia.SnapshotDeleter, error)
+	stdout     io.Writer
+	stderr     io.Writer
+	now        time.Time
+}
+
+// usageError maps to exitUsage.
+type usageError struct{ msg string }
+
+func (e *usageError) Error() string { return e.msg }
+
+// refusedError maps to exitRefused.
+type refusedError struct{ msg string }
+
+func (e *refusedError) Error() string { return e.msg }
+
+// infraError maps to exitInfra.
+type infraError struct{ err error }
+
+func (e *infraError) Error() string { return e.err.Error() }
+func (e *infraError) Unwrap() error { return e.err }
+
+// exitCodeFor maps an error to the documented exit code.
+func exitCodeFor(err error) int {
+	if err == nil {
+		return exitSuccess
+	}
+	var usage *usageError
+	if errors.As(err, &usage) {
+		return exitUsage
+	}
+	var refused *refusedError
+	if errors.As(err, &refused) {
+		return exitRefused
+	}
+	if errors.Is(err, journal.ErrNoJournaledSpec) || errors.Is(err, journal.ErrNotOperatorOwned) {
+		return exitRefused
+	}
+	return exitInfra
+}
+
+// scheme registers corev1 for the ConfigMap journal source. RS/RD go
+// through unstructured.Unstructured and need no registration.
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], newRuntime(os.Stdout, os.Stderr)))
+}
+
+// run is the testable entrypoint.
+func run(args []string, rt *cliRuntime) int {
+	if len(args) == 0 {
+		printUsage(rt.stderr)
+		return exitUsage
+	}
+	switch args[0] {
+	case cmdJournal:
+		return runJournal(rt, args[1:])
+	case cmdReplay:
+		return runReplay(rt, args[1:])
+	case cmdInventory:
+		return runInventory(rt, args[1:])
+	case cmdPrune:
+		return runPrune(rt, args[1:])
+	case cmdBackup:
+		return runBackup(rt, args[1:])
+	case "-h", "--help", cmdHelp:
+		printUsage(rt.stdout)
+		return exitSuccess
+	default:
+		_, _ = fmt.Fprintf(rt.stderr, "pvc-plumber-ctl: unknown subcommand %q\n\n", args[0])
+		printUsage(rt.stderr)
+		return exitUsage
+	}
+}
+
+func printUsage(w io.Writer) {
+	_, _ = fmt.Fprint(w, `pvc-plumber-ctl — maintenance CLI for the pvc-plumber v4 operator
+
+Usage:
+  pvc-plumber-ctl <command> [flags]
+
+Commands:
+  journal    Query the executor write journal. Read-only.
+  replay     Recreate a deleted operator-owned RS/RD from its journaled spec.
+             Dry run unless --confirm.
+  inventory  Report orphaned kopia lineages, opted-in PVCs with no lineage,
+             and suspected renames. Read-only; kopia settings come from
+             BACKEND_TYPE and KOPIA_* as in the operator.
+  prune      Delete allow-listed orphaned kopia lineages older than
+             --min-age. Dry run unless --confirm; refuses any source a
+             live PVC, RS or RD still references.
+  backup     Back up one PVC now through its operator-owned RS, whatever
+             its tier, and wait for the sync. A scheduled RS gets its
+             schedule back afterwards.
+
+Journal sources (one required):
+  --configmap <namespace>/<name>   the operator's configmap journal sink
+  --file <path>                    a file sink copied out of the pod
+
+Run "pvc-plumber-ctl <command> -h" for command-specific flags.
+
+Exit codes:
+  0  success / dry run rendered
+  1  usage error
+  2  replay refused (no journaled spec, not operator-owned, already exists),
+     prune refused (a source is referenced, too recent, or absent), or
+     backup refused (no RS, not operator-owned, another backup running)
+     or superseded
+  4  infrastructure error (kubeconfig, RBAC, unreadable journal, apiserver,
+     kopia settings or repository scan), or a backup that failed or
+     timed out
+`)
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/mitchross/pvc-plumber/internal/inventory"
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+	v4labels "github.com/mitchross/pvc-plumber/internal/v4/labels"
+)
+
+const (
+	testJournalCM = "pvc-plumber/pvc-plumber-journal"
+	testNS        = "myapp"
+	testName      = "data"
+	testRepo      = "volsync-kopia-repository"
+)
+
+var (
+	testNow   = time.Date(2026, 6, 3, 12, 0, 0, 0, time.UTC)
+	testRSGVK = schema.GroupVersionKind{Group: "volsync.backube", Version: "v1alpha1", Kind: "ReplicationSource"}
+)
+
+// testRuntime wires run() to a fake client and captured output.
+func testRuntime(c client.Client) (*cliRuntime, *bytes.Buffer, *bytes.Buffer) {
+	var stdout, stderr bytes.Buffer
+	return &cliRuntime{
+		newClient: func() (client.Client, error) { return c, nil },
+		stdout:    &stdout,
+		stderr:    &stderr,
+		now:       testNow,
+	}, &stdout, &stderr
+}
+
+// deletedRSEntry is the journal line the operator writes when it
+// deletes an operator-owned RS.
+func deletedRSEntry(managedBy string) journal.Entry {
+	spec := map[string]any{"sourcePVC": testName, "kopia": map[string]any{"repository": testRepo}}
+	return journal.Entry{
+		Time: testNow.Add(-time.Hour), Trigger: journal.TriggerReconcile, Op: "delete",
+		GVK: "volsync.backube/v1alpha1/ReplicationSource", Namespace: testNS, Name: testName,
+		BeforeResourceVersion: "42", Status: "succeeded",
+		Labels:   map[string]string{v4labels.LabelManagedByKey: managedBy},
+		Spec:     spec,
+		SpecHash: journal.SpecHash(spec),
+	}
+}
+
+// seedJournal writes entries into the configmap sink on c.
+func seedJournal(t *testing.T, c client.Client, entries ...journal.Entry) {
+	t.Helper()
+	sink := journal.NewConfigMapSink(c, "pvc-plumber", "pvc-plumber-journal", 0)
+	for _, e := range entries {
+		if err := sink.Write(context.Background(), e); err != nil {
+			t.Fatalf("seed journal: %v", err)
+		}
+	}
+}
+
+func TestRun_NoArgsIsUsage(t *testing.T) {
+	rt, _, _ := testRuntime(nil)
+	if got := run(nil, rt); got != exitUsage {
+		t.Errorf("exit: got %d, want %d", got, exitUsage)
+	}
+}
+
+func TestJournal_RequiresExactlyOneSource(t *testing.T) {
+	rt, _, _ := testRuntime(nil)
+	if got := run([]string{cmdJournal}, rt); got != exitUsage {
+		t.Errorf("no source: exit %d, want %d", got, exitUsage)
+	}
+	if got := run([]string{cmdJournal, "--configmap", testJournalCM, "--file", "/tmp/x"}, rt); got != exitUsage {
+		t.Errorf("both sources: exit %d, want %d", got, exitUsage)
+	}
+}
+
+func TestJournal_ConfigMapJSON(t *testing.T) {
+	fc := fake.NewClientBuilder().Build()
+	seedJournal(t, fc, deletedRSEntry(v4labels.LabelManagedByValue))
+	rt, stdout, stderr := testRuntime(fc)
+
+	if got := run([]string{cmdJournal, "--configmap", testJournalCM, "--since", "24h", "--output", outJSON}, rt); got != exitSuccess {
+		t.Fatalf("exit %d: %s", got, stderr.String())
+	}
+	var entries []journal.Entry
+	if err := json.Unmarshal(stdout.Bytes(), &entries); err != nil {
+		t.Fatalf("decode: %v\n%s", err, stdout.String())
+	}
+	if len(entries) != 1 || entries[0].Op != "delete" {
+		t.Errorf("entries: %+v", entries)
+	}
+}
+
+func TestJournal_FileSourceTableNeedsNoCluster(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	fs, err := journal.NewFileSink(path, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = fs.Write(context.Background(), deletedRSEntry(v4labels.LabelManagedByValue))
+	_ = fs.Close()
+
+	rt, stdout, stderr := testRuntime(nil)
+	rt.newClient = func() (client.Client, error) {
+		t.Fatal("file source must not build a kube client")
+		return nil, nil
+	}
+	if got := run([]string{cmdJournal, "--file", path}, rt); got != exitSuccess {
+		t.Fatalf("exit %d: %s", got, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "myapp/data") || !strings.Contains(stdout.String(), "42→") {
+		t.Errorf("table output missing row:\n%s", stdout.String())
+	}
+}
+
+func TestReplay_DryRunWritesNothing(t *testing.T) {
+	fc := fake.NewClientBuilder().Build()
+	seedJournal(t, fc, deletedRSEntry(v4labels.LabelManagedByValue))
+	rt, stdout, stderr := testRuntime(fc)
+
+	args := []string{cmdReplay, "--configmap", testJournalCM, "--kind", "ReplicationSource", "--namespace", testNS, "--name", testName}
+	if got := run(args, rt); got != exitSuccess {
+		t.Fatalf("exit %d: %s", got, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), testRepo) {
+		t.Errorf("dry run should print the reconstructed body:\n%s", stdout.String())
+	}
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(testRSGVK)
+	if err := fc.Get(context.Background(), client.ObjectKey{Namespace: testNS, Name: testName}, live); err == nil {
+		t.Error("dry run created the RS")
+	}
+}
+
+func TestReplay_ConfirmCreatesAndJournals(t *testing.T) {
+	fc := fake.NewClientBuilder().Build()
+	seedJournal(t, fc, deletedRSEntry(v4labels.LabelManagedByValue))
+	rt, _, stderr := testRuntime(fc)
+
+	args := []string{cmdReplay, "--configmap", testJournalCM, "--kind", "ReplicationSource", "--namespace", testNS, "--name", testName, "--confirm"}
+	if got := run(args, rt); got != exitSuccess {
+		t.Fatalf("exit %d: %s", got, stderr.String())
+	}
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(testRSGVK)
+	if err := fc.Get(context.Background(), client.ObjectKey{Namespace: testNS, Name: testName}, live); err != nil {
+		t.Fatalf("RS not created: %v", err)
+	}
+
+	entries, _ := journal.NewConfigMapSink(fc, "pvc-plumber", "pvc-plumber-journal", 0).Read(context.Background())
+	if last := entries[len(entries)-1]; last.Trigger != journal.TriggerReplay {
+		t.Errorf("replay not journaled: %+v", last)
+	}
+
+	// Second run: the RS exists now, so the executor refuses.
+	rt, _, _ = testRuntime(fc)
+	if got := run(args, rt); got != exitRefused {
+		t.Errorf("second replay: exit %d, want %d (exists)", got, exitRefused)
+	}
+}
+
+func TestReplay_ForeignBodyRefused(t *testing.T) {
+	fc := fake.NewClientBuilder().Build()
+	seedJournal(t, fc, deletedRSEntry("argocd"))
+	rt, _, _ := testRuntime(fc)
+	args := []string{cmdReplay, "--configmap", testJournalCM, "--kind", "ReplicationSource", "--namespace", testNS, "--name", testName, "--confirm"}
+	if got := run(args, rt); got != exitRefused {
+		t.Errorf("exit %d, want %d", got, exitRefused)
+	}
+}
+
+func TestReplay_InvalidKindIsUsage(t *testing.T) {
+	rt, _, _ := testRuntime(nil)
+	args := []string{cmdReplay, "--configmap", testJournalCM, "--kind", "Secret", "--namespace", testNS, "--name", testName}
+	if got := run(args, rt); got != exitUsage {
+		t.Errorf("exit %d, want %d", got, exitUsage)
+	}
+}
+
+type fakeLister struct {
+	snaps []kopia.SnapshotInfo
+	err   error
+}
+
+func (f fakeLister) ListAllSnapshots(context.Context) ([]kopia.SnapshotInfo, error) {
+	return f.snaps, f.err
+}
+
+func inventoryRuntime(l kopia.SnapshotLister) (*cliRuntime, *bytes.Buffer, *bytes.Buffer) {
+	c := fake.NewClientBuilder().WithObjects(&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Namespace: testNS, Name: testName, Labels: map[string]string{v4labels.LabelEnabled: "true"},
+	}}).Build()
+	rt, stdout, stderr := testRuntime(c)
+	rt.newLister = func() (kopia.SnapshotLister, error) { return l, nil }
+	return rt, stdout, stderr
+}
+
+func TestInventory_Table(t *testing.T) {
+	rt, stdout, stderr := inventoryRuntime(fakeLister{snaps: []kopia.SnapshotInfo{
+		{ID: "a", Source: kopia.SnapshotSource{UserName: testName, Host: testNS, Path: "/data"}, StartTime: testNow.Add(-time.Hour), TotalSize: 10},
+		{ID: "b", Source: kopia.LegacySource(testNS, "cache"), StartTime: testNow.Add(-48 * time.Hour), TotalSize: 4096},
+	}})
+	if got := run([]string{cmdInventory}, rt); got != exitSuccess {
+		t.Fatalf("exit %d, stderr %q", got, stderr.String())
+	}
+	out := stdout.String()
+	for _, want := range []string{
+		"2 lineages, 1 PVCs (1 opted in); 1 orphaned (4096 bytes",
+		"ORPHANED LINEAGES (1)",
+		"cache-backup@myapp:/data",
+		"myapp/cache,myapp/cache-backup",
+		"PVCS WITHOUT LINEAGE (0)",
+		"SUSPECTED RENAMES (0)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestInventory_JSON(t *testing.T) {
+	rt, stdout, _ := inventoryRuntime(fakeLister{})
+	if got := run([]string{cmdInventory, "--output", outJSON}, rt); got != exitSuccess {
+		t.Fatalf("exit %d", got)
+	}
+	var r inventory.Report
+	if err := json.Unmarshal(stdout.Bytes(), &r); err != nil {
+		t.Fatalf("decode: %v\n%s", err, stdout.String())
+	}
+	if !r.GeneratedAt.Equal(testNow) || len(r.PVCsWithoutLineage) != 1 || r.PVCsWithoutLineage[0].PVC != testName {
+		t.Errorf("report: %+v", r)
+	}
+}
+
+func TestInventory_ScanFailureIsInfra(t *testing.T) {
+	rt, _, stderr := inventoryRuntime(fakeLister{err: errors.New("bucket unreachable")})
+	if got := run([]string{cmdInventory}, rt); got != exitInfra || !strings.Contains(stderr.String(), "bucket unreachable") {
+		t.Errorf("exit %d, stderr %q", got, stderr.String())
+	}
+
+	rt.newLister = func() (kopia.SnapshotLister, error) {
+		return nil, &infraError{err: errors.New("kopia settings: KOPIA_PASSWORD is required")}
+	}
+	if got := run([]string{cmdInventory}, rt); got != exitInfra {
+		t.Errorf("settings error: exit %d, want %d", got, exitInfra)
+	}
+	if got := run([]string{cmdInventory, "--output", "yaml"}, rt); got != exitUsage {
+		t.Errorf("bad output: exit %d, want %d", got, exitUsage)
+	}
+}
+
+// fakeRepo is a kopia.SnapshotDeleter over a fixed snapshot list.
+type fakeRepo struct {
+	fakeLister
+	deleted []string
+}
+
+func (f *fakeRepo) ListAllSnapshotsWithCheckpoints(ctx context.Context) ([]kopia.SnapshotInfo, error) {
+	return f.ListAllSnapshots(ctx)
+}
+
+func (f *fakeRepo) DeleteSnapshot(_ context.Context, id string) error {
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+func pruneRuntime() (*cliRuntime, *fakeRepo, *bytes.Buffer, *bytes.Buffer) {
+	repo := &fakeRepo{fakeLister: fakeLister{snaps: []kopia.SnapshotInfo{
+		{ID: "o1", Source: kopia.LegacySource("gone", "cache"), StartTime: testNow.Add(-60 * 24 * time.Hour)},
+		{ID: "o2", Source: kopia.LegacySource("gone", "cache"), StartTime: testNow.Add(-59 * 24 * time.Hour)},
+		{ID: "l1", Source: kopia.LegacySource(testNS, testName), StartTime: testNow.Add(-60 * 24 * time.Hour)},
+	}}}
+	rt, stdout, stderr := inventoryRuntime(repo)
+	rt.newDeleter = func(context.Context) (kopia.SnapshotDeleter, error) { return repo, nil }
+	return rt, repo, stdout, stderr
+}
+
+func TestPrune_DryRunByDefault(t *testing.T) {
+	rt, repo, stdout, stderr := pruneRuntime()
+	if got := run([]string{cmdPrune, "--source", "cache-backup@gone:/data", "--min-age", "720h"}, rt); got != exitSuccess {
+		t.Fatalf("exit %d, stderr %q", got, stderr.String())
+	}
+	if len(repo.deleted) != 0 || !strings.Contains(stdout.String(), "would-delete") || !strings.Contains(stderr.String(), "dry run") {
+		t.Errorf("deleted %v, stdout %q", repo.deleted, stdout.String())
+	}
+}
+
+func TestPrune_ConfirmWritesJournal(t *testing.T) {
+	rt, repo, _, stderr := pruneRuntime()
+	path := filepath.Join(t.TempDir(), "prune.jsonl")
+	args := []string{cmdPrune, "--source", "cache-backup@gone:/data", "--min-age", "720h", "--confirm", "--journal", path, "--output", outJSON}
+	if got := run(args, rt); got != exitSuccess {
+		t.Fatalf("exit %d, stderr %q", got, stderr.String())
+	}
+	if !slices.Equal(repo.deleted, []string{"o1", "o2"}) {
+		t.Errorf("deleted %v", repo.deleted)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(raw)), "\n"); len(lines) != 2 || !strings.Contains(lines[1], `"snapshot_id":"o2"`) {
+		t.Errorf("journal: %s", raw)
+	}
+}
+
+func TestPrune_RefusesLivePVCLineage(t *testing.T) {
+	rt, repo, stdout, _ := pruneRuntime()
+	args := []string{cmdPrune, "--source", "cache-backup@gone:/data", "--source", "data-backup@myapp:/data",
+		"--min-age", "720h", "--confirm", "--journal", filepath.Join(t.TempDir(), "j")}
+	if got := run(args, rt); got != exitRefused {
+		t.Fatalf("exit %d, want %d", got, exitRefused)
+	}
+	if len(repo.deleted) != 0 || !strings.Contains(stdout.String(), "still referenced by PVC myapp/data") {
+		t.Errorf("deleted %v, stdout %q", repo.deleted, stdout.String())
+	}
+}
+
+func TestPrune_UsageErrors(t *testing.T) {
+	rt, _, _, _ := pruneRuntime()
+	for name, args := range map[string][]string{
+		"no source":           {cmdPrune, "--min-age", "720h"},
+		"bad source":          {cmdPrune, "--source", "cache", "--min-age", "720h"},
+		"no min age":          {cmdPrune, "--source", "cache-backup@gone:/data"},
+		"confirm w/o journal": {cmdPrune, "--source", "cache-backup@gone:/data", "--min-age", "720h", "--confirm"},
+	} {
+		if got := run(args, rt); got != exitUsage {
+			t.Errorf("%s: exit %d, want %d", name, got, exitUsage)
+		}
+	}
+}
+
+// backupRS is an operator-owned, scheduled RS for myapp/data.
+func backupRS(managedBy string) *unstructured.Unstructured {
+	rs := &unstructured.Unstructured{}
+	rs.SetGroupVersionKind(testRSGVK)
+	rs.SetNamespace(testNS)
+	rs.SetName(testName)
+	rs.SetLabels(map[string]string{v4labels.LabelManagedByKey: managedBy})
+	_ = unstructured.SetNestedField(rs.Object, "0 3 * * *", "spec", "trigger", "schedule")
+	_ = unstructured.SetNestedField(rs.Object, testRepo, "spec", "kopia", "repository")
+	return rs
+}
+
+// volsyncClient is a fake whose RS reads complete any manual trigger,
+// as VolSync would once the sync finished.
+func volsyncClient(objs ...client.Object) client.WithWatch {
+	return fake.NewClientBuilder().WithObjects(objs...).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			if err := c.Get(ctx, key, obj, opts...); err != nil {
+				return err
+			}
+			u, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				return nil
+			}
+			manual, _, _ := unstructured.NestedString(u.Object, "spec", "trigger", "manual")
+			if synced, _, _ := unstructured.NestedString(u.Object, "status", "lastManualSync"); manual == "" || synced == manual {
+				return nil
+			}
+			_ = unstructured.SetNestedField(u.Object, manual, "status", "lastManualSync")
+			if err := c.Update(ctx, u); err != nil {
+				return err
+			}
+			return c.Get(ctx, key, obj, opts...)
+		},
+	}).Build()
+}
+
+func TestBackup_SucceedsAndRestoresSchedule(t *testing.T) {
+	c := volsyncClient(backupRS(v4labels.LabelManagedByValue))
+	rt, stdout, stderr := testRuntime(c)
+	if got := run([]string{cmdBackup, "--namespace", testNS, "--pvc", testName, "--output", outJSON}, rt); got != exitSuccess {
+		t.Fatalf("exit %d, stderr %q", got, stderr.String())
+	}
+	var res struct {
+		Status           string `json:"status"`
+		ScheduleRestored bool   `json:"schedule_restored"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &res); err != nil {
+		t.Fatalf("decode %q: %v", stdout.String(), err)
+	}
+	if res.Status != "succeeded" || !res.ScheduleRestored {
+		t.Errorf("result %s", stdout.String())
+	}
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(testRSGVK)
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: testNS, Name: testName}, live); err != nil {
+		t.Fatal(err)
+	}
+	if schedule, _, _ := unstructured.NestedString(live.Object, "spec", "trigger", "schedule"); schedule != "0 3 * * *" {
+		t.Errorf("live schedule %q after backup", schedule)
+	}
+}
+
+func TestBackup_Refusals(t *testing.T) {
+	for name, objs := range map[string][]client.Object{
+		"no RS":      nil,
+		"argo-owned": {backupRS("argocd")},
+	} {
+		rt, _, stderr := testRuntime(volsyncClient(objs...))
+		if got := run([]string{cmdBackup, "--namespace", testNS, "--pvc", testName}, rt); got != exitRefused {
+			t.Errorf("%s: exit %d, want %d (stderr %q)", name, got, exitRefused, stderr.String())
+		}
+	}
+}
+
+func TestBackup_UsageErrors(t *testing.T) {
+	rt, _, _ := testRuntime(nil)
+	for name, args := range map[string][]string{
+		"no namespace":  {cmdBackup, "--pvc", testName},
+		"no pvc":        {cmdBackup, "--namespace", testNS},
+		"zero timeout":  {cmdBackup, "--namespace", testNS, "--pvc", testName, "--timeout", "0s"},
+		"bad configmap": {cmdBackup, "--namespace", testNS, "--pvc", testName, "--configmap", "journal"},
+		"bad output":    {cmdBackup, "--namespace", testNS, "--pvc", testName, "--output", "yaml"},
+	} {
+		if got := run(args, rt); got != exitUsage {
+			t.Errorf("%s: exit %d, want %d", name, got, exitUsage)
+		}
+	}
+}
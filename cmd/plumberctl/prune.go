@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+	"github.com/mitchross/pvc-plumber/internal/config"
+	"github.com/mitchross/pvc-plumber/internal/inventory"
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+)
+
+// pruneTimeout bounds a whole prune: the scan, the reference listing
+// and one `kopia snapshot delete` per manifest.
+const pruneTimeout = 30 * time.Minute
+
+// newPruneRepository is the production cliRuntime.newDeleter: the kopia
+// CLI client, connected, for BACKEND_TYPE=kopia-s3 or kopia-fs. It is
+// never the native reader — that one cannot write — whatever
+// KOPIA_READER says.
+func newPruneRepository(ctx context.Context) (kopia.SnapshotDeleter, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, &infraError{err: fmt.Errorf("kopia settings: %w", err)}
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	kopts := kopia.Options{ConnectTimeout: cfg.KopiaConnectTimeout}
+	var kc *kopia.Client
+	switch cfg.BackendType {
+	case backend.TypeKopiaS3:
+		var creds kopia.CredentialsSource
+		if cfg.KopiaCredentialsPath != "" {
+			creds = kopia.NewDirCredentialsSource(cfg.KopiaCredentialsPath)
+		} else {
+			creds = kopia.NewStaticCredentialsSource(cfg.KopiaPassword, cfg.KopiaS3AccessKey, cfg.KopiaS3SecretKey)
+		}
+		kc = kopia.NewClient(kopia.S3Config{
+			Endpoint:   cfg.KopiaS3Endpoint,
+			Bucket:     cfg.KopiaS3Bucket,
+			DisableTLS: cfg.KopiaS3DisableTLS,
+		}, creds, logger, kopts)
+	case backend.TypeKopiaFS:
+		kc = kopia.NewFilesystemClient(kopia.FilesystemConfig{Path: cfg.KopiaFSPath},
+			kopia.NewPasswordCredentialsSource(cfg.KopiaPassword), logger, kopts)
+	default:
+		return nil, &infraError{err: fmt.Errorf("prune needs a kopia backend (BACKEND_TYPE=kopia-s3 or kopia-fs), got %q", cfg.BackendType)}
+	}
+	if err := kc.Connect(ctx); err != nil {
+		return nil, &infraError{err: fmt.Errorf("connect to kopia repository: %w", err)}
+	}
+	return kc, nil
+}
+
+// runPrune handles `pvc-plumber-ctl prune ...`. A dry run unless
+// --confirm; see kopia.Pruner for the guards every source must pass.
+func runPrune(rt *cliRuntime, args []string) int {
+	fs := flag.NewFlagSet(cmdPrune, flag.ContinueOnError)
+	fs.SetOutput(rt.stderr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(rt.stderr, "Usage: pvc-plumber-ctl prune --source <user@host:/path> [--source ...] --min-age <duration> [--confirm --journal <path>]")
+		_, _ = fmt.Fprintln(rt.stderr, "Kopia settings are read from BACKEND_TYPE and KOPIA_* as in the operator.")
+		fs.PrintDefaults()
+	}
+	var sources []kopia.SnapshotSource
+	var minAge time.Duration
+	var confirm bool
+	var journalPath, output string
+	fs.Func("source", "snapshot source to prune, as reported by `inventory` (repeatable; required)", func(raw string) error {
+		s, err := kopia.ParseSnapshotSource(raw)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, s)
+		return nil
+	})
+	fs.DurationVar(&minAge, "min-age", 0, "minimum time since the source's newest snapshot, e.g. 720h (required)")
+	fs.BoolVar(&confirm, "confirm", false, "delete; without it the run is a dry run")
+	fs.StringVar(&journalPath, "journal", "", "append one JSON line per deleted manifest ID here (required with --confirm)")
+	fs.StringVar(&output, "output", outTable, "output format: table|json")
+	fs.StringVar(&kubeconfigPath, "kubeconfig", "", "path to kubeconfig")
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	err := validateOutput(output)
+	switch {
+	case err != nil:
+	case len(sources) == 0:
+		err = &usageError{msg: "at least one --source is required"}
+	case minAge <= 0:
+		err = &usageError{msg: "--min-age is required and must be positive"}
+	case confirm && journalPath == "":
+		err = &usageError{msg: "--confirm requires --journal"}
+	}
+	if err != nil {
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		return exitCodeFor(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pruneTimeout)
+	defer cancel()
+	c, err := rt.newClient()
+	if err != nil {
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		return exitCodeFor(err)
+	}
+	repo, err := rt.newDeleter(ctx)
+	if err != nil {
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		return exitCodeFor(err)
+	}
+	p := &kopia.Pruner{
+		Repo:       repo,
+		References: inventory.KubeReferences{Reader: c},
+		Now:        func() time.Time { return rt.now },
+	}
+	if confirm {
+		// Opened before anything is deleted, so an unwritable path fails
+		// the run up front rather than after the first delete.
+		f, err := os.OpenFile(journalPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			_, _ = fmt.Fprintf(rt.stderr, "open prune journal: %v\n", err)
+			return exitInfra
+		}
+		defer func() { _ = f.Close() }()
+		p.Journal = f
+	}
+
+	report, err := p.Prune(ctx, kopia.PruneOptions{Allow: sources, MinAge: minAge, Confirm: confirm})
+	code := exitSuccess
+	if output == outJSON {
+		code = encodeJSON(rt, report)
+	} else {
+		renderPrune(rt.stdout, report)
+	}
+	switch {
+	case errors.Is(err, kopia.ErrPruneRefused):
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		return exitRefused
+	case err != nil:
+		_, _ = fmt.Fprintln(rt.stderr, err)
+		return exitInfra
+	}
+	if report.DryRun {
+		_, _ = fmt.Fprintln(rt.stderr, "dry run: re-run with --confirm --journal <path> to delete")
+	}
+	return code
+}
+
+func renderPrune(w io.Writer, r kopia.PruneReport) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "SOURCE\tACTION\tSNAPSHOTS\tLATEST\tREASON")
+	for _, s := range r.Sources {
+		action := s.Action
+		if r.DryRun && action == kopia.PruneDelete {
+			action = "would-delete"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", s.Source, action, len(s.SnapshotIDs), formatLatest(s.LatestSnapshotAt), s.Reason)
+	}
+	_ = tw.Flush()
+	if len(r.Deleted) > 0 {
+		_, _ = fmt.Fprintf(w, "deleted %d manifests: %s\n", len(r.Deleted), strings.Join(r.Deleted, ","))
+	}
+}
@@ -10,10 +10,13 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/mitchross/pvc-plumber/internal/backend"
 	"github.com/mitchross/pvc-plumber/internal/cache"
+	"github.com/mitchross/pvc-plumber/internal/composite"
 	"github.com/mitchross/pvc-plumber/internal/config"
 	"github.com/mitchross/pvc-plumber/internal/handler"
 	"github.com/mitchross/pvc-plumber/internal/kopia"
+	"github.com/mitchross/pvc-plumber/internal/kopia/native"
 	"github.com/mitchross/pvc-plumber/internal/s3"
 )
 
@@ -52,64 +55,45 @@ func main() {
 		"cache_ttl", cfg.CacheTTL,
 		"re_warm_interval", cfg.ReWarmInterval)
 
-	// Create backend based on configuration
-	var backendClient handler.BackendClient
-	switch cfg.BackendType {
-	case "s3":
-		logger.Info("initializing s3 backend",
-			"endpoint", cfg.S3Endpoint,
-			"bucket", cfg.S3Bucket,
-			"secure", cfg.S3Secure)
-		s3Client, err := s3.NewClient(cfg.S3Endpoint, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey, cfg.S3Secure)
-		if err != nil {
-			logger.Error("failed to create S3 client", "error", err)
-			os.Exit(1)
+	// Create backend based on configuration. BACKENDS builds every member
+	// and merges them through a composite; see cmd/operator/httpserver.go.
+	var members []composite.Member
+	var credWatcher *kopia.CredentialsWatcher
+	for _, typ := range cfg.BackendTypes() {
+		member, w := newBackend(typ, cfg, logger)
+		members = append(members, composite.Member{Name: typ, Backend: member})
+		if w != nil {
+			credWatcher = w
 		}
-		backendClient = s3Client
-
-	case "kopia-s3":
-		logger.Info("initializing kopia-s3 backend",
-			"endpoint", cfg.KopiaS3Endpoint,
-			"bucket", cfg.KopiaS3Bucket,
-			"disable_tls", cfg.KopiaS3DisableTLS,
-			"credentials_path", cfg.KopiaCredentialsPath,
-		)
-		// Same credentials-source selection as the operator binary —
-		// directory-mounted Secret when available, env-var-loaded creds as
-		// the fallback. The legacy HTTP-only deployment shape typically
-		// keeps env-var creds, so the static source path runs in production
-		// for v1.x callers; v3.1.0+ deployments use the dir source.
-		var creds kopia.CredentialsSource
-		if cfg.KopiaCredentialsPath != "" {
-			creds = kopia.NewDirCredentialsSource(cfg.KopiaCredentialsPath)
-		} else {
-			creds = kopia.NewStaticCredentialsSource(cfg.KopiaPassword, cfg.KopiaS3AccessKey, cfg.KopiaS3SecretKey)
-		}
-		kopiaClient := kopia.NewClient(kopia.S3Config{
-			Endpoint:   cfg.KopiaS3Endpoint,
-			Bucket:     cfg.KopiaS3Bucket,
-			DisableTLS: cfg.KopiaS3DisableTLS,
-		}, creds, logger, kopia.Options{ConnectTimeout: cfg.KopiaConnectTimeout})
-		if err := kopiaClient.Connect(context.Background()); err != nil {
-			logger.Error("failed to connect to kopia repository", "error", err)
+	}
+	var backendClient handler.BackendClient = members[0].Backend
+	if len(members) > 1 {
+		logger.Info("initializing composite backend", "members", cfg.Backends, "policy", cfg.BackendMergePolicy)
+		cc, err := composite.New(members, cfg.BackendMergePolicy, logger)
+		if err != nil {
+			logger.Error("failed to create composite backend", "error", err)
 			os.Exit(1)
 		}
-		backendClient = kopiaClient
+		backendClient = cc
 	}
 
 	// Wrap backend with cache
 	cachedBackend := cache.New(backendClient, cfg.CacheTTL, logger)
+	cachedBackend.SetNegativeTTL(cfg.CacheNegativeTTL)
 
-	// Pre-warm cache for kopia backend
-	var kopiaClient *kopia.Client
-	if cfg.BackendType == "kopia-s3" {
-		if kc, ok := backendClient.(*kopia.Client); ok {
+	// Pre-warm cache for kopia backend. Not for a composite: a scan of one
+	// member can't stand in for the merged answer.
+	var kopiaClient kopia.SourceSummarizer
+	if (cfg.BackendType == "kopia-s3" || cfg.BackendType == "kopia-fs") && len(members) == 1 {
+		if kc, ok := backendClient.(kopia.SourceSummarizer); ok {
 			kopiaClient = kc
-			sources, err := kc.ListAllSources(context.Background())
+			cachedBackend.SetScanner(kc)
+			cachedBackend.SetSummaryBackend(cfg.BackendType)
+			sources, err := kc.SummarizeAllSources(context.Background())
 			if err != nil {
 				logger.Warn("cache pre-warm failed, will populate on demand", "error", err)
 			} else {
-				cachedBackend.PreWarm(sources)
+				cachedBackend.PreWarmSummaries(sources)
 			}
 		}
 	}
@@ -121,10 +105,14 @@ func main() {
 	}
 	h := handler.NewWithHealthChecker(cachedBackend, healthChecker, logger)
 	h.SetRequestTimeout(cfg.HTTPTimeout)
+	if credWatcher != nil {
+		h.SetRotationCounter(credWatcher)
+	}
 
 	// Setup HTTP server
 	mux := http.NewServeMux()
 	mux.HandleFunc("/exists/", h.HandleExists)
+	mux.HandleFunc("/exists:batch", h.HandleExistsBatch)
 	mux.HandleFunc("/healthz", h.HandleHealthz)
 	mux.HandleFunc("/readyz", h.HandleReadyz)
 	mux.HandleFunc("/metrics", h.HandleMetrics)
@@ -175,13 +163,150 @@ func main() {
 	logger.Info("server stopped")
 }
 
-// runCacheReWarmLoop periodically re-runs kopia ListAllSources and
+// newBackend constructs one uncached backend of type typ, exiting on
+// failure like the rest of main. The watcher is non-nil when typ is
+// kopia-s3 with directory-mounted credentials.
+func newBackend(typ string, cfg *config.Config, logger *slog.Logger) (handler.BackendClient, *kopia.CredentialsWatcher) {
+	var backendClient handler.BackendClient
+	var credWatcher *kopia.CredentialsWatcher
+	switch typ {
+	case "s3":
+		logger.Info("initializing s3 backend",
+			"endpoint", cfg.S3Endpoint,
+			"bucket", cfg.S3Bucket,
+			"secure", cfg.S3Secure,
+			"layout", cfg.S3Layout)
+		layout, err := s3.NewLayout(s3.LayoutOptions{Name: cfg.S3Layout, Prefix: cfg.S3Prefix, IndexKey: cfg.S3IndexKey})
+		if err != nil {
+			logger.Error("invalid S3 layout", "error", err)
+			os.Exit(1)
+		}
+		s3Client, err := s3.NewClient(cfg.S3Endpoint, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey, cfg.S3Secure, s3.Options{Layout: layout})
+		if err != nil {
+			logger.Error("failed to create S3 client", "error", err)
+			os.Exit(1)
+		}
+		backendClient = s3Client
+
+	case "kopia-s3":
+		logger.Info("initializing kopia-s3 backend",
+			"endpoint", cfg.KopiaS3Endpoint,
+			"bucket", cfg.KopiaS3Bucket,
+			"disable_tls", cfg.KopiaS3DisableTLS,
+			"credentials_path", cfg.KopiaCredentialsPath,
+			"reader", cfg.KopiaReader,
+		)
+		// Same credentials-source selection as the operator binary —
+		// directory-mounted Secret when available, env-var-loaded creds as
+		// the fallback. The legacy HTTP-only deployment shape typically
+		// keeps env-var creds, so the static source path runs in production
+		// for v1.x callers; v3.1.0+ deployments use the dir source.
+		var creds kopia.CredentialsSource
+		if cfg.KopiaCredentialsPath != "" {
+			creds = kopia.NewDirCredentialsSource(cfg.KopiaCredentialsPath)
+		} else {
+			creds = kopia.NewStaticCredentialsSource(cfg.KopiaPassword, cfg.KopiaS3AccessKey, cfg.KopiaS3SecretKey)
+		}
+		s3cfg := kopia.S3Config{
+			Endpoint:   cfg.KopiaS3Endpoint,
+			Bucket:     cfg.KopiaS3Bucket,
+			DisableTLS: cfg.KopiaS3DisableTLS,
+		}
+		kopts := kopia.Options{ConnectTimeout: cfg.KopiaConnectTimeout}
+		switch cfg.KopiaReader {
+		case config.KopiaReaderNative:
+			nativeClient, err := native.NewS3Client(native.S3Options{
+				Endpoint:   cfg.KopiaS3Endpoint,
+				Bucket:     cfg.KopiaS3Bucket,
+				DisableTLS: cfg.KopiaS3DisableTLS,
+			}, creds, logger, native.Options{ConnectTimeout: cfg.KopiaConnectTimeout})
+			if err != nil {
+				logger.Error("failed to create native kopia reader", "error", err)
+				os.Exit(1)
+			}
+			if err := nativeClient.Connect(context.Background()); err != nil {
+				logger.Error("failed to connect to kopia repository", "error", err)
+				os.Exit(1)
+			}
+			backendClient = nativeClient
+			credWatcher = watchCredentials(context.Background(), creds, nativeClient, logger)
+		case config.KopiaReaderServer:
+			// No credential watcher: the kopia server keeps the keys it
+			// started with, so a rotation needs a restart in this mode.
+			serverClient, err := kopia.ConnectServer(context.Background(), kopia.ServerConfig{
+				URL:      cfg.KopiaServerURL,
+				Username: cfg.KopiaServerUsername,
+				Password: cfg.KopiaServerPassword,
+				Timeout:  cfg.KopiaServerTimeout,
+			}, kopia.ManagedServerOptions{Address: cfg.KopiaServerAddress},
+				kopia.NewClient(s3cfg, creds, logger, kopts), logger)
+			if err != nil {
+				logger.Error("failed to connect to kopia server", "error", err)
+				os.Exit(1)
+			}
+			backendClient = serverClient
+		default:
+			kopiaClient := kopia.NewClient(s3cfg, creds, logger, kopts)
+			if err := kopiaClient.Connect(context.Background()); err != nil {
+				logger.Error("failed to connect to kopia repository", "error", err)
+				os.Exit(1)
+			}
+			backendClient = kopiaClient
+			credWatcher = watchCredentials(context.Background(), creds, kopiaClient, logger)
+		}
+
+	case "kopia-fs":
+		logger.Info("initializing kopia-fs backend",
+			"path", cfg.KopiaFSPath,
+			"reader", cfg.KopiaReader,
+		)
+		creds := kopia.NewPasswordCredentialsSource(cfg.KopiaPassword)
+		if cfg.KopiaReader == config.KopiaReaderNative {
+			nativeClient := native.NewClient(native.DirStore{Dir: cfg.KopiaFSPath}, creds, logger,
+				native.Options{ConnectTimeout: cfg.KopiaConnectTimeout, BackendType: backend.TypeKopiaFS})
+			if err := nativeClient.Connect(context.Background()); err != nil {
+				logger.Error("failed to open kopia repository", "error", err)
+				os.Exit(1)
+			}
+			backendClient = nativeClient
+			break
+		}
+		kopiaClient := kopia.NewFilesystemClient(kopia.FilesystemConfig{Path: cfg.KopiaFSPath}, creds, logger,
+			kopia.Options{ConnectTimeout: cfg.KopiaConnectTimeout})
+		if err := kopiaClient.Connect(context.Background()); err != nil {
+			logger.Error("failed to connect to kopia repository", "error", err)
+			os.Exit(1)
+		}
+		backendClient = kopiaClient
+	}
+	return backendClient, credWatcher
+}
+
+// watchCredentials mirrors the operator's: start a CredentialsWatcher for
+// directory-mounted credentials so a Secret rotation reconnects target
+// without a restart. Env-var credentials can't change under a running
+// process, so they get none.
+func watchCredentials(ctx context.Context, creds kopia.CredentialsSource, target kopia.Reconnector, logger *slog.Logger) *kopia.CredentialsWatcher {
+	dir, ok := creds.(*kopia.DirCredentialsSource)
+	if !ok {
+		return nil
+	}
+	w := kopia.NewCredentialsWatcher(dir, target, logger)
+	go func() {
+		if err := w.Run(ctx); err != nil {
+			logger.Warn("kopia credential rotation disabled", "error", err)
+		}
+	}()
+	return w
+}
+
+// runCacheReWarmLoop periodically re-runs kopia SummarizeAllSources and
 // refreshes the cache. Returns when ctx is canceled (shutdown). Each
 // tick is bounded by a per-call timeout so a hung kopia subprocess
 // can't pin the goroutine across multiple intervals.
 func runCacheReWarmLoop(
 	ctx context.Context,
-	kopiaClient *kopia.Client,
+	kopiaClient kopia.SourceSummarizer,
 	cachedBackend *cache.CachedClient,
 	interval time.Duration,
 	logger *slog.Logger,
@@ -204,13 +329,13 @@ func runCacheReWarmLoop(
 			return
 		case <-ticker.C:
 			callCtx, cancel := context.WithTimeout(ctx, callTimeout)
-			sources, err := kopiaClient.ListAllSources(callCtx)
+			sources, err := kopiaClient.SummarizeAllSources(callCtx)
 			cancel()
 			if err != nil {
 				logger.Warn("cache re-warm failed; keeping previous entries", "error", err)
 				continue
 			}
-			cachedBackend.Refresh(sources)
+			cachedBackend.RefreshSummaries(sources)
 		}
 	}
 }
@@ -1,5 +1,11 @@
 package backend
 
+import (
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/naming"
+)
+
 const (
 	DecisionRestore = "restore"
 	DecisionFresh   = "fresh"
@@ -14,9 +20,20 @@ const (
 // `kopia-s3`. This is a breaking rename for anyone who scrapes
 // `pvc_plumber_backup_check_total{backend="…"}` metrics or filters logs by
 // backend label — see CHANGELOG v3.0.0 for the migration guidance.
+//
+// TypeKopiaFS brings the filesystem repository back as a separate,
+// development-and-CI backend next to kopia-s3 rather than undoing that
+// rename: same readers and snapshot parsing, a local directory instead
+// of a bucket.
+//
+// TypeComposite labels merged answers from several backends
+// (internal/composite); it is never a BACKEND_TYPE value — BACKENDS
+// selects the members.
 const (
-	TypeS3      = "s3"
-	TypeKopiaS3 = "kopia-s3"
+	TypeS3        = "s3"
+	TypeKopiaS3   = "kopia-s3"
+	TypeKopiaFS   = "kopia-fs"
+	TypeComposite = "composite"
 )
 
 // CheckResult represents the result of a backup existence check.
@@ -29,4 +46,90 @@ type CheckResult struct {
 	Backend       string `json:"backend"`
 	Source        string `json:"source,omitempty"`
 	Error         string `json:"error,omitempty"`
+
+	// Identity is the backup identity checked (naming.KopiaIdentity.Key:
+	// "<namespace>/<pvc>" or the override). Source above is where the
+	// backend found it, or where it looked when nothing was found.
+	Identity string `json:"identity,omitempty"`
+
+	// CacheAgeSeconds is how long ago the answer was cached, set only
+	// when it was served from the cache; nil means the backend answered
+	// this request.
+	CacheAgeSeconds *int64 `json:"cacheAgeSeconds,omitempty"`
+
+	// Backends holds each member's own result, in precedence order, when
+	// the answer was merged from several backends (Backend=composite).
+	Backends []CheckResult `json:"backends,omitempty"`
+
+	// Snapshot metadata, populated by backends that can see individual
+	// snapshots (kopia). Zero / omitted when the backend only knows
+	// existence (s3) or the check failed. LatestSnapshotAt is the start
+	// time of the newest complete snapshot in the lineage.
+	SnapshotCount      int       `json:"snapshotCount,omitempty"`
+	LatestSnapshotAt   time.Time `json:"latestSnapshotAt,omitzero"`
+	LatestSnapshotSize int64     `json:"latestSnapshotSize,omitempty"`
+}
+
+// SnapshotSummary is the per-lineage freshness summary a backend derives
+// from its snapshot list, and what the cache stores for pre-warmed keys.
+type SnapshotSummary struct {
+	Count      int
+	LatestAt   time.Time
+	LatestSize int64
+	// Source is the lineage the latest snapshot belongs to, when the
+	// summary merges several (a PVC with both legacy and v4 snapshots).
+	Source string
+}
+
+// ApplySummary copies a SnapshotSummary onto the result's metadata fields.
+func (r *CheckResult) ApplySummary(s SnapshotSummary) {
+	r.SnapshotCount = s.Count
+	r.LatestSnapshotAt = s.LatestAt
+	r.LatestSnapshotSize = s.LatestSize
+	if s.Source != "" {
+		r.Source = s.Source
+	}
+}
+
+// Query identifies the backups a check is about. Namespace and PVC label
+// the result and scope the lookup; Identity decides what is looked up.
+type Query struct {
+	Namespace string
+	PVC       string
+	Identity  naming.KopiaIdentity
+}
+
+// NewQuery resolves the identity for a PVC through naming.IdentityFor:
+// the pvc-plumber.io/backup-identity override when non-empty, otherwise
+// the default <namespace>/<pvc> identity.
+func NewQuery(namespace, pvc, identityOverride string) Query {
+	return Query{Namespace: namespace, PVC: pvc, Identity: naming.IdentityFor(namespace, pvc, identityOverride)}
+}
+
+// Overridden reports whether the query carries an explicit identity
+// rather than the PVC's default one.
+func (q Query) Overridden() bool {
+	return q.Identity != naming.IdentityFor(q.Namespace, q.PVC, "")
+}
+
+// Key is the query's cache key: "<namespace>/<pvc>" for the default
+// identity — the form pre-warmed summaries are keyed on — with
+// "#<identity>" appended for an override, so a PVC checked under two
+// identities never shares an entry.
+func (q Query) Key() string {
+	key := q.Namespace + "/" + q.PVC
+	if q.Overridden() {
+		key += "#" + q.Identity.Key()
+	}
+	return key
+}
+
+// Result starts a CheckResult labelled with the query.
+func (q Query) Result(backendType string) CheckResult {
+	return CheckResult{
+		Namespace: q.Namespace,
+		Pvc:       q.PVC,
+		Backend:   backendType,
+		Identity:  q.Identity.Key(),
+	}
 }
@@ -0,0 +1,26 @@
+package backend
+
+import "testing"
+
+func TestQuery_KeyAndOverride(t *testing.T) {
+	for _, tc := range []struct {
+		q          Query
+		key        string
+		identity   string
+		overridden bool
+	}{
+		{NewQuery("myapp", "data", ""), "myapp/data", "myapp/data", false},
+		{NewQuery("immich-prod", "library", "immich-library"), "immich-prod/library#immich-library", "immich-library", true},
+	} {
+		if got := tc.q.Key(); got != tc.key {
+			t.Errorf("%+v: Key = %q, want %q", tc.q, got, tc.key)
+		}
+		if got := tc.q.Overridden(); got != tc.overridden {
+			t.Errorf("%+v: Overridden = %v", tc.q, got)
+		}
+		r := tc.q.Result(TypeKopiaS3)
+		if r.Namespace != tc.q.Namespace || r.Pvc != tc.q.PVC || r.Backend != TypeKopiaS3 || r.Identity != tc.identity {
+			t.Errorf("%+v: Result = %+v", tc.q, r)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,21 +15,35 @@ import (
 
 // BackendClient matches handler.BackendClient for wrapping.
 type BackendClient interface {
-	CheckBackupExists(ctx context.Context, namespace, pvc string) backend.CheckResult
+	CheckBackupExists(ctx context.Context, q backend.Query) backend.CheckResult
 }
 
 type entry struct {
 	result    backend.CheckResult
+	cachedAt  time.Time
 	expiresAt time.Time
+	// override marks an entry for an identity override. Scans key only
+	// default identities, so a refresh never evicts these.
+	override bool
 }
 
-// CachedClient wraps a backend with an in-memory TTL cache.
+// CachedClient wraps a backend with an in-memory TTL cache. Positive and
+// negative answers age out on separate TTLs: a stale "exists" costs one
+// needless restore attempt, a stale "missing" can provision an empty
+// volume over a PVC whose backups just landed.
 type CachedClient struct {
-	inner  BackendClient
-	ttl    time.Duration
-	logger *slog.Logger
-	mu     sync.RWMutex
-	items  map[string]entry
+	inner       BackendClient
+	ttl         time.Duration
+	negativeTTL time.Duration
+	logger      *slog.Logger
+	mu          sync.RWMutex
+	items       map[string]entry
+
+	// lastScan is the set of keys with backups in the previous full
+	// scan, nil until the first. Each scan is diffed against it.
+	lastScan map[string]bool
+	appeared atomic.Int64
+	vanished atomic.Int64
 
 	// sf deduplicates concurrent cache-miss lookups for the same key.
 	// Kyverno issues 3 admission calls per PVC (one mutate, two validate),
@@ -37,16 +52,77 @@ type CachedClient struct {
 	// the upstream Kopia catalog; the others wait for and share the result.
 	sf           singleflight.Group
 	dedupedCalls atomic.Int64
+
+	// scanner, when set, lets CheckBackupExistsBatch resolve many misses
+	// with one repository scan instead of one backend call each.
+	scanner Scanner
+	// summaryBackend labels entries built from scans and pre-warms, which
+	// have no backend result to copy the label from.
+	summaryBackend string
 }
 
-// New creates a cached wrapper around a backend client.
+// Scanner is a whole-repository scan summarized per "namespace/pvc"
+// key. kopia.SourceSummarizer satisfies it; it is restated here so the
+// cache does not depend on the kopia package.
+type Scanner interface {
+	SummarizeAllSources(ctx context.Context) (map[string]backend.SnapshotSummary, error)
+}
+
+// batchScanMin is the number of scannable misses at which a batch pays
+// for a full repository scan instead of individual lookups. One miss is
+// cheaper to look up on its own.
+const batchScanMin = 2
+
+// scanFlightKey is the singleflight key for batch scans. The NUL byte
+// keeps it out of the "namespace/pvc" key space.
+const scanFlightKey = "\x00scan"
+
+// New creates a cached wrapper around a backend client. ttl applies to
+// every answer until SetNegativeTTL gives "missing" answers their own.
 func New(inner BackendClient, ttl time.Duration, logger *slog.Logger) *CachedClient {
 	return &CachedClient{
-		inner:  inner,
-		ttl:    ttl,
-		logger: logger,
-		items:  make(map[string]entry),
+		inner:       inner,
+		ttl:         ttl,
+		negativeTTL: ttl,
+		logger:      logger,
+		items:       make(map[string]entry),
+
+		summaryBackend: backend.TypeKopiaS3,
+	}
+}
+
+// SetSummaryBackend sets the CheckResult.Backend label of entries built
+// from scans and pre-warms — backend.TypeKopiaS3 unless the summaries come
+// from a kopia-fs repository. Call before serving traffic.
+func (c *CachedClient) SetSummaryBackend(typ string) {
+	c.summaryBackend = typ
+}
+
+// SetNegativeTTL sets how long a "no backup" answer is cached. Zero
+// disables caching of negative answers: every miss goes to the backend.
+// Call before serving traffic.
+func (c *CachedClient) SetNegativeTTL(ttl time.Duration) {
+	c.negativeTTL = ttl
+}
+
+func (c *CachedClient) ttlFor(exists bool) time.Duration {
+	if exists {
+		return c.ttl
 	}
+	return c.negativeTTL
+}
+
+// LineagesAppeared returns how many keys gained backups between
+// consecutive scans. Exposed for Prometheus.
+func (c *CachedClient) LineagesAppeared() int64 {
+	return c.appeared.Load()
+}
+
+// LineagesVanished returns how many keys that had backups in one scan
+// had none in the next. Exposed for Prometheus; anything above zero
+// deserves a look.
+func (c *CachedClient) LineagesVanished() int64 {
+	return c.vanished.Load()
 }
 
 // DedupedCalls returns the number of /exists lookups that were served by a
@@ -56,49 +132,139 @@ func (c *CachedClient) DedupedCalls() int64 {
 	return c.dedupedCalls.Load()
 }
 
+// SetScanner enables scan-backed batch lookups (see
+// CheckBackupExistsBatch). Call before serving traffic.
+func (c *CachedClient) SetScanner(s Scanner) {
+	c.scanner = s
+}
+
 // PreWarm populates the cache with known backup sources.
 // Keys in the map are "namespace/pvc", values are whether a backup exists.
 // Entries already in the cache are overwritten; entries not present in
 // `sources` are left untouched and continue to age out via TTL. Use this
 // for one-shot warming at startup; use Refresh for the periodic loop.
+//
+// Entries warmed this way carry no snapshot metadata; prefer
+// PreWarmSummaries when the backend can supply it.
 func (c *CachedClient) PreWarm(sources map[string]bool) {
+	c.apply(c.boolEntries(sources), false)
+}
+
+// PreWarmSummaries is PreWarm for per-source snapshot summaries (see
+// kopia.Client.SummarizeAllSources): a key exists when its summary has at
+// least one snapshot, and the cached result carries the latest-snapshot
+// time and size so cache hits answer freshness questions too.
+func (c *CachedClient) PreWarmSummaries(sources map[string]backend.SnapshotSummary) {
+	c.apply(c.summaryEntries(sources), false)
+}
+
+// Refresh applies a full scan to the cache. Keys in `sources` are
+// upserted with a new TTL; default-identity entries the scan does not
+// list are evicted immediately rather than left to age out, so deleted
+// backups stop returning stale exists=true. Identity-override entries,
+// which no scan keys, keep their own TTL. This is the periodic re-warm
+// path.
+func (c *CachedClient) Refresh(sources map[string]bool) {
+	c.apply(c.boolEntries(sources), true)
+}
+
+// RefreshSummaries is Refresh for per-source snapshot summaries.
+func (c *CachedClient) RefreshSummaries(sources map[string]backend.SnapshotSummary) {
+	c.apply(c.summaryEntries(sources), true)
+}
+
+// apply merges one full scan's entries into the cache, evicting the
+// default-identity entries it does not list when evict is set, and diffs
+// the scan's positive keys against the previous scan's.
+func (c *CachedClient) apply(items map[string]entry, evict bool) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	evicted := 0
+	if evict {
+		for key, e := range c.items {
+			if _, ok := items[key]; !ok && !e.override {
+				delete(c.items, key)
+				evicted++
+			}
+		}
+	}
+	for key, e := range items {
+		c.items[key] = e
+	}
+	appeared, vanished := c.diffScanLocked(items)
+	total := len(c.items)
+	c.mu.Unlock()
 
-	expiry := time.Now().Add(c.ttl)
-	for key, exists := range sources {
-		if e, ok := buildEntry(key, exists, expiry); ok {
-			c.items[key] = e
+	for _, key := range vanished {
+		// Backups do not disappear on their own: retention keeps at least
+		// the latest snapshot. A vanished lineage is a deleted repository
+		// path, a wrong bucket, or a broken reader — page someone.
+		c.logger.Error("backup lineage vanished between scans", "key", key)
+	}
+	for _, key := range appeared {
+		c.logger.Info("backup lineage appeared", "key", key)
+	}
+	c.logger.Info("cache refreshed", "entries", total, "scanned", len(items),
+		"evicted", evicted, "appeared", len(appeared), "vanished", len(vanished))
+}
+
+// diffScanLocked records the scan's positive keys as the new baseline
+// and returns the keys that gained or lost backups since the previous
+// scan. The first scan only sets the baseline. Caller holds c.mu.
+func (c *CachedClient) diffScanLocked(items map[string]entry) (appeared, vanished []string) {
+	positives := make(map[string]bool, len(items))
+	for key, e := range items {
+		if e.result.Exists {
+			positives[key] = true
 		}
 	}
-	c.logger.Info("cache pre-warmed", "entries", len(c.items))
+	if c.lastScan != nil {
+		for key := range positives {
+			if !c.lastScan[key] {
+				appeared = append(appeared, key)
+			}
+		}
+		for key := range c.lastScan {
+			if !positives[key] {
+				vanished = append(vanished, key)
+			}
+		}
+		sort.Strings(appeared)
+		sort.Strings(vanished)
+		c.appeared.Add(int64(len(appeared)))
+		c.vanished.Add(int64(len(vanished)))
+	}
+	c.lastScan = positives
+	return appeared, vanished
 }
 
-// Refresh replaces the entire cache with entries built from `sources`.
-// Unlike PreWarm, entries that are absent from `sources` are evicted
-// immediately rather than left to age out. This is the periodic
-// re-warm path: call it on a ticker so deleted backups stop returning
-// stale exists=true after their TTL would have hidden the change.
-func (c *CachedClient) Refresh(sources map[string]bool) {
-	expiry := time.Now().Add(c.ttl)
-	newItems := make(map[string]entry, len(sources))
+func (c *CachedClient) boolEntries(sources map[string]bool) map[string]entry {
+	now := time.Now()
+	items := make(map[string]entry, len(sources))
 	for key, exists := range sources {
-		if e, ok := buildEntry(key, exists, expiry); ok {
-			newItems[key] = e
+		if e, ok := c.buildEntry(key, exists, backend.SnapshotSummary{}, now); ok {
+			items[key] = e
 		}
 	}
+	return items
+}
 
-	c.mu.Lock()
-	c.items = newItems
-	c.mu.Unlock()
-
-	c.logger.Info("cache refreshed", "entries", len(newItems))
+func (c *CachedClient) summaryEntries(sources map[string]backend.SnapshotSummary) map[string]entry {
+	now := time.Now()
+	items := make(map[string]entry, len(sources))
+	for key, sum := range sources {
+		if e, ok := c.buildEntry(key, sum.Count > 0, sum, now); ok {
+			items[key] = e
+		}
+	}
+	return items
 }
 
-// buildEntry parses a "namespace/pvc" key and constructs a cache entry.
+// buildEntry parses a "namespace/pvc" key and constructs a cache entry
+// expiring on the TTL for its answer. Warmed keys always name a PVC's
+// default identity, so the key doubles as the result's Identity.
 // Returns (entry, true) on success, or (zero, false) when the key is
 // malformed (missing slash, empty namespace, or empty pvc).
-func buildEntry(key string, exists bool, expiry time.Time) (entry, bool) {
+func (c *CachedClient) buildEntry(key string, exists bool, sum backend.SnapshotSummary, now time.Time) (entry, bool) {
 	var namespace, pvc string
 	for i := 0; i < len(key); i++ {
 		if key[i] == '/' {
@@ -110,29 +276,48 @@ func buildEntry(key string, exists bool, expiry time.Time) (entry, bool) {
 	if namespace == "" || pvc == "" {
 		return entry{}, false
 	}
-	return entry{
-		result: backend.CheckResult{
-			Exists:        exists,
-			Decision:      decisionForExists(exists),
-			Authoritative: true,
-			Namespace:     namespace,
-			Pvc:           pvc,
-			Backend:       backend.TypeKopiaS3,
-			Source:        pvc + "-backup@" + namespace + ":/data",
-		},
-		expiresAt: expiry,
-	}, true
+	result := backend.CheckResult{
+		Exists:        exists,
+		Decision:      decisionForExists(exists),
+		Authoritative: true,
+		Namespace:     namespace,
+		Pvc:           pvc,
+		Backend:       c.summaryBackend,
+		Identity:      key,
+	}
+	// Source comes from the summary: the lineage (legacy or v4) that holds
+	// the latest snapshot. Bare PreWarm/Refresh entries leave it empty.
+	result.ApplySummary(sum)
+	return entry{result: result, cachedAt: now, expiresAt: now.Add(c.ttlFor(exists))}, true
 }
 
-func (c *CachedClient) CheckBackupExists(ctx context.Context, namespace, pvc string) backend.CheckResult {
-	key := namespace + "/" + pvc
+// hitLocked returns the entry's result stamped with its age, or false when
+// the entry is absent or expired. Caller holds c.mu (read).
+func (c *CachedClient) hitLocked(key string, now time.Time) (backend.CheckResult, bool) {
+	e, ok := c.items[key]
+	if !ok || !now.Before(e.expiresAt) {
+		return backend.CheckResult{}, false
+	}
+	result := e.result
+	age := int64(now.Sub(e.cachedAt).Seconds())
+	result.CacheAgeSeconds = &age
+	return result, true
+}
+
+// CheckBackupExists answers from the cache when it can. Entries are keyed
+// on Query.Key, so a PVC checked under an identity override never shares
+// an entry with its default identity; overrides are never pre-warmed and
+// always start with a live lookup.
+func (c *CachedClient) CheckBackupExists(ctx context.Context, q backend.Query) backend.CheckResult {
+	key := q.Key()
+	namespace, pvc := q.Namespace, q.PVC
 
 	// Check cache
 	c.mu.RLock()
-	if e, ok := c.items[key]; ok && time.Now().Before(e.expiresAt) {
+	if result, ok := c.hitLocked(key, time.Now()); ok {
 		c.mu.RUnlock()
-		c.logger.Debug("cache hit", "namespace", namespace, "pvc", pvc, "exists", e.result.Exists)
-		return e.result
+		c.logger.Debug("cache hit", "namespace", namespace, "pvc", pvc, "exists", result.Exists, "age_seconds", *result.CacheAgeSeconds)
+		return result
 	}
 	c.mu.RUnlock()
 
@@ -144,12 +329,14 @@ func (c *CachedClient) CheckBackupExists(ctx context.Context, namespace, pvc str
 	var executed bool
 	v, _, _ := c.sf.Do(key, func() (any, error) {
 		executed = true
-		result := c.inner.CheckBackupExists(ctx, namespace, pvc)
+		result := c.inner.CheckBackupExists(ctx, q)
 
-		// Only cache successful checks (no errors)
-		if result.Error == "" && result.Authoritative {
+		// Only cache successful checks (no errors), each on the TTL for
+		// its answer.
+		if ttl := c.ttlFor(result.Exists); result.Error == "" && result.Authoritative && ttl > 0 {
+			now := time.Now()
 			c.mu.Lock()
-			c.items[key] = entry{result: result, expiresAt: time.Now().Add(c.ttl)}
+			c.items[key] = entry{result: result, cachedAt: now, expiresAt: now.Add(ttl), override: q.Overridden()}
 			c.mu.Unlock()
 		}
 		return result, nil
@@ -176,6 +363,94 @@ func (c *CachedClient) CheckBackupExists(ctx context.Context, namespace, pvc str
 	return result
 }
 
+// CheckBackupExistsBatch answers many queries at once, in order. Cache
+// hits are served as usual. When a scanner is set and at least
+// batchScanMin misses are on their PVC's default identity, those misses
+// are resolved from one repository scan: a key the scan summarizes has
+// backups, a key it does not is fresh, and every scanned key is merged
+// into the cache. Identity overrides — which the scan does not key — and
+// all misses when the scan fails or no scanner is set fall through to
+// CheckBackupExists one by one.
+func (c *CachedClient) CheckBackupExistsBatch(ctx context.Context, qs []backend.Query) []backend.CheckResult {
+	results := make([]backend.CheckResult, len(qs))
+	var misses []int
+	scannable := 0
+	now := time.Now()
+	c.mu.RLock()
+	for i, q := range qs {
+		if result, ok := c.hitLocked(q.Key(), now); ok {
+			results[i] = result
+			continue
+		}
+		misses = append(misses, i)
+		if !q.Overridden() {
+			scannable++
+		}
+	}
+	c.mu.RUnlock()
+
+	var scanned map[string]entry
+	if c.scanner != nil && scannable >= batchScanMin {
+		sums, err := c.scan(ctx)
+		if err != nil {
+			c.logger.Warn("batch scan failed, checking misses individually", "misses", len(misses), "error", err)
+		} else {
+			scanned = c.scannedEntries(qs, misses, sums)
+		}
+	}
+
+	for _, i := range misses {
+		if e, ok := scanned[qs[i].Key()]; ok {
+			results[i] = e.result
+			continue
+		}
+		results[i] = c.CheckBackupExists(ctx, qs[i])
+	}
+	c.logger.Debug("batch check complete", "queries", len(qs), "misses", len(misses), "scanned", scanned != nil)
+	return results
+}
+
+// scan runs one scanner pass, collapsing concurrent batches onto it.
+func (c *CachedClient) scan(ctx context.Context) (map[string]backend.SnapshotSummary, error) {
+	v, err, _ := c.sf.Do(scanFlightKey, func() (any, error) {
+		return c.scanner.SummarizeAllSources(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sums, ok := v.(map[string]backend.SnapshotSummary)
+	if !ok {
+		return nil, fmt.Errorf("scan returned unexpected type %T", v)
+	}
+	return sums, nil
+}
+
+// scannedEntries merges a scan into the cache — diffed like any other
+// full scan, but without evicting — and returns the entries for the
+// batch's default-identity misses. A miss the scan did not summarize
+// is cached as fresh: the scan covered the whole repository.
+func (c *CachedClient) scannedEntries(qs []backend.Query, misses []int, sums map[string]backend.SnapshotSummary) map[string]entry {
+	now := time.Now()
+	items := c.summaryEntries(sums)
+	out := make(map[string]entry, len(misses))
+	for _, i := range misses {
+		if qs[i].Overridden() {
+			continue
+		}
+		key := qs[i].Key()
+		e, ok := items[key]
+		if !ok {
+			if e, ok = c.buildEntry(key, false, backend.SnapshotSummary{}, now); !ok {
+				continue
+			}
+			items[key] = e
+		}
+		out[key] = e
+	}
+	c.apply(items, false)
+	return out
+}
+
 func decisionForExists(exists bool) string {
 	if exists {
 		return backend.DecisionRestore
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"io"
 	"log/slog"
 	"sync"
@@ -25,11 +26,12 @@ type fakeBackend struct {
 	result backend.CheckResult
 }
 
-func (f *fakeBackend) CheckBackupExists(_ context.Context, namespace, pvc string) backend.CheckResult {
+func (f *fakeBackend) CheckBackupExists(_ context.Context, q backend.Query) backend.CheckResult {
 	f.calls.Add(1)
 	r := f.result
-	r.Namespace = namespace
-	r.Pvc = pvc
+	r.Namespace = q.Namespace
+	r.Pvc = q.PVC
+	r.Identity = q.Identity.Key()
 	return r
 }
 
@@ -135,7 +137,7 @@ func TestCheckBackupExists_ServesFromRefreshedCache(t *testing.T) {
 	c.Refresh(map[string]bool{testKey: true})
 
 	// Cache hit — backend should NOT be called.
-	res := c.CheckBackupExists(context.Background(), "app-a", "data")
+	res := c.CheckBackupExists(context.Background(), backend.NewQuery("app-a", "data", ""))
 	if !res.Exists {
 		t.Errorf("Refresh wrote exists=true, got false")
 	}
@@ -154,12 +156,12 @@ type blockingBackend struct {
 	result  backend.CheckResult
 }
 
-func (b *blockingBackend) CheckBackupExists(_ context.Context, namespace, pvc string) backend.CheckResult {
+func (b *blockingBackend) CheckBackupExists(_ context.Context, q backend.Query) backend.CheckResult {
 	b.calls.Add(1)
 	<-b.release
 	r := b.result
-	r.Namespace = namespace
-	r.Pvc = pvc
+	r.Namespace = q.Namespace
+	r.Pvc = q.PVC
 	return r
 }
 
@@ -185,7 +187,7 @@ func TestCheckBackupExists_SingleflightDedupsConcurrentLookups(t *testing.T) {
 	for i := 0; i < callers; i++ {
 		go func() {
 			defer wg.Done()
-			res := c.CheckBackupExists(context.Background(), "ns", "data")
+			res := c.CheckBackupExists(context.Background(), backend.NewQuery("ns", "data", ""))
 			if !res.Exists {
 				t.Errorf("expected exists=true from shared result, got false")
 			}
@@ -218,8 +220,255 @@ func TestCheckBackupExists_FallsThroughOnEvictedEntry(t *testing.T) {
 	c.Refresh(map[string]bool{}) // evict everything
 
 	// Cache miss after eviction — backend MUST be called.
-	c.CheckBackupExists(context.Background(), "app-a", "data")
+	c.CheckBackupExists(context.Background(), backend.NewQuery("app-a", "data", ""))
 	if got := bk.calls.Load(); got != 1 {
 		t.Errorf("backend was called %d times after eviction, want 1", got)
 	}
 }
+
+func TestRefreshSummaries_CachesSnapshotMetadata(t *testing.T) {
+	bk := &fakeBackend{}
+	c := New(bk, time.Minute, discardLogger())
+	latest := time.Date(2026, 6, 1, 3, 0, 0, 0, time.UTC)
+
+	c.PreWarmSummaries(map[string]backend.SnapshotSummary{
+		"app-b/data": {Count: 1, LatestAt: latest.Add(-time.Hour), LatestSize: 1},
+	})
+	c.RefreshSummaries(map[string]backend.SnapshotSummary{
+		testKey: {Count: 3, LatestAt: latest, LatestSize: 4096, Source: "data@app-a:/data"},
+	})
+
+	res := c.CheckBackupExists(context.Background(), backend.NewQuery("app-a", "data", ""))
+	if bk.calls.Load() != 0 {
+		t.Fatalf("refreshed key should be a cache hit, backend called %d times", bk.calls.Load())
+	}
+	if !res.Exists || res.Decision != backend.DecisionRestore {
+		t.Errorf("exists/decision: %+v", res)
+	}
+	if res.SnapshotCount != 3 || !res.LatestSnapshotAt.Equal(latest) || res.LatestSnapshotSize != 4096 {
+		t.Errorf("snapshot metadata not cached: %+v", res)
+	}
+	if res.Source != "data@app-a:/data" || res.Identity != testKey {
+		t.Errorf("source/identity: %+v", res)
+	}
+	if _, ok := c.items["app-b/data"]; ok {
+		t.Error("RefreshSummaries must evict keys absent from the new set")
+	}
+}
+
+// TestSetSummaryBackend_LabelsScannedEntries pins that entries built from a
+// kopia-fs scan say kopia-fs, not the kopia-s3 default.
+func TestSetSummaryBackend_LabelsScannedEntries(t *testing.T) {
+	bk := &fakeBackend{}
+	c := New(bk, time.Minute, discardLogger())
+	c.SetSummaryBackend(backend.TypeKopiaFS)
+	c.RefreshSummaries(map[string]backend.SnapshotSummary{testKey: {Count: 1}})
+
+	res := c.CheckBackupExists(context.Background(), backend.NewQuery("app-a", "data", ""))
+	if bk.calls.Load() != 0 || res.Backend != backend.TypeKopiaFS {
+		t.Errorf("backend calls %d, label %q, want a kopia-fs cache hit", bk.calls.Load(), res.Backend)
+	}
+}
+
+// TestCheckBackupExists_OverrideNeverSharesDefaultEntry pins Query.Key as
+// the cache key: a warmed default-identity entry must not answer for the
+// same PVC checked under a backup-identity override, and the override's
+// live result is cached under its own key.
+func TestCheckBackupExists_OverrideNeverSharesDefaultEntry(t *testing.T) {
+	bk := &fakeBackend{result: backend.CheckResult{Decision: backend.DecisionFresh, Authoritative: true}}
+	c := New(bk, time.Minute, discardLogger())
+	c.Refresh(map[string]bool{testKey: true})
+
+	q := backend.NewQuery("app-a", "data", "shared-identity")
+	res := c.CheckBackupExists(context.Background(), q)
+	if res.Exists || res.Identity != "shared-identity" || bk.calls.Load() != 1 {
+		t.Fatalf("override served from the default entry: %+v (calls %d)", res, bk.calls.Load())
+	}
+	_ = c.CheckBackupExists(context.Background(), q)
+	if bk.calls.Load() != 1 {
+		t.Errorf("override result not cached: calls %d", bk.calls.Load())
+	}
+	if res := c.CheckBackupExists(context.Background(), backend.NewQuery("app-a", "data", "")); !res.Exists {
+		t.Errorf("default entry clobbered: %+v", res)
+	}
+	if _, ok := c.items["app-a/data#shared-identity"]; !ok {
+		t.Errorf("keys: %v", c.items)
+	}
+}
+
+// fakeScanner is a Scanner returning a fixed summary map.
+type fakeScanner struct {
+	sums  map[string]backend.SnapshotSummary
+	err   error
+	calls atomic.Int64
+}
+
+func (f *fakeScanner) SummarizeAllSources(context.Context) (map[string]backend.SnapshotSummary, error) {
+	f.calls.Add(1)
+	return f.sums, f.err
+}
+
+// TestCheckBackupExistsBatch_ScansOnceForMisses pins the batch contract:
+// hits come from the cache, default-identity misses from one scan (keys
+// it lacks are fresh), overrides from the backend, all in request order.
+func TestCheckBackupExistsBatch_ScansOnceForMisses(t *testing.T) {
+	bk := &fakeBackend{result: backend.CheckResult{Exists: true, Decision: backend.DecisionRestore, Authoritative: true}}
+	sc := &fakeScanner{sums: map[string]backend.SnapshotSummary{
+		"app-b/data": {Count: 2, LatestSize: 7, Source: "data@app-b:/data"},
+	}}
+	c := New(bk, time.Minute, discardLogger())
+	c.SetScanner(sc)
+	c.Refresh(map[string]bool{testKey: true})
+
+	qs := []backend.Query{
+		backend.NewQuery("app-a", "data", ""),              // hit
+		backend.NewQuery("app-b", "data", ""),              // scanned, exists
+		backend.NewQuery("app-c", "data", ""),              // scanned, fresh
+		backend.NewQuery("app-d", "data", "shared-backup"), // override → backend
+	}
+	got := c.CheckBackupExistsBatch(context.Background(), qs)
+	if len(got) != 4 {
+		t.Fatalf("results: %+v", got)
+	}
+	if !got[0].Exists || got[0].Pvc != "data" || got[0].Namespace != "app-a" {
+		t.Errorf("hit: %+v", got[0])
+	}
+	if !got[1].Exists || got[1].SnapshotCount != 2 || got[1].Source != "data@app-b:/data" || !got[1].Authoritative {
+		t.Errorf("scanned: %+v", got[1])
+	}
+	if got[2].Exists || got[2].Decision != backend.DecisionFresh || !got[2].Authoritative || got[2].Namespace != "app-c" {
+		t.Errorf("scanned fresh: %+v", got[2])
+	}
+	if got[3].Identity != "shared-backup" || got[3].Namespace != "app-d" {
+		t.Errorf("override: %+v", got[3])
+	}
+	if sc.calls.Load() != 1 || bk.calls.Load() != 1 {
+		t.Errorf("scans %d, backend calls %d; want 1 and 1", sc.calls.Load(), bk.calls.Load())
+	}
+
+	// The scan was merged into the cache: a repeat is all hits.
+	_ = c.CheckBackupExistsBatch(context.Background(), qs)
+	if sc.calls.Load() != 1 || bk.calls.Load() != 1 {
+		t.Errorf("repeat batch: scans %d, backend calls %d", sc.calls.Load(), bk.calls.Load())
+	}
+}
+
+func TestCheckBackupExistsBatch_SingleMissSkipsScan(t *testing.T) {
+	bk := &fakeBackend{result: backend.CheckResult{Decision: backend.DecisionFresh, Authoritative: true}}
+	sc := &fakeScanner{}
+	c := New(bk, time.Minute, discardLogger())
+	c.SetScanner(sc)
+
+	_ = c.CheckBackupExistsBatch(context.Background(), []backend.Query{backend.NewQuery("app-a", "data", "")})
+	if sc.calls.Load() != 0 || bk.calls.Load() != 1 {
+		t.Errorf("scans %d, backend calls %d; want 0 and 1", sc.calls.Load(), bk.calls.Load())
+	}
+}
+
+func TestCheckBackupExistsBatch_ScanErrorFallsBack(t *testing.T) {
+	bk := &fakeBackend{result: backend.CheckResult{Decision: backend.DecisionFresh, Authoritative: true}}
+	sc := &fakeScanner{err: errors.New("kopia snapshot list failed")}
+	c := New(bk, time.Minute, discardLogger())
+	c.SetScanner(sc)
+
+	got := c.CheckBackupExistsBatch(context.Background(), []backend.Query{
+		backend.NewQuery("app-a", "data", ""),
+		backend.NewQuery("app-b", "data", ""),
+	})
+	if bk.calls.Load() != 2 || got[0].Namespace != "app-a" || got[1].Namespace != "app-b" {
+		t.Errorf("fallback: calls %d, results %+v", bk.calls.Load(), got)
+	}
+}
+
+func TestNegativeTTL_ShorterForMissingAnswers(t *testing.T) {
+	c := New(&fakeBackend{}, time.Minute, discardLogger())
+	c.SetNegativeTTL(5 * time.Second)
+
+	before := time.Now()
+	c.Refresh(map[string]bool{testKey: true, "app-b/data": false})
+	if ttl := c.items[testKey].expiresAt.Sub(before); ttl < 59*time.Second {
+		t.Errorf("positive TTL = %v, want ~1m", ttl)
+	}
+	if ttl := c.items["app-b/data"].expiresAt.Sub(before); ttl > 6*time.Second {
+		t.Errorf("negative TTL = %v, want ~5s", ttl)
+	}
+}
+
+func TestNegativeTTL_ZeroNeverCachesMissing(t *testing.T) {
+	bk := &fakeBackend{result: backend.CheckResult{Decision: backend.DecisionFresh, Authoritative: true}}
+	c := New(bk, time.Minute, discardLogger())
+	c.SetNegativeTTL(0)
+
+	q := backend.NewQuery("app-a", "data", "")
+	_ = c.CheckBackupExists(context.Background(), q)
+	_ = c.CheckBackupExists(context.Background(), q)
+	if got := bk.calls.Load(); got != 2 {
+		t.Errorf("backend calls = %d, want 2 (negative answers uncached)", got)
+	}
+
+	bk.result = backend.CheckResult{Exists: true, Decision: backend.DecisionRestore, Authoritative: true}
+	_ = c.CheckBackupExists(context.Background(), q)
+	_ = c.CheckBackupExists(context.Background(), q)
+	if got := bk.calls.Load(); got != 3 {
+		t.Errorf("backend calls = %d, want 3 (positive answer cached)", got)
+	}
+}
+
+func TestCheckBackupExists_CacheAge(t *testing.T) {
+	bk := &fakeBackend{result: backend.CheckResult{Exists: true, Decision: backend.DecisionRestore, Authoritative: true}}
+	c := New(bk, time.Minute, discardLogger())
+	q := backend.NewQuery("app-a", "data", "")
+
+	if live := c.CheckBackupExists(context.Background(), q); live.CacheAgeSeconds != nil {
+		t.Errorf("live answer carries a cache age: %d", *live.CacheAgeSeconds)
+	}
+	e := c.items[testKey]
+	e.cachedAt = e.cachedAt.Add(-42 * time.Second)
+	c.items[testKey] = e
+	hit := c.CheckBackupExists(context.Background(), q)
+	if hit.CacheAgeSeconds == nil || *hit.CacheAgeSeconds != 42 {
+		t.Errorf("cache age: %v", hit.CacheAgeSeconds)
+	}
+	if c.items[testKey].result.CacheAgeSeconds != nil {
+		t.Error("stamping the age mutated the cached entry")
+	}
+}
+
+// TestRefresh_DiffsScans pins change detection: the first scan is the
+// baseline, later scans count keys gaining or losing backups, and a
+// vanished key is evicted.
+func TestRefresh_DiffsScans(t *testing.T) {
+	c := New(&fakeBackend{}, time.Minute, discardLogger())
+
+	c.PreWarmSummaries(map[string]backend.SnapshotSummary{testKey: {Count: 3}, "app-b/data": {Count: 1}})
+	if c.LineagesAppeared() != 0 || c.LineagesVanished() != 0 {
+		t.Fatalf("baseline scan counted changes: +%d -%d", c.LineagesAppeared(), c.LineagesVanished())
+	}
+
+	c.RefreshSummaries(map[string]backend.SnapshotSummary{testKey: {Count: 4}, "app-c/data": {Count: 1}})
+	if c.LineagesAppeared() != 1 || c.LineagesVanished() != 1 {
+		t.Errorf("after refresh: +%d -%d, want +1 -1", c.LineagesAppeared(), c.LineagesVanished())
+	}
+	if _, ok := c.items["app-b/data"]; ok {
+		t.Error("vanished key still cached")
+	}
+
+	// An unchanged scan changes nothing.
+	c.RefreshSummaries(map[string]backend.SnapshotSummary{testKey: {Count: 4}, "app-c/data": {Count: 1}})
+	if c.LineagesAppeared() != 1 || c.LineagesVanished() != 1 {
+		t.Errorf("unchanged scan: +%d -%d", c.LineagesAppeared(), c.LineagesVanished())
+	}
+}
+
+func TestRefresh_KeepsOverrideEntries(t *testing.T) {
+	bk := &fakeBackend{result: backend.CheckResult{Exists: true, Decision: backend.DecisionRestore, Authoritative: true}}
+	c := New(bk, time.Minute, discardLogger())
+	q := backend.NewQuery("app-a", "data", "shared-identity")
+	_ = c.CheckBackupExists(context.Background(), q)
+
+	c.Refresh(map[string]bool{})
+	_ = c.CheckBackupExists(context.Background(), q)
+	if got := bk.calls.Load(); got != 1 {
+		t.Errorf("backend calls = %d, want 1 (override entry survives refresh)", got)
+	}
+}
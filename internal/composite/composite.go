@@ -0,0 +1,241 @@
+// Package composite answers backup-existence checks from several backends
+// at once — a repository migration (old restic bucket, new kopia repo) or
+// a primary repository with an offsite copy — and merges their answers
+// under an explicit policy.
+package composite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+)
+
+// Merge policies, the values of BACKEND_MERGE_POLICY.
+const (
+	// PolicyAnyExists answers "restore" as soon as any backend
+	// authoritatively holds a backup, and "fresh" only when every backend
+	// authoritatively holds none. The migration shape: a PVC backed up to
+	// either repository restores.
+	PolicyAnyExists = "any-exists"
+	// PolicyAllAgree answers only when every backend is authoritative and
+	// agrees; any disagreement or failure is unknown. The verification
+	// shape, for proving an offsite copy is complete before relying on it.
+	PolicyAllAgree = "all-must-agree"
+	// PolicyPrimary takes the first backend's answer and falls through to
+	// the next, in order, only while answers are not authoritative.
+	PolicyPrimary = "primary-with-fallback"
+)
+
+// Policies lists the valid merge policies, in documentation order.
+var Policies = []string{PolicyAnyExists, PolicyAllAgree, PolicyPrimary}
+
+// Checker matches handler.BackendClient; restated so this package does not
+// depend on the handler.
+type Checker interface {
+	CheckBackupExists(ctx context.Context, q backend.Query) backend.CheckResult
+}
+
+// healthChecker matches handler.HealthChecker.
+type healthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// Member is one backend of a Client, named for logs and health errors.
+type Member struct {
+	Name    string
+	Backend Checker
+}
+
+// Client fans a check out to every member concurrently and merges the
+// answers. Members are queried in parallel under every policy, so the
+// latency is the slowest member's and the per-backend results in
+// CheckResult.Backends are always complete — an operator diagnosing a
+// migration wants to see what each repository said, not only the one
+// that decided.
+type Client struct {
+	members []Member
+	policy  string
+	logger  *slog.Logger
+}
+
+// New constructs a composite over members, in precedence order. It needs
+// at least two members and a known policy.
+func New(members []Member, policy string, logger *slog.Logger) (*Client, error) {
+	if len(members) < 2 {
+		return nil, fmt.Errorf("composite backend needs at least 2 members, got %d", len(members))
+	}
+	switch policy {
+	case PolicyAnyExists, PolicyAllAgree, PolicyPrimary:
+	default:
+		return nil, fmt.Errorf("invalid merge policy %q (must be one of %s)", policy, strings.Join(Policies, ", "))
+	}
+	return &Client{members: members, policy: policy, logger: logger}, nil
+}
+
+// CheckBackupExists queries every member and merges the results under the
+// configured policy. The merged result carries Backend=composite and the
+// members' own results, in member order, in Backends.
+func (c *Client) CheckBackupExists(ctx context.Context, q backend.Query) backend.CheckResult {
+	results := make([]backend.CheckResult, len(c.members))
+	var wg sync.WaitGroup
+	for i, m := range c.members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = m.Backend.CheckBackupExists(ctx, q)
+		}()
+	}
+	wg.Wait()
+
+	var merged backend.CheckResult
+	switch c.policy {
+	case PolicyAllAgree:
+		merged = c.mergeAllAgree(q, results)
+	case PolicyPrimary:
+		merged = c.mergePrimary(q, results)
+	default:
+		merged = c.mergeAnyExists(q, results)
+	}
+	merged.Backends = results
+	c.logger.Debug("composite backup check complete",
+		"namespace", q.Namespace, "pvc", q.PVC,
+		"policy", c.policy, "decision", merged.Decision, "authoritative", merged.Authoritative)
+	return merged
+}
+
+// settled reports whether r is an answer a policy may act on.
+func settled(r backend.CheckResult) bool {
+	return r.Authoritative && r.Error == "" && r.Decision != backend.DecisionUnknown
+}
+
+// adopt labels a member's answer as the composite's. Snapshot metadata and
+// Source come from the member that decided.
+func adopt(q backend.Query, r backend.CheckResult) backend.CheckResult {
+	out := q.Result(backend.TypeComposite)
+	out.Exists, out.Decision, out.Authoritative = r.Exists, r.Decision, true
+	out.Source = r.Source
+	out.SnapshotCount, out.LatestSnapshotAt, out.LatestSnapshotSize = r.SnapshotCount, r.LatestSnapshotAt, r.LatestSnapshotSize
+	return out
+}
+
+// unknown is the merged answer when no policy outcome is safe.
+func unknown(q backend.Query, reason string) backend.CheckResult {
+	out := q.Result(backend.TypeComposite)
+	out.Decision = backend.DecisionUnknown
+	out.Error = reason
+	return out
+}
+
+// failures summarizes the members that did not settle, for Error.
+func (c *Client) failures(results []backend.CheckResult) string {
+	var parts []string
+	for i, r := range results {
+		if !settled(r) {
+			msg := r.Error
+			if msg == "" {
+				msg = "not authoritative"
+			}
+			parts = append(parts, c.members[i].Name+": "+msg)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// mergeAnyExists: the first member (in precedence order) that holds a
+// backup decides. Without one, "fresh" needs every member to have
+// settled — a member that failed might hold the only copy.
+func (c *Client) mergeAnyExists(q backend.Query, results []backend.CheckResult) backend.CheckResult {
+	for _, r := range results {
+		if settled(r) && r.Exists {
+			return adopt(q, r)
+		}
+	}
+	for _, r := range results {
+		if !settled(r) {
+			return unknown(q, "cannot rule out a backup: "+c.failures(results))
+		}
+	}
+	return adopt(q, results[0])
+}
+
+// mergeAllAgree: every member must settle on the same answer; the
+// primary's metadata is reported.
+func (c *Client) mergeAllAgree(q backend.Query, results []backend.CheckResult) backend.CheckResult {
+	for _, r := range results {
+		if !settled(r) {
+			return unknown(q, c.failures(results))
+		}
+	}
+	for _, r := range results[1:] {
+		if r.Exists != results[0].Exists {
+			return unknown(q, "backends disagree: "+c.agreement(results))
+		}
+	}
+	return adopt(q, results[0])
+}
+
+// agreement renders each member's answer, for the disagreement error.
+func (c *Client) agreement(results []backend.CheckResult) string {
+	parts := make([]string, len(results))
+	for i, r := range results {
+		parts[i] = fmt.Sprintf("%s exists=%t", c.members[i].Name, r.Exists)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// mergePrimary: the first settled member decides.
+func (c *Client) mergePrimary(q backend.Query, results []backend.CheckResult) backend.CheckResult {
+	for _, r := range results {
+		if settled(r) {
+			return adopt(q, r)
+		}
+	}
+	return unknown(q, c.failures(results))
+}
+
+// HealthCheck aggregates member health for /readyz. Under all-must-agree
+// and any-exists every member must be healthy: a failed member makes
+// every answer unknown under the first, and every "no backup" answer
+// unknown under the second, so a fresh PVC could never be decided. Under
+// primary-with-fallback one healthy member is enough to give
+// authoritative answers, so the composite stays ready and the failing
+// members are logged. Members without a health check count as healthy.
+func (c *Client) HealthCheck(ctx context.Context) error {
+	errs := make([]error, len(c.members))
+	var wg sync.WaitGroup
+	for i, m := range c.members {
+		hc, ok := m.Backend.(healthChecker)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := hc.HealthCheck(ctx); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", m.Name, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	joined := errors.Join(errs...)
+	if failed == 0 {
+		return nil
+	}
+	if c.policy != PolicyPrimary || failed == len(c.members) {
+		return joined
+	}
+	c.logger.Warn("composite backend degraded", "policy", c.policy, "error", joined)
+	return nil
+}
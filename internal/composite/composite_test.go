@@ -0,0 +1,152 @@
+package composite
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+)
+
+// stub answers every query with a fixed outcome.
+type stub struct {
+	typ       string
+	exists    bool
+	failed    bool
+	healthErr error
+	count     int
+}
+
+func (s stub) CheckBackupExists(_ context.Context, q backend.Query) backend.CheckResult {
+	r := q.Result(s.typ)
+	if s.failed {
+		r.Decision = backend.DecisionUnknown
+		r.Error = "listing failed"
+		return r
+	}
+	r.Exists, r.Authoritative = s.exists, true
+	r.Decision = backend.DecisionFresh
+	if s.exists {
+		r.Decision = backend.DecisionRestore
+		r.SnapshotCount = s.count
+		r.Source = s.typ + "-source"
+	}
+	return r
+}
+
+func (s stub) HealthCheck(context.Context) error { return s.healthErr }
+
+var (
+	has     = stub{typ: backend.TypeKopiaS3, exists: true, count: 3}
+	hasS3   = stub{typ: backend.TypeS3, exists: true, count: 1}
+	none    = stub{typ: backend.TypeKopiaS3}
+	noneS3  = stub{typ: backend.TypeS3}
+	broken  = stub{typ: backend.TypeKopiaS3, failed: true}
+	broken3 = stub{typ: backend.TypeS3, failed: true}
+)
+
+func newClient(t *testing.T, policy string, primary, secondary stub) *Client {
+	t.Helper()
+	c, err := New([]Member{{Name: "primary", Backend: primary}, {Name: "secondary", Backend: secondary}},
+		policy, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCheckBackupExists_Policies(t *testing.T) {
+	for _, tc := range []struct {
+		name               string
+		policy             string
+		primary, secondary stub
+		decision           string
+		source             string
+	}{
+		{"any: secondary holds it", PolicyAnyExists, none, hasS3, backend.DecisionRestore, "s3-source"},
+		{"any: primary wins precedence", PolicyAnyExists, has, hasS3, backend.DecisionRestore, "kopia-s3-source"},
+		{"any: exists despite a failure", PolicyAnyExists, broken, hasS3, backend.DecisionRestore, "s3-source"},
+		{"any: both empty", PolicyAnyExists, none, noneS3, backend.DecisionFresh, ""},
+		{"any: empty plus failure is unknown", PolicyAnyExists, none, broken3, backend.DecisionUnknown, ""},
+		{"agree: both hold it", PolicyAllAgree, has, hasS3, backend.DecisionRestore, "kopia-s3-source"},
+		{"agree: both empty", PolicyAllAgree, none, noneS3, backend.DecisionFresh, ""},
+		{"agree: disagreement is unknown", PolicyAllAgree, has, noneS3, backend.DecisionUnknown, ""},
+		{"agree: failure is unknown", PolicyAllAgree, has, broken3, backend.DecisionUnknown, ""},
+		{"primary: primary decides", PolicyPrimary, none, hasS3, backend.DecisionFresh, ""},
+		{"primary: falls back on unknown", PolicyPrimary, broken, hasS3, backend.DecisionRestore, "s3-source"},
+		{"primary: all unknown", PolicyPrimary, broken, broken3, backend.DecisionUnknown, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newClient(t, tc.policy, tc.primary, tc.secondary).
+				CheckBackupExists(context.Background(), backend.NewQuery("myapp", "data", ""))
+			if r.Decision != tc.decision || r.Source != tc.source {
+				t.Errorf("decision %q source %q, want %q %q (%+v)", r.Decision, r.Source, tc.decision, tc.source, r)
+			}
+			if r.Authoritative != (tc.decision != backend.DecisionUnknown) || (r.Error == "") != r.Authoritative {
+				t.Errorf("authoritative %v with error %q", r.Authoritative, r.Error)
+			}
+			if r.Backend != backend.TypeComposite || r.Namespace != "myapp" || r.Pvc != "data" || r.Identity != "myapp/data" {
+				t.Errorf("labels: %+v", r)
+			}
+			if len(r.Backends) != 2 || r.Backends[0].Backend != tc.primary.typ || r.Backends[1].Backend != tc.secondary.typ {
+				t.Errorf("per-backend results: %+v", r.Backends)
+			}
+		})
+	}
+}
+
+func TestCheckBackupExists_ErrorNamesMembers(t *testing.T) {
+	r := newClient(t, PolicyAllAgree, has, noneS3).CheckBackupExists(context.Background(), backend.NewQuery("myapp", "data", ""))
+	if !strings.Contains(r.Error, "primary exists=true") || !strings.Contains(r.Error, "secondary exists=false") {
+		t.Errorf("error %q", r.Error)
+	}
+	r = newClient(t, PolicyPrimary, broken, broken3).CheckBackupExists(context.Background(), backend.NewQuery("myapp", "data", ""))
+	if !strings.Contains(r.Error, "primary: listing failed") || !strings.Contains(r.Error, "secondary: listing failed") {
+		t.Errorf("error %q", r.Error)
+	}
+}
+
+func TestCheckBackupExists_CarriesPrimaryMetadata(t *testing.T) {
+	r := newClient(t, PolicyAllAgree, has, hasS3).CheckBackupExists(context.Background(), backend.NewQuery("myapp", "data", ""))
+	if r.SnapshotCount != 3 {
+		t.Errorf("SnapshotCount = %d, want the primary's 3", r.SnapshotCount)
+	}
+}
+
+func TestHealthCheck_Aggregation(t *testing.T) {
+	down := errors.New("bucket unreachable")
+	sick := stub{typ: backend.TypeS3, healthErr: down}
+	sickKopia := stub{typ: backend.TypeKopiaS3, healthErr: down}
+	for _, tc := range []struct {
+		policy             string
+		primary, secondary stub
+		wantErr            bool
+	}{
+		{PolicyAnyExists, none, noneS3, false},
+		{PolicyAnyExists, none, sick, true},
+		{PolicyPrimary, none, sick, false},
+		{PolicyAllAgree, none, sick, true},
+		{PolicyAnyExists, sickKopia, sick, true},
+	} {
+		err := newClient(t, tc.policy, tc.primary, tc.secondary).HealthCheck(context.Background())
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s primary=%v secondary=%v: err %v", tc.policy, tc.primary.healthErr, tc.secondary.healthErr, err)
+		}
+		if err != nil && !errors.Is(err, down) {
+			t.Errorf("%s: error %v does not wrap the member's", tc.policy, err)
+		}
+	}
+}
+
+func TestNew_Validates(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if _, err := New([]Member{{Name: "only", Backend: none}}, PolicyAnyExists, logger); err == nil {
+		t.Error("one member accepted")
+	}
+	if _, err := New([]Member{{Name: "a", Backend: none}, {Name: "b", Backend: noneS3}}, "majority", logger); err == nil {
+		t.Error("unknown policy accepted")
+	}
+}
@@ -3,10 +3,15 @@ package config
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mitchross/pvc-plumber/internal/backend"
+	"github.com/mitchross/pvc-plumber/internal/composite"
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+	"github.com/mitchross/pvc-plumber/internal/s3"
 )
 
 // Default values for log level and HTTP port. Centralized so the strings
@@ -16,14 +21,45 @@ const (
 	defaultPort     = "8080"
 )
 
+// defaultCacheNegativeTTL is the CACHE_NEGATIVE_TTL default: a quarter of
+// the default CACHE_TTL, so a backup written moments after a "missing"
+// answer is seen within seconds.
+const defaultCacheNegativeTTL = 15 * time.Second
+
+// KOPIA_READER values: how the kopia-s3 backend reads the repository.
+const (
+	// KopiaReaderCLI forks the kopia binary (`kopia snapshot list`) per
+	// query. The default, and the only reader before the native one.
+	KopiaReaderCLI = "cli"
+	// KopiaReaderNative reads the repository's blobs over S3 in-process
+	// (internal/kopia/native) — no kopia binary, no on-disk kopia config.
+	KopiaReaderNative = "native"
+	// KopiaReaderServer queries a kopia server's HTTP API over one
+	// keep-alive connection: an external server at KOPIA_SERVER_URL, or a
+	// loopback `kopia server start` the process supervises when unset.
+	KopiaReaderServer = "server"
+)
+
 type Config struct {
 	// Common settings
-	BackendType    string
-	HTTPTimeout    time.Duration
-	CacheTTL       time.Duration
-	ReWarmInterval time.Duration // 0 disables the periodic re-warm loop
-	Port           string
-	LogLevel       string
+	BackendType string
+	HTTPTimeout time.Duration
+	CacheTTL    time.Duration // how long a "backup exists" answer is cached
+	// CacheNegativeTTL is how long a "no backup" answer is cached. Shorter
+	// than CacheTTL by default: a stale "missing" lets a PVC provision
+	// empty over backups that just landed. 0 never caches it.
+	CacheNegativeTTL time.Duration
+	ReWarmInterval   time.Duration // 0 disables the periodic re-warm loop
+	Port             string
+	LogLevel         string
+
+	// Backends is the ordered member list of a composite backend, from
+	// BACKENDS (e.g. "kopia-s3,s3"); nil for a single backend. When set,
+	// BackendType is its first entry — the primary — and every member's
+	// settings are loaded. BackendMergePolicy (BACKEND_MERGE_POLICY,
+	// default composite.PolicyAnyExists) decides how their answers merge.
+	Backends           []string
+	BackendMergePolicy string
 
 	// S3 backend settings
 	S3Endpoint  string
@@ -32,6 +68,16 @@ type Config struct {
 	S3SecretKey string
 	S3Secure    bool
 
+	// S3Layout selects how the s3 backend maps a backup identity onto the
+	// bucket: s3.LayoutPrefix (default — any object under
+	// "<namespace>/<pvc>/"), s3.LayoutRestic (a restic repository per
+	// identity, counted from its snapshots/ objects) or s3.LayoutIndex (a
+	// JSON index object at S3IndexKey written by a backup hook). S3Prefix
+	// is prepended to every key consulted.
+	S3Layout   string
+	S3Prefix   string
+	S3IndexKey string
+
 	// Kopia (S3) backend settings. v3.0.0 removed the filesystem-backed
 	// kopia repo (KOPIA_REPOSITORY_PATH); the operator now connects to an
 	// S3-compatible backend (e.g. RustFS, MinIO) the same way VolSync mover
@@ -52,6 +98,12 @@ type Config struct {
 	KopiaS3SecretKey  string
 	KopiaS3DisableTLS bool
 
+	// KopiaFSPath is the filesystem repository directory for
+	// BACKEND_TYPE=kopia-fs (KOPIA_FS_PATH). That backend reads
+	// KopiaPassword from KOPIA_PASSWORD and shares KopiaReader /
+	// KopiaConnectTimeout with kopia-s3.
+	KopiaFSPath string
+
 	// KopiaCredentialsPath is the directory the operator reads kopia
 	// credentials from on each subprocess invocation. Defaults to
 	// `/var/secret/pvc-plumber-kopia` (matches the deployment.yaml
@@ -73,6 +125,43 @@ type Config struct {
 	// the calling reconcile.
 	KopiaConnectTimeout time.Duration
 
+	// KopiaReader is KopiaReaderCLI (default) or KopiaReaderNative, from
+	// KOPIA_READER. Both answer /exists identically; the native reader
+	// drops the per-query subprocess and keeps parsed index and manifest
+	// blobs in memory between calls.
+	KopiaReader string
+
+	// Kopia repository maintenance (kopia.Maintainer). Each interval is
+	// the cadence written into kopia's maintenance schedule for that
+	// mode, from KOPIA_MAINTENANCE_QUICK_INTERVAL /
+	// KOPIA_MAINTENANCE_FULL_INTERVAL; 0 (the default for both) leaves
+	// the mode disabled, and with both disabled no loop runs. Maintenance
+	// is claimed under KopiaMaintenanceOwner (KOPIA_MAINTENANCE_OWNER,
+	// "user@host", default kopia.DefaultMaintenanceOwner), over a kopia
+	// connection of its own in KopiaMaintenanceConfigFile
+	// (KOPIA_MAINTENANCE_CONFIG_FILE, default
+	// kopia.DefaultMaintenanceConfigFile). KopiaMaintenanceRunTimeout
+	// (KOPIA_MAINTENANCE_RUN_TIMEOUT) bounds one run; 0 leaves
+	// kopia.DefaultMaintenanceRunTimeout. Needs the CLI reader: the
+	// native reader is read-only and the server reader holds no client
+	// connection of its own.
+	KopiaMaintenanceQuickInterval time.Duration
+	KopiaMaintenanceFullInterval  time.Duration
+	KopiaMaintenanceOwner         string
+	KopiaMaintenanceConfigFile    string
+	KopiaMaintenanceRunTimeout    time.Duration
+
+	// Kopia server reader settings (KopiaReader == KopiaReaderServer).
+	// KopiaServerURL selects an external server reached with
+	// KopiaServerUsername / KopiaServerPassword; left empty, the process
+	// runs its own server on KopiaServerAddress (loopback) and generates
+	// the credentials itself. KopiaServerTimeout bounds each API call.
+	KopiaServerURL      string
+	KopiaServerUsername string
+	KopiaServerPassword string
+	KopiaServerAddress  string
+	KopiaServerTimeout  time.Duration
+
 	// ExternalSecret rendering knobs used by the PVC reconciler when it
 	// templates the per-PVC `volsync-<pvc>` ExternalSecret. Defaults pin to
 	// the reference cluster's 1Password Connect setup (vault item
@@ -123,9 +212,31 @@ func LoadWithOptions(opts LoadOptions) (*Config, error) {
 		if backendType == "" {
 			backendType = backend.TypeS3
 		}
-		if backendType != backend.TypeS3 && backendType != backend.TypeKopiaS3 {
-			return nil, fmt.Errorf("invalid BACKEND_TYPE: %s (must be %q or %q)",
-				backendType, backend.TypeS3, backend.TypeKopiaS3)
+		if !slices.Contains(backendTypes, backendType) {
+			return nil, fmt.Errorf("invalid BACKEND_TYPE: %s (must be %q, %q or %q)",
+				backendType, backend.TypeS3, backend.TypeKopiaS3, backend.TypeKopiaFS)
+		}
+	}
+
+	var backends []string
+	mergePolicy := ""
+	if v := os.Getenv("BACKENDS"); v != "" && !opts.SkipBackend {
+		var err error
+		if backends, err = parseBackends(v); err != nil {
+			return nil, err
+		}
+		// BACKEND_TYPE left at its default is fine; one that names a
+		// different primary is a conflicting config, not a tiebreak.
+		if explicit := os.Getenv("BACKEND_TYPE"); explicit != "" && explicit != backends[0] {
+			return nil, fmt.Errorf("BACKEND_TYPE=%s conflicts with BACKENDS=%s (the first entry is the primary)", explicit, v)
+		}
+		backendType = backends[0]
+		mergePolicy = os.Getenv("BACKEND_MERGE_POLICY")
+		if mergePolicy == "" {
+			mergePolicy = composite.PolicyAnyExists
+		}
+		if !slices.Contains(composite.Policies, mergePolicy) {
+			return nil, fmt.Errorf("invalid BACKEND_MERGE_POLICY: %s (must be one of %s)", mergePolicy, strings.Join(composite.Policies, ", "))
 		}
 	}
 
@@ -147,6 +258,22 @@ func LoadWithOptions(opts LoadOptions) (*Config, error) {
 		cacheTTL = duration
 	}
 
+	cacheNegativeTTL := defaultCacheNegativeTTL
+	if ttlStr := os.Getenv("CACHE_NEGATIVE_TTL"); ttlStr != "" {
+		duration, err := time.ParseDuration(ttlStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CACHE_NEGATIVE_TTL: %w", err)
+		}
+		if duration < 0 {
+			return nil, fmt.Errorf("CACHE_NEGATIVE_TTL must be >= 0, got %s", ttlStr)
+		}
+		cacheNegativeTTL = duration
+	}
+	// A "missing" answer never outlives an "exists" one.
+	if cacheNegativeTTL > cacheTTL {
+		cacheNegativeTTL = cacheTTL
+	}
+
 	reWarmInterval := 90 * time.Second
 	if intervalStr := os.Getenv("RE_WARM_INTERVAL"); intervalStr != "" {
 		duration, err := time.ParseDuration(intervalStr)
@@ -170,24 +297,33 @@ func LoadWithOptions(opts LoadOptions) (*Config, error) {
 	}
 
 	cfg := &Config{
-		BackendType:    backendType,
-		HTTPTimeout:    httpTimeout,
-		CacheTTL:       cacheTTL,
-		ReWarmInterval: reWarmInterval,
-		Port:           port,
-		LogLevel:       logLevel,
+		BackendType:        backendType,
+		Backends:           backends,
+		BackendMergePolicy: mergePolicy,
+		HTTPTimeout:        httpTimeout,
+		CacheTTL:           cacheTTL,
+		CacheNegativeTTL:   cacheNegativeTTL,
+		ReWarmInterval:     reWarmInterval,
+		Port:               port,
+		LogLevel:           logLevel,
 	}
 
 	// Backend-specific validation — skipped entirely when opts.SkipBackend.
 	if !opts.SkipBackend {
-		switch backendType {
-		case backend.TypeS3:
-			if err := loadS3Config(cfg); err != nil {
-				return nil, err
-			}
-		case backend.TypeKopiaS3:
-			if err := loadKopiaS3Config(cfg); err != nil {
-				return nil, err
+		for _, typ := range cfg.BackendTypes() {
+			switch typ {
+			case backend.TypeS3:
+				if err := loadS3Config(cfg); err != nil {
+					return nil, err
+				}
+			case backend.TypeKopiaS3:
+				if err := loadKopiaS3Config(cfg); err != nil {
+					return nil, err
+				}
+			case backend.TypeKopiaFS:
+				if err := loadKopiaFSConfig(cfg); err != nil {
+					return nil, err
+				}
 			}
 		}
 	}
@@ -201,6 +337,48 @@ func LoadWithOptions(opts LoadOptions) (*Config, error) {
 	return cfg, nil
 }
 
+// backendTypes are the valid BACKEND_TYPE / BACKENDS values.
+var backendTypes = []string{backend.TypeS3, backend.TypeKopiaS3, backend.TypeKopiaFS}
+
+func isKopia(typ string) bool {
+	return typ == backend.TypeKopiaS3 || typ == backend.TypeKopiaFS
+}
+
+// BackendTypes returns the backends to build, in precedence order: the
+// composite members, or just BackendType.
+func (c *Config) BackendTypes() []string {
+	if len(c.Backends) > 0 {
+		return c.Backends
+	}
+	return []string{c.BackendType}
+}
+
+// parseBackends validates a BACKENDS list. Each backend type has one set
+// of settings (S3_*, KOPIA_S3_*), so a type can appear once; a composite
+// of one member is just BACKEND_TYPE.
+func parseBackends(v string) ([]string, error) {
+	var out []string
+	for _, typ := range strings.Split(v, ",") {
+		typ = strings.TrimSpace(typ)
+		if !slices.Contains(backendTypes, typ) {
+			return nil, fmt.Errorf("invalid BACKENDS entry: %q (must be %q, %q or %q)", typ, backend.TypeS3, backend.TypeKopiaS3, backend.TypeKopiaFS)
+		}
+		if slices.Contains(out, typ) {
+			return nil, fmt.Errorf("BACKENDS lists %s twice", typ)
+		}
+		// Both kopia backends share KOPIA_READER, KOPIA_PASSWORD and the
+		// process's single kopia config, so they can't be members together.
+		if isKopia(typ) && slices.ContainsFunc(out, isKopia) {
+			return nil, fmt.Errorf("BACKENDS can list only one of %s and %s", backend.TypeKopiaS3, backend.TypeKopiaFS)
+		}
+		out = append(out, typ)
+	}
+	if len(out) < 2 {
+		return nil, fmt.Errorf("BACKENDS needs at least 2 entries, got %q (use BACKEND_TYPE for one)", v)
+	}
+	return out, nil
+}
+
 func loadS3Config(cfg *Config) error {
 	cfg.S3Endpoint = os.Getenv("S3_ENDPOINT")
 	if cfg.S3Endpoint == "" {
@@ -231,6 +409,19 @@ func loadS3Config(cfg *Config) error {
 		}
 	}
 
+	cfg.S3Layout = os.Getenv("S3_LAYOUT")
+	if cfg.S3Layout == "" {
+		cfg.S3Layout = s3.LayoutPrefix
+	}
+	if cfg.S3Layout != s3.LayoutPrefix && cfg.S3Layout != s3.LayoutRestic && cfg.S3Layout != s3.LayoutIndex {
+		return fmt.Errorf("invalid S3_LAYOUT: %s (must be %q, %q or %q)", cfg.S3Layout, s3.LayoutPrefix, s3.LayoutRestic, s3.LayoutIndex)
+	}
+	cfg.S3Prefix = os.Getenv("S3_PREFIX")
+	cfg.S3IndexKey = os.Getenv("S3_INDEX_KEY")
+	if cfg.S3IndexKey == "" {
+		cfg.S3IndexKey = s3.DefaultIndexKey
+	}
+
 	return nil
 }
 
@@ -294,16 +485,16 @@ func loadKopiaS3Config(cfg *Config) error {
 		cfg.KopiaCredentialsPath = "/var/secret/pvc-plumber-kopia"
 	}
 
-	cfg.KopiaConnectTimeout = 60 * time.Second
-	if v := os.Getenv("KOPIA_CONNECT_TIMEOUT"); v != "" {
-		d, err := time.ParseDuration(v)
-		if err != nil {
-			return fmt.Errorf("invalid KOPIA_CONNECT_TIMEOUT: %w", err)
-		}
-		if d <= 0 {
-			return fmt.Errorf("KOPIA_CONNECT_TIMEOUT must be > 0, got %s", v)
+	if err := loadKopiaReaderConfig(cfg); err != nil {
+		return err
+	}
+	if cfg.KopiaReader == KopiaReaderServer {
+		if err := loadKopiaServerConfig(cfg); err != nil {
+			return err
 		}
-		cfg.KopiaConnectTimeout = d
+	}
+	if err := loadKopiaMaintenanceConfig(cfg); err != nil {
+		return err
 	}
 
 	// Either path-based creds OR env-var creds must be available. The
@@ -327,6 +518,117 @@ func loadKopiaS3Config(cfg *Config) error {
 	return nil
 }
 
+// loadKopiaReaderConfig reads the settings every kopia backend shares:
+// KOPIA_CONNECT_TIMEOUT and KOPIA_READER.
+func loadKopiaReaderConfig(cfg *Config) error {
+	cfg.KopiaConnectTimeout = 60 * time.Second
+	if v := os.Getenv("KOPIA_CONNECT_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid KOPIA_CONNECT_TIMEOUT: %w", err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("KOPIA_CONNECT_TIMEOUT must be > 0, got %s", v)
+		}
+		cfg.KopiaConnectTimeout = d
+	}
+
+	cfg.KopiaReader = KopiaReaderCLI
+	if v := os.Getenv("KOPIA_READER"); v != "" {
+		if v != KopiaReaderCLI && v != KopiaReaderNative && v != KopiaReaderServer {
+			return fmt.Errorf("invalid KOPIA_READER: %s (must be %q, %q or %q)", v, KopiaReaderCLI, KopiaReaderNative, KopiaReaderServer)
+		}
+		cfg.KopiaReader = v
+	}
+	return nil
+}
+
+// loadKopiaFSConfig loads BACKEND_TYPE=kopia-fs: a kopia filesystem
+// repository at KOPIA_FS_PATH, opened with KOPIA_PASSWORD — the shape for
+// running the operator's backup-truth path on a laptop or in CI against a
+// `kopia repository create filesystem` temp directory, with no S3
+// endpoint. The path must exist at startup; a typo otherwise only shows
+// up as a connect failure. KOPIA_READER=server is not offered: the
+// managed server exists to spare an S3 round trip per query, which a
+// local directory doesn't pay.
+func loadKopiaFSConfig(cfg *Config) error {
+	cfg.KopiaFSPath = os.Getenv("KOPIA_FS_PATH")
+	if cfg.KopiaFSPath == "" {
+		return fmt.Errorf("KOPIA_FS_PATH is required for kopia-fs backend")
+	}
+	fi, err := os.Stat(cfg.KopiaFSPath)
+	if err != nil {
+		return fmt.Errorf("invalid KOPIA_FS_PATH: %w", err)
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("invalid KOPIA_FS_PATH: %s is not a directory", cfg.KopiaFSPath)
+	}
+
+	cfg.KopiaPassword = os.Getenv("KOPIA_PASSWORD")
+	if cfg.KopiaPassword == "" {
+		return fmt.Errorf("KOPIA_PASSWORD is required for kopia-fs backend")
+	}
+
+	if err := loadKopiaReaderConfig(cfg); err != nil {
+		return err
+	}
+	if cfg.KopiaReader == KopiaReaderServer {
+		return fmt.Errorf("KOPIA_READER=%s is not supported for kopia-fs backend (use %q or %q)", KopiaReaderServer, KopiaReaderCLI, KopiaReaderNative)
+	}
+	return loadKopiaMaintenanceConfig(cfg)
+}
+
+// loadKopiaMaintenanceConfig reads the KOPIA_MAINTENANCE_* knobs. Called
+// after loadKopiaReaderConfig, because maintenance is only offered on the
+// CLI reader.
+func loadKopiaMaintenanceConfig(cfg *Config) error {
+	for _, knob := range []struct {
+		env string
+		dst *time.Duration
+	}{
+		{"KOPIA_MAINTENANCE_QUICK_INTERVAL", &cfg.KopiaMaintenanceQuickInterval},
+		{"KOPIA_MAINTENANCE_FULL_INTERVAL", &cfg.KopiaMaintenanceFullInterval},
+		{"KOPIA_MAINTENANCE_RUN_TIMEOUT", &cfg.KopiaMaintenanceRunTimeout},
+	} {
+		v := os.Getenv(knob.env)
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", knob.env, err)
+		}
+		if d < 0 {
+			return fmt.Errorf("%s must be >= 0, got %s", knob.env, v)
+		}
+		*knob.dst = d
+	}
+
+	cfg.KopiaMaintenanceOwner = os.Getenv("KOPIA_MAINTENANCE_OWNER")
+	if cfg.KopiaMaintenanceOwner == "" {
+		cfg.KopiaMaintenanceOwner = kopia.DefaultMaintenanceOwner
+	}
+	user, host, ok := strings.Cut(cfg.KopiaMaintenanceOwner, "@")
+	if !ok || user == "" || host == "" {
+		return fmt.Errorf("invalid KOPIA_MAINTENANCE_OWNER: %q (want user@host)", cfg.KopiaMaintenanceOwner)
+	}
+	cfg.KopiaMaintenanceConfigFile = os.Getenv("KOPIA_MAINTENANCE_CONFIG_FILE")
+	if cfg.KopiaMaintenanceConfigFile == "" {
+		cfg.KopiaMaintenanceConfigFile = kopia.DefaultMaintenanceConfigFile
+	}
+
+	if cfg.KopiaMaintenanceEnabled() && cfg.KopiaReader != KopiaReaderCLI {
+		return fmt.Errorf("kopia maintenance needs KOPIA_READER=%s, got %q", KopiaReaderCLI, cfg.KopiaReader)
+	}
+	return nil
+}
+
+// KopiaMaintenanceEnabled reports whether any maintenance mode has a
+// cadence.
+func (c *Config) KopiaMaintenanceEnabled() bool {
+	return c.KopiaMaintenanceQuickInterval > 0 || c.KopiaMaintenanceFullInterval > 0
+}
+
 // loadExternalSecretsConfig reads the (optional, defaulted) env vars that
 // parameterize how the PVC reconciler renders each `volsync-<pvc>`
 // ExternalSecret. Defaults match the reference cluster's 1Password Connect
@@ -356,3 +658,35 @@ func loadExternalSecretsConfig(cfg *Config) {
 		cfg.ExternalSecretsS3SecretKeyProperty = "k8s-admin-secret-key"
 	}
 }
+
+// loadKopiaServerConfig reads the KOPIA_SERVER_* knobs of the server
+// reader. An external server needs its basic-auth password; the managed
+// one generates its own.
+func loadKopiaServerConfig(cfg *Config) error {
+	cfg.KopiaServerURL = os.Getenv("KOPIA_SERVER_URL")
+	cfg.KopiaServerUsername = os.Getenv("KOPIA_SERVER_USERNAME")
+	if cfg.KopiaServerUsername == "" {
+		cfg.KopiaServerUsername = "kopia"
+	}
+	cfg.KopiaServerPassword = os.Getenv("KOPIA_SERVER_PASSWORD")
+	cfg.KopiaServerAddress = os.Getenv("KOPIA_SERVER_ADDRESS")
+	if cfg.KopiaServerAddress == "" {
+		cfg.KopiaServerAddress = "127.0.0.1:51515"
+	}
+	if cfg.KopiaServerURL != "" && cfg.KopiaServerPassword == "" {
+		return fmt.Errorf("KOPIA_SERVER_PASSWORD is required when KOPIA_SERVER_URL is set")
+	}
+
+	cfg.KopiaServerTimeout = 10 * time.Second
+	if v := os.Getenv("KOPIA_SERVER_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid KOPIA_SERVER_TIMEOUT: %w", err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("KOPIA_SERVER_TIMEOUT must be > 0, got %s", v)
+		}
+		cfg.KopiaServerTimeout = d
+	}
+	return nil
+}
@@ -2,10 +2,13 @@ package config
 
 import (
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/mitchross/pvc-plumber/internal/backend"
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+	"github.com/mitchross/pvc-plumber/internal/s3"
 )
 
 // Test fixture constants. Centralizing these stops goconst from complaining
@@ -19,6 +22,9 @@ const (
 	envS3AccessKey        = "S3_ACCESS_KEY"
 	envS3SecretKey        = "S3_SECRET_KEY"
 	envS3Secure           = "S3_SECURE"
+	envS3Layout           = "S3_LAYOUT"
+	envS3Prefix           = "S3_PREFIX"
+	envS3IndexKey         = "S3_INDEX_KEY"
 	envHTTPTimeout        = "HTTP_TIMEOUT"
 	envPort               = "PORT"
 	envLogLevel           = "LOG_LEVEL"
@@ -55,14 +61,20 @@ const (
 // every test case so the table runs are hermetic regardless of ambient env.
 var allEnvVars = []string{
 	envBackendType, envS3Endpoint, envS3Bucket, envS3AccessKey, envS3SecretKey,
-	envS3Secure, envHTTPTimeout, envPort, envLogLevel,
+	envS3Secure, envS3Layout, envS3Prefix, envS3IndexKey,
+	envHTTPTimeout, envPort, envLogLevel,
 	envKopiaPassword, envKopiaS3Endpoint, envKopiaS3Bucket, envKopiaS3DisableTLS,
 	envAWSAccessKeyID, envAWSSecretAccessKey,
 	envESStoreName, envESVaultKey, envESKopiaPasswordProperty,
 	envESS3AccessKeyProperty, envESS3SecretKeyProperty,
-	"RE_WARM_INTERVAL",
+	"RE_WARM_INTERVAL", "CACHE_TTL", "CACHE_NEGATIVE_TTL",
+	"BACKENDS", "BACKEND_MERGE_POLICY", "KOPIA_FS_PATH",
+	envMaintQuick, envMaintFull, "KOPIA_MAINTENANCE_OWNER",
+	"KOPIA_MAINTENANCE_CONFIG_FILE", "KOPIA_MAINTENANCE_RUN_TIMEOUT",
 	// v3.1.0 lazy-credentials env vars
-	envKopiaCredentialsPath, envKopiaConnectTimeout,
+	envKopiaCredentialsPath, envKopiaConnectTimeout, envKopiaReader,
+	envKopiaServerURL, envKopiaServerUsername, envKopiaServerPassword,
+	envKopiaServerAddress, envKopiaServerTimeout,
 }
 
 // v3.1.0 env-var names. Promoted to constants because the test file
@@ -71,6 +83,14 @@ var allEnvVars = []string{
 const (
 	envKopiaCredentialsPath = "KOPIA_CREDENTIALS_PATH"
 	envKopiaConnectTimeout  = "KOPIA_CONNECT_TIMEOUT"
+	envKopiaReader          = "KOPIA_READER"
+	envKopiaServerURL       = "KOPIA_SERVER_URL"
+	envKopiaServerUsername  = "KOPIA_SERVER_USERNAME"
+	envKopiaServerPassword  = "KOPIA_SERVER_PASSWORD"
+	envKopiaServerAddress   = "KOPIA_SERVER_ADDRESS"
+	envKopiaServerTimeout   = "KOPIA_SERVER_TIMEOUT"
+	envMaintQuick           = "KOPIA_MAINTENANCE_QUICK_INTERVAL"
+	envMaintFull            = "KOPIA_MAINTENANCE_FULL_INTERVAL"
 )
 
 // snapshotEnv saves the current values of allEnvVars; restoreEnv puts them
@@ -353,6 +373,239 @@ func TestLoad_ReWarmInterval(t *testing.T) {
 	}
 }
 
+func TestLoad_CacheTTLs(t *testing.T) {
+	saved := snapshotEnv()
+	t.Cleanup(func() { restoreEnv(saved) })
+
+	tests := []struct {
+		name    string
+		ttl     string
+		neg     string
+		wantErr bool
+		wantTTL time.Duration
+		wantNeg time.Duration
+	}{
+		{"defaults", "", "", false, 60 * time.Second, 15 * time.Second},
+		{"explicit negative", "", "5s", false, 60 * time.Second, 5 * time.Second},
+		{"zero never caches negatives", "", "0s", false, 60 * time.Second, 0},
+		{"negative capped at positive", "10s", "30s", false, 10 * time.Second, 10 * time.Second},
+		{"default capped at short positive", "5s", "", false, 5 * time.Second, 5 * time.Second},
+		{"negative duration rejected", "", "-1s", true, 0, 0},
+		{"unparseable rejected", "", "soon", true, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnv()
+			_ = os.Setenv(envBackendType, "s3")
+			_ = os.Setenv(envS3Endpoint, testEndpoint)
+			_ = os.Setenv(envS3Bucket, "bucket")
+			_ = os.Setenv(envS3AccessKey, "k")
+			_ = os.Setenv(envS3SecretKey, "s")
+			if tt.ttl != "" {
+				_ = os.Setenv("CACHE_TTL", tt.ttl)
+			}
+			if tt.neg != "" {
+				_ = os.Setenv("CACHE_NEGATIVE_TTL", tt.neg)
+			}
+
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil (cfg=%+v)", cfg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.CacheTTL != tt.wantTTL || cfg.CacheNegativeTTL != tt.wantNeg {
+				t.Errorf("CacheTTL = %v, CacheNegativeTTL = %v; want %v, %v", cfg.CacheTTL, cfg.CacheNegativeTTL, tt.wantTTL, tt.wantNeg)
+			}
+		})
+	}
+}
+
+func TestLoad_CompositeBackends(t *testing.T) {
+	saved := snapshotEnv()
+	t.Cleanup(func() { restoreEnv(saved) })
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		wantErr     bool
+		wantPrimary string
+		wantPolicy  string
+	}{
+		{"kopia primary, default policy", map[string]string{"BACKENDS": "kopia-s3, s3"}, false, "kopia-s3", "any-exists"},
+		{"explicit policy", map[string]string{"BACKENDS": "s3,kopia-s3", "BACKEND_MERGE_POLICY": "primary-with-fallback"}, false, "s3", "primary-with-fallback"},
+		{"matching BACKEND_TYPE", map[string]string{"BACKENDS": "kopia-s3,s3", envBackendType: "kopia-s3"}, false, "kopia-s3", "any-exists"},
+		{"conflicting BACKEND_TYPE", map[string]string{"BACKENDS": "kopia-s3,s3", envBackendType: "s3"}, true, "", ""},
+		{"single entry", map[string]string{"BACKENDS": "s3"}, true, "", ""},
+		{"duplicate", map[string]string{"BACKENDS": "s3,s3"}, true, "", ""},
+		{"unknown type", map[string]string{"BACKENDS": "s3,restic"}, true, "", ""},
+		{"unknown policy", map[string]string{"BACKENDS": "s3,kopia-s3", "BACKEND_MERGE_POLICY": "majority"}, true, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnv()
+			for k, v := range map[string]string{
+				envS3Endpoint: testEndpoint, envS3Bucket: testBucket, envS3AccessKey: testAccess, envS3SecretKey: testSecret,
+				envKopiaS3Endpoint: testKopiaEndpoint, envKopiaS3Bucket: testKopiaBucket,
+			} {
+				_ = os.Setenv(k, v)
+			}
+			for k, v := range tt.env {
+				_ = os.Setenv(k, v)
+			}
+
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil (cfg=%+v)", cfg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.BackendType != tt.wantPrimary || cfg.BackendMergePolicy != tt.wantPolicy || len(cfg.BackendTypes()) != 2 {
+				t.Errorf("BackendType %q, policy %q, types %v", cfg.BackendType, cfg.BackendMergePolicy, cfg.BackendTypes())
+			}
+			// Both members' settings are loaded.
+			if cfg.S3Bucket != testBucket || cfg.KopiaS3Bucket != testKopiaBucket {
+				t.Errorf("member settings not loaded: s3 %q, kopia %q", cfg.S3Bucket, cfg.KopiaS3Bucket)
+			}
+		})
+	}
+
+	clearAllEnv()
+	_ = os.Setenv("BACKENDS", "s3,kopia-s3")
+	if cfg, err := LoadWithOptions(LoadOptions{SkipBackend: true}); err != nil || cfg.Backends != nil {
+		t.Errorf("SkipBackend: cfg %+v, err %v", cfg, err)
+	}
+}
+
+func TestLoad_KopiaFSBackend(t *testing.T) {
+	saved := snapshotEnv()
+	t.Cleanup(func() { restoreEnv(saved) })
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{"cli reader", map[string]string{"KOPIA_FS_PATH": dir, envKopiaPassword: testKopiaPassword}, false},
+		{"native reader", map[string]string{"KOPIA_FS_PATH": dir, envKopiaPassword: testKopiaPassword, envKopiaReader: "native"}, false},
+		{"server reader rejected", map[string]string{"KOPIA_FS_PATH": dir, envKopiaPassword: testKopiaPassword, envKopiaReader: "server"}, true},
+		{"missing path", map[string]string{envKopiaPassword: testKopiaPassword}, true},
+		{"path does not exist", map[string]string{"KOPIA_FS_PATH": dir + "/missing", envKopiaPassword: testKopiaPassword}, true},
+		{"missing password", map[string]string{"KOPIA_FS_PATH": dir}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnv()
+			_ = os.Setenv(envBackendType, "kopia-fs")
+			for k, v := range tt.env {
+				_ = os.Setenv(k, v)
+			}
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil (cfg=%+v)", cfg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.BackendType != "kopia-fs" || cfg.KopiaFSPath != dir || cfg.KopiaPassword != testKopiaPassword {
+				t.Errorf("cfg = %+v", cfg)
+			}
+		})
+	}
+
+	// Two kopia repositories in one composite would share one kopia config.
+	clearAllEnv()
+	_ = os.Setenv("BACKENDS", "kopia-s3,kopia-fs")
+	if _, err := Load(); err == nil {
+		t.Error("BACKENDS=kopia-s3,kopia-fs accepted")
+	}
+}
+
+func TestLoad_KopiaMaintenance(t *testing.T) {
+	saved := snapshotEnv()
+	t.Cleanup(func() { restoreEnv(saved) })
+	dir := t.TempDir()
+
+	tests := []struct {
+		name      string
+		env       map[string]string
+		wantErr   bool
+		wantQuick time.Duration
+		wantFull  time.Duration
+		wantOwner string
+	}{
+		{"disabled by default", nil, false, 0, 0, "pvc-plumber@pvc-plumber"},
+		{"both modes", map[string]string{envMaintQuick: "1h", envMaintFull: "24h"}, false, time.Hour, 24 * time.Hour, "pvc-plumber@pvc-plumber"},
+		{"custom owner", map[string]string{envMaintFull: "24h", "KOPIA_MAINTENANCE_OWNER": "ops@cluster"}, false, 0, 24 * time.Hour, "ops@cluster"},
+		{"bad interval", map[string]string{envMaintQuick: "hourly"}, true, 0, 0, ""},
+		{"negative interval", map[string]string{envMaintFull: "-1h"}, true, 0, 0, ""},
+		{"bad owner", map[string]string{envMaintFull: "24h", "KOPIA_MAINTENANCE_OWNER": "ops"}, true, 0, 0, ""},
+		{"bad run timeout", map[string]string{envMaintFull: "24h", "KOPIA_MAINTENANCE_RUN_TIMEOUT": "-1m"}, true, 0, 0, ""},
+		{"native reader rejected", map[string]string{envMaintQuick: "1h", envKopiaReader: "native"}, true, 0, 0, ""},
+		{"native reader without maintenance", map[string]string{envKopiaReader: "native"}, false, 0, 0, "pvc-plumber@pvc-plumber"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnv()
+			_ = os.Setenv(envBackendType, "kopia-fs")
+			_ = os.Setenv("KOPIA_FS_PATH", dir)
+			_ = os.Setenv(envKopiaPassword, testKopiaPassword)
+			for k, v := range tt.env {
+				_ = os.Setenv(k, v)
+			}
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil (cfg=%+v)", cfg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.KopiaMaintenanceQuickInterval != tt.wantQuick || cfg.KopiaMaintenanceFullInterval != tt.wantFull ||
+				cfg.KopiaMaintenanceOwner != tt.wantOwner || cfg.KopiaMaintenanceEnabled() != (tt.wantQuick+tt.wantFull > 0) {
+				t.Errorf("cfg = %+v", cfg)
+			}
+		})
+	}
+
+	// kopia-s3 reads the same knobs.
+	clearAllEnv()
+	for k, v := range map[string]string{
+		envBackendType: "kopia-s3", envKopiaS3Endpoint: "rustfs:9000", envKopiaS3Bucket: "volsync-kopia",
+		envMaintQuick: "30m",
+	} {
+		_ = os.Setenv(k, v)
+	}
+	if cfg, err := Load(); err != nil || cfg.KopiaMaintenanceQuickInterval != 30*time.Minute ||
+		cfg.KopiaMaintenanceConfigFile != kopia.DefaultMaintenanceConfigFile || cfg.KopiaMaintenanceRunTimeout != 0 {
+		t.Errorf("kopia-s3: cfg %+v, err %v", cfg, err)
+	}
+
+	// The maintenance connection and run bound are configurable.
+	_ = os.Setenv("KOPIA_MAINTENANCE_CONFIG_FILE", "/var/run/kopia/maintenance.config")
+	_ = os.Setenv("KOPIA_MAINTENANCE_RUN_TIMEOUT", "30m")
+	if cfg, err := Load(); err != nil || cfg.KopiaMaintenanceConfigFile != "/var/run/kopia/maintenance.config" ||
+		cfg.KopiaMaintenanceRunTimeout != 30*time.Minute {
+		t.Errorf("kopia-s3 overrides: cfg %+v, err %v", cfg, err)
+	}
+}
+
 // TestLoad_KopiaS3Backend exercises the v3.0.0 kopia-s3 backend path.
 // Replaces the v2 TestLoad_KopiaBackend (which validated KOPIA_REPOSITORY_PATH
 // stat-on-disk semantics that no longer exist).
@@ -530,6 +783,164 @@ func TestLoad_KopiaS3Backend_DefaultsCredentialsPath(t *testing.T) {
 	if cfg.KopiaConnectTimeout != 60*time.Second {
 		t.Errorf("KopiaConnectTimeout = %v, want 60s", cfg.KopiaConnectTimeout)
 	}
+	if cfg.KopiaReader != KopiaReaderCLI {
+		t.Errorf("KopiaReader = %q, want %q", cfg.KopiaReader, KopiaReaderCLI)
+	}
+}
+
+// TestLoad_KopiaS3Backend_Reader pins KOPIA_READER parsing: the two known
+// readers are accepted verbatim, anything else fails startup rather than
+// silently falling back to the CLI.
+func TestLoad_KopiaS3Backend_Reader(t *testing.T) {
+	saved := snapshotEnv()
+	t.Cleanup(func() { restoreEnv(saved) })
+
+	for _, tc := range []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{KopiaReaderCLI, KopiaReaderCLI, false},
+		{KopiaReaderNative, KopiaReaderNative, false},
+		{KopiaReaderServer, KopiaReaderServer, false},
+		{"kopia-go", "", true},
+	} {
+		t.Run(tc.raw, func(t *testing.T) {
+			clearAllEnv()
+			_ = os.Setenv(envBackendType, backend.TypeKopiaS3)
+			_ = os.Setenv(envKopiaS3Endpoint, testKopiaEndpoint)
+			_ = os.Setenv(envKopiaS3Bucket, testKopiaBucket)
+			_ = os.Setenv(envKopiaReader, tc.raw)
+
+			cfg, err := Load()
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected error for %q", tc.raw)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.KopiaReader != tc.want {
+				t.Errorf("KopiaReader = %q, want %q", cfg.KopiaReader, tc.want)
+			}
+		})
+	}
+}
+
+// TestLoad_S3Backend_Layout pins S3_LAYOUT / S3_PREFIX / S3_INDEX_KEY:
+// the prefix layout by default, known layouts verbatim, anything else a
+// startup error.
+func TestLoad_S3Backend_Layout(t *testing.T) {
+	saved := snapshotEnv()
+	t.Cleanup(func() { restoreEnv(saved) })
+
+	for _, tc := range []struct {
+		layout, prefix, indexKey string
+		wantLayout, wantIndexKey string
+		wantErr                  bool
+	}{
+		{"", "", "", s3.LayoutPrefix, s3.DefaultIndexKey, false},
+		{s3.LayoutRestic, "restic/", "", s3.LayoutRestic, s3.DefaultIndexKey, false},
+		{s3.LayoutIndex, "", "meta/index.json", s3.LayoutIndex, "meta/index.json", false},
+		{"kopia", "", "", "", "", true},
+	} {
+		t.Run(tc.layout, func(t *testing.T) {
+			clearAllEnv()
+			for k, v := range map[string]string{
+				envBackendType: "s3", envS3Endpoint: testEndpoint, envS3Bucket: testBucket,
+				envS3AccessKey: testAccess, envS3SecretKey: testSecret,
+				envS3Layout: tc.layout, envS3Prefix: tc.prefix, envS3IndexKey: tc.indexKey,
+			} {
+				_ = os.Setenv(k, v)
+			}
+			cfg, err := Load()
+			if tc.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "S3_LAYOUT") {
+					t.Errorf("error = %v, want S3_LAYOUT error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.S3Layout != tc.wantLayout || cfg.S3Prefix != tc.prefix || cfg.S3IndexKey != tc.wantIndexKey {
+				t.Errorf("got layout %q prefix %q index %q", cfg.S3Layout, cfg.S3Prefix, cfg.S3IndexKey)
+			}
+		})
+	}
+}
+
+// TestLoad_KopiaS3Backend_ServerReader pins the KOPIA_SERVER_* knobs:
+// managed-server defaults, an external server requiring its password, and
+// timeout validation.
+func TestLoad_KopiaS3Backend_ServerReader(t *testing.T) {
+	saved := snapshotEnv()
+	t.Cleanup(func() { restoreEnv(saved) })
+
+	for _, tc := range []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+		check   func(t *testing.T, cfg *Config)
+	}{
+		{
+			name: "managed defaults",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.KopiaServerURL != "" || cfg.KopiaServerAddress != "127.0.0.1:51515" ||
+					cfg.KopiaServerTimeout != 10*time.Second || cfg.KopiaServerUsername != "kopia" {
+					t.Errorf("defaults: %+v", cfg)
+				}
+			},
+		},
+		{
+			name: "external server",
+			env: map[string]string{
+				envKopiaServerURL: "https://kopia.backup.svc:51515", envKopiaServerUsername: "reader",
+				envKopiaServerPassword: "pw", envKopiaServerTimeout: "3s",
+			},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.KopiaServerURL != "https://kopia.backup.svc:51515" || cfg.KopiaServerUsername != "reader" ||
+					cfg.KopiaServerPassword != "pw" || cfg.KopiaServerTimeout != 3*time.Second {
+					t.Errorf("external: %+v", cfg)
+				}
+			},
+		},
+		{
+			name:    "external server without password",
+			env:     map[string]string{envKopiaServerURL: "http://kopia:51515"},
+			wantErr: "KOPIA_SERVER_PASSWORD",
+		},
+		{
+			name:    "invalid timeout",
+			env:     map[string]string{envKopiaServerTimeout: "0s"},
+			wantErr: "KOPIA_SERVER_TIMEOUT",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clearAllEnv()
+			_ = os.Setenv(envBackendType, backend.TypeKopiaS3)
+			_ = os.Setenv(envKopiaS3Endpoint, testKopiaEndpoint)
+			_ = os.Setenv(envKopiaS3Bucket, testKopiaBucket)
+			_ = os.Setenv(envKopiaReader, KopiaReaderServer)
+			for k, v := range tc.env {
+				_ = os.Setenv(k, v)
+			}
+
+			cfg, err := Load()
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("error = %v, want mention of %s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			tc.check(t, cfg)
+		})
+	}
 }
 
 // TestLoad_KopiaS3Backend_PathlessRequiresEnvVarCreds pins the inverse: when
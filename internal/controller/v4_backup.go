@@ -0,0 +1,443 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+	"github.com/mitchross/pvc-plumber/internal/v4/executor"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+	"github.com/mitchross/pvc-plumber/internal/v4/labels"
+	"github.com/mitchross/pvc-plumber/internal/v4/mode"
+	"github.com/mitchross/pvc-plumber/internal/v4/naming"
+	"github.com/mitchross/pvc-plumber/internal/v4/planner"
+)
+
+// On-demand backups.
+//
+// The builder renders trigger.manual for TierManual and leaves firing it
+// to whoever patches the string; every other tier runs on its cron. The
+// moment a backup is wanted NOW — right before an app upgrade or a
+// schema migration — is the moment nobody should be hand-editing an RS.
+// OnDemandBackup does it for them, for any tier:
+//
+//  1. read the PVC's RS (named after the PVC) and remember its trigger;
+//  2. update it, through executor.Execute, to a unique
+//     trigger.manual ("on-demand-<time>"), dropping any schedule, and
+//     annotate it with the hold's deadline
+//     (labels.AnnotationBackupOnDemandUntil);
+//  3. poll until status.lastManualSync equals that trigger — VolSync
+//     sets it when the sync the trigger fired completes;
+//  4. put the original trigger back (for a manual-tier RS the new
+//     manual string stays: changing it again would fire another sync)
+//     and drop the annotation;
+//  5. report the sync's mover status and, with a kopia backend wired,
+//     the snapshot it produced.
+//
+// Both RS updates go through the executor, so its GVK allow-list and
+// managed-by ownership re-check apply: an RS that Argo owns, or that
+// was relabeled since step 1, is refused, never switched. Both are
+// journaled with Trigger=backup-on-demand.
+//
+// Step 4 runs on a context detached from the caller's: a client that
+// disconnects or times out must not leave a scheduled RS in manual. If
+// the operator itself dies between steps 2 and 4, the hold annotation
+// outlives it, and once its deadline passes the reconciler sees it as
+// drift (planner.CurrentState.RSOnDemandExpired): it renders a
+// scheduled RS's schedule back, and drops the hold from a manual-tier
+// RS while keeping its manual trigger. Until then a second request for
+// the same RS is refused rather than queued.
+//
+// A reconciler update while the hold is live (say, the tier annotation
+// changed mid-backup) replaces the trigger; the wait then ends as
+// superseded and the original trigger is not restored over it.
+
+// DefaultBackupTimeout bounds the wait for one on-demand backup when
+// none is configured. A first backup of a large volume can take longer;
+// raise PVC_PLUMBER_BACKUP_TIMEOUT for those.
+const DefaultBackupTimeout = 30 * time.Minute
+
+// DefaultBackupPollInterval is how often the RS status is re-read while
+// waiting.
+const DefaultBackupPollInterval = 5 * time.Second
+
+// backupRestoreTimeout bounds restoring the original trigger, on a
+// context of its own.
+const backupRestoreTimeout = 30 * time.Second
+
+// backupHoldGrace is added to the timeout to form the hold deadline, so
+// the reconciler never repairs a hold the waiting request is about to
+// release itself.
+const backupHoldGrace = 2 * time.Minute
+
+// backupSnapshotSkew allows for the mover's clock running behind the
+// operator's when matching a sync or snapshot to the request.
+const backupSnapshotSkew = time.Minute
+
+// onDemandTriggerPrefix starts every trigger.manual an on-demand backup
+// sets, so the RS and its journal entries say where a sync came from.
+const onDemandTriggerPrefix = "on-demand-"
+
+// Pre-write refusals. The HTTP endpoint maps them to status codes;
+// plumberctl to exit codes.
+var (
+	// ErrBackupNotFound: the PVC has no RS to trigger.
+	ErrBackupNotFound = errors.New("no ReplicationSource")
+	// ErrBackupRefused: the trigger was not switched — audit mode, an
+	// executor refusal (not-owned), or another on-demand backup holding
+	// the RS.
+	ErrBackupRefused = errors.New("on-demand backup refused")
+)
+
+// BackupStatus is how an on-demand backup that did switch the trigger
+// ended.
+type BackupStatus string
+
+const (
+	// BackupSucceeded: status.lastManualSync reached the trigger.
+	BackupSucceeded BackupStatus = "succeeded"
+	// BackupFailed: the mover reported a failed sync before the
+	// timeout. VolSync keeps retrying; the trigger is restored anyway.
+	BackupFailed BackupStatus = "failed"
+	// BackupTimedOut: neither happened within the timeout (or the
+	// caller went away).
+	BackupTimedOut BackupStatus = "timeout"
+	// BackupSuperseded: something else replaced the trigger while the
+	// backup was waiting.
+	BackupSuperseded BackupStatus = "superseded"
+)
+
+// BackupSnapshot is the kopia snapshot an on-demand backup produced.
+type BackupSnapshot struct {
+	ID        string    `json:"id"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time,omitzero"`
+	TotalSize int64     `json:"total_size"`
+	FileCount int64     `json:"file_count"`
+}
+
+// BackupResult is the POST /backup response body and the plumberctl
+// backup output.
+type BackupResult struct {
+	Namespace         string       `json:"namespace"`
+	PVC               string       `json:"pvc"`
+	ReplicationSource string       `json:"replication_source"`
+	Trigger           string       `json:"trigger"`
+	Status            BackupStatus `json:"status"`
+	RequestedAt       time.Time    `json:"requested_at"`
+	FinishedAt        time.Time    `json:"finished_at"`
+	// Schedule is the cron the RS ran on before the backup ("" for a
+	// manual-tier RS); ScheduleRestored says whether it was put back.
+	Schedule         string `json:"schedule,omitempty"`
+	ScheduleRestored bool   `json:"schedule_restored"`
+	// Source is the kopia lineage the RS writes (username@hostname:/data).
+	Source string `json:"source,omitempty"`
+	// Snapshot is the newest snapshot of Source taken since the request;
+	// nil without a kopia backend, or when none has appeared.
+	Snapshot *BackupSnapshot `json:"snapshot,omitempty"`
+	Mover    MoverStatus     `json:"mover"`
+	Detail   string          `json:"detail,omitempty"`
+}
+
+// OnDemandBackup triggers one-shot backups of operator-owned RSes. Safe
+// for concurrent use. A second request for a PVC is refused while the
+// first runs: by an in-process lock here, and by the hold annotation
+// when it comes from another replica or from plumberctl.
+type OnDemandBackup struct {
+	Client client.Client
+	Mode   mode.Mode
+	// Journal records both trigger updates. nil disables journaling.
+	Journal *journal.Journal
+	// Snapshots, when non-nil, looks up the snapshot the backup
+	// produced. nil reports the mover status only.
+	Snapshots LineageLister
+	// Timeout bounds the wait for the sync; zero means
+	// DefaultBackupTimeout. PollInterval zero means
+	// DefaultBackupPollInterval.
+	Timeout      time.Duration
+	PollInterval time.Duration
+	// Now is injected for deterministic tests. nil → time.Now.
+	Now func() time.Time
+
+	// mu guards inflight, which refuses a second request this process
+	// is already serving before it touches the apiserver.
+	mu       sync.Mutex
+	inflight map[string]bool
+}
+
+// Trigger runs one on-demand backup of namespace/pvc and waits for it.
+// A returned error means the RS trigger was never switched (or the
+// switch itself failed); a backup that was started always comes back
+// as a BackupResult, whatever its Status.
+func (b *OnDemandBackup) Trigger(ctx context.Context, namespace, pvc string) (BackupResult, error) {
+	logger := log.FromContext(ctx).WithName("backup-on-demand")
+	key := namespace + "/" + pvc
+	if !b.claim(key) {
+		return BackupResult{}, fmt.Errorf("%w: an on-demand backup of %s is already running", ErrBackupRefused, key)
+	}
+	defer b.release(key)
+
+	if !b.Mode.WritesResources() {
+		return BackupResult{}, fmt.Errorf("%w: mode %s does not write resources", ErrBackupRefused, b.Mode)
+	}
+
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(rsGVK)
+	if err := b.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: pvc}, live); err != nil {
+		if apierrors.IsNotFound(err) {
+			return BackupResult{}, fmt.Errorf("%w %s/%s", ErrBackupNotFound, namespace, pvc)
+		}
+		return BackupResult{}, fmt.Errorf("get RS %s: %w", key, err)
+	}
+	now := b.now()
+	if until, ok := onDemandUntil(live); ok {
+		if now.Before(until) {
+			return BackupResult{}, fmt.Errorf("%w: an on-demand backup holds RS %s until %s", ErrBackupRefused, key, until.Format(time.RFC3339))
+		}
+		return BackupResult{}, fmt.Errorf("%w: an earlier on-demand backup of %s did not restore its trigger; the reconciler repairs it on its next pass", ErrBackupRefused, key)
+	}
+
+	original, _, _ := unstructured.NestedMap(live.Object, "spec", "trigger")
+	schedule, _, _ := unstructured.NestedString(live.Object, "spec", "trigger", "schedule")
+	trigger := onDemandTriggerPrefix + now.UTC().Format("20060102-150405")
+	res := BackupResult{
+		Namespace:         namespace,
+		PVC:               pvc,
+		ReplicationSource: live.GetName(),
+		Trigger:           trigger,
+		RequestedAt:       now,
+		Schedule:          schedule,
+		Source:            rsSource(live).String(),
+	}
+
+	hold := onDemandRS(live)
+	_ = unstructured.SetNestedMap(hold.Object, map[string]any{"manual": trigger}, "spec", "trigger")
+	anns := hold.GetAnnotations()
+	if anns == nil {
+		anns = map[string]string{}
+	}
+	anns[labels.AnnotationBackupOnDemandUntil] = now.Add(b.timeout() + backupHoldGrace).UTC().Format(time.RFC3339)
+	hold.SetAnnotations(anns)
+	if err := b.update(ctx, hold); err != nil {
+		return BackupResult{}, err
+	}
+	logger.Info("on-demand backup triggered", "rs", key, "trigger", trigger, "schedule", schedule)
+
+	res.Status, res.Mover, res.Detail = b.wait(ctx, namespace, pvc, trigger, now)
+	res.FinishedAt = b.now()
+
+	// Restore on a context the caller cannot cancel.
+	rctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), backupRestoreTimeout)
+	defer cancel()
+	restored, err := b.restore(rctx, namespace, pvc, trigger, original)
+	res.ScheduleRestored = restored && schedule != ""
+	if err != nil {
+		logger.Error(err, "on-demand backup could not restore the RS trigger; the reconciler repairs it after the hold expires", "rs", key)
+		res.Detail = joinDetail(res.Detail, "trigger not restored: "+err.Error())
+	}
+
+	if res.Status == BackupSucceeded {
+		res.Snapshot, err = b.snapshot(rctx, live, now)
+		if err != nil {
+			res.Detail = joinDetail(res.Detail, "snapshot lookup: "+err.Error())
+		}
+	}
+	logger.Info("on-demand backup finished", "rs", key, "status", string(res.Status), "schedule_restored", res.ScheduleRestored)
+	return res, nil
+}
+
+// wait polls the RS until lastManualSync reaches trigger, the mover
+// reports a failure, the trigger is replaced, or the timeout passes.
+func (b *OnDemandBackup) wait(ctx context.Context, namespace, pvc, trigger string, since time.Time) (BackupStatus, MoverStatus, string) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout())
+	defer cancel()
+	poll := b.PollInterval
+	if poll <= 0 {
+		poll = DefaultBackupPollInterval
+	}
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	var last MoverStatus
+	for {
+		rs := &unstructured.Unstructured{}
+		rs.SetGroupVersionKind(rsGVK)
+		err := b.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: pvc}, rs)
+		switch {
+		case apierrors.IsNotFound(err):
+			return BackupSuperseded, last, "the ReplicationSource was deleted while waiting"
+		case err == nil:
+			last = readMoverStatus(rs)
+			if synced, _, _ := unstructured.NestedString(rs.Object, "status", "lastManualSync"); synced == trigger {
+				return BackupSucceeded, last, ""
+			}
+			if current, _, _ := unstructured.NestedString(rs.Object, "spec", "trigger", "manual"); current != trigger {
+				return BackupSuperseded, last, "the RS trigger was changed while waiting"
+			}
+			// A failed result left over from an earlier sync is not
+			// this backup's; only one that started after the switch is.
+			if last.MoverResult == volsyncMoverFailed && !last.LastSyncStartTime.Before(since.Add(-backupSnapshotSkew)) {
+				return BackupFailed, last, "the mover reported a failed sync; see mover.mover_logs"
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return BackupTimedOut, last, fmt.Sprintf("lastManualSync did not reach %q within %s", trigger, b.timeout())
+		case <-ticker.C:
+		}
+	}
+}
+
+// restore puts original back as the RS trigger and drops the hold
+// annotation, unless the trigger is no longer the one this backup set.
+// It reports whether the trigger was restored.
+func (b *OnDemandBackup) restore(ctx context.Context, namespace, pvc, trigger string, original map[string]any) (bool, error) {
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(rsGVK)
+	if err := b.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: pvc}, live); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("get RS: %w", err)
+	}
+	if current, _, _ := unstructured.NestedString(live.Object, "spec", "trigger", "manual"); current != trigger {
+		return false, nil
+	}
+	rs := onDemandRS(live)
+	if _, scheduled := original["schedule"]; scheduled {
+		_ = unstructured.SetNestedMap(rs.Object, original, "spec", "trigger")
+	}
+	anns := rs.GetAnnotations()
+	delete(anns, labels.AnnotationBackupOnDemandUntil)
+	rs.SetAnnotations(anns)
+	if err := b.update(ctx, rs); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// update sends one RS update through the executor and journals it.
+func (b *OnDemandBackup) update(ctx context.Context, rs *unstructured.Unstructured) error {
+	res := executor.Execute(ctx, b.Client, b.Mode, planner.Plan{Ops: []planner.PlannedOp{{Kind: planner.OpUpdate, Resource: rs}}})
+	b.Journal.RecordResult(ctx, journal.TriggerBackupOnDemand, res)
+	for _, out := range res.Attempted {
+		switch out.Status {
+		case executor.OpRefused:
+			return fmt.Errorf("%w: executor refused update of RS %s/%s: %s", ErrBackupRefused, out.Namespace, out.Name, out.Reason)
+		case executor.OpSkipped:
+			return fmt.Errorf("%w: executor skipped update of RS %s/%s: %s", ErrBackupRefused, out.Namespace, out.Name, out.Reason)
+		case executor.OpFailed:
+			return fmt.Errorf("update RS %s/%s: %s: %w", out.Namespace, out.Name, out.Reason, out.Err)
+		}
+	}
+	return nil
+}
+
+// snapshot returns the newest complete snapshot of the RS's lineage
+// that started after the request, or nil.
+func (b *OnDemandBackup) snapshot(ctx context.Context, rs *unstructured.Unstructured, since time.Time) (*BackupSnapshot, error) {
+	if b.Snapshots == nil {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, restoreSelectionTimeout)
+	defer cancel()
+	snaps, err := b.Snapshots.ListSnapshots(ctx, rsSource(rs))
+	if err != nil {
+		return nil, err
+	}
+	snap, _, ok := selectSnapshot(snaps, time.Time{}, 0)
+	if !ok || snap.StartTime.Before(since.Add(-backupSnapshotSkew)) {
+		return nil, nil
+	}
+	return &BackupSnapshot{
+		ID:        snap.ID,
+		StartTime: snap.StartTime,
+		EndTime:   snap.EndTime,
+		TotalSize: snap.TotalSize,
+		FileCount: snap.FileCount,
+	}, nil
+}
+
+func (b *OnDemandBackup) claim(key string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.inflight[key] {
+		return false
+	}
+	if b.inflight == nil {
+		b.inflight = map[string]bool{}
+	}
+	b.inflight[key] = true
+	return true
+}
+
+func (b *OnDemandBackup) release(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.inflight, key)
+}
+
+func (b *OnDemandBackup) timeout() time.Duration {
+	if b.Timeout > 0 {
+		return b.Timeout
+	}
+	return DefaultBackupTimeout
+}
+
+func (b *OnDemandBackup) now() time.Time {
+	if b.Now != nil {
+		return b.Now()
+	}
+	return time.Now()
+}
+
+// onDemandRS copies live for an update: metadata the apiserver owns and
+// the status block are dropped; the executor re-applies resourceVersion
+// and UID from its own read.
+func onDemandRS(live *unstructured.Unstructured) *unstructured.Unstructured {
+	rs := live.DeepCopy()
+	delete(rs.Object, "status")
+	rs.SetManagedFields(nil)
+	return rs
+}
+
+// onDemandUntil parses the hold annotation on rs. ok is false when there
+// is none; an unparseable value reads as already expired (zero time).
+func onDemandUntil(rs *unstructured.Unstructured) (time.Time, bool) {
+	raw, ok := rs.GetAnnotations()[labels.AnnotationBackupOnDemandUntil]
+	if !ok {
+		return time.Time{}, false
+	}
+	until, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, true
+	}
+	return until, true
+}
+
+// rsSource is the kopia lineage an RS writes, from its own spec — the
+// identity actually in use, whatever the PVC's annotations say now.
+func rsSource(rs *unstructured.Unstructured) kopia.SnapshotSource {
+	user, _, _ := unstructured.NestedString(rs.Object, "spec", "kopia", "username")
+	host, _, _ := unstructured.NestedString(rs.Object, "spec", "kopia", "hostname")
+	return kopia.V4Source(rs.GetNamespace(), naming.KopiaIdentity{Username: user, Hostname: host})
+}
+
+func joinDetail(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	}
+	return a + "; " + b
+}
@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+)
+
+// DefaultBackupIndexInterval is the BackupIndex refresh cadence when none
+// is configured.
+const DefaultBackupIndexInterval = 10 * time.Minute
+
+// errBackupIndexEmpty is the answer before the first listing lands.
+var errBackupIndexEmpty = errors.New("backup index not loaded yet")
+
+// BackupIndex is the BackupChecker the reconciler's freshness reads go
+// through. A kopia reader answers CheckBackupExists with a scan of the
+// whole repository, and Reconcile runs once per PVC; BackupIndex instead
+// lists the repository once every Interval and answers every lookup from
+// that listing in memory, so no reconcile waits on the bucket. Register
+// it with the manager via mgr.Add; it implements manager.Runnable and
+// LeaderElectionRunnable.
+//
+// Until the first listing succeeds every answer is non-authoritative,
+// which lastBackup ignores; a failed refresh keeps the previous listing.
+type BackupIndex struct {
+	// Lister is the repository scan, e.g. the native reader.
+	Lister kopia.SnapshotLister
+
+	// Interval is the refresh cadence. <= 0 means
+	// DefaultBackupIndexInterval.
+	Interval time.Duration
+
+	mu    sync.RWMutex
+	snaps []kopia.SnapshotInfo
+	// loaded is false until the first listing succeeds; an empty
+	// repository lists no snapshots and is still loaded.
+	loaded bool
+}
+
+// NeedLeaderElection keeps the scans on the replica that reconciles.
+func (b *BackupIndex) NeedLeaderElection() bool { return true }
+
+// Start refreshes immediately and then every Interval until ctx is
+// cancelled. Refresh errors are logged and retried on the next tick.
+func (b *BackupIndex) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("backup-index")
+	interval := b.Interval
+	if interval <= 0 {
+		interval = DefaultBackupIndexInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := b.Refresh(ctx); err != nil {
+			logger.Error(err, "backup index refresh failed")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh lists the repository once and replaces the index with the
+// result.
+func (b *BackupIndex) Refresh(ctx context.Context) error {
+	snaps, err := b.Lister.ListAllSnapshots(ctx)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	b.snaps, b.loaded = snaps, true
+	b.mu.Unlock()
+	log.FromContext(ctx).WithName("backup-index").V(1).Info("backup index refreshed", "snapshots", len(snaps))
+	return nil
+}
+
+// CheckBackupExists implements BackupChecker from the last listing, over
+// the query's candidate sources as the readers do. It never touches the
+// repository.
+func (b *BackupIndex) CheckBackupExists(_ context.Context, q backend.Query) backend.CheckResult {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if !b.loaded {
+		return kopia.CheckResultFor(q, nil, errBackupIndexEmpty)
+	}
+	candidates := kopia.QuerySources(q)
+	var snaps []kopia.SnapshotInfo
+	for _, s := range b.snaps {
+		if slices.Contains(candidates, s.Source) {
+			snaps = append(snaps, s)
+		}
+	}
+	return kopia.CheckResultFor(q, snaps, nil)
+}
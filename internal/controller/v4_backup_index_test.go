@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+)
+
+// countingLister is a SnapshotLister over a fixed listing that counts
+// scans.
+type countingLister struct {
+	snaps []kopia.SnapshotInfo
+	err   error
+	scans int
+}
+
+func (l *countingLister) ListAllSnapshots(context.Context) ([]kopia.SnapshotInfo, error) {
+	l.scans++
+	return l.snaps, l.err
+}
+
+// TestBackupIndex_ReconcileNeverScans pins that freshness reads through
+// the index cost no repository scan: one Refresh, then every PVC's
+// reconcile answers from memory.
+func TestBackupIndex_ReconcileNeverScans(t *testing.T) {
+	now := fixedTime()
+	lister := &countingLister{snaps: []kopia.SnapshotInfo{
+		{ID: "a", Source: kopia.SnapshotSource{Host: testNSMyapp, UserName: "data", Path: "/data"}, StartTime: now.Add(-2 * time.Hour)},
+		{ID: "b", Source: kopia.SnapshotSource{Host: testNSMyapp, UserName: "logs-backup", Path: "/data"}, StartTime: now.Add(-3 * time.Hour)},
+	}}
+	index := &BackupIndex{Lister: lister}
+	if err := index.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	f := newV4Fixture(t,
+		makePVC(testNSMyapp, "data", labelsEnabledManage(), nil),
+		makePVC(testNSMyapp, "logs", labelsEnabledManage(), nil),
+		makePVC(testNSMyapp, "empty", labelsEnabledManage(), nil),
+	)
+	f.rec.Backups = index
+	for _, name := range []string{"data", "logs", "empty", "data"} {
+		f.reconcile(testNSMyapp, name)
+	}
+
+	if lister.scans != 1 {
+		t.Errorf("repository scans = %d, want 1 (the Refresh)", lister.scans)
+	}
+	if e := snapshotEntry(t, f, "data"); e.LastBackupSource != BackupSourceKopia || !e.LastBackupAt.Equal(now.Add(-2*time.Hour)) {
+		t.Errorf("data: %+v", e)
+	}
+	if e := snapshotEntry(t, f, "logs"); e.LastBackupSource != BackupSourceKopia || !e.LastBackupAt.Equal(now.Add(-3*time.Hour)) {
+		t.Errorf("logs (legacy lineage): %+v", e)
+	}
+	if e := snapshotEntry(t, f, "empty"); e.Freshness != FreshnessNever {
+		t.Errorf("empty: %+v", e)
+	}
+}
+
+func TestBackupIndex_IdentityOverride(t *testing.T) {
+	at := fixedTime()
+	index := &BackupIndex{Lister: &countingLister{snaps: []kopia.SnapshotInfo{
+		{ID: "a", Source: kopia.SnapshotSource{Host: testNSMyapp, UserName: "shared-data", Path: "/data"}, StartTime: at},
+		{ID: "b", Source: kopia.SnapshotSource{Host: testNSMyapp, UserName: "data-backup", Path: "/data"}, StartTime: at.Add(time.Hour)},
+	}}}
+	if err := index.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	r := index.CheckBackupExists(context.Background(), backend.NewQuery(testNSMyapp, "data", "shared-data"))
+	if !r.Authoritative || r.SnapshotCount != 1 || !r.LatestSnapshotAt.Equal(at) {
+		t.Errorf("override must read only its own lineage: %+v", r)
+	}
+}
+
+// TestBackupIndex_NotLoadedIsNonAuthoritative pins the startup window
+// and a failing first scan: no listing is not "no backups".
+func TestBackupIndex_NotLoadedIsNonAuthoritative(t *testing.T) {
+	lister := &countingLister{err: errors.New("bucket unreachable")}
+	index := &BackupIndex{Lister: lister}
+	q := backend.NewQuery(testNSMyapp, "data", "")
+	if r := index.CheckBackupExists(context.Background(), q); r.Authoritative || r.Exists {
+		t.Errorf("before the first refresh: %+v", r)
+	}
+	if err := index.Refresh(context.Background()); err == nil {
+		t.Fatal("Refresh swallowed the listing error")
+	}
+	if r := index.CheckBackupExists(context.Background(), q); r.Authoritative {
+		t.Errorf("after a failed refresh: %+v", r)
+	}
+
+	lister.err = nil
+	if err := index.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if r := index.CheckBackupExists(context.Background(), q); !r.Authoritative || r.Exists {
+		t.Errorf("empty repository is an authoritative no: %+v", r)
+	}
+}
@@ -0,0 +1,286 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+	"github.com/mitchross/pvc-plumber/internal/v4/auditclient"
+	"github.com/mitchross/pvc-plumber/internal/v4/builder"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+	v4labels "github.com/mitchross/pvc-plumber/internal/v4/labels"
+	"github.com/mitchross/pvc-plumber/internal/v4/mode"
+	"github.com/mitchross/pvc-plumber/internal/v4/naming"
+)
+
+// backupFixture wires an OnDemandBackup to a fake client behind the
+// auditclient wrapper. The fake has no VolSync controller; onSync, when
+// set, plays it on every RS read that finds an on-demand trigger.
+type backupFixture struct {
+	t       *testing.T
+	fake    client.WithWatch
+	journal *journal.Journal
+	backup  *OnDemandBackup
+	onSync  func(rs *unstructured.Unstructured, trigger string)
+}
+
+func newBackupFixture(t *testing.T, m mode.Mode, objs ...client.Object) *backupFixture {
+	t.Helper()
+	f := &backupFixture{t: t, journal: journal.New(nil)}
+	f.fake = fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(objs...).
+		WithInterceptorFuncs(interceptor.Funcs{Get: f.get}).Build()
+	auditC := auditclient.New(f.fake, m, slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)))
+	f.backup = &OnDemandBackup{
+		Client:       auditC,
+		Mode:         m,
+		Journal:      f.journal,
+		Timeout:      50 * time.Millisecond,
+		PollInterval: time.Millisecond,
+		Now:          fixedTime,
+	}
+	return f
+}
+
+// get reads through to the fake, then lets onSync act on an RS whose
+// trigger an on-demand backup set, writing the result back.
+func (f *backupFixture) get(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if err := c.Get(ctx, key, obj, opts...); err != nil {
+		return err
+	}
+	rs, ok := obj.(*unstructured.Unstructured)
+	if !ok || f.onSync == nil || rs.GroupVersionKind() != rsGVK {
+		return nil
+	}
+	trigger, _, _ := unstructured.NestedString(rs.Object, "spec", "trigger", "manual")
+	if !strings.HasPrefix(trigger, onDemandTriggerPrefix) {
+		return nil
+	}
+	f.onSync(rs, trigger)
+	if err := c.Update(ctx, rs); err != nil {
+		return err
+	}
+	return c.Get(ctx, key, obj, opts...)
+}
+
+// synced plays a VolSync sync that completes the manual trigger.
+func synced(rs *unstructured.Unstructured, trigger string) {
+	_ = unstructured.SetNestedField(rs.Object, trigger, "status", "lastManualSync")
+	_ = unstructured.SetNestedField(rs.Object, fixedTime().Add(2*time.Minute).Format(time.RFC3339), "status", "lastSyncTime")
+	_ = unstructured.SetNestedField(rs.Object, "Successful", "status", "latestMoverStatus", "result")
+}
+
+// backupRS is the RS the reconciler renders for myapp/data at tier.
+func backupRS(tier v4labels.Tier) *unstructured.Unstructured {
+	return builder.BuildRS(builder.Inputs{
+		Namespace: testNSMyapp, PVCName: testPVCName, PVCCapacity: "1Gi",
+		Spec: v4labels.Spec{Tier: tier}, NamingStrategy: naming.StrategyBareDst,
+		DefaultRepoSecret: testRepoSecretShare, DefaultStorageClass: "longhorn",
+		DefaultUID: 568, DefaultGID: 568, DefaultFSGroup: 568,
+	})
+}
+
+func (f *backupFixture) liveRS() *unstructured.Unstructured {
+	f.t.Helper()
+	rs := &unstructured.Unstructured{}
+	rs.SetGroupVersionKind(rsGVK)
+	if err := f.fake.Get(context.Background(), types.NamespacedName{Namespace: testNSMyapp, Name: testPVCName}, rs); err != nil {
+		f.t.Fatal(err)
+	}
+	return rs
+}
+
+func TestOnDemandBackup_ScheduledRS(t *testing.T) {
+	rs := backupRS(v4labels.TierDaily)
+	schedule, _, _ := unstructured.NestedString(rs.Object, "spec", "trigger", "schedule")
+	f := newBackupFixture(t, mode.Permissive, rs)
+	f.onSync = synced
+	src := rsSource(rs).String()
+	f.backup.Snapshots = &stubLineages{lineages: map[string][]kopia.SnapshotInfo{src: {
+		{ID: "before", StartTime: fixedTime().Add(-24 * time.Hour)},
+		{ID: "fresh", StartTime: fixedTime().Add(30 * time.Second), TotalSize: 42, FileCount: 3},
+	}}}
+
+	res, err := f.backup.Trigger(context.Background(), testNSMyapp, testPVCName)
+	if err != nil {
+		t.Fatalf("Trigger: %v", err)
+	}
+	if res.Status != BackupSucceeded {
+		t.Fatalf("Status = %q (%s), want succeeded", res.Status, res.Detail)
+	}
+	if res.Trigger != "on-demand-20260523-120000" {
+		t.Errorf("Trigger = %q", res.Trigger)
+	}
+	if res.Schedule != schedule || !res.ScheduleRestored {
+		t.Errorf("Schedule = %q restored=%v, want %q restored", res.Schedule, res.ScheduleRestored, schedule)
+	}
+	if res.Source != src || res.Snapshot == nil || res.Snapshot.ID != "fresh" || res.Snapshot.TotalSize != 42 {
+		t.Errorf("Source = %q, Snapshot = %+v; want %s / fresh", res.Source, res.Snapshot, src)
+	}
+	if res.Mover.MoverResult != "Successful" {
+		t.Errorf("Mover = %+v", res.Mover)
+	}
+
+	live := f.liveRS()
+	trigger, _, _ := unstructured.NestedMap(live.Object, "spec", "trigger")
+	if len(trigger) != 1 || trigger["schedule"] != schedule {
+		t.Errorf("live trigger after backup = %v, want only the original schedule", trigger)
+	}
+	if _, ok := live.GetAnnotations()[v4labels.AnnotationBackupOnDemandUntil]; ok {
+		t.Error("hold annotation left on the RS")
+	}
+	entries := mustQuery(t, f.journal)
+	if len(entries) != 2 {
+		t.Fatalf("journal has %d entries, want 2 (switch and restore)", len(entries))
+	}
+	for _, e := range entries {
+		if e.Trigger != journal.TriggerBackupOnDemand || e.Op != "update" || e.Status != "succeeded" {
+			t.Errorf("journal entry = %s %s %s", e.Trigger, e.Op, e.Status)
+		}
+	}
+}
+
+// A manual-tier RS keeps the one-shot trigger: changing it back would
+// fire another sync.
+func TestOnDemandBackup_ManualRS(t *testing.T) {
+	f := newBackupFixture(t, mode.Permissive, backupRS(v4labels.TierManual))
+	f.onSync = synced
+
+	res, err := f.backup.Trigger(context.Background(), testNSMyapp, testPVCName)
+	if err != nil {
+		t.Fatalf("Trigger: %v", err)
+	}
+	if res.Status != BackupSucceeded || res.ScheduleRestored || res.Snapshot != nil {
+		t.Fatalf("result = %+v, want succeeded, nothing to restore, no snapshot lookup", res)
+	}
+	live := f.liveRS()
+	if manual, _, _ := unstructured.NestedString(live.Object, "spec", "trigger", "manual"); manual != res.Trigger {
+		t.Errorf("live trigger.manual = %q, want %q", manual, res.Trigger)
+	}
+	if _, ok := live.GetAnnotations()[v4labels.AnnotationBackupOnDemandUntil]; ok {
+		t.Error("hold annotation left on the RS")
+	}
+}
+
+// Every way the backup can end after the switch restores the schedule,
+// except a superseded trigger, which is not overwritten.
+func TestOnDemandBackup_Outcomes(t *testing.T) {
+	cases := []struct {
+		name         string
+		onSync       func(rs *unstructured.Unstructured, trigger string)
+		want         BackupStatus
+		wantRestored bool
+	}{
+		{name: "timeout", want: BackupTimedOut, wantRestored: true},
+		{
+			name: "mover failed",
+			onSync: func(rs *unstructured.Unstructured, _ string) {
+				_ = unstructured.SetNestedField(rs.Object, fixedTime().Format(time.RFC3339), "status", "lastSyncStartTime")
+				_ = unstructured.SetNestedField(rs.Object, "Failed", "status", "latestMoverStatus", "result")
+			},
+			want: BackupFailed, wantRestored: true,
+		},
+		{
+			name: "stale failure is not this backup's",
+			onSync: func(rs *unstructured.Unstructured, _ string) {
+				_ = unstructured.SetNestedField(rs.Object, fixedTime().Add(-time.Hour).Format(time.RFC3339), "status", "lastSyncStartTime")
+				_ = unstructured.SetNestedField(rs.Object, "Failed", "status", "latestMoverStatus", "result")
+			},
+			want: BackupTimedOut, wantRestored: true,
+		},
+		{
+			name: "superseded",
+			onSync: func(rs *unstructured.Unstructured, _ string) {
+				_ = unstructured.SetNestedField(rs.Object, "someone-else", "spec", "trigger", "manual")
+			},
+			want: BackupSuperseded,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newBackupFixture(t, mode.Permissive, backupRS(v4labels.TierDaily))
+			f.onSync = tc.onSync
+
+			res, err := f.backup.Trigger(context.Background(), testNSMyapp, testPVCName)
+			if err != nil {
+				t.Fatalf("Trigger: %v", err)
+			}
+			if res.Status != tc.want || res.ScheduleRestored != tc.wantRestored {
+				t.Fatalf("Status = %q restored=%v (%s), want %q restored=%v", res.Status, res.ScheduleRestored, res.Detail, tc.want, tc.wantRestored)
+			}
+			_, scheduled, _ := unstructured.NestedString(f.liveRS().Object, "spec", "trigger", "schedule")
+			if scheduled != tc.wantRestored {
+				t.Errorf("live RS has a schedule: %v, want %v", scheduled, tc.wantRestored)
+			}
+		})
+	}
+}
+
+// Refusals never switch the trigger.
+func TestOnDemandBackup_Refusals(t *testing.T) {
+	held := func(until time.Time) *unstructured.Unstructured {
+		rs := backupRS(v4labels.TierDaily)
+		anns := rs.GetAnnotations()
+		anns[v4labels.AnnotationBackupOnDemandUntil] = until.Format(time.RFC3339)
+		rs.SetAnnotations(anns)
+		return rs
+	}
+	argo := backupRS(v4labels.TierDaily)
+	argo.SetLabels(map[string]string{managedByLabel: "argocd"})
+
+	cases := []struct {
+		name    string
+		mode    mode.Mode
+		rs      *unstructured.Unstructured
+		wantErr error
+		wantMsg string
+	}{
+		{name: "audit mode", mode: mode.Audit, rs: backupRS(v4labels.TierDaily), wantErr: ErrBackupRefused, wantMsg: "does not write"},
+		{name: "no RS", mode: mode.Permissive, wantErr: ErrBackupNotFound},
+		{name: "argo-owned", mode: mode.Permissive, rs: argo, wantErr: ErrBackupRefused, wantMsg: "not-owned"},
+		{name: "held", mode: mode.Permissive, rs: held(fixedTime().Add(time.Minute)), wantErr: ErrBackupRefused, wantMsg: "holds RS"},
+		{name: "abandoned hold", mode: mode.Permissive, rs: held(fixedTime().Add(-time.Minute)), wantErr: ErrBackupRefused, wantMsg: "did not restore"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var objs []client.Object
+			if tc.rs != nil {
+				objs = append(objs, tc.rs)
+			}
+			f := newBackupFixture(t, tc.mode, objs...)
+			_, err := f.backup.Trigger(context.Background(), testNSMyapp, testPVCName)
+			if !errors.Is(err, tc.wantErr) || !strings.Contains(err.Error(), tc.wantMsg) {
+				t.Fatalf("err = %v, want %v containing %q", err, tc.wantErr, tc.wantMsg)
+			}
+			if tc.rs == nil {
+				return
+			}
+			if _, ok, _ := unstructured.NestedString(f.liveRS().Object, "spec", "trigger", "manual"); ok {
+				t.Error("refused backup switched the trigger")
+			}
+		})
+	}
+}
+
+// A second request from the same process is refused without reading
+// the RS.
+func TestOnDemandBackup_InflightRefused(t *testing.T) {
+	f := newBackupFixture(t, mode.Permissive, backupRS(v4labels.TierDaily))
+	if !f.backup.claim(testNSMyapp + "/" + testPVCName) {
+		t.Fatal("first claim failed")
+	}
+	_, err := f.backup.Trigger(context.Background(), testNSMyapp, testPVCName)
+	if !errors.Is(err, ErrBackupRefused) || !strings.Contains(err.Error(), "already running") {
+		t.Fatalf("err = %v, want an already-running refusal", err)
+	}
+}
@@ -0,0 +1,494 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/builder"
+	"github.com/mitchross/pvc-plumber/internal/v4/executor"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+	"github.com/mitchross/pvc-plumber/internal/v4/labels"
+	"github.com/mitchross/pvc-plumber/internal/v4/mode"
+	"github.com/mitchross/pvc-plumber/internal/v4/planner"
+)
+
+// Restore-to-clone.
+//
+// Getting one file back used to mean restoring over the live PVC
+// (restore-as-of on the application's own RD, with the app scaled down)
+// or hand-writing an RD and a PVC. A clone request does the second
+// declaratively: annotate the source PVC with
+//
+//	pvc-plumber.io/clone-to: [<namespace>/]<name>
+//	pvc-plumber.io/clone-as-of: 2026-05-17T03:00:00Z   (optional)
+//	pvc-plumber.io/clone-ttl: 72h                       (optional)
+//
+// and CloneRunner creates, in the target namespace, an RD named
+// "<pvc>-clone-<id>" cloned from the PVC's live RD (same repository and
+// sourceIdentity, restoreAsOf from the request) and the target PVC with
+// dataSourceRef pointing at it. The original PVC and its RS/RD are only
+// ever read. <id> hashes the request (source, target, as-of), so editing
+// any of them is a new clone.
+//
+// Write boundary. Both objects go through executor.Execute and are
+// journaled with Trigger=restore-clone. The PVC is the only PVC the
+// executor will write, and only because it carries the clone label and
+// an RD data source (executor.AllowedClonePVCGVK); the runner refuses
+// on its own, before the executor, a target in a system namespace or in
+// a namespace without the pvc-plumber.io/managed-namespace write gate,
+// and an existing target PVC it did not create — a clone never
+// overwrites anything. Because dataSourceRef cannot cross namespaces,
+// the RD lives with the target PVC, and a target namespace other than
+// the source's needs the kopia repository Secret the RD references.
+//
+// Expiry. Every clone object carries pvc-plumber.io/clone-expires-at
+// (creation + TTL). Each sweep deletes the expired ones, PVC first, and
+// remembers the request id so a clone-to annotation left in place does
+// not resurrect the clone on the next sweep. The ids are kept in memory
+// and, across restarts, recovered from the journal's clone deletes —
+// with only the in-memory journal ring, a restart may clone once more
+// and expire it again after the TTL.
+
+// DefaultCloneTTL is a clone's lifetime when neither the PVC nor the
+// operator configures one.
+const DefaultCloneTTL = 24 * time.Hour
+
+// DefaultCloneScanInterval is the sweep cadence when none is configured.
+const DefaultCloneScanInterval = time.Minute
+
+// CloneStatus is where a clone request stands, surfaced in /audit.
+type CloneStatus string
+
+const (
+	// CloneCreated: this sweep created the clone's objects.
+	CloneCreated CloneStatus = "created"
+
+	// CloneRestoring: the target PVC exists and is not Bound yet —
+	// VolSync is still restoring into it.
+	CloneRestoring CloneStatus = "restoring"
+
+	// CloneReady: the target PVC is Bound and usable.
+	CloneReady CloneStatus = "ready"
+
+	// CloneExpired: the TTL elapsed and the clone was deleted. The
+	// request is not acted on again.
+	CloneExpired CloneStatus = "expired"
+
+	// CloneConflict: an object the runner did not create already holds
+	// the target name. Nothing is written.
+	CloneConflict CloneStatus = "conflict"
+
+	// CloneRefused: the target namespace may not be written, or the
+	// executor refused a write (Reason says which).
+	CloneRefused CloneStatus = "refused"
+
+	// CloneSkipped: the operator is in audit mode; the executor
+	// short-circuited the creates.
+	CloneSkipped CloneStatus = "skipped"
+
+	// CloneError: the request is malformed, the source PVC has no RD to
+	// clone from, or a read or write failed. Retried on the next sweep.
+	CloneError CloneStatus = "error"
+)
+
+// CloneRecord is a PVC's clone request and its state, surfaced as
+// ParityEntry.RestoreClone.
+type CloneRecord struct {
+	// Target and RD are "<namespace>/<name>" of the clone's PVC and RD.
+	Target    string      `json:"target"`
+	RD        string      `json:"rd,omitempty"`
+	ID        string      `json:"id,omitempty"`
+	AsOf      time.Time   `json:"as_of,omitzero"`
+	ExpiresAt time.Time   `json:"expires_at,omitzero"`
+	Status    CloneStatus `json:"status"`
+	Reason    string      `json:"reason,omitempty"`
+}
+
+// CloneRunner creates and expires restore-to-clone objects. Register it
+// with the manager via mgr.Add; it implements manager.Runnable and
+// LeaderElectionRunnable. Tests drive it through Sweep.
+type CloneRunner struct {
+	// Client reads PVCs, RDs and namespaces and writes through the
+	// executor. Production passes the auditclient-wrapped manager
+	// client.
+	Client client.Client
+
+	// Store supplies each source PVC's observed RD and receives the
+	// clone records.
+	Store *Store
+
+	// Mode gates the executor, exactly as on V4AuditReconciler.
+	Mode mode.Mode
+
+	// SystemNamespaces are never a clone's source or target.
+	SystemNamespaces map[string]struct{}
+
+	// DefaultTTL applies to requests without pvc-plumber.io/clone-ttl.
+	// <= 0 means DefaultCloneTTL.
+	DefaultTTL time.Duration
+
+	// Interval is the sweep cadence. <= 0 means
+	// DefaultCloneScanInterval.
+	Interval time.Duration
+
+	// Journal records clone writes and is where expired request ids
+	// are recovered from after a restart. nil is fine.
+	Journal *journal.Journal
+
+	// Now is injected for deterministic tests. nil → time.Now.
+	Now func() time.Time
+
+	mu            sync.Mutex
+	expired       map[string]struct{}
+	expiredLoaded bool
+}
+
+// NeedLeaderElection keeps clone writes on a single replica.
+func (r *CloneRunner) NeedLeaderElection() bool { return true }
+
+// Start runs a sweep immediately and then every Interval until ctx is
+// cancelled. Sweep errors are logged and retried on the next tick.
+func (r *CloneRunner) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("restore-clone")
+	interval := r.Interval
+	if interval <= 0 {
+		interval = DefaultCloneScanInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Sweep(ctx); err != nil {
+			logger.Error(err, "restore clone sweep failed")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep expires clones past their TTL, then acts on every clone request
+// and publishes the records to the Store. Returns an error without
+// touching the Store when a list fails; per-request failures are
+// recorded as CloneError instead.
+func (r *CloneRunner) Sweep(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+
+	r.loadExpired(ctx)
+	if err := r.expire(ctx, now); err != nil {
+		return err
+	}
+
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := r.Client.List(ctx, pvcs); err != nil {
+		return fmt.Errorf("list PVCs: %w", err)
+	}
+	records := make(map[string]CloneRecord)
+	for i := range pvcs.Items {
+		pvc := &pvcs.Items[i]
+		if _, ok := pvc.GetAnnotations()[labels.AnnotationCloneTo]; !ok || pvc.DeletionTimestamp != nil {
+			continue
+		}
+		if _, isSystem := r.SystemNamespaces[pvc.Namespace]; isSystem {
+			continue
+		}
+		records[pvc.Namespace+"/"+pvc.Name] = r.clone(ctx, pvc, now)
+	}
+	r.Store.SetClones(records)
+	return nil
+}
+
+// clone acts on one PVC's clone request.
+func (r *CloneRunner) clone(ctx context.Context, src *corev1.PersistentVolumeClaim, now time.Time) CloneRecord {
+	logger := log.FromContext(ctx).WithName("restore-clone")
+	spec := labels.Parse(src.GetLabels(), src.GetAnnotations())
+	if !spec.HasClone() {
+		return CloneRecord{
+			Target: src.GetAnnotations()[labels.AnnotationCloneTo],
+			Status: CloneError,
+			Reason: "invalid clone request: " + errors.Join(spec.Errors...).Error(),
+		}
+	}
+	ns := spec.CloneNamespace
+	if ns == "" {
+		ns = src.Namespace
+	}
+	id := cloneID(src.Namespace, src.Name, ns, spec.CloneName, spec.CloneAsOf)
+	in := builder.CloneInputs{
+		Namespace:       ns,
+		Name:            spec.CloneName,
+		RDName:          cloneRDName(src.Name, id),
+		ID:              id,
+		SourceNamespace: src.Namespace,
+		SourcePVC:       src.Name,
+		AsOf:            spec.CloneAsOf,
+	}
+	rec := CloneRecord{
+		Target: ns + "/" + in.Name,
+		RD:     ns + "/" + in.RDName,
+		ID:     id,
+		AsOf:   spec.CloneAsOf,
+	}
+	fail := func(status CloneStatus, reason string) CloneRecord {
+		rec.Status, rec.Reason = status, reason
+		return rec
+	}
+
+	if _, gone := r.expired[id]; gone {
+		return fail(CloneExpired, "TTL elapsed and the clone was deleted; remove "+labels.AnnotationCloneTo+
+			", or change the target or as-of to clone again")
+	}
+	if ns == src.Namespace && in.Name == src.Name {
+		return fail(CloneRefused, "the target is the source PVC")
+	}
+	if _, isSystem := r.SystemNamespaces[ns]; isSystem {
+		return fail(CloneRefused, "target namespace "+ns+" is a system namespace")
+	}
+	nsObj := &corev1.Namespace{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: ns}, nsObj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fail(CloneRefused, "target namespace "+ns+" does not exist")
+		}
+		return fail(CloneError, "get namespace "+ns+": "+err.Error())
+	}
+	if !labels.NamespaceManaged(nsObj.GetLabels()) {
+		return fail(CloneRefused, "target namespace "+ns+" is not labeled "+labels.NamespaceManagedLabel+"=true")
+	}
+
+	// An existing target is either this clone (report it) or someone
+	// else's PVC (never touch it).
+	target := &corev1.PersistentVolumeClaim{}
+	switch err := r.Client.Get(ctx, types.NamespacedName{Namespace: ns, Name: in.Name}, target); {
+	case err == nil:
+		if target.GetLabels()[labels.LabelClone] != "true" || target.GetAnnotations()[labels.AnnotationCloneID] != id {
+			return fail(CloneConflict, "PVC "+rec.Target+" already exists and is not this clone")
+		}
+		rec.ExpiresAt, _ = time.Parse(time.RFC3339, target.GetAnnotations()[labels.AnnotationCloneExpiresAt])
+		if target.Status.Phase == corev1.ClaimBound {
+			rec.Status = CloneReady
+		} else {
+			rec.Status = CloneRestoring
+		}
+		return rec
+	case !apierrors.IsNotFound(err):
+		return fail(CloneError, "get PVC "+rec.Target+": "+err.Error())
+	}
+
+	// The clone RD may outlive its PVC (the owner deleted the PVC by
+	// hand); then only the PVC is recreated, and the clone keeps the
+	// RD's expiry.
+	var ops []planner.PlannedOp
+	cloneRD := &unstructured.Unstructured{}
+	cloneRD.SetGroupVersionKind(rdGVK)
+	switch err := r.Client.Get(ctx, types.NamespacedName{Namespace: ns, Name: in.RDName}, cloneRD); {
+	case err == nil:
+		if cloneRD.GetLabels()[labels.LabelClone] != "true" || cloneRD.GetAnnotations()[labels.AnnotationCloneID] != id {
+			return fail(CloneConflict, "RD "+rec.RD+" already exists and is not this clone's")
+		}
+		in.ExpiresAt, _ = time.Parse(time.RFC3339, cloneRD.GetAnnotations()[labels.AnnotationCloneExpiresAt])
+	case apierrors.IsNotFound(err):
+		liveRD, reason := r.sourceRD(ctx, src)
+		if liveRD == nil {
+			return fail(CloneError, reason)
+		}
+		ttl := spec.CloneTTL
+		if ttl <= 0 {
+			ttl = r.ttl()
+		}
+		in.ExpiresAt = now.Add(ttl).UTC()
+		cloneRD = builder.BuildCloneRD(liveRD, in)
+		ops = append(ops, planner.PlannedOp{Kind: planner.OpCreate, Resource: cloneRD})
+	default:
+		return fail(CloneError, "get RD "+rec.RD+": "+err.Error())
+	}
+	pvc, err := builder.BuildClonePVC(cloneRD, in)
+	if err != nil {
+		return fail(CloneError, "render clone PVC: "+err.Error())
+	}
+	ops = append(ops, planner.PlannedOp{Kind: planner.OpCreate, Resource: pvc})
+	rec.ExpiresAt = in.ExpiresAt
+
+	res := executor.Execute(ctx, r.Client, r.Mode, planner.Plan{Ops: ops})
+	r.Journal.RecordResult(ctx, journal.TriggerRestoreClone, res)
+	for _, out := range res.Attempted {
+		switch out.Status {
+		case executor.OpFailed:
+			return fail(CloneError, fmt.Sprintf("create %s: %s: %v", out.Name, out.Reason, out.Err))
+		case executor.OpRefused:
+			return fail(CloneRefused, fmt.Sprintf("executor refused %s: %s", out.Name, out.Reason))
+		case executor.OpSkipped:
+			return fail(CloneSkipped, out.Reason)
+		}
+	}
+	logger.Info("restore clone created",
+		"source", src.Namespace+"/"+src.Name, "target", rec.Target, "rd", rec.RD,
+		"as_of", spec.CloneAsOf, "expires_at", rec.ExpiresAt)
+	rec.Status = CloneCreated
+	return rec
+}
+
+// sourceRD returns the live RD the reconciler observed for src, or nil
+// and the reason there is none.
+func (r *CloneRunner) sourceRD(ctx context.Context, src *corev1.PersistentVolumeClaim) (*unstructured.Unstructured, string) {
+	e, ok := r.Store.Get(src.Namespace, src.Name)
+	if !ok || !e.Current.RDPresent || e.Current.RDName == "" {
+		return nil, "no ReplicationDestination observed for " + src.Namespace + "/" + src.Name + " to clone from"
+	}
+	rd := &unstructured.Unstructured{}
+	rd.SetGroupVersionKind(rdGVK)
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: src.Namespace, Name: e.Current.RDName}, rd); err != nil {
+		return nil, "get RD " + src.Namespace + "/" + e.Current.RDName + ": " + err.Error()
+	}
+	return rd, ""
+}
+
+// expire deletes every clone object past its expiry, PVCs before RDs so
+// a PVC is never left pointing at a missing data source, and remembers
+// the request ids. An unparseable expiry is left alone: the object
+// carries the clone label, so deleting it on a guess would be deleting
+// something whose lifetime nobody knows.
+func (r *CloneRunner) expire(ctx context.Context, now time.Time) error {
+	logger := log.FromContext(ctx).WithName("restore-clone")
+	selector := client.MatchingLabels{
+		labels.LabelManagedByKey: labels.LabelManagedByValue,
+		labels.LabelClone:        "true",
+	}
+	var objs []*unstructured.Unstructured
+	pvcs := &unstructured.UnstructuredList{}
+	pvcs.SetGroupVersionKind(executor.AllowedClonePVCGVK.GroupVersion().WithKind(executor.AllowedClonePVCGVK.Kind + "List"))
+	if err := r.Client.List(ctx, pvcs, selector); err != nil {
+		return fmt.Errorf("list clone PVCs: %w", err)
+	}
+	for i := range pvcs.Items {
+		pvcs.Items[i].SetGroupVersionKind(executor.AllowedClonePVCGVK)
+		objs = append(objs, &pvcs.Items[i])
+	}
+	rds := &unstructured.UnstructuredList{}
+	rds.SetGroupVersionKind(rdGVK.GroupVersion().WithKind(rdGVK.Kind + "List"))
+	if err := r.Client.List(ctx, rds, selector); err != nil && !meta.IsNoMatchError(err) {
+		return fmt.Errorf("list clone RDs: %w", err)
+	}
+	for i := range rds.Items {
+		rds.Items[i].SetGroupVersionKind(rdGVK)
+		objs = append(objs, &rds.Items[i])
+	}
+
+	var ops []planner.PlannedOp
+	for _, obj := range objs {
+		if obj.GetDeletionTimestamp() != nil {
+			continue
+		}
+		if _, isSystem := r.SystemNamespaces[obj.GetNamespace()]; isSystem {
+			continue
+		}
+		raw := obj.GetAnnotations()[labels.AnnotationCloneExpiresAt]
+		at, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			logger.Info("clone object has no valid expiry; leaving it",
+				"kind", obj.GetKind(), "namespace", obj.GetNamespace(), "name", obj.GetName(), "expires_at", raw)
+			continue
+		}
+		if now.Before(at) {
+			continue
+		}
+		ops = append(ops, planner.PlannedOp{Kind: planner.OpDelete, Resource: obj.DeepCopy()})
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+	res := executor.Execute(ctx, r.Client, r.Mode, planner.Plan{Ops: ops})
+	r.Journal.RecordResult(ctx, journal.TriggerRestoreClone, res)
+	for i, out := range res.Attempted {
+		if out.Status == executor.OpSucceeded {
+			r.markExpired(ops[i].Resource.GetAnnotations()[labels.AnnotationCloneID])
+		}
+		logger.Info("restore clone expired",
+			"kind", ops[i].Resource.GetKind(), "namespace", out.Namespace, "name", out.Name,
+			"result", string(out.Status), "reason", out.Reason)
+	}
+	return nil
+}
+
+// loadExpired seeds the expired ids from the journal's succeeded clone
+// deletes, once. A journal read error is not fatal: the ids are a guard
+// against re-cloning, and the next sweep tries again.
+func (r *CloneRunner) loadExpired(ctx context.Context) {
+	if r.expiredLoaded {
+		return
+	}
+	entries, err := r.Journal.Query(ctx, journal.Filter{Op: string(planner.OpDelete), Status: string(executor.OpSucceeded)})
+	if err != nil {
+		log.FromContext(ctx).WithName("restore-clone").Error(err, "read expired clones from the journal")
+		return
+	}
+	for _, e := range entries {
+		if e.Trigger == journal.TriggerRestoreClone {
+			r.markExpired(e.Annotations[labels.AnnotationCloneID])
+		}
+	}
+	r.expiredLoaded = true
+}
+
+func (r *CloneRunner) markExpired(id string) {
+	if id == "" {
+		return
+	}
+	if r.expired == nil {
+		r.expired = make(map[string]struct{})
+	}
+	r.expired[id] = struct{}{}
+}
+
+// cloneID is 8 hex characters of sha256 over the request: source,
+// target and as-of. The TTL is deliberately not part of it — extending a
+// TTL should not re-clone.
+func cloneID(srcNS, srcPVC, dstNS, dstName string, asOf time.Time) string {
+	var at string
+	if !asOf.IsZero() {
+		at = asOf.UTC().Format(time.RFC3339)
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{srcNS, srcPVC, dstNS, dstName, at}, "\x00")))
+	return hex.EncodeToString(sum[:4])
+}
+
+// cloneRDName is "<pvc>-clone-<id>", with the PVC part shortened when
+// the whole would not be a valid object name.
+func cloneRDName(pvc, id string) string {
+	const maxName = 253
+	suffix := "-clone-" + id
+	if len(pvc)+len(suffix) > maxName {
+		pvc = strings.TrimRight(pvc[:maxName-len(suffix)], "-.")
+	}
+	return pvc + suffix
+}
+
+func (r *CloneRunner) ttl() time.Duration {
+	if r.DefaultTTL <= 0 {
+		return DefaultCloneTTL
+	}
+	return r.DefaultTTL
+}
+
+func (r *CloneRunner) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
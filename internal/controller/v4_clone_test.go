@@ -0,0 +1,314 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/auditclient"
+	"github.com/mitchross/pvc-plumber/internal/v4/builder"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+	v4labels "github.com/mitchross/pvc-plumber/internal/v4/labels"
+	"github.com/mitchross/pvc-plumber/internal/v4/mode"
+	"github.com/mitchross/pvc-plumber/internal/v4/naming"
+)
+
+const testCloneNS = "scratch"
+
+// cloneFixture wires a CloneRunner to a fake client behind the
+// auditclient wrapper, with managed namespaces myapp and scratch and an
+// unmanaged one, other.
+type cloneFixture struct {
+	t       *testing.T
+	fake    client.WithWatch
+	store   *Store
+	journal *journal.Journal
+	runner  *CloneRunner
+	clock   time.Time
+}
+
+func newCloneFixture(t *testing.T, m mode.Mode) *cloneFixture {
+	t.Helper()
+	managed := map[string]string{v4labels.NamespaceManagedLabel: "true"}
+	fakeC := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: testNSMyapp, Labels: managed}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: testCloneNS, Labels: managed}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", Labels: managed}},
+	).Build()
+	auditC := auditclient.New(fakeC, m, slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)))
+	store := NewStore(m.String(), "bare-dst", testRepoSecretShare)
+	store.now = fixedTime
+	f := &cloneFixture{t: t, fake: fakeC, store: store, journal: journal.New(nil), clock: fixedTime()}
+	f.runner = f.newRunner(auditC, m)
+	return f
+}
+
+func (f *cloneFixture) newRunner(c client.Client, m mode.Mode) *CloneRunner {
+	return &CloneRunner{
+		Client:           c,
+		Store:            f.store,
+		Mode:             m,
+		SystemNamespaces: map[string]struct{}{"kube-system": {}},
+		DefaultTTL:       24 * time.Hour,
+		Journal:          f.journal,
+		Now:              func() time.Time { return f.clock },
+	}
+}
+
+// source seeds the PVC myapp/data with anns, its operator-owned RD, and
+// the Store entry the reconciler would have recorded for it.
+func (f *cloneFixture) source(anns map[string]string) *unstructured.Unstructured {
+	f.t.Helper()
+	in := builder.Inputs{
+		Namespace: testNSMyapp, PVCName: "data", PVCCapacity: "1Gi", PVCAccessModes: []string{"ReadWriteOnce"},
+		Spec: v4labels.Spec{Tier: v4labels.TierDaily}, NamingStrategy: naming.StrategyBareDst,
+		DefaultRepoSecret: testRepoSecretShare, DefaultStorageClass: "longhorn",
+		DefaultUID: 568, DefaultGID: 568, DefaultFSGroup: 568,
+	}
+	rd := builder.BuildRD(in)
+	f.store.Set(ParityEntry{
+		Namespace: testNSMyapp, PVC: "data",
+		Current: CurrentState{RDPresent: true, RDName: rd.GetName(), RDManagedBy: v4labels.LabelManagedByValue},
+	})
+	f.seed(makePVC(testNSMyapp, "data", labelsEnabledManage(), anns), rd)
+	return rd
+}
+
+func (f *cloneFixture) seed(objs ...client.Object) {
+	f.t.Helper()
+	for _, o := range objs {
+		if err := f.fake.Create(context.Background(), o); err != nil {
+			f.t.Fatal(err)
+		}
+	}
+}
+
+// sweep runs one sweep and returns the source PVC's clone record.
+func (f *cloneFixture) sweep() *CloneRecord {
+	f.t.Helper()
+	if err := f.runner.Sweep(context.Background()); err != nil {
+		f.t.Fatalf("Sweep: %v", err)
+	}
+	for _, e := range f.store.Snapshot().Entries {
+		if e.Namespace == testNSMyapp && e.PVC == "data" {
+			return e.RestoreClone
+		}
+	}
+	return nil
+}
+
+func (f *cloneFixture) get(ns, name string, obj client.Object) bool {
+	f.t.Helper()
+	return f.fake.Get(context.Background(), types.NamespacedName{Namespace: ns, Name: name}, obj) == nil
+}
+
+func TestCloneRunner_CreatesAndExpires(t *testing.T) {
+	f := newCloneFixture(t, mode.Permissive)
+	liveRD := f.source(map[string]string{
+		v4labels.AnnotationCloneTo:   testCloneNS + "/data-restore",
+		v4labels.AnnotationCloneAsOf: "2026-05-17T03:00:00Z",
+		v4labels.AnnotationCloneTTL:  "2h",
+	})
+
+	rec := f.sweep()
+	if rec == nil || rec.Status != CloneCreated || rec.Target != testCloneNS+"/data-restore" {
+		t.Fatalf("record: %+v", rec)
+	}
+	if !strings.HasPrefix(rec.RD, testCloneNS+"/data-clone-") || !rec.ExpiresAt.Equal(f.clock.Add(2*time.Hour)) {
+		t.Errorf("record: %+v", rec)
+	}
+
+	rd := &unstructured.Unstructured{}
+	rd.SetGroupVersionKind(rdGVK)
+	if !f.get(testCloneNS, strings.TrimPrefix(rec.RD, testCloneNS+"/"), rd) {
+		t.Fatal("clone RD not created")
+	}
+	asOf, _, _ := unstructured.NestedString(rd.Object, "spec", "kopia", "restoreAsOf")
+	wantSrc, _, _ := unstructured.NestedString(liveRD.Object, "spec", "kopia", "username")
+	gotSrc, _, _ := unstructured.NestedString(rd.Object, "spec", "kopia", "username")
+	if asOf != "2026-05-17T03:00:00Z" || gotSrc != wantSrc {
+		t.Errorf("clone RD: restoreAsOf=%q username=%q (want %q)", asOf, gotSrc, wantSrc)
+	}
+	pvc := &corev1.PersistentVolumeClaim{}
+	if !f.get(testCloneNS, "data-restore", pvc) {
+		t.Fatal("clone PVC not created")
+	}
+	if ref := pvc.Spec.DataSourceRef; ref == nil || ref.Kind != rdGVK.Kind || ref.Name != rd.GetName() {
+		t.Errorf("dataSourceRef: %+v", ref)
+	}
+
+	if rec := f.sweep(); rec.Status != CloneRestoring {
+		t.Errorf("second sweep: %+v", rec)
+	}
+	pvc.Status.Phase = corev1.ClaimBound
+	if err := f.fake.Status().Update(context.Background(), pvc); err != nil {
+		t.Fatal(err)
+	}
+	if rec := f.sweep(); rec.Status != CloneReady {
+		t.Errorf("bound: %+v", rec)
+	}
+
+	f.clock = f.clock.Add(2 * time.Hour)
+	if rec := f.sweep(); rec.Status != CloneExpired {
+		t.Errorf("after TTL: %+v", rec)
+	}
+	if f.get(testCloneNS, "data-restore", &corev1.PersistentVolumeClaim{}) || f.get(testCloneNS, rd.GetName(), rd) {
+		t.Error("clone objects survived expiry")
+	}
+	// The source's own objects are untouched.
+	if !f.get(testNSMyapp, "data", &corev1.PersistentVolumeClaim{}) || !f.get(testNSMyapp, liveRD.GetName(), liveRD) {
+		t.Error("source PVC or RD gone")
+	}
+
+	// The annotation is still there; the clone is not recreated, by
+	// this runner or by one restarted over the same journal.
+	if rec := f.sweep(); rec.Status != CloneExpired {
+		t.Errorf("after expiry: %+v", rec)
+	}
+	f.runner = f.newRunner(f.runner.Client, mode.Permissive)
+	if rec := f.sweep(); rec.Status != CloneExpired || f.get(testCloneNS, "data-restore", &corev1.PersistentVolumeClaim{}) {
+		t.Errorf("restarted runner re-cloned: %+v", rec)
+	}
+
+	entries, _ := f.journal.Query(context.Background(), journal.Filter{})
+	var creates, deletes int
+	for _, e := range entries {
+		if e.Trigger != journal.TriggerRestoreClone || e.Status != "succeeded" {
+			t.Errorf("journal entry: %+v", e)
+		}
+		switch e.Op {
+		case "create":
+			creates++
+		case "delete":
+			deletes++
+		}
+	}
+	if creates != 2 || deletes != 2 {
+		t.Errorf("journal: %d creates, %d deletes", creates, deletes)
+	}
+}
+
+func TestCloneRunner_RefusesUnsafeTargets(t *testing.T) {
+	cases := []struct {
+		name   string
+		anns   map[string]string
+		seed   []client.Object
+		noRD   bool
+		mode   mode.Mode
+		status CloneStatus
+		reason string
+	}{
+		{name: "unmanaged namespace", anns: map[string]string{v4labels.AnnotationCloneTo: "other/x"}, status: CloneRefused, reason: v4labels.NamespaceManagedLabel},
+		{name: "system namespace", anns: map[string]string{v4labels.AnnotationCloneTo: "kube-system/x"}, status: CloneRefused, reason: "system namespace"},
+		{name: "missing namespace", anns: map[string]string{v4labels.AnnotationCloneTo: "nowhere/x"}, status: CloneRefused, reason: "does not exist"},
+		{name: "the source itself", anns: map[string]string{v4labels.AnnotationCloneTo: "data"}, status: CloneRefused, reason: "source PVC"},
+		{
+			name:   "existing PVC",
+			anns:   map[string]string{v4labels.AnnotationCloneTo: testCloneNS + "/taken"},
+			seed:   []client.Object{makePVC(testCloneNS, "taken", nil, nil)},
+			status: CloneConflict,
+		},
+		{name: "no RD to clone", anns: map[string]string{v4labels.AnnotationCloneTo: "x"}, noRD: true, status: CloneError, reason: "no ReplicationDestination"},
+		{name: "malformed", anns: map[string]string{v4labels.AnnotationCloneTo: "Not_A_Name"}, status: CloneError, reason: "invalid clone request"},
+		{name: "audit mode", anns: map[string]string{v4labels.AnnotationCloneTo: "x"}, mode: mode.Audit, status: CloneSkipped},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := tc.mode
+			if m == mode.Unspecified {
+				m = mode.Permissive
+			}
+			f := newCloneFixture(t, m)
+			f.seed(tc.seed...)
+			if tc.noRD {
+				f.seed(makePVC(testNSMyapp, "data", labelsEnabledManage(), tc.anns))
+				f.store.Set(ParityEntry{Namespace: testNSMyapp, PVC: "data"})
+			} else {
+				f.source(tc.anns)
+			}
+			rec := f.sweep()
+			if rec == nil || rec.Status != tc.status || !strings.Contains(rec.Reason, tc.reason) {
+				t.Fatalf("record: %+v, want %s containing %q", rec, tc.status, tc.reason)
+			}
+			for _, e := range mustQuery(t, f.journal) {
+				if e.Op == "create" && e.Status == "succeeded" {
+					t.Errorf("wrote %s %s/%s", e.Kind(), e.Namespace, e.Name)
+				}
+			}
+			if tc.status == CloneConflict {
+				taken := &corev1.PersistentVolumeClaim{}
+				if !f.get(testCloneNS, "taken", taken) || taken.Spec.DataSourceRef != nil || len(taken.Labels) != 0 {
+					t.Errorf("existing PVC touched: %+v", taken)
+				}
+			}
+		})
+	}
+}
+
+// The clone's RD outliving its PVC (deleted by hand) gets only the PVC
+// back, with the RD's original expiry.
+func TestCloneRunner_RecreatesDeletedPVC(t *testing.T) {
+	f := newCloneFixture(t, mode.Permissive)
+	f.source(map[string]string{v4labels.AnnotationCloneTo: "data-restore"})
+	first := f.sweep()
+	if first.Status != CloneCreated {
+		t.Fatalf("first sweep: %+v", first)
+	}
+	pvc := &corev1.PersistentVolumeClaim{}
+	f.get(testNSMyapp, "data-restore", pvc)
+	if err := f.fake.Delete(context.Background(), pvc); err != nil {
+		t.Fatal(err)
+	}
+
+	f.clock = f.clock.Add(time.Hour)
+	rec := f.sweep()
+	if rec.Status != CloneCreated || !rec.ExpiresAt.Equal(first.ExpiresAt) {
+		t.Errorf("recreate: %+v, want expiry %v", rec, first.ExpiresAt)
+	}
+	if !f.get(testNSMyapp, "data-restore", &corev1.PersistentVolumeClaim{}) {
+		t.Error("PVC not recreated")
+	}
+}
+
+func TestCloneIDAndRDName(t *testing.T) {
+	asOf := fixedTime()
+	base := cloneID("ns", "data", "ns", "restore", asOf)
+	if len(base) != 8 || base != cloneID("ns", "data", "ns", "restore", asOf.In(time.FixedZone("x", 3600))) {
+		t.Errorf("id %q not stable", base)
+	}
+	for _, other := range []string{
+		cloneID("ns", "data", "ns", "restore", time.Time{}),
+		cloneID("ns", "data", "scratch", "restore", asOf),
+		cloneID("ns", "data", "ns", "restore-2", asOf),
+	} {
+		if other == base {
+			t.Error("different request, same id")
+		}
+	}
+	if got := cloneRDName("data", base); got != "data-clone-"+base {
+		t.Errorf("cloneRDName: %q", got)
+	}
+	if got := cloneRDName(strings.Repeat("a", 253), base); len(got) > 253 || !strings.HasSuffix(got, "-clone-"+base) {
+		t.Errorf("long name: %d %q", len(got), got)
+	}
+}
+
+func mustQuery(t *testing.T, j *journal.Journal) []journal.Entry {
+	t.Helper()
+	entries, err := j.Query(context.Background(), journal.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
@@ -0,0 +1,673 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/builder"
+	"github.com/mitchross/pvc-plumber/internal/v4/executor"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+	"github.com/mitchross/pvc-plumber/internal/v4/labels"
+	"github.com/mitchross/pvc-plumber/internal/v4/mode"
+)
+
+// Restore drills.
+//
+// A green RS status proves the mover uploaded something; it does not
+// prove the upload restores. Every restore failure this operator has
+// seen in practice — a PVC backed up under the wrong identity, a
+// snapshot of an empty volume taken before the app wrote anything, a
+// repository Secret rotated in one namespace only — was invisible until
+// the day the restore was needed. DrillRunner exercises the restore path
+// continuously instead, one PVC at a time:
+//
+//  1. pick the managed PVC drilled longest ago (never-drilled first);
+//  2. in the sandbox namespace, create an RD cloned from the PVC's live
+//     operator-owned RD (builder.BuildDrillRD) with a unique manual
+//     trigger, and a PVC whose dataSourceRef points at it;
+//  3. wait for the RD's status.lastManualSync to reach the trigger;
+//  4. run a verification Job against the restored PVC (file count, a
+//     checksum manifest, or an app-provided probe — labels.DrillVerify);
+//  5. record the result on the PVC's /audit entry and delete the drill
+//     objects.
+//
+// The whole drill is bounded by Timeout; running out is a failed drill.
+// A drill the runner could not even set up (RBAC, a malformed RD) is
+// recorded as an error, not a failure — that is the operator's problem,
+// not the backup's.
+//
+// Write boundary. Drill writes bypass executor.Execute, whose allow-list
+// is RS/RD only and must stay that way for the reconciler. The runner
+// applies its own: RD, PVC and Job only, only in SandboxNamespace, and a
+// delete only of objects carrying the drill label. Every write is
+// journaled with Trigger=restore-drill. The sandbox needs the kopia
+// repository Secret the drilled RDs reference; nothing is ever written
+// in the drilled PVC's own namespace.
+//
+// Scheduling state (when each PVC was last drilled, the in-flight drill)
+// is in memory. A restart forgets it: the next drill starts with
+// never-drilled PVCs again and the sweep at the start of every drill
+// deletes whatever the interrupted one left in the sandbox.
+
+// DefaultDrillInterval is the gap between the end of one drill and the
+// start of the next when none is configured.
+const DefaultDrillInterval = 6 * time.Hour
+
+// DefaultDrillTimeout bounds one drill (restore plus verification) when
+// none is configured.
+const DefaultDrillTimeout = 2 * time.Hour
+
+// DefaultDrillImage runs the verification Job when neither the operator
+// nor the PVC names one. It needs sh, find, wc and sha256sum.
+const DefaultDrillImage = "busybox:1.37"
+
+// DefaultDrillPollInterval is how often Start steps an in-flight drill.
+const DefaultDrillPollInterval = 30 * time.Second
+
+// DrillResult is the outcome of one drill.
+type DrillResult string
+
+const (
+	// DrillPassed: the restore completed and verification succeeded.
+	DrillPassed DrillResult = "passed"
+
+	// DrillFailed: the restore or the verification failed or timed out.
+	// The backup is suspect.
+	DrillFailed DrillResult = "failed"
+
+	// DrillError: the drill could not be set up or lost its objects.
+	// Says nothing about the backup.
+	DrillError DrillResult = "error"
+)
+
+// AllDrillResults returns every result in a stable order, for the
+// zero-filled summary map.
+func AllDrillResults() []DrillResult {
+	return []DrillResult{DrillPassed, DrillFailed, DrillError}
+}
+
+// DrillRecord is the latest drill of one PVC, surfaced as
+// ParityEntry.RestoreDrill.
+type DrillRecord struct {
+	Result DrillResult `json:"result"`
+	Verify string      `json:"verify"`
+	// Drill is "<sandbox>/<name>" of the drill objects, for correlating
+	// with the journal and events. The objects themselves are gone.
+	Drill           string    `json:"drill"`
+	StartedAt       time.Time `json:"started_at"`
+	FinishedAt      time.Time `json:"finished_at"`
+	DurationSeconds int64     `json:"duration_seconds"`
+	Reason          string    `json:"reason,omitempty"`
+}
+
+var (
+	drillPVCGVK = corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaim")
+	drillJobGVK = batchv1.SchemeGroupVersion.WithKind("Job")
+)
+
+// Drill write refusal reasons, journaled like executor refusals.
+const (
+	drillRefuseKind    = "forbidden-kind"
+	drillRefuseSandbox = "outside-sandbox"
+	drillRefuseOwner   = "not-drill-owned"
+)
+
+// drillPhase is where an in-flight drill is.
+type drillPhase int
+
+const (
+	drillRestoring drillPhase = iota
+	drillVerifying
+)
+
+// drillRun is the in-flight drill.
+type drillRun struct {
+	namespace, pvc string
+	in             builder.DrillInputs
+	phase          drillPhase
+	started        time.Time
+}
+
+func (d *drillRun) key() string { return d.namespace + "/" + d.pvc }
+
+// DrillRunner schedules and runs restore drills. Register it with the
+// manager via mgr.Add; it implements manager.Runnable and
+// LeaderElectionRunnable. Tests drive it through Step.
+type DrillRunner struct {
+	// Client reads entries' PVCs and RDs and writes the drill objects.
+	// Production passes the auditclient-wrapped manager client.
+	Client client.Client
+
+	// Store supplies the candidate PVCs (its reconciled entries) and
+	// receives the drill records.
+	Store *Store
+
+	// Mode gates writes as on the executor: audit and unspecified never
+	// drill.
+	Mode mode.Mode
+
+	// SandboxNamespace is where every drill object is created. Required;
+	// must not be a system namespace.
+	SandboxNamespace string
+
+	// SystemNamespaces are never drilled and never a sandbox.
+	SystemNamespaces map[string]struct{}
+
+	// Interval is the gap between drills. <= 0 means DefaultDrillInterval.
+	Interval time.Duration
+
+	// Timeout bounds one drill. <= 0 means DefaultDrillTimeout.
+	Timeout time.Duration
+
+	// Image runs the verification Job unless the PVC overrides it.
+	// Empty means DefaultDrillImage.
+	Image string
+
+	// PollInterval is how often Start calls Step. <= 0 means
+	// DefaultDrillPollInterval.
+	PollInterval time.Duration
+
+	// Journal records every drill write. nil is fine.
+	Journal *journal.Journal
+
+	// Now is injected for deterministic tests. nil → time.Now.
+	Now func() time.Time
+
+	mu        sync.Mutex
+	active    *drillRun
+	nextAt    time.Time
+	lastDrill map[string]time.Time
+}
+
+// NeedLeaderElection keeps drills on a single replica.
+func (r *DrillRunner) NeedLeaderElection() bool { return true }
+
+// Start steps the runner every PollInterval until ctx is cancelled.
+// Step errors are logged and retried on the next tick.
+func (r *DrillRunner) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("restore-drill")
+	interval := r.PollInterval
+	if interval <= 0 {
+		interval = DefaultDrillPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Step(ctx); err != nil {
+			logger.Error(err, "restore drill step failed")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Step advances the drill state machine by at most one transition:
+// start a drill when one is due, or check the in-flight one. Returns an
+// error only for conditions worth retrying (apiserver reads, the sweep);
+// drill outcomes, including setup errors, are recorded, not returned.
+func (r *DrillRunner) Step(ctx context.Context) error {
+	if r.Mode == mode.Audit || r.Mode == mode.Unspecified {
+		return nil
+	}
+	if err := r.checkSandbox(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if r.active == nil {
+		if now.Before(r.nextAt) {
+			return nil
+		}
+		return r.begin(ctx, now)
+	}
+	return r.advance(ctx, now)
+}
+
+// checkSandbox refuses a configuration that would let drill writes land
+// anywhere but a dedicated namespace.
+func (r *DrillRunner) checkSandbox() error {
+	if r.SandboxNamespace == "" {
+		return errors.New("restore drill: no sandbox namespace configured")
+	}
+	if _, isSystem := r.SystemNamespaces[r.SandboxNamespace]; isSystem {
+		return fmt.Errorf("restore drill: sandbox namespace %q is a system namespace", r.SandboxNamespace)
+	}
+	return nil
+}
+
+// drillCandidate is a PVC eligible for the next drill.
+type drillCandidate struct {
+	namespace, pvc string
+	spec           labels.Spec
+	rd             *unstructured.Unstructured
+}
+
+// begin sweeps the sandbox, picks the next PVC and creates its drill RD
+// and PVC.
+func (r *DrillRunner) begin(ctx context.Context, now time.Time) error {
+	logger := log.FromContext(ctx).WithName("restore-drill")
+	if err := r.sweep(ctx); err != nil {
+		return err
+	}
+	c, ok, err := r.pick(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		r.nextAt = now.Add(r.interval())
+		logger.V(1).Info("no PVC eligible for a restore drill")
+		return nil
+	}
+
+	image := c.spec.DrillImage
+	if image == "" {
+		image = r.Image
+	}
+	if image == "" {
+		image = DefaultDrillImage
+	}
+	run := &drillRun{
+		namespace: c.namespace,
+		pvc:       c.pvc,
+		started:   now,
+		in: builder.DrillInputs{
+			SandboxNamespace: r.SandboxNamespace,
+			Name:             drillName(c.namespace, c.pvc, now),
+			Trigger:          "drill-" + strconv.FormatInt(now.Unix(), 10),
+			SourceNamespace:  c.namespace,
+			SourcePVC:        c.pvc,
+			Verify:           c.spec.DrillVerify,
+			Manifest:         c.spec.DrillManifest,
+			Probe:            c.spec.DrillProbe,
+			Image:            image,
+		},
+	}
+
+	rd := builder.BuildDrillRD(c.rd, run.in)
+	pvc, err := builder.BuildDrillPVC(rd, run.in)
+	if err != nil {
+		r.finish(ctx, run, now, DrillError, "render drill PVC: "+err.Error())
+		return nil
+	}
+	if err := r.write(ctx, drillOpCreate, rd); err != nil {
+		r.finish(ctx, run, now, DrillError, "create drill RD: "+err.Error())
+		return nil
+	}
+	if err := r.write(ctx, drillOpCreate, pvc); err != nil {
+		r.finish(ctx, run, now, DrillError, "create drill PVC: "+err.Error())
+		return nil
+	}
+	r.active = run
+	logger.Info("restore drill started",
+		"pvc", run.key(), "drill", r.SandboxNamespace+"/"+run.in.Name, "verify", string(run.in.Verify))
+	return nil
+}
+
+// pick returns the eligible PVC drilled longest ago, never-drilled
+// first, ties broken by key. Eligible: the reconciler saw an
+// operator-owned RD and at least one completed backup, the PVC still
+// exists and has not opted out, and neither the PVC nor the RD lives in
+// a system namespace or the sandbox.
+func (r *DrillRunner) pick(ctx context.Context) (drillCandidate, bool, error) {
+	var entries []ParityEntry
+	for _, e := range r.Store.Snapshot().Entries {
+		if _, isSystem := r.SystemNamespaces[e.Namespace]; isSystem || e.Namespace == r.SandboxNamespace {
+			continue
+		}
+		if !e.Current.RDPresent || e.Current.RDManagedBy != labels.LabelManagedByValue || e.LastBackupAt.IsZero() {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		ti, tj := r.lastDrill[entries[i].Key()], r.lastDrill[entries[j].Key()]
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return entries[i].Key() < entries[j].Key()
+	})
+
+	for _, e := range entries {
+		pvc := &corev1.PersistentVolumeClaim{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: e.Namespace, Name: e.PVC}, pvc); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return drillCandidate{}, false, fmt.Errorf("get PVC %s: %w", e.Key(), err)
+		}
+		if pvc.DeletionTimestamp != nil {
+			continue
+		}
+		spec := labels.Parse(pvc.GetLabels(), pvc.GetAnnotations())
+		if spec.DrillVerify == labels.DrillVerifyNone {
+			continue
+		}
+		rd := &unstructured.Unstructured{}
+		rd.SetGroupVersionKind(rdGVK)
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: e.Namespace, Name: e.Current.RDName}, rd); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return drillCandidate{}, false, fmt.Errorf("get RD %s/%s: %w", e.Namespace, e.Current.RDName, err)
+		}
+		if rd.GetLabels()[labels.LabelManagedByKey] != labels.LabelManagedByValue {
+			continue
+		}
+		return drillCandidate{namespace: e.Namespace, pvc: e.PVC, spec: spec, rd: rd}, true, nil
+	}
+	return drillCandidate{}, false, nil
+}
+
+// advance checks the in-flight drill.
+func (r *DrillRunner) advance(ctx context.Context, now time.Time) error {
+	run := r.active
+	elapsed := now.Sub(run.started)
+	timeout := r.timeout()
+	key := types.NamespacedName{Namespace: r.SandboxNamespace, Name: run.in.Name}
+
+	switch run.phase {
+	case drillRestoring:
+		rd := &unstructured.Unstructured{}
+		rd.SetGroupVersionKind(rdGVK)
+		if err := r.Client.Get(ctx, key, rd); err != nil {
+			if apierrors.IsNotFound(err) {
+				r.finish(ctx, run, now, DrillError, "drill RD disappeared during restore")
+				return nil
+			}
+			return fmt.Errorf("get drill RD %s: %w", key, err)
+		}
+		if synced, _, _ := unstructured.NestedString(rd.Object, "status", "lastManualSync"); synced != run.in.Trigger {
+			if elapsed > timeout {
+				r.finish(ctx, run, now, DrillFailed, "restore did not complete within "+timeout.String()+moverResultSuffix(rd))
+			}
+			return nil
+		}
+		// Whatever is left of the budget bounds the verification pod too,
+		// so a hung probe cannot outlive the drill.
+		run.in.JobDeadline = max(int64((timeout - elapsed).Seconds()), 1)
+		job, err := builder.BuildDrillJob(rd, run.in)
+		if err != nil {
+			r.finish(ctx, run, now, DrillError, "render verification Job: "+err.Error())
+			return nil
+		}
+		if err := r.write(ctx, drillOpCreate, job); err != nil {
+			r.finish(ctx, run, now, DrillError, "create verification Job: "+err.Error())
+			return nil
+		}
+		run.phase = drillVerifying
+		return nil
+
+	default:
+		job := &batchv1.Job{}
+		if err := r.Client.Get(ctx, key, job); err != nil {
+			if apierrors.IsNotFound(err) {
+				r.finish(ctx, run, now, DrillError, "verification Job disappeared")
+				return nil
+			}
+			return fmt.Errorf("get verification Job %s: %w", key, err)
+		}
+		if done, failed, msg := jobOutcome(job); done {
+			if failed {
+				r.finish(ctx, run, now, DrillFailed, "verification ("+string(run.in.Verify)+") failed"+msg)
+			} else {
+				r.finish(ctx, run, now, DrillPassed, "")
+			}
+			return nil
+		}
+		if elapsed > timeout {
+			r.finish(ctx, run, now, DrillFailed, "verification did not finish within "+timeout.String())
+		}
+		return nil
+	}
+}
+
+// jobOutcome reads a Job's terminal state. msg is ": <condition
+// message>" when the Job failed with one.
+func jobOutcome(job *batchv1.Job) (done, failed bool, msg string) {
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			return true, false, ""
+		case batchv1.JobFailed:
+			if c.Message != "" {
+				msg = ": " + c.Message
+			}
+			return true, true, msg
+		}
+	}
+	switch {
+	case job.Status.Succeeded > 0:
+		return true, false, ""
+	case job.Status.Failed > 0:
+		return true, true, ""
+	}
+	return false, false, ""
+}
+
+// moverResultSuffix names the RD's latest mover result, when it has
+// one, for the timeout reason.
+func moverResultSuffix(rd *unstructured.Unstructured) string {
+	if res, _, _ := unstructured.NestedString(rd.Object, "status", "latestMoverStatus", "result"); res != "" {
+		return " (latest mover result: " + res + ")"
+	}
+	return ""
+}
+
+// finish records the drill, deletes its objects and schedules the next
+// one. Cleanup failures are logged only: the sweep before the next
+// drill retries them.
+func (r *DrillRunner) finish(ctx context.Context, run *drillRun, now time.Time, result DrillResult, reason string) {
+	logger := log.FromContext(ctx).WithName("restore-drill")
+	r.Store.SetDrill(run.namespace, run.pvc, DrillRecord{
+		Result:          result,
+		Verify:          string(run.in.Verify),
+		Drill:           r.SandboxNamespace + "/" + run.in.Name,
+		StartedAt:       run.started,
+		FinishedAt:      now,
+		DurationSeconds: int64(now.Sub(run.started).Seconds()),
+		Reason:          reason,
+	})
+	if r.lastDrill == nil {
+		r.lastDrill = make(map[string]time.Time)
+	}
+	r.lastDrill[run.key()] = now
+	r.active = nil
+	r.nextAt = now.Add(r.interval())
+
+	logger.Info("restore drill finished",
+		"pvc", run.key(), "drill", r.SandboxNamespace+"/"+run.in.Name,
+		"result", string(result), "reason", reason, "duration", now.Sub(run.started).String())
+
+	key := types.NamespacedName{Namespace: r.SandboxNamespace, Name: run.in.Name}
+	rd := &unstructured.Unstructured{}
+	rd.SetGroupVersionKind(rdGVK)
+	for _, obj := range []client.Object{&batchv1.Job{}, &corev1.PersistentVolumeClaim{}, rd} {
+		if err := r.Client.Get(ctx, key, obj); err != nil {
+			if !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+				logger.Error(err, "restore drill cleanup: get failed", "name", key.String())
+			}
+			continue
+		}
+		if err := r.write(ctx, drillOpDelete, obj); err != nil {
+			logger.Error(err, "restore drill cleanup: delete failed", "name", key.String())
+		}
+	}
+}
+
+// sweep deletes every drill-labeled object in the sandbox. Runs before
+// each drill, so only leftovers from an earlier drill (a crash, a failed
+// cleanup) are ever found.
+func (r *DrillRunner) sweep(ctx context.Context) error {
+	selector := client.MatchingLabels{
+		labels.LabelManagedByKey: labels.LabelManagedByValue,
+		labels.LabelDrill:        "true",
+	}
+	in := client.InNamespace(r.SandboxNamespace)
+
+	var stale []client.Object
+	jobs := &batchv1.JobList{}
+	if err := r.Client.List(ctx, jobs, in, selector); err != nil {
+		return fmt.Errorf("list drill Jobs: %w", err)
+	}
+	for i := range jobs.Items {
+		stale = append(stale, &jobs.Items[i])
+	}
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := r.Client.List(ctx, pvcs, in, selector); err != nil {
+		return fmt.Errorf("list drill PVCs: %w", err)
+	}
+	for i := range pvcs.Items {
+		stale = append(stale, &pvcs.Items[i])
+	}
+	rds := &unstructured.UnstructuredList{}
+	rds.SetGroupVersionKind(rdGVK.GroupVersion().WithKind(rdGVK.Kind + "List"))
+	if err := r.Client.List(ctx, rds, in, selector); err != nil && !meta.IsNoMatchError(err) {
+		return fmt.Errorf("list drill RDs: %w", err)
+	}
+	for i := range rds.Items {
+		rds.Items[i].SetGroupVersionKind(rdGVK)
+		stale = append(stale, &rds.Items[i])
+	}
+
+	var errs []error
+	for _, obj := range stale {
+		if obj.GetDeletionTimestamp() != nil {
+			continue
+		}
+		if err := r.write(ctx, drillOpDelete, obj); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Drill write ops, journaled as Entry.Op.
+const (
+	drillOpCreate = "create"
+	drillOpDelete = "delete"
+)
+
+// write is the drill's only path to the apiserver for mutations. It
+// enforces the drill allow-list (module doc), performs the op, and
+// journals the outcome. A delete of an already-gone object is a no-op.
+func (r *DrillRunner) write(ctx context.Context, op string, obj client.Object) error {
+	gvk, err := apiutil.GVKForObject(obj, r.Client.Scheme())
+	if err != nil {
+		return fmt.Errorf("resolve kind of %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+	}
+	entry := journal.Entry{
+		Trigger:     journal.TriggerRestoreDrill,
+		Op:          op,
+		GVK:         gvk.GroupVersion().String() + "/" + gvk.Kind,
+		Namespace:   obj.GetNamespace(),
+		Name:        obj.GetName(),
+		Labels:      obj.GetLabels(),
+		Annotations: obj.GetAnnotations(),
+	}
+	if u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj); err == nil {
+		if spec, ok := u["spec"].(map[string]any); ok {
+			entry.Spec = spec
+			entry.SpecHash = journal.SpecHash(spec)
+		}
+	}
+
+	if reason := r.refuse(gvk, obj); reason != "" {
+		entry.Status, entry.Reason = string(executor.OpRefused), reason
+		r.Journal.Append(ctx, entry)
+		return fmt.Errorf("refused %s %s %s/%s: %s", op, gvk.Kind, obj.GetNamespace(), obj.GetName(), reason)
+	}
+
+	switch op {
+	case drillOpCreate:
+		err = r.Client.Create(ctx, obj)
+		entry.AfterResourceVersion = obj.GetResourceVersion()
+	default:
+		entry.BeforeResourceVersion = obj.GetResourceVersion()
+		err = r.Client.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+	}
+	if err != nil {
+		entry.Status, entry.Error = string(executor.OpFailed), err.Error()
+	} else {
+		entry.Status = string(executor.OpSucceeded)
+	}
+	r.Journal.Append(ctx, entry)
+	return err
+}
+
+// refuse returns the reason a drill write is not allowed, or "".
+func (r *DrillRunner) refuse(gvk schema.GroupVersionKind, obj client.Object) string {
+	switch gvk {
+	case rdGVK, drillPVCGVK, drillJobGVK:
+	default:
+		return drillRefuseKind
+	}
+	if obj.GetNamespace() == "" || obj.GetNamespace() != r.SandboxNamespace {
+		return drillRefuseSandbox
+	}
+	lbls := obj.GetLabels()
+	if lbls[labels.LabelDrill] != "true" || lbls[labels.LabelManagedByKey] != labels.LabelManagedByValue {
+		return drillRefuseOwner
+	}
+	return ""
+}
+
+// drillName is "drill-<hash of ns/pvc>-<start time>": a valid DNS label
+// whatever the PVC is called (PVC names may be 253 characters, Job names
+// may not), and unique per drill so a previous drill's PVC still
+// terminating never collides with the next one's.
+func drillName(namespace, pvc string, now time.Time) string {
+	sum := sha256.Sum256([]byte(namespace + "/" + pvc))
+	return "drill-" + hex.EncodeToString(sum[:4]) + "-" + strconv.FormatInt(now.Unix(), 36)
+}
+
+func (r *DrillRunner) interval() time.Duration {
+	if r.Interval <= 0 {
+		return DefaultDrillInterval
+	}
+	return r.Interval
+}
+
+func (r *DrillRunner) timeout() time.Duration {
+	if r.Timeout <= 0 {
+		return DefaultDrillTimeout
+	}
+	return r.Timeout
+}
+
+func (r *DrillRunner) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,374 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/auditclient"
+	"github.com/mitchross/pvc-plumber/internal/v4/builder"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+	v4labels "github.com/mitchross/pvc-plumber/internal/v4/labels"
+	"github.com/mitchross/pvc-plumber/internal/v4/mode"
+	"github.com/mitchross/pvc-plumber/internal/v4/naming"
+)
+
+const testDrillSandbox = "drill-sandbox"
+
+// drillFixture wires a DrillRunner to a fake client behind the
+// auditclient wrapper. The fake has no VolSync or Job controller; the
+// test plays both (populate, finishJob).
+type drillFixture struct {
+	t       *testing.T
+	fake    client.WithWatch
+	store   *Store
+	journal *journal.Journal
+	runner  *DrillRunner
+	clock   time.Time
+}
+
+func newDrillFixture(t *testing.T, m mode.Mode) *drillFixture {
+	t.Helper()
+	fakeC := fake.NewClientBuilder().WithScheme(newTestScheme(t)).Build()
+	auditC := auditclient.New(fakeC, m, slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)))
+	store := NewStore(m.String(), "bare-dst", testRepoSecretShare)
+	store.now = fixedTime
+	f := &drillFixture{t: t, fake: fakeC, store: store, journal: journal.New(nil), clock: fixedTime()}
+	f.runner = &DrillRunner{
+		Client:           auditC,
+		Store:            store,
+		Mode:             m,
+		SandboxNamespace: testDrillSandbox,
+		SystemNamespaces: map[string]struct{}{"kube-system": {}},
+		Interval:         time.Hour,
+		Timeout:          30 * time.Minute,
+		Journal:          f.journal,
+		Now:              func() time.Time { return f.clock },
+	}
+	return f
+}
+
+// drillablePVC returns a PVC and its operator-owned RD as the reconciler
+// would have rendered them, and records the Store entry that makes the
+// PVC a candidate.
+func (f *drillFixture) drillablePVC(ns, name string, anns map[string]string) []client.Object {
+	in := builder.Inputs{
+		Namespace: ns, PVCName: name, PVCCapacity: "1Gi", PVCAccessModes: []string{"ReadWriteOnce"},
+		Spec: v4labels.Spec{Tier: v4labels.TierDaily}, NamingStrategy: naming.StrategyBareDst,
+		DefaultRepoSecret: testRepoSecretShare, DefaultStorageClass: "longhorn",
+		DefaultUID: 568, DefaultGID: 568, DefaultFSGroup: 568,
+	}
+	rd := builder.BuildRD(in)
+	f.store.Set(ParityEntry{
+		Namespace: ns, PVC: name,
+		Current:      CurrentState{RDPresent: true, RDName: rd.GetName(), RDManagedBy: v4labels.LabelManagedByValue},
+		LastBackupAt: fixedTime().Add(-time.Hour),
+	})
+	return []client.Object{makePVC(ns, name, nil, anns), rd}
+}
+
+func (f *drillFixture) seed(objs ...client.Object) {
+	f.t.Helper()
+	for _, o := range objs {
+		if err := f.fake.Create(context.Background(), o); err != nil {
+			f.t.Fatal(err)
+		}
+	}
+}
+
+func (f *drillFixture) step() {
+	f.t.Helper()
+	if err := f.runner.Step(context.Background()); err != nil {
+		f.t.Fatalf("Step: %v", err)
+	}
+}
+
+// drillRD returns the in-flight drill RD, or nil.
+func (f *drillFixture) drillRD() *unstructured.Unstructured {
+	f.t.Helper()
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(rdGVK.GroupVersion().WithKind(rdGVK.Kind + "List"))
+	if err := f.fake.List(context.Background(), list, client.InNamespace(testDrillSandbox)); err != nil {
+		f.t.Fatal(err)
+	}
+	if len(list.Items) == 0 {
+		return nil
+	}
+	return &list.Items[0]
+}
+
+// populate plays VolSync: marks the drill RD's manual sync done.
+func (f *drillFixture) populate() {
+	f.t.Helper()
+	rd := f.drillRD()
+	trigger, _, _ := unstructured.NestedString(rd.Object, "spec", "trigger", "manual")
+	_ = unstructured.SetNestedField(rd.Object, trigger, "status", "lastManualSync")
+	if err := f.fake.Update(context.Background(), rd); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+// finishJob plays the Job controller.
+func (f *drillFixture) finishJob(succeeded bool, msg string) {
+	f.t.Helper()
+	job := &batchv1.Job{}
+	if err := f.fake.Get(context.Background(), types.NamespacedName{Namespace: testDrillSandbox, Name: f.drillRD().GetName()}, job); err != nil {
+		f.t.Fatal(err)
+	}
+	cond := batchv1.JobCondition{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}
+	if !succeeded {
+		cond = batchv1.JobCondition{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: msg}
+	}
+	job.Status.Conditions = append(job.Status.Conditions, cond)
+	if err := f.fake.Status().Update(context.Background(), job); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+// runDrill drives one drill to completion from idle.
+func (f *drillFixture) runDrill(pass bool) {
+	f.t.Helper()
+	f.step()
+	f.populate()
+	f.step()
+	f.finishJob(pass, "")
+	f.step()
+}
+
+func (f *drillFixture) sandboxCounts() (rds, pvcs, jobs int) {
+	f.t.Helper()
+	ctx := context.Background()
+	if rd := f.drillRD(); rd != nil {
+		rds = 1
+	}
+	var pl corev1.PersistentVolumeClaimList
+	var jl batchv1.JobList
+	if err := f.fake.List(ctx, &pl, client.InNamespace(testDrillSandbox)); err != nil {
+		f.t.Fatal(err)
+	}
+	if err := f.fake.List(ctx, &jl, client.InNamespace(testDrillSandbox)); err != nil {
+		f.t.Fatal(err)
+	}
+	return rds, len(pl.Items), len(jl.Items)
+}
+
+func TestDrill_RestoresVerifiesRecordsAndCleansUp(t *testing.T) {
+	f := newDrillFixture(t, mode.Permissive)
+	f.seed(f.drillablePVC(testNSMyapp, "data", nil)...)
+	ctx := context.Background()
+
+	f.step()
+	rd := f.drillRD()
+	if rd == nil {
+		t.Fatal("no drill RD created")
+	}
+	if user, _, _ := unstructured.NestedString(rd.Object, "spec", "kopia", "username"); user != "data" {
+		t.Errorf("drill RD restores identity %q, want the source RD's", user)
+	}
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := f.fake.Get(ctx, types.NamespacedName{Namespace: testDrillSandbox, Name: rd.GetName()}, pvc); err != nil {
+		t.Fatalf("drill PVC: %v", err)
+	}
+	if ref := pvc.Spec.DataSourceRef; ref == nil || ref.Kind != rdGVK.Kind || ref.Name != rd.GetName() {
+		t.Errorf("dataSourceRef: %+v", ref)
+	}
+
+	// Not populated yet: no Job.
+	f.clock = f.clock.Add(time.Minute)
+	f.step()
+	if _, _, jobs := f.sandboxCounts(); jobs != 0 {
+		t.Fatalf("Job created before the restore finished")
+	}
+
+	f.populate()
+	f.step()
+	job := &batchv1.Job{}
+	if err := f.fake.Get(ctx, types.NamespacedName{Namespace: testDrillSandbox, Name: rd.GetName()}, job); err != nil {
+		t.Fatalf("verification Job: %v", err)
+	}
+	// 29 of 30 minutes left.
+	if d := job.Spec.ActiveDeadlineSeconds; d == nil || *d != 29*60 {
+		t.Errorf("Job deadline: %v", d)
+	}
+
+	f.clock = f.clock.Add(2 * time.Minute)
+	f.finishJob(true, "")
+	f.step()
+
+	snap := f.store.Snapshot()
+	rec := snap.Entries[0].RestoreDrill
+	if rec == nil || rec.Result != DrillPassed || rec.Verify != "file-count" || rec.DurationSeconds != 180 ||
+		rec.Drill != testDrillSandbox+"/"+rd.GetName() {
+		t.Fatalf("drill record: %+v", rec)
+	}
+	if got := snap.Summary.ByDrillResult; got[DrillPassed] != 1 || got[DrillFailed] != 0 {
+		t.Errorf("by_drill_result: %v", got)
+	}
+	if rds, pvcs, jobs := f.sandboxCounts(); rds+pvcs+jobs != 0 {
+		t.Errorf("left behind: rds=%d pvcs=%d jobs=%d", rds, pvcs, jobs)
+	}
+
+	// Three creates, three deletes, all in the sandbox, all journaled.
+	entries, _ := f.journal.Query(ctx, journal.Filter{})
+	if len(entries) != 6 {
+		t.Fatalf("journal: %d entries, want 6", len(entries))
+	}
+	for _, je := range entries {
+		if je.Trigger != journal.TriggerRestoreDrill || je.Namespace != testDrillSandbox || je.Status != "succeeded" {
+			t.Errorf("journal entry: %+v", je)
+		}
+	}
+
+	// The next drill waits for Interval.
+	f.clock = f.clock.Add(30 * time.Minute)
+	f.step()
+	if f.drillRD() != nil {
+		t.Error("next drill started before Interval elapsed")
+	}
+}
+
+func TestDrill_Failures(t *testing.T) {
+	t.Run("restore times out", func(t *testing.T) {
+		f := newDrillFixture(t, mode.Permissive)
+		f.seed(f.drillablePVC(testNSMyapp, "data", nil)...)
+		f.step()
+		f.clock = f.clock.Add(31 * time.Minute)
+		f.step()
+		rec := f.store.Snapshot().Entries[0].RestoreDrill
+		if rec == nil || rec.Result != DrillFailed || !strings.Contains(rec.Reason, "restore did not complete within 30m0s") {
+			t.Fatalf("drill record: %+v", rec)
+		}
+		if rds, pvcs, _ := f.sandboxCounts(); rds+pvcs != 0 {
+			t.Errorf("left behind: rds=%d pvcs=%d", rds, pvcs)
+		}
+	})
+	t.Run("verification fails", func(t *testing.T) {
+		f := newDrillFixture(t, mode.Permissive)
+		f.seed(f.drillablePVC(testNSMyapp, "data", map[string]string{
+			v4labels.AnnotationDrillManifest: "SHA256SUMS"})...)
+		f.step()
+		f.populate()
+		f.step()
+		f.finishJob(false, "BackoffLimitExceeded")
+		f.step()
+		rec := f.store.Snapshot().Entries[0].RestoreDrill
+		if rec == nil || rec.Result != DrillFailed || rec.Verify != "checksum-manifest" ||
+			!strings.Contains(rec.Reason, "BackoffLimitExceeded") {
+			t.Fatalf("drill record: %+v", rec)
+		}
+	})
+	t.Run("drill RD deleted underneath", func(t *testing.T) {
+		f := newDrillFixture(t, mode.Permissive)
+		f.seed(f.drillablePVC(testNSMyapp, "data", nil)...)
+		f.step()
+		if err := f.fake.Delete(context.Background(), f.drillRD()); err != nil {
+			t.Fatal(err)
+		}
+		f.step()
+		if rec := f.store.Snapshot().Entries[0].RestoreDrill; rec == nil || rec.Result != DrillError {
+			t.Fatalf("drill record: %+v", rec)
+		}
+	})
+}
+
+// Never-drilled PVCs go first in key order, then the one drilled longest
+// ago; opted-out PVCs, PVCs without a backup, and PVCs whose RD is not
+// operator-owned are never drilled.
+func TestDrill_SelectionOrder(t *testing.T) {
+	f := newDrillFixture(t, mode.Permissive)
+	f.seed(f.drillablePVC("app-b", "data", nil)...)
+	f.seed(f.drillablePVC("app-a", "data", nil)...)
+	f.seed(f.drillablePVC("app-c", "data", map[string]string{v4labels.AnnotationDrillVerify: "none"})...)
+	f.seed(f.drillablePVC("app-d", "data", nil)...)
+	nb, _ := f.store.Get("app-d", "data")
+	nb.LastBackupAt = time.Time{}
+	f.store.Set(nb)
+	argo := f.drillablePVC("app-e", "data", nil)
+	argo[1].SetLabels(map[string]string{managedByLabel: "argocd"})
+	f.seed(argo...)
+
+	var order []string
+	for range 3 {
+		f.runDrill(true)
+		for _, e := range f.store.Snapshot().Entries {
+			if e.RestoreDrill != nil && e.RestoreDrill.FinishedAt.Equal(f.clock) {
+				order = append(order, e.Namespace)
+			}
+		}
+		f.clock = f.clock.Add(time.Hour)
+	}
+	if strings.Join(order, ",") != "app-a,app-b,app-a" {
+		t.Errorf("drill order: %v", order)
+	}
+}
+
+func TestDrill_AuditModeNeverWrites(t *testing.T) {
+	f := newDrillFixture(t, mode.Audit)
+	f.seed(f.drillablePVC(testNSMyapp, "data", nil)...)
+	f.step()
+	if rds, pvcs, jobs := f.sandboxCounts(); rds+pvcs+jobs != 0 {
+		t.Errorf("audit mode drilled: rds=%d pvcs=%d jobs=%d", rds, pvcs, jobs)
+	}
+}
+
+func TestDrill_WriteBoundary(t *testing.T) {
+	ctx := context.Background()
+	f := newDrillFixture(t, mode.Permissive)
+
+	f.runner.SandboxNamespace = "kube-system"
+	if err := f.runner.Step(ctx); err == nil {
+		t.Error("system namespace accepted as sandbox")
+	}
+	f.runner.SandboxNamespace = testDrillSandbox
+
+	drillMeta := metav1.ObjectMeta{Labels: map[string]string{
+		managedByLabel: v4labels.LabelManagedByValue, v4labels.LabelDrill: "true"}}
+	outside := &corev1.PersistentVolumeClaim{ObjectMeta: *drillMeta.DeepCopy()}
+	outside.Namespace, outside.Name = testNSMyapp, "x"
+	unlabeled := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: testDrillSandbox, Name: "y"}}
+	secret := &corev1.Secret{ObjectMeta: *drillMeta.DeepCopy()}
+	secret.Namespace, secret.Name = testDrillSandbox, "z"
+	for obj, reason := range map[client.Object]string{
+		outside: drillRefuseSandbox, unlabeled: drillRefuseOwner, secret: drillRefuseKind,
+	} {
+		if err := f.runner.write(ctx, drillOpCreate, obj); err == nil || !strings.Contains(err.Error(), reason) {
+			t.Errorf("%s/%s: err=%v, want %s", obj.GetNamespace(), obj.GetName(), err, reason)
+		}
+	}
+	var pvcs corev1.PersistentVolumeClaimList
+	if err := f.fake.List(ctx, &pvcs); err != nil || len(pvcs.Items) != 0 {
+		t.Errorf("refused writes reached the apiserver: %d PVCs, %v", len(pvcs.Items), err)
+	}
+
+	// The sweep removes drill leftovers only.
+	leftover := &corev1.PersistentVolumeClaim{ObjectMeta: *drillMeta.DeepCopy()}
+	leftover.Namespace, leftover.Name = testDrillSandbox, "drill-old"
+	f.seed(leftover, unlabeled)
+	if err := f.runner.sweep(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.fake.List(ctx, &pvcs, client.InNamespace(testDrillSandbox)); err != nil || len(pvcs.Items) != 1 || pvcs.Items[0].Name != "y" {
+		t.Errorf("after sweep: %+v, %v", pvcs.Items, err)
+	}
+}
+
+func TestDrillName(t *testing.T) {
+	name := drillName("ns", strings.Repeat("p", 253), fixedTime())
+	if len(name) > 63 || !strings.HasPrefix(name, "drill-") {
+		t.Errorf("drillName: %q", name)
+	}
+	if name == drillName("ns", strings.Repeat("p", 253), fixedTime().Add(time.Second)) {
+		t.Error("drill names repeat across drills")
+	}
+}
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+	"github.com/mitchross/pvc-plumber/internal/v4/labels"
+)
+
+// BackupFreshness is the /audit verdict on whether a PVC's backups are
+// actually running, as opposed to whether its RS/RD objects have the
+// right shape. A perfectly-matched RS whose mover fails every night is
+// already-matches AND overdue.
+type BackupFreshness string
+
+const (
+	// FreshnessFresh: the latest backup is within the tier's window.
+	FreshnessFresh BackupFreshness = "fresh"
+	// FreshnessOverdue: a backup exists but is older than the window.
+	FreshnessOverdue BackupFreshness = "overdue"
+	// FreshnessNever: the PVC is expected to be backed up on a cadence
+	// but no completed backup has been observed at all.
+	FreshnessNever BackupFreshness = "never"
+)
+
+// AllBackupFreshness returns every verdict in a stable order, for the
+// zero-filled summary map.
+func AllBackupFreshness() []BackupFreshness {
+	return []BackupFreshness{FreshnessFresh, FreshnessOverdue, FreshnessNever}
+}
+
+// Where ParityEntry.LastBackupAt came from.
+const (
+	BackupSourceVolSync = "volsync-status"
+	BackupSourceKopia   = "kopia"
+)
+
+// BackupChecker is the optional kopia-side view of a PVC's backups —
+// satisfied by cache.CachedClient, kopia.Client and the native reader.
+// Only the snapshot metadata fields of the result are used here.
+type BackupChecker interface {
+	CheckBackupExists(ctx context.Context, q backend.Query) backend.CheckResult
+}
+
+// freshnessWindowFor returns the freshness window for a PVC, or (0,
+// false) when it has none: not opted in, exempt, or on a tier without a
+// cadence (manual, disabled, unspecified).
+func freshnessWindowFor(spec labels.Spec) (time.Duration, bool) {
+	if !indexable(spec) {
+		return 0, false
+	}
+	return spec.Tier.FreshnessWindow()
+}
+
+// lastBackup returns the newest completed-backup time known for the PVC
+// and where it came from. The RS's status.lastSyncTime is always
+// consulted; Backups, when set, adds the kopia repository's own latest
+// snapshot, looked up under the PVC's backup identity (the override when
+// set), bounded like the restore preview's listing. A non-authoritative
+// kopia result — including a timeout — is ignored rather than treated
+// as "no backup".
+func (r *V4AuditReconciler) lastBackup(ctx context.Context, namespace, pvc string, spec labels.Spec, current CurrentState) (time.Time, string) {
+	at, source := current.RSLastSyncTime, ""
+	if !at.IsZero() {
+		source = BackupSourceVolSync
+	}
+	if r.Backups != nil {
+		ctx, cancel := context.WithTimeout(ctx, restoreSelectionTimeout)
+		defer cancel()
+		res := r.Backups.CheckBackupExists(ctx, backend.NewQuery(namespace, pvc, spec.BackupIdentity))
+		if res.Authoritative && res.LatestSnapshotAt.After(at) {
+			at, source = res.LatestSnapshotAt, BackupSourceKopia
+		}
+	}
+	return at, source
+}
+
+// applyFreshness fills the read-time freshness fields on an entry,
+// against the same generatedAt the report header carries. Entries with
+// no window are left untouched (no verdict).
+func applyFreshness(e *ParityEntry, generatedAt time.Time) {
+	if e.FreshnessWindowSeconds <= 0 {
+		return
+	}
+	if e.LastBackupAt.IsZero() {
+		e.Freshness = FreshnessNever
+		return
+	}
+	age := generatedAt.Sub(e.LastBackupAt)
+	if age < 0 {
+		age = 0
+	}
+	secs := int64(age.Seconds())
+	e.BackupAgeSeconds = &secs
+	if age > time.Duration(e.FreshnessWindowSeconds)*time.Second {
+		e.Freshness = FreshnessOverdue
+	} else {
+		e.Freshness = FreshnessFresh
+	}
+}
@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+	v4labels "github.com/mitchross/pvc-plumber/internal/v4/labels"
+	"github.com/mitchross/pvc-plumber/internal/v4/mode"
+)
+
+// withLastSync stamps status.lastSyncTime onto an RS built by makeRS.
+func withLastSync(rs *unstructured.Unstructured, at time.Time) *unstructured.Unstructured {
+	_ = unstructured.SetNestedField(rs.Object, at.UTC().Format(time.RFC3339), "status", "lastSyncTime")
+	return rs
+}
+
+// stubBackups is a BackupChecker returning a fixed latest snapshot.
+type stubBackups struct {
+	latest     time.Time
+	calls      int
+	identities []string
+}
+
+func (s *stubBackups) CheckBackupExists(_ context.Context, q backend.Query) backend.CheckResult {
+	s.calls++
+	s.identities = append(s.identities, q.Identity.Key())
+	return backend.CheckResult{Exists: true, Authoritative: true, Namespace: q.Namespace, Pvc: q.PVC, LatestSnapshotAt: s.latest}
+}
+
+func snapshotEntry(t *testing.T, f *v4Fixture, name string) ParityEntry {
+	t.Helper()
+	for _, e := range f.store.Snapshot().Entries {
+		if e.Namespace == testNSMyapp && e.PVC == name {
+			return e
+		}
+	}
+	t.Fatalf("no entry for %s", name)
+	return ParityEntry{}
+}
+
+func TestV4Freshness_VerdictsFromRSLastSyncTime(t *testing.T) {
+	now := fixedTime()
+	f := newV4Fixture(t,
+		makePVC(testNSMyapp, "fresh", labelsEnabledManage(), nil),
+		withLastSync(makeRS(testNSMyapp, "fresh", "", testRepoSecretShare, "fresh"), now.Add(-time.Hour)),
+		makePVC(testNSMyapp, "late", labelsEnabledManage(), nil),
+		withLastSync(makeRS(testNSMyapp, "late", "", testRepoSecretShare, "late"), now.Add(-72*time.Hour)),
+		makePVC(testNSMyapp, "never", labelsEnabledManage(), nil),
+		makePVC(testNSMyapp, "ignored", nil, nil),
+	)
+	for _, name := range []string{"fresh", "late", "never", "ignored"} {
+		f.reconcile(testNSMyapp, name)
+	}
+
+	fresh := snapshotEntry(t, f, "fresh")
+	if fresh.Freshness != FreshnessFresh || fresh.BackupAgeSeconds == nil || *fresh.BackupAgeSeconds != 3600 ||
+		fresh.LastBackupSource != BackupSourceVolSync || fresh.FreshnessWindowSeconds != int64((48*time.Hour).Seconds()) {
+		t.Errorf("fresh: %+v", fresh)
+	}
+	if late := snapshotEntry(t, f, "late"); late.Freshness != FreshnessOverdue {
+		t.Errorf("late: %+v", late)
+	}
+	never := snapshotEntry(t, f, "never")
+	if never.Freshness != FreshnessNever || never.BackupAgeSeconds != nil || !never.LastBackupAt.IsZero() {
+		t.Errorf("never: %+v", never)
+	}
+	if ig := snapshotEntry(t, f, "ignored"); ig.Freshness != "" || ig.FreshnessWindowSeconds != 0 {
+		t.Errorf("not-opted-in PVC must carry no verdict: %+v", ig)
+	}
+
+	got := f.store.Snapshot().Summary.ByFreshness
+	if got[FreshnessFresh] != 1 || got[FreshnessOverdue] != 1 || got[FreshnessNever] != 1 {
+		t.Errorf("summary.by_freshness: %v", got)
+	}
+}
+
+func TestV4Freshness_ManualTierHasNoWindow(t *testing.T) {
+	f := newV4Fixture(t, makePVC(testNSMyapp, "data", labelsEnabledManageTier("manual"), nil))
+	f.reconcile(testNSMyapp, "data")
+	if e := snapshotEntry(t, f, "data"); e.Freshness != "" {
+		t.Errorf("manual tier: %+v", e)
+	}
+}
+
+func TestV4Freshness_KopiaNewerThanRSWins(t *testing.T) {
+	now := fixedTime()
+	f := newV4Fixture(t,
+		makePVC(testNSMyapp, "data", labelsEnabledManage(), nil),
+		withLastSync(makeRS(testNSMyapp, "data", "", testRepoSecretShare, "data"), now.Add(-72*time.Hour)),
+	)
+	stub := &stubBackups{latest: now.Add(-2 * time.Hour)}
+	f.rec.Backups = stub
+	f.reconcile(testNSMyapp, "data")
+
+	e := snapshotEntry(t, f, "data")
+	if e.Freshness != FreshnessFresh || e.LastBackupSource != BackupSourceKopia || !e.LastBackupAt.Equal(stub.latest) {
+		t.Errorf("entry: %+v", e)
+	}
+}
+
+// TestV4Freshness_KopiaLookupHonorsIdentityOverride pins that a PVC with
+// a backup-identity override gets its kopia freshness from the override's
+// lineage instead of being skipped.
+func TestV4Freshness_KopiaLookupHonorsIdentityOverride(t *testing.T) {
+	now := fixedTime()
+	f := newV4Fixture(t, makePVC(testNSMyapp, "data", labelsEnabledManage(),
+		map[string]string{v4labels.AnnotationBackupIdentity: "shared-data"}))
+	stub := &stubBackups{latest: now.Add(-time.Hour)}
+	f.rec.Backups = stub
+	f.reconcile(testNSMyapp, "data")
+
+	if len(stub.identities) == 0 || stub.identities[0] != "shared-data" {
+		t.Fatalf("kopia lookups: %v", stub.identities)
+	}
+	if e := snapshotEntry(t, f, "data"); e.LastBackupSource != BackupSourceKopia || e.Freshness != FreshnessFresh {
+		t.Errorf("entry: %+v", e)
+	}
+}
+
+func TestApplyFreshness_AgeComputedAtReadTime(t *testing.T) {
+	last := fixedTime()
+	e := ParityEntry{LastBackupAt: last, FreshnessWindowSeconds: 3 * 3600}
+	applyFreshness(&e, last.Add(3*time.Hour))
+	if e.Freshness != FreshnessFresh {
+		t.Errorf("exactly at the window: %q", e.Freshness)
+	}
+	e = ParityEntry{LastBackupAt: last, FreshnessWindowSeconds: 3 * 3600}
+	applyFreshness(&e, last.Add(3*time.Hour+time.Second))
+	if e.Freshness != FreshnessOverdue || *e.BackupAgeSeconds != 3*3600+1 {
+		t.Errorf("past the window: %+v", e)
+	}
+}
+
+func TestChildEventPredicate_LastSyncTimeMovePasses(t *testing.T) {
+	p := childEventPredicate()
+	oldRS := withLastSync(makeRS(testNSMyapp, "data", "", testRepoSecretShare, "data"), fixedTime().Add(-time.Hour))
+	newRS := withLastSync(oldRS.DeepCopy(), fixedTime())
+	if !p.Update(event.UpdateEvent{ObjectOld: oldRS, ObjectNew: newRS}) {
+		t.Error("lastSyncTime advance must re-enqueue")
+	}
+	other := oldRS.DeepCopy()
+	_ = unstructured.SetNestedField(other.Object, "snapshot 3f2a saved", "status", "latestMoverStatus", "logs")
+	if p.Update(event.UpdateEvent{ObjectOld: oldRS, ObjectNew: other}) {
+		t.Error("mover log churn must still be dropped")
+	}
+}
+
+func TestStoreSnapshot_ByFreshnessZeroFilled(t *testing.T) {
+	s := NewStore(mode.Audit.String(), "bare-dst", testRepoSecretShare)
+	got := s.Snapshot().Summary.ByFreshness
+	if len(got) != 3 || got[FreshnessOverdue] != 0 {
+		t.Errorf("by_freshness: %v", got)
+	}
+}
@@ -0,0 +1,1184 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/executor"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+	"github.com/mitchross/pvc-plumber/internal/v4/labels"
+	"github.com/mitchross/pvc-plumber/internal/v4/mode"
+)
+
+// Pre/post backup hooks.
+//
+// The RS the builder renders takes a crash-consistent copy of the
+// volume: whatever was on disk the instant the mover's snapshot was
+// cut. That is fine for most applications and wrong for the ones that
+// keep state in memory — a SQLite database mid-transaction in its WAL,
+// anything with a write-back cache. Those restore, but not always to
+// something the application will open. A PVC can ask for the
+// application to be quiesced around its scheduled backup instead:
+//
+//	pvc-plumber.io/pre-backup-exec: "<container>:<command>"      (or <container>:["argv", …])
+//	pvc-plumber.io/post-backup-exec: "<container>:<command>"
+//	pvc-plumber.io/pre-backup-scale-to-zero: deployment/<name>   (or statefulset/<name>)
+//	pvc-plumber.io/backup-hook-timeout: 2m                        (optional)
+//
+// VolSync owns the schedule, so HookRunner does not trigger anything.
+// It watches the RS's status.nextSyncTime and runs the pre-hooks Lead
+// before it: the exec in every running pod that mounts the PVC, then the
+// scale to zero, which waits until no pod mounts the PVC any more. It
+// then holds until status.lastSyncTime moves past the start of the run
+// (the sync completed), the mover reports a failed attempt, or MaxHold
+// runs out, and runs the post-hooks: the post exec, and the scale back
+// to the recorded replica count.
+//
+// The post-hooks always run once the pre-hooks have started — after a
+// failed or timed-out pre-hook (the backup then goes ahead
+// crash-consistent, which beats no backup), a failed sync, a hold that
+// ran out, a PVC that lost its annotations mid-run, and an operator
+// shutting down. A crash is the one case the runner cannot cover in
+// process. For scale-to-zero the replica count is kept on the workload
+// itself (labels.AnnotationBackupHookReplicas), and the next pass
+// scales back any workload that carries it and is not held by a run in
+// flight. An interrupted post exec is not retried.
+//
+// Write boundary. Hooks act on application objects the operator does
+// not own, so they bypass executor.Execute and apply their own
+// allow-list. They run only for a PVC whose RS the operator owns. They
+// exec only into a pod in the PVC's namespace that mounts the PVC, and
+// scale only a Deployment or StatefulSet in the PVC's namespace whose
+// pod template mounts it — a typo'd annotation cannot reach an
+// unrelated workload. A scale changes spec.replicas and the
+// replicas annotation and nothing else. Every exec and scale is
+// journaled with Trigger=backup-hook, and every run's outcome is on the
+// PVC's /audit entry as backup_hooks.
+//
+// Step only schedules and advances runs. Each run's pre-hooks, and
+// later its post-hooks, run in a goroutine of their own, so a slow hook
+// delays neither Step nor another PVC's window. Each hook is bounded by
+// the hook timeout, and the pre-hooks as a whole — an exec in every
+// mounting pod, then the wait for them to go — by one deadline
+// HookLeadMargin before the window, so nothing is still quiescing when
+// the mover starts. A hook timeout must fit in that budget (Lead less
+// the margin): a PVC whose backup-hook-timeout does not reports error
+// and runs no hooks.
+
+// DefaultHookTimeout bounds one hook (an exec, or a scale-down waiting
+// for pods to go) when neither the PVC nor the operator sets one.
+const DefaultHookTimeout = time.Minute
+
+// DefaultHookLead is how long before the RS's nextSyncTime the
+// pre-hooks start.
+const DefaultHookLead = 2 * time.Minute
+
+// DefaultHookMaxHold bounds how long an application stays quiesced
+// waiting for its sync.
+const DefaultHookMaxHold = time.Hour
+
+// DefaultHookPollInterval is how often Start calls Step.
+const DefaultHookPollInterval = 15 * time.Second
+
+// HookLeadMargin is how long before the RS's nextSyncTime the pre-hooks
+// must be done. A run's pre-hooks share one deadline that far ahead of
+// the window, so they have at most Lead - HookLeadMargin however many
+// pods they exec into; a window that opens later than that is skipped.
+const HookLeadMargin = 15 * time.Second
+
+// hookScalePoll is how often a scale-down checks whether the pods have
+// gone.
+const hookScalePoll = 2 * time.Second
+
+// maxHookOutputBytes caps the command output kept in a failed exec's
+// detail.
+const maxHookOutputBytes = 512
+
+// HookStatus is where a PVC's hooks stand, surfaced in /audit.
+type HookStatus string
+
+const (
+	// HookArmed: the hooks are valid and wait for the next scheduled
+	// sync.
+	HookArmed HookStatus = "armed"
+
+	// HookHolding: the pre-hooks ran and the runner waits for the sync
+	// to finish.
+	HookHolding HookStatus = "holding"
+
+	// HookDisabled: backup hooks are off on this operator, or it runs in
+	// audit mode. The annotations have no effect.
+	HookDisabled HookStatus = "disabled"
+
+	// HookError: the annotations are malformed, or the PVC has no RS to
+	// time the hooks by. Reason says which.
+	HookError HookStatus = "error"
+)
+
+// HookOutcome is how one hook run ended.
+type HookOutcome string
+
+const (
+	// HookRunSucceeded: every hook succeeded and the sync completed in
+	// between.
+	HookRunSucceeded HookOutcome = "succeeded"
+
+	// HookRunPreFailed: a pre-hook failed. The post-hooks ran and the
+	// backup went ahead crash-consistent.
+	HookRunPreFailed HookOutcome = "pre-hook-failed"
+
+	// HookRunSyncFailed: the mover reported a failed attempt, or the RS
+	// disappeared, while the application was quiesced.
+	HookRunSyncFailed HookOutcome = "sync-failed"
+
+	// HookRunTimedOut: the sync did not finish within MaxHold.
+	HookRunTimedOut HookOutcome = "timeout"
+
+	// HookRunInterrupted: the run ended early because the operator shut
+	// down, the PVC or its hook annotations went away, or (recovered)
+	// an earlier operator died mid-run.
+	HookRunInterrupted HookOutcome = "interrupted"
+
+	// HookRunPostFailed: a post-hook failed. Wins over every other
+	// outcome: the application may still be quiesced.
+	HookRunPostFailed HookOutcome = "post-hook-failed"
+)
+
+// HookActionResult is the result of one exec or scale.
+type HookActionResult string
+
+const (
+	HookActionSucceeded HookActionResult = "succeeded"
+	HookActionFailed    HookActionResult = "failed"
+	HookActionTimedOut  HookActionResult = "timeout"
+	HookActionRefused   HookActionResult = "refused"
+)
+
+// HookAction is one exec into one pod, or one scale of one workload.
+type HookAction struct {
+	// Phase is "pre" or "post".
+	Phase string `json:"phase"`
+	// Hook is "exec" or "scale".
+	Hook string `json:"hook"`
+	// Target is "pod/<name>" (with Container) or
+	// "deployment|statefulset/<name>".
+	Target         string           `json:"target"`
+	Container      string           `json:"container,omitempty"`
+	Result         HookActionResult `json:"result"`
+	StartedAt      time.Time        `json:"started_at"`
+	DurationMillis int64            `json:"duration_ms"`
+	Detail         string           `json:"detail,omitempty"`
+}
+
+// HookRun is one bracketing of a scheduled sync. Outcome and FinishedAt
+// are empty while the run holds.
+type HookRun struct {
+	// Window is the RS nextSyncTime the run was started for.
+	Window     time.Time    `json:"window"`
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at,omitzero"`
+	Outcome    HookOutcome  `json:"outcome,omitempty"`
+	Reason     string       `json:"reason,omitempty"`
+	Actions    []HookAction `json:"actions"`
+}
+
+// HookRecord is a PVC's hooks and their latest run, surfaced as
+// ParityEntry.BackupHooks.
+type HookRecord struct {
+	Status HookStatus `json:"status"`
+	// NextWindow is the RS's nextSyncTime when the runner last read it.
+	NextWindow time.Time `json:"next_window,omitzero"`
+	Reason     string    `json:"reason,omitempty"`
+	// LastRun is the run in flight (Status holding) or the latest
+	// finished one. Kept in memory: a restart forgets it, the journal
+	// does not.
+	LastRun *HookRun `json:"last_run,omitempty"`
+}
+
+// PodExecer runs a command in a container of a running pod and returns
+// its output. The operator binary implements it over pods/exec.
+type PodExecer interface {
+	Exec(ctx context.Context, namespace, pod, container string, command []string) (stdout, stderr string, err error)
+}
+
+// Hook journal ops (Entry.Op under journal.TriggerBackupHook).
+const (
+	hookOpExec  = "exec"
+	hookOpScale = "scale"
+)
+
+const (
+	hookPhasePre  = "pre"
+	hookPhasePost = "post"
+)
+
+var (
+	hookPodGVK         = corev1.SchemeGroupVersion.WithKind("Pod")
+	hookDeploymentGVK  = appsv1.SchemeGroupVersion.WithKind("Deployment")
+	hookStatefulSetGVK = appsv1.SchemeGroupVersion.WithKind("StatefulSet")
+)
+
+// hookRun is a run in flight. hooks are the PVC's hooks as parsed when
+// the run started; the post-hooks run from them even if the annotations
+// change or disappear meanwhile. scaled, run, busy and finishing are
+// guarded by HookRunner.mu.
+type hookRun struct {
+	namespace, pvc string
+	rsName         string
+	hooks          labels.Spec
+	timeout        time.Duration
+	scaled         bool
+	run            HookRun
+	// busy is set while a goroutine runs the pre- or post-hooks;
+	// finishing once the post-hooks have started.
+	busy, finishing bool
+}
+
+func (h *hookRun) key() string { return h.namespace + "/" + h.pvc }
+
+// HookRunner runs pre/post backup hooks around scheduled syncs.
+// Register it with the manager via mgr.Add; it implements
+// manager.Runnable and LeaderElectionRunnable. Tests drive it through
+// Step.
+type HookRunner struct {
+	// Client lists PVCs, reads RSes and patches workloads. Production
+	// passes the auditclient-wrapped manager client.
+	Client client.Client
+
+	// Reader reads pods and workloads. Production passes the manager's
+	// API reader, so hooks do not start cluster-wide Pod, Deployment
+	// and StatefulSet informers. nil means Client.
+	Reader client.Reader
+
+	// Exec runs exec hooks. Required when Enabled.
+	Exec PodExecer
+
+	// Store supplies each PVC's observed RS and receives the hook
+	// records.
+	Store *Store
+
+	// Enabled is the operator-wide switch. Off, or in audit mode, the
+	// runner only reports PVCs with hook annotations as disabled.
+	Enabled bool
+
+	// Mode gates writes as on the executor.
+	Mode mode.Mode
+
+	// SystemNamespaces never run hooks.
+	SystemNamespaces map[string]struct{}
+
+	// Timeout bounds one hook unless the PVC sets
+	// pvc-plumber.io/backup-hook-timeout. <= 0 means
+	// DefaultHookTimeout.
+	Timeout time.Duration
+
+	// Lead is how long before nextSyncTime the pre-hooks start. <= 0
+	// means DefaultHookLead.
+	Lead time.Duration
+
+	// MaxHold bounds the wait for the sync. <= 0 means
+	// DefaultHookMaxHold.
+	MaxHold time.Duration
+
+	// PollInterval is how often Start calls Step. <= 0 means
+	// DefaultHookPollInterval.
+	PollInterval time.Duration
+
+	// Journal records every exec and scale. nil is fine.
+	Journal *journal.Journal
+
+	// Now is injected for deterministic tests. nil → time.Now.
+	Now func() time.Time
+
+	mu   sync.Mutex
+	runs map[string]*hookRun
+	last map[string]HookRun
+	// done is the window each PVC last ran for, so a window is
+	// bracketed once even though it stays open for Lead.
+	done map[string]time.Time
+	// records is what Step last published, so a hook goroutine can
+	// update its PVC's record without waiting for the next Step.
+	records map[string]HookRecord
+	// wg tracks the hook goroutines.
+	wg sync.WaitGroup
+	// scaleMu serializes the read-modify-write of a workload's replicas
+	// across runs, so two PVCs scaling one workload agree on who holds
+	// it. Lock order is scaleMu, then mu; recoverScale, which runs under
+	// mu, only ever TryLocks it.
+	scaleMu sync.Mutex
+}
+
+// NeedLeaderElection keeps hooks on a single replica.
+func (r *HookRunner) NeedLeaderElection() bool { return true }
+
+// Start steps the runner every PollInterval until ctx is cancelled,
+// then runs the post-hooks of every run still in flight: a rolling
+// update of the operator must not leave an application scaled to zero.
+func (r *HookRunner) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("backup-hooks")
+	interval := r.PollInterval
+	if interval <= 0 {
+		interval = DefaultHookPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Step(ctx); err != nil {
+			logger.Error(err, "backup hook step failed")
+		}
+		select {
+		case <-ctx.Done():
+			r.Release(context.WithoutCancel(ctx), "the operator is shutting down")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Release ends every run in flight as interrupted, running its
+// post-hooks, and returns once all hook goroutines are done. A run still
+// in its pre-hooks is ended when they return. Not for use concurrently
+// with Step.
+func (r *HookRunner) Release(ctx context.Context, reason string) {
+	for {
+		r.mu.Lock()
+		for _, run := range r.runs {
+			if !run.busy {
+				r.finish(ctx, run, HookRunInterrupted, reason)
+			}
+		}
+		left := len(r.runs)
+		r.mu.Unlock()
+		r.wg.Wait()
+		if left == 0 {
+			return
+		}
+	}
+}
+
+// Step reads every PVC with hook annotations, starts, advances or ends
+// its run, and publishes the records to the Store. Returns an error
+// without touching the Store when the PVC list fails; per-PVC problems
+// are recorded instead.
+func (r *HookRunner) Step(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := r.Client.List(ctx, pvcs); err != nil {
+		return fmt.Errorf("list PVCs: %w", err)
+	}
+	records := make(map[string]HookRecord)
+	for i := range pvcs.Items {
+		pvc := &pvcs.Items[i]
+		if !hasHookAnnotation(pvc.GetAnnotations()) || pvc.DeletionTimestamp != nil {
+			continue
+		}
+		if _, isSystem := r.SystemNamespaces[pvc.Namespace]; isSystem {
+			continue
+		}
+		records[pvc.Namespace+"/"+pvc.Name] = r.step(ctx, pvc)
+	}
+	// A run outlives its annotations: whatever the pre-hooks did is
+	// undone even if the PVC is gone. One still in its pre-hooks is
+	// ended on a later Step.
+	for key, run := range r.runs {
+		if _, ok := records[key]; !ok && !run.busy {
+			r.finish(ctx, run, HookRunInterrupted, "the PVC or its hook annotations went away mid-run")
+		}
+	}
+	r.records = records
+	r.Store.SetHooks(records)
+	return nil
+}
+
+// step acts on one PVC and returns its record.
+func (r *HookRunner) step(ctx context.Context, pvc *corev1.PersistentVolumeClaim) HookRecord {
+	key := pvc.Namespace + "/" + pvc.Name
+	if run, ok := r.runs[key]; ok {
+		return r.advance(ctx, run)
+	}
+	rec := r.record(key, HookArmed)
+	switch {
+	case !r.Enabled:
+		rec.Status, rec.Reason = HookDisabled, "backup hooks are not enabled on this operator"
+		return rec
+	case r.Mode == mode.Audit || r.Mode == mode.Unspecified:
+		rec.Status, rec.Reason = HookDisabled, "mode=audit"
+		return rec
+	}
+	spec := labels.Parse(pvc.GetLabels(), pvc.GetAnnotations())
+	if !spec.HasBackupHooks() {
+		rec.Status, rec.Reason = HookError, "invalid backup hooks: "+errors.Join(spec.Errors...).Error()
+		return rec
+	}
+	if !spec.PreBackupScale.IsZero() {
+		r.recoverScale(ctx, pvc.Namespace, pvc.Name, spec.PreBackupScale)
+		rec.LastRun = r.lastRun(key)
+	}
+	timeout := spec.BackupHookTimeout
+	if timeout <= 0 {
+		timeout = r.timeout()
+	}
+	if budget := r.preBudget(); timeout >= budget {
+		rec.Status, rec.Reason = HookError, fmt.Sprintf("hook timeout %s is not shorter than the pre-hook budget %s (hook lead %s less %s): the pre-hooks could still run when the sync starts; lower %s",
+			timeout, budget, r.lead(), HookLeadMargin, labels.AnnotationBackupHookTimeout)
+		return rec
+	}
+
+	e, ok := r.Store.Get(pvc.Namespace, pvc.Name)
+	if !ok || !e.Current.RSPresent || e.Current.RSName == "" {
+		rec.Status, rec.Reason = HookError, "no ReplicationSource observed to time the hooks by"
+		return rec
+	}
+	// An operator-owned RS means the namespace gate and the PVC fuse
+	// labels both passed: hooks reach no further than the reconciler.
+	if e.Current.RSManagedBy != labels.LabelManagedByValue {
+		rec.Status, rec.Reason = HookError, "the ReplicationSource is not operator-owned; hooks run only around backups the operator manages"
+		return rec
+	}
+	rs := &unstructured.Unstructured{}
+	rs.SetGroupVersionKind(rsGVK)
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: pvc.Namespace, Name: e.Current.RSName}, rs); err != nil {
+		rec.Status, rec.Reason = HookError, "get RS "+pvc.Namespace+"/"+e.Current.RSName+": "+err.Error()
+		return rec
+	}
+	st := readMoverStatus(rs)
+	rec.NextWindow = st.NextSyncTime
+	if st.NextSyncTime.IsZero() {
+		rec.Reason = "the RS has no nextSyncTime; hooks run only around scheduled syncs"
+		return rec
+	}
+
+	// The window is open from Lead before nextSyncTime until
+	// HookLeadMargin before it: the pre-hooks must be over when the
+	// mover starts, and quiescing any later is too late.
+	now := r.now()
+	start := st.NextSyncTime.Add(-r.lead())
+	if now.Before(start) || !now.Before(st.NextSyncTime.Add(-HookLeadMargin)) || r.done[key].Equal(st.NextSyncTime) {
+		return rec
+	}
+	if !st.LastSyncStartTime.IsZero() && !st.LastSyncStartTime.Before(start) {
+		return rec
+	}
+
+	run := &hookRun{
+		namespace: pvc.Namespace,
+		pvc:       pvc.Name,
+		rsName:    e.Current.RSName,
+		hooks:     spec,
+		timeout:   timeout,
+		run:       HookRun{Window: st.NextSyncTime, StartedAt: now},
+	}
+	if r.runs == nil {
+		r.runs = make(map[string]*hookRun)
+	}
+	r.runs[key] = run
+	log.FromContext(ctx).WithName("backup-hooks").Info("running pre-backup hooks",
+		"pvc", key, "window", st.NextSyncTime)
+	budget := st.NextSyncTime.Add(-HookLeadMargin).Sub(now)
+	r.launch(run, func() {
+		pctx, cancel := context.WithTimeout(ctx, budget)
+		defer cancel()
+		if !r.pre(pctx, run) {
+			r.mu.Lock()
+			run.finishing = true
+			r.mu.Unlock()
+			r.complete(ctx, run, HookRunPreFailed, "a pre-hook failed; the backup runs crash-consistent")
+			return
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		run.busy = false
+		r.publish(key, HookHolding)
+	})
+	rec = r.running(run)
+	rec.NextWindow = st.NextSyncTime
+	return rec
+}
+
+// launch runs fn, one of run's hook phases, in a goroutine of its own.
+// The caller holds mu; fn takes it only to record what it did.
+func (r *HookRunner) launch(run *hookRun, fn func()) {
+	run.busy = true
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		fn()
+	}()
+}
+
+// advance checks the sync a holding run waits for and starts the
+// post-hooks when the sync is over one way or another. A run whose
+// hooks are running is left to its goroutine.
+func (r *HookRunner) advance(ctx context.Context, run *hookRun) HookRecord {
+	key := run.key()
+	if run.busy {
+		return r.running(run)
+	}
+	rs := &unstructured.Unstructured{}
+	rs.SetGroupVersionKind(rsGVK)
+	err := r.Client.Get(ctx, types.NamespacedName{Namespace: run.namespace, Name: run.rsName}, rs)
+	switch {
+	case apierrors.IsNotFound(err):
+		r.finish(ctx, run, HookRunSyncFailed, "the RS disappeared while the application was quiesced")
+		return r.running(run)
+	case err != nil:
+		// A transient read error must not end a hold early; MaxHold
+		// still bounds it.
+		if r.now().Sub(run.run.StartedAt) > r.maxHold() {
+			r.finish(ctx, run, HookRunTimedOut, "could not read the RS: "+err.Error())
+			return r.running(run)
+		}
+		rec := r.record(key, HookHolding)
+		rec.Reason = "get RS: " + err.Error()
+		return rec
+	}
+
+	st := readMoverStatus(rs)
+	switch {
+	case !st.LastSyncTime.IsZero() && !st.LastSyncTime.Before(run.run.StartedAt):
+		r.finish(ctx, run, HookRunSucceeded, "")
+	case st.MoverResult == volsyncMoverFailed && !st.LastSyncStartTime.IsZero() && !st.LastSyncStartTime.Before(run.run.StartedAt):
+		r.finish(ctx, run, HookRunSyncFailed, "the mover reported a failed sync attempt")
+	case r.now().Sub(run.run.StartedAt) > r.maxHold():
+		r.finish(ctx, run, HookRunTimedOut, "the sync did not finish within "+r.maxHold().String())
+	default:
+		rec := r.record(key, HookHolding)
+		rec.NextWindow = st.NextSyncTime
+		return rec
+	}
+	rec := r.running(run)
+	rec.NextWindow = st.NextSyncTime
+	return rec
+}
+
+// running is the record of a run whose hooks a goroutine is running.
+func (r *HookRunner) running(run *hookRun) HookRecord {
+	rec := r.record(run.key(), HookHolding)
+	rec.Reason = "pre-hooks running"
+	if run.finishing {
+		rec.Reason = "post-hooks running"
+	}
+	return rec
+}
+
+// pre runs the pre-hooks: the exec first (a flush needs the
+// application up), then the scale to zero. Reports whether all
+// succeeded; it stops at the first failure. ctx carries the phase
+// deadline, which each hook's own timeout nests under.
+func (r *HookRunner) pre(ctx context.Context, run *hookRun) bool {
+	if h := run.hooks.PreBackupExec; !h.IsZero() {
+		if !r.execHook(ctx, run, hookPhasePre, h) {
+			return false
+		}
+	}
+	if t := run.hooks.PreBackupScale; !t.IsZero() {
+		return r.scaleDown(ctx, run, t)
+	}
+	return true
+}
+
+// post runs every post-hook whatever the others do, and reports
+// whether all succeeded.
+func (r *HookRunner) post(ctx context.Context, run *hookRun) bool {
+	ok := true
+	if t := run.hooks.PreBackupScale; !t.IsZero() && run.scaled {
+		ok = r.scaleUp(ctx, run, t) && ok
+	}
+	if h := run.hooks.PostBackupExec; !h.IsZero() {
+		ok = r.execHook(ctx, run, hookPhasePost, h) && ok
+	}
+	return ok
+}
+
+// finish starts the post-hooks of a run that is not busy; complete
+// files the run when they are done. The caller holds mu.
+func (r *HookRunner) finish(ctx context.Context, run *hookRun, outcome HookOutcome, reason string) {
+	if run.busy {
+		return
+	}
+	run.finishing = true
+	r.launch(run, func() { r.complete(ctx, run, outcome, reason) })
+}
+
+// complete runs the post-hooks and files the run. A post-hook failure
+// overrides the outcome. The post-hooks run even if ctx is cancelled:
+// an operator shutting down must still scale the application back.
+func (r *HookRunner) complete(ctx context.Context, run *hookRun, outcome HookOutcome, reason string) {
+	ctx = context.WithoutCancel(ctx)
+	if !r.post(ctx, run) {
+		reason = joinDetail("a post-hook failed; the application may still be quiesced", reason)
+		outcome = HookRunPostFailed
+	}
+	key := run.key()
+	r.mu.Lock()
+	run.run.Outcome, run.run.Reason, run.run.FinishedAt = outcome, reason, r.now()
+	if r.last == nil {
+		r.last = make(map[string]HookRun)
+	}
+	if r.done == nil {
+		r.done = make(map[string]time.Time)
+	}
+	r.last[key] = run.run
+	r.done[key] = run.run.Window
+	delete(r.runs, key)
+	r.publish(key, HookArmed)
+	r.mu.Unlock()
+
+	logger := log.FromContext(ctx).WithName("backup-hooks")
+	if outcome == HookRunSucceeded {
+		logger.Info("backup hooks finished", "pvc", key, "window", run.run.Window, "outcome", string(outcome))
+	} else {
+		logger.Error(errors.New(reason), "backup hooks finished", "pvc", key, "window", run.run.Window, "outcome", string(outcome))
+	}
+}
+
+// publish replaces key's record in the Store once a hook goroutine has
+// moved its run on, keeping the next window Step last read. A PVC Step
+// no longer reports stays absent. The caller holds mu.
+func (r *HookRunner) publish(key string, status HookStatus) {
+	prev, ok := r.records[key]
+	if !ok {
+		return
+	}
+	rec := r.record(key, status)
+	rec.NextWindow = prev.NextWindow
+	r.records[key] = rec
+	r.Store.SetHooks(r.records)
+}
+
+// addAction appends a to run's actions from a hook goroutine.
+func (r *HookRunner) addAction(run *hookRun, a HookAction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run.run.Actions = append(run.run.Actions, a)
+}
+
+// record builds key's record: the run in flight, else the last one.
+func (r *HookRunner) record(key string, status HookStatus) HookRecord {
+	rec := HookRecord{Status: status}
+	if run, ok := r.runs[key]; ok {
+		cp := run.run
+		cp.Actions = append([]HookAction(nil), cp.Actions...)
+		rec.LastRun = &cp
+		return rec
+	}
+	rec.LastRun = r.lastRun(key)
+	return rec
+}
+
+func (r *HookRunner) lastRun(key string) *HookRun {
+	last, ok := r.last[key]
+	if !ok {
+		return nil
+	}
+	last.Actions = append([]HookAction(nil), last.Actions...)
+	return &last
+}
+
+// execHook runs h in every running pod that mounts the PVC. Reports
+// whether it succeeded everywhere; no such pod is a failure — the
+// application is not there to quiesce, or to resume.
+func (r *HookRunner) execHook(ctx context.Context, run *hookRun, phase string, h labels.ExecHook) bool {
+	started := r.now()
+	var pods []corev1.Pod
+	err := errors.New("pod exec is not configured on this operator")
+	if r.Exec != nil {
+		pods, err = r.mountingPods(ctx, run.namespace, run.pvc, true)
+		if err == nil && len(pods) == 0 {
+			err = errors.New("no running pod mounts the PVC")
+		}
+	}
+	if err != nil {
+		r.addAction(run, HookAction{
+			Phase: phase, Hook: hookOpExec, Target: "pvc/" + run.pvc, Container: h.Container,
+			Result: HookActionFailed, StartedAt: started, Detail: err.Error(),
+		})
+		return false
+	}
+	ok := true
+	for i := range pods {
+		a := r.exec(ctx, run, phase, &pods[i], h)
+		r.addAction(run, a)
+		ok = ok && a.Result == HookActionSucceeded
+	}
+	return ok
+}
+
+// exec is the hooks' only path into a pod. It re-checks the allow-list
+// (the pod mounts the PVC, in the PVC's namespace), runs the command
+// under the hook timeout and journals the outcome.
+func (r *HookRunner) exec(ctx context.Context, run *hookRun, phase string, pod *corev1.Pod, h labels.ExecHook) HookAction {
+	a := HookAction{Phase: phase, Hook: hookOpExec, Target: "pod/" + pod.Name, Container: h.Container, StartedAt: r.now()}
+	command := h.Exec()
+	argv := make([]any, len(command))
+	for i, arg := range command {
+		argv[i] = arg
+	}
+	spec := map[string]any{
+		"phase":     phase,
+		"pvc":       run.pvc,
+		"container": h.Container,
+		"command":   argv,
+	}
+	entry := journal.Entry{
+		Trigger:   journal.TriggerBackupHook,
+		Op:        hookOpExec,
+		GVK:       hookPodGVK.GroupVersion().String() + "/" + hookPodGVK.Kind,
+		Namespace: pod.Namespace,
+		Name:      pod.Name,
+		Spec:      spec,
+		SpecHash:  journal.SpecHash(spec),
+	}
+
+	var refusal string
+	switch {
+	case pod.Namespace != run.namespace || !podMountsPVC(&pod.Spec, run.pvc):
+		refusal = "pod-not-mounting-pvc"
+	case !hasContainer(pod, h.Container):
+		refusal = "no-such-container"
+	}
+	if refusal != "" {
+		a.Result, a.Detail = HookActionRefused, refusal
+		entry.Status, entry.Reason = string(executor.OpRefused), refusal
+		r.Journal.Append(ctx, entry)
+		return a
+	}
+
+	ectx, cancel := context.WithTimeout(ctx, run.timeout)
+	defer cancel()
+	stdout, stderr, err := r.Exec.Exec(ectx, pod.Namespace, pod.Name, h.Container, command)
+	a.DurationMillis = r.now().Sub(a.StartedAt).Milliseconds()
+	switch {
+	case err == nil:
+		a.Result = HookActionSucceeded
+		entry.Status = string(executor.OpSucceeded)
+	case errors.Is(ectx.Err(), context.DeadlineExceeded):
+		a.Result, a.Detail = HookActionTimedOut, hookTimeoutDetail(ctx, run)
+		entry.Status, entry.Error = string(executor.OpFailed), a.Detail
+	default:
+		a.Result = HookActionFailed
+		a.Detail = joinDetail(err.Error(), tailOutput(stderr, stdout))
+		entry.Status, entry.Error = string(executor.OpFailed), a.Detail
+	}
+	r.Journal.Append(ctx, entry)
+	return a
+}
+
+// scaleDown scales t to zero, records the replica count on it, and
+// waits until no pod mounts the PVC. A workload another run already
+// holds at zero is shared, not re-recorded: its annotation keeps the
+// count from before the first hold.
+func (r *HookRunner) scaleDown(ctx context.Context, run *hookRun, t labels.ScaleTarget) bool {
+	a := HookAction{Phase: hookPhasePre, Hook: hookOpScale, Target: t.String(), StartedAt: r.now()}
+	defer func() {
+		a.DurationMillis = r.now().Sub(a.StartedAt).Milliseconds()
+		r.addAction(run, a)
+	}()
+
+	var done bool
+	a.Result, a.Detail, done = r.holdAtZero(ctx, run, t)
+	if done {
+		return a.Result == HookActionSucceeded
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, run.timeout)
+	defer cancel()
+	if err := r.waitNoPods(wctx, run.namespace, run.pvc); err != nil {
+		a.Result = HookActionFailed
+		if errors.Is(wctx.Err(), context.DeadlineExceeded) {
+			a.Result = HookActionTimedOut
+			a.Detail = joinDetail(a.Detail, hookTimeoutDetail(ctx, run))
+		}
+		a.Detail = joinDetail(a.Detail, err.Error())
+		return false
+	}
+	a.Result = HookActionSucceeded
+	return true
+}
+
+// holdAtZero is scaleDown's write, under scaleMu. done reports that
+// there is nothing to wait for: it failed, or the workload was already
+// at zero.
+func (r *HookRunner) holdAtZero(ctx context.Context, run *hookRun, t labels.ScaleTarget) (res HookActionResult, detail string, done bool) {
+	r.scaleMu.Lock()
+	defer r.scaleMu.Unlock()
+	obj, err := r.workload(ctx, run.namespace, t)
+	if err != nil {
+		return HookActionFailed, err.Error(), true
+	}
+	if _, held := obj.GetAnnotations()[labels.AnnotationBackupHookReplicas]; held {
+		detail = "already held at zero by another hook run"
+	} else {
+		replicas := workloadReplicas(obj)
+		if replicas == 0 {
+			return HookActionSucceeded, "already at 0 replicas; nothing to scale back", true
+		}
+		if res, detail := r.scale(ctx, run, hookPhasePre, obj, 0, strconv.Itoa(int(replicas))); res != HookActionSucceeded {
+			return res, detail, true
+		}
+		detail = fmt.Sprintf("scaled from %d", replicas)
+	}
+	r.mu.Lock()
+	run.scaled = true
+	r.mu.Unlock()
+	return "", detail, false
+}
+
+// scaleUp scales t back to the count recorded on it. A workload
+// someone scaled up meanwhile keeps their count; one another run still
+// holds stays down until that run ends.
+func (r *HookRunner) scaleUp(ctx context.Context, run *hookRun, t labels.ScaleTarget) bool {
+	a := HookAction{Phase: hookPhasePost, Hook: hookOpScale, Target: t.String(), StartedAt: r.now()}
+	defer func() {
+		a.DurationMillis = r.now().Sub(a.StartedAt).Milliseconds()
+		r.addAction(run, a)
+	}()
+	r.scaleMu.Lock()
+	defer r.scaleMu.Unlock()
+	r.mu.Lock()
+	other := r.heldBy(run.namespace, t, run.key())
+	r.mu.Unlock()
+	if other != "" {
+		a.Result, a.Detail = HookActionSucceeded, "left at zero: still held for "+other
+	} else {
+		a.Result, a.Detail = r.restoreReplicas(ctx, run, t)
+	}
+	// Released either way: the last run holding t must not count this
+	// one as still holding it.
+	r.mu.Lock()
+	run.scaled = false
+	r.mu.Unlock()
+	return a.Result == HookActionSucceeded
+}
+
+// restoreReplicas is the scale back shared by scaleUp and recovery.
+func (r *HookRunner) restoreReplicas(ctx context.Context, run *hookRun, t labels.ScaleTarget) (HookActionResult, string) {
+	obj, err := r.workload(ctx, run.namespace, t)
+	if err != nil {
+		return HookActionFailed, err.Error()
+	}
+	raw, held := obj.GetAnnotations()[labels.AnnotationBackupHookReplicas]
+	if !held {
+		return HookActionSucceeded, "not held at zero; nothing to do"
+	}
+	want, err := strconv.ParseInt(raw, 10, 32)
+	if err != nil || want < 0 {
+		// Scaling to a guess is worse than leaving it for a human; the
+		// annotation stays so the next pass reports it again.
+		return HookActionFailed, fmt.Sprintf("%s=%q is not a replica count; scale %s by hand", labels.AnnotationBackupHookReplicas, raw, t)
+	}
+	target := int32(want)
+	detail := fmt.Sprintf("scaled back to %d", want)
+	if cur := workloadReplicas(obj); cur != 0 {
+		target = cur
+		detail = fmt.Sprintf("left at %d: scaled by someone else during the backup", cur)
+	}
+	if res, d := r.scale(ctx, run, hookPhasePost, obj, target, ""); res != HookActionSucceeded {
+		return res, d
+	}
+	return HookActionSucceeded, detail
+}
+
+// recoverScale scales back a workload left at zero by a run this
+// process does not know about — an operator that died mid-backup. The
+// caller holds mu, so it cannot wait for scaleMu: while a hook
+// goroutine is scaling, recovery waits for a later Step.
+func (r *HookRunner) recoverScale(ctx context.Context, namespace, pvc string, t labels.ScaleTarget) {
+	key := namespace + "/" + pvc
+	if !r.scaleMu.TryLock() {
+		return
+	}
+	defer r.scaleMu.Unlock()
+	if r.heldBy(namespace, t, key) != "" {
+		return
+	}
+	obj, err := r.workload(ctx, namespace, t)
+	if err != nil {
+		return
+	}
+	if _, held := obj.GetAnnotations()[labels.AnnotationBackupHookReplicas]; !held {
+		return
+	}
+	now := r.now()
+	run := &hookRun{namespace: namespace, pvc: pvc, timeout: r.timeout(), run: HookRun{StartedAt: now}}
+	a := HookAction{Phase: hookPhasePost, Hook: hookOpScale, Target: t.String(), StartedAt: now}
+	a.Result, a.Detail = r.restoreReplicas(ctx, run, t)
+	a.DurationMillis = r.now().Sub(now).Milliseconds()
+	run.run.Actions = []HookAction{a}
+	run.run.Outcome, run.run.Reason = HookRunInterrupted, "recovered: a previous operator left "+t.String()+" at zero"
+	if a.Result != HookActionSucceeded {
+		run.run.Outcome = HookRunPostFailed
+	}
+	run.run.FinishedAt = r.now()
+	if r.last == nil {
+		r.last = make(map[string]HookRun)
+	}
+	r.last[key] = run.run
+	log.FromContext(ctx).WithName("backup-hooks").Info("scaled back a workload left at zero by an interrupted hook run",
+		"pvc", key, "workload", t.String(), "result", string(a.Result), "detail", a.Detail)
+}
+
+// heldBy returns the key of a run in flight, other than self, that
+// holds t at zero, or "". The caller holds mu.
+func (r *HookRunner) heldBy(namespace string, t labels.ScaleTarget, self string) string {
+	for key, run := range r.runs {
+		if key != self && run.scaled && run.namespace == namespace && run.hooks.PreBackupScale == t {
+			return key
+		}
+	}
+	return ""
+}
+
+// scale is the hooks' only path to a workload write. It re-checks the
+// allow-list (a Deployment or StatefulSet in the PVC's namespace whose
+// pod template mounts the PVC), merge-patches spec.replicas and the
+// replicas annotation — set to hold, removed when hold is "" — and
+// journals the outcome.
+func (r *HookRunner) scale(ctx context.Context, run *hookRun, phase string, obj client.Object, replicas int32, hold string) (HookActionResult, string) {
+	gvk := hookDeploymentGVK
+	var podSpec *corev1.PodSpec
+	var claims []corev1.PersistentVolumeClaim
+	switch w := obj.(type) {
+	case *appsv1.Deployment:
+		podSpec = &w.Spec.Template.Spec
+	case *appsv1.StatefulSet:
+		gvk, podSpec, claims = hookStatefulSetGVK, &w.Spec.Template.Spec, w.Spec.VolumeClaimTemplates
+	}
+	spec := map[string]any{"phase": phase, "pvc": run.pvc, "replicas": int64(replicas)}
+	entry := journal.Entry{
+		Trigger:               journal.TriggerBackupHook,
+		Op:                    hookOpScale,
+		GVK:                   gvk.GroupVersion().String() + "/" + gvk.Kind,
+		Namespace:             obj.GetNamespace(),
+		Name:                  obj.GetName(),
+		BeforeResourceVersion: obj.GetResourceVersion(),
+		Spec:                  spec,
+		SpecHash:              journal.SpecHash(spec),
+	}
+	if podSpec == nil || obj.GetNamespace() != run.namespace ||
+		!(podMountsPVC(podSpec, run.pvc) || claimTemplatesMatch(claims, obj.GetName(), run.pvc)) {
+		entry.Status, entry.Reason = string(executor.OpRefused), "workload-not-mounting-pvc"
+		r.Journal.Append(ctx, entry)
+		return HookActionRefused, "the workload does not mount " + run.pvc
+	}
+
+	before, ok := obj.DeepCopyObject().(client.Object)
+	if !ok {
+		return HookActionFailed, "copy workload"
+	}
+	anns := obj.GetAnnotations()
+	if anns == nil {
+		anns = map[string]string{}
+	}
+	if hold != "" {
+		anns[labels.AnnotationBackupHookReplicas] = hold
+	} else {
+		delete(anns, labels.AnnotationBackupHookReplicas)
+	}
+	obj.SetAnnotations(anns)
+	switch w := obj.(type) {
+	case *appsv1.Deployment:
+		w.Spec.Replicas = &replicas
+	case *appsv1.StatefulSet:
+		w.Spec.Replicas = &replicas
+	}
+	if err := r.Client.Patch(ctx, obj, client.MergeFrom(before)); err != nil {
+		entry.Status, entry.Error = string(executor.OpFailed), err.Error()
+		r.Journal.Append(ctx, entry)
+		return HookActionFailed, "patch " + strings.ToLower(gvk.Kind) + "/" + obj.GetName() + ": " + err.Error()
+	}
+	entry.Status, entry.AfterResourceVersion = string(executor.OpSucceeded), obj.GetResourceVersion()
+	r.Journal.Append(ctx, entry)
+	return HookActionSucceeded, ""
+}
+
+// workload reads the Deployment or StatefulSet t names.
+func (r *HookRunner) workload(ctx context.Context, namespace string, t labels.ScaleTarget) (client.Object, error) {
+	var obj client.Object = &appsv1.Deployment{}
+	if t.Kind == labels.ScaleKindStatefulSet {
+		obj = &appsv1.StatefulSet{}
+	}
+	if err := r.reader().Get(ctx, types.NamespacedName{Namespace: namespace, Name: t.Name}, obj); err != nil {
+		return nil, fmt.Errorf("get %s: %w", t, err)
+	}
+	return obj, nil
+}
+
+// workloadReplicas is spec.replicas, which defaults to 1.
+func workloadReplicas(obj client.Object) int32 {
+	var p *int32
+	switch w := obj.(type) {
+	case *appsv1.Deployment:
+		p = w.Spec.Replicas
+	case *appsv1.StatefulSet:
+		p = w.Spec.Replicas
+	}
+	if p == nil {
+		return 1
+	}
+	return *p
+}
+
+// mountingPods lists the pods in namespace that mount pvc and have not
+// terminated; running restricts them to pods in phase Running.
+func (r *HookRunner) mountingPods(ctx context.Context, namespace, pvc string, running bool) ([]corev1.Pod, error) {
+	pods := &corev1.PodList{}
+	if err := r.reader().List(ctx, pods, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("list pods in %s: %w", namespace, err)
+	}
+	var out []corev1.Pod
+	for _, p := range pods.Items {
+		switch {
+		case !podMountsPVC(&p.Spec, pvc):
+		case p.Status.Phase == corev1.PodSucceeded || p.Status.Phase == corev1.PodFailed:
+		case running && (p.Status.Phase != corev1.PodRunning || p.DeletionTimestamp != nil):
+		default:
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// waitNoPods polls until no pod mounts pvc or ctx ends.
+func (r *HookRunner) waitNoPods(ctx context.Context, namespace, pvc string) error {
+	for {
+		pods, err := r.mountingPods(ctx, namespace, pvc, false)
+		if err != nil {
+			return err
+		}
+		if len(pods) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d pod(s) still mount the PVC, e.g. %s", len(pods), pods[0].Name)
+		case <-time.After(hookScalePoll):
+		}
+	}
+}
+
+// hookTimeoutDetail says which deadline a timed-out hook hit: its own
+// timeout, or the pre-hooks' shared one in ctx.
+func hookTimeoutDetail(ctx context.Context, run *hookRun) string {
+	if ctx.Err() != nil {
+		return "the pre-hooks ran out of time before the sync"
+	}
+	return "did not finish within " + run.timeout.String()
+}
+
+// hasHookAnnotation reports whether any hook annotation is set, valid
+// or not: a malformed hook is reported, not ignored.
+func hasHookAnnotation(anns map[string]string) bool {
+	for _, k := range []string{
+		labels.AnnotationPreBackupExec,
+		labels.AnnotationPostBackupExec,
+		labels.AnnotationPreBackupScaleToZero,
+		labels.AnnotationBackupHookTimeout,
+	} {
+		if strings.TrimSpace(anns[k]) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func podMountsPVC(spec *corev1.PodSpec, pvc string) bool {
+	for _, v := range spec.Volumes {
+		if v.PersistentVolumeClaim != nil && v.PersistentVolumeClaim.ClaimName == pvc {
+			return true
+		}
+	}
+	return false
+}
+
+// claimTemplatesMatch reports whether pvc is one of a StatefulSet's
+// per-ordinal claims, "<template>-<statefulset>-<ordinal>".
+func claimTemplatesMatch(claims []corev1.PersistentVolumeClaim, sts, pvc string) bool {
+	for _, c := range claims {
+		ordinal, ok := strings.CutPrefix(pvc, c.Name+"-"+sts+"-")
+		if !ok {
+			continue
+		}
+		if _, err := strconv.ParseUint(ordinal, 10, 32); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func hasContainer(pod *corev1.Pod, name string) bool {
+	for _, c := range pod.Spec.Containers {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// tailOutput keeps the end of a failed command's output, stderr first.
+func tailOutput(stderr, stdout string) string {
+	out := strings.TrimSpace(stderr)
+	if out == "" {
+		out = strings.TrimSpace(stdout)
+	}
+	if len(out) > maxHookOutputBytes {
+		out = "…" + out[len(out)-maxHookOutputBytes:]
+	}
+	return out
+}
+
+func (r *HookRunner) reader() client.Reader {
+	if r.Reader != nil {
+		return r.Reader
+	}
+	return r.Client
+}
+
+func (r *HookRunner) timeout() time.Duration {
+	if r.Timeout <= 0 {
+		return DefaultHookTimeout
+	}
+	return r.Timeout
+}
+
+func (r *HookRunner) lead() time.Duration {
+	if r.Lead <= 0 {
+		return DefaultHookLead
+	}
+	return r.Lead
+}
+
+// preBudget is the most time a run's pre-hooks can have: the lead less
+// HookLeadMargin.
+func (r *HookRunner) preBudget() time.Duration {
+	return r.lead() - HookLeadMargin
+}
+
+func (r *HookRunner) maxHold() time.Duration {
+	if r.MaxHold <= 0 {
+		return DefaultHookMaxHold
+	}
+	return r.MaxHold
+}
+
+func (r *HookRunner) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
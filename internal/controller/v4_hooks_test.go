@@ -0,0 +1,711 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/auditclient"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+	v4labels "github.com/mitchross/pvc-plumber/internal/v4/labels"
+	"github.com/mitchross/pvc-plumber/internal/v4/mode"
+)
+
+// fakeExecer records every exec as "<pod>/<container>: <command>" (and
+// its full argv), fails the commands listed in fail and holds the ones
+// listed in block until their channel closes or ctx ends. Hook
+// goroutines share it.
+type fakeExecer struct {
+	mu    sync.Mutex
+	calls []string
+	argv  [][]string
+	fail  map[string]error
+	block map[string]chan struct{}
+}
+
+func (e *fakeExecer) Exec(ctx context.Context, _, pod, container string, command []string) (string, string, error) {
+	if ch := e.block[command[len(command)-1]]; ch != nil {
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return "", "", ctx.Err()
+		}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls = append(e.calls, pod+"/"+container+": "+command[len(command)-1])
+	e.argv = append(e.argv, command)
+	if err := e.fail[command[len(command)-1]]; err != nil {
+		return "", "fsfreeze: permission denied", err
+	}
+	return "ok", "", nil
+}
+
+// hookFixture wires a HookRunner to a fake client behind the
+// auditclient wrapper. The PVC myapp/data carries anns; its RS is due
+// one minute after the fixture's clock, inside the default lead.
+type hookFixture struct {
+	t       *testing.T
+	fake    client.WithWatch
+	store   *Store
+	journal *journal.Journal
+	execer  *fakeExecer
+	runner  *HookRunner
+	clock   time.Time
+}
+
+func newHookFixture(t *testing.T, m mode.Mode, anns map[string]string, objs ...client.Object) *hookFixture {
+	t.Helper()
+	rs := makeRS(testNSMyapp, testPVCName, v4labels.LabelManagedByValue, "s3://bucket", testPVCName)
+	_ = unstructured.SetNestedField(rs.Object, fixedTime().Add(time.Minute).Format(time.RFC3339), "status", "nextSyncTime")
+	objs = append(objs, makePVC(testNSMyapp, testPVCName, labelsEnabledManage(), anns), rs)
+	fakeC := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(objs...).Build()
+	auditC := auditclient.New(fakeC, m, slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)))
+
+	store := NewStore(m.String(), "bare-dst", testRepoSecretShare)
+	store.now = fixedTime
+	store.Set(ParityEntry{
+		Namespace: testNSMyapp, PVC: testPVCName,
+		Current: CurrentState{RSPresent: true, RSName: testPVCName, RSManagedBy: v4labels.LabelManagedByValue},
+	})
+	f := &hookFixture{t: t, fake: fakeC, store: store, journal: journal.New(nil), execer: &fakeExecer{}, clock: fixedTime()}
+	f.runner = &HookRunner{
+		Client:           auditC,
+		Exec:             f.execer,
+		Store:            store,
+		Enabled:          true,
+		Mode:             m,
+		SystemNamespaces: map[string]struct{}{"kube-system": {}},
+		Journal:          f.journal,
+		Now:              func() time.Time { return f.clock },
+	}
+	return f
+}
+
+// step runs one Step, waits for the hook goroutines it started, and
+// returns myapp/data's hook record.
+func (f *hookFixture) step() *HookRecord {
+	f.t.Helper()
+	if err := f.runner.Step(context.Background()); err != nil {
+		f.t.Fatalf("Step: %v", err)
+	}
+	f.runner.wg.Wait()
+	for _, e := range f.store.Snapshot().Entries {
+		if e.Namespace == testNSMyapp && e.PVC == testPVCName {
+			return e.BackupHooks
+		}
+	}
+	return nil
+}
+
+// syncRS plays VolSync: edit mutates the live RS.
+func (f *hookFixture) syncRS(edit func(rs *unstructured.Unstructured)) {
+	f.t.Helper()
+	rs := &unstructured.Unstructured{}
+	rs.SetGroupVersionKind(rsGVK)
+	key := types.NamespacedName{Namespace: testNSMyapp, Name: testPVCName}
+	if err := f.fake.Get(context.Background(), key, rs); err != nil {
+		f.t.Fatal(err)
+	}
+	edit(rs)
+	if err := f.fake.Update(context.Background(), rs); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+// completed plays a successful scheduled sync at at.
+func completed(at time.Time) func(rs *unstructured.Unstructured) {
+	return func(rs *unstructured.Unstructured) {
+		_ = unstructured.SetNestedField(rs.Object, at.Add(-30*time.Second).Format(time.RFC3339), "status", "lastSyncStartTime")
+		_ = unstructured.SetNestedField(rs.Object, at.Format(time.RFC3339), "status", "lastSyncTime")
+		_ = unstructured.SetNestedField(rs.Object, "Successful", "status", "latestMoverStatus", "result")
+	}
+}
+
+func (f *hookFixture) deployment(name string) *appsv1.Deployment {
+	f.t.Helper()
+	d := &appsv1.Deployment{}
+	if err := f.fake.Get(context.Background(), types.NamespacedName{Namespace: testNSMyapp, Name: name}, d); err != nil {
+		f.t.Fatal(err)
+	}
+	return d
+}
+
+// hookPod is a running pod in myapp with one container, app, mounting
+// claim.
+func hookPod(name, claim string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNSMyapp, Name: name},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app"}},
+			Volumes: []corev1.Volume{{Name: "data", VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim},
+			}}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+// hookDeployment is a Deployment in myapp at replicas, its template
+// mounting claim. anns may be nil.
+func hookDeployment(name, claim string, replicas int32, anns map[string]string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNSMyapp, Name: name, Annotations: anns},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{Spec: hookPod("", claim).Spec},
+		},
+	}
+}
+
+func journalOps(t *testing.T, j *journal.Journal) []string {
+	t.Helper()
+	var out []string
+	for _, e := range mustQuery(t, j) {
+		if e.Trigger != journal.TriggerBackupHook {
+			t.Errorf("entry %s/%s trigger %q, want %q", e.Op, e.Name, e.Trigger, journal.TriggerBackupHook)
+		}
+		out = append(out, e.Op+" "+e.Name+" "+e.Status)
+	}
+	return out
+}
+
+func TestHookRunner_ExecBracketsScheduledSync(t *testing.T) {
+	f := newHookFixture(t, mode.Permissive, map[string]string{
+		v4labels.AnnotationPreBackupExec:  "app:sqlite3 /data/db .backup",
+		v4labels.AnnotationPostBackupExec: "app:touch /data/.resumed",
+	}, hookPod("app-0", testPVCName), hookPod("unrelated", "other"))
+
+	// Outside the window nothing runs.
+	f.clock = fixedTime().Add(-5 * time.Minute)
+	rec := f.step()
+	if rec == nil || rec.Status != HookArmed || rec.LastRun != nil {
+		t.Fatalf("before the window: %+v", rec)
+	}
+	if !rec.NextWindow.Equal(fixedTime().Add(time.Minute)) {
+		t.Errorf("next_window = %s", rec.NextWindow)
+	}
+
+	f.clock = fixedTime()
+	rec = f.step()
+	if rec.Status != HookHolding || rec.LastRun == nil || rec.LastRun.Outcome != "" {
+		t.Fatalf("in the window: %+v", rec)
+	}
+	if got := strings.Join(f.execer.calls, "; "); got != "app-0/app: sqlite3 /data/db .backup" {
+		t.Fatalf("pre exec calls: %s", got)
+	}
+
+	// Still holding while the sync has not finished.
+	f.clock = fixedTime().Add(2 * time.Minute)
+	if rec = f.step(); rec.Status != HookHolding {
+		t.Fatalf("mid-sync: %+v", rec)
+	}
+
+	f.syncRS(completed(fixedTime().Add(3 * time.Minute)))
+	f.clock = fixedTime().Add(4 * time.Minute)
+	rec = f.step()
+	if rec.Status != HookArmed || rec.LastRun == nil || rec.LastRun.Outcome != HookRunSucceeded {
+		t.Fatalf("after the sync: %+v", rec)
+	}
+	if n := len(rec.LastRun.Actions); n != 2 {
+		t.Fatalf("actions: %+v", rec.LastRun.Actions)
+	}
+	post := rec.LastRun.Actions[1]
+	if post.Phase != "post" || post.Target != "pod/app-0" || post.Result != HookActionSucceeded {
+		t.Errorf("post action: %+v", post)
+	}
+	want := "exec app-0 succeeded; exec app-0 succeeded"
+	if got := strings.Join(journalOps(t, f.journal), "; "); got != want {
+		t.Errorf("journal: %s, want %s", got, want)
+	}
+
+	// The window is bracketed once, even though it stays open.
+	f.clock = fixedTime().Add(30 * time.Second)
+	f.step()
+	if len(f.execer.calls) != 2 {
+		t.Errorf("window ran twice: %v", f.execer.calls)
+	}
+}
+
+func TestHookRunner_ScaleToZeroAndBack(t *testing.T) {
+	f := newHookFixture(t, mode.Permissive, map[string]string{
+		v4labels.AnnotationPreBackupScaleToZero: "deployment/app",
+	}, hookDeployment("app", testPVCName, 3, nil))
+
+	rec := f.step()
+	if rec.Status != HookHolding {
+		t.Fatalf("pre: %+v", rec)
+	}
+	d := f.deployment("app")
+	if *d.Spec.Replicas != 0 || d.Annotations[v4labels.AnnotationBackupHookReplicas] != "3" {
+		t.Fatalf("after scale-down: replicas=%d annotations=%v", *d.Spec.Replicas, d.Annotations)
+	}
+
+	f.syncRS(completed(fixedTime().Add(3 * time.Minute)))
+	f.clock = fixedTime().Add(4 * time.Minute)
+	rec = f.step()
+	if rec.LastRun.Outcome != HookRunSucceeded {
+		t.Fatalf("run: %+v", rec.LastRun)
+	}
+	d = f.deployment("app")
+	if *d.Spec.Replicas != 3 {
+		t.Errorf("replicas = %d, want 3", *d.Spec.Replicas)
+	}
+	if _, held := d.Annotations[v4labels.AnnotationBackupHookReplicas]; held {
+		t.Errorf("replicas annotation left behind: %v", d.Annotations)
+	}
+	want := "scale app succeeded; scale app succeeded"
+	if got := strings.Join(journalOps(t, f.journal), "; "); got != want {
+		t.Errorf("journal: %s, want %s", got, want)
+	}
+}
+
+// A failed pre-hook does not skip the post-hooks: whatever the pre-hook
+// got done is undone, and the backup goes ahead crash-consistent.
+func TestHookRunner_PreHookFailureStillRunsPost(t *testing.T) {
+	f := newHookFixture(t, mode.Permissive, map[string]string{
+		v4labels.AnnotationPreBackupExec:  "app:fsfreeze -f /data",
+		v4labels.AnnotationPostBackupExec: "app:fsfreeze -u /data",
+	}, hookPod("app-0", testPVCName))
+	f.execer.fail = map[string]error{"fsfreeze -f /data": errors.New("command terminated with exit code 1")}
+
+	rec := f.step()
+	if rec.Status != HookArmed || rec.LastRun == nil || rec.LastRun.Outcome != HookRunPreFailed {
+		t.Fatalf("record: %+v", rec)
+	}
+	if got := strings.Join(f.execer.calls, "; "); got != "app-0/app: fsfreeze -f /data; app-0/app: fsfreeze -u /data" {
+		t.Errorf("exec calls: %s", got)
+	}
+	pre := rec.LastRun.Actions[0]
+	if pre.Result != HookActionFailed || !strings.Contains(pre.Detail, "exit code 1") || !strings.Contains(pre.Detail, "permission denied") {
+		t.Errorf("pre action: %+v", pre)
+	}
+}
+
+// However the hold ends, the workload is scaled back.
+func TestHookRunner_HoldEndings(t *testing.T) {
+	cases := []struct {
+		name  string
+		after func(f *hookFixture)
+		want  HookOutcome
+	}{
+		{
+			name: "mover failed",
+			after: func(f *hookFixture) {
+				f.syncRS(func(rs *unstructured.Unstructured) {
+					_ = unstructured.SetNestedField(rs.Object, fixedTime().Add(time.Minute).Format(time.RFC3339), "status", "lastSyncStartTime")
+					_ = unstructured.SetNestedField(rs.Object, volsyncMoverFailed, "status", "latestMoverStatus", "result")
+				})
+				f.clock = fixedTime().Add(5 * time.Minute)
+			},
+			want: HookRunSyncFailed,
+		},
+		{
+			name: "earlier failure is not this sync",
+			after: func(f *hookFixture) {
+				f.syncRS(func(rs *unstructured.Unstructured) {
+					_ = unstructured.SetNestedField(rs.Object, fixedTime().Add(-24*time.Hour).Format(time.RFC3339), "status", "lastSyncStartTime")
+					_ = unstructured.SetNestedField(rs.Object, volsyncMoverFailed, "status", "latestMoverStatus", "result")
+				})
+				f.clock = fixedTime().Add(5 * time.Minute)
+			},
+			want: "",
+		},
+		{
+			name:  "max hold",
+			after: func(f *hookFixture) { f.clock = fixedTime().Add(DefaultHookMaxHold + time.Minute) },
+			want:  HookRunTimedOut,
+		},
+		{
+			name: "RS deleted",
+			after: func(f *hookFixture) {
+				rs := &unstructured.Unstructured{}
+				rs.SetGroupVersionKind(rsGVK)
+				rs.SetNamespace(testNSMyapp)
+				rs.SetName(testPVCName)
+				if err := f.fake.Delete(context.Background(), rs); err != nil {
+					f.t.Fatal(err)
+				}
+			},
+			want: HookRunSyncFailed,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newHookFixture(t, mode.Permissive, map[string]string{
+				v4labels.AnnotationPreBackupScaleToZero: "deployment/app",
+			}, hookDeployment("app", testPVCName, 2, nil))
+			if rec := f.step(); rec.Status != HookHolding {
+				t.Fatalf("pre: %+v", rec)
+			}
+			tc.after(f)
+			rec := f.step()
+			if tc.want == "" {
+				if rec.Status != HookHolding {
+					t.Fatalf("want still holding: %+v", rec)
+				}
+				return
+			}
+			if rec.LastRun == nil || rec.LastRun.Outcome != tc.want {
+				t.Fatalf("record: %+v", rec)
+			}
+			if d := f.deployment("app"); *d.Spec.Replicas != 2 {
+				t.Errorf("replicas = %d, want 2", *d.Spec.Replicas)
+			}
+		})
+	}
+}
+
+// Hooks never act when the operator has them off or cannot write, and a
+// PVC whose hooks cannot run says why.
+func TestHookRunner_ReportsWithoutActing(t *testing.T) {
+	valid := map[string]string{v4labels.AnnotationPreBackupExec: "app:sync"}
+	cases := []struct {
+		name      string
+		mode      mode.Mode
+		anns      map[string]string
+		disable   bool
+		noRS      bool
+		foreignRS bool
+		want      HookStatus
+		reason    string
+	}{
+		{name: "hooks off", mode: mode.Permissive, anns: valid, disable: true, want: HookDisabled, reason: "not enabled"},
+		{name: "audit mode", mode: mode.Audit, anns: valid, want: HookDisabled, reason: "mode=audit"},
+		{name: "malformed", mode: mode.Permissive, anns: map[string]string{v4labels.AnnotationPreBackupExec: "sync"}, want: HookError, reason: "invalid backup hooks"},
+		{name: "post exec with scale", mode: mode.Permissive, anns: map[string]string{
+			v4labels.AnnotationPreBackupScaleToZero: "deployment/app",
+			v4labels.AnnotationPostBackupExec:       "app:sync",
+		}, want: HookError, reason: "invalid backup hooks"},
+		{name: "no RS", mode: mode.Permissive, anns: valid, noRS: true, want: HookError, reason: "no ReplicationSource"},
+		{name: "RS not operator-owned", mode: mode.Permissive, anns: valid, foreignRS: true, want: HookError, reason: "not operator-owned"},
+		{name: "timeout not shorter than the lead", mode: mode.Permissive, anns: map[string]string{
+			v4labels.AnnotationPreBackupExec:     "app:sync",
+			v4labels.AnnotationBackupHookTimeout: DefaultHookLead.String(),
+		}, want: HookError, reason: "not shorter than the pre-hook budget"},
+		{name: "timeout inside the lead margin", mode: mode.Permissive, anns: map[string]string{
+			v4labels.AnnotationPreBackupExec:     "app:sync",
+			v4labels.AnnotationBackupHookTimeout: (DefaultHookLead - HookLeadMargin).String(),
+		}, want: HookError, reason: "not shorter than the pre-hook budget"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newHookFixture(t, tc.mode, tc.anns, hookPod("app-0", testPVCName))
+			f.runner.Enabled = !tc.disable
+			if tc.noRS {
+				f.store.Set(ParityEntry{Namespace: testNSMyapp, PVC: testPVCName})
+			}
+			if tc.foreignRS {
+				f.store.Set(ParityEntry{Namespace: testNSMyapp, PVC: testPVCName, Current: CurrentState{RSPresent: true, RSName: testPVCName}})
+			}
+			rec := f.step()
+			if rec == nil || rec.Status != tc.want || !strings.Contains(rec.Reason, tc.reason) {
+				t.Fatalf("record: %+v, want %s (%s)", rec, tc.want, tc.reason)
+			}
+			if len(f.execer.calls) != 0 {
+				t.Errorf("exec ran: %v", f.execer.calls)
+			}
+		})
+	}
+}
+
+// PVCs without hook annotations, and PVCs in system namespaces, get no
+// record at all.
+func TestHookRunner_SkipsUnannotated(t *testing.T) {
+	f := newHookFixture(t, mode.Permissive, nil)
+	if rec := f.step(); rec != nil {
+		t.Fatalf("record for a PVC without hooks: %+v", rec)
+	}
+}
+
+// A typo'd or malicious annotation cannot reach a workload or container
+// unrelated to the PVC.
+func TestHookRunner_AllowList(t *testing.T) {
+	t.Run("workload not mounting the PVC", func(t *testing.T) {
+		f := newHookFixture(t, mode.Permissive, map[string]string{
+			v4labels.AnnotationPreBackupScaleToZero: "deployment/ingress",
+		}, hookDeployment("ingress", "certs", 2, nil))
+		rec := f.step()
+		if rec.LastRun == nil || rec.LastRun.Outcome != HookRunPreFailed {
+			t.Fatalf("record: %+v", rec)
+		}
+		if a := rec.LastRun.Actions[0]; a.Result != HookActionRefused {
+			t.Errorf("action: %+v", a)
+		}
+		if d := f.deployment("ingress"); *d.Spec.Replicas != 2 || len(d.Annotations) != 0 {
+			t.Errorf("refused workload was written: replicas=%d annotations=%v", *d.Spec.Replicas, d.Annotations)
+		}
+		if got := strings.Join(journalOps(t, f.journal), "; "); got != "scale ingress refused" {
+			t.Errorf("journal: %s", got)
+		}
+	})
+	t.Run("missing container", func(t *testing.T) {
+		f := newHookFixture(t, mode.Permissive, map[string]string{
+			v4labels.AnnotationPreBackupExec: "sidecar:sync",
+		}, hookPod("app-0", testPVCName))
+		rec := f.step()
+		if rec.LastRun == nil || rec.LastRun.Outcome != HookRunPreFailed {
+			t.Fatalf("record: %+v", rec)
+		}
+		if a := rec.LastRun.Actions[0]; a.Result != HookActionRefused || a.Detail != "no-such-container" {
+			t.Errorf("action: %+v", a)
+		}
+		if len(f.execer.calls) != 0 {
+			t.Errorf("exec ran: %v", f.execer.calls)
+		}
+	})
+	t.Run("no pod mounts the PVC", func(t *testing.T) {
+		f := newHookFixture(t, mode.Permissive, map[string]string{
+			v4labels.AnnotationPreBackupExec: "app:sync",
+		}, hookPod("other-0", "other"))
+		rec := f.step()
+		if rec.LastRun == nil || rec.LastRun.Outcome != HookRunPreFailed || len(f.execer.calls) != 0 {
+			t.Fatalf("record: %+v calls=%v", rec, f.execer.calls)
+		}
+	})
+}
+
+// An operator that died mid-run left the workload at zero; the next one
+// scales it back from the annotation even outside a window.
+func TestHookRunner_RecoversWorkloadLeftAtZero(t *testing.T) {
+	f := newHookFixture(t, mode.Permissive, map[string]string{
+		v4labels.AnnotationPreBackupScaleToZero: "deployment/app",
+	}, hookDeployment("app", testPVCName, 0, map[string]string{v4labels.AnnotationBackupHookReplicas: "2"}))
+	f.clock = fixedTime().Add(-time.Hour)
+
+	rec := f.step()
+	if rec.Status != HookArmed || rec.LastRun == nil || rec.LastRun.Outcome != HookRunInterrupted ||
+		!strings.Contains(rec.LastRun.Reason, "recovered") {
+		t.Fatalf("record: %+v", rec)
+	}
+	d := f.deployment("app")
+	if *d.Spec.Replicas != 2 {
+		t.Errorf("replicas = %d, want 2", *d.Spec.Replicas)
+	}
+	if _, held := d.Annotations[v4labels.AnnotationBackupHookReplicas]; held {
+		t.Errorf("replicas annotation left behind")
+	}
+}
+
+// Shutdown and a PVC losing its annotations both end a hold early, and
+// both scale the workload back.
+func TestHookRunner_InterruptedRunsScaleBack(t *testing.T) {
+	t.Run("release", func(t *testing.T) {
+		f := newHookFixture(t, mode.Permissive, map[string]string{
+			v4labels.AnnotationPreBackupScaleToZero: "deployment/app",
+		}, hookDeployment("app", testPVCName, 2, nil))
+		if rec := f.step(); rec.Status != HookHolding {
+			t.Fatalf("pre: %+v", rec)
+		}
+		f.runner.Release(context.Background(), "the operator is shutting down")
+		if d := f.deployment("app"); *d.Spec.Replicas != 2 {
+			t.Errorf("replicas = %d, want 2", *d.Spec.Replicas)
+		}
+		if rec := f.step(); rec.LastRun == nil || rec.LastRun.Outcome != HookRunInterrupted {
+			t.Errorf("record: %+v", rec)
+		}
+	})
+	t.Run("annotations removed", func(t *testing.T) {
+		f := newHookFixture(t, mode.Permissive, map[string]string{
+			v4labels.AnnotationPreBackupScaleToZero: "deployment/app",
+		}, hookDeployment("app", testPVCName, 2, nil))
+		if rec := f.step(); rec.Status != HookHolding {
+			t.Fatalf("pre: %+v", rec)
+		}
+		pvc := &corev1.PersistentVolumeClaim{}
+		if err := f.fake.Get(context.Background(), types.NamespacedName{Namespace: testNSMyapp, Name: testPVCName}, pvc); err != nil {
+			t.Fatal(err)
+		}
+		pvc.Annotations = nil
+		if err := f.fake.Update(context.Background(), pvc); err != nil {
+			t.Fatal(err)
+		}
+		if rec := f.step(); rec != nil {
+			t.Errorf("record for a PVC without hooks: %+v", rec)
+		}
+		if d := f.deployment("app"); *d.Spec.Replicas != 2 {
+			t.Errorf("replicas = %d, want 2", *d.Spec.Replicas)
+		}
+	})
+}
+
+// Two PVCs scaling the same workload: it stays down until both syncs
+// are over, and comes back to the count from before the first.
+func TestHookRunner_SharedWorkload(t *testing.T) {
+	anns := map[string]string{v4labels.AnnotationPreBackupScaleToZero: "statefulset/db"}
+	replicas := int32(1)
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNSMyapp, Name: "db"},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:             &replicas,
+			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{ObjectMeta: metav1.ObjectMeta{Name: "wal"}}},
+			Template:             corev1.PodTemplateSpec{Spec: hookPod("", testPVCName).Spec},
+		},
+	}
+	walRS := makeRS(testNSMyapp, "wal-db-0", v4labels.LabelManagedByValue, "s3://bucket", "wal-db-0")
+	_ = unstructured.SetNestedField(walRS.Object, fixedTime().Add(time.Minute).Format(time.RFC3339), "status", "nextSyncTime")
+	f := newHookFixture(t, mode.Permissive, anns, sts, walRS, makePVC(testNSMyapp, "wal-db-0", labelsEnabledManage(), anns))
+	f.store.Set(ParityEntry{
+		Namespace: testNSMyapp, PVC: "wal-db-0",
+		Current: CurrentState{RSPresent: true, RSName: "wal-db-0", RSManagedBy: v4labels.LabelManagedByValue},
+	})
+
+	f.step()
+	got := &appsv1.StatefulSet{}
+	readSTS := func() {
+		t.Helper()
+		if err := f.fake.Get(context.Background(), types.NamespacedName{Namespace: testNSMyapp, Name: "db"}, got); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readSTS()
+	if *got.Spec.Replicas != 0 || got.Annotations[v4labels.AnnotationBackupHookReplicas] != "1" {
+		t.Fatalf("after scale-down: replicas=%d annotations=%v", *got.Spec.Replicas, got.Annotations)
+	}
+
+	// data's sync finishes first; wal-db-0 still holds the workload.
+	f.syncRS(completed(fixedTime().Add(3 * time.Minute)))
+	f.clock = fixedTime().Add(4 * time.Minute)
+	f.step()
+	readSTS()
+	if *got.Spec.Replicas != 0 {
+		t.Fatalf("scaled up while another run holds it: replicas=%d", *got.Spec.Replicas)
+	}
+
+	f.clock = fixedTime().Add(DefaultHookMaxHold + time.Minute)
+	f.step()
+	readSTS()
+	if *got.Spec.Replicas != 1 {
+		t.Errorf("replicas = %d, want 1", *got.Spec.Replicas)
+	}
+	if _, held := got.Annotations[v4labels.AnnotationBackupHookReplicas]; held {
+		t.Errorf("replicas annotation left behind")
+	}
+}
+
+// A hook that hangs holds only its own PVC: Step returns, and another
+// PVC due in the same window runs its hooks meanwhile.
+func TestHookRunner_SlowHookDoesNotBlockOthers(t *testing.T) {
+	otherRS := makeRS(testNSMyapp, "other", v4labels.LabelManagedByValue, "s3://bucket", "other")
+	_ = unstructured.SetNestedField(otherRS.Object, fixedTime().Add(time.Minute).Format(time.RFC3339), "status", "nextSyncTime")
+	f := newHookFixture(t, mode.Permissive, map[string]string{v4labels.AnnotationPreBackupExec: "app:slow"},
+		hookPod("app-0", testPVCName), hookPod("other-0", "other"), otherRS,
+		makePVC(testNSMyapp, "other", labelsEnabledManage(), map[string]string{v4labels.AnnotationPreBackupExec: "app:quick"}))
+	f.store.Set(ParityEntry{
+		Namespace: testNSMyapp, PVC: "other",
+		Current: CurrentState{RSPresent: true, RSName: "other", RSManagedBy: v4labels.LabelManagedByValue},
+	})
+	release := make(chan struct{})
+	f.execer.block = map[string]chan struct{}{"slow": release}
+
+	if err := f.runner.Step(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// other's pre-hook finishes while data's still hangs.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		f.execer.mu.Lock()
+		calls := strings.Join(f.execer.calls, "; ")
+		f.execer.mu.Unlock()
+		if calls == "other-0/app: quick" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("other's pre-hook did not run while data's hung: %q", calls)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := f.runner.Step(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range f.store.Snapshot().Entries {
+		if e.PVC == testPVCName && (e.BackupHooks == nil || e.BackupHooks.Status != HookHolding || e.BackupHooks.Reason != "pre-hooks running") {
+			t.Errorf("data while its pre-hook hangs: %+v", e.BackupHooks)
+		}
+	}
+
+	close(release)
+	if rec := f.step(); rec.Status != HookHolding || rec.Reason != "" || len(rec.LastRun.Actions) != 1 {
+		t.Errorf("data after its pre-hook returned: %+v", rec)
+	}
+	f.runner.Release(context.Background(), "test done")
+}
+
+// The pre-hooks share one deadline HookLeadMargin before the window:
+// an exec hanging in each of two pods ends the phase there, not after
+// two hook timeouts, and the backup goes ahead crash-consistent.
+func TestHookRunner_PreHooksShareOneDeadline(t *testing.T) {
+	f := newHookFixture(t, mode.Permissive, map[string]string{v4labels.AnnotationPreBackupExec: "app:hang"},
+		hookPod("app-0", testPVCName), hookPod("app-1", testPVCName))
+	f.execer.block = map[string]chan struct{}{"hang": make(chan struct{})}
+	// 200ms of real time before the deadline.
+	f.clock = fixedTime().Add(time.Minute - HookLeadMargin - 200*time.Millisecond)
+
+	began := time.Now()
+	rec := f.step()
+	if took := time.Since(began); took > 10*time.Second {
+		t.Fatalf("pre-hooks took %s; the phase deadline did not bound them", took)
+	}
+	if rec.LastRun == nil || rec.LastRun.Outcome != HookRunPreFailed || len(rec.LastRun.Actions) != 2 {
+		t.Fatalf("record: %+v", rec)
+	}
+	for _, a := range rec.LastRun.Actions {
+		if a.Result != HookActionTimedOut || !strings.Contains(a.Detail, "ran out of time before the sync") {
+			t.Errorf("action: %+v", a)
+		}
+	}
+}
+
+// No run starts once the window is within HookLeadMargin of the sync.
+func TestHookRunner_WindowClosesAtTheMargin(t *testing.T) {
+	f := newHookFixture(t, mode.Permissive, map[string]string{v4labels.AnnotationPreBackupExec: "app:sync"},
+		hookPod("app-0", testPVCName))
+	f.clock = fixedTime().Add(time.Minute - HookLeadMargin)
+	if rec := f.step(); rec.Status != HookArmed || rec.LastRun != nil || len(f.execer.calls) != 0 {
+		t.Fatalf("inside the margin: %+v, calls %v", rec, f.execer.calls)
+	}
+}
+
+// The exec form runs its argv as is, without a shell.
+func TestHookRunner_ExecForm(t *testing.T) {
+	f := newHookFixture(t, mode.Permissive, map[string]string{
+		v4labels.AnnotationPreBackupExec: `app:["redis-cli","SAVE"]`,
+	}, hookPod("app-0", testPVCName))
+	if rec := f.step(); rec.Status != HookHolding {
+		t.Fatalf("record: %+v", rec)
+	}
+	if len(f.execer.argv) != 1 || strings.Join(f.execer.argv[0], " ") != "redis-cli SAVE" {
+		t.Errorf("argv: %q", f.execer.argv)
+	}
+	f.runner.Release(context.Background(), "test done")
+}
+
+func TestClaimTemplatesMatch(t *testing.T) {
+	claims := []corev1.PersistentVolumeClaim{{ObjectMeta: metav1.ObjectMeta{Name: "data"}}}
+	cases := map[string]bool{
+		"data-db-0":    true,
+		"data-db-12":   true,
+		"data-db-":     false,
+		"data-db-x":    false,
+		"data-other-0": false,
+		"wal-db-0":     false,
+	}
+	for pvc, want := range cases {
+		if got := claimTemplatesMatch(claims, "db", pvc); got != want {
+			t.Errorf("claimTemplatesMatch(%q) = %t, want %t", pvc, got, want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/decision"
+	"github.com/mitchross/pvc-plumber/internal/v4/labels"
+)
+
+// IdentityIndex is the cluster-wide map of opted-in PVC → kopia lineage
+// (decision.LineageFor: the "<username>@<hostname>:/data" source its RS
+// writes), maintained by the V4AuditReconciler and read back into
+// planner.Inputs.KnownIdentities on every reconcile.
+//
+// Why it exists: decision.Decide has always carried a duplicate-identity
+// rule, but nothing ever populated Input.KnownIdentities, so two PVCs
+// whose RSes write the same kopia source — the same
+// pvc-plumber.io/backup-identity in one namespace, or an override "b"
+// next to the PVC <ns>/b — silently shared one lineage. The index is
+// what finally gives the planner something to compare against.
+//
+// The key is the rendered source, not the annotation: an override
+// carries no hostname and the mover falls back to the namespace, so the
+// same override in two namespaces is two lineages and no collision.
+//
+// Membership: only PVCs past the opt-in rules and not validly exempt are
+// indexed (see indexable). A not-opted-in PVC has no snapshots to collide
+// with; an exempt PVC never gets a ReplicationSource.
+//
+// Warm-up: the index starts empty and fills as the PVC informer's
+// initial list is reconciled, so the first PVC of a colliding pair can be
+// planned before its partner is known. Observe reports the partner set
+// whenever a group's membership changes, and the reconciler re-enqueues
+// those PVCs, so the earlier one converges to needs-human-review within a
+// reconcile of the later one being indexed.
+//
+// Safe for concurrent use.
+type IdentityIndex struct {
+	mu sync.RWMutex
+	// byPVC maps ns/pvc → identity.
+	byPVC map[types.NamespacedName]string
+	// byIdentity maps identity → set of PVCs resolving to it.
+	byIdentity map[string]map[types.NamespacedName]struct{}
+}
+
+// IdentityCollision is one shared lineage as reported in the /audit
+// summary. Identity is the kopia source ("b@ns:/data"); PVCs are
+// "<namespace>/<pvc>", sorted.
+type IdentityCollision struct {
+	Identity string   `json:"identity"`
+	PVCs     []string `json:"pvcs"`
+}
+
+// NewIdentityIndex returns an empty index.
+func NewIdentityIndex() *IdentityIndex {
+	return &IdentityIndex{
+		byPVC:      make(map[types.NamespacedName]string),
+		byIdentity: make(map[string]map[types.NamespacedName]struct{}),
+	}
+}
+
+// Observe records that key resolves to identity, replacing any previous
+// identity for key. It returns the OTHER PVCs whose collision status may
+// have changed — members of the group key left and of the group it
+// joined — or nil when key's identity is unchanged.
+func (x *IdentityIndex) Observe(key types.NamespacedName, identity string) []types.NamespacedName {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	prev, had := x.byPVC[key]
+	if had && prev == identity {
+		return nil
+	}
+	var affected []types.NamespacedName
+	if had {
+		affected = x.removeLocked(key, prev)
+	}
+	x.byPVC[key] = identity
+	group := x.byIdentity[identity]
+	if group == nil {
+		group = make(map[types.NamespacedName]struct{})
+		x.byIdentity[identity] = group
+	}
+	for other := range group {
+		affected = append(affected, other)
+	}
+	group[key] = struct{}{}
+	return affected
+}
+
+// Forget removes key from the index (PVC deleted, opted out, or exempted)
+// and returns the PVCs that shared its identity.
+func (x *IdentityIndex) Forget(key types.NamespacedName) []types.NamespacedName {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	prev, had := x.byPVC[key]
+	if !had {
+		return nil
+	}
+	return x.removeLocked(key, prev)
+}
+
+func (x *IdentityIndex) removeLocked(key types.NamespacedName, identity string) []types.NamespacedName {
+	delete(x.byPVC, key)
+	group := x.byIdentity[identity]
+	delete(group, key)
+	if len(group) == 0 {
+		delete(x.byIdentity, identity)
+		return nil
+	}
+	out := make([]types.NamespacedName, 0, len(group))
+	for other := range group {
+		out = append(out, other)
+	}
+	return out
+}
+
+// RefsFor returns every indexed PVC resolving to identity (including the
+// caller itself, if indexed) as decision.IdentityRefs, the shape
+// planner.Inputs.KnownIdentities takes. Only the matching group is
+// returned: the planner's check is an equality match, so handing it the
+// whole cluster would cost O(PVCs) per reconcile for no extra signal.
+func (x *IdentityIndex) RefsFor(identity string) []decision.IdentityRef {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	group := x.byIdentity[identity]
+	if len(group) == 0 {
+		return nil
+	}
+	out := make([]decision.IdentityRef, 0, len(group))
+	for k := range group {
+		out = append(out, decision.IdentityRef{Namespace: k.Namespace, PVCName: k.Name, Identity: identity})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		return out[i].PVCName < out[j].PVCName
+	})
+	return out
+}
+
+// Collisions returns every identity shared by two or more PVCs, sorted by
+// identity. Never nil, so /audit always carries the key.
+func (x *IdentityIndex) Collisions() []IdentityCollision {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	out := []IdentityCollision{}
+	for identity, group := range x.byIdentity {
+		if len(group) < 2 {
+			continue
+		}
+		pvcs := make([]string, 0, len(group))
+		for k := range group {
+			pvcs = append(pvcs, k.String())
+		}
+		sort.Strings(pvcs)
+		out = append(out, IdentityCollision{Identity: identity, PVCs: pvcs})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Identity < out[j].Identity })
+	return out
+}
+
+// indexable reports whether a PVC with this spec belongs in the
+// IdentityIndex: opted in past planner rules 4-5 (enabled=true or a
+// legacy backup label) and not exempt.
+func indexable(spec labels.Spec) bool {
+	return spec.ExemptKind == labels.ExemptNone && (spec.Enabled || spec.Origin != labels.OriginNone)
+}
@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	v4labels "github.com/mitchross/pvc-plumber/internal/v4/labels"
+	"github.com/mitchross/pvc-plumber/internal/v4/mode"
+)
+
+func nn(ns, name string) types.NamespacedName {
+	return types.NamespacedName{Namespace: ns, Name: name}
+}
+
+func TestIdentityIndex_ObserveForgetReportPeers(t *testing.T) {
+	x := NewIdentityIndex()
+	if peers := x.Observe(nn("a", "one"), "shared"); len(peers) != 0 {
+		t.Errorf("first member: peers %v", peers)
+	}
+	if peers := x.Observe(nn("b", "two"), "shared"); len(peers) != 1 || peers[0] != nn("a", "one") {
+		t.Errorf("second member: peers %v", peers)
+	}
+	if peers := x.Observe(nn("b", "two"), "shared"); peers != nil {
+		t.Errorf("unchanged identity must report no peers: %v", peers)
+	}
+	if got := x.Collisions(); len(got) != 1 || got[0].Identity != "shared" || strings.Join(got[0].PVCs, ",") != "a/one,b/two" {
+		t.Errorf("collisions: %+v", got)
+	}
+	if refs := x.RefsFor("shared"); len(refs) != 2 || refs[0].Namespace != "a" {
+		t.Errorf("RefsFor: %+v", refs)
+	}
+
+	// b/two moves to its own identity: a/one is the only peer affected.
+	if peers := x.Observe(nn("b", "two"), "b/two"); len(peers) != 1 || peers[0] != nn("a", "one") {
+		t.Errorf("move: peers %v", peers)
+	}
+	if got := x.Collisions(); len(got) != 0 {
+		t.Errorf("collisions after move: %+v", got)
+	}
+	if peers := x.Forget(nn("a", "one")); peers != nil {
+		t.Errorf("forget sole member: peers %v", peers)
+	}
+	if peers := x.Forget(nn("zz", "absent")); peers != nil {
+		t.Errorf("forget absent: peers %v", peers)
+	}
+}
+
+func TestIndexable(t *testing.T) {
+	cases := []struct {
+		name string
+		spec v4labels.Spec
+		want bool
+	}{
+		{"not opted in", v4labels.Spec{}, false},
+		{"manage only", v4labels.Spec{ManageVolSync: true}, false},
+		{"enabled", v4labels.Spec{Enabled: true, Origin: v4labels.OriginNew}, true},
+		{"legacy", v4labels.Spec{Origin: v4labels.OriginLegacyOnly}, true},
+		{"exempt", v4labels.Spec{Enabled: true, Origin: v4labels.OriginNew, ExemptKind: v4labels.ExemptValid}, false},
+	}
+	for _, tc := range cases {
+		if got := indexable(tc.spec); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// drainPeers returns the PVC keys queued on the reconciler's identity
+// channel since the last drain.
+func drainPeers(f *v4Fixture) []string {
+	var out []string
+	for {
+		select {
+		case ev := <-f.rec.identityEvents:
+			out = append(out, ev.Object.GetNamespace()+"/"+ev.Object.GetName())
+		default:
+			return out
+		}
+	}
+}
+
+func TestV4Reconcile_DuplicateIdentity_BothSidesNeedHumanReview(t *testing.T) {
+	// b's override equals a's name, so b's RS writes a's default kopia
+	// source a@myapp:/data.
+	f := newV4ModeFixture(t, mode.Permissive,
+		makePVC(testNSMyapp, "a", labelsEnabledManage(), nil),
+		makePVC(testNSMyapp, "b", labelsEnabledManage(), map[string]string{
+			v4labels.AnnotationBackupIdentity: "a",
+		}),
+	)
+	f.rec.identityEvents = make(chan event.GenericEvent, identityEventBuffer)
+
+	// Index cold: a is planned before b is known and gets its children.
+	if e := f.reconcile(testNSMyapp, "a"); e.Action != ActionWouldCreate {
+		t.Fatalf("a (cold index): got %q", e.Action)
+	}
+	f.assertDidWriteByVerb(t, 2, 0, 0)
+
+	// b joins the identity: refused, and a is re-enqueued.
+	eb := f.reconcile(testNSMyapp, "b")
+	if eb.Action != ActionNeedsHumanReview || len(eb.PlannedOps) != 0 {
+		t.Fatalf("b: action %q ops %d", eb.Action, len(eb.PlannedOps))
+	}
+	if len(eb.Blockers) != 1 || !strings.Contains(eb.Blockers[0], "myapp/a") || !strings.Contains(eb.Blockers[0], "myapp/b") {
+		t.Errorf("b blockers must name both PVCs: %v", eb.Blockers)
+	}
+	if peers := drainPeers(f); len(peers) != 1 || peers[0] != "myapp/a" {
+		t.Errorf("re-enqueued peers: %v", peers)
+	}
+	if f.liveExists(rsGVK, testNSMyapp, "b") {
+		t.Error("RS created for the colliding PVC")
+	}
+
+	// The re-enqueued reconcile of a now sees the collision too.
+	if ea := f.reconcile(testNSMyapp, "a"); ea.Action != ActionNeedsHumanReview {
+		t.Errorf("a (warm index): got %q", ea.Action)
+	}
+	f.assertDidWriteByVerb(t, 2, 0, 0)
+
+	sum := f.store.Snapshot().Summary
+	if len(sum.IdentityCollisions) != 1 || sum.IdentityCollisions[0].Identity != "a@myapp:/data" ||
+		strings.Join(sum.IdentityCollisions[0].PVCs, ",") != "myapp/a,myapp/b" {
+		t.Errorf("summary collisions: %+v", sum.IdentityCollisions)
+	}
+
+	// b deleted: a is re-enqueued and recovers.
+	if err := f.fake.Delete(context.Background(), makePVC(testNSMyapp, "b", nil, nil)); err != nil {
+		t.Fatal(err)
+	}
+	f.reconcile(testNSMyapp, "b")
+	if peers := drainPeers(f); len(peers) != 1 || peers[0] != "myapp/a" {
+		t.Errorf("peers after delete: %v", peers)
+	}
+	if ea := f.reconcile(testNSMyapp, "a"); ea.Action != ActionAlreadyMatches {
+		t.Errorf("a after collision cleared: got %q (%v)", ea.Action, ea.Blockers)
+	}
+	if got := f.store.Snapshot().Summary.IdentityCollisions; got == nil || len(got) != 0 {
+		t.Errorf("collisions after cleanup: %#v", got)
+	}
+}
+
+func TestV4Reconcile_DuplicateIdentity_NotOptedInPeerIgnored(t *testing.T) {
+	// c is not opted in, so its would-be default identity never enters
+	// the index even though b's override names it.
+	f := newV4ModeFixture(t, mode.Permissive,
+		makePVC(testNSMyapp, "c", nil, nil),
+		makePVC(testNSMyapp, "b", labelsEnabledManage(), map[string]string{
+			v4labels.AnnotationBackupIdentity: testNSMyapp + "/c",
+		}),
+	)
+	f.reconcile(testNSMyapp, "c")
+	if e := f.reconcile(testNSMyapp, "b"); e.Action != ActionWouldCreate {
+		t.Errorf("b: got %q (%v)", e.Action, e.Blockers)
+	}
+}
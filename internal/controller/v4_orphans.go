@@ -0,0 +1,405 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/executor"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+	"github.com/mitchross/pvc-plumber/internal/v4/labels"
+	"github.com/mitchross/pvc-plumber/internal/v4/mode"
+	"github.com/mitchross/pvc-plumber/internal/v4/planner"
+)
+
+// Orphaned-child detection.
+//
+// The reconciler is PVC-driven: when a PVC is deleted it drops the Store
+// entry (see Reconcile step 2) and never looks at that PVC's children
+// again. pvc-plumber deliberately sets no ownerReference from RS/RD back
+// to the PVC (children must outlive transient PVC churn — a PVC deleted
+// and recreated by a restore must find its RD still there), so nothing
+// garbage-collects them either. A decommissioned app therefore leaves an
+// operator-owned ReplicationSource behind that keeps scheduling mover
+// Jobs against a PVC that no longer exists, and an RD holding a restore
+// pointer nobody will use.
+//
+// OrphanReaper is the periodic sweep that closes that gap. It lists every
+// RS/RD carrying app.kubernetes.io/managed-by=pvc-plumber, resolves the
+// owning PVC from the pvc-plumber.io/source-namespace + /source-pvc labels
+// the builder stamps, and reports every child whose PVC is gone in the
+// /audit `orphans` section.
+//
+// Reaping is opt-in and asymmetric:
+//
+//   - ReplicationSource: deleted once it has been continuously orphaned
+//     for Grace (default 7 days — long enough that a cluster rebuild or a
+//     slow app migration, where the PVC comes back under the same name,
+//     never loses its backup chain). The delete goes through
+//     executor.Execute, so the GVK allow-list and the ownership re-check
+//     apply and the write is journaled like any reconcile write. Like
+//     every reconcile write it also needs the namespace write gate: an
+//     RS in a namespace without pvc-plumber.io/managed-namespace=true is
+//     reported with reap=refused and left in place.
+//   - ReplicationDestination: never deleted. It is the only in-cluster
+//     pointer to the kopia identity a future restore needs, and it costs
+//     nothing while idle. Reported with reap=retained.
+//
+// Grace is measured from the first sweep in this process that saw the
+// child orphaned, not from the PVC's deletion (which leaves no trace).
+// A restart therefore restarts the clock — the fail-safe direction.
+
+// DefaultOrphanGrace is the reap grace period when none is configured.
+const DefaultOrphanGrace = 7 * 24 * time.Hour
+
+// DefaultOrphanScanInterval is the sweep cadence when none is configured.
+const DefaultOrphanScanInterval = 10 * time.Minute
+
+// OrphanReapState is the per-child reaping verdict surfaced in /audit.
+type OrphanReapState string
+
+const (
+	// OrphanReapDisabled: reaping is off (the default); report only.
+	OrphanReapDisabled OrphanReapState = "disabled"
+
+	// OrphanReapPending: reaping is on but the grace period has not
+	// elapsed. ReapAfter says when it will.
+	OrphanReapPending OrphanReapState = "pending"
+
+	// OrphanReapRetained: a ReplicationDestination. Never reaped.
+	OrphanReapRetained OrphanReapState = "retained"
+
+	// OrphanReapDeleted: the RS was deleted by this sweep.
+	OrphanReapDeleted OrphanReapState = "deleted"
+
+	// OrphanReapSkipped: grace elapsed but the operator is in audit
+	// mode; the executor short-circuited the delete.
+	OrphanReapSkipped OrphanReapState = "skipped"
+
+	// OrphanReapRefused: the delete was not attempted because the RS's
+	// namespace is not opted in to writes or its source PVC is back on
+	// the apiserver, or the executor refused it (e.g. the live object
+	// lost its managed-by label between list and delete).
+	OrphanReapRefused OrphanReapState = "refused"
+
+	// OrphanReapFailed: the apiserver rejected the delete. Retried on
+	// the next sweep.
+	OrphanReapFailed OrphanReapState = "failed"
+)
+
+// OrphanEntry is one operator-owned RS/RD whose source PVC no longer
+// exists.
+type OrphanEntry struct {
+	Kind            string    `json:"kind"`
+	Namespace       string    `json:"namespace"`
+	Name            string    `json:"name"`
+	SourceNamespace string    `json:"source_namespace"`
+	SourcePVC       string    `json:"source_pvc"`
+	CreatedAt       time.Time `json:"created_at,omitzero"`
+	OrphanedSince   time.Time `json:"orphaned_since"`
+	// AgeSeconds is computed by Store.Snapshot as GeneratedAt -
+	// OrphanedSince, like ParityEntry.AgeSeconds.
+	AgeSeconds int64           `json:"age_seconds"`
+	ReapAfter  time.Time       `json:"reap_after,omitzero"`
+	Reap       OrphanReapState `json:"reap"`
+	ReapReason string          `json:"reap_reason,omitempty"`
+}
+
+// Key identifies the child across sweeps.
+func (e OrphanEntry) Key() string {
+	return e.Kind + "/" + e.Namespace + "/" + e.Name
+}
+
+// OrphanReaper sweeps for orphaned operator-owned RS/RD. Register it with
+// the manager via mgr.Add; it implements manager.Runnable and
+// LeaderElectionRunnable.
+type OrphanReaper struct {
+	// Client reads RS/RD/PVCs and, when reaping, deletes through the
+	// executor. Production passes the auditclient-wrapped manager client
+	// so audit mode cannot write even if Mode were misconfigured.
+	Client client.Client
+
+	// Reader re-reads the source PVC right before each reap. Production
+	// passes the manager's API reader: Client's informer cache can lag,
+	// and a PVC recreated during the grace window must keep its RS.
+	// nil means Client.
+	Reader client.Reader
+
+	// Store receives the orphan list after every successful sweep.
+	Store *Store
+
+	// Mode gates the executor, exactly as on V4AuditReconciler.
+	Mode mode.Mode
+
+	// SystemNamespaces are never swept.
+	SystemNamespaces map[string]struct{}
+
+	// ReapReplicationSources enables deleting orphaned RS after Grace.
+	// False (the default) reports only.
+	ReapReplicationSources bool
+
+	// Grace is how long an RS must stay orphaned before it is reaped.
+	// <= 0 means DefaultOrphanGrace.
+	Grace time.Duration
+
+	// Interval is the sweep cadence. <= 0 means DefaultOrphanScanInterval.
+	Interval time.Duration
+
+	// Journal records reap deletes with Trigger=orphan-reap. nil is fine.
+	Journal *journal.Journal
+
+	// Now is injected for deterministic tests. nil → time.Now.
+	Now func() time.Time
+
+	mu        sync.Mutex
+	firstSeen map[string]time.Time
+}
+
+// NeedLeaderElection keeps reaping on a single replica.
+func (o *OrphanReaper) NeedLeaderElection() bool { return true }
+
+// Start runs a sweep immediately and then every Interval until ctx is
+// cancelled. Sweep errors are logged and retried on the next tick; the
+// Store keeps the previous orphan list meanwhile.
+func (o *OrphanReaper) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("orphan-reaper")
+	interval := o.Interval
+	if interval <= 0 {
+		interval = DefaultOrphanScanInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := o.Sweep(ctx); err != nil {
+			logger.Error(err, "orphan sweep failed")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep runs one detection (and, when enabled, reaping) pass and
+// publishes the result to the Store. Returns an error without touching
+// the Store when any list or PVC read fails other than NotFound — a
+// partial view could report a live PVC's children as orphans.
+func (o *OrphanReaper) Sweep(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("orphan-reaper")
+	now := o.now()
+
+	var candidates []OrphanEntry
+	var objs []*unstructured.Unstructured
+	for _, gvk := range []schema.GroupVersionKind{rsGVK, rdGVK} {
+		children, err := o.listManaged(ctx, gvk)
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			e, orphaned, err := o.classify(ctx, child)
+			if err != nil {
+				return err
+			}
+			if orphaned {
+				candidates = append(candidates, e)
+				objs = append(objs, child)
+			}
+		}
+	}
+
+	seen := o.trackFirstSeen(candidates, now)
+	grace := o.grace()
+	out := make([]OrphanEntry, 0, len(candidates))
+	for i, e := range candidates {
+		e.OrphanedSince = seen[e.Key()]
+		switch {
+		case e.Kind == rdGVK.Kind:
+			e.Reap = OrphanReapRetained
+		case !o.ReapReplicationSources:
+			e.Reap = OrphanReapDisabled
+		default:
+			e.ReapAfter = e.OrphanedSince.Add(grace)
+			if now.Before(e.ReapAfter) {
+				e.Reap = OrphanReapPending
+			} else {
+				e.Reap, e.ReapReason = o.reap(ctx, e, objs[i])
+				if e.Reap == OrphanReapFailed {
+					logger.Info("orphan reap failed; retrying next sweep",
+						"namespace", e.Namespace, "name", e.Name, "reason", e.ReapReason)
+				} else {
+					logger.Info("orphan reap",
+						"namespace", e.Namespace, "name", e.Name,
+						"source_pvc", e.SourceNamespace+"/"+e.SourcePVC,
+						"result", string(e.Reap), "reason", e.ReapReason)
+				}
+			}
+		}
+		out = append(out, e)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Key() < out[j].Key() })
+	o.Store.SetOrphans(out)
+	logger.V(1).Info("orphan sweep complete", "orphans", len(out))
+	return nil
+}
+
+// listManaged lists one kind cluster-wide, filtered server-side to the
+// operator's managed-by label. A missing VolSync CRD is an empty list,
+// matching observeCurrent's tolerance.
+func (o *OrphanReaper) listManaged(ctx context.Context, gvk schema.GroupVersionKind) ([]*unstructured.Unstructured, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind + "List"})
+	if err := o.Client.List(ctx, list, client.MatchingLabels{labels.LabelManagedByKey: labels.LabelManagedByValue}); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list %s: %w", gvk.Kind, err)
+	}
+	out := make([]*unstructured.Unstructured, 0, len(list.Items))
+	for i := range list.Items {
+		item := &list.Items[i]
+		item.SetGroupVersionKind(gvk)
+		out = append(out, item)
+	}
+	return out, nil
+}
+
+// classify reports whether child is orphaned. Children without a
+// source-pvc label, already being deleted, or in a system namespace are
+// never orphans — the sweep only acts on what it can resolve exactly.
+func (o *OrphanReaper) classify(ctx context.Context, child *unstructured.Unstructured) (OrphanEntry, bool, error) {
+	if _, isSystem := o.SystemNamespaces[child.GetNamespace()]; isSystem {
+		return OrphanEntry{}, false, nil
+	}
+	if child.GetDeletionTimestamp() != nil {
+		return OrphanEntry{}, false, nil
+	}
+	lbls := child.GetLabels()
+	pvcName := lbls[labels.LabelSourcePVC]
+	if pvcName == "" {
+		return OrphanEntry{}, false, nil
+	}
+	pvcNS := lbls[labels.LabelSourceNamespace]
+	if pvcNS == "" {
+		pvcNS = child.GetNamespace()
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	err := o.Client.Get(ctx, types.NamespacedName{Namespace: pvcNS, Name: pvcName}, pvc)
+	switch {
+	case err == nil:
+		return OrphanEntry{}, false, nil
+	case !apierrors.IsNotFound(err):
+		return OrphanEntry{}, false, fmt.Errorf("get PVC %s/%s for %s %s/%s: %w",
+			pvcNS, pvcName, child.GetKind(), child.GetNamespace(), child.GetName(), err)
+	}
+
+	return OrphanEntry{
+		Kind:            child.GetKind(),
+		Namespace:       child.GetNamespace(),
+		Name:            child.GetName(),
+		SourceNamespace: pvcNS,
+		SourcePVC:       pvcName,
+		CreatedAt:       child.GetCreationTimestamp().UTC(),
+	}, true, nil
+}
+
+// trackFirstSeen records when each orphan was first observed and forgets
+// children that are no longer orphaned (PVC recreated, child deleted),
+// so an orphan that recovers and later orphans again restarts its grace.
+func (o *OrphanReaper) trackFirstSeen(orphans []OrphanEntry, now time.Time) map[string]time.Time {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	next := make(map[string]time.Time, len(orphans))
+	for _, e := range orphans {
+		if t, ok := o.firstSeen[e.Key()]; ok {
+			next[e.Key()] = t
+		} else {
+			next[e.Key()] = now
+		}
+	}
+	o.firstSeen = next
+	out := make(map[string]time.Time, len(next))
+	for k, v := range next {
+		out[k] = v
+	}
+	return out
+}
+
+// reap deletes one orphaned RS through the executor and maps the outcome
+// to a reap state. The source PVC is read again first, through Reader: a
+// PVC that exists there is back, and its RS is not deleted. Then the
+// namespace write gate is checked, fail-closed as in Reconcile: a missing
+// Namespace is unmanaged. Any other read error is a failed reap retried
+// on the next sweep.
+func (o *OrphanReaper) reap(ctx context.Context, e OrphanEntry, rs *unstructured.Unstructured) (OrphanReapState, string) {
+	pvc := &corev1.PersistentVolumeClaim{}
+	err := o.reader().Get(ctx, types.NamespacedName{Namespace: e.SourceNamespace, Name: e.SourcePVC}, pvc)
+	switch {
+	case err == nil:
+		return OrphanReapRefused, "source PVC " + e.SourceNamespace + "/" + e.SourcePVC + " exists again"
+	case !apierrors.IsNotFound(err):
+		return OrphanReapFailed, "get PVC " + e.SourceNamespace + "/" + e.SourcePVC + ": " + err.Error()
+	}
+
+	ns := rs.GetNamespace()
+	nsObj := &corev1.Namespace{}
+	if err := o.Client.Get(ctx, types.NamespacedName{Name: ns}, nsObj); err != nil && !apierrors.IsNotFound(err) {
+		return OrphanReapFailed, "get namespace " + ns + ": " + err.Error()
+	}
+	if !labels.NamespaceManaged(nsObj.GetLabels()) {
+		return OrphanReapRefused, "namespace " + ns + " is not labeled " + labels.NamespaceManagedLabel + "=true"
+	}
+
+	res := executor.Execute(ctx, o.Client, o.Mode, planner.Plan{
+		Ops: []planner.PlannedOp{{Kind: planner.OpDelete, Resource: rs.DeepCopy()}},
+	})
+	o.Journal.RecordResult(ctx, journal.TriggerOrphanReap, res)
+	if len(res.Attempted) == 0 {
+		return OrphanReapFailed, "no-outcome"
+	}
+	out := res.Attempted[0]
+	switch out.Status {
+	case executor.OpSucceeded:
+		return OrphanReapDeleted, out.Reason
+	case executor.OpSkipped:
+		return OrphanReapSkipped, out.Reason
+	case executor.OpRefused:
+		return OrphanReapRefused, out.Reason
+	default:
+		return OrphanReapFailed, out.Reason
+	}
+}
+
+func (o *OrphanReaper) reader() client.Reader {
+	if o.Reader != nil {
+		return o.Reader
+	}
+	return o.Client
+}
+
+func (o *OrphanReaper) grace() time.Duration {
+	if o.Grace <= 0 {
+		return DefaultOrphanGrace
+	}
+	return o.Grace
+}
+
+func (o *OrphanReaper) now() time.Time {
+	if o.Now != nil {
+		return o.Now()
+	}
+	return time.Now()
+}
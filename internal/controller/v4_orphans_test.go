@@ -0,0 +1,310 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/auditclient"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+	v4labels "github.com/mitchross/pvc-plumber/internal/v4/labels"
+	"github.com/mitchross/pvc-plumber/internal/v4/mode"
+)
+
+// orphanFixture wires an OrphanReaper to a fake client behind the same
+// auditclient wrapper production uses, with a settable clock.
+type orphanFixture struct {
+	t      *testing.T
+	fake   client.WithWatch
+	audit  *auditclient.Client
+	store  *Store
+	reaper *OrphanReaper
+	clock  time.Time
+}
+
+func newOrphanFixture(t *testing.T, m mode.Mode, reap bool, seedObjs ...client.Object) *orphanFixture {
+	t.Helper()
+	fakeC := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(seedObjs...).Build()
+	auditC := auditclient.New(fakeC, m, slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)))
+	store := NewStore(m.String(), "bare-dst", testRepoSecretShare)
+	store.now = fixedTime
+	f := &orphanFixture{t: t, fake: fakeC, audit: auditC, store: store, clock: fixedTime()}
+	f.reaper = &OrphanReaper{
+		Client:                 auditC,
+		Store:                  store,
+		Mode:                   m,
+		SystemNamespaces:       map[string]struct{}{"kube-system": {}},
+		ReapReplicationSources: reap,
+		Grace:                  time.Hour,
+		Now:                    func() time.Time { return f.clock },
+	}
+	return f
+}
+
+func (f *orphanFixture) sweep() []OrphanEntry {
+	f.t.Helper()
+	if err := f.reaper.Sweep(context.Background()); err != nil {
+		f.t.Fatalf("Sweep: %v", err)
+	}
+	return f.store.Snapshot().Orphans
+}
+
+// ownedChild stamps the operator's managed-by + source labels onto an RS
+// or RD built by makeRS / makeRD.
+func ownedChild(u *unstructured.Unstructured, sourcePVC string) *unstructured.Unstructured {
+	u.SetLabels(map[string]string{
+		managedByLabel:                v4labels.LabelManagedByValue,
+		v4labels.LabelSourceNamespace: u.GetNamespace(),
+		v4labels.LabelSourcePVC:       sourcePVC,
+	})
+	return u
+}
+
+// orphanNamespace is the source namespace, opted in to writes or not.
+func orphanNamespace(managed bool) *corev1.Namespace {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: testNSMyapp}}
+	if managed {
+		ns.Labels = map[string]string{v4labels.NamespaceManagedLabel: "true"}
+	}
+	return ns
+}
+
+func TestOrphanSweep_ReportsOnlyOwnedChildrenOfMissingPVCs(t *testing.T) {
+	f := newOrphanFixture(t, mode.Permissive, false,
+		// Orphaned pair: PVC "gone" does not exist.
+		ownedChild(makeRS(testNSMyapp, "gone", "", testRepoSecretShare, "gone"), "gone"),
+		ownedChild(makeRD(testNSMyapp, "gone-dst", "", testRepoSecretShare), "gone"),
+		// Live pair.
+		makePVC(testNSMyapp, "alive", nil, nil),
+		ownedChild(makeRS(testNSMyapp, "alive", "", testRepoSecretShare, "alive"), "alive"),
+		// Argo-owned child of a missing PVC: not ours, never reported.
+		makeRS(testNSMyapp, "argo-gone", "argocd", testRepoSecretShare, "argo-gone"),
+		// Operator-owned but no source-pvc label: unresolvable, skipped.
+		makeRS(testNSMyapp, "unlabeled", v4labels.LabelManagedByValue, testRepoSecretShare, "unlabeled"),
+		// System namespace.
+		ownedChild(makeRS("kube-system", "sys", "", testRepoSecretShare, "sys"), "sys"),
+	)
+
+	orphans := f.sweep()
+	if len(orphans) != 2 {
+		t.Fatalf("orphans: got %d (%+v), want 2", len(orphans), orphans)
+	}
+	byKind := map[string]OrphanEntry{}
+	for _, o := range orphans {
+		byKind[o.Kind] = o
+	}
+	if rs := byKind[rsGVK.Kind]; rs.Name != "gone" || rs.SourcePVC != "gone" || rs.Reap != OrphanReapDisabled {
+		t.Errorf("RS orphan: %+v", rs)
+	}
+	if rd := byKind[rdGVK.Kind]; rd.Name != "gone-dst" || rd.Reap != OrphanReapRetained {
+		t.Errorf("RD orphan: %+v", rd)
+	}
+	if got := f.store.Snapshot().Summary.Orphans; got != 2 {
+		t.Errorf("summary.orphans: got %d, want 2", got)
+	}
+	if d := f.audit.DidWriteTotals(); d.Total() != 0 {
+		t.Errorf("report-only sweep wrote: %+v", d)
+	}
+}
+
+func TestOrphanSweep_AgeTracksFirstSeen(t *testing.T) {
+	f := newOrphanFixture(t, mode.Permissive, false,
+		ownedChild(makeRS(testNSMyapp, "gone", "", testRepoSecretShare, "gone"), "gone"))
+	f.clock = fixedTime().Add(-30 * time.Minute)
+	first := f.sweep()
+	f.clock = fixedTime()
+	second := f.sweep()
+
+	if !second[0].OrphanedSince.Equal(first[0].OrphanedSince) {
+		t.Errorf("OrphanedSince moved: %v → %v", first[0].OrphanedSince, second[0].OrphanedSince)
+	}
+	// Snapshot's clock is fixedTime(); first seen 30m earlier.
+	if second[0].AgeSeconds != 1800 {
+		t.Errorf("AgeSeconds: got %d, want 1800", second[0].AgeSeconds)
+	}
+}
+
+func TestOrphanSweep_ReapsRSAfterGraceKeepsRD(t *testing.T) {
+	f := newOrphanFixture(t, mode.Permissive, true,
+		orphanNamespace(true),
+		ownedChild(makeRS(testNSMyapp, "gone", "", testRepoSecretShare, "gone"), "gone"),
+		ownedChild(makeRD(testNSMyapp, "gone-dst", "", testRepoSecretShare), "gone"),
+	)
+	mem := &recordingSink{}
+	f.reaper.Journal = journal.New(nil, mem)
+
+	orphans := f.sweep()
+	for _, o := range orphans {
+		if o.Kind == rsGVK.Kind && (o.Reap != OrphanReapPending || !o.ReapAfter.Equal(fixedTime().Add(time.Hour))) {
+			t.Errorf("within grace: %+v", o)
+		}
+	}
+	if d := f.audit.DidWriteTotals(); d.Delete != 0 {
+		t.Fatalf("deleted inside grace")
+	}
+
+	f.clock = fixedTime().Add(time.Hour)
+	orphans = f.sweep()
+	var rsState OrphanReapState
+	for _, o := range orphans {
+		if o.Kind == rsGVK.Kind {
+			rsState = o.Reap
+		}
+	}
+	if rsState != OrphanReapDeleted {
+		t.Errorf("RS reap after grace: got %q, want %q", rsState, OrphanReapDeleted)
+	}
+	if exists(t, f.fake, rsGVK.Kind, "gone") {
+		t.Error("RS still present after reap")
+	}
+	if !exists(t, f.fake, rdGVK.Kind, "gone-dst") {
+		t.Error("RD was deleted; it must be retained")
+	}
+	if len(mem.entries) != 1 || mem.entries[0].Trigger != journal.TriggerOrphanReap || mem.entries[0].Op != "delete" {
+		t.Errorf("journal: %+v", mem.entries)
+	}
+
+	// Next sweep: only the RD remains orphaned.
+	orphans = f.sweep()
+	if len(orphans) != 1 || orphans[0].Kind != rdGVK.Kind {
+		t.Errorf("after reap: %+v", orphans)
+	}
+}
+
+func TestOrphanSweep_PVCReturnsResetsGrace(t *testing.T) {
+	f := newOrphanFixture(t, mode.Permissive, true,
+		ownedChild(makeRS(testNSMyapp, "gone", "", testRepoSecretShare, "gone"), "gone"))
+	f.sweep()
+
+	// PVC recreated (e.g. by a rebuild) before grace expires.
+	if err := f.fake.Create(context.Background(), makePVC(testNSMyapp, "gone", nil, nil)); err != nil {
+		t.Fatal(err)
+	}
+	f.clock = fixedTime().Add(30 * time.Minute)
+	if orphans := f.sweep(); len(orphans) != 0 {
+		t.Fatalf("PVC back, still orphaned: %+v", orphans)
+	}
+
+	// Deleted again: the clock restarts from this sweep, so an hour
+	// after the ORIGINAL first-seen the RS is still pending.
+	pvc := makePVC(testNSMyapp, "gone", nil, nil)
+	if err := f.fake.Delete(context.Background(), pvc); err != nil {
+		t.Fatal(err)
+	}
+	f.sweep()
+	f.clock = fixedTime().Add(time.Hour)
+	orphans := f.sweep()
+	if len(orphans) != 1 || orphans[0].Reap != OrphanReapPending {
+		t.Errorf("grace did not restart: %+v", orphans)
+	}
+}
+
+// The sweep reads PVCs through the manager's cache. A PVC recreated
+// during the grace window that the cache has not caught up with yet must
+// not cost the RS its life: the reap re-reads the PVC from the apiserver
+// (Reader) and refuses.
+func TestOrphanSweep_PVCBackOnAPIServerNeverDeletes(t *testing.T) {
+	f := newOrphanFixture(t, mode.Permissive, true,
+		orphanNamespace(true),
+		ownedChild(makeRS(testNSMyapp, "gone", "", testRepoSecretShare, "gone"), "gone"))
+	apiserver := fake.NewClientBuilder().WithScheme(newTestScheme(t)).
+		WithObjects(makePVC(testNSMyapp, "gone", nil, nil)).Build()
+	f.reaper.Reader = apiserver
+	mem := &recordingSink{}
+	f.reaper.Journal = journal.New(nil, mem)
+
+	f.sweep()
+	f.clock = fixedTime().Add(2 * time.Hour)
+	orphans := f.sweep()
+	if len(orphans) != 1 || orphans[0].Reap != OrphanReapRefused {
+		t.Fatalf("reap with the PVC back: %+v", orphans)
+	}
+	if !exists(t, f.fake, rsGVK.Kind, "gone") {
+		t.Error("RS deleted although its PVC exists on the apiserver")
+	}
+	if d := f.audit.DidWriteTotals(); d.Total() != 0 || len(mem.entries) != 0 {
+		t.Errorf("writes: %+v, journal: %+v", d, mem.entries)
+	}
+
+	// Gone on the apiserver too: the next sweep past grace reaps.
+	f.reaper.Reader = nil
+	if orphans := f.sweep(); len(orphans) != 1 || orphans[0].Reap != OrphanReapDeleted {
+		t.Errorf("reap once the PVC is really gone: %+v", orphans)
+	}
+}
+
+func TestOrphanSweep_AuditModeNeverDeletes(t *testing.T) {
+	f := newOrphanFixture(t, mode.Audit, true,
+		orphanNamespace(true),
+		ownedChild(makeRS(testNSMyapp, "gone", "", testRepoSecretShare, "gone"), "gone"))
+	f.sweep()
+	f.clock = fixedTime().Add(2 * time.Hour)
+	orphans := f.sweep()
+	if len(orphans) != 1 || orphans[0].Reap != OrphanReapSkipped {
+		t.Errorf("audit reap: %+v", orphans)
+	}
+	if !exists(t, f.fake, rsGVK.Kind, "gone") {
+		t.Error("audit mode deleted the RS")
+	}
+	if d := f.audit.DidWriteTotals(); d.Total() != 0 {
+		t.Errorf("audit mode wrote: %+v", d)
+	}
+}
+
+func TestOrphanSweep_ClosedNamespaceNeverDeletes(t *testing.T) {
+	f := newOrphanFixture(t, mode.Permissive, true,
+		orphanNamespace(false),
+		ownedChild(makeRS(testNSMyapp, "gone", "", testRepoSecretShare, "gone"), "gone"))
+	mem := &recordingSink{}
+	f.reaper.Journal = journal.New(nil, mem)
+	f.sweep()
+	f.clock = fixedTime().Add(2 * time.Hour)
+	orphans := f.sweep()
+	if len(orphans) != 1 || orphans[0].Reap != OrphanReapRefused {
+		t.Fatalf("closed-namespace reap: %+v", orphans)
+	}
+	if !exists(t, f.fake, rsGVK.Kind, "gone") {
+		t.Error("RS deleted in a namespace without the write opt-in")
+	}
+	if d := f.audit.DidWriteTotals(); d.Total() != 0 {
+		t.Errorf("closed namespace wrote: %+v", d)
+	}
+	if len(mem.entries) != 0 {
+		t.Errorf("journal: %+v", mem.entries)
+	}
+}
+
+func TestStoreSnapshot_OrphansAlwaysPresent(t *testing.T) {
+	s := NewStore(testModeAudit, "bare-dst", testRepoSecretShare)
+	rep := s.Snapshot()
+	if rep.Orphans == nil || len(rep.Orphans) != 0 || rep.Summary.Orphans != 0 {
+		t.Errorf("empty store orphans: %#v / %d", rep.Orphans, rep.Summary.Orphans)
+	}
+}
+
+// recordingSink is an in-memory journal.Sink.
+type recordingSink struct{ entries []journal.Entry }
+
+func (m *recordingSink) Write(_ context.Context, e journal.Entry) error {
+	m.entries = append(m.entries, e)
+	return nil
+}
+
+func exists(t *testing.T, c client.Client, kind, name string) bool {
+	t.Helper()
+	u := &unstructured.Unstructured{}
+	if kind == rsGVK.Kind {
+		u.SetGroupVersionKind(rsGVK)
+	} else {
+		u.SetGroupVersionKind(rdGVK)
+	}
+	return c.Get(context.Background(), client.ObjectKey{Namespace: testNSMyapp, Name: name}, u) == nil
+}
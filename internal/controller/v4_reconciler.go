@@ -19,8 +19,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	"github.com/mitchross/pvc-plumber/internal/v4/decision"
 	"github.com/mitchross/pvc-plumber/internal/v4/executor"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
 	"github.com/mitchross/pvc-plumber/internal/v4/labels"
 	"github.com/mitchross/pvc-plumber/internal/v4/mode"
 	"github.com/mitchross/pvc-plumber/internal/v4/naming"
@@ -170,8 +173,38 @@ type V4AuditReconciler struct {
 	// disables periodic requeue (the test default; production sets a few
 	// minutes via cmd/operator/main.go).
 	ResyncInterval time.Duration
+
+	// Journal, when non-nil, receives every executor outcome that
+	// reached the write stage (succeeded / refused / failed) so the
+	// history of operator writes outlives the per-PVC ExecutionResult,
+	// which the next reconcile overwrites. Audit-mode skips are never
+	// journaled. nil disables journaling (the test default).
+	Journal *journal.Journal
+
+	// identityEvents feeds the channel source SetupWithManager registers:
+	// when a reconcile changes which PVCs share a backup identity, the
+	// other members of the group are pushed here so their verdicts flip
+	// to (or back from) needs-human-review without waiting for a resync.
+	// nil outside a manager (unit tests), which disables the re-enqueue.
+	identityEvents chan event.GenericEvent
+
+	// Backups, when non-nil, supplements each entry's last-backup time
+	// with the kopia repository's latest snapshot (see lastBackup).
+	// cmd/operator wires it alongside Snapshots when BACKEND_TYPE names a
+	// kopia backend; nil leaves freshness to the RS's status.lastSyncTime.
+	Backups BackupChecker
+
+	// Snapshots, when non-nil, resolves a PVC's point-in-time restore
+	// annotations to the kopia snapshot a restore would select, for
+	// /audit (see restoreSelection). nil leaves the selection unresolved;
+	// cmd/operator wires it when BACKEND_TYPE names a kopia backend.
+	Snapshots LineageLister
 }
 
+// identityEventBuffer bounds identityEvents. A full buffer drops the
+// re-enqueue (logged); ResyncInterval and the next PVC event converge it.
+const identityEventBuffer = 256
+
 // SetupWithManager registers the reconciler with the controller-runtime
 // manager. The PVC remains the reconcile primary (For), but as of rc7 the
 // controller ALSO watches the VolSync ReplicationSource / Replication
@@ -195,6 +228,18 @@ type V4AuditReconciler struct {
 // cached unstructured Get has always worked without registering the VolSync
 // types in the manager scheme. So no scheme change is needed here.
 //
+// A channel source carries identity-collision peers (see
+// enqueueIdentityPeers): the IdentityIndex is in-memory state, not a
+// watched object, so a PVC joining or leaving a shared backup identity
+// has to re-enqueue the other members itself.
+//
+// Namespaces are watched too (namespaceEventPredicate /
+// mapNamespaceToPVCs): the v4.0.1 write gate reads the Namespace's
+// pvc-plumber.io/managed-namespace label inside every PVC reconcile, so
+// flipping that label must re-evaluate every PVC in the namespace — an
+// open→closed flip in particular has to stop writes immediately, not at
+// the next resync.
+//
 // We do NOT use Owns()/EnqueueRequestForOwner: pvc-plumber deliberately sets
 // no ownerReference from a child back to its PVC (children must outlive
 // transient PVC churn), and Argo-owned inline children carry no pvc-plumber
@@ -206,11 +251,14 @@ func (r *V4AuditReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	rs.SetGroupVersionKind(rsGVK)
 	rd := &unstructured.Unstructured{}
 	rd.SetGroupVersionKind(rdGVK)
+	r.identityEvents = make(chan event.GenericEvent, identityEventBuffer)
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1.PersistentVolumeClaim{}).
 		Watches(rs, handler.EnqueueRequestsFromMapFunc(r.mapChildToPVC), builder.WithPredicates(childEventPredicate())).
 		Watches(rd, handler.EnqueueRequestsFromMapFunc(r.mapChildToPVC), builder.WithPredicates(childEventPredicate())).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.mapNamespaceToPVCs), builder.WithPredicates(namespaceEventPredicate())).
+		WatchesRawSource(source.Channel(r.identityEvents, &handler.EnqueueRequestForObject{})).
 		Named("pvc-plumber-v4-audit").
 		Complete(r)
 }
@@ -220,11 +268,14 @@ func (r *V4AuditReconciler) SetupWithManager(mgr ctrl.Manager) error {
 //   - Create / Delete: always pass. Delete is THE fix — the incident was a
 //     prune (a Delete) that produced no reconcile. Create covers a child
 //     reappearing with the wrong shape.
-//   - Update: pass only when .metadata.generation changed. VolSync writes
-//     RS/RD .status (lastSyncTime, latestMoverStatus) on every backup tick,
-//     bumping resourceVersion but NOT generation; the reconciler reads only
-//     spec/metadata, so status-only updates carry no parity value and would
-//     otherwise amplify the reconcile rate needlessly.
+//   - Update: pass when .metadata.generation changed, or when the
+//     status fields /audit derives verdicts from moved (moverStatusKey:
+//     lastSyncTime, latest mover result, derived health). VolSync writes
+//     RS/RD .status on every backup tick, bumping resourceVersion but NOT
+//     generation; condition messages and mover logs churn without
+//     changing any verdict and would otherwise amplify the reconcile
+//     rate needlessly. The key moves a handful of times per backup run
+//     (syncing → healthy/failing).
 //   - Generic: dropped.
 //
 // Unrelated-resource filtering is intentionally NOT done here (a label
@@ -240,11 +291,67 @@ func childEventPredicate() predicate.Predicate {
 			if e.ObjectOld == nil || e.ObjectNew == nil {
 				return true
 			}
-			return e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration()
+			if e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration() {
+				return true
+			}
+			oldU, okOld := e.ObjectOld.(*unstructured.Unstructured)
+			newU, okNew := e.ObjectNew.(*unstructured.Unstructured)
+			return okOld && okNew && moverStatusKey(oldU) != moverStatusKey(newU)
+		},
+	}
+}
+
+// namespaceEventPredicate filters the Namespace watch down to changes
+// the reconciler can act on: an Update whose pvc-plumber.io/* labels or
+// annotations differ (the managed-namespace write gate today, any future
+// namespace-scoped knob tomorrow). Everything else is dropped:
+//
+//   - Create: a new namespace has no PVCs yet, and on startup the PVC
+//     informer's own initial list already enqueues every PVC.
+//   - Delete: the namespace's PVCs are deleted with it and each PVC
+//     Delete event cleans its own Store entry.
+//   - Updates touching only foreign metadata (Argo tracking annotations,
+//     kube's own labels): no effect on any verdict.
+func namespaceEventPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc:  func(event.CreateEvent) bool { return false },
+		DeleteFunc:  func(event.DeleteEvent) bool { return false },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if e.ObjectOld == nil || e.ObjectNew == nil {
+				return true
+			}
+			return !labels.OwnKeysEqual(e.ObjectOld.GetLabels(), e.ObjectNew.GetLabels()) ||
+				!labels.OwnKeysEqual(e.ObjectOld.GetAnnotations(), e.ObjectNew.GetAnnotations())
 		},
 	}
 }
 
+// mapNamespaceToPVCs enqueues every PVC in the namespace. The List is
+// served by the manager's PVC informer cache (already running for
+// For(&PVC{})), so a label flip on a namespace with many PVCs costs no
+// apiserver round-trips. A List error enqueues nothing; the resync
+// interval is the backstop.
+func (r *V4AuditReconciler) mapNamespaceToPVCs(ctx context.Context, obj client.Object) []reconcile.Request {
+	if obj == nil {
+		return nil
+	}
+	ns := obj.GetName()
+	if _, isSystem := r.SystemNamespaces[ns]; isSystem {
+		return nil
+	}
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := r.List(ctx, pvcs, client.InNamespace(ns)); err != nil {
+		log.FromContext(ctx).Error(err, "v4: list PVCs for namespace event", "namespace", ns)
+		return nil
+	}
+	reqs := make([]reconcile.Request, 0, len(pvcs.Items))
+	for i := range pvcs.Items {
+		reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: ns, Name: pvcs.Items[i].Name}})
+	}
+	return reqs
+}
+
 // mapChildToPVC translates an RS/RD watch event into a reconcile request
 // for the owning PVC. Returns nil (enqueue nothing) whenever the owning PVC
 // cannot be resolved or does not exist — it never guesses.
@@ -353,6 +460,7 @@ func resolveSourcePVC(obj client.Object) (namespace, name string) {
 //  6. Compute expected state  → ExpectedState (always, even for
 //     not-opted-in PVCs — the report shows
 //     what the v4 names WOULD be).
+//     6.5. Update the IdentityIndex → KnownIdentities.
 //  7. Observe current RS/RD   → CurrentState.
 //  8. Classify owner          → OwnerClassification.
 //  9. Decide action           → ActionDecision.
@@ -371,6 +479,7 @@ func (r *V4AuditReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	if err := r.Get(ctx, req.NamespacedName, pvc); err != nil {
 		if apierrors.IsNotFound(err) {
 			r.Store.Delete(req.Namespace, req.Name)
+			r.enqueueIdentityPeers(ctx, r.Store.Identities().Forget(req.NamespacedName))
 			logger.V(1).Info("v4 audit: PVC gone, removed Store entry")
 			return ctrl.Result{}, nil
 		}
@@ -387,6 +496,20 @@ func (r *V4AuditReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	// still classify the action as skipped-not-opted-in.
 	expected := ComputeExpected(req.Namespace, req.Name, spec, r.NamingStrategy, r.DefaultRepoSecret)
 
+	// Step 6.5: backup-identity index. Record (or drop) this PVC's
+	// resolved identity and collect every PVC sharing it for the
+	// planner's duplicate-identity rule. Peers whose group changed are
+	// re-enqueued so both sides of a collision see it.
+	identities := r.Store.Identities()
+	var knownIdentities []decision.IdentityRef
+	if indexable(spec) {
+		lineage := decision.LineageFor(req.Namespace, req.Name, spec.BackupIdentity)
+		r.enqueueIdentityPeers(ctx, identities.Observe(req.NamespacedName, lineage))
+		knownIdentities = identities.RefsFor(lineage)
+	} else {
+		r.enqueueIdentityPeers(ctx, identities.Forget(req.NamespacedName))
+	}
+
 	// Step 7: observe current RS/RD.
 	current, err := r.observeCurrent(ctx, req.Namespace, expected)
 	if err != nil {
@@ -450,6 +573,7 @@ func (r *V4AuditReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		Current:              toPlannerCurrent(current),
 		Owner:                planner.OwnerClassification(string(owner)),
 		NamespaceManaged:     nsManaged,
+		KnownIdentities:      knownIdentities,
 		NamingStrategy:       r.NamingStrategy,
 		DefaultRepoSecret:    r.DefaultRepoSecret,
 		DefaultSnapshotClass: r.DefaultSnapshotClass,
@@ -472,6 +596,7 @@ func (r *V4AuditReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	// The reconciler decides what to log + whether to surface the
 	// failures in the Store entry.
 	execResult := executor.Execute(ctx, r.Client, r.Mode, plan)
+	r.Journal.RecordResult(ctx, journal.TriggerReconcile, execResult)
 
 	// Step 11: assemble + Store. PlannedOps is reduced to a compact
 	// summary (Kind / GVK / Namespace / Name) so the /audit response
@@ -519,6 +644,13 @@ func (r *V4AuditReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			}
 		}
 	}
+	if window, ok := freshnessWindowFor(spec); ok {
+		entry.FreshnessWindowSeconds = int64(window.Seconds())
+		entry.LastBackupAt, entry.LastBackupSource = r.lastBackup(ctx, req.Namespace, req.Name, spec, current)
+	}
+	if spec.HasRestoreSelection() {
+		entry.RestoreSelection = r.restoreSelection(ctx, req.Namespace, req.Name, spec)
+	}
 	if r.Now != nil {
 		entry.EvaluatedAt = r.Now()
 	}
@@ -539,6 +671,25 @@ func (r *V4AuditReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	return r.resultFor(spec), nil
 }
 
+// enqueueIdentityPeers pushes each peer onto identityEvents without
+// blocking the reconcile. Drops are logged, not retried: the dropped
+// peer still converges on its next event or resync.
+func (r *V4AuditReconciler) enqueueIdentityPeers(ctx context.Context, peers []types.NamespacedName) {
+	if r.identityEvents == nil {
+		return
+	}
+	for _, p := range peers {
+		pvc := &corev1.PersistentVolumeClaim{}
+		pvc.SetNamespace(p.Namespace)
+		pvc.SetName(p.Name)
+		select {
+		case r.identityEvents <- event.GenericEvent{Object: pvc}:
+		default:
+			log.FromContext(ctx).Info("v4 audit: identity peer re-enqueue dropped (buffer full)", "peer", p)
+		}
+	}
+}
+
 // resultFor returns the reconcile Result. Write-eligible PVCs are requeued
 // after ResyncInterval (when set) so a missed RS/RD watch event self-heals
 // within a bounded window; everything else returns the zero Result (event-
@@ -581,16 +732,22 @@ func toExecutionResultSummary(r executor.Result) ExecutionResultSummary {
 // ignored (2026-06-09 review finding).
 func toPlannerCurrent(c CurrentState) planner.CurrentState {
 	return planner.CurrentState{
-		RSPresent:    c.RSPresent,
-		RSName:       c.RSName,
-		RSManagedBy:  c.RSManagedBy,
-		RSRepository: c.RSRepository,
-		RSSourcePVC:  c.RSSourcePVC,
-		RSSchedule:   c.RSSchedule,
-		RDPresent:    c.RDPresent,
-		RDName:       c.RDName,
-		RDManagedBy:  c.RDManagedBy,
-		RDRepository: c.RDRepository,
+		RSPresent:         c.RSPresent,
+		RSName:            c.RSName,
+		RSManagedBy:       c.RSManagedBy,
+		RSRepository:      c.RSRepository,
+		RSSourcePVC:       c.RSSourcePVC,
+		RSSchedule:        c.RSSchedule,
+		RSOnDemandExpired: c.RSOnDemandExpired,
+		RSManualTrigger:   c.RSManualTrigger,
+		RDPresent:         c.RDPresent,
+		RDName:            c.RDName,
+		RDManagedBy:       c.RDManagedBy,
+		RDRepository:      c.RDRepository,
+		RDRestoreAsOf:     c.RDRestoreAsOf,
+		RDRestorePrevious: c.RDRestorePrevious,
+		RSMoverFailure:    moverFailure(c.RSStatus),
+		RDMoverFailure:    moverFailure(c.RDStatus),
 	}
 }
 
@@ -685,6 +842,18 @@ func (r *V4AuditReconciler) observeCurrent(ctx context.Context, namespace string
 		cur.RSRepository, _, _ = unstructured.NestedString(rs.Object, "spec", "kopia", "repository")
 		cur.RSSourcePVC, _, _ = unstructured.NestedString(rs.Object, "spec", "sourcePVC")
 		cur.RSSchedule, _, _ = unstructured.NestedString(rs.Object, "spec", "trigger", "schedule")
+		cur.RSManualTrigger, _, _ = unstructured.NestedString(rs.Object, "spec", "trigger", "manual")
+		if until, ok := onDemandUntil(rs); ok {
+			now := time.Now()
+			if r.Now != nil {
+				now = r.Now()
+			}
+			cur.RSOnDemandUntil = until
+			cur.RSOnDemandExpired = !now.Before(until)
+		}
+		st := readMoverStatus(rs)
+		cur.RSLastSyncTime = st.LastSyncTime
+		cur.RSStatus = &st
 	} else if !apierrors.IsNotFound(err) {
 		if meta.IsNoMatchError(err) {
 			logger.V(1).Info("v4 audit: VolSync ReplicationSource CRD not installed; treating as not-present")
@@ -701,6 +870,10 @@ func (r *V4AuditReconciler) observeCurrent(ctx context.Context, namespace string
 		cur.RDName = rd.GetName()
 		cur.RDManagedBy = rd.GetLabels()[managedByLabel]
 		cur.RDRepository, _, _ = unstructured.NestedString(rd.Object, "spec", "kopia", "repository")
+		cur.RDRestoreAsOf, _, _ = unstructured.NestedString(rd.Object, "spec", "kopia", "restoreAsOf")
+		cur.RDRestorePrevious, _, _ = unstructured.NestedInt64(rd.Object, "spec", "kopia", "previous")
+		st := readMoverStatus(rd)
+		cur.RDStatus = &st
 	} else if !apierrors.IsNotFound(err) {
 		if meta.IsNoMatchError(err) {
 			logger.V(1).Info("v4 audit: VolSync ReplicationDestination CRD not installed; treating as not-present")
@@ -5,6 +5,7 @@ import (
 	"context"
 	"log/slog"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -17,6 +18,7 @@ import (
 
 	"github.com/mitchross/pvc-plumber/internal/v4/auditclient"
 	"github.com/mitchross/pvc-plumber/internal/v4/builder"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
 	v4labels "github.com/mitchross/pvc-plumber/internal/v4/labels"
 	"github.com/mitchross/pvc-plumber/internal/v4/mode"
 	"github.com/mitchross/pvc-plumber/internal/v4/naming"
@@ -714,10 +716,8 @@ func labelsEnabledManage() map[string]string {
 }
 
 // labelsEnabledManageTier returns enabled + manage-volsync with a custom
-// tier value. Used by the tier=disabled cases today; parametric on
-// purpose for upcoming hourly/daily/custom-tier scenarios.
-//
-//nolint:unparam // both current callers pass "disabled"; future tier cases will diverge
+// tier value. Used by the tier=disabled cases and the manual-tier
+// freshness case.
 func labelsEnabledManageTier(tier string) map[string]string {
 	return map[string]string{
 		v4labels.LabelEnabled:       labelTrue,
@@ -1405,6 +1405,53 @@ func TestV4Reconcile_Patch67_PermissiveTierDisabledOperatorOwned_DeletesRSRD(t *
 	f.assertDidWriteByVerb(t, 0, 0, 2)
 }
 
+// Permissive tear-down is journaled with the live spec of each deleted
+// child, and a deleted RS can be replayed from that journal. Audit mode
+// journals nothing even though it plans the same ops.
+func TestV4Reconcile_Permissive_DeletesJournaledAndReplayable(t *testing.T) {
+	pvc := makePVC(testNSMyapp, "perm-journal", labelsEnabledManageTier("disabled"), nil)
+	rs := makeRS(testNSMyapp, "perm-journal", ManagedByPVCPlumberLabelValue, testRepoSecretShare, "perm-journal")
+	rd := makeRD(testNSMyapp, "perm-journal-dst", ManagedByPVCPlumberLabelValue, testRepoSecretShare)
+	f := newV4ModeFixture(t, mode.Permissive, pvc, rs, rd)
+	j := journal.New(nil)
+	f.rec.Journal = j
+
+	f.reconcile(testNSMyapp, "perm-journal")
+
+	entries, err := j.Query(context.Background(), journal.Filter{Namespace: testNSMyapp, Op: "delete"})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("journaled deletes: got %d, want 2 (%+v)", len(entries), entries)
+	}
+	for _, e := range entries {
+		if e.Status != "succeeded" || e.BeforeResourceVersion == "" || e.SpecHash == "" {
+			t.Errorf("delete entry incomplete: %+v", e)
+		}
+	}
+
+	out, err := journal.Replay(context.Background(), f.rec.Client, mode.Permissive, j, entries,
+		journal.Target{Kind: rsGVK.Kind, Namespace: testNSMyapp, Name: "perm-journal"})
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if out.Status != "succeeded" {
+		t.Fatalf("Replay outcome: %+v", out)
+	}
+	if got := f.liveRepo(rsGVK, testNSMyapp, "perm-journal"); got != testRepoSecretShare {
+		t.Errorf("replayed RS repository: got %q, want %q", got, testRepoSecretShare)
+	}
+
+	audit := newV4Fixture(t, makePVC(testNSMyapp, "audit-journal", labelsEnabledManage(), nil))
+	auditJournal := journal.New(nil)
+	audit.rec.Journal = auditJournal
+	audit.reconcile(testNSMyapp, "audit-journal")
+	if got, _ := auditJournal.Query(context.Background(), journal.Filter{}); len(got) != 0 {
+		t.Errorf("audit mode journaled %d entries, want 0", len(got))
+	}
+}
+
 // Permissive: enabled-only / manage-only / legacy-only / no-label /
 // backup-exempt all leave the cluster untouched — planner's empty Ops
 // is the gate, executor mechanics never engage.
@@ -1724,6 +1771,69 @@ func TestV4Reconcile_Permissive_ScheduleDrift_Repaired(t *testing.T) {
 	}
 }
 
+// An on-demand backup that died mid-hold leaves a scheduled RS on its
+// one-shot manual trigger. Once the hold deadline passes the reconciler
+// renders the schedule back; while it is live, the RS is left alone. A
+// manual-tier RS only loses the hold: its manual trigger stays, so the
+// repair fires no sync.
+func TestV4Reconcile_Permissive_OnDemandHold(t *testing.T) {
+	cases := []struct {
+		name  string
+		tier  v4labels.Tier
+		until time.Time
+		want  ActionKind
+	}{
+		{name: "expired", tier: v4labels.TierDaily, until: fixedTime().Add(-time.Minute), want: ActionWouldUpdate},
+		{name: "live", tier: v4labels.TierDaily, until: fixedTime().Add(time.Hour), want: ActionAlreadyMatches},
+		{name: "manual expired", tier: v4labels.TierManual, until: fixedTime().Add(-time.Minute), want: ActionWouldUpdate},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pvc := makePVC(testNSMyapp, testPVCName, map[string]string{
+				v4labels.LabelEnabled:       labelTrue,
+				v4labels.LabelManageVolSync: labelTrue,
+				v4labels.LabelTier:          tc.tier.String(),
+			}, nil)
+			rs := makeRS(testNSMyapp, testPVCName, v4labels.LabelManagedByValue,
+				naming.DefaultRepoSecretName, testPVCName)
+			_ = unstructured.SetNestedField(rs.Object, "on-demand-20260517-030000", "spec", "trigger", "manual")
+			rs.SetAnnotations(map[string]string{v4labels.AnnotationBackupOnDemandUntil: tc.until.Format(time.RFC3339)})
+			rd := makeRD(testNSMyapp, testPVCName+"-dst", v4labels.LabelManagedByValue,
+				naming.DefaultRepoSecretName)
+
+			f := newV4ModeFixture(t, mode.Permissive, pvc, rs, rd)
+			entry := f.reconcile(testNSMyapp, testPVCName)
+			if entry.Action != tc.want {
+				t.Fatalf("Action: got %q, want %q", entry.Action, tc.want)
+			}
+			if !entry.Current.RSOnDemandUntil.Equal(tc.until) {
+				t.Errorf("Current.RSOnDemandUntil: got %v, want %v", entry.Current.RSOnDemandUntil, tc.until)
+			}
+			if tc.want != ActionWouldUpdate {
+				return
+			}
+			live := &unstructured.Unstructured{}
+			live.SetGroupVersionKind(rsGVK)
+			if err := f.fake.Get(context.Background(),
+				types.NamespacedName{Namespace: testNSMyapp, Name: testPVCName}, live); err != nil {
+				t.Fatalf("get live RS: %v", err)
+			}
+			got, _, _ := unstructured.NestedString(live.Object, "spec", "trigger", "schedule")
+			if tc.tier == v4labels.TierManual {
+				manual, _, _ := unstructured.NestedString(live.Object, "spec", "trigger", "manual")
+				if got != "" || manual != "on-demand-20260517-030000" {
+					t.Errorf("live RS trigger after repair: schedule %q, manual %q; want the live manual trigger kept", got, manual)
+				}
+			} else if want := builder.ScheduleFor(testNSMyapp, testPVCName, v4labels.TierDaily); got != want {
+				t.Errorf("live RS schedule after repair: got %q, want %q", got, want)
+			}
+			if _, ok := live.GetAnnotations()[v4labels.AnnotationBackupOnDemandUntil]; ok {
+				t.Error("hold annotation survived the repair")
+			}
+		})
+	}
+}
+
 // The matching case must NOT regress to a false-positive would-update:
 // an operator-owned RS already carrying the builder's schedule stays
 // already-matches with zero writes.
@@ -125,6 +125,16 @@ const (
 	// that makes a DRY cluster-wide RS/RD write ClusterRoleBinding safe.
 	// Mirrors planner.ActionSkippedNamespaceNotManaged (same wire string).
 	ActionSkippedNamespaceNotManaged ActionKind = "skipped-namespace-not-managed"
+
+	// ActionMoverFailing: the RS/RD objects would otherwise be
+	// already-matches, but VolSync reports the mover failing (see
+	// MoverFailing). Configuration is fine; backups (or the restore
+	// destination) are not running. Puts mover failures in the same
+	// ledger as configuration drift so on-call does not need a second
+	// dashboard to notice nightly backups have stopped. Zero ops — the
+	// operator cannot fix a mover failure by rewriting the spec.
+	// Mirrors planner.ActionMoverFailing.
+	ActionMoverFailing ActionKind = "mover-failing"
 )
 
 // AllActionKinds returns every defined ActionKind, sorted for deterministic
@@ -133,6 +143,7 @@ func AllActionKinds() []ActionKind {
 	return []ActionKind{
 		ActionAlreadyMatches,
 		ActionInlineArgoObserved,
+		ActionMoverFailing,
 		ActionNeedsHumanReview,
 		ActionSkippedExempt,
 		ActionSkippedNamespaceNotManaged,
@@ -265,11 +276,36 @@ type CurrentState struct {
 	// silently ignored because this was never read). Additive /audit
 	// JSON field.
 	RSSchedule string `json:"rs_schedule,omitempty"`
+	// RSOnDemandUntil is the deadline of an on-demand backup's hold on
+	// the RS trigger (labels.AnnotationBackupOnDemandUntil); zero when
+	// no on-demand backup is running. RSOnDemandExpired is set once the
+	// deadline has passed with the hold still in place, which the
+	// planner repairs as drift.
+	RSOnDemandUntil   time.Time `json:"rs_on_demand_until,omitzero"`
+	RSOnDemandExpired bool      `json:"rs_on_demand_expired,omitempty"`
+	// RSManualTrigger is the live spec.trigger.manual. The planner keeps
+	// it when it drops an expired hold from a manual-tier RS.
+	RSManualTrigger string `json:"rs_manual_trigger,omitempty"`
+	// RSLastSyncTime is the live status.lastSyncTime — when the mover
+	// last completed successfully. Feeds the entry's freshness verdict.
+	RSLastSyncTime time.Time `json:"rs_last_sync_time,omitzero"`
+	// RSStatus is the rest of the live RS .status (conditions, sync
+	// timings, latest mover result/logs) plus its derived health. Nil
+	// when no RS is present.
+	RSStatus *MoverStatus `json:"rs_status,omitempty"`
 
 	RDPresent    bool   `json:"rd_present"`
 	RDName       string `json:"rd_name,omitempty"`
 	RDManagedBy  string `json:"rd_managed_by,omitempty"`
 	RDRepository string `json:"rd_repository,omitempty"`
+	// RDRestoreAsOf / RDRestorePrevious are the live RD's point-in-time
+	// selection (spec.kopia.restoreAsOf / previous); empty when it
+	// restores the latest snapshot.
+	RDRestoreAsOf     string `json:"rd_restore_as_of,omitempty"`
+	RDRestorePrevious int64  `json:"rd_restore_previous,omitempty"`
+	// RDStatus is the RD's .status, as RSStatus. never-synced is the
+	// normal state for an RD that has never been triggered.
+	RDStatus *MoverStatus `json:"rd_status,omitempty"`
 }
 
 // PlannedOpSummary is the audit-surfaced shape of a single planner operation.
@@ -351,6 +387,44 @@ type ParityEntry struct {
 	// MaxAge and the entry's age exceeds it.
 	AgeSeconds int64 `json:"age_seconds"`
 	Stale      bool  `json:"stale"`
+
+	// Backup freshness. The reconciler records LastBackupAt (newest of
+	// the RS's status.lastSyncTime and, when a backend is wired, the
+	// kopia repository's latest snapshot) plus the tier's window;
+	// Snapshot() derives BackupAgeSeconds and Freshness at read time so
+	// a backup that stops running turns overdue without waiting for a
+	// reconcile. All four are omitted for PVCs with no window (not
+	// opted in, exempt, manual / disabled tier).
+	LastBackupAt           time.Time       `json:"last_backup_at,omitzero"`
+	LastBackupSource       string          `json:"last_backup_source,omitempty"`
+	FreshnessWindowSeconds int64           `json:"freshness_window_seconds,omitempty"`
+	BackupAgeSeconds       *int64          `json:"backup_age_seconds,omitempty"`
+	Freshness              BackupFreshness `json:"freshness,omitempty"`
+
+	// RestoreDrill is the outcome of the most recent restore drill of
+	// this PVC (see v4_drill.go). Attached by Snapshot() from the
+	// Store's drill records, like the orphan list, so a reconcile
+	// rewriting the entry never drops it. Omitted until the PVC has been
+	// drilled.
+	RestoreDrill *DrillRecord `json:"restore_drill,omitempty"`
+
+	// RestoreSelection previews the snapshot a restore of this PVC
+	// would pick under its restore-as-of / restore-previous annotations
+	// (see v4_restore_selection.go). Omitted when the PVC restores the
+	// latest snapshot.
+	RestoreSelection *RestoreSelection `json:"restore_selection,omitempty"`
+
+	// RestoreClone is this PVC's restore-to-clone request and where it
+	// stands (see v4_clone.go). Attached by Snapshot() from the
+	// CloneRunner's latest sweep. Omitted when the PVC requests no
+	// clone.
+	RestoreClone *CloneRecord `json:"restore_clone,omitempty"`
+
+	// BackupHooks is where this PVC's pre/post backup hooks stand and
+	// how their latest run went (see v4_hooks.go). Attached by
+	// Snapshot() from the HookRunner's latest step. Omitted when the PVC
+	// sets no hook annotation.
+	BackupHooks *HookRecord `json:"backup_hooks,omitempty"`
 }
 
 // Key returns the stable map key used by the Store and by the /audit
@@ -369,6 +443,11 @@ type ParityReport struct {
 	DefaultRepoSecret string        `json:"default_repo_secret"`
 	Summary           ReportSummary `json:"summary"`
 	Entries           []ParityEntry `json:"entries"`
+
+	// Orphans lists operator-owned RS/RD whose source PVC no longer
+	// exists, as of the OrphanReaper's last sweep. Always present (empty
+	// when the reaper is not running or found nothing). See v4_orphans.go.
+	Orphans []OrphanEntry `json:"orphans"`
 }
 
 // ReportSummary holds the aggregate counts shown at the top of /audit.
@@ -387,6 +466,25 @@ type ReportSummary struct {
 	// and monitoring. Both added in rc7 after the nginx-example incident.
 	EntriesStale      int       `json:"entries_stale"`
 	OldestEvaluatedAt time.Time `json:"oldest_evaluated_at,omitzero"`
+
+	// Orphans is len(ParityReport.Orphans).
+	Orphans int `json:"orphans"`
+
+	// IdentityCollisions lists every kopia backup identity resolved by
+	// two or more opted-in PVCs (see IdentityIndex). Each affected PVC's
+	// entry is also needs-human-review with a blocker naming the others.
+	// Always present; empty on a healthy cluster.
+	IdentityCollisions []IdentityCollision `json:"identity_collisions"`
+
+	// ByFreshness counts entries per backup-freshness verdict, zero-
+	// filled. Entries without a verdict are not counted, so the buckets
+	// sum to the number of PVCs expected to be backed up on a cadence.
+	// by_freshness.overdue is the "backups silently stopped" alarm.
+	ByFreshness map[BackupFreshness]int `json:"by_freshness"`
+
+	// ByDrillResult counts entries by their latest restore-drill result,
+	// zero-filled. Undrilled PVCs are not counted.
+	ByDrillResult map[DrillResult]int `json:"by_drill_result"`
 }
 
 // Store is the in-memory parity registry. Written to by the
@@ -403,6 +501,31 @@ type Store struct {
 	mu      sync.RWMutex
 	entries map[string]ParityEntry
 
+	// orphans is replaced wholesale by the OrphanReaper after each sweep.
+	orphans []OrphanEntry
+
+	// namespaceNotes are appended to every entry of their namespace at
+	// Snapshot() time. Replaced wholesale by whoever computes them (the
+	// backup usage tracker's soft-quota warnings).
+	namespaceNotes map[string]string
+
+	// drills holds the latest restore-drill record per "<ns>/<pvc>",
+	// written by the DrillRunner. Unlike entries they survive the PVC's
+	// Store entry being deleted and recreated.
+	drills map[string]DrillRecord
+
+	// clones is replaced wholesale by the CloneRunner after each sweep,
+	// keyed "<ns>/<pvc>" of the source PVC.
+	clones map[string]CloneRecord
+
+	// hooks is replaced wholesale by the HookRunner after each step,
+	// keyed "<ns>/<pvc>".
+	hooks map[string]HookRecord
+
+	// identities is the reconciler's identity index; it carries its own
+	// lock and is read (not copied) by Snapshot.
+	identities *IdentityIndex
+
 	// Metadata included in every Snapshot(); set at construction.
 	operatorMode      string
 	namingStrategy    string
@@ -435,10 +558,18 @@ func NewStore(operatorMode, namingStrategy, defaultRepoSecret string) *Store {
 		operatorMode:      operatorMode,
 		namingStrategy:    namingStrategy,
 		defaultRepoSecret: defaultRepoSecret,
+		identities:        NewIdentityIndex(),
 		now:               time.Now,
 	}
 }
 
+// Identities returns the backup-identity index the reconciler maintains
+// alongside the entries. It lives on the Store so /audit's summary and
+// the reconciler's collision check always read the same index.
+func (s *Store) Identities() *IdentityIndex {
+	return s.identities
+}
+
 // Set inserts or replaces the entry for a (namespace, pvc) pair.
 // EvaluatedAt is set to now() if the caller left it zero; that lets
 // reconciler callers omit the field and tests inject a fixed value.
@@ -466,6 +597,62 @@ func (s *Store) Delete(namespace, pvc string) {
 	delete(s.entries, namespace+"/"+pvc)
 }
 
+// SetOrphans replaces the orphan list. The slice is copied.
+func (s *Store) SetOrphans(orphans []OrphanEntry) {
+	cp := append([]OrphanEntry(nil), orphans...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orphans = cp
+}
+
+// SetNamespaceNotes replaces the per-namespace notes. Keeping them
+// apart from the entries means a note appears (and clears) with the next
+// Snapshot(), not the next reconcile of each PVC. The map is copied.
+func (s *Store) SetNamespaceNotes(notes map[string]string) {
+	cp := make(map[string]string, len(notes))
+	for ns, note := range notes {
+		cp[ns] = note
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.namespaceNotes = cp
+}
+
+// SetDrill records the latest restore-drill outcome for (namespace, pvc).
+// The map is copied on write so Snapshot can read it outside the lock.
+func (s *Store) SetDrill(namespace, pvc string, rec DrillRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := make(map[string]DrillRecord, len(s.drills)+1)
+	for k, v := range s.drills {
+		next[k] = v
+	}
+	next[namespace+"/"+pvc] = rec
+	s.drills = next
+}
+
+// SetClones replaces the clone records. The map is copied.
+func (s *Store) SetClones(records map[string]CloneRecord) {
+	cp := make(map[string]CloneRecord, len(records))
+	for k, v := range records {
+		cp[k] = v
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clones = cp
+}
+
+// SetHooks replaces the backup hook records. The map is copied.
+func (s *Store) SetHooks(records map[string]HookRecord) {
+	cp := make(map[string]HookRecord, len(records))
+	for k, v := range records {
+		cp[k] = v
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = cp
+}
+
 // Len returns the current number of entries.
 func (s *Store) Len() int {
 	s.mu.RLock()
@@ -487,6 +674,11 @@ func (s *Store) Snapshot() ParityReport {
 	for _, e := range s.entries {
 		entries = append(entries, e)
 	}
+	orphans := append(make([]OrphanEntry, 0, len(s.orphans)), s.orphans...)
+	nsNotes := s.namespaceNotes
+	drills := s.drills
+	clones := s.clones
+	hooks := s.hooks
 	maxAge := s.maxAge
 	generatedAt := s.now()
 	s.mu.RUnlock()
@@ -503,6 +695,9 @@ func (s *Store) Snapshot() ParityReport {
 		ByAction:  zeroActionMap(),
 		ByOwner:   zeroOwnerMap(),
 		BySource:  zeroSourceMap(),
+
+		ByFreshness:   zeroFreshnessMap(),
+		ByDrillResult: zeroDrillResultMap(),
 	}
 	for i := range entries {
 		e := &entries[i]
@@ -529,7 +724,37 @@ func (s *Store) Snapshot() ParityReport {
 				summary.OldestEvaluatedAt = e.EvaluatedAt
 			}
 		}
+
+		if note, ok := nsNotes[e.Namespace]; ok {
+			// Fresh slice: the stored entry's Notes backing array is
+			// shared with every other Snapshot().
+			e.Notes = append(append([]string(nil), e.Notes...), note)
+		}
+
+		if rec, ok := drills[e.Key()]; ok {
+			e.RestoreDrill = &rec
+			summary.ByDrillResult[rec.Result]++
+		}
+		if rec, ok := clones[e.Key()]; ok {
+			e.RestoreClone = &rec
+		}
+		if rec, ok := hooks[e.Key()]; ok {
+			e.BackupHooks = &rec
+		}
+
+		applyFreshness(e, generatedAt)
+		if e.Freshness != "" {
+			summary.ByFreshness[e.Freshness]++
+		}
+	}
+
+	for i := range orphans {
+		if age := generatedAt.Sub(orphans[i].OrphanedSince); age > 0 {
+			orphans[i].AgeSeconds = int64(age.Seconds())
+		}
 	}
+	summary.Orphans = len(orphans)
+	summary.IdentityCollisions = s.identities.Collisions()
 
 	return ParityReport{
 		GeneratedAt:       generatedAt,
@@ -538,9 +763,26 @@ func (s *Store) Snapshot() ParityReport {
 		DefaultRepoSecret: s.defaultRepoSecret,
 		Summary:           summary,
 		Entries:           entries,
+		Orphans:           orphans,
 	}
 }
 
+func zeroDrillResultMap() map[DrillResult]int {
+	out := make(map[DrillResult]int, 3)
+	for _, k := range AllDrillResults() {
+		out[k] = 0
+	}
+	return out
+}
+
+func zeroFreshnessMap() map[BackupFreshness]int {
+	out := make(map[BackupFreshness]int, 3)
+	for _, k := range AllBackupFreshness() {
+		out[k] = 0
+	}
+	return out
+}
+
 func zeroActionMap() map[ActionKind]int {
 	out := make(map[ActionKind]int, 10)
 	for _, k := range AllActionKinds() {
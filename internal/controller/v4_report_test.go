@@ -41,6 +41,7 @@ func TestActionKindValues(t *testing.T) {
 		ActionWriteGateMissing:   actionWriteGateMissingStr,
 
 		ActionSkippedNamespaceNotManaged: "skipped-namespace-not-managed",
+		ActionMoverFailing:               "mover-failing",
 	}
 	for k, s := range want {
 		if string(k) != s {
@@ -267,6 +268,41 @@ func TestStore_PreservesExplicitEvaluatedAt(t *testing.T) {
 	}
 }
 
+// Namespace notes land on every entry of their namespace at read time,
+// after the entry's own notes, and clear with the next SetNamespaceNotes.
+func TestStoreSnapshot_NamespaceNotes(t *testing.T) {
+	s := NewStore(testModeAudit, "bare-dst", testRepoSecretShare)
+	s.now = fixedTime
+	s.Set(ParityEntry{Namespace: testNSMyapp, PVC: "a", Notes: []string{"own"}})
+	s.Set(ParityEntry{Namespace: testNSMyapp, PVC: "b"})
+	s.Set(ParityEntry{Namespace: "other", PVC: "c"})
+	s.SetNamespaceNotes(map[string]string{testNSMyapp: "over quota"})
+
+	notes := map[string][]string{}
+	for _, e := range s.Snapshot().Entries {
+		notes[e.Key()] = e.Notes
+	}
+	if got := notes["myapp/a"]; len(got) != 2 || got[0] != "own" || got[1] != "over quota" {
+		t.Errorf("myapp/a notes = %v", got)
+	}
+	if got := notes["myapp/b"]; len(got) != 1 || got[0] != "over quota" {
+		t.Errorf("myapp/b notes = %v", got)
+	}
+	if got := notes["other/c"]; len(got) != 0 {
+		t.Errorf("other/c notes = %v", got)
+	}
+	if e, _ := s.Get(testNSMyapp, "a"); len(e.Notes) != 1 {
+		t.Errorf("stored entry mutated: %v", e.Notes)
+	}
+
+	s.SetNamespaceNotes(nil)
+	for _, e := range s.Snapshot().Entries {
+		if e.Key() == "myapp/b" && len(e.Notes) != 0 {
+			t.Errorf("note not cleared: %v", e.Notes)
+		}
+	}
+}
+
 func TestSnapshot_Isolation(t *testing.T) {
 	// Mutating the returned report MUST NOT affect the underlying store.
 	s := NewStore(testModeAudit, "bare-dst", testRepoSecretShare)
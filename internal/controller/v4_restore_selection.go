@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+	"github.com/mitchross/pvc-plumber/internal/v4/labels"
+	"github.com/mitchross/pvc-plumber/internal/v4/naming"
+)
+
+// Point-in-time restore preview. The pvc-plumber.io/restore-as-of and
+// restore-previous annotations are rendered into the PVC's RD as kopia
+// restoreAsOf / previous, and VolSync's mover resolves them to a
+// snapshot only when the restore actually runs. After a ransomware hit
+// that is too late to find out the as-of time was a day off, so /audit
+// resolves the same selection against the repository ahead of time and
+// names the snapshot the restore will use.
+
+// LineageLister lists one kopia lineage, oldest first — satisfied by
+// kopia.Client, kopia.ServerClient and the native reader.
+type LineageLister interface {
+	ListSnapshots(ctx context.Context, source kopia.SnapshotSource) ([]kopia.SnapshotInfo, error)
+}
+
+// restoreSelectionTimeout bounds one lineage listing. The preview is
+// informational; a slow repository must not hold a reconcile worker.
+const restoreSelectionTimeout = 30 * time.Second
+
+// RestoreSelectionStatus says whether the preview found a snapshot.
+type RestoreSelectionStatus string
+
+const (
+	// RestoreSelectionSelected: SnapshotID is what the restore will use.
+	RestoreSelectionSelected RestoreSelectionStatus = "selected"
+	// RestoreSelectionNoMatch: the lineage has no snapshot satisfying the
+	// selection — too early an as-of, or previous past the oldest. The
+	// RD's restore will fail.
+	RestoreSelectionNoMatch RestoreSelectionStatus = "no-match"
+	// RestoreSelectionUnresolved: no kopia backend is wired, so only the
+	// requested selection is shown.
+	RestoreSelectionUnresolved RestoreSelectionStatus = "unresolved"
+	// RestoreSelectionError: listing the lineage failed (Detail says why).
+	RestoreSelectionError RestoreSelectionStatus = "error"
+)
+
+// RestoreSelection is the /audit restore_selection object.
+type RestoreSelection struct {
+	AsOf     time.Time `json:"as_of,omitzero"`
+	Previous int32     `json:"previous,omitempty"`
+	// Source is the kopia lineage the RD restores from
+	// (username@hostname:/data).
+	Source string                 `json:"source"`
+	Status RestoreSelectionStatus `json:"status"`
+
+	SnapshotID   string    `json:"snapshot_id,omitempty"`
+	SnapshotTime time.Time `json:"snapshot_time,omitzero"`
+	// Eligible counts the complete snapshots at or before AsOf (all of
+	// them without AsOf) — the pool Previous steps back through.
+	Eligible int    `json:"eligible"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// restoreSelection previews the PVC's point-in-time restore against
+// the lineage its RD names (the v4 source of its backup identity —
+// never the legacy lineage, which the RD does not read).
+func (r *V4AuditReconciler) restoreSelection(ctx context.Context, namespace, pvc string, spec labels.Spec) *RestoreSelection {
+	src := kopia.V4Source(namespace, naming.IdentityFor(namespace, pvc, spec.BackupIdentity))
+	sel := &RestoreSelection{
+		AsOf:     spec.RestoreAsOf,
+		Previous: spec.RestorePrevious,
+		Source:   src.String(),
+	}
+	if r.Snapshots == nil {
+		sel.Status = RestoreSelectionUnresolved
+		sel.Detail = "no kopia backend configured; VolSync resolves the selection when the restore runs"
+		return sel
+	}
+	ctx, cancel := context.WithTimeout(ctx, restoreSelectionTimeout)
+	defer cancel()
+	snaps, err := r.Snapshots.ListSnapshots(ctx, src)
+	if err != nil {
+		sel.Status = RestoreSelectionError
+		sel.Detail = err.Error()
+		return sel
+	}
+	snap, eligible, ok := selectSnapshot(snaps, spec.RestoreAsOf, spec.RestorePrevious)
+	sel.Eligible = eligible
+	if !ok {
+		sel.Status = RestoreSelectionNoMatch
+		sel.Detail = fmt.Sprintf("%d eligible snapshot(s); the restore will fail until the selection is changed", eligible)
+		return sel
+	}
+	sel.Status = RestoreSelectionSelected
+	sel.SnapshotID = snap.ID
+	sel.SnapshotTime = snap.StartTime
+	return sel
+}
+
+// selectSnapshot applies VolSync's kopia selection: drop snapshots that
+// started after asOf (when set), order newest first, and step back
+// previous entries. Incomplete (checkpoint-only) snapshots are never
+// candidates — the mover's `kopia snapshot list` does not show them.
+// Returns the pick, how many snapshots were eligible, and whether one
+// was found.
+func selectSnapshot(snaps []kopia.SnapshotInfo, asOf time.Time, previous int32) (kopia.SnapshotInfo, int, bool) {
+	eligible := make([]kopia.SnapshotInfo, 0, len(snaps))
+	for _, s := range snaps {
+		if s.Incomplete() || (!asOf.IsZero() && s.StartTime.After(asOf)) {
+			continue
+		}
+		eligible = append(eligible, s)
+	}
+	sort.SliceStable(eligible, func(i, j int) bool { return eligible[i].StartTime.After(eligible[j].StartTime) })
+	if int(previous) >= len(eligible) {
+		return kopia.SnapshotInfo{}, len(eligible), false
+	}
+	return eligible[previous], len(eligible), true
+}
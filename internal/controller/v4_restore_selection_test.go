@@ -0,0 +1,175 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+	v4labels "github.com/mitchross/pvc-plumber/internal/v4/labels"
+	"github.com/mitchross/pvc-plumber/internal/v4/mode"
+)
+
+// stubLineages is a LineageLister serving fixed lineages by source.
+type stubLineages struct {
+	lineages map[string][]kopia.SnapshotInfo
+	err      error
+	sources  []string
+}
+
+func (s *stubLineages) ListSnapshots(_ context.Context, src kopia.SnapshotSource) ([]kopia.SnapshotInfo, error) {
+	s.sources = append(s.sources, src.String())
+	return s.lineages[src.String()], s.err
+}
+
+// hourlySnaps is a lineage of n snapshots an hour apart ending at last,
+// oldest first, with IDs s0..s(n-1).
+func hourlySnaps(last time.Time, n int) []kopia.SnapshotInfo {
+	out := make([]kopia.SnapshotInfo, 0, n)
+	for i := range n {
+		out = append(out, kopia.SnapshotInfo{
+			ID:        "s" + strconv.Itoa(i),
+			StartTime: last.Add(-time.Duration(n-1-i) * time.Hour),
+		})
+	}
+	return out
+}
+
+func TestSelectSnapshot(t *testing.T) {
+	last := fixedTime()
+	snaps := hourlySnaps(last, 4) // s0 (-3h) .. s3 (latest)
+	snaps = append(snaps, kopia.SnapshotInfo{ID: "partial", StartTime: last.Add(time.Minute), IncompleteReason: "checkpoint"})
+	cases := []struct {
+		name     string
+		asOf     time.Time
+		previous int32
+		wantID   string
+		eligible int
+	}{
+		{name: "latest complete", wantID: "s3", eligible: 4},
+		{name: "previous 1", previous: 1, wantID: "s2", eligible: 4},
+		{name: "as-of between snapshots", asOf: last.Add(-90 * time.Minute), wantID: "s1", eligible: 2},
+		{name: "as-of exactly on a snapshot", asOf: last.Add(-time.Hour), wantID: "s2", eligible: 3},
+		{name: "as-of then previous", asOf: last.Add(-time.Hour), previous: 2, wantID: "s0", eligible: 3},
+		{name: "previous past the oldest", previous: 4, eligible: 4},
+		{name: "as-of before the first backup", asOf: last.Add(-4 * time.Hour), eligible: 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, eligible, ok := selectSnapshot(snaps, tc.asOf, tc.previous)
+			if ok != (tc.wantID != "") || got.ID != tc.wantID || eligible != tc.eligible {
+				t.Errorf("got %q eligible=%d ok=%v, want %q eligible=%d", got.ID, eligible, ok, tc.wantID, tc.eligible)
+			}
+		})
+	}
+}
+
+func TestV4RestoreSelection_PreviewInAudit(t *testing.T) {
+	now := fixedTime()
+	asOf := now.Add(-90 * time.Minute)
+	anns := map[string]string{
+		v4labels.AnnotationRestoreAsOf:     asOf.Format(time.RFC3339),
+		v4labels.AnnotationRestorePrevious: "1",
+	}
+	v4Src := "data@" + testNSMyapp + ":/data"
+
+	t.Run("selected from the v4 lineage", func(t *testing.T) {
+		f := newV4Fixture(t, makePVC(testNSMyapp, "data", labelsEnabledManage(), anns))
+		stub := &stubLineages{lineages: map[string][]kopia.SnapshotInfo{v4Src: hourlySnaps(now, 4)}}
+		f.rec.Snapshots = stub
+		e := f.reconcile(testNSMyapp, "data")
+
+		sel := e.RestoreSelection
+		if sel == nil || sel.Status != RestoreSelectionSelected || sel.SnapshotID != "s0" || !sel.SnapshotTime.Equal(now.Add(-3*time.Hour)) {
+			t.Fatalf("selection: %+v", sel)
+		}
+		if sel.Source != v4Src || !sel.AsOf.Equal(asOf) || sel.Previous != 1 || sel.Eligible != 2 {
+			t.Errorf("selection: %+v", sel)
+		}
+		if len(stub.sources) != 1 || stub.sources[0] != v4Src {
+			t.Errorf("listed %v, want only %s", stub.sources, v4Src)
+		}
+	})
+
+	t.Run("identity override names the lineage", func(t *testing.T) {
+		overridden := map[string]string{v4labels.AnnotationBackupIdentity: "shared-data"}
+		for k, v := range anns {
+			overridden[k] = v
+		}
+		f := newV4Fixture(t, makePVC(testNSMyapp, "data", labelsEnabledManage(), overridden))
+		stub := &stubLineages{}
+		f.rec.Snapshots = stub
+		e := f.reconcile(testNSMyapp, "data")
+		if want := "shared-data@" + testNSMyapp + ":/data"; e.RestoreSelection.Source != want || stub.sources[0] != want {
+			t.Errorf("source: %q, listed %v, want %s", e.RestoreSelection.Source, stub.sources, want)
+		}
+		if e.RestoreSelection.Status != RestoreSelectionNoMatch {
+			t.Errorf("empty lineage: %+v", e.RestoreSelection)
+		}
+	})
+
+	t.Run("listing error", func(t *testing.T) {
+		f := newV4Fixture(t, makePVC(testNSMyapp, "data", labelsEnabledManage(), anns))
+		f.rec.Snapshots = &stubLineages{err: errors.New("repository unreachable")}
+		e := f.reconcile(testNSMyapp, "data")
+		if e.RestoreSelection.Status != RestoreSelectionError || e.RestoreSelection.Detail != "repository unreachable" {
+			t.Errorf("selection: %+v", e.RestoreSelection)
+		}
+	})
+
+	t.Run("no backend", func(t *testing.T) {
+		f := newV4Fixture(t, makePVC(testNSMyapp, "data", labelsEnabledManage(), anns))
+		e := f.reconcile(testNSMyapp, "data")
+		if e.RestoreSelection == nil || e.RestoreSelection.Status != RestoreSelectionUnresolved || e.RestoreSelection.SnapshotID != "" {
+			t.Errorf("selection: %+v", e.RestoreSelection)
+		}
+	})
+
+	t.Run("no annotations, no preview and no listing", func(t *testing.T) {
+		f := newV4Fixture(t, makePVC(testNSMyapp, "data", labelsEnabledManage(), nil))
+		stub := &stubLineages{}
+		f.rec.Snapshots = stub
+		if e := f.reconcile(testNSMyapp, "data"); e.RestoreSelection != nil || len(stub.sources) != 0 {
+			t.Errorf("selection %+v, listed %v", e.RestoreSelection, stub.sources)
+		}
+	})
+}
+
+// TestV4RestoreSelection_PermissiveUpdatesRD pins the write path: adding
+// the annotations to a PVC whose operator-owned RD restores the latest
+// snapshot updates the RD with the selection and a re-keyed trigger, and
+// the next reconcile sees the pair as matching.
+func TestV4RestoreSelection_PermissiveUpdatesRD(t *testing.T) {
+	anns := map[string]string{
+		v4labels.AnnotationRestoreAsOf:     "2026-05-22T03:00:00Z",
+		v4labels.AnnotationRestorePrevious: "1",
+	}
+	pvc := makePVC(testNSMyapp, "data", labelsEnabledManage(), anns)
+	rs := makeRS(testNSMyapp, "data", ManagedByPVCPlumberLabelValue, testRepoSecretShare, "data")
+	rd := makeRD(testNSMyapp, "data-dst", ManagedByPVCPlumberLabelValue, testRepoSecretShare)
+	f := newV4ModeFixture(t, mode.Permissive, pvc, rs, rd)
+
+	if e := f.reconcile(testNSMyapp, "data"); e.Action != ActionWouldUpdate {
+		t.Fatalf("Action: got %q, want %q", e.Action, ActionWouldUpdate)
+	}
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(rdGVK)
+	if err := f.fake.Get(context.Background(), types.NamespacedName{Namespace: testNSMyapp, Name: "data-dst"}, live); err != nil {
+		t.Fatal(err)
+	}
+	asOf, _, _ := unstructured.NestedString(live.Object, "spec", "kopia", "restoreAsOf")
+	prev, _, _ := unstructured.NestedInt64(live.Object, "spec", "kopia", "previous")
+	trigger, _, _ := unstructured.NestedString(live.Object, "spec", "trigger", "manual")
+	if asOf != "2026-05-22T03:00:00Z" || prev != 1 || trigger != "restore-as-of-20260522T030000Z-previous-1" {
+		t.Errorf("live RD: restoreAsOf=%q previous=%d trigger=%q", asOf, prev, trigger)
+	}
+
+	if e := f.reconcile(testNSMyapp, "data"); e.Action != ActionAlreadyMatches {
+		t.Errorf("second reconcile: got %q, want %q (notes %v)", e.Action, ActionAlreadyMatches, e.Notes)
+	}
+}
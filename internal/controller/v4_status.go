@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// MoverHealth is the derived health of a VolSync RS/RD mover, read from
+// the object's .status. It answers "are backups actually running?",
+// which the parity verdict (shape vs. expected) cannot.
+type MoverHealth string
+
+const (
+	// MoverHealthy: the latest completed mover run succeeded.
+	MoverHealthy MoverHealth = "healthy"
+	// MoverSyncing: a run is in progress and the previous one (if any)
+	// did not fail.
+	MoverSyncing MoverHealth = "syncing"
+	// MoverFailing: the latest completed mover run failed, or VolSync
+	// reports the Synchronizing condition False with reason Error. Wins
+	// over syncing so a retry loop never hides the failure.
+	MoverFailing MoverHealth = "failing"
+	// MoverNeverSynced: no run has ever completed. Normal for a brand-new
+	// RS and for an RD that has never been triggered for a restore.
+	MoverNeverSynced MoverHealth = "never-synced"
+)
+
+// VolSync status vocabulary (volsync.backube/v1alpha1).
+const (
+	volsyncCondSynchronizing = "Synchronizing"
+	volsyncReasonError       = "Error"
+	volsyncMoverFailed       = "Failed"
+)
+
+// maxMoverLogBytes caps latestMoverStatus.logs copied into /audit. VolSync
+// already trims mover logs, but the report is served whole and an entry
+// per PVC adds up.
+const maxMoverLogBytes = 2048
+
+// StatusCondition is a VolSync status condition as surfaced in /audit.
+type StatusCondition struct {
+	Type               string    `json:"type"`
+	Status             string    `json:"status"`
+	Reason             string    `json:"reason,omitempty"`
+	Message            string    `json:"message,omitempty"`
+	LastTransitionTime time.Time `json:"last_transition_time,omitzero"`
+}
+
+// MoverStatus is the status block shared by ReplicationSource and
+// ReplicationDestination, plus the derived Health.
+type MoverStatus struct {
+	LastSyncTime      time.Time         `json:"last_sync_time,omitzero"`
+	LastSyncStartTime time.Time         `json:"last_sync_start_time,omitzero"`
+	LastSyncDuration  string            `json:"last_sync_duration,omitempty"`
+	NextSyncTime      time.Time         `json:"next_sync_time,omitzero"`
+	MoverResult       string            `json:"mover_result,omitempty"`
+	MoverLogs         string            `json:"mover_logs,omitempty"`
+	Conditions        []StatusCondition `json:"conditions,omitempty"`
+	Health            MoverHealth       `json:"health"`
+}
+
+// readMoverStatus extracts the VolSync status block from a live RS/RD.
+// Missing or malformed fields are left zero; nothing here can fail the
+// reconcile.
+func readMoverStatus(u *unstructured.Unstructured) MoverStatus {
+	var st MoverStatus
+	st.LastSyncTime = nestedRFC3339(u, "status", "lastSyncTime")
+	st.LastSyncStartTime = nestedRFC3339(u, "status", "lastSyncStartTime")
+	st.NextSyncTime = nestedRFC3339(u, "status", "nextSyncTime")
+	st.LastSyncDuration, _, _ = unstructured.NestedString(u.Object, "status", "lastSyncDuration")
+	st.MoverResult, _, _ = unstructured.NestedString(u.Object, "status", "latestMoverStatus", "result")
+	logs, _, _ := unstructured.NestedString(u.Object, "status", "latestMoverStatus", "logs")
+	if len(logs) > maxMoverLogBytes {
+		logs = logs[len(logs)-maxMoverLogBytes:]
+	}
+	st.MoverLogs = logs
+
+	conds, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	for _, raw := range conds {
+		m, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		c := StatusCondition{}
+		c.Type, _ = m["type"].(string)
+		c.Status, _ = m["status"].(string)
+		c.Reason, _ = m["reason"].(string)
+		c.Message, _ = m["message"].(string)
+		if ts, ok := m["lastTransitionTime"].(string); ok {
+			c.LastTransitionTime, _ = time.Parse(time.RFC3339, ts)
+		}
+		if c.Type != "" {
+			st.Conditions = append(st.Conditions, c)
+		}
+	}
+	st.Health = classifyMoverHealth(st)
+	return st
+}
+
+// classifyMoverHealth derives MoverHealth. Precedence: failing → syncing
+// → healthy → never-synced.
+func classifyMoverHealth(st MoverStatus) MoverHealth {
+	syncing := false
+	for _, c := range st.Conditions {
+		if c.Type != volsyncCondSynchronizing {
+			continue
+		}
+		if c.Status == "False" && c.Reason == volsyncReasonError {
+			return MoverFailing
+		}
+		syncing = c.Status == "True"
+	}
+	if st.MoverResult == volsyncMoverFailed {
+		return MoverFailing
+	}
+	switch {
+	case syncing:
+		return MoverSyncing
+	case !st.LastSyncTime.IsZero():
+		return MoverHealthy
+	default:
+		return MoverNeverSynced
+	}
+}
+
+// nestedRFC3339 reads an RFC3339 timestamp string field. Zero when
+// absent or unparseable.
+func nestedRFC3339(u *unstructured.Unstructured, fields ...string) time.Time {
+	raw, found, err := unstructured.NestedString(u.Object, fields...)
+	if err != nil || !found || raw == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// moverStatusKey summarises the status fields /audit derives verdicts
+// from, for the child watch predicate. Condition messages and mover logs
+// are deliberately excluded: they change without moving any verdict.
+func moverStatusKey(u *unstructured.Unstructured) string {
+	st := readMoverStatus(u)
+	return st.LastSyncTime.Format(time.RFC3339) + "|" + st.MoverResult + "|" + string(st.Health)
+}
+
+// moverFailure renders a failing mover for planner.CurrentState: "" when
+// the object is absent or not failing, otherwise a one-line reason (the
+// Synchronizing condition's message when VolSync gave one).
+func moverFailure(st *MoverStatus) string {
+	if st == nil || st.Health != MoverFailing {
+		return ""
+	}
+	for _, c := range st.Conditions {
+		if c.Type == volsyncCondSynchronizing && c.Status == "False" && c.Message != "" {
+			return c.Message
+		}
+	}
+	if st.MoverResult != "" {
+		return "latest mover result: " + st.MoverResult
+	}
+	return "mover failing"
+}
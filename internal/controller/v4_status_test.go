@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	v4labels "github.com/mitchross/pvc-plumber/internal/v4/labels"
+)
+
+// withMoverStatus stamps a VolSync-shaped .status onto an RS/RD. A zero
+// lastSync leaves status.lastSyncTime unset; an empty result leaves
+// latestMoverStatus unset; syncCond is the Synchronizing condition as
+// "status/reason" ("" for none).
+func withMoverStatus(u *unstructured.Unstructured, lastSync time.Time, result, syncCond string) *unstructured.Unstructured {
+	if !lastSync.IsZero() {
+		_ = unstructured.SetNestedField(u.Object, lastSync.UTC().Format(time.RFC3339), "status", "lastSyncTime")
+	}
+	if result != "" {
+		_ = unstructured.SetNestedField(u.Object, result, "status", "latestMoverStatus", "result")
+		_ = unstructured.SetNestedField(u.Object, "mover log tail", "status", "latestMoverStatus", "logs")
+	}
+	if syncCond != "" {
+		status, reason, _ := strings.Cut(syncCond, "/")
+		_ = unstructured.SetNestedSlice(u.Object, []any{map[string]any{
+			"type":               volsyncCondSynchronizing,
+			"status":             status,
+			"reason":             reason,
+			"message":            "unable to open repository",
+			"lastTransitionTime": fixedTime().Add(-time.Minute).Format(time.RFC3339),
+		}}, "status", "conditions")
+	}
+	return u
+}
+
+func TestReadMoverStatus_Fields(t *testing.T) {
+	rs := withMoverStatus(makeRS(testNSMyapp, "data", "", testRepoSecretShare, "data"),
+		fixedTime().Add(-time.Hour), "Successful", "False/WaitingForSchedule")
+	_ = unstructured.SetNestedField(rs.Object, "2m31s", "status", "lastSyncDuration")
+	_ = unstructured.SetNestedField(rs.Object, fixedTime().Add(-time.Hour-3*time.Minute).Format(time.RFC3339), "status", "lastSyncStartTime")
+	_ = unstructured.SetNestedField(rs.Object, fixedTime().Add(23*time.Hour).Format(time.RFC3339), "status", "nextSyncTime")
+
+	st := readMoverStatus(rs)
+	if !st.LastSyncTime.Equal(fixedTime().Add(-time.Hour)) || st.LastSyncDuration != "2m31s" ||
+		st.LastSyncStartTime.IsZero() || !st.NextSyncTime.Equal(fixedTime().Add(23*time.Hour)) {
+		t.Errorf("timings: %+v", st)
+	}
+	if st.MoverResult != "Successful" || st.MoverLogs != "mover log tail" {
+		t.Errorf("mover: %+v", st)
+	}
+	if len(st.Conditions) != 1 || st.Conditions[0].Reason != "WaitingForSchedule" || st.Conditions[0].LastTransitionTime.IsZero() {
+		t.Errorf("conditions: %+v", st.Conditions)
+	}
+	if st.Health != MoverHealthy {
+		t.Errorf("health: got %q, want %q", st.Health, MoverHealthy)
+	}
+}
+
+func TestReadMoverStatus_LogsTruncatedToTail(t *testing.T) {
+	rs := makeRS(testNSMyapp, "data", "", testRepoSecretShare, "data")
+	long := strings.Repeat("x", maxMoverLogBytes) + "END"
+	_ = unstructured.SetNestedField(rs.Object, long, "status", "latestMoverStatus", "logs")
+	st := readMoverStatus(rs)
+	if len(st.MoverLogs) != maxMoverLogBytes || !strings.HasSuffix(st.MoverLogs, "END") {
+		t.Errorf("logs: len %d", len(st.MoverLogs))
+	}
+}
+
+func TestClassifyMoverHealth(t *testing.T) {
+	last := fixedTime().Add(-time.Hour)
+	cases := []struct {
+		name     string
+		lastSync time.Time
+		result   string
+		cond     string
+		want     MoverHealth
+	}{
+		{"nothing", time.Time{}, "", "", MoverNeverSynced},
+		{"first run in progress", time.Time{}, "", "True/SyncInProgress", MoverSyncing},
+		{"completed", last, "Successful", "False/WaitingForSchedule", MoverHealthy},
+		{"rerun after success", last, "Successful", "True/SyncInProgress", MoverSyncing},
+		{"latest failed", last, "Failed", "False/WaitingForSchedule", MoverFailing},
+		{"retry after failure", last, "Failed", "True/SyncInProgress", MoverFailing},
+		{"condition error", time.Time{}, "", "False/Error", MoverFailing},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			u := withMoverStatus(makeRS(testNSMyapp, "data", "", testRepoSecretShare, "data"), tc.lastSync, tc.result, tc.cond)
+			if got := readMoverStatus(u).Health; got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestV4Reconcile_MatchingButMoverFailing(t *testing.T) {
+	pvc := makePVC(testNSOpenWebUI, testPVCStorageName,
+		map[string]string{v4labels.LabelEnabled: labelTrue, v4labels.LabelTier: backupDaily}, nil)
+	rs := withMoverStatus(
+		makeRS(testNSOpenWebUI, testPVCStorageName, ManagedByArgoCDLabelValue, testRepoSecretShare, testPVCStorageName),
+		fixedTime().Add(-30*time.Hour), "Failed", "False/Error")
+	rd := makeRD(testNSOpenWebUI, testPVCStorageName+"-dst", ManagedByArgoCDLabelValue, testRepoSecretShare)
+
+	f := newV4Fixture(t, pvc, rs, rd)
+	entry := f.reconcile(testNSOpenWebUI, testPVCStorageName)
+
+	if entry.Action != ActionMoverFailing {
+		t.Fatalf("Action: got %q, want %q", entry.Action, ActionMoverFailing)
+	}
+	if entry.Current.RSStatus == nil || entry.Current.RSStatus.Health != MoverFailing ||
+		entry.Current.RSStatus.MoverResult != "Failed" {
+		t.Errorf("RSStatus: %+v", entry.Current.RSStatus)
+	}
+	if entry.Current.RDStatus == nil || entry.Current.RDStatus.Health != MoverNeverSynced {
+		t.Errorf("RDStatus: %+v", entry.Current.RDStatus)
+	}
+	found := false
+	for _, n := range entry.Notes {
+		found = found || strings.Contains(n, "unable to open repository")
+	}
+	if !found {
+		t.Errorf("notes must carry the condition message: %v", entry.Notes)
+	}
+	if got := f.store.Snapshot().Summary.ByAction[ActionMoverFailing]; got != 1 {
+		t.Errorf("summary.by_action[mover-failing]: got %d, want 1", got)
+	}
+	f.assertNoWrites()
+}
+
+func TestV4Reconcile_NoChildrenNoStatus(t *testing.T) {
+	f := newV4Fixture(t, makePVC(testNSMyapp, "data", labelsEnabledManage(), nil))
+	entry := f.reconcile(testNSMyapp, "data")
+	if entry.Current.RSStatus != nil || entry.Current.RDStatus != nil {
+		t.Errorf("absent children must carry no status: %+v", entry.Current)
+	}
+}
+
+func TestChildEventPredicate_MoverHealthChangePasses(t *testing.T) {
+	p := childEventPredicate()
+	oldRS := withMoverStatus(makeRS(testNSMyapp, "data", "", testRepoSecretShare, "data"),
+		fixedTime().Add(-time.Hour), "Successful", "False/WaitingForSchedule")
+	newRS := withMoverStatus(oldRS.DeepCopy(), time.Time{}, "Failed", "False/Error")
+	if !p.Update(event.UpdateEvent{ObjectOld: oldRS, ObjectNew: newRS}) {
+		t.Error("healthy → failing must re-enqueue")
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"testing"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -215,6 +217,146 @@ func TestV4MapChildToPVC_SkipsSystemNamespace(t *testing.T) {
 	}
 }
 
+// =============================================================================
+// Namespace watch — managed-namespace gate flips re-evaluate every PVC
+// =============================================================================
+
+func gateNamespace(name string, managed string, anns map[string]string) *corev1.Namespace {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: anns}}
+	if managed != "" {
+		ns.Labels = map[string]string{v4labels.NamespaceManagedLabel: managed}
+	}
+	return ns
+}
+
+func TestNamespaceEventPredicate(t *testing.T) {
+	p := namespaceEventPredicate()
+	open := gateNamespace(testNSMyapp, labelTrue, nil)
+	closed := gateNamespace(testNSMyapp, "false", nil)
+
+	if p.Create(event.CreateEvent{Object: open}) {
+		t.Error("CreateFunc: namespace create must be dropped (PVC informer covers startup)")
+	}
+	if p.Delete(event.DeleteEvent{Object: open}) {
+		t.Error("DeleteFunc: namespace delete must be dropped (PVC deletes follow)")
+	}
+	if p.Generic(event.GenericEvent{Object: open}) {
+		t.Error("GenericFunc: must be dropped")
+	}
+
+	cases := []struct {
+		name     string
+		old, new *corev1.Namespace
+		want     bool
+	}{
+		{"open → closed", open, closed, true},
+		{"closed → open", closed, open, true},
+		{"label removed", open, gateNamespace(testNSMyapp, "", nil), true},
+		{"future pvc-plumber annotation", open, gateNamespace(testNSMyapp, labelTrue, map[string]string{"pvc-plumber.io/default-tier": "hourly"}), true},
+		{"foreign annotation only", open, gateNamespace(testNSMyapp, labelTrue, map[string]string{"argocd.argoproj.io/tracking-id": "x"}), false},
+		{"no change", open, gateNamespace(testNSMyapp, labelTrue, nil), false},
+	}
+	for _, tc := range cases {
+		if got := p.Update(event.UpdateEvent{ObjectOld: tc.old, ObjectNew: tc.new}); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestV4MapNamespaceToPVCs_EnqueuesEveryPVCInNamespace(t *testing.T) {
+	f := newV4Fixture(t,
+		makePVC(testNSMyapp, "a", nil, nil),
+		makePVC(testNSMyapp, "b", labelsEnabledManage(), nil),
+		makePVC("other", "c", nil, nil),
+	)
+	reqs := f.rec.mapNamespaceToPVCs(context.Background(), gateNamespace(testNSMyapp, labelTrue, nil))
+	got := map[string]bool{}
+	for _, r := range reqs {
+		if r.Namespace != testNSMyapp {
+			t.Errorf("foreign namespace enqueued: %s", r.NamespacedName)
+		}
+		got[r.Name] = true
+	}
+	if len(reqs) != 2 || !got["a"] || !got["b"] {
+		t.Errorf("requests: %+v, want myapp/a + myapp/b", reqs)
+	}
+
+	if reqs := f.rec.mapNamespaceToPVCs(context.Background(), gateNamespace("kube-system", labelTrue, nil)); len(reqs) != 0 {
+		t.Errorf("system namespace must not enqueue: %+v", reqs)
+	}
+}
+
+// The open→closed transition: a namespace that was managed (the operator
+// created the PVC's RS/RD) loses its managed-namespace label while the
+// RS has drifted and a repair is pending. The namespace event must
+// re-enqueue the PVC, and the re-evaluation must land on the gate verdict
+// with zero writes — the pending update never reaches the apiserver.
+// Re-opening the namespace then lets the repair through.
+func TestV4NamespaceGate_OpenToClosedSuppressesPendingWrites(t *testing.T) {
+	ctx := context.Background()
+	pvc := makePVC(testNSMyapp, "gate", labelsEnabledManageTier(backupDaily), nil)
+	f := newV4ModeFixture(t, mode.Permissive, gateNamespace(testNSMyapp, labelTrue, nil), pvc)
+
+	if entry := f.reconcile(testNSMyapp, "gate"); entry.Action != ActionWouldCreate {
+		t.Fatalf("initial: got %q, want %q", entry.Action, ActionWouldCreate)
+	}
+	f.assertDidWriteByVerb(t, 2, 0, 0)
+
+	// Drift the live RS so the next evaluation would plan an update.
+	rs := &unstructured.Unstructured{}
+	rs.SetGroupVersionKind(rsGVK)
+	if err := f.fake.Get(ctx, types.NamespacedName{Namespace: testNSMyapp, Name: "gate"}, rs); err != nil {
+		t.Fatal(err)
+	}
+	_ = unstructured.SetNestedField(rs.Object, "drifted-repo", "spec", "kopia", "repository")
+	if err := f.fake.Update(ctx, rs); err != nil {
+		t.Fatal(err)
+	}
+
+	// Close the gate.
+	oldNS := &corev1.Namespace{}
+	if err := f.fake.Get(ctx, types.NamespacedName{Name: testNSMyapp}, oldNS); err != nil {
+		t.Fatal(err)
+	}
+	newNS := oldNS.DeepCopy()
+	newNS.Labels[v4labels.NamespaceManagedLabel] = "false"
+	if err := f.fake.Update(ctx, newNS); err != nil {
+		t.Fatal(err)
+	}
+	if !namespaceEventPredicate().Update(event.UpdateEvent{ObjectOld: oldNS, ObjectNew: newNS}) {
+		t.Fatal("gate flip did not pass the namespace predicate")
+	}
+	reqs := f.rec.mapNamespaceToPVCs(ctx, newNS)
+	if len(reqs) != 1 || reqs[0].Name != "gate" {
+		t.Fatalf("gate flip enqueued %+v, want myapp/gate", reqs)
+	}
+
+	entry := f.reconcile(reqs[0].Namespace, reqs[0].Name)
+	if entry.Action != ActionSkippedNamespaceNotManaged {
+		t.Errorf("closed gate: got %q, want %q", entry.Action, ActionSkippedNamespaceNotManaged)
+	}
+	if len(entry.PlannedOps) != 0 {
+		t.Errorf("closed gate planned %d ops, want 0", len(entry.PlannedOps))
+	}
+	f.assertDidWriteByVerb(t, 2, 0, 0)
+	if got := f.liveRepo(rsGVK, testNSMyapp, "gate"); got != "drifted-repo" {
+		t.Errorf("closed gate repaired the RS anyway (repo=%q)", got)
+	}
+
+	// Re-open: the same path now lets the repair through.
+	reopened := newNS.DeepCopy()
+	reopened.Labels[v4labels.NamespaceManagedLabel] = labelTrue
+	if err := f.fake.Update(ctx, reopened); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range f.rec.mapNamespaceToPVCs(ctx, reopened) {
+		f.reconcile(r.Namespace, r.Name)
+	}
+	if got := f.liveRepo(rsGVK, testNSMyapp, "gate"); got != testRepoSecretShare {
+		t.Errorf("re-opened gate: repo=%q, want %q", got, testRepoSecretShare)
+	}
+}
+
 // =============================================================================
 // Partial operator-owned state: recreate ONLY the missing child
 // =============================================================================
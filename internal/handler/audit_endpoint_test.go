@@ -177,7 +177,7 @@ func TestAuditHandler_GET_BodyIsValidJSON(t *testing.T) {
 	}
 
 	// Top-level keys we expect every response to carry.
-	for _, key := range []string{"generated_at", "operator_mode", "naming_strategy", "default_repo_secret", "summary", "entries"} {
+	for _, key := range []string{"generated_at", "operator_mode", "naming_strategy", "default_repo_secret", "summary", "entries", "orphans"} {
 		if _, ok := generic[key]; !ok {
 			t.Errorf("missing top-level key %q in response: %s", key, rr.Body.String())
 		}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/mitchross/pvc-plumber/internal/controller"
+)
+
+// BackupPathPrefix is the route BackupHandler is mounted on.
+const BackupPathPrefix = "/backup/"
+
+// BackupTriggerer is the surface BackupHandler needs. The production
+// *controller.OnDemandBackup satisfies it; tests use a fake.
+type BackupTriggerer interface {
+	Trigger(ctx context.Context, namespace, pvc string) (controller.BackupResult, error)
+}
+
+// BackupHandler serves POST /backup/{namespace}/{pvc}: a one-shot
+// backup of the PVC through its operator-owned RS
+// (controller.OnDemandBackup). The request blocks until the backup
+// finishes or times out, so the server's write timeout must cover the
+// backup timeout.
+//
+// Unlike every other route on the v4 server this one writes, so it is
+// authenticated: the caller sends "Authorization: Bearer <token>", and
+// the token must equal the contents of tokenFile. The file is read per
+// request, so rotating the Secret it is mounted from takes effect
+// without a restart; an empty or unreadable file refuses every request.
+//
+// Status codes:
+//
+//	200  succeeded (body: controller.BackupResult)
+//	400  malformed namespace or PVC name
+//	401  missing or wrong token
+//	404  the PVC has no ReplicationSource
+//	405  not POST
+//	409  refused before the trigger was switched (audit mode, not
+//	     operator-owned, another backup in flight), or superseded
+//	502  the mover reported a failed sync
+//	503  the token file is missing or empty
+//	504  the sync did not complete within the backup timeout
+//	500  anything else (apiserver errors)
+//
+// Every result after the switch — 200, 409 superseded, 502, 504 —
+// carries the BackupResult body, including whether the RS schedule was
+// restored.
+type BackupHandler struct {
+	backup    BackupTriggerer
+	tokenFile string
+	logger    *slog.Logger
+}
+
+// NewBackupHandler constructs a BackupHandler. backup must be non-nil
+// and tokenFile non-empty; logger may be nil.
+func NewBackupHandler(backup BackupTriggerer, tokenFile string, logger *slog.Logger) *BackupHandler {
+	return &BackupHandler{backup: backup, tokenFile: tokenFile, logger: logger}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *BackupHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if code, msg := h.authenticate(r); code != 0 {
+		if code == http.StatusUnauthorized {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="pvc-plumber"`)
+		}
+		http.Error(w, msg, code)
+		return
+	}
+	namespace, pvc, err := parseBackupPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.backup.Trigger(r.Context(), namespace, pvc)
+	if err != nil {
+		code := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, controller.ErrBackupNotFound):
+			code = http.StatusNotFound
+		case errors.Is(err, controller.ErrBackupRefused):
+			code = http.StatusConflict
+		}
+		if h.logger != nil {
+			h.logger.Warn("on-demand backup not started", "namespace", namespace, "pvc", pvc, "error", err)
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	code := http.StatusOK
+	switch res.Status {
+	case controller.BackupFailed:
+		code = http.StatusBadGateway
+	case controller.BackupTimedOut:
+		code = http.StatusGatewayTimeout
+	case controller.BackupSuperseded:
+		code = http.StatusConflict
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(res); err != nil && h.logger != nil {
+		h.logger.Warn("backup endpoint encode failed", "error", err)
+	}
+}
+
+// authenticate returns 0 when r carries the configured token, else the
+// status code and message to refuse it with.
+func (h *BackupHandler) authenticate(r *http.Request) (int, string) {
+	raw, err := os.ReadFile(h.tokenFile)
+	want := strings.TrimSpace(string(raw))
+	if err != nil || want == "" {
+		if h.logger != nil {
+			h.logger.Error("backup API token unavailable; refusing request", "file", h.tokenFile, "error", err)
+		}
+		return http.StatusServiceUnavailable, "backup API token not configured"
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(got)), []byte(want)) != 1 {
+		return http.StatusUnauthorized, "unauthorized"
+	}
+	return 0, ""
+}
+
+// parseBackupPath splits /backup/{namespace}/{pvc}. Both must be valid
+// object names, which also keeps a stray "/" out of either.
+func parseBackupPath(path string) (namespace, pvc string, err error) {
+	rest := strings.TrimPrefix(path, BackupPathPrefix)
+	namespace, pvc, found := strings.Cut(rest, "/")
+	if !found || namespace == "" || pvc == "" {
+		return "", "", fmt.Errorf("path must be %s{namespace}/{pvc}", BackupPathPrefix)
+	}
+	if msgs := validation.IsDNS1123Label(namespace); len(msgs) > 0 {
+		return "", "", fmt.Errorf("invalid namespace %q: %s", namespace, strings.Join(msgs, "; "))
+	}
+	if msgs := validation.IsDNS1123Subdomain(pvc); len(msgs) > 0 {
+		return "", "", fmt.Errorf("invalid pvc %q: %s", pvc, strings.Join(msgs, "; "))
+	}
+	return namespace, pvc, nil
+}
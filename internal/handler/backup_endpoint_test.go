@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mitchross/pvc-plumber/internal/controller"
+)
+
+// fakeBackup returns a fixed result or error and records its target.
+type fakeBackup struct {
+	res    controller.BackupResult
+	err    error
+	called string
+}
+
+func (f *fakeBackup) Trigger(_ context.Context, namespace, pvc string) (controller.BackupResult, error) {
+	f.called = namespace + "/" + pvc
+	return f.res, f.err
+}
+
+func writeToken(t *testing.T, token string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte(token), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func backupRequest(method, path, token string) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func TestBackupHandler_Succeeded(t *testing.T) {
+	fb := &fakeBackup{res: controller.BackupResult{Namespace: "myapp", PVC: "data", Status: controller.BackupSucceeded, ScheduleRestored: true}}
+	h := NewBackupHandler(fb, writeToken(t, "s3cret\n"), nil)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, backupRequest(http.MethodPost, "/backup/myapp/data", "s3cret"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want 200 (%s)", rec.Code, rec.Body.String())
+	}
+	if fb.called != "myapp/data" {
+		t.Errorf("triggered %q, want myapp/data", fb.called)
+	}
+	var got controller.BackupResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Status != controller.BackupSucceeded || !got.ScheduleRestored {
+		t.Errorf("body = %+v", got)
+	}
+}
+
+// Nothing is triggered unless the request is a well-formed, authenticated
+// POST.
+func TestBackupHandler_Rejects(t *testing.T) {
+	cases := []struct {
+		name   string
+		token  string // file contents
+		method string
+		path   string
+		bearer string
+		want   int
+	}{
+		{name: "GET", token: "s3cret", method: http.MethodGet, path: "/backup/myapp/data", bearer: "s3cret", want: http.StatusMethodNotAllowed},
+		{name: "no token", token: "s3cret", method: http.MethodPost, path: "/backup/myapp/data", want: http.StatusUnauthorized},
+		{name: "wrong token", token: "s3cret", method: http.MethodPost, path: "/backup/myapp/data", bearer: "guess", want: http.StatusUnauthorized},
+		{name: "empty token file", token: " \n", method: http.MethodPost, path: "/backup/myapp/data", bearer: "", want: http.StatusServiceUnavailable},
+		{name: "missing pvc", token: "s3cret", method: http.MethodPost, path: "/backup/myapp", bearer: "s3cret", want: http.StatusBadRequest},
+		{name: "extra segment", token: "s3cret", method: http.MethodPost, path: "/backup/myapp/data/x", bearer: "s3cret", want: http.StatusBadRequest},
+		{name: "bad namespace", token: "s3cret", method: http.MethodPost, path: "/backup/My_App/data", bearer: "s3cret", want: http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fb := &fakeBackup{}
+			h := NewBackupHandler(fb, writeToken(t, tc.token), nil)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, backupRequest(tc.method, tc.path, tc.bearer))
+			if rec.Code != tc.want {
+				t.Fatalf("status: got %d, want %d (%s)", rec.Code, tc.want, rec.Body.String())
+			}
+			if fb.called != "" {
+				t.Errorf("backup triggered for a rejected request (%s)", fb.called)
+			}
+		})
+	}
+}
+
+func TestBackupHandler_StatusMapping(t *testing.T) {
+	cases := []struct {
+		name string
+		res  controller.BackupResult
+		err  error
+		want int
+	}{
+		{name: "not found", err: fmt.Errorf("%w myapp/data", controller.ErrBackupNotFound), want: http.StatusNotFound},
+		{name: "refused", err: fmt.Errorf("%w: not-owned", controller.ErrBackupRefused), want: http.StatusConflict},
+		{name: "apiserver", err: fmt.Errorf("get RS: boom"), want: http.StatusInternalServerError},
+		{name: "failed", res: controller.BackupResult{Status: controller.BackupFailed}, want: http.StatusBadGateway},
+		{name: "timeout", res: controller.BackupResult{Status: controller.BackupTimedOut}, want: http.StatusGatewayTimeout},
+		{name: "superseded", res: controller.BackupResult{Status: controller.BackupSuperseded}, want: http.StatusConflict},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewBackupHandler(&fakeBackup{res: tc.res, err: tc.err}, writeToken(t, "s3cret"), nil)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, backupRequest(http.MethodPost, "/backup/myapp/data", "s3cret"))
+			if rec.Code != tc.want {
+				t.Fatalf("status: got %d, want %d (%s)", rec.Code, tc.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestBackupHandler_UnreadableTokenFile(t *testing.T) {
+	h := NewBackupHandler(&fakeBackup{}, filepath.Join(t.TempDir(), "absent"), nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, backupRequest(http.MethodPost, "/backup/myapp/data", "anything"))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status: got %d, want 503", rec.Code)
+	}
+}
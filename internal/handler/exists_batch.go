@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+)
+
+// Limits for POST /exists:batch. The item cap keeps one request from
+// monopolising the backend; the body cap is generous for it (an item is
+// well under 1 KiB) and stops a runaway client before decoding.
+const (
+	maxBatchItems     = 1000
+	maxBatchBodyBytes = 1 << 20
+)
+
+// BatchChecker is an optional interface a backend can implement to
+// answer many queries at once more cheaply than one call each.
+// cache.CachedClient implements it with a single repository scan.
+type BatchChecker interface {
+	CheckBackupExistsBatch(ctx context.Context, qs []backend.Query) []backend.CheckResult
+}
+
+// BatchItem is one entry of a batch request: a namespace plus a PVC, an
+// identity override, or both. With both, the PVC labels the result and
+// the identity decides the lookup, exactly like /exists?identity=.
+type BatchItem struct {
+	Namespace string `json:"namespace"`
+	PVC       string `json:"pvc,omitempty"`
+	Identity  string `json:"identity,omitempty"`
+}
+
+// BatchRequest is the POST /exists:batch body.
+type BatchRequest struct {
+	Items []BatchItem `json:"items"`
+}
+
+// BatchResponse is the POST /exists:batch answer. Results are in request
+// order. Authoritative is true only when every result is: a caller
+// gating on the batch must treat false as "do not trust the fresh
+// answers" and inspect the per-item results.
+type BatchResponse struct {
+	Authoritative bool                  `json:"authoritative"`
+	Count         int                   `json:"count"`
+	Results       []backend.CheckResult `json:"results"`
+	Error         string                `json:"error,omitempty"`
+}
+
+// HandleExistsBatch serves POST /exists:batch. A malformed body or item
+// is 400 and nothing is checked. Otherwise the answer is 200 even when
+// some items are not authoritative — the per-item results and the
+// aggregate flag carry that, so one flaky item does not hide the rest.
+func (h *Handler) HandleExistsBatch(w http.ResponseWriter, r *http.Request) {
+	h.requestsTotal.Add(1)
+	if r.Method != http.MethodPost {
+		h.requestsErrors.Add(1)
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	qs, err := decodeBatch(http.MaxBytesReader(w, r.Body, maxBatchBodyBytes))
+	if err != nil {
+		h.requestsErrors.Add(1)
+		h.logger.Warn("invalid batch request", "error", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(BatchResponse{Error: err.Error()})
+		return
+	}
+
+	ctx := r.Context()
+	if h.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.requestTimeout)
+		defer cancel()
+	}
+
+	var results []backend.CheckResult
+	if bc, ok := h.backend.(BatchChecker); ok {
+		results = bc.CheckBackupExistsBatch(ctx, qs)
+	} else {
+		results = make([]backend.CheckResult, len(qs))
+		for i, q := range qs {
+			results[i] = h.backend.CheckBackupExists(ctx, q)
+		}
+	}
+
+	resp := BatchResponse{Authoritative: true, Count: len(results), Results: results}
+	failed := 0
+	for _, result := range results {
+		h.recordBackupCheck(result)
+		if result.Error != "" || !result.Authoritative || result.Decision == backend.DecisionUnknown {
+			resp.Authoritative = false
+			failed++
+		}
+	}
+	if failed > 0 {
+		h.requestsErrors.Add(1)
+	}
+	h.logger.Info("batch backup check complete", "items", len(results), "non_authoritative", failed)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// decodeBatch parses and validates a batch body into queries.
+func decodeBatch(body io.Reader) ([]backend.Query, error) {
+	var req BatchRequest
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, fmt.Errorf("request body exceeds %d bytes", maxBatchBodyBytes)
+		}
+		return nil, fmt.Errorf("invalid JSON body: %w", err)
+	}
+	if len(req.Items) == 0 {
+		return nil, errors.New("items must not be empty")
+	}
+	if len(req.Items) > maxBatchItems {
+		return nil, fmt.Errorf("too many items: %d (max %d)", len(req.Items), maxBatchItems)
+	}
+	qs := make([]backend.Query, len(req.Items))
+	for i, it := range req.Items {
+		ns, pvc, identity := strings.TrimSpace(it.Namespace), strings.TrimSpace(it.PVC), strings.TrimSpace(it.Identity)
+		if ns == "" || (pvc == "" && identity == "") || strings.Contains(ns, "/") || strings.Contains(pvc, "/") {
+			return nil, fmt.Errorf("items[%d]: need a namespace and a pvc or identity", i)
+		}
+		qs[i] = backend.NewQuery(ns, pvc, identity)
+	}
+	return qs, nil
+}
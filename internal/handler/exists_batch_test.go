@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+)
+
+// queryEchoBackend answers every query fresh and authoritative, except
+// PVCs named "flaky", which come back unknown.
+type queryEchoBackend struct {
+	calls int
+}
+
+func (b *queryEchoBackend) CheckBackupExists(_ context.Context, q backend.Query) backend.CheckResult {
+	b.calls++
+	r := q.Result(backend.TypeKopiaS3)
+	if q.PVC == "flaky" {
+		r.Decision = backend.DecisionUnknown
+		r.Error = "kopia snapshot list failed"
+		return r
+	}
+	r.Decision = backend.DecisionFresh
+	r.Authoritative = true
+	return r
+}
+
+// batchingBackend records that the batch path was used.
+type batchingBackend struct {
+	queryEchoBackend
+	batches int
+}
+
+func (b *batchingBackend) CheckBackupExistsBatch(ctx context.Context, qs []backend.Query) []backend.CheckResult {
+	b.batches++
+	out := make([]backend.CheckResult, len(qs))
+	for i, q := range qs {
+		out[i] = b.CheckBackupExists(ctx, q)
+	}
+	return out
+}
+
+func postBatch(t *testing.T, h *Handler, body string) (*httptest.ResponseRecorder, BatchResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.HandleExistsBatch(rec, httptest.NewRequest(http.MethodPost, "/exists:batch", strings.NewReader(body)))
+	var resp BatchResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	return rec, resp
+}
+
+func batchTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+}
+
+func TestHandleExistsBatch_InOrderWithAggregate(t *testing.T) {
+	bk := &queryEchoBackend{}
+	h := New(bk, batchTestLogger())
+
+	rec, resp := postBatch(t, h, `{"items": [
+		{"namespace": "myapp", "pvc": "data"},
+		{"namespace": "immich-prod", "pvc": "library", "identity": "immich-library"},
+		{"namespace": "immich-prod", "identity": "immich-thumbs"}
+	]}`)
+	if rec.Code != http.StatusOK || !resp.Authoritative || resp.Count != 3 || bk.calls != 3 {
+		t.Fatalf("status %d, resp %+v, calls %d", rec.Code, resp, bk.calls)
+	}
+	if r := resp.Results[0]; r.Namespace != "myapp" || r.Pvc != "data" || r.Identity != "myapp/data" {
+		t.Errorf("item 0: %+v", r)
+	}
+	if r := resp.Results[1]; r.Pvc != "library" || r.Identity != "immich-library" {
+		t.Errorf("item 1: %+v", r)
+	}
+	if r := resp.Results[2]; r.Pvc != "" || r.Identity != "immich-thumbs" {
+		t.Errorf("item 2: %+v", r)
+	}
+}
+
+func TestHandleExistsBatch_OneUnknownClearsAggregate(t *testing.T) {
+	h := New(&queryEchoBackend{}, batchTestLogger())
+	rec, resp := postBatch(t, h, `{"items": [{"namespace": "myapp", "pvc": "data"}, {"namespace": "myapp", "pvc": "flaky"}]}`)
+	if rec.Code != http.StatusOK || resp.Authoritative {
+		t.Fatalf("status %d, resp %+v", rec.Code, resp)
+	}
+	if !resp.Results[0].Authoritative || resp.Results[1].Decision != backend.DecisionUnknown {
+		t.Errorf("results: %+v", resp.Results)
+	}
+	if h.requestsErrors.Load() != 1 {
+		t.Errorf("requestsErrors = %d, want 1", h.requestsErrors.Load())
+	}
+}
+
+func TestHandleExistsBatch_UsesBatchChecker(t *testing.T) {
+	bk := &batchingBackend{}
+	h := New(bk, batchTestLogger())
+	if _, resp := postBatch(t, h, `{"items": [{"namespace": "a", "pvc": "x"}, {"namespace": "b", "pvc": "y"}]}`); resp.Count != 2 {
+		t.Fatalf("resp: %+v", resp)
+	}
+	if bk.batches != 1 {
+		t.Errorf("batches = %d, want 1", bk.batches)
+	}
+}
+
+func TestHandleExistsBatch_RejectsBadRequests(t *testing.T) {
+	h := New(&queryEchoBackend{}, batchTestLogger())
+	for name, body := range map[string]string{
+		"not json":        `{"items": [`,
+		"empty":           `{"items": []}`,
+		"no namespace":    `{"items": [{"pvc": "data"}]}`,
+		"no pvc/identity": `{"items": [{"namespace": "myapp"}]}`,
+		"slash in pvc":    `{"items": [{"namespace": "myapp", "pvc": "a/b"}]}`,
+		"unknown field":   `{"items": [{"namespace": "myapp", "pvc": "data", "name": "x"}]}`,
+		"too many":        `{"items": [` + strings.Repeat(`{"namespace":"a","pvc":"b"},`, maxBatchItems) + `{"namespace":"a","pvc":"b"}]}`,
+	} {
+		rec, resp := postBatch(t, h, body)
+		if rec.Code != http.StatusBadRequest || resp.Error == "" || resp.Authoritative {
+			t.Errorf("%s: status %d, resp %+v", name, rec.Code, resp)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.HandleExistsBatch(rec, httptest.NewRequest(http.MethodGet, "/exists:batch", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != http.MethodPost {
+		t.Errorf("GET: status %d, Allow %q", rec.Code, rec.Header().Get("Allow"))
+	}
+}
@@ -4,14 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/mitchross/pvc-plumber/internal/backend"
+	"github.com/mitchross/pvc-plumber/internal/kopia"
 )
 
 // JSON keys used in /healthz and /readyz responses.
@@ -22,7 +25,7 @@ const (
 
 // BackendClient interface for dependency injection and testing
 type BackendClient interface {
-	CheckBackupExists(ctx context.Context, namespace, pvc string) backend.CheckResult
+	CheckBackupExists(ctx context.Context, q backend.Query) backend.CheckResult
 }
 
 // HealthChecker is an optional interface backends can implement for readiness checks.
@@ -38,9 +41,40 @@ type DedupCounter interface {
 	DedupedCalls() int64
 }
 
+// LineageChangeCounter is an optional interface a wrapped backend can
+// implement to report how many backup lineages appeared or vanished
+// between consecutive repository scans. Exposed via the
+// pvcplumber_cache_lineages_{appeared,vanished}_total metrics.
+type LineageChangeCounter interface {
+	LineagesAppeared() int64
+	LineagesVanished() int64
+}
+
+// RotationCounter reports applied and failed kopia credential rotations
+// (kopia.CredentialsWatcher). It is not discovered on the backend — the
+// watcher sits beside the reader, not inside the cache — so callers wire
+// it with SetRotationCounter. Exposed via the
+// pvcplumber_kopia_credential_rotations_total metric.
+type RotationCounter interface {
+	CredentialRotations() int64
+	CredentialRotationFailures() int64
+}
+
+// MaintenanceReporter reports the kopia maintenance loop's state
+// (kopia.Maintainer). Like RotationCounter it sits beside the backend
+// and is wired with SetMaintenanceReporter. Exposed in /readyz detail
+// and the pvcplumber_kopia_maintenance_* metrics, or by
+// MaintenanceHandler and NewMaintenanceMetricsHandler in the v4
+// operator.
+type MaintenanceReporter interface {
+	Status() kopia.MaintenanceStatus
+}
+
 type Handler struct {
 	backend        BackendClient
 	healthChecker  HealthChecker
+	rotations      RotationCounter
+	maintenance    MaintenanceReporter
 	logger         *slog.Logger
 	requestTimeout time.Duration
 	requestsTotal  atomic.Int64
@@ -82,6 +116,17 @@ func (h *Handler) SetRequestTimeout(timeout time.Duration) {
 	h.requestTimeout = timeout
 }
 
+// SetRotationCounter enables the credential-rotation metric.
+func (h *Handler) SetRotationCounter(rc RotationCounter) {
+	h.rotations = rc
+}
+
+// SetMaintenanceReporter enables the maintenance metrics and /readyz
+// detail.
+func (h *Handler) SetMaintenanceReporter(mr MaintenanceReporter) {
+	h.maintenance = mr
+}
+
 func (h *Handler) HandleExists(w http.ResponseWriter, r *http.Request) {
 	h.requestsTotal.Add(1)
 
@@ -121,7 +166,13 @@ func (h *Handler) HandleExists(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Info("checking backup", "namespace", namespace, "pvc", pvc)
+	// ?identity= names the backup identity explicitly — the value of the
+	// PVC's pvc-plumber.io/backup-identity annotation. Without it the PVC's
+	// default identity is checked, under both the legacy and v4 kopia
+	// source conventions.
+	q := backend.NewQuery(namespace, pvc, strings.TrimSpace(r.URL.Query().Get("identity")))
+
+	h.logger.Info("checking backup", "namespace", namespace, "pvc", pvc, "identity", q.Identity.Key())
 
 	ctx := r.Context()
 	if h.requestTimeout > 0 {
@@ -130,7 +181,7 @@ func (h *Handler) HandleExists(w http.ResponseWriter, r *http.Request) {
 		defer cancel()
 	}
 
-	result := h.backend.CheckBackupExists(ctx, namespace, pvc)
+	result := h.backend.CheckBackupExists(ctx, q)
 
 	if result.Error != "" {
 		h.requestsErrors.Add(1)
@@ -140,6 +191,8 @@ func (h *Handler) HandleExists(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("backup check complete",
 		"namespace", namespace,
 		"pvc", pvc,
+		"identity", result.Identity,
+		"source", result.Source,
 		"exists", result.Exists,
 		"decision", result.Decision,
 		"authoritative", result.Authoritative,
@@ -157,21 +210,27 @@ func (h *Handler) HandleHealthz(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(map[string]string{healthStatusKey: healthStatusOK})
 }
 
+// HandleReadyz reports backend health. With a maintenance reporter set
+// the body also carries a kopia_maintenance detail block; maintenance
+// state never affects readiness itself — a failed or deferred run
+// leaves /exists answering exactly as before, and pulling the pod from
+// the Service over it would turn a housekeeping problem into an
+// admission outage.
 func (h *Handler) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	body := map[string]any{healthStatusKey: healthStatusOK}
+	if h.maintenance != nil {
+		body["kopia_maintenance"] = h.maintenance.Status()
+	}
+	w.Header().Set("Content-Type", "application/json")
 	if h.healthChecker != nil {
 		if err := h.healthChecker.HealthCheck(r.Context()); err != nil {
 			h.logger.Warn("readiness check failed", "error", err)
-			w.Header().Set("Content-Type", "application/json")
+			body[healthStatusKey] = "not ready"
+			body["error"] = err.Error()
 			w.WriteHeader(http.StatusServiceUnavailable)
-			_ = json.NewEncoder(w).Encode(map[string]string{
-				healthStatusKey: "not ready",
-				"error":         err.Error(),
-			})
-			return
 		}
 	}
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]string{healthStatusKey: healthStatusOK})
+	_ = json.NewEncoder(w).Encode(body)
 }
 
 func (h *Handler) HandleMetrics(w http.ResponseWriter, r *http.Request) {
@@ -192,6 +251,70 @@ func (h *Handler) HandleMetrics(w http.ResponseWriter, r *http.Request) {
 		_, _ = fmt.Fprintf(w, "# TYPE pvcplumber_exists_singleflight_dedup_total counter\n")
 		_, _ = fmt.Fprintf(w, "pvcplumber_exists_singleflight_dedup_total %d\n", dc.DedupedCalls())
 	}
+	if lc, ok := h.backend.(LineageChangeCounter); ok {
+		_, _ = fmt.Fprintf(w, "# HELP pvcplumber_cache_lineages_appeared_total Total number of backup lineages that gained snapshots between consecutive repository scans\n")
+		_, _ = fmt.Fprintf(w, "# TYPE pvcplumber_cache_lineages_appeared_total counter\n")
+		_, _ = fmt.Fprintf(w, "pvcplumber_cache_lineages_appeared_total %d\n", lc.LineagesAppeared())
+		_, _ = fmt.Fprintf(w, "# HELP pvcplumber_cache_lineages_vanished_total Total number of backup lineages that had snapshots in one repository scan and none in the next\n")
+		_, _ = fmt.Fprintf(w, "# TYPE pvcplumber_cache_lineages_vanished_total counter\n")
+		_, _ = fmt.Fprintf(w, "pvcplumber_cache_lineages_vanished_total %d\n", lc.LineagesVanished())
+	}
+	if h.rotations != nil {
+		_, _ = fmt.Fprintf(w, "# HELP pvcplumber_kopia_credential_rotations_total Total number of kopia credential rotations by result\n")
+		_, _ = fmt.Fprintf(w, "# TYPE pvcplumber_kopia_credential_rotations_total counter\n")
+		_, _ = fmt.Fprintf(w, "pvcplumber_kopia_credential_rotations_total{result=\"success\"} %d\n", h.rotations.CredentialRotations())
+		_, _ = fmt.Fprintf(w, "pvcplumber_kopia_credential_rotations_total{result=\"failure\"} %d\n", h.rotations.CredentialRotationFailures())
+	}
+	if h.maintenance != nil {
+		writeMaintenanceMetrics(w, h.maintenance.Status())
+	}
+}
+
+// writeMaintenanceMetrics renders kopia.MaintenanceStatus. Timestamps
+// and durations are 0 for a mode that has not run since startup.
+func writeMaintenanceMetrics(w io.Writer, s kopia.MaintenanceStatus) {
+	modes := []struct {
+		name string
+		st   kopia.MaintenanceModeStatus
+	}{{kopia.MaintenanceQuick, s.Quick}, {kopia.MaintenanceFull, s.Full}}
+
+	_, _ = fmt.Fprintf(w, "# HELP pvcplumber_kopia_maintenance_runs_total Total number of kopia maintenance runs by mode and result (skipped = deferred while movers ran)\n")
+	_, _ = fmt.Fprintf(w, "# TYPE pvcplumber_kopia_maintenance_runs_total counter\n")
+	for _, m := range modes {
+		_, _ = fmt.Fprintf(w, "pvcplumber_kopia_maintenance_runs_total{mode=%q,result=%q} %d\n", m.name, kopia.MaintenanceSucceeded, m.st.Succeeded)
+		_, _ = fmt.Fprintf(w, "pvcplumber_kopia_maintenance_runs_total{mode=%q,result=%q} %d\n", m.name, kopia.MaintenanceFailed, m.st.Failed)
+		_, _ = fmt.Fprintf(w, "pvcplumber_kopia_maintenance_runs_total{mode=%q,result=%q} %d\n", m.name, kopia.MaintenanceSkipped, m.st.Skipped)
+	}
+	_, _ = fmt.Fprintf(w, "# HELP pvcplumber_kopia_maintenance_last_success_timestamp_seconds Unix time of the last successful kopia maintenance run by mode\n")
+	_, _ = fmt.Fprintf(w, "# TYPE pvcplumber_kopia_maintenance_last_success_timestamp_seconds gauge\n")
+	for _, m := range modes {
+		var ts int64
+		if !m.st.LastSuccessAt.IsZero() {
+			ts = m.st.LastSuccessAt.Unix()
+		}
+		_, _ = fmt.Fprintf(w, "pvcplumber_kopia_maintenance_last_success_timestamp_seconds{mode=%q} %d\n", m.name, ts)
+	}
+	_, _ = fmt.Fprintf(w, "# HELP pvcplumber_kopia_maintenance_last_duration_seconds Duration of the last completed kopia maintenance run by mode\n")
+	_, _ = fmt.Fprintf(w, "# TYPE pvcplumber_kopia_maintenance_last_duration_seconds gauge\n")
+	for _, m := range modes {
+		var d float64
+		if m.st.LastRun != nil && m.st.LastRun.Result != kopia.MaintenanceSkipped {
+			d = m.st.LastRun.DurationSeconds
+		}
+		_, _ = fmt.Fprintf(w, "pvcplumber_kopia_maintenance_last_duration_seconds{mode=%q} %g\n", m.name, d)
+	}
+	_, _ = fmt.Fprintf(w, "# HELP pvcplumber_kopia_maintenance_freed_bytes_total Total repository bytes released by kopia maintenance by mode\n")
+	_, _ = fmt.Fprintf(w, "# TYPE pvcplumber_kopia_maintenance_freed_bytes_total counter\n")
+	for _, m := range modes {
+		_, _ = fmt.Fprintf(w, "pvcplumber_kopia_maintenance_freed_bytes_total{mode=%q} %d\n", m.name, m.st.FreedBytes)
+	}
+	owned := 0
+	if s.RepositoryOwner == "" || s.RepositoryOwner == s.Owner {
+		owned = 1
+	}
+	_, _ = fmt.Fprintf(w, "# HELP pvcplumber_kopia_maintenance_owned Whether this process holds (or can claim) kopia maintenance ownership\n")
+	_, _ = fmt.Fprintf(w, "# TYPE pvcplumber_kopia_maintenance_owned gauge\n")
+	_, _ = fmt.Fprintf(w, "pvcplumber_kopia_maintenance_owned %d\n", owned)
 }
 
 func (h *Handler) recordBackupCheck(result backend.CheckResult) {
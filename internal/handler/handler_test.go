@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/mitchross/pvc-plumber/internal/backend"
+	"github.com/mitchross/pvc-plumber/internal/kopia"
 )
 
 // Test fixture constants. Centralized so goconst stops complaining about
@@ -24,9 +25,11 @@ const (
 // mockBackendClient implements BackendClient interface for testing
 type mockBackendClient struct {
 	result backend.CheckResult
+	query  backend.Query
 }
 
-func (m *mockBackendClient) CheckBackupExists(ctx context.Context, namespace, pvc string) backend.CheckResult {
+func (m *mockBackendClient) CheckBackupExists(ctx context.Context, q backend.Query) backend.CheckResult {
+	m.query = q
 	return m.result
 }
 
@@ -34,14 +37,14 @@ type deadlineCapturingBackend struct {
 	hasDeadline bool
 }
 
-func (m *deadlineCapturingBackend) CheckBackupExists(ctx context.Context, namespace, pvc string) backend.CheckResult {
+func (m *deadlineCapturingBackend) CheckBackupExists(ctx context.Context, q backend.Query) backend.CheckResult {
 	_, m.hasDeadline = ctx.Deadline()
 	return backend.CheckResult{
 		Exists:        false,
 		Decision:      backend.DecisionFresh,
 		Authoritative: true,
-		Namespace:     namespace,
-		Pvc:           pvc,
+		Namespace:     q.Namespace,
+		Pvc:           q.PVC,
 		Backend:       backend.TypeKopiaS3,
 	}
 }
@@ -177,6 +180,32 @@ func TestHandleExists(t *testing.T) {
 	}
 }
 
+func TestHandleExists_IdentityQuery(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	for _, tc := range []struct {
+		url          string
+		wantIdentity string
+		overridden   bool
+	}{
+		{"/exists/immich-prod/library", "immich-prod/library", false},
+		{"/exists/immich-prod/library?identity=immich-library", "immich-library", true},
+		{"/exists/immich-prod/library?identity=%20immich-library%20", "immich-library", true},
+		{"/exists/immich-prod/library?identity=", "immich-prod/library", false},
+	} {
+		mock := &mockBackendClient{result: backend.CheckResult{Decision: backend.DecisionFresh, Authoritative: true}}
+		h := New(mock, logger)
+		rec := httptest.NewRecorder()
+		h.HandleExists(rec, httptest.NewRequest(http.MethodGet, tc.url, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status %d", tc.url, rec.Code)
+		}
+		q := mock.query
+		if q.Namespace != "immich-prod" || q.PVC != "library" || q.Identity.Key() != tc.wantIdentity || q.Overridden() != tc.overridden {
+			t.Errorf("%s: query %+v", tc.url, q)
+		}
+	}
+}
+
 func TestHandleHealthz(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 	handler := New(nil, logger)
@@ -275,6 +304,63 @@ func TestHandleMetrics(t *testing.T) {
 	}
 }
 
+// lineageCountingBackend is a backend that reports scan diff counters.
+type lineageCountingBackend struct {
+	mockBackendClient
+}
+
+func (lineageCountingBackend) LineagesAppeared() int64 { return 3 }
+func (lineageCountingBackend) LineagesVanished() int64 { return 1 }
+
+func TestHandleMetrics_LineageChanges(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	w := httptest.NewRecorder()
+	New(&lineageCountingBackend{}, logger).HandleMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		"pvcplumber_cache_lineages_appeared_total 3\n",
+		"pvcplumber_cache_lineages_vanished_total 1\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	New(&mockBackendClient{}, logger).HandleMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if strings.Contains(w.Body.String(), "lineages") {
+		t.Error("lineage metrics emitted for a backend without counters")
+	}
+}
+
+type fixedRotations struct{}
+
+func (fixedRotations) CredentialRotations() int64        { return 2 }
+func (fixedRotations) CredentialRotationFailures() int64 { return 1 }
+
+func TestHandleMetrics_CredentialRotations(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	h := New(&mockBackendClient{}, logger)
+
+	w := httptest.NewRecorder()
+	h.HandleMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if strings.Contains(w.Body.String(), "credential_rotations") {
+		t.Error("rotation metric emitted without a counter")
+	}
+
+	h.SetRotationCounter(fixedRotations{})
+	w = httptest.NewRecorder()
+	h.HandleMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		`pvcplumber_kopia_credential_rotations_total{result="success"} 2` + "\n",
+		`pvcplumber_kopia_credential_rotations_total{result="failure"} 1` + "\n",
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+}
+
 func TestMetricsCounters(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 
@@ -339,3 +425,72 @@ func TestMetricsErrorCounter(t *testing.T) {
 		t.Errorf("Expected labeled unknown counter, got: %s", body)
 	}
 }
+
+type fixedMaintenance struct{ s kopia.MaintenanceStatus }
+
+func (f fixedMaintenance) Status() kopia.MaintenanceStatus { return f.s }
+
+func testMaintenanceStatus() kopia.MaintenanceStatus {
+	last := time.Date(2026, 6, 3, 3, 0, 0, 0, time.UTC)
+	return kopia.MaintenanceStatus{
+		Owner: kopia.DefaultMaintenanceOwner,
+		Quick: kopia.MaintenanceModeStatus{
+			Interval: "1h0m0s", Succeeded: 5, Skipped: 2,
+			LastRun: &kopia.MaintenanceRun{Mode: kopia.MaintenanceQuick, Result: kopia.MaintenanceSkipped, Reason: "1 mover jobs active"},
+		},
+		Full: kopia.MaintenanceModeStatus{
+			Interval: "24h0m0s", Succeeded: 1, Failed: 1, LastSuccessAt: last, FreedBytes: 4096,
+			LastRun: &kopia.MaintenanceRun{Mode: kopia.MaintenanceFull, Result: kopia.MaintenanceSucceeded, StartedAt: last, DurationSeconds: 12.5, FreedBytes: 4096},
+		},
+	}
+}
+
+func TestHandleMetrics_Maintenance(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	h := New(&mockBackendClient{}, logger)
+	h.SetMaintenanceReporter(fixedMaintenance{testMaintenanceStatus()})
+
+	w := httptest.NewRecorder()
+	h.HandleMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		`pvcplumber_kopia_maintenance_runs_total{mode="quick",result="success"} 5` + "\n",
+		`pvcplumber_kopia_maintenance_runs_total{mode="quick",result="skipped"} 2` + "\n",
+		`pvcplumber_kopia_maintenance_runs_total{mode="full",result="failure"} 1` + "\n",
+		`pvcplumber_kopia_maintenance_last_success_timestamp_seconds{mode="full"} 1780455600` + "\n",
+		`pvcplumber_kopia_maintenance_last_success_timestamp_seconds{mode="quick"} 0` + "\n",
+		`pvcplumber_kopia_maintenance_last_duration_seconds{mode="full"} 12.5` + "\n",
+		// A deferral is not a run; its duration is not reported.
+		`pvcplumber_kopia_maintenance_last_duration_seconds{mode="quick"} 0` + "\n",
+		`pvcplumber_kopia_maintenance_freed_bytes_total{mode="full"} 4096` + "\n",
+		"pvcplumber_kopia_maintenance_owned 1\n",
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+}
+
+func TestHandleReadyz_MaintenanceDetail(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	s := testMaintenanceStatus()
+	s.Full.LastRun.Result = kopia.MaintenanceFailed
+	h := New(nil, logger)
+	h.SetMaintenanceReporter(fixedMaintenance{s})
+
+	w := httptest.NewRecorder()
+	h.HandleReadyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	// A failed maintenance run does not make the pod unready.
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d", w.Code)
+	}
+	var body struct {
+		Status      string                  `json:"status"`
+		Maintenance kopia.MaintenanceStatus `json:"kopia_maintenance"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Status != "ok" || body.Maintenance.Full.LastRun.Result != kopia.MaintenanceFailed || body.Maintenance.Full.FreedBytes != 4096 {
+		t.Errorf("body: %+v", body)
+	}
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/inventory"
+)
+
+// InventoryScanTimeout bounds one GET /audit/inventory. A cold native
+// scan reads every index and manifest blob in the repository, which is
+// far slower than the in-memory /audit; the server mounting this
+// handler needs a WriteTimeout above it.
+const InventoryScanTimeout = 45 * time.Second
+
+// InventoryReporter is the surface InventoryHandler needs. The
+// production *inventory.Auditor satisfies it; tests use a fake.
+type InventoryReporter interface {
+	Report(ctx context.Context) (inventory.Report, error)
+}
+
+// InventoryHandler serves GET /audit/inventory: the orphaned-backup
+// inventory (see package inventory). Unlike /audit it is computed per
+// request — a PVC listing and a full repository scan — so there is no
+// HEAD shortcut and a failure of either is 503, never a partial report:
+// an inventory missing half the PVCs would call their lineages orphaned.
+//
+// Read-only and unauthenticated like /audit. It names namespaces, PVCs
+// and snapshot sources, never repository credentials.
+type InventoryHandler struct {
+	reporter InventoryReporter
+	logger   *slog.Logger
+}
+
+// NewInventoryHandler constructs an InventoryHandler. reporter must be
+// non-nil; logger may be nil.
+func NewInventoryHandler(reporter InventoryReporter, logger *slog.Logger) *InventoryHandler {
+	return &InventoryHandler{reporter: reporter, logger: logger}
+}
+
+// ServeHTTP implements http.Handler. GET only, 405 otherwise.
+func (h *InventoryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), InventoryScanTimeout)
+	defer cancel()
+	report, err := h.reporter.Report(ctx)
+	if err != nil {
+		if h.logger != nil {
+			h.logger.Warn("inventory report failed", "error", err)
+		}
+		http.Error(w, "inventory unavailable: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(report); err != nil && h.logger != nil {
+		h.logger.Warn("inventory endpoint encode failed", "error", err)
+	}
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/inventory"
+)
+
+type fakeInventory struct {
+	report      inventory.Report
+	err         error
+	hasDeadline bool
+}
+
+func (f *fakeInventory) Report(ctx context.Context) (inventory.Report, error) {
+	_, f.hasDeadline = ctx.Deadline()
+	return f.report, f.err
+}
+
+func TestInventoryHandler_Get(t *testing.T) {
+	fi := &fakeInventory{report: inventory.Build(nil, []inventory.PVC{{Namespace: "myapp", Name: "data", OptedIn: true}},
+		time.Date(2026, 6, 3, 12, 0, 0, 0, time.UTC))}
+	rec := httptest.NewRecorder()
+	NewInventoryHandler(fi, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/audit/inventory", nil))
+
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status %d, content-type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var got inventory.Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Summary.PVCsWithoutLineage != 1 || got.PVCsWithoutLineage[0].Identity != "myapp/data" {
+		t.Errorf("report: %+v", got)
+	}
+	// Empty lists render as [] so jq pipelines need no null guards.
+	if !strings.Contains(rec.Body.String(), `"orphaned_lineages":[]`) {
+		t.Errorf("body: %s", rec.Body.String())
+	}
+	if !fi.hasDeadline {
+		t.Error("report computed without the scan timeout")
+	}
+}
+
+func TestInventoryHandler_FailureIs503(t *testing.T) {
+	fi := &fakeInventory{err: errors.New("scan kopia repository: access denied")}
+	rec := httptest.NewRecorder()
+	NewInventoryHandler(fi, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/audit/inventory", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "access denied") {
+		t.Errorf("status %d body %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestInventoryHandler_MethodNotAllowed(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewInventoryHandler(&fakeInventory{}, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/audit/inventory", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET" {
+		t.Errorf("status %d allow %q", rec.Code, rec.Header().Get("Allow"))
+	}
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+)
+
+// Limits for GET /journal. The default keeps a bare curl readable; the
+// ceiling bounds the response for a ConfigMap- or file-backed journal
+// holding thousands of entries with full specs.
+const (
+	defaultJournalLimit = 100
+	maxJournalLimit     = 1000
+	journalQueryTimeout = 5 * time.Second
+)
+
+// JournalQuerier is the read-only surface JournalHandler needs. The
+// production *journal.Journal satisfies it; tests use a fake.
+type JournalQuerier interface {
+	Query(ctx context.Context, f journal.Filter) ([]journal.Entry, error)
+}
+
+// JournalResponse is the GET /journal body.
+type JournalResponse struct {
+	Count   int             `json:"count"`
+	Entries []journal.Entry `json:"entries"`
+}
+
+// JournalHandler serves GET /journal: the executor write journal,
+// newest first, filtered by query parameters:
+//
+//	namespace, name   exact match on the target object
+//	kind              ReplicationSource | ReplicationDestination
+//	op                create | update | delete
+//	status            succeeded | refused | failed
+//	since             RFC3339 timestamp; older entries dropped
+//	limit             1..1000, default 100
+//
+// Like /audit it is read-only and unauthenticated; it exposes the same
+// RS/RD specs the cluster already serves to anyone who can read them,
+// never Secret material.
+type JournalHandler struct {
+	querier JournalQuerier
+	logger  *slog.Logger
+}
+
+// NewJournalHandler constructs a JournalHandler. querier must be
+// non-nil; logger may be nil.
+func NewJournalHandler(querier JournalQuerier, logger *slog.Logger) *JournalHandler {
+	return &JournalHandler{querier: querier, logger: logger}
+}
+
+// ServeHTTP implements http.Handler. Method routing matches /audit:
+// GET and HEAD only, 405 otherwise. Malformed parameters are 400; a
+// sink read failure (ConfigMap unreachable, unreadable file) is 503 so
+// callers can tell "no history" from "history temporarily unavailable".
+func (h *JournalHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	default:
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	f, err := parseJournalFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), journalQueryTimeout)
+	defer cancel()
+	entries, err := h.querier.Query(ctx, f)
+	if err != nil {
+		if h.logger != nil {
+			h.logger.Warn("journal query failed", "error", err)
+		}
+		http.Error(w, "journal unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if entries == nil {
+		entries = []journal.Entry{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(JournalResponse{Count: len(entries), Entries: entries}); err != nil {
+		if h.logger != nil {
+			h.logger.Warn("journal endpoint encode failed", "error", err)
+		}
+	}
+}
+
+// parseJournalFilter maps query parameters onto journal.Filter.
+func parseJournalFilter(q url.Values) (journal.Filter, error) {
+	f := journal.Filter{
+		Namespace: q.Get("namespace"),
+		Name:      q.Get("name"),
+		Kind:      q.Get("kind"),
+		Op:        q.Get("op"),
+		Status:    q.Get("status"),
+		Limit:     defaultJournalLimit,
+	}
+	if raw := q.Get("since"); raw != "" {
+		since, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return f, fmt.Errorf("invalid since: %w", err)
+		}
+		f.Since = since
+	}
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxJournalLimit {
+			return f, fmt.Errorf("invalid limit %q: must be 1..%d", raw, maxJournalLimit)
+		}
+		f.Limit = n
+	}
+	return f, nil
+}
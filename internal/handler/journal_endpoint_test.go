@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+)
+
+// fakeJournal records the last Filter it was asked for.
+type fakeJournal struct {
+	entries []journal.Entry
+	err     error
+	got     journal.Filter
+}
+
+func (f *fakeJournal) Query(_ context.Context, flt journal.Filter) ([]journal.Entry, error) {
+	f.got = flt
+	if f.err != nil {
+		return nil, f.err
+	}
+	return journal.Select(f.entries, flt), nil
+}
+
+func journalFixture() *fakeJournal {
+	t0 := time.Date(2026, 6, 2, 9, 0, 0, 0, time.UTC)
+	return &fakeJournal{entries: []journal.Entry{
+		{Time: t0, Op: "create", GVK: "volsync.backube/v1alpha1/ReplicationSource", Namespace: "myapp", Name: "data", Status: "succeeded"},
+		{Time: t0.Add(time.Hour), Op: "delete", GVK: "volsync.backube/v1alpha1/ReplicationSource", Namespace: "myapp", Name: "data", Status: "succeeded"},
+		{Time: t0.Add(2 * time.Hour), Op: "update", GVK: "volsync.backube/v1alpha1/ReplicationDestination", Namespace: "other", Name: "x-dst", Status: "refused", Reason: "not-owned"},
+	}}
+}
+
+func TestJournalHandler_GetFiltersNewestFirst(t *testing.T) {
+	fj := journalFixture()
+	h := NewJournalHandler(fj, nil)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/journal?namespace=myapp&kind=ReplicationSource", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want 200 (%s)", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type: got %q", ct)
+	}
+	var resp JournalResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Count != 2 || resp.Entries[0].Op != "delete" {
+		t.Errorf("want 2 entries newest first, got %+v", resp)
+	}
+	if fj.got.Limit != defaultJournalLimit {
+		t.Errorf("default limit: got %d, want %d", fj.got.Limit, defaultJournalLimit)
+	}
+}
+
+func TestJournalHandler_EmptyIsArrayNotNull(t *testing.T) {
+	h := NewJournalHandler(&fakeJournal{}, nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/journal", nil))
+	if want := `{"count":0,"entries":[]}` + "\n"; rec.Body.String() != want {
+		t.Errorf("body: got %q, want %q", rec.Body.String(), want)
+	}
+}
+
+func TestJournalHandler_BadParams400(t *testing.T) {
+	h := NewJournalHandler(journalFixture(), nil)
+	for _, q := range []string{"since=yesterday", "limit=0", "limit=5000", "limit=abc"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/journal?"+q, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", q, rec.Code)
+		}
+	}
+}
+
+func TestJournalHandler_SinkErrorIs503(t *testing.T) {
+	h := NewJournalHandler(&fakeJournal{err: errors.New("configmap unreachable")}, nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/journal", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status: got %d, want 503", rec.Code)
+	}
+}
+
+func TestJournalHandler_MethodNotAllowed(t *testing.T) {
+	h := NewJournalHandler(journalFixture(), nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/journal", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, HEAD" {
+		t.Errorf("got %d Allow=%q", rec.Code, rec.Header().Get("Allow"))
+	}
+}
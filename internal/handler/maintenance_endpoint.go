@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// MaintenanceHandler serves GET /kopia/maintenance: the maintenance
+// loop's status (kopia.MaintenanceStatus), the same block the legacy
+// /readyz carries as kopia_maintenance. The v4 operator runs the loop as
+// a manager runnable and mounts this, with NewMaintenanceMetricsHandler,
+// on the manager's metrics server. Maintenance state never affects
+// readiness, so it has a route of its own rather than a place in /readyz.
+//
+// Read-only and unauthenticated like /audit.
+type MaintenanceHandler struct {
+	reporter MaintenanceReporter
+	logger   *slog.Logger
+}
+
+// NewMaintenanceHandler constructs a MaintenanceHandler. reporter must
+// be non-nil; logger may be nil.
+func NewMaintenanceHandler(reporter MaintenanceReporter, logger *slog.Logger) *MaintenanceHandler {
+	return &MaintenanceHandler{reporter: reporter, logger: logger}
+}
+
+// ServeHTTP implements http.Handler. GET only, 405 otherwise.
+func (h *MaintenanceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(h.reporter.Status()); err != nil && h.logger != nil {
+		h.logger.Warn("maintenance endpoint encode failed", "error", err)
+	}
+}
+
+// NewMaintenanceMetricsHandler serves the pvcplumber_kopia_maintenance_*
+// series as Prometheus text, for a scrape target beside the manager's
+// own /metrics.
+func NewMaintenanceMetricsHandler(reporter MaintenanceReporter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMaintenanceMetrics(w, reporter.Status())
+	})
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+)
+
+func TestMaintenanceHandler_Get(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewMaintenanceHandler(fixedMaintenance{testMaintenanceStatus()}, nil).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/kopia/maintenance", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status %d, content-type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var got kopia.MaintenanceStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Owner != kopia.DefaultMaintenanceOwner || got.Full.FreedBytes != 4096 || got.Quick.LastRun.Result != kopia.MaintenanceSkipped {
+		t.Errorf("status: %+v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	NewMaintenanceHandler(fixedMaintenance{}, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/kopia/maintenance", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status %d", rec.Code)
+	}
+}
+
+func TestMaintenanceMetricsHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewMaintenanceMetricsHandler(fixedMaintenance{testMaintenanceStatus()}).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/kopia/maintenance/metrics", nil))
+	if !strings.Contains(rec.Body.String(), `pvcplumber_kopia_maintenance_freed_bytes_total{mode="full"} 4096`) {
+		t.Errorf("metrics:\n%s", rec.Body.String())
+	}
+}
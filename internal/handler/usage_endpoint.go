@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/mitchross/pvc-plumber/internal/inventory"
+)
+
+// UsageSource is the surface the usage handlers need. The production
+// *inventory.UsageTracker satisfies it; tests use a fake.
+type UsageSource interface {
+	Status() inventory.UsageStatus
+}
+
+// UsageHandler serves GET /audit/usage: the per-identity and
+// per-namespace backup storage report (inventory.UsageReport). Unlike
+// /audit/inventory it never scans on request — it serves the tracker's
+// latest report, refreshed on the re-warm cadence, so it is as cheap as
+// /audit. 503 until the first refresh succeeds; after that a failed
+// refresh keeps serving the previous report, whose generated_at shows
+// its age.
+//
+// Read-only and unauthenticated like /audit.
+type UsageHandler struct {
+	source UsageSource
+	logger *slog.Logger
+}
+
+// NewUsageHandler constructs a UsageHandler. source must be non-nil;
+// logger may be nil.
+func NewUsageHandler(source UsageSource, logger *slog.Logger) *UsageHandler {
+	return &UsageHandler{source: source, logger: logger}
+}
+
+// ServeHTTP implements http.Handler. GET only, 405 otherwise.
+func (h *UsageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	st := h.source.Status()
+	if st.Report == nil {
+		msg := "usage report not computed yet"
+		if st.LastError != "" {
+			msg += ": " + st.LastError
+		}
+		http.Error(w, msg, http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(st.Report); err != nil && h.logger != nil {
+		h.logger.Warn("usage endpoint encode failed", "error", err)
+	}
+}
+
+// NewUsageMetricsHandler serves the usage report as Prometheus text. The
+// v4 HTTP server leaves /metrics to the controller-runtime manager, so
+// this is mounted beside the report at /audit/usage/metrics and scraped
+// as its own target.
+func NewUsageMetricsHandler(source UsageSource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeUsageMetrics(w, source.Status())
+	})
+}
+
+// writeUsageMetrics renders inventory.UsageStatus. Per-identity series
+// carry the namespace too so they aggregate without a join; before the
+// first refresh only the refresh counters are written.
+func writeUsageMetrics(w io.Writer, st inventory.UsageStatus) {
+	_, _ = fmt.Fprintf(w, "# HELP pvcplumber_backup_usage_refresh_errors_total Total number of failed backup usage refreshes\n")
+	_, _ = fmt.Fprintf(w, "# TYPE pvcplumber_backup_usage_refresh_errors_total counter\n")
+	_, _ = fmt.Fprintf(w, "pvcplumber_backup_usage_refresh_errors_total %d\n", st.RefreshErrors)
+	r := st.Report
+	if r == nil {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "# HELP pvcplumber_backup_usage_generated_timestamp_seconds Unix time the backup usage report was computed\n")
+	_, _ = fmt.Fprintf(w, "# TYPE pvcplumber_backup_usage_generated_timestamp_seconds gauge\n")
+	_, _ = fmt.Fprintf(w, "pvcplumber_backup_usage_generated_timestamp_seconds %d\n", r.GeneratedAt.Unix())
+
+	_, _ = fmt.Fprintf(w, "# HELP pvcplumber_backup_identity_latest_bytes Logical size of the latest complete snapshot by backup identity\n")
+	_, _ = fmt.Fprintf(w, "# TYPE pvcplumber_backup_identity_latest_bytes gauge\n")
+	for _, u := range r.Identities {
+		_, _ = fmt.Fprintf(w, "pvcplumber_backup_identity_latest_bytes{namespace=%q,identity=%q} %d\n", u.Namespace, u.Identity, u.LatestSnapshotBytes)
+	}
+	_, _ = fmt.Fprintf(w, "# HELP pvcplumber_backup_identity_retained_bytes Sum of the logical sizes of all retained snapshots by backup identity\n")
+	_, _ = fmt.Fprintf(w, "# TYPE pvcplumber_backup_identity_retained_bytes gauge\n")
+	for _, u := range r.Identities {
+		_, _ = fmt.Fprintf(w, "pvcplumber_backup_identity_retained_bytes{namespace=%q,identity=%q} %d\n", u.Namespace, u.Identity, u.RetainedBytes)
+	}
+	_, _ = fmt.Fprintf(w, "# HELP pvcplumber_backup_identity_snapshots Number of retained snapshots by backup identity\n")
+	_, _ = fmt.Fprintf(w, "# TYPE pvcplumber_backup_identity_snapshots gauge\n")
+	for _, u := range r.Identities {
+		_, _ = fmt.Fprintf(w, "pvcplumber_backup_identity_snapshots{namespace=%q,identity=%q} %d\n", u.Namespace, u.Identity, u.SnapshotCount)
+	}
+
+	_, _ = fmt.Fprintf(w, "# HELP pvcplumber_backup_namespace_latest_bytes Logical size of the latest complete snapshots summed by namespace\n")
+	_, _ = fmt.Fprintf(w, "# TYPE pvcplumber_backup_namespace_latest_bytes gauge\n")
+	for _, n := range r.Namespaces {
+		_, _ = fmt.Fprintf(w, "pvcplumber_backup_namespace_latest_bytes{namespace=%q} %d\n", n.Namespace, n.LatestSnapshotBytes)
+	}
+	_, _ = fmt.Fprintf(w, "# HELP pvcplumber_backup_namespace_retained_bytes Sum of the logical sizes of all retained snapshots by namespace\n")
+	_, _ = fmt.Fprintf(w, "# TYPE pvcplumber_backup_namespace_retained_bytes gauge\n")
+	for _, n := range r.Namespaces {
+		_, _ = fmt.Fprintf(w, "pvcplumber_backup_namespace_retained_bytes{namespace=%q} %d\n", n.Namespace, n.RetainedBytes)
+	}
+	_, _ = fmt.Fprintf(w, "# HELP pvcplumber_backup_namespace_snapshots Number of retained snapshots by namespace\n")
+	_, _ = fmt.Fprintf(w, "# TYPE pvcplumber_backup_namespace_snapshots gauge\n")
+	for _, n := range r.Namespaces {
+		_, _ = fmt.Fprintf(w, "pvcplumber_backup_namespace_snapshots{namespace=%q} %d\n", n.Namespace, n.SnapshotCount)
+	}
+	_, _ = fmt.Fprintf(w, "# HELP pvcplumber_backup_namespace_soft_quota_bytes Backup soft quota by namespace (namespaces with a valid quota only)\n")
+	_, _ = fmt.Fprintf(w, "# TYPE pvcplumber_backup_namespace_soft_quota_bytes gauge\n")
+	for _, n := range r.Namespaces {
+		if n.SoftQuotaBytes > 0 {
+			_, _ = fmt.Fprintf(w, "pvcplumber_backup_namespace_soft_quota_bytes{namespace=%q} %d\n", n.Namespace, n.SoftQuotaBytes)
+		}
+	}
+	_, _ = fmt.Fprintf(w, "# HELP pvcplumber_backup_namespace_over_soft_quota Whether the namespace's latest snapshots exceed its backup soft quota\n")
+	_, _ = fmt.Fprintf(w, "# TYPE pvcplumber_backup_namespace_over_soft_quota gauge\n")
+	for _, n := range r.Namespaces {
+		if n.SoftQuotaBytes > 0 {
+			over := 0
+			if n.OverSoftQuota {
+				over = 1
+			}
+			_, _ = fmt.Fprintf(w, "pvcplumber_backup_namespace_over_soft_quota{namespace=%q} %d\n", n.Namespace, over)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/inventory"
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+)
+
+type fakeUsage struct{ st inventory.UsageStatus }
+
+func (f fakeUsage) Status() inventory.UsageStatus { return f.st }
+
+func usageFixture() inventory.UsageStatus {
+	snaps := []kopia.SnapshotInfo{{
+		ID:        "a1",
+		Source:    kopia.SnapshotSource{Host: "immich", UserName: "library", Path: "/data"},
+		StartTime: time.Date(2026, 6, 2, 3, 0, 0, 0, time.UTC),
+		TotalSize: 2048,
+	}}
+	r := inventory.BuildUsage(snaps, []inventory.PVC{{Namespace: "immich", Name: "library", OptedIn: true}},
+		map[string]string{"immich": "1Ki"}, time.Date(2026, 6, 3, 12, 0, 0, 0, time.UTC))
+	return inventory.UsageStatus{Report: &r, RefreshErrors: 2}
+}
+
+func TestUsageHandler_Get(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewUsageHandler(fakeUsage{st: usageFixture()}, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/audit/usage", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status %d, content-type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var got inventory.UsageReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(got.Identities) != 1 || got.Identities[0].Identity != "library@immich:/data" || !got.Namespaces[0].OverSoftQuota {
+		t.Errorf("report: %+v", got)
+	}
+}
+
+// Before the first successful refresh there is nothing to serve; the
+// last error says why.
+func TestUsageHandler_NotReady(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewUsageHandler(fakeUsage{st: inventory.UsageStatus{LastError: "scan kopia repository: timeout"}}, nil).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/audit/usage", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "timeout") {
+		t.Errorf("status %d, body %q", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	NewUsageHandler(fakeUsage{}, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/audit/usage", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status %d", rec.Code)
+	}
+}
+
+func TestUsageMetrics(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewUsageMetricsHandler(fakeUsage{st: usageFixture()}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"pvcplumber_backup_usage_refresh_errors_total 2\n",
+		`pvcplumber_backup_identity_latest_bytes{namespace="immich",identity="library@immich:/data"} 2048`,
+		`pvcplumber_backup_identity_snapshots{namespace="immich",identity="library@immich:/data"} 1`,
+		`pvcplumber_backup_namespace_retained_bytes{namespace="immich"} 2048`,
+		`pvcplumber_backup_namespace_soft_quota_bytes{namespace="immich"} 1024`,
+		`pvcplumber_backup_namespace_over_soft_quota{namespace="immich"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	NewUsageMetricsHandler(fakeUsage{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if strings.Contains(rec.Body.String(), "pvcplumber_backup_identity_") {
+		t.Errorf("per-identity series before the first report:\n%s", rec.Body.String())
+	}
+}
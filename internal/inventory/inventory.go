@@ -0,0 +1,285 @@
+// Package inventory cross-references the kopia repository's backup
+// lineages with the cluster's PVCs. Nothing else does: the reconciler
+// only ever looks from a PVC toward its lineage, and the cache pre-warm
+// only keeps lineages that map to a PVC key, so a lineage whose PVC was
+// deleted years ago costs repository space forever without showing up
+// anywhere, and an opted-in PVC that never got a first backup looks the
+// same as one that is simply between backups.
+//
+// The report answers three questions, served at /audit/inventory and by
+// `pvc-plumber-ctl inventory`:
+//
+//   - Orphaned lineages: lineages no existing PVC would restore from —
+//     the input for repository cost cleanup.
+//   - PVCs without a lineage: opted-in PVCs with no backup under any of
+//     their candidate sources — the DR-confidence gap.
+//   - Suspected renames: lineages no PVC claims under its current
+//     identity, but that an existing PVC would own under the other
+//     naming convention (a PVC that gained a backup-identity override
+//     after its backups started, so its history sits under the old
+//     source).
+//
+// Build is pure; Auditor wires it to a PVC lister and a repository scan.
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+	"github.com/mitchross/pvc-plumber/internal/v4/naming"
+)
+
+// Naming conventions a lineage can match a PVC under.
+const (
+	// ConventionV4 is {pvc}@{namespace}:/data, the v4 default identity.
+	ConventionV4 = "v4"
+	// ConventionLegacy is {pvc}-backup@{namespace}:/data, every pre-v4
+	// backup.
+	ConventionLegacy = "legacy"
+)
+
+// PVC is the cluster side of the cross-reference: every PVC that exists,
+// opted in or not — a lineage whose PVC still exists is not an orphan
+// even when the PVC has since opted out.
+type PVC struct {
+	Namespace string
+	Name      string
+	// BackupIdentity is the pvc-plumber.io/backup-identity override, empty
+	// for the default identity.
+	BackupIdentity string
+	// OptedIn is the reconciler's opt-in rule: enabled=true or a legacy
+	// backup label, and not backup-exempt. Only opted-in PVCs are expected
+	// to have a lineage.
+	OptedIn bool
+}
+
+func (p PVC) query() backend.Query {
+	return backend.NewQuery(p.Namespace, p.Name, p.BackupIdentity)
+}
+
+// Lineage is one kopia snapshot source and its latest complete snapshot.
+type Lineage struct {
+	Source        string `json:"source"`
+	SnapshotCount int    `json:"snapshot_count"`
+	// LatestSnapshotAt and LatestSnapshotSize describe the newest complete
+	// snapshot; both are zero for a lineage holding only checkpoints.
+	// The size is the snapshot's logical size, not the repository space
+	// it pins — kopia deduplicates across lineages, so deleting one frees
+	// at most that much.
+	LatestSnapshotAt   time.Time `json:"latest_snapshot_at,omitzero"`
+	LatestSnapshotSize int64     `json:"latest_snapshot_size,omitempty"`
+}
+
+// OrphanedLineage is a lineage no existing PVC would restore from.
+type OrphanedLineage struct {
+	Lineage
+	// FormerPVCs are the namespace/pvc keys the source maps to under the
+	// naming conventions — the PVC it most likely belonged to. Empty for a
+	// source that follows neither convention (not written by VolSync).
+	FormerPVCs []string `json:"former_pvcs,omitempty"`
+}
+
+// PVCWithoutLineage is an opted-in PVC with no snapshot under any of its
+// candidate sources.
+type PVCWithoutLineage struct {
+	Namespace string `json:"namespace"`
+	PVC       string `json:"pvc"`
+	Identity  string `json:"identity"`
+	// ExpectedSources are the sources a backup is looked up under, the
+	// one the next backup will write first.
+	ExpectedSources []string `json:"expected_sources"`
+}
+
+// SuspectedRename is a lineage an existing PVC would own under a naming
+// convention other than the one it uses now.
+type SuspectedRename struct {
+	Lineage
+	Namespace string `json:"namespace"`
+	PVC       string `json:"pvc"`
+	// MatchedConvention is the convention under which the lineage names
+	// the PVC; Identity and ExpectedSources are what the PVC uses today.
+	MatchedConvention string   `json:"matched_convention"`
+	Identity          string   `json:"identity"`
+	ExpectedSources   []string `json:"expected_sources"`
+}
+
+// Summary holds the report's counts. OrphanedBytes sums the orphans'
+// latest logical sizes: an upper bound on what deleting them frees.
+type Summary struct {
+	Lineages           int   `json:"lineages"`
+	PVCs               int   `json:"pvcs"`
+	OptedInPVCs        int   `json:"opted_in_pvcs"`
+	OrphanedLineages   int   `json:"orphaned_lineages"`
+	OrphanedBytes      int64 `json:"orphaned_bytes"`
+	PVCsWithoutLineage int   `json:"pvcs_without_lineage"`
+	SuspectedRenames   int   `json:"suspected_renames"`
+}
+
+// Report is the /audit/inventory body. The three lists are always
+// present (empty, not null) and sorted for stable diffs.
+type Report struct {
+	GeneratedAt        time.Time           `json:"generated_at"`
+	Summary            Summary             `json:"summary"`
+	OrphanedLineages   []OrphanedLineage   `json:"orphaned_lineages"`
+	PVCsWithoutLineage []PVCWithoutLineage `json:"pvcs_without_lineage"`
+	SuspectedRenames   []SuspectedRename   `json:"suspected_renames"`
+}
+
+// match is one way an existing PVC relates to a source.
+type match struct {
+	pvc        PVC
+	convention string
+}
+
+// alternates returns the sources p would use under the conventions it
+// does not use today, by convention. A PVC on its default identity
+// already claims both the v4 and the legacy source (kopia.QuerySources),
+// so only an overridden PVC has alternates: the two defaults it left.
+func alternates(p PVC) map[kopia.SnapshotSource]string {
+	if p.BackupIdentity == "" {
+		return nil
+	}
+	return map[kopia.SnapshotSource]string{
+		kopia.V4Source(p.Namespace, naming.IdentityFor(p.Namespace, p.Name, "")): ConventionV4,
+		kopia.LegacySource(p.Namespace, p.Name):                                  ConventionLegacy,
+	}
+}
+
+// Build cross-references snaps (a whole-repository scan) with pvcs.
+func Build(snaps []kopia.SnapshotInfo, pvcs []PVC, now time.Time) Report {
+	bySource := make(map[kopia.SnapshotSource][]kopia.SnapshotInfo)
+	for _, s := range snaps {
+		bySource[s.Source] = append(bySource[s.Source], s)
+	}
+
+	claimed := make(map[kopia.SnapshotSource]bool)
+	renamed := make(map[kopia.SnapshotSource][]match)
+	report := Report{
+		GeneratedAt:        now,
+		OrphanedLineages:   []OrphanedLineage{},
+		PVCsWithoutLineage: []PVCWithoutLineage{},
+		SuspectedRenames:   []SuspectedRename{},
+	}
+	for _, p := range pvcs {
+		q := p.query()
+		sources := kopia.QuerySources(q)
+		found := false
+		for _, src := range sources {
+			claimed[src] = true
+			found = found || len(bySource[src]) > 0
+		}
+		for src, convention := range alternates(p) {
+			renamed[src] = append(renamed[src], match{pvc: p, convention: convention})
+		}
+		if p.OptedIn {
+			report.Summary.OptedInPVCs++
+			if !found {
+				report.PVCsWithoutLineage = append(report.PVCsWithoutLineage, PVCWithoutLineage{
+					Namespace:       p.Namespace,
+					PVC:             p.Name,
+					Identity:        q.Identity.Key(),
+					ExpectedSources: sourceStrings(sources),
+				})
+			}
+		}
+	}
+
+	for src, lineage := range bySource {
+		if claimed[src] {
+			continue
+		}
+		l := summarize(src, lineage)
+		if matches := renamed[src]; len(matches) > 0 {
+			for _, m := range matches {
+				report.SuspectedRenames = append(report.SuspectedRenames, SuspectedRename{
+					Lineage:           l,
+					Namespace:         m.pvc.Namespace,
+					PVC:               m.pvc.Name,
+					MatchedConvention: m.convention,
+					Identity:          m.pvc.query().Identity.Key(),
+					ExpectedSources:   sourceStrings(kopia.QuerySources(m.pvc.query())),
+				})
+			}
+			continue
+		}
+		report.OrphanedLineages = append(report.OrphanedLineages, OrphanedLineage{Lineage: l, FormerPVCs: kopia.PVCKeys(src)})
+		report.Summary.OrphanedBytes += l.LatestSnapshotSize
+	}
+
+	sort.Slice(report.OrphanedLineages, func(i, j int) bool {
+		return report.OrphanedLineages[i].Source < report.OrphanedLineages[j].Source
+	})
+	sort.Slice(report.PVCsWithoutLineage, func(i, j int) bool {
+		a, b := report.PVCsWithoutLineage[i], report.PVCsWithoutLineage[j]
+		return a.Namespace+"/"+a.PVC < b.Namespace+"/"+b.PVC
+	})
+	sort.Slice(report.SuspectedRenames, func(i, j int) bool {
+		a, b := report.SuspectedRenames[i], report.SuspectedRenames[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.Namespace+"/"+a.PVC < b.Namespace+"/"+b.PVC
+	})
+
+	report.Summary.Lineages = len(bySource)
+	report.Summary.PVCs = len(pvcs)
+	report.Summary.OrphanedLineages = len(report.OrphanedLineages)
+	report.Summary.PVCsWithoutLineage = len(report.PVCsWithoutLineage)
+	report.Summary.SuspectedRenames = len(report.SuspectedRenames)
+	return report
+}
+
+func summarize(src kopia.SnapshotSource, snaps []kopia.SnapshotInfo) Lineage {
+	sum := kopia.Summarize(snaps)
+	return Lineage{
+		Source:             src.String(),
+		SnapshotCount:      sum.Count,
+		LatestSnapshotAt:   sum.LatestAt,
+		LatestSnapshotSize: sum.LatestSize,
+	}
+}
+
+func sourceStrings(sources []kopia.SnapshotSource) []string {
+	out := make([]string, len(sources))
+	for i, s := range sources {
+		out[i] = s.String()
+	}
+	return out
+}
+
+// PVCLister lists every PVC in the cluster.
+type PVCLister interface {
+	ListPVCs(ctx context.Context) ([]PVC, error)
+}
+
+// Auditor produces Reports from a live PVC listing and repository scan.
+type Auditor struct {
+	PVCs     PVCLister
+	Lineages kopia.SnapshotLister
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// Report lists the PVCs first, then scans the repository. A PVC deleted
+// between the two still claims its lineage, which errs on the side a
+// cleanup input must: a lineage is only ever reported orphaned late,
+// never early.
+func (a *Auditor) Report(ctx context.Context) (Report, error) {
+	pvcs, err := a.PVCs.ListPVCs(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("list PVCs: %w", err)
+	}
+	snaps, err := a.Lineages.ListAllSnapshots(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("scan kopia repository: %w", err)
+	}
+	now := time.Now
+	if a.Now != nil {
+		now = a.Now
+	}
+	return Build(snaps, pvcs, now()), nil
+}
@@ -0,0 +1,249 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+	"github.com/mitchross/pvc-plumber/internal/config"
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+	"github.com/mitchross/pvc-plumber/internal/v4/labels"
+)
+
+var testNow = time.Date(2026, 6, 3, 12, 0, 0, 0, time.UTC)
+
+func snap(id, user, host string, day int, size int64) kopia.SnapshotInfo {
+	return kopia.SnapshotInfo{
+		ID:        id,
+		Source:    kopia.SnapshotSource{Host: host, UserName: user, Path: "/data"},
+		StartTime: time.Date(2026, 5, day, 3, 0, 0, 0, time.UTC),
+		TotalSize: size,
+	}
+}
+
+func TestBuild(t *testing.T) {
+	snaps := []kopia.SnapshotInfo{
+		// Claimed: karakeep/data's legacy and v4 lineages.
+		snap("a1", "data-backup", "karakeep", 1, 10),
+		snap("a2", "data", "karakeep", 2, 20),
+		// Claimed by a PVC that has since opted out.
+		snap("b1", "scratch", "karakeep", 1, 5),
+		// Orphaned: the PVC is gone; the newest complete snapshot is the
+		// one reported.
+		snap("c1", "old-backup", "immich", 1, 100),
+		snap("c2", "old-backup", "immich", 3, 300),
+		{ID: "c3", Source: kopia.LegacySource("immich", "old"), StartTime: time.Date(2026, 5, 4, 0, 0, 0, 0, time.UTC), TotalSize: 1, IncompleteReason: "checkpoint"},
+		// Orphaned, and not written by VolSync at all.
+		{ID: "d1", Source: kopia.SnapshotSource{Host: "laptop", UserName: "root", Path: "/home"}, StartTime: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), TotalSize: 7},
+		// Suspected rename: immich/library moved to an override, its old
+		// v4 lineage is left behind.
+		snap("e1", "library", "immich", 1, 50),
+		snap("e2", "immich-library", "immich", 2, 60),
+	}
+	pvcs := []PVC{
+		{Namespace: "karakeep", Name: "data", OptedIn: true},
+		{Namespace: "karakeep", Name: "scratch"},
+		{Namespace: "immich", Name: "library", BackupIdentity: "immich-library", OptedIn: true},
+		// Opted in, never backed up.
+		{Namespace: "paperless", Name: "media", OptedIn: true},
+		{Namespace: "paperless", Name: "consume", BackupIdentity: "paperless-consume", OptedIn: true},
+		// Not opted in and not backed up: nothing to report.
+		{Namespace: "paperless", Name: "tmp"},
+	}
+
+	r := Build(snaps, pvcs, testNow)
+
+	if !r.GeneratedAt.Equal(testNow) {
+		t.Errorf("GeneratedAt = %v", r.GeneratedAt)
+	}
+	want := Summary{Lineages: 7, PVCs: 6, OptedInPVCs: 4, OrphanedLineages: 2, OrphanedBytes: 307, PVCsWithoutLineage: 2, SuspectedRenames: 1}
+	if r.Summary != want {
+		t.Errorf("summary = %+v, want %+v", r.Summary, want)
+	}
+
+	if len(r.OrphanedLineages) != 2 {
+		t.Fatalf("orphans: %+v", r.OrphanedLineages)
+	}
+	if o := r.OrphanedLineages[0]; o.Source != "old-backup@immich:/data" || o.SnapshotCount != 3 ||
+		o.LatestSnapshotSize != 300 || o.LatestSnapshotAt.Day() != 3 || !slices.Equal(o.FormerPVCs, []string{"immich/old", "immich/old-backup"}) {
+		t.Errorf("orphan[0] = %+v", o)
+	}
+	if o := r.OrphanedLineages[1]; o.Source != "root@laptop:/home" || o.FormerPVCs != nil {
+		t.Errorf("orphan[1] = %+v", o)
+	}
+
+	var unbacked []string
+	for _, p := range r.PVCsWithoutLineage {
+		unbacked = append(unbacked, p.Namespace+"/"+p.PVC+"="+p.Identity)
+	}
+	if !slices.Equal(unbacked, []string{"paperless/consume=paperless-consume", "paperless/media=paperless/media"}) {
+		t.Errorf("pvcs without lineage: %v", unbacked)
+	}
+	if got := r.PVCsWithoutLineage[1].ExpectedSources; !slices.Equal(got, []string{"media@paperless:/data", "media-backup@paperless:/data"}) {
+		t.Errorf("expected sources: %v", got)
+	}
+
+	rn := r.SuspectedRenames[0]
+	if rn.Source != "library@immich:/data" || rn.Namespace != "immich" || rn.PVC != "library" ||
+		rn.MatchedConvention != ConventionV4 || rn.Identity != "immich-library" ||
+		!slices.Equal(rn.ExpectedSources, []string{"immich-library@immich:/data"}) || rn.LatestSnapshotSize != 50 {
+		t.Errorf("rename = %+v", rn)
+	}
+}
+
+// TestBuild_LegacyRename pins the legacy half of the rename check, and
+// that a renamed PVC with no lineage of its own is reported twice: once
+// as unbacked, once as the rename that explains why.
+func TestBuild_LegacyRename(t *testing.T) {
+	r := Build(
+		[]kopia.SnapshotInfo{snap("a", "data-backup", "myapp", 1, 1)},
+		[]PVC{{Namespace: "myapp", Name: "data", BackupIdentity: "myapp-data", OptedIn: true}},
+		testNow)
+	if len(r.SuspectedRenames) != 1 || r.SuspectedRenames[0].MatchedConvention != ConventionLegacy ||
+		len(r.PVCsWithoutLineage) != 1 || len(r.OrphanedLineages) != 0 {
+		t.Errorf("report: %+v", r)
+	}
+}
+
+func TestBuild_EmptyListsAreNotNull(t *testing.T) {
+	r := Build(nil, nil, testNow)
+	if r.OrphanedLineages == nil || r.PVCsWithoutLineage == nil || r.SuspectedRenames == nil {
+		t.Errorf("nil list in %+v", r)
+	}
+}
+
+func pvc(ns, name string, lbls, annotations map[string]string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Namespace: ns, Name: name, Labels: lbls, Annotations: annotations,
+	}}
+}
+
+func TestKubePVCs(t *testing.T) {
+	c := fake.NewClientBuilder().WithObjects(
+		pvc("myapp", "data", map[string]string{labels.LabelEnabled: "true"},
+			map[string]string{labels.AnnotationBackupIdentity: "myapp-data"}),
+		pvc("myapp", "legacy", map[string]string{labels.LegacyLabelBackup: "daily"}, nil),
+		pvc("myapp", "exempt", map[string]string{labels.LabelEnabled: "true", labels.LegacyLabelBackupExempt: "true"},
+			map[string]string{labels.LegacyAnnotationBackupExemptReasonFQ: "scratch"}),
+		pvc("kube-system", "etcd", nil, nil),
+	).Build()
+
+	got, err := KubePVCs{Reader: c}.ListPVCs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	byKey := map[string]PVC{}
+	for _, p := range got {
+		byKey[p.Namespace+"/"+p.Name] = p
+	}
+	if len(byKey) != 4 {
+		t.Fatalf("listed %v", byKey)
+	}
+	if p := byKey["myapp/data"]; !p.OptedIn || p.BackupIdentity != "myapp-data" {
+		t.Errorf("data: %+v", p)
+	}
+	if !byKey["myapp/legacy"].OptedIn || byKey["myapp/exempt"].OptedIn || byKey["kube-system/etcd"].OptedIn {
+		t.Errorf("opt-in: %+v", byKey)
+	}
+}
+
+type failingLister struct{}
+
+func (failingLister) ListPVCs(context.Context) ([]PVC, error) { return nil, errors.New("forbidden") }
+
+// TestAuditor_FixtureRepository runs the whole path over the native
+// reader's fixture repository through a kopia-fs NewReader.
+func TestAuditor_FixtureRepository(t *testing.T) {
+	reader, err := NewReader(&config.Config{
+		BackendType:   backend.TypeKopiaFS,
+		KopiaFSPath:   "../kopia/native/testdata/repo",
+		KopiaPassword: "pvc-plumber-fixture",
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithObjects(
+		pvc("myapp", "data", map[string]string{labels.LabelEnabled: "true"}, nil),
+	).Build()
+	a := &Auditor{PVCs: KubePVCs{Reader: c}, Lineages: reader, Now: func() time.Time { return testNow }}
+
+	r, err := a.Report(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var orphans []string
+	for _, o := range r.OrphanedLineages {
+		orphans = append(orphans, o.Source)
+	}
+	if !slices.Equal(orphans, []string{"cache-backup@myapp:/data", "root@builder:/srv"}) || r.Summary.Lineages != 3 ||
+		len(r.PVCsWithoutLineage) != 0 {
+		t.Errorf("orphans %v, report %+v", orphans, r)
+	}
+
+	a.PVCs = failingLister{}
+	if _, err := a.Report(context.Background()); err == nil {
+		t.Error("PVC listing error not returned")
+	}
+}
+
+func TestNewReader_RequiresKopiaBackend(t *testing.T) {
+	if _, err := NewReader(&config.Config{BackendType: backend.TypeS3}, slog.New(slog.NewTextHandler(io.Discard, nil))); err == nil {
+		t.Error("s3 backend accepted")
+	}
+}
+
+func volsyncObject(kind, ns, name string, spec map[string]any) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]any{"spec": spec}}
+	u.SetGroupVersionKind(schema.GroupVersionKind{Group: "volsync.backube", Version: "v1alpha1", Kind: kind})
+	u.SetNamespace(ns)
+	u.SetName(name)
+	return u
+}
+
+func TestKubeReferences(t *testing.T) {
+	c := fake.NewClientBuilder().WithObjects(
+		pvc("myapp", "data", nil, map[string]string{labels.AnnotationBackupIdentity: "myapp-data"}),
+		pvc("myapp", "cache", nil, nil),
+		volsyncObject("ReplicationSource", "immich", "library", map[string]any{
+			"kopia": map[string]any{"username": "library", "hostname": "immich"}}),
+		volsyncObject("ReplicationSource", "legacy", "db-backup", map[string]any{"sourcePVC": "db", "kopia": map[string]any{}}),
+		volsyncObject("ReplicationDestination", "restore", "photos-dst", map[string]any{
+			"kopia": map[string]any{"username": "photos-dst", "sourceIdentity": map[string]any{
+				"sourceName": "photos", "sourceNamespace": "immich", "sourcePVCName": "photos"}}}),
+	).Build()
+
+	refs, err := KubeReferences{Reader: c}.ReferencedSources(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for src, want := range map[string]string{
+		"myapp-data@myapp:/data":     "PVC myapp/data (backup-identity myapp-data)",
+		"cache@myapp:/data":          "PVC myapp/cache",
+		"cache-backup@myapp:/data":   "PVC myapp/cache",
+		"library@immich:/data":       "ReplicationSource immich/library",
+		"db-backup@legacy:/data":     "ReplicationSource legacy/db-backup",
+		"db@legacy:/data":            "ReplicationSource legacy/db-backup",
+		"photos-backup@immich:/data": "ReplicationDestination restore/photos-dst",
+	} {
+		s, _ := kopia.ParseSnapshotSource(src)
+		if refs[s] != want {
+			t.Errorf("%s: got %q, want %q", src, refs[s], want)
+		}
+	}
+	// An override replaces the defaults; myapp/data's legacy lineage is
+	// not referenced by anything.
+	if reason, ok := refs[kopia.LegacySource("myapp", "data")]; ok {
+		t.Errorf("legacy lineage referenced by %q", reason)
+	}
+}
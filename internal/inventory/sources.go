@@ -0,0 +1,183 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+	"github.com/mitchross/pvc-plumber/internal/config"
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+	"github.com/mitchross/pvc-plumber/internal/kopia/native"
+	"github.com/mitchross/pvc-plumber/internal/v4/labels"
+	"github.com/mitchross/pvc-plumber/internal/v4/naming"
+)
+
+// VolSync list GVKs for KubeReferences. Local, like internal/v4/adopt's,
+// so the unstructured client needs no VolSync API module.
+var volsyncListGVKs = []schema.GroupVersionKind{
+	{Group: "volsync.backube", Version: "v1alpha1", Kind: "ReplicationSourceList"},
+	{Group: "volsync.backube", Version: "v1alpha1", Kind: "ReplicationDestinationList"},
+}
+
+// KubePVCs lists PVCs from the apiserver, in every namespace. System
+// namespaces are included on purpose: the reconciler skips them, but a
+// lineage written from one before it was declared a system namespace
+// still belongs to a live PVC and must not be reported orphaned.
+type KubePVCs struct {
+	Reader client.Reader
+}
+
+// ListPVCs implements PVCLister.
+func (k KubePVCs) ListPVCs(ctx context.Context) ([]PVC, error) {
+	var list corev1.PersistentVolumeClaimList
+	if err := k.Reader.List(ctx, &list); err != nil {
+		return nil, err
+	}
+	out := make([]PVC, 0, len(list.Items))
+	for i := range list.Items {
+		pvc := &list.Items[i]
+		spec := labels.Parse(pvc.GetLabels(), pvc.GetAnnotations())
+		out = append(out, PVC{
+			Namespace:      pvc.Namespace,
+			Name:           pvc.Name,
+			BackupIdentity: spec.BackupIdentity,
+			// Same rule as the reconciler's identity index (indexable).
+			OptedIn: spec.ExemptKind == labels.ExemptNone && (spec.Enabled || spec.Origin != labels.OriginNone),
+		})
+	}
+	return out, nil
+}
+
+// KubeReferences is the pruner's view of what live cluster state still
+// points at (kopia.ReferenceLister). It is deliberately wider than the
+// inventory's notion of "claimed":
+//
+//   - every PVC, opted in or not, claims the sources its query would
+//     read (an override, or the v4 and legacy defaults);
+//   - every ReplicationSource and ReplicationDestination, whoever owns
+//     it, claims the kopia username/hostname in its spec. One with no
+//     username is written by the mover under a default we don't resolve
+//     here, so it claims both its own name and, when it names one, its
+//     source PVC's default sources. A ReplicationDestination also
+//     claims the lineage its sourceIdentity restores from.
+//
+// A false "referenced" only costs a refused prune; a missed one costs a
+// lineage somebody still restores from.
+type KubeReferences struct {
+	Reader client.Reader
+}
+
+// ReferencedSources implements kopia.ReferenceLister.
+func (k KubeReferences) ReferencedSources(ctx context.Context) (map[kopia.SnapshotSource]string, error) {
+	refs := map[kopia.SnapshotSource]string{}
+	claim := func(src kopia.SnapshotSource, reason string) {
+		if _, ok := refs[src]; !ok {
+			refs[src] = reason
+		}
+	}
+
+	var pvcs corev1.PersistentVolumeClaimList
+	if err := k.Reader.List(ctx, &pvcs); err != nil {
+		return nil, fmt.Errorf("list PVCs: %w", err)
+	}
+	for i := range pvcs.Items {
+		pvc := &pvcs.Items[i]
+		spec := labels.Parse(pvc.GetLabels(), pvc.GetAnnotations())
+		reason := "PVC " + pvc.Namespace + "/" + pvc.Name
+		if spec.BackupIdentity != "" {
+			reason += " (backup-identity " + spec.BackupIdentity + ")"
+		}
+		for _, src := range kopia.QuerySources(backend.NewQuery(pvc.Namespace, pvc.Name, spec.BackupIdentity)) {
+			claim(src, reason)
+		}
+	}
+
+	for _, gvk := range volsyncListGVKs {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk)
+		if err := k.Reader.List(ctx, list); err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, fmt.Errorf("list %s: %w", gvk.Kind, err)
+		}
+		kind := gvk.Kind[:len(gvk.Kind)-len("List")]
+		for i := range list.Items {
+			obj := &list.Items[i]
+			ns := obj.GetNamespace()
+			reason := kind + " " + ns + "/" + obj.GetName()
+			user, _, _ := unstructured.NestedString(obj.Object, "spec", "kopia", "username")
+			host, _, _ := unstructured.NestedString(obj.Object, "spec", "kopia", "hostname")
+			if user != "" {
+				claim(kopia.V4Source(ns, naming.KopiaIdentity{Username: user, Hostname: host}), reason)
+				continue
+			}
+			claim(kopia.V4Source(ns, naming.KopiaIdentity{Username: obj.GetName()}), reason)
+			if pvc, _, _ := unstructured.NestedString(obj.Object, "spec", "sourcePVC"); pvc != "" {
+				for _, src := range kopia.QuerySources(backend.NewQuery(ns, pvc, "")) {
+					claim(src, reason)
+				}
+			}
+		}
+		// A ReplicationDestination's sourceIdentity names the lineage it
+		// restores from, whatever its own username says.
+		for i := range list.Items {
+			obj := &list.Items[i]
+			pvc, _, _ := unstructured.NestedString(obj.Object, "spec", "kopia", "sourceIdentity", "sourcePVCName")
+			if pvc == "" {
+				continue
+			}
+			ns, _, _ := unstructured.NestedString(obj.Object, "spec", "kopia", "sourceIdentity", "sourceNamespace")
+			if ns == "" {
+				ns = obj.GetNamespace()
+			}
+			for _, src := range kopia.QuerySources(backend.NewQuery(ns, pvc, "")) {
+				claim(src, kind+" "+obj.GetNamespace()+"/"+obj.GetName())
+			}
+		}
+	}
+	return refs, nil
+}
+
+// NewReader builds the repository scan for an inventory from the kopia
+// settings in cfg. It is always the native reader, whatever KOPIA_READER
+// says: it is read-only, needs no `kopia repository connect` (which
+// writes a config file and would have to run at startup), and opens the
+// repository lazily on the first scan — so wiring an inventory into the
+// v4 operator, which must start while the backup infrastructure is
+// unreachable, adds no startup dependency. The concrete reader is
+// returned because it also lists single lineages (ListSnapshots), which
+// the reconciler's point-in-time restore preview uses.
+func NewReader(cfg *config.Config, logger *slog.Logger) (*native.Client, error) {
+	switch cfg.BackendType {
+	case backend.TypeKopiaS3:
+		var creds kopia.CredentialsSource
+		if cfg.KopiaCredentialsPath != "" {
+			creds = kopia.NewDirCredentialsSource(cfg.KopiaCredentialsPath)
+		} else {
+			creds = kopia.NewStaticCredentialsSource(cfg.KopiaPassword, cfg.KopiaS3AccessKey, cfg.KopiaS3SecretKey)
+		}
+		nc, err := native.NewS3Client(native.S3Options{
+			Endpoint:   cfg.KopiaS3Endpoint,
+			Bucket:     cfg.KopiaS3Bucket,
+			DisableTLS: cfg.KopiaS3DisableTLS,
+		}, creds, logger, native.Options{ConnectTimeout: cfg.KopiaConnectTimeout})
+		if err != nil {
+			return nil, fmt.Errorf("create native kopia reader: %w", err)
+		}
+		return nc, nil
+	case backend.TypeKopiaFS:
+		return native.NewClient(native.DirStore{Dir: cfg.KopiaFSPath},
+			kopia.NewPasswordCredentialsSource(cfg.KopiaPassword), logger,
+			native.Options{ConnectTimeout: cfg.KopiaConnectTimeout, BackendType: backend.TypeKopiaFS}), nil
+	default:
+		return nil, fmt.Errorf("inventory needs a kopia backend (BACKEND_TYPE=kopia-s3 or kopia-fs), got %q", cfg.BackendType)
+	}
+}
@@ -0,0 +1,359 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+	"github.com/mitchross/pvc-plumber/internal/v4/labels"
+)
+
+// IdentityUsage is the repository footprint of one backup identity: the
+// union of the lineages its PVC reads from (kopia.QuerySources), keyed
+// by the v4 source the PVC writes, or a single unclaimed lineage, which
+// is reported under its own source. Keying on the rendered source keeps
+// the same override in two namespaces apart: kopia records each under
+// its own namespace's hostname.
+//
+// All sizes are logical (kopia's stats.totalSize). Kopia deduplicates
+// within and across lineages, so physical usage cannot be attributed to
+// an identity at all; LatestSnapshotBytes is the best per-identity
+// proxy for what it pins, RetainedBytes an upper bound that counts
+// unchanged data once per retained snapshot.
+type IdentityUsage struct {
+	Identity  string `json:"identity"`
+	Namespace string `json:"namespace"`
+	// PVCs are the namespace/pvc keys writing the identity's source.
+	// Empty for an unclaimed lineage; more than one only for an identity
+	// collision, which /audit reports separately.
+	PVCs    []string `json:"pvcs"`
+	Sources []string `json:"sources"`
+	// Orphaned marks a lineage no existing PVC claims (see Report's
+	// orphaned_lineages and suspected_renames).
+	Orphaned            bool      `json:"orphaned,omitempty"`
+	SnapshotCount       int       `json:"snapshot_count"`
+	LatestSnapshotAt    time.Time `json:"latest_snapshot_at,omitzero"`
+	LatestSnapshotBytes int64     `json:"latest_snapshot_bytes"`
+	RetainedBytes       int64     `json:"retained_bytes"`
+}
+
+// NamespaceUsage sums a namespace's identities, orphaned lineages
+// included: they are still on the bill.
+type NamespaceUsage struct {
+	Namespace           string `json:"namespace"`
+	Identities          int    `json:"identities"`
+	SnapshotCount       int    `json:"snapshot_count"`
+	LatestSnapshotBytes int64  `json:"latest_snapshot_bytes"`
+	RetainedBytes       int64  `json:"retained_bytes"`
+	// SoftQuota is the namespace's pvc-plumber.io/backup-soft-quota
+	// annotation as written, SoftQuotaBytes its parsed value. The quota
+	// is compared with LatestSnapshotBytes, not RetainedBytes, so a
+	// static volume with a long retention does not trip it.
+	SoftQuota      string `json:"soft_quota,omitempty"`
+	SoftQuotaBytes int64  `json:"soft_quota_bytes,omitempty"`
+	OverSoftQuota  bool   `json:"over_soft_quota,omitempty"`
+	// SoftQuotaError is set when the annotation does not parse; the
+	// quota is then ignored.
+	SoftQuotaError string `json:"soft_quota_error,omitempty"`
+}
+
+// UsageSummary totals the report. NamespacesOverSoftQuota counts
+// namespaces whose quota parsed and is exceeded.
+type UsageSummary struct {
+	Identities              int   `json:"identities"`
+	Namespaces              int   `json:"namespaces"`
+	Snapshots               int   `json:"snapshots"`
+	LatestSnapshotBytes     int64 `json:"latest_snapshot_bytes"`
+	RetainedBytes           int64 `json:"retained_bytes"`
+	NamespacesOverSoftQuota int   `json:"namespaces_over_soft_quota"`
+}
+
+// UsageReport is the /audit/usage body. Both lists are always present
+// and sorted: namespaces by name, identities by namespace then identity.
+type UsageReport struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	Summary     UsageSummary     `json:"summary"`
+	Namespaces  []NamespaceUsage `json:"namespaces"`
+	Identities  []IdentityUsage  `json:"identities"`
+}
+
+// BuildUsage attributes snaps (a whole-repository scan) to the identities
+// of pvcs and sums them per namespace. quotas maps a namespace to its raw
+// soft-quota annotation; a quota on a namespace with no backups is
+// reported with zero usage so a typo in it still surfaces.
+//
+// An unclaimed lineage is counted in the namespace kopia.PVCKeys places
+// it in, falling back to its hostname — under both naming conventions
+// that is the namespace it was written from.
+func BuildUsage(snaps []kopia.SnapshotInfo, pvcs []PVC, quotas map[string]string, now time.Time) UsageReport {
+	bySource := make(map[kopia.SnapshotSource][]kopia.SnapshotInfo)
+	for _, s := range snaps {
+		bySource[s.Source] = append(bySource[s.Source], s)
+	}
+
+	// Sorted so a source two colliding PVCs both claim lands on the same
+	// identity every time.
+	pvcs = append([]PVC(nil), pvcs...)
+	sort.Slice(pvcs, func(i, j int) bool {
+		return pvcs[i].Namespace+"/"+pvcs[i].Name < pvcs[j].Namespace+"/"+pvcs[j].Name
+	})
+	type group struct {
+		usage IdentityUsage
+		snaps []kopia.SnapshotInfo
+	}
+	groups := make(map[string]*group)
+	owner := make(map[kopia.SnapshotSource]string)
+	for _, p := range pvcs {
+		q := p.query()
+		key := kopia.V4Source(q.Namespace, q.Identity).String()
+		g, ok := groups[key]
+		if !ok {
+			g = &group{usage: IdentityUsage{Identity: key, Namespace: p.Namespace, Sources: []string{}}}
+			groups[key] = g
+		}
+		g.usage.PVCs = append(g.usage.PVCs, p.Namespace+"/"+p.Name)
+		for _, src := range kopia.QuerySources(q) {
+			if _, taken := owner[src]; !taken {
+				owner[src] = key
+			}
+		}
+	}
+	for src, lineage := range bySource {
+		key, ok := owner[src]
+		if !ok {
+			key = src.String()
+			ns := src.Host
+			if keys := kopia.PVCKeys(src); len(keys) > 0 {
+				ns, _, _ = strings.Cut(keys[0], "/")
+			}
+			groups[key] = &group{usage: IdentityUsage{Identity: key, Namespace: ns, PVCs: []string{}, Sources: []string{}, Orphaned: true}}
+		}
+		g := groups[key]
+		g.usage.Sources = append(g.usage.Sources, src.String())
+		g.snaps = append(g.snaps, lineage...)
+	}
+
+	report := UsageReport{GeneratedAt: now, Namespaces: []NamespaceUsage{}, Identities: []IdentityUsage{}}
+	byNS := make(map[string]*NamespaceUsage)
+	nsUsage := func(ns string) *NamespaceUsage {
+		n, ok := byNS[ns]
+		if !ok {
+			n = &NamespaceUsage{Namespace: ns}
+			byNS[ns] = n
+		}
+		return n
+	}
+	for _, g := range groups {
+		if len(g.snaps) == 0 {
+			// A PVC with no backups yet pins nothing; the inventory's
+			// pvcs_without_lineage is where it belongs.
+			continue
+		}
+		u := g.usage
+		sum := kopia.Summarize(g.snaps)
+		u.SnapshotCount = sum.Count
+		u.LatestSnapshotAt = sum.LatestAt
+		u.LatestSnapshotBytes = sum.LatestSize
+		for _, s := range g.snaps {
+			u.RetainedBytes += s.TotalSize
+		}
+		sort.Strings(u.Sources)
+		report.Identities = append(report.Identities, u)
+
+		n := nsUsage(u.Namespace)
+		n.Identities++
+		n.SnapshotCount += u.SnapshotCount
+		n.LatestSnapshotBytes += u.LatestSnapshotBytes
+		n.RetainedBytes += u.RetainedBytes
+	}
+	for ns, raw := range quotas {
+		n := nsUsage(ns)
+		n.SoftQuota = raw
+		q, err := resource.ParseQuantity(raw)
+		if err != nil || q.Sign() <= 0 {
+			n.SoftQuotaError = fmt.Sprintf("%s=%q is not a positive quantity; ignored", labels.NamespaceBackupSoftQuotaAnnotation, raw)
+			continue
+		}
+		n.SoftQuotaBytes = q.Value()
+		n.OverSoftQuota = n.LatestSnapshotBytes > n.SoftQuotaBytes
+	}
+
+	for _, n := range byNS {
+		report.Namespaces = append(report.Namespaces, *n)
+		report.Summary.Snapshots += n.SnapshotCount
+		report.Summary.LatestSnapshotBytes += n.LatestSnapshotBytes
+		report.Summary.RetainedBytes += n.RetainedBytes
+		if n.OverSoftQuota {
+			report.Summary.NamespacesOverSoftQuota++
+		}
+	}
+	sort.Slice(report.Namespaces, func(i, j int) bool {
+		return report.Namespaces[i].Namespace < report.Namespaces[j].Namespace
+	})
+	sort.Slice(report.Identities, func(i, j int) bool {
+		a, b := report.Identities[i], report.Identities[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Identity < b.Identity
+	})
+	report.Summary.Identities = len(report.Identities)
+	report.Summary.Namespaces = len(report.Namespaces)
+	return report
+}
+
+// NamespaceNotes returns the /audit note each namespace's PVC entries
+// should carry: a soft quota that is exceeded or does not parse.
+// Namespaces within their quota, or without one, are absent.
+func (r UsageReport) NamespaceNotes() map[string]string {
+	out := make(map[string]string)
+	for _, n := range r.Namespaces {
+		switch {
+		case n.SoftQuotaError != "":
+			out[n.Namespace] = "namespace backup soft quota: " + n.SoftQuotaError
+		case n.OverSoftQuota:
+			out[n.Namespace] = fmt.Sprintf("WARNING: namespace backups use %s (latest snapshots, logical), over the %s=%s soft quota",
+				resource.NewQuantity(n.LatestSnapshotBytes, resource.BinarySI), labels.NamespaceBackupSoftQuotaAnnotation, n.SoftQuota)
+		}
+	}
+	return out
+}
+
+// QuotaLister reports each namespace's raw soft-quota annotation.
+type QuotaLister interface {
+	SoftQuotas(ctx context.Context) (map[string]string, error)
+}
+
+// KubeQuotas reads labels.NamespaceBackupSoftQuotaAnnotation from every
+// Namespace.
+type KubeQuotas struct {
+	Reader client.Reader
+}
+
+// SoftQuotas implements QuotaLister.
+func (k KubeQuotas) SoftQuotas(ctx context.Context) (map[string]string, error) {
+	var list corev1.NamespaceList
+	if err := k.Reader.List(ctx, &list); err != nil {
+		return nil, err
+	}
+	out := make(map[string]string)
+	for i := range list.Items {
+		if raw, ok := list.Items[i].Annotations[labels.NamespaceBackupSoftQuotaAnnotation]; ok {
+			out[list.Items[i].Name] = raw
+		}
+	}
+	return out, nil
+}
+
+// UsageTracker recomputes the UsageReport on a fixed cadence and holds
+// the latest one for /audit/usage and the usage metrics, so neither
+// scans the repository per request. A failed refresh keeps the previous
+// report (and its GeneratedAt, which then ages) rather than clearing it.
+type UsageTracker struct {
+	PVCs     PVCLister
+	Quotas   QuotaLister
+	Lineages kopia.SnapshotLister
+	// OnReport, when set, is called with every new report — the
+	// operator uses it to push NamespaceNotes into the /audit store.
+	OnReport func(UsageReport)
+	Logger   *slog.Logger
+	// Now defaults to time.Now.
+	Now func() time.Time
+
+	mu          sync.Mutex
+	report      *UsageReport
+	lastErr     string
+	refreshErrs int64
+}
+
+// Refresh lists PVCs and quotas, scans the repository and replaces the
+// held report. As with Auditor.Report, PVCs are listed first.
+func (t *UsageTracker) Refresh(ctx context.Context) error {
+	report, err := t.build(ctx)
+	t.mu.Lock()
+	if err != nil {
+		t.lastErr = err.Error()
+		t.refreshErrs++
+		t.mu.Unlock()
+		return err
+	}
+	t.report, t.lastErr = &report, ""
+	t.mu.Unlock()
+	if t.OnReport != nil {
+		t.OnReport(report)
+	}
+	return nil
+}
+
+func (t *UsageTracker) build(ctx context.Context) (UsageReport, error) {
+	pvcs, err := t.PVCs.ListPVCs(ctx)
+	if err != nil {
+		return UsageReport{}, fmt.Errorf("list PVCs: %w", err)
+	}
+	quotas, err := t.Quotas.SoftQuotas(ctx)
+	if err != nil {
+		return UsageReport{}, fmt.Errorf("list namespace quotas: %w", err)
+	}
+	snaps, err := t.Lineages.ListAllSnapshots(ctx)
+	if err != nil {
+		return UsageReport{}, fmt.Errorf("scan kopia repository: %w", err)
+	}
+	now := time.Now
+	if t.Now != nil {
+		now = t.Now
+	}
+	return BuildUsage(snaps, pvcs, quotas, now()), nil
+}
+
+// UsageStatus is what a UsageTracker holds: the latest report (nil
+// until the first refresh succeeds), the last refresh error ("" after a
+// success) and the number of failed refreshes.
+type UsageStatus struct {
+	Report        *UsageReport
+	LastError     string
+	RefreshErrors int64
+}
+
+// Status returns a copy of the tracker's state.
+func (t *UsageTracker) Status() UsageStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st := UsageStatus{LastError: t.lastErr, RefreshErrors: t.refreshErrs}
+	if t.report != nil {
+		r := *t.report
+		st.Report = &r
+	}
+	return st
+}
+
+// Run refreshes immediately and then every interval until ctx is done.
+// Each refresh is bounded by timeout.
+func (t *UsageTracker) Run(ctx context.Context, interval, timeout time.Duration) {
+	refresh := func() {
+		callCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		if err := t.Refresh(callCtx); err != nil && t.Logger != nil {
+			t.Logger.Warn("backup usage refresh failed; keeping previous report", "error", err)
+		}
+	}
+	refresh()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refresh()
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package inventory
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+	"github.com/mitchross/pvc-plumber/internal/v4/labels"
+)
+
+func TestBuildUsage(t *testing.T) {
+	snaps := []kopia.SnapshotInfo{
+		// karakeep/data: legacy and v4 lineages merge into one identity.
+		snap("a1", "data-backup", "karakeep", 1, 10),
+		snap("a2", "data", "karakeep", 2, 20),
+		// immich/library on an override identity.
+		snap("b1", "immich-library", "immich", 1, 500),
+		snap("b2", "immich-library", "immich", 2, 600),
+		{ID: "b3", Source: kopia.SnapshotSource{Host: "immich", UserName: "immich-library", Path: "/data"},
+			StartTime: time.Date(2026, 5, 3, 0, 0, 0, 0, time.UTC), TotalSize: 1, IncompleteReason: "checkpoint"},
+		// Orphaned legacy lineage, still billed to immich.
+		snap("c1", "old-backup", "immich", 1, 100),
+	}
+	pvcs := []PVC{
+		{Namespace: "karakeep", Name: "data", OptedIn: true},
+		{Namespace: "immich", Name: "library", BackupIdentity: "immich-library", OptedIn: true},
+		// No backups yet: not reported.
+		{Namespace: "paperless", Name: "media", OptedIn: true},
+	}
+	quotas := map[string]string{"immich": "512", "karakeep": "1Gi", "paperless": "lots"}
+
+	r := BuildUsage(snaps, pvcs, quotas, testNow)
+
+	var ids []string
+	for _, u := range r.Identities {
+		ids = append(ids, u.Identity)
+	}
+	if !slices.Equal(ids, []string{"immich-library@immich:/data", "old-backup@immich:/data", "data@karakeep:/data"}) {
+		t.Fatalf("identities %v", ids)
+	}
+	lib, orphan, kk := r.Identities[0], r.Identities[1], r.Identities[2]
+	// Latest is the newest complete snapshot; retained counts every manifest.
+	if lib.SnapshotCount != 3 || lib.LatestSnapshotBytes != 600 || lib.RetainedBytes != 1101 ||
+		!slices.Equal(lib.PVCs, []string{"immich/library"}) || lib.Orphaned {
+		t.Errorf("immich-library: %+v", lib)
+	}
+	if !orphan.Orphaned || orphan.Namespace != "immich" || len(orphan.PVCs) != 0 || orphan.RetainedBytes != 100 {
+		t.Errorf("orphan: %+v", orphan)
+	}
+	if !slices.Equal(kk.Sources, []string{"data-backup@karakeep:/data", "data@karakeep:/data"}) ||
+		kk.LatestSnapshotBytes != 20 || kk.RetainedBytes != 30 {
+		t.Errorf("karakeep/data: %+v", kk)
+	}
+
+	ns := map[string]NamespaceUsage{}
+	for _, n := range r.Namespaces {
+		ns[n.Namespace] = n
+	}
+	if im := ns["immich"]; im.Identities != 2 || im.LatestSnapshotBytes != 700 || im.SoftQuotaBytes != 512 || !im.OverSoftQuota {
+		t.Errorf("immich: %+v", im)
+	}
+	if k := ns["karakeep"]; k.OverSoftQuota || k.SoftQuotaBytes != 1<<30 {
+		t.Errorf("karakeep: %+v", k)
+	}
+	// A quota on a namespace with no backups is still reported, so a
+	// malformed one surfaces.
+	if p := ns["paperless"]; p.SoftQuotaError == "" || p.Identities != 0 {
+		t.Errorf("paperless: %+v", p)
+	}
+	if r.Summary.Identities != 3 || r.Summary.Namespaces != 3 || r.Summary.Snapshots != 6 ||
+		r.Summary.LatestSnapshotBytes != 720 || r.Summary.NamespacesOverSoftQuota != 1 {
+		t.Errorf("summary: %+v", r.Summary)
+	}
+
+	notes := r.NamespaceNotes()
+	if len(notes) != 2 || !strings.Contains(notes["immich"], "over the "+labels.NamespaceBackupSoftQuotaAnnotation+"=512 soft quota") ||
+		!strings.Contains(notes["paperless"], "not a positive quantity") {
+		t.Errorf("notes: %v", notes)
+	}
+}
+
+// The same override in two namespaces writes two kopia sources, one per
+// namespace hostname, so each namespace is billed only its own.
+func TestBuildUsage_SameOverrideInTwoNamespaces(t *testing.T) {
+	snaps := []kopia.SnapshotInfo{
+		snap("a1", "shared", "team-a", 1, 10),
+		snap("b1", "shared", "team-b", 1, 300),
+	}
+	pvcs := []PVC{
+		{Namespace: "team-a", Name: "data", BackupIdentity: "shared", OptedIn: true},
+		{Namespace: "team-b", Name: "data", BackupIdentity: "shared", OptedIn: true},
+	}
+	r := BuildUsage(snaps, pvcs, nil, testNow)
+	if len(r.Identities) != 2 {
+		t.Fatalf("identities: %+v", r.Identities)
+	}
+	a, b := r.Identities[0], r.Identities[1]
+	if a.Identity != "shared@team-a:/data" || !slices.Equal(a.PVCs, []string{"team-a/data"}) || a.LatestSnapshotBytes != 10 {
+		t.Errorf("team-a: %+v", a)
+	}
+	if b.Identity != "shared@team-b:/data" || !slices.Equal(b.PVCs, []string{"team-b/data"}) || b.Namespace != "team-b" || b.LatestSnapshotBytes != 300 {
+		t.Errorf("team-b: %+v", b)
+	}
+}
+
+func TestBuildUsage_EmptyListsAreNotNull(t *testing.T) {
+	r := BuildUsage(nil, nil, nil, testNow)
+	if r.Namespaces == nil || r.Identities == nil {
+		t.Errorf("nil lists: %+v", r)
+	}
+}
+
+func TestKubeQuotas(t *testing.T) {
+	c := fake.NewClientBuilder().WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "immich",
+			Annotations: map[string]string{labels.NamespaceBackupSoftQuotaAnnotation: "200Gi"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "karakeep"}},
+	).Build()
+	got, err := KubeQuotas{Reader: c}.SoftQuotas(context.Background())
+	if err != nil || len(got) != 1 || got["immich"] != "200Gi" {
+		t.Errorf("SoftQuotas = %v, %v", got, err)
+	}
+}
+
+type staticSnapshots []kopia.SnapshotInfo
+
+func (s staticSnapshots) ListAllSnapshots(context.Context) ([]kopia.SnapshotInfo, error) {
+	return s, nil
+}
+
+type staticQuotas map[string]string
+
+func (q staticQuotas) SoftQuotas(context.Context) (map[string]string, error) { return q, nil }
+
+// A failed refresh keeps the previous report and counts the failure;
+// OnReport only sees successful ones.
+func TestUsageTracker_Refresh(t *testing.T) {
+	var pushed []map[string]string
+	tr := &UsageTracker{
+		PVCs:     staticPVCs{{Namespace: "immich", Name: "library", OptedIn: true}},
+		Quotas:   staticQuotas{"immich": "1"},
+		Lineages: staticSnapshots{snap("a1", "library", "immich", 1, 10)},
+		OnReport: func(r UsageReport) { pushed = append(pushed, r.NamespaceNotes()) },
+		Now:      func() time.Time { return testNow },
+	}
+	if st := tr.Status(); st.Report != nil {
+		t.Fatalf("report before first refresh: %+v", st)
+	}
+	if err := tr.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	tr.PVCs = failingLister{}
+	if err := tr.Refresh(context.Background()); err == nil {
+		t.Fatal("PVC listing error not returned")
+	}
+	st := tr.Status()
+	if st.Report == nil || st.Report.Summary.Identities != 1 || st.RefreshErrors != 1 || !strings.Contains(st.LastError, "forbidden") {
+		t.Errorf("status: %+v", st)
+	}
+	if len(pushed) != 1 || pushed[0]["immich"] == "" {
+		t.Errorf("OnReport calls: %v", pushed)
+	}
+}
+
+type staticPVCs []PVC
+
+func (p staticPVCs) ListPVCs(context.Context) ([]PVC, error) { return p, nil }
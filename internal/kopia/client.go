@@ -2,7 +2,6 @@ package kopia
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -46,6 +45,7 @@ const (
 	kopiaCmdConnect    = "connect"
 	kopiaCmdStatus     = "status"
 	kopiaCmdS3         = "s3"
+	kopiaCmdFilesystem = "filesystem"
 )
 
 // CredentialsSource hides where kopia credentials come from. v3.1.0 defaults
@@ -157,6 +157,27 @@ func (s *StaticCredentialsSource) Load() (Creds, error) {
 	return s.creds, nil
 }
 
+// PasswordCredentialsSource returns only a repository password — all a
+// filesystem repository (BACKEND_TYPE=kopia-fs) needs, where there are no
+// S3 keys to carry.
+type PasswordCredentialsSource struct {
+	password string
+}
+
+// NewPasswordCredentialsSource constructs a CredentialsSource for a
+// filesystem repository. Load reports ErrCredentialsNotReady when the
+// password is empty.
+func NewPasswordCredentialsSource(password string) *PasswordCredentialsSource {
+	return &PasswordCredentialsSource{password: password}
+}
+
+func (p *PasswordCredentialsSource) Load() (Creds, error) {
+	if p.password == "" {
+		return Creds{}, fmt.Errorf("%w: repository password is empty", ErrCredentialsNotReady)
+	}
+	return Creds{Password: p.password}, nil
+}
+
 // S3Config bundles the static (non-credential) inputs `kopia repository
 // connect s3` needs. v3.1.0 split credentials out of this struct — they're
 // loaded lazily through CredentialsSource on each call so a Secret update
@@ -168,6 +189,12 @@ type S3Config struct {
 	DisableTLS bool
 }
 
+// FilesystemConfig locates a filesystem repository for BACKEND_TYPE=kopia-fs:
+// the directory `kopia repository create filesystem --path` was pointed at.
+type FilesystemConfig struct {
+	Path string
+}
+
 // Client wraps the kopia CLI for backup-existence checks against an S3-backed
 // Kopia repository. v3.1.0 lazy-loads credentials from a CredentialsSource on
 // every subprocess invocation, so a Secret update via ESO is observed
@@ -175,16 +202,28 @@ type S3Config struct {
 // the pod at startup.
 type Client struct {
 	cfg            S3Config
+	fs             *FilesystemConfig // non-nil for a filesystem repository
+	backendType    string            // CheckResult.Backend label
 	creds          CredentialsSource
 	connectTimeout time.Duration
 	logger         *slog.Logger
 	executor       CommandExecutor
 
+	// username and hostname, when set, override the client identity
+	// `kopia repository connect` records (see NewMaintainer).
+	username string
+	hostname string
+
 	// connected is set true once Connect() has succeeded. HealthCheck
 	// requires this to be true before it spawns `kopia repository status`
 	// — there's no point probing the repo over a never-connected client.
 	mu        sync.RWMutex
 	connected bool
+
+	// connectMu serializes Connect/Reconnect so a credential rotation
+	// racing a startup connect can't interleave two `kopia repository
+	// connect` runs writing the same on-disk config.
+	connectMu sync.Mutex
 }
 
 // Options bundles the optional knobs NewClient accepts so the constructor
@@ -199,6 +238,12 @@ type Options struct {
 	// backoff on a CrashLoopBackOff would be longer than this; we want to
 	// fail fast and let the manager's own restart loop drive recovery.
 	ConnectTimeout time.Duration
+
+	// ConfigFile, when set, is passed to every kopia invocation as
+	// --config-file, so the client keeps a connection of its own instead
+	// of sharing the default KOPIA_CONFIG_PATH with the other clients in
+	// the process.
+	ConfigFile string
 }
 
 // NewClient creates a new Kopia client. creds may be nil for tests that
@@ -211,21 +256,60 @@ func NewClient(cfg S3Config, creds CredentialsSource, logger *slog.Logger, opts
 	}
 	return &Client{
 		cfg:            cfg,
+		backendType:    backend.TypeKopiaS3,
 		creds:          creds,
 		connectTimeout: connectTimeout,
 		logger:         logger,
-		executor:       &RealExecutor{},
+		executor:       withConfigFile(&RealExecutor{}, opts.ConfigFile),
 	}
 }
 
+// NewFilesystemClient creates a Kopia client for a filesystem repository
+// (BACKEND_TYPE=kopia-fs). Everything past Connect — snapshot listing and
+// parsing, health checks, the managed server — is shared with the S3
+// client; only the connect flags and the result label differ.
+func NewFilesystemClient(cfg FilesystemConfig, creds CredentialsSource, logger *slog.Logger, opts Options) *Client {
+	c := NewClient(S3Config{}, creds, logger, opts)
+	c.fs = &cfg
+	c.backendType = backend.TypeKopiaFS
+	return c
+}
+
+// BackendType is the CheckResult.Backend label this client reports:
+// kopia-s3, or kopia-fs for a filesystem repository.
+func (c *Client) BackendType() string {
+	return c.backendType
+}
+
 // NewClientWithExecutor creates a new Kopia client with a custom executor
 // (for testing). Mirrors NewClient's argument order — same opts shape.
 func NewClientWithExecutor(cfg S3Config, creds CredentialsSource, logger *slog.Logger, executor CommandExecutor, opts Options) *Client {
 	c := NewClient(cfg, creds, logger, opts)
-	c.executor = executor
+	c.executor = withConfigFile(executor, opts.ConfigFile)
 	return c
 }
 
+// configFileExecutor prefixes every kopia invocation with --config-file
+// (Options.ConfigFile).
+type configFileExecutor struct {
+	inner CommandExecutor
+	path  string
+}
+
+func withConfigFile(e CommandExecutor, path string) CommandExecutor {
+	if path == "" {
+		return e
+	}
+	return configFileExecutor{inner: e, path: path}
+}
+
+func (e configFileExecutor) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if name == "kopia" {
+		args = append([]string{"--config-file=" + e.path}, args...)
+	}
+	return e.inner.Run(ctx, name, args...)
+}
+
 // Connect connects to the kopia repository over S3. v3.1.0 retries with
 // exponential backoff on ErrCredentialsNotReady up to cfg.ConnectTimeout
 // (default 60s, configurable via the KOPIA_CONNECT_TIMEOUT env var). If
@@ -237,59 +321,91 @@ func NewClientWithExecutor(cfg S3Config, creds CredentialsSource, logger *slog.L
 // access-key, secret-key, password, optional --disable-tls), so the same
 // RustFS bucket and creds the mover Jobs see also work here.
 func (c *Client) Connect(ctx context.Context) error {
-	deadline := time.Now().Add(c.connectTimeout)
+	c.connectMu.Lock()
+	defer c.connectMu.Unlock()
+
+	creds, err := AwaitCredentials(ctx, c.creds, c.connectTimeout, c.logger)
+	if err != nil {
+		return err
+	}
+
+	var args []string
+	if c.fs != nil {
+		c.logger.Info("connecting to kopia repository (filesystem)", "path", c.fs.Path)
+		args = filesystemConnectArgs(*c.fs, creds)
+	} else {
+		c.logger.Info("connecting to kopia repository (s3)",
+			"endpoint", c.cfg.Endpoint,
+			"bucket", c.cfg.Bucket,
+			"disable_tls", c.cfg.DisableTLS,
+		)
+		args = connectArgs(c.cfg, creds)
+	}
+	if c.username != "" {
+		args = append(args, "--override-username="+c.username, "--override-hostname="+c.hostname)
+	}
+
+	output, err := c.executor.Run(ctx, "kopia", args...)
+	if err != nil {
+		c.logger.Error("failed to connect to kopia repository", "error", err, "output", string(output))
+		return fmt.Errorf("failed to connect to kopia repository: %w", err)
+	}
+
+	c.mu.Lock()
+	c.connected = true
+	c.mu.Unlock()
+	c.logger.Info("connected to kopia repository", "backend", c.backendType)
+	return nil
+}
+
+// Reconnect re-runs Connect with freshly loaded credentials after a
+// rotation (see CredentialsWatcher). A failed connect leaves connected
+// untouched and kopia only rewrites its on-disk config on success, so
+// queries keep running on the previous session until the new one is in
+// place.
+func (c *Client) Reconnect(ctx context.Context) error {
+	return c.Connect(ctx)
+}
+
+// AwaitCredentials loads creds from src, retrying with exponential backoff
+// (250ms doubling to 5s) while the source reports ErrCredentialsNotReady,
+// for at most timeout. Any other error from the source returns at once.
+// Shared by the CLI client's Connect and the native reader's Connect so
+// both ride out the same ESO render window the same way.
+func AwaitCredentials(ctx context.Context, src CredentialsSource, timeout time.Duration, logger *slog.Logger) (Creds, error) {
+	deadline := time.Now().Add(timeout)
 	backoff := 250 * time.Millisecond
 	const maxBackoff = 5 * time.Second
 
 	attempt := 0
 	for {
 		attempt++
-		creds, err := c.creds.Load()
-		if err != nil {
-			if !errors.Is(err, ErrCredentialsNotReady) {
-				return fmt.Errorf("load kopia credentials: %w", err)
-			}
-			// Credentials not ready — back off and retry, unless we've
-			// exhausted the budget.
-			if time.Now().After(deadline) {
-				return fmt.Errorf("kopia credentials still not ready after %s: %w", c.connectTimeout, err)
-			}
-			c.logger.Warn("kopia credentials not ready, retrying",
-				"attempt", attempt,
-				"backoff", backoff,
-				"error", err,
-			)
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(backoff):
-			}
-			backoff *= 2
-			if backoff > maxBackoff {
-				backoff = maxBackoff
-			}
-			continue
+		creds, err := src.Load()
+		if err == nil {
+			return creds, nil
 		}
-
-		c.logger.Info("connecting to kopia repository (s3)",
-			"endpoint", c.cfg.Endpoint,
-			"bucket", c.cfg.Bucket,
-			"disable_tls", c.cfg.DisableTLS,
+		if !errors.Is(err, ErrCredentialsNotReady) {
+			return Creds{}, fmt.Errorf("load kopia credentials: %w", err)
+		}
+		// Credentials not ready — back off and retry, unless we've
+		// exhausted the budget.
+		if time.Now().After(deadline) {
+			return Creds{}, fmt.Errorf("kopia credentials still not ready after %s: %w", timeout, err)
+		}
+		logger.Warn("kopia credentials not ready, retrying",
 			"attempt", attempt,
+			"backoff", backoff,
+			"error", err,
 		)
-
-		args := connectArgs(c.cfg, creds)
-		output, err := c.executor.Run(ctx, "kopia", args...)
-		if err != nil {
-			c.logger.Error("failed to connect to kopia repository", "error", err, "output", string(output))
-			return fmt.Errorf("failed to connect to kopia repository: %w", err)
+		select {
+		case <-ctx.Done():
+			return Creds{}, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
 		}
-
-		c.mu.Lock()
-		c.connected = true
-		c.mu.Unlock()
-		c.logger.Info("connected to kopia repository (s3)")
-		return nil
 	}
 }
 
@@ -311,7 +427,19 @@ func connectArgs(cfg S3Config, creds Creds) []string {
 	return args
 }
 
-// CheckBackupExists checks if a backup exists for the given namespace/pvc.
+// filesystemConnectArgs assembles `kopia repository connect filesystem`.
+// The repository password is the only credential.
+func filesystemConnectArgs(cfg FilesystemConfig, creds Creds) []string {
+	return []string{
+		kopiaCmdRepository, kopiaCmdConnect, kopiaCmdFilesystem,
+		"--path", cfg.Path,
+		"--password", creds.Password,
+	}
+}
+
+// CheckBackupExists checks whether any of the query's candidate sources
+// (see QuerySources) holds a snapshot — one `kopia snapshot list` per
+// source.
 //
 // v3.1.0: this no longer requires creds at call time — kopia keeps the
 // connection state in its on-disk config (KOPIA_CONFIG_PATH), so a subprocess
@@ -320,108 +448,29 @@ func connectArgs(cfg S3Config, creds Creds) []string {
 // command-failed error and return DecisionUnknown — the validating webhook
 // then denies as today, ArgoCD retries, and the next reconcile triggers
 // Connect() which picks up fresh creds via the lazy-load path.
-func (c *Client) CheckBackupExists(ctx context.Context, namespace, pvc string) backend.CheckResult {
-	// VolSync creates snapshots with source: {pvc}-backup@{namespace}:/data
-	source := fmt.Sprintf("%s-backup@%s:/data", pvc, namespace)
-
-	c.logger.Debug("checking kopia snapshot", "source", source)
+func (c *Client) CheckBackupExists(ctx context.Context, q backend.Query) backend.CheckResult {
+	c.logger.Debug("checking kopia snapshots", "namespace", q.Namespace, "pvc", q.PVC, "identity", q.Identity.Key())
 
-	output, err := c.executor.Run(ctx, "kopia", "snapshot", "list", source, "--json")
-	if err != nil {
-		// Check if it's an exit error (command ran but returned non-zero)
-		var exitErr *exec.ExitError
-		if errors.As(err, &exitErr) {
-			c.logger.Error("kopia snapshot list failed",
-				"source", source,
-				"error", err,
-				"stderr", string(exitErr.Stderr))
-		}
-		return backend.CheckResult{
-			Exists:        false,
-			Decision:      backend.DecisionUnknown,
-			Authoritative: false,
-			Namespace:     namespace,
-			Pvc:           pvc,
-			Backend:       backend.TypeKopiaS3,
-			Source:        source,
-			Error:         fmt.Sprintf("failed to list snapshots: %v", err),
-		}
-	}
-
-	// Parse JSON output - empty array means no snapshots
-	var snapshots []any
-	if err := json.Unmarshal(output, &snapshots); err != nil {
-		c.logger.Error("failed to parse kopia output", "error", err, "output", string(output))
-		return backend.CheckResult{
-			Exists:        false,
-			Decision:      backend.DecisionUnknown,
-			Authoritative: false,
-			Namespace:     namespace,
-			Pvc:           pvc,
-			Backend:       backend.TypeKopiaS3,
-			Source:        source,
-			Error:         fmt.Sprintf("failed to parse kopia output: %v", err),
-		}
-	}
-
-	exists := len(snapshots) > 0
-	decision := backend.DecisionFresh
-	if exists {
-		decision = backend.DecisionRestore
-	}
-	c.logger.Debug("kopia snapshot check complete", "source", source, "exists", exists, "count", len(snapshots))
-
-	return backend.CheckResult{
-		Exists:        exists,
-		Decision:      decision,
-		Authoritative: true,
-		Namespace:     namespace,
-		Pvc:           pvc,
-		Backend:       backend.TypeKopiaS3,
-		Source:        source,
+	result := CheckQuery(ctx, q, c.ListSnapshots)
+	result.Backend = c.backendType
+	if result.Error == "" {
+		c.logger.Debug("kopia snapshot check complete", "source", result.Source, "exists", result.Exists, "count", result.SnapshotCount)
 	}
-}
-
-// snapshotSource represents the source field in kopia snapshot JSON output.
-type snapshotSource struct {
-	Host     string `json:"host"`
-	UserName string `json:"userName"`
-	Path     string `json:"path"`
-}
-
-type snapshotEntry struct {
-	Source snapshotSource `json:"source"`
+	return result
 }
 
 // ListAllSources returns all unique backup sources as namespace/pvc pairs.
 // Uses "kopia snapshot list --all --json" — one call to scan the entire repo.
+// Callers that want snapshot times and sizes use SummarizeAllSources.
 func (c *Client) ListAllSources(ctx context.Context) (map[string]bool, error) {
-	c.logger.Info("listing all kopia snapshots for cache pre-warm")
-
-	output, err := c.executor.Run(ctx, "kopia", "snapshot", "list", "--all", "--json")
+	summaries, err := c.SummarizeAllSources(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list all snapshots: %w", err)
+		return nil, err
 	}
-
-	var entries []snapshotEntry
-	if err := json.Unmarshal(output, &entries); err != nil {
-		return nil, fmt.Errorf("failed to parse snapshot list: %w", err)
+	sources := make(map[string]bool, len(summaries))
+	for key := range summaries {
+		sources[key] = true
 	}
-
-	// Build set of namespace/pvc pairs that have backups
-	// Source format: userName={pvc}-backup, host={namespace}, path=/data
-	sources := make(map[string]bool)
-	for _, e := range entries {
-		userName := e.Source.UserName
-		namespace := e.Source.Host
-		if len(userName) > 7 && userName[len(userName)-7:] == "-backup" {
-			pvc := userName[:len(userName)-7]
-			key := namespace + "/" + pvc
-			sources[key] = true
-		}
-	}
-
-	c.logger.Info("snapshot scan complete", "unique_sources", len(sources))
 	return sources, nil
 }
 
@@ -61,12 +61,25 @@ type mockExecutor struct {
 	// test lifetime. Used by HealthCheck tests to assert subprocess
 	// invocation behavior.
 	callCount atomic.Int64
+
+	// bySource, when set, answers `kopia snapshot list <source>` per
+	// source; sources it does not name list as empty. listed records
+	// every source asked for, in order.
+	bySource map[string]string
+	listed   []string
 }
 
 func (m *mockExecutor) Run(_ context.Context, name string, args ...string) ([]byte, error) {
 	m.callCount.Add(1)
 	m.lastName = name
 	m.lastArgs = append([]string(nil), args...)
+	if m.bySource != nil && len(args) >= 3 && args[0] == "snapshot" && args[1] == "list" {
+		m.listed = append(m.listed, args[2])
+		if out, ok := m.bySource[args[2]]; ok {
+			return []byte(out), m.err
+		}
+		return []byte("[]"), m.err
+	}
 	return m.output, m.err
 }
 
@@ -158,6 +171,39 @@ func TestConnect_Success(t *testing.T) {
 	}
 }
 
+// TestConnect_Filesystem pins the kopia-fs connect shape: `repository
+// connect filesystem --path`, the password as the only credential, no S3
+// flags — and kopia-fs as the result label.
+func TestConnect_Filesystem(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	mock := &mockExecutor{output: []byte("Connected to repository.")}
+	client := NewFilesystemClient(FilesystemConfig{Path: "/tmp/repo"}, NewPasswordCredentialsSource(testPassword), logger, Options{})
+	client.executor = mock
+
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	want := []string{cliRepository, "connect", "filesystem", "--path", "/tmp/repo", "--password", testPassword}
+	if !slices.Equal(mock.lastArgs, want) {
+		t.Errorf("executor args = %v, want %v", mock.lastArgs, want)
+	}
+
+	mock.output = []byte("[]")
+	r := client.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "data", ""))
+	if r.Backend != backend.TypeKopiaFS || client.BackendType() != backend.TypeKopiaFS || !r.Authoritative {
+		t.Errorf("result: %+v", r)
+	}
+}
+
+func TestPasswordCredentialsSource(t *testing.T) {
+	if got, err := NewPasswordCredentialsSource("p").Load(); err != nil || got != (Creds{Password: "p"}) {
+		t.Errorf("Load() = %+v, %v", got, err)
+	}
+	if _, err := NewPasswordCredentialsSource("").Load(); !errors.Is(err, ErrCredentialsNotReady) {
+		t.Errorf("empty password: %v, want ErrCredentialsNotReady", err)
+	}
+}
+
 // TestConnect_NoDisableTLSWhenFalse pins the inverse: when DisableTLS is
 // false (production-shaped HTTPS RustFS or any TLS-terminated endpoint),
 // `--disable-tls` must NOT be passed.
@@ -197,6 +243,35 @@ func TestConnect_Failure(t *testing.T) {
 	}
 }
 
+// TestReconnect_FailureKeepsConnection pins the rotation contract: a
+// reconnect that fails (new Secret half-rendered, wrong password) must
+// not flip the client to disconnected, or readiness would drop a pod
+// whose previous session still works.
+func TestReconnect_FailureKeepsConnection(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	mock := &mockExecutor{output: []byte("connected")}
+	client := NewClientWithExecutor(testS3Config(), testCreds(), logger, mock, Options{})
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	mock.err = errors.New("exit status 1")
+	if err := client.Reconnect(context.Background()); err == nil {
+		t.Fatal("Reconnect should have returned an error")
+	}
+	if !client.IsConnected() {
+		t.Error("failed Reconnect dropped the previous connection")
+	}
+
+	mock.err = nil
+	if err := client.Reconnect(context.Background()); err != nil {
+		t.Fatalf("Reconnect: %v", err)
+	}
+	if got := mock.callCount.Load(); got != 3 {
+		t.Errorf("kopia invoked %d times, want 3", got)
+	}
+}
+
 // TestConnect_RetriesOnCredentialsNotReady pins the v3.1.0 backoff loop:
 // when the credentials source returns ErrCredentialsNotReady on the first
 // few calls and then succeeds, Connect() should keep retrying until the
@@ -291,19 +366,18 @@ func TestCheckBackupExists_Found(t *testing.T) {
 	snapshotJSON := `[
 		{
 			"id": "abc123",
-			"source": {"host": "test-pvc-backup", "userName": "karakeep", "path": "/data"},
+			"source": {"host": "karakeep", "userName": "test-pvc-backup", "path": "/data"},
 			"startTime": "2024-01-15T10:00:00Z"
 		}
 	]`
 
 	mock := &mockExecutor{
-		output: []byte(snapshotJSON),
-		err:    nil,
+		bySource: map[string]string{"test-pvc-backup@karakeep:/data": snapshotJSON},
 	}
 
 	client := NewClientWithExecutor(testS3Config(), testCreds(), logger, mock, Options{})
 
-	result := client.CheckBackupExists(context.Background(), "karakeep", "test-pvc")
+	result := client.CheckBackupExists(context.Background(), backend.NewQuery("karakeep", "test-pvc", ""))
 
 	if !result.Exists {
 		t.Error("Exists should be true")
@@ -341,7 +415,7 @@ func TestCheckBackupExists_NotFound(t *testing.T) {
 
 	client := NewClientWithExecutor(testS3Config(), testCreds(), logger, mock, Options{})
 
-	result := client.CheckBackupExists(context.Background(), "foo", "bar")
+	result := client.CheckBackupExists(context.Background(), backend.NewQuery("foo", "bar", ""))
 
 	if result.Exists {
 		t.Error("Exists should be false")
@@ -352,8 +426,9 @@ func TestCheckBackupExists_NotFound(t *testing.T) {
 	if !result.Authoritative {
 		t.Error("Authoritative should be true")
 	}
-	if result.Source != "bar-backup@foo:/data" {
-		t.Errorf("Source = %v, want bar-backup@foo:/data", result.Source)
+	// Nothing found: Source is the v4 source the next backup will use.
+	if result.Source != "bar@foo:/data" {
+		t.Errorf("Source = %v, want bar@foo:/data", result.Source)
 	}
 	if result.Backend != backend.TypeKopiaS3 {
 		t.Errorf("Backend = %v, want %s", result.Backend, backend.TypeKopiaS3)
@@ -370,7 +445,7 @@ func TestCheckBackupExists_CommandError(t *testing.T) {
 
 	client := NewClientWithExecutor(testS3Config(), testCreds(), logger, mock, Options{})
 
-	result := client.CheckBackupExists(context.Background(), "test-ns", "test-pvc")
+	result := client.CheckBackupExists(context.Background(), backend.NewQuery("test-ns", "test-pvc", ""))
 
 	if result.Exists {
 		t.Error("Exists should be false on error")
@@ -399,7 +474,7 @@ func TestCheckBackupExists_InvalidJSON(t *testing.T) {
 
 	client := NewClientWithExecutor(testS3Config(), testCreds(), logger, mock, Options{})
 
-	result := client.CheckBackupExists(context.Background(), "test-ns", "test-pvc")
+	result := client.CheckBackupExists(context.Background(), backend.NewQuery("test-ns", "test-pvc", ""))
 
 	if result.Exists {
 		t.Error("Exists should be false on JSON parse error")
@@ -490,7 +565,7 @@ func TestCheckBackupExists_Integration(t *testing.T) {
 		t.Fatalf("Failed to connect: %v", err)
 	}
 
-	result := client.CheckBackupExists(context.Background(), "test-ns", "test-pvc")
+	result := client.CheckBackupExists(context.Background(), backend.NewQuery("test-ns", "test-pvc", ""))
 	t.Logf("Result: exists=%v, namespace=%s, pvc=%s, backend=%s, error=%s",
 		result.Exists, result.Namespace, result.Pvc, result.Backend, result.Error)
 }
@@ -0,0 +1,184 @@
+package kopia
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Reconnector is implemented by readers that can re-establish their
+// repository connection from freshly loaded credentials. The CLI Client
+// re-runs `kopia repository connect`; the native reader re-opens the
+// format blob under the new password.
+type Reconnector interface {
+	Reconnect(ctx context.Context) error
+}
+
+// Fingerprint returns a short, non-reversible digest of creds for logs and
+// metrics: enough to tell two rotations apart, never enough to recover a
+// secret. The NUL separators keep ("ab","c") and ("a","bc") distinct.
+func Fingerprint(c Creds) string {
+	sum := sha256.Sum256([]byte(c.Password + "\x00" + c.AccessKey + "\x00" + c.SecretKey))
+	return hex.EncodeToString(sum[:6])
+}
+
+// Defaults for CredentialsWatcher. kubelet's AtomicWriter swaps the
+// `..data` symlink in a handful of filesystem operations within
+// milliseconds, so a short debounce collapses one rotation into one
+// reconnect. A failed reconnect is retried on its own: a Secret rotated
+// mid-way (new password, old S3 keys still rendering) produces no further
+// events once kubelet settles.
+const (
+	defaultCredentialsDebounce = 500 * time.Millisecond
+	defaultCredentialsRetry    = 30 * time.Second
+)
+
+// CredentialsWatcher watches a DirCredentialsSource's directory and
+// reconnects a reader when the credentials' content changes.
+//
+// Before this, DirCredentialsSource.Load was only consulted by Connect;
+// once connected, a Secret rotation went unnoticed until kopia's on-disk
+// session broke and HealthCheck took the pod out of readiness — with
+// failurePolicy=Fail on the PVC webhook that is an admission outage for
+// the length of a rollout. Reconnects are serialized on the watcher's
+// goroutine, and a failed one leaves the reader on its previous
+// connection, so a half-rendered Secret never takes a working pod down.
+type CredentialsWatcher struct {
+	src    *DirCredentialsSource
+	target Reconnector
+	logger *slog.Logger
+
+	debounce time.Duration
+	retry    time.Duration
+
+	rotations atomic.Int64
+	failures  atomic.Int64
+
+	mu          sync.Mutex
+	fingerprint string
+}
+
+// NewCredentialsWatcher constructs a watcher. Nothing is watched until Run.
+func NewCredentialsWatcher(src *DirCredentialsSource, target Reconnector, logger *slog.Logger) *CredentialsWatcher {
+	return &CredentialsWatcher{
+		src:      src,
+		target:   target,
+		logger:   logger,
+		debounce: defaultCredentialsDebounce,
+		retry:    defaultCredentialsRetry,
+	}
+}
+
+// CredentialRotations returns how many rotations were applied.
+func (w *CredentialsWatcher) CredentialRotations() int64 { return w.rotations.Load() }
+
+// CredentialRotationFailures returns how many reconnect attempts after a
+// content change failed.
+func (w *CredentialsWatcher) CredentialRotationFailures() int64 { return w.failures.Load() }
+
+// Fingerprint returns the fingerprint of the credentials the reader is
+// currently connected with, or "" before the first successful load.
+func (w *CredentialsWatcher) Fingerprint() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.fingerprint
+}
+
+// Run watches the directory until ctx is cancelled. The baseline
+// fingerprint is taken from whatever is on disk when Run starts — the
+// caller has just connected with it. Any event in the directory arms the
+// debounce timer; the fingerprint comparison, not the event type, decides
+// whether anything changed, so kubelet's `..data_tmp` churn and plain
+// in-place file writes (docker-compose bind mounts) are handled alike.
+// Returns an error only if the directory cannot be watched.
+func (w *CredentialsWatcher) Run(ctx context.Context) error {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create credentials watcher: %w", err)
+	}
+	defer func() { _ = fw.Close() }()
+	if err := fw.Add(w.src.Dir); err != nil {
+		return fmt.Errorf("watch credentials directory %s: %w", w.src.Dir, err)
+	}
+
+	if creds, err := w.src.Load(); err == nil {
+		w.mu.Lock()
+		w.fingerprint = Fingerprint(creds)
+		w.mu.Unlock()
+	}
+	w.logger.Info("watching kopia credentials for rotation", "dir", w.src.Dir, "fingerprint", w.Fingerprint())
+
+	timer := time.NewTimer(0)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-fw.Events:
+			if !ok {
+				return nil
+			}
+			if ev.Op == fsnotify.Chmod {
+				continue
+			}
+			timer.Reset(w.debounce)
+		case err, ok := <-fw.Errors:
+			if !ok {
+				return nil
+			}
+			w.logger.Warn("credentials watcher error", "dir", w.src.Dir, "error", err)
+		case <-timer.C:
+			if !w.check(ctx) {
+				timer.Reset(w.retry)
+			}
+		}
+	}
+}
+
+// check reconnects when the on-disk credentials differ from the ones in
+// use. It returns false when a reconnect was attempted and failed, so Run
+// schedules a retry; credentials that are mid-render (ErrCredentialsNotReady)
+// are left to the next filesystem event.
+func (w *CredentialsWatcher) check(ctx context.Context) bool {
+	creds, err := w.src.Load()
+	if err != nil {
+		w.logger.Debug("kopia credentials changed but not loadable yet", "error", err)
+		return true
+	}
+	next := Fingerprint(creds)
+	prev := w.Fingerprint()
+	if next == prev {
+		return true
+	}
+
+	if err := w.target.Reconnect(ctx); err != nil {
+		w.failures.Add(1)
+		w.logger.Error("kopia credential rotation failed; keeping previous connection",
+			"old_fingerprint", prev,
+			"new_fingerprint", next,
+			"retry_in", w.retry,
+			"error", err,
+		)
+		return false
+	}
+	w.mu.Lock()
+	w.fingerprint = next
+	w.mu.Unlock()
+	w.logger.Info("kopia credentials rotated",
+		"old_fingerprint", prev,
+		"new_fingerprint", next,
+	)
+	w.rotations.Add(1)
+	return true
+}
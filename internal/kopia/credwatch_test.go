@@ -0,0 +1,194 @@
+package kopia
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// writeAtomicSecret lays out dir the way kubelet's AtomicWriter does: the
+// payload lives in a timestamped directory, `..data` points at it, and
+// each key is a symlink through `..data`. Calling it again swaps `..data`
+// via a `..data_tmp` rename, which is the only event a rotation produces.
+func writeAtomicSecret(t *testing.T, dir, version string, creds Creds) {
+	t.Helper()
+	payload := filepath.Join(dir, "..ts-"+version)
+	if err := os.Mkdir(payload, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, v := range map[string]string{
+		"KOPIA_PASSWORD":        creds.Password,
+		"AWS_ACCESS_KEY_ID":     creds.AccessKey,
+		"AWS_SECRET_ACCESS_KEY": creds.SecretKey,
+	} {
+		mustWrite(t, filepath.Join(payload, name), v+"\n")
+		link := filepath.Join(dir, name)
+		if _, err := os.Lstat(link); errors.Is(err, os.ErrNotExist) {
+			if err := os.Symlink(filepath.Join("..data", name), link); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	tmp := filepath.Join(dir, "..data_tmp")
+	if err := os.Symlink(filepath.Base(payload), tmp); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fakeReconnector records reconnects and fails the first failFirst calls.
+type fakeReconnector struct {
+	calls     atomic.Int32
+	failFirst int32
+}
+
+func (f *fakeReconnector) Reconnect(context.Context) error {
+	if f.calls.Add(1) <= f.failFirst {
+		return errors.New("kopia repository connect failed")
+	}
+	return nil
+}
+
+// lockedBuffer is a log sink safe to read while Run writes to it.
+type lockedBuffer struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (l *lockedBuffer) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.b.Write(p)
+}
+
+func (l *lockedBuffer) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.b.String()
+}
+
+func startWatcher(t *testing.T, dir string, target Reconnector) (*CredentialsWatcher, *lockedBuffer) {
+	t.Helper()
+	logs := &lockedBuffer{}
+	w := NewCredentialsWatcher(NewDirCredentialsSource(dir), target, slog.New(slog.NewTextHandler(logs, nil)))
+	w.debounce = 20 * time.Millisecond
+	w.retry = 50 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- w.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-errc; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	})
+	// Run takes the baseline before it starts reacting; wait for it so a
+	// rotation written right after this returns is seen as a change.
+	deadline := time.Now().Add(2 * time.Second)
+	for w.Fingerprint() == "" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return w, logs
+}
+
+// awaitRotation waits until w has applied a rotation.
+func awaitRotation(t *testing.T, w *CredentialsWatcher) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for w.CredentialRotations() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no rotation applied")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := Fingerprint(Creds{Password: "ab", AccessKey: "c", SecretKey: "d"})
+	b := Fingerprint(Creds{Password: "a", AccessKey: "bc", SecretKey: "d"})
+	if a == b || len(a) != 12 {
+		t.Errorf("fingerprints %q and %q", a, b)
+	}
+}
+
+func TestCredentialsWatcher_ReconnectsOnAtomicSwap(t *testing.T) {
+	dir := t.TempDir()
+	v1 := Creds{Password: "pw-1", AccessKey: "ak-1", SecretKey: "sk-1"}
+	v2 := Creds{Password: "pw-2", AccessKey: "ak-1", SecretKey: "sk-1"}
+	writeAtomicSecret(t, dir, "1", v1)
+
+	rc := &fakeReconnector{}
+	w, logs := startWatcher(t, dir, rc)
+	if w.Fingerprint() != Fingerprint(v1) {
+		t.Fatalf("baseline fingerprint %q, want %q", w.Fingerprint(), Fingerprint(v1))
+	}
+
+	writeAtomicSecret(t, dir, "2", v2)
+	awaitRotation(t, w)
+	if w.CredentialRotations() != 1 || w.CredentialRotationFailures() != 0 {
+		t.Errorf("rotations %d, failures %d", w.CredentialRotations(), w.CredentialRotationFailures())
+	}
+	if w.Fingerprint() != Fingerprint(v2) {
+		t.Errorf("fingerprint %q, want %q", w.Fingerprint(), Fingerprint(v2))
+	}
+	out := logs.String()
+	if !strings.Contains(out, "kopia credentials rotated") || !strings.Contains(out, Fingerprint(v2)) {
+		t.Errorf("rotation log missing: %s", out)
+	}
+	if strings.Contains(out, "pw-2") || strings.Contains(out, "pw-1") {
+		t.Errorf("log leaks a password: %s", out)
+	}
+}
+
+func TestCredentialsWatcher_UnchangedContentDoesNotReconnect(t *testing.T) {
+	dir := t.TempDir()
+	v1 := Creds{Password: "pw-1", AccessKey: "ak-1", SecretKey: "sk-1"}
+	writeAtomicSecret(t, dir, "1", v1)
+
+	rc := &fakeReconnector{}
+	startWatcher(t, dir, rc)
+
+	// kubelet re-projects on resync even when nothing changed.
+	writeAtomicSecret(t, dir, "2", v1)
+	time.Sleep(200 * time.Millisecond)
+	if n := rc.calls.Load(); n != 0 {
+		t.Errorf("reconnects = %d, want 0", n)
+	}
+}
+
+func TestCredentialsWatcher_RetriesFailedReconnect(t *testing.T) {
+	dir := t.TempDir()
+	writeAtomicSecret(t, dir, "1", Creds{Password: "pw-1", AccessKey: "ak-1", SecretKey: "sk-1"})
+
+	rc := &fakeReconnector{failFirst: 1}
+	w, logs := startWatcher(t, dir, rc)
+	old := w.Fingerprint()
+
+	writeAtomicSecret(t, dir, "2", Creds{Password: "pw-2", AccessKey: "ak-2", SecretKey: "sk-2"})
+	awaitRotation(t, w)
+	if w.CredentialRotations() != 1 || w.CredentialRotationFailures() != 1 {
+		t.Errorf("rotations %d, failures %d", w.CredentialRotations(), w.CredentialRotationFailures())
+	}
+	if w.Fingerprint() == old {
+		t.Error("fingerprint not advanced after the retried reconnect")
+	}
+	if !strings.Contains(logs.String(), "keeping previous connection") {
+		t.Errorf("failure log missing: %s", logs.String())
+	}
+}
+
+func TestCredentialsWatcher_MissingDirIsAnError(t *testing.T) {
+	w := NewCredentialsWatcher(NewDirCredentialsSource(filepath.Join(t.TempDir(), "absent")), &fakeReconnector{}, slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError})))
+	if err := w.Run(context.Background()); err == nil {
+		t.Error("Run on a missing directory returned nil")
+	}
+}
@@ -0,0 +1,404 @@
+package kopia
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Maintenance modes, as kopia names them.
+const (
+	MaintenanceQuick = "quick"
+	MaintenanceFull  = "full"
+)
+
+// Maintenance run results.
+const (
+	MaintenanceSucceeded = "success"
+	MaintenanceFailed    = "failure"
+	MaintenanceSkipped   = "skipped"
+)
+
+// DefaultMaintenanceOwner is the kopia client identity the maintenance
+// loop claims ownership under. It is fixed rather than derived from the
+// pod: a Deployment's pod hostname changes on every rollout, and kopia
+// refuses to run maintenance for anyone but the recorded owner, so a
+// per-pod owner would orphan the schedule at the first restart. Every
+// replica therefore shares it, which is why the loop only runs on the
+// elected leader (NeedLeaderElection).
+const DefaultMaintenanceOwner = "pvc-plumber@pvc-plumber"
+
+// DefaultMaintenanceConfigFile is where the maintenance client keeps
+// its kopia connection when the operator does not say otherwise. It is
+// a file of its own because connecting records the client identity,
+// which for maintenance is the owner rather than the pod.
+const DefaultMaintenanceConfigFile = "/tmp/pvc-plumber-maintenance/repository.config"
+
+// defaultMaintenanceCheckInterval is how often the loop asks kopia
+// whether a run is due. The cadences themselves are kopia's schedule.
+const defaultMaintenanceCheckInterval = time.Minute
+
+// DefaultMaintenanceRunTimeout bounds one maintenance run when
+// MaintenanceOptions leaves it unset. kopia takes the maintenance lock
+// for the run and lets it lapse if the process is killed, so a run cut
+// short is retried at the next due time.
+const DefaultMaintenanceRunTimeout = 2 * time.Hour
+
+// MoverActivity reports how many VolSync mover Jobs are running against
+// the repository. Maintenance is deferred while any are: a full run's
+// blob garbage collection racing a mover's upload is exactly the window
+// kopia's safety margins exist for, and there is no reason to spend
+// them.
+type MoverActivity interface {
+	ActiveMovers(ctx context.Context) (int, error)
+}
+
+// MaintenanceOptions configures a Maintainer. A zero interval leaves
+// that mode disabled in kopia's schedule.
+type MaintenanceOptions struct {
+	QuickInterval time.Duration
+	FullInterval  time.Duration
+	// Owner is the "user@host" identity to claim maintenance under.
+	// Defaults to DefaultMaintenanceOwner.
+	Owner string
+	// CheckInterval defaults to one minute.
+	CheckInterval time.Duration
+	// RunTimeout bounds one run, the blob measurements around it
+	// included. Defaults to DefaultMaintenanceRunTimeout.
+	RunTimeout time.Duration
+}
+
+// MaintenanceRun is the outcome of one due run, or of the decision not
+// to start it.
+type MaintenanceRun struct {
+	Mode            string    `json:"mode"`
+	Result          string    `json:"result"`
+	Reason          string    `json:"reason,omitempty"`
+	StartedAt       time.Time `json:"started_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	FreedBytes      int64     `json:"freed_bytes"`
+}
+
+// MaintenanceModeStatus is the per-mode view in MaintenanceStatus.
+type MaintenanceModeStatus struct {
+	Interval      string          `json:"interval"`
+	LastRun       *MaintenanceRun `json:"last_run,omitempty"`
+	LastSuccessAt time.Time       `json:"last_success_at,omitzero"`
+	NextDueAt     time.Time       `json:"next_due_at,omitzero"`
+	Succeeded     int64           `json:"succeeded"`
+	Failed        int64           `json:"failed"`
+	Skipped       int64           `json:"skipped"`
+	FreedBytes    int64           `json:"freed_bytes_total"`
+}
+
+// MaintenanceStatus is what /readyz and /metrics report.
+type MaintenanceStatus struct {
+	Owner string `json:"owner"`
+	// RepositoryOwner is the owner kopia last reported; it differs from
+	// Owner while another client holds maintenance.
+	RepositoryOwner string                `json:"repository_owner,omitempty"`
+	Quick           MaintenanceModeStatus `json:"quick"`
+	Full            MaintenanceModeStatus `json:"full"`
+}
+
+// Maintainer owns `kopia maintenance run` for the shared repository.
+//
+// Before it, nothing did: mover Jobs connect as their own per-PVC
+// identities, none of which is the maintenance owner, so kopia's
+// auto-maintenance never fired, deleted snapshots never released blobs,
+// and the index kept growing with every mover run.
+//
+// The cadences live in kopia's own schedule (`kopia maintenance set
+// --quick-interval/--full-interval`), written when the loop first claims
+// ownership, and the loop only asks kopia whether a run is due. That
+// keeps "when did maintenance last run" in the repository, where it
+// survives pod restarts, instead of in a timer every rollout resets.
+//
+// The Maintainer connects its client itself, under the owner identity,
+// so the client must be its own: built with an Options.ConfigFile no
+// other client uses. Sharing the reader's config would change the
+// identity every other query in the process runs under.
+type Maintainer struct {
+	client *Client
+	movers MoverActivity
+	opts   MaintenanceOptions
+	logger *slog.Logger
+	now    func() time.Time
+
+	// connected is cleared when a kopia call fails, so the next tick
+	// connects again with freshly loaded credentials.
+	connected bool
+	// configured is set once this process has written its owner and
+	// intervals into the repository's maintenance params.
+	configured bool
+
+	mu     sync.Mutex
+	status MaintenanceStatus
+}
+
+// NewMaintainer constructs a Maintainer over a CLI client it takes over:
+// the client is connected on the first tick, under the owner identity.
+// movers may be nil, in which case runs are never deferred.
+func NewMaintainer(c *Client, movers MoverActivity, opts MaintenanceOptions, logger *slog.Logger) *Maintainer {
+	if opts.Owner == "" {
+		opts.Owner = DefaultMaintenanceOwner
+	}
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = defaultMaintenanceCheckInterval
+	}
+	if opts.RunTimeout <= 0 {
+		opts.RunTimeout = DefaultMaintenanceRunTimeout
+	}
+	c.username, c.hostname, _ = strings.Cut(opts.Owner, "@")
+	m := &Maintainer{client: c, movers: movers, opts: opts, logger: logger, now: time.Now}
+	m.status.Owner = opts.Owner
+	m.status.Quick.Interval = intervalString(opts.QuickInterval)
+	m.status.Full.Interval = intervalString(opts.FullInterval)
+	return m
+}
+
+func intervalString(d time.Duration) string {
+	if d <= 0 {
+		return "disabled"
+	}
+	return d.String()
+}
+
+// Status returns a copy of the current status.
+func (m *Maintainer) Status() MaintenanceStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.status
+	if s.Quick.LastRun != nil {
+		r := *s.Quick.LastRun
+		s.Quick.LastRun = &r
+	}
+	if s.Full.LastRun != nil {
+		r := *s.Full.LastRun
+		s.Full.LastRun = &r
+	}
+	return s
+}
+
+// NeedLeaderElection keeps maintenance on a single replica: kopia only
+// checks the owner name, which every replica shares, so two replicas
+// would both run it.
+func (m *Maintainer) NeedLeaderElection() bool { return true }
+
+// Start checks for due maintenance every CheckInterval until ctx is
+// cancelled. Failures are logged and recorded, never returned: a
+// repository that can't be maintained right now still answers /exists.
+func (m *Maintainer) Start(ctx context.Context) error {
+	m.logger.Info("kopia maintenance loop started",
+		"owner", m.opts.Owner,
+		"quick_interval", m.status.Quick.Interval,
+		"full_interval", m.status.Full.Interval,
+	)
+	ticker := time.NewTicker(m.opts.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			m.tick(ctx)
+		}
+	}
+}
+
+// maintenanceInfo is the part of `kopia maintenance info --json` the loop
+// reads: the owner from the params and the next due times from the
+// schedule.
+type maintenanceInfo struct {
+	Owner    string `json:"owner"`
+	Schedule struct {
+		NextFullMaintenance  time.Time `json:"nextFullMaintenance"`
+		NextQuickMaintenance time.Time `json:"nextQuickMaintenance"`
+	} `json:"schedule"`
+}
+
+// tick runs at most one maintenance, full taking precedence: kopia's
+// full run includes the quick tasks, so the quick due time moves too.
+func (m *Maintainer) tick(ctx context.Context) {
+	if !m.connected {
+		if err := m.client.Connect(ctx); err != nil {
+			m.logger.Warn("kopia maintenance: connect failed", "error", err)
+			return
+		}
+		m.connected = true
+	}
+
+	info, err := m.info(ctx)
+	if err != nil {
+		// Possibly rotated credentials or an expired session: connect
+		// again next tick.
+		m.connected = false
+		m.logger.Warn("kopia maintenance: read schedule failed", "error", err)
+		return
+	}
+	m.mu.Lock()
+	m.status.RepositoryOwner = info.Owner
+	m.mu.Unlock()
+	if info.Owner != "" && info.Owner != m.opts.Owner {
+		// Someone else — a VolSync KopiaMaintenance, an operator's
+		// laptop — owns maintenance. Taking it over silently would mean
+		// two schedules; report it and leave it.
+		m.logger.Debug("kopia maintenance owned by another client", "owner", info.Owner)
+		return
+	}
+	if !m.configured || info.Owner == "" {
+		if err := m.claim(ctx); err != nil {
+			m.logger.Warn("kopia maintenance: claim ownership failed", "error", err)
+			return
+		}
+		m.configured = true
+		if info, err = m.info(ctx); err != nil {
+			m.connected = false
+			m.logger.Warn("kopia maintenance: read schedule failed", "error", err)
+			return
+		}
+	}
+
+	now := m.now()
+	m.mu.Lock()
+	m.status.Quick.NextDueAt = info.Schedule.NextQuickMaintenance
+	m.status.Full.NextDueAt = info.Schedule.NextFullMaintenance
+	m.mu.Unlock()
+	var mode string
+	switch {
+	case m.opts.FullInterval > 0 && !now.Before(info.Schedule.NextFullMaintenance):
+		mode = MaintenanceFull
+	case m.opts.QuickInterval > 0 && !now.Before(info.Schedule.NextQuickMaintenance):
+		mode = MaintenanceQuick
+	default:
+		return
+	}
+
+	if m.movers != nil {
+		active, err := m.movers.ActiveMovers(ctx)
+		switch {
+		case err != nil:
+			m.record(MaintenanceRun{Mode: mode, Result: MaintenanceSkipped, Reason: "mover check failed: " + err.Error(), StartedAt: now})
+			return
+		case active > 0:
+			m.record(MaintenanceRun{Mode: mode, Result: MaintenanceSkipped, Reason: fmt.Sprintf("%d mover jobs active", active), StartedAt: now})
+			return
+		}
+	}
+	m.record(m.run(ctx, mode))
+}
+
+func (m *Maintainer) info(ctx context.Context) (maintenanceInfo, error) {
+	out, err := m.client.executor.Run(ctx, "kopia", "maintenance", "info", "--json")
+	if err != nil {
+		return maintenanceInfo{}, err
+	}
+	var info maintenanceInfo
+	if err := json.Unmarshal(out, &info); err != nil {
+		return maintenanceInfo{}, fmt.Errorf("parse maintenance info: %w", err)
+	}
+	return info, nil
+}
+
+// claim makes the current client identity the maintenance owner and
+// writes the configured cadences into kopia's schedule.
+func (m *Maintainer) claim(ctx context.Context) error {
+	args := []string{"maintenance", "set", "--owner=me",
+		"--enable-quick=" + strconv.FormatBool(m.opts.QuickInterval > 0),
+		"--enable-full=" + strconv.FormatBool(m.opts.FullInterval > 0),
+	}
+	if m.opts.QuickInterval > 0 {
+		args = append(args, "--quick-interval="+m.opts.QuickInterval.String())
+	}
+	if m.opts.FullInterval > 0 {
+		args = append(args, "--full-interval="+m.opts.FullInterval.String())
+	}
+	if _, err := m.client.executor.Run(ctx, "kopia", args...); err != nil {
+		return err
+	}
+	m.logger.Info("claimed kopia maintenance ownership", "owner", m.opts.Owner)
+	return nil
+}
+
+// run executes one maintenance, bounded by RunTimeout, and measures
+// what it freed as the drop in total blob bytes. A failed measurement
+// leaves FreedBytes at zero rather than failing a run that succeeded.
+func (m *Maintainer) run(ctx context.Context, mode string) MaintenanceRun {
+	ctx, cancel := context.WithTimeout(ctx, m.opts.RunTimeout)
+	defer cancel()
+	r := MaintenanceRun{Mode: mode, StartedAt: m.now()}
+	before, beforeErr := m.blobBytes(ctx)
+
+	args := []string{"maintenance", "run"}
+	if mode == MaintenanceFull {
+		args = append(args, "--full")
+	}
+	start := time.Now()
+	out, err := m.client.executor.Run(ctx, "kopia", args...)
+	r.DurationSeconds = time.Since(start).Seconds()
+	if err != nil {
+		r.Result, r.Reason = MaintenanceFailed, err.Error()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			r.Reason = "did not finish within " + m.opts.RunTimeout.String()
+		}
+		m.logger.Warn("kopia maintenance failed", "mode", mode, "error", err, "output", string(out))
+		return r
+	}
+	r.Result = MaintenanceSucceeded
+	if after, afterErr := m.blobBytes(ctx); beforeErr == nil && afterErr == nil && after < before {
+		r.FreedBytes = before - after
+	}
+	m.logger.Info("kopia maintenance complete", "mode", mode, "duration_seconds", r.DurationSeconds, "freed_bytes", r.FreedBytes)
+	return r
+}
+
+// blobBytes returns the repository's total blob size from `kopia blob
+// stats --raw`.
+func (m *Maintainer) blobBytes(ctx context.Context) (int64, error) {
+	out, err := m.client.executor.Run(ctx, "kopia", "blob", "stats", "--raw")
+	if err != nil {
+		return 0, err
+	}
+	return parseBlobStatsTotal(out)
+}
+
+// parseBlobStatsTotal reads the "Total: <bytes>" line of `kopia blob
+// stats --raw`.
+func parseBlobStatsTotal(out []byte) (int64, error) {
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(sc.Text()), "Total:"); ok {
+			return strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("no Total line in blob stats output")
+}
+
+func (m *Maintainer) record(r MaintenanceRun) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := &m.status.Quick
+	if r.Mode == MaintenanceFull {
+		s = &m.status.Full
+	}
+	s.LastRun = &r
+	switch r.Result {
+	case MaintenanceSucceeded:
+		s.Succeeded++
+		s.LastSuccessAt = r.StartedAt
+		s.FreedBytes += r.FreedBytes
+	case MaintenanceFailed:
+		s.Failed++
+	case MaintenanceSkipped:
+		s.Skipped++
+		m.logger.Debug("kopia maintenance deferred", "mode", r.Mode, "reason", r.Reason)
+	}
+}
@@ -0,0 +1,266 @@
+package kopia
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var maintNow = time.Date(2026, 6, 3, 12, 0, 0, 0, time.UTC)
+
+const maintConfigFile = "/tmp/maintenance/repository.config"
+
+// maintConnect is the connect argv the test maintainer's client runs.
+var maintConnect = strings.Join(append(connectArgs(testS3Config(), Creds{Password: testPassword, AccessKey: testAccessKey, SecretKey: testSecretKey}),
+	"--override-username=pvc-plumber", "--override-hostname=pvc-plumber"), " ")
+
+// maintExecutor scripts the kopia maintenance surface: the info owner
+// and due times, blob totals before and after a run, and failures and
+// hangs (until ctx ends) by subcommand. calls records every argv,
+// joined, less the --config-file every call must lead with.
+type maintExecutor struct {
+	owner     string
+	nextQuick time.Time
+	nextFull  time.Time
+	blobs     []string // successive `blob stats` outputs
+	fail      map[string]bool
+	hang      map[string]bool
+	calls     []string
+}
+
+func (e *maintExecutor) Run(ctx context.Context, _ string, args ...string) ([]byte, error) {
+	if len(args) == 0 || args[0] != "--config-file="+maintConfigFile {
+		return nil, errors.New("not on the maintenance config file")
+	}
+	call := strings.Join(args[1:], " ")
+	e.calls = append(e.calls, call)
+	for prefix := range e.fail {
+		if strings.HasPrefix(call, prefix) {
+			return []byte("boom"), errors.New("exit status 1")
+		}
+	}
+	for prefix := range e.hang {
+		if strings.HasPrefix(call, prefix) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+	}
+	switch {
+	case call == "maintenance info --json":
+		return []byte(`{"owner": "` + e.owner + `", "quick": {"interval": 3600000000000, "enabled": true},
+			"schedule": {"nextFullMaintenance": "` + e.nextFull.Format(time.RFC3339) + `",
+			"nextQuickMaintenance": "` + e.nextQuick.Format(time.RFC3339) + `", "runs": {}}}`), nil
+	case strings.HasPrefix(call, "maintenance set"):
+		e.owner = DefaultMaintenanceOwner
+	case call == "blob stats --raw":
+		out := e.blobs[0]
+		e.blobs = e.blobs[1:]
+		return []byte(out), nil
+	}
+	return nil, nil
+}
+
+type fakeMovers struct {
+	active int
+	err    error
+}
+
+func (f fakeMovers) ActiveMovers(context.Context) (int, error) { return f.active, f.err }
+
+func newTestMaintainer(exec *maintExecutor, movers MoverActivity) *Maintainer {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := NewClientWithExecutor(testS3Config(), testCreds(), logger, exec, Options{ConfigFile: maintConfigFile})
+	m := NewMaintainer(c, movers, MaintenanceOptions{QuickInterval: time.Hour, FullInterval: 24 * time.Hour}, logger)
+	m.now = func() time.Time { return maintNow }
+	return m
+}
+
+func TestMaintainer_ClaimsUnownedRepositoryAndRunsFull(t *testing.T) {
+	exec := &maintExecutor{
+		nextQuick: maintNow.Add(-time.Minute),
+		nextFull:  maintNow.Add(-time.Minute),
+		blobs:     []string{"Count: 10\nTotal: 5000\nAverage: 500\n", "Count: 4\nTotal: 1200\n"},
+	}
+	m := newTestMaintainer(exec, fakeMovers{})
+	m.tick(context.Background())
+
+	wantPrefix := []string{
+		maintConnect,
+		"maintenance info --json",
+		"maintenance set --owner=me --enable-quick=true --enable-full=true --quick-interval=1h0m0s --full-interval=24h0m0s",
+		"maintenance info --json",
+		"blob stats --raw",
+		"maintenance run --full",
+		"blob stats --raw",
+	}
+	if !slices.Equal(exec.calls, wantPrefix) {
+		t.Fatalf("calls:\n%s", strings.Join(exec.calls, "\n"))
+	}
+	s := m.Status()
+	if s.Full.Succeeded != 1 || s.Full.LastRun.FreedBytes != 3800 || s.Full.FreedBytes != 3800 ||
+		!s.Full.LastSuccessAt.Equal(maintNow) || s.Quick.LastRun != nil || s.RepositoryOwner != "" {
+		t.Errorf("status: %+v", s)
+	}
+
+	// Second tick: already connected and configured, only quick is due.
+	exec.calls = nil
+	exec.owner = DefaultMaintenanceOwner
+	exec.nextFull = maintNow.Add(time.Hour)
+	exec.blobs = []string{"Total: 1200\n", "Total: 1300\n"}
+	m.tick(context.Background())
+	if exec.calls[0] != "maintenance info --json" ||
+		slices.Contains(exec.calls, "maintenance run --full") || !slices.Contains(exec.calls, "maintenance run") ||
+		slices.ContainsFunc(exec.calls, func(c string) bool { return strings.HasPrefix(c, "maintenance set") }) {
+		t.Errorf("calls: %v", exec.calls)
+	}
+	if s := m.Status(); s.Quick.Succeeded != 1 || s.Quick.LastRun.FreedBytes != 0 || s.RepositoryOwner != DefaultMaintenanceOwner {
+		t.Errorf("growth must not count as freed: %+v", s.Quick)
+	}
+}
+
+// Every replica claims maintenance under the same owner, so the loop
+// must only run on the elected leader; Start returns once ctx ends.
+func TestMaintainer_LeaderElectedRunnable(t *testing.T) {
+	m := newTestMaintainer(&maintExecutor{}, nil)
+	if !m.NeedLeaderElection() {
+		t.Error("NeedLeaderElection: got false")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.Start(ctx); err != nil {
+		t.Errorf("Start: %v", err)
+	}
+}
+
+func TestMaintainer_NothingDue(t *testing.T) {
+	exec := &maintExecutor{owner: DefaultMaintenanceOwner, nextQuick: maintNow.Add(time.Minute), nextFull: maintNow.Add(time.Hour)}
+	m := newTestMaintainer(exec, fakeMovers{})
+	m.configured = true
+	m.tick(context.Background())
+	if len(exec.calls) != 2 {
+		t.Errorf("calls: %v", exec.calls)
+	}
+	if s := m.Status(); !s.Quick.NextDueAt.Equal(maintNow.Add(time.Minute)) {
+		t.Errorf("next due: %+v", s.Quick)
+	}
+}
+
+func TestMaintainer_OwnedByAnotherClientIsLeftAlone(t *testing.T) {
+	exec := &maintExecutor{owner: "admin@laptop", nextQuick: maintNow.Add(-time.Hour)}
+	m := newTestMaintainer(exec, fakeMovers{})
+	m.tick(context.Background())
+	for _, c := range exec.calls {
+		if strings.HasPrefix(c, "maintenance set") || strings.HasPrefix(c, "maintenance run") {
+			t.Errorf("touched another owner's maintenance: %q", c)
+		}
+	}
+	if s := m.Status(); s.RepositoryOwner != "admin@laptop" {
+		t.Errorf("status: %+v", s)
+	}
+}
+
+func TestMaintainer_DeferredWhileMoversActive(t *testing.T) {
+	for name, movers := range map[string]fakeMovers{
+		"active":       {active: 2},
+		"check failed": {err: errors.New("forbidden")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			exec := &maintExecutor{owner: DefaultMaintenanceOwner, nextQuick: maintNow.Add(-time.Minute), nextFull: maintNow.Add(time.Hour)}
+			m := newTestMaintainer(exec, movers)
+			m.configured = true
+			m.tick(context.Background())
+			if slices.Contains(exec.calls, "maintenance run") {
+				t.Error("ran with movers active")
+			}
+			if s := m.Status(); s.Quick.Skipped != 1 || s.Quick.LastRun.Result != MaintenanceSkipped {
+				t.Errorf("status: %+v", s.Quick)
+			}
+		})
+	}
+}
+
+func TestMaintainer_FailedRunIsRecorded(t *testing.T) {
+	exec := &maintExecutor{
+		owner: DefaultMaintenanceOwner, nextQuick: maintNow.Add(-time.Minute), nextFull: maintNow.Add(time.Hour),
+		blobs: []string{"Total: 1\n"}, fail: map[string]bool{"maintenance run": true},
+	}
+	m := newTestMaintainer(exec, nil)
+	m.configured = true
+	m.tick(context.Background())
+	if s := m.Status(); s.Quick.Failed != 1 || s.Quick.LastRun.Result != MaintenanceFailed || !s.Quick.LastSuccessAt.IsZero() {
+		t.Errorf("status: %+v", s.Quick)
+	}
+
+	// A failing connect stops the tick before anything else.
+	exec = &maintExecutor{fail: map[string]bool{"repository connect": true}}
+	m = newTestMaintainer(exec, nil)
+	m.tick(context.Background())
+	if len(exec.calls) != 1 {
+		t.Errorf("calls: %v", exec.calls)
+	}
+}
+
+// A failed schedule read may be rotated credentials or an expired
+// session, so the next tick connects again.
+func TestMaintainer_ReconnectsAfterAFailedRead(t *testing.T) {
+	exec := &maintExecutor{owner: DefaultMaintenanceOwner, nextQuick: maintNow.Add(time.Hour), nextFull: maintNow.Add(time.Hour),
+		fail: map[string]bool{"maintenance info": true}}
+	m := newTestMaintainer(exec, nil)
+	m.tick(context.Background())
+	delete(exec.fail, "maintenance info")
+	m.tick(context.Background())
+	want := []string{maintConnect, "maintenance info --json", maintConnect, "maintenance info --json"}
+	if !slices.Equal(exec.calls[:4], want) {
+		t.Errorf("calls:\n%s", strings.Join(exec.calls, "\n"))
+	}
+}
+
+// A hung run is cut off at RunTimeout rather than holding the loop, and
+// with it the leader's maintenance, forever.
+func TestMaintainer_RunTimeout(t *testing.T) {
+	exec := &maintExecutor{
+		owner: DefaultMaintenanceOwner, nextQuick: maintNow.Add(-time.Minute), nextFull: maintNow.Add(time.Hour),
+		blobs: []string{"Total: 1\n"}, hang: map[string]bool{"maintenance run": true},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := NewClientWithExecutor(testS3Config(), testCreds(), logger, exec, Options{ConfigFile: maintConfigFile})
+	m := NewMaintainer(c, nil, MaintenanceOptions{QuickInterval: time.Hour, RunTimeout: 50 * time.Millisecond}, logger)
+	m.now = func() time.Time { return maintNow }
+	m.configured = true
+	m.tick(context.Background())
+	if s := m.Status(); s.Quick.Failed != 1 || s.Quick.LastRun.Reason != "did not finish within 50ms" {
+		t.Errorf("status: %+v", s.Quick)
+	}
+}
+
+func TestMaintainer_DisabledModes(t *testing.T) {
+	exec := &maintExecutor{nextQuick: maintNow.Add(-time.Minute), nextFull: maintNow.Add(-time.Minute), blobs: []string{"Total: 2\n", "Total: 1\n"}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	m := NewMaintainer(NewClientWithExecutor(testS3Config(), testCreds(), logger, exec, Options{ConfigFile: maintConfigFile}), nil,
+		MaintenanceOptions{QuickInterval: time.Hour}, logger)
+	m.now = func() time.Time { return maintNow }
+	m.tick(context.Background())
+	if !slices.Contains(exec.calls, "maintenance set --owner=me --enable-quick=true --enable-full=false --quick-interval=1h0m0s") ||
+		!slices.Contains(exec.calls, "maintenance run") {
+		t.Errorf("calls: %v", exec.calls)
+	}
+	if s := m.Status(); s.Full.Interval != "disabled" || s.Quick.Succeeded != 1 {
+		t.Errorf("status: %+v", s)
+	}
+}
+
+func TestParseBlobStatsTotal(t *testing.T) {
+	if v, err := parseBlobStatsTotal([]byte("Count: 3\nTotal: 12345\nAverage: 4115\n")); err != nil || v != 12345 {
+		t.Errorf("got %d, %v", v, err)
+	}
+	for _, bad := range []string{"", "Count: 3\n", "Total: 1.2 GB\n"} {
+		if _, err := parseBlobStatsTotal([]byte(bad)); err == nil {
+			t.Errorf("%q parsed", bad)
+		}
+	}
+}
@@ -0,0 +1,401 @@
+// Package native reads a kopia repository directly from its blob storage
+// — format blob, index blobs, manifest contents — and enumerates snapshot
+// manifests without spawning the kopia binary.
+//
+// The CLI-backed kopia.Client forks `kopia snapshot list` for every cache
+// miss and every re-warm tick. Each fork re-opens the repository, which
+// on a large bucket means re-listing and re-reading every index blob and
+// paying scrypt again: seconds of CPU and tens of MB of RSS per call, on
+// the admission-webhook path. This reader keeps the decrypted repository
+// and the parsed index and manifest contents in memory between calls, so
+// a steady-state scan is one LIST per index prefix plus a GET of the
+// format blob, and only new blobs are fetched.
+//
+// Scope is deliberately narrow: snapshot manifests only, index format v2,
+// no ECC. Anything else fails loudly rather than reading as "no backups".
+package native
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+)
+
+// manifestTypeSnapshot is the manifest "type" label of snapshot manifests.
+// Policies, maintenance schedules and user profiles share the manifest
+// store under other types.
+const manifestTypeSnapshot = "snapshot"
+
+// Client is a read-only kopia repository reader over a BlobStore. It
+// satisfies the same BackendClient / HealthChecker / SourceSummarizer
+// contracts as kopia.Client and reports the same Backend label (kopia-s3,
+// or kopia-fs over a DirStore), so it is a drop-in replacement behind the
+// cache.
+type Client struct {
+	store          BlobStore
+	backendType    string
+	creds          kopia.CredentialsSource
+	connectTimeout time.Duration
+	logger         *slog.Logger
+
+	// mu serialises scans. The cache in front absorbs concurrent /exists
+	// traffic, so one scan at a time costs nothing and keeps the memo
+	// maps below free of finer locking.
+	mu sync.Mutex
+	// repo is the opened repository for (formatRaw, password). Re-derived
+	// only when either changes: scrypt costs ~64MiB and ~100ms.
+	repo      *repository
+	formatRaw []byte
+	password  string
+	// indexes and manifests memoise parsed blobs/contents by ID. Both are
+	// content-addressed, so an entry never goes stale; entries whose blob
+	// or content is no longer live are dropped after each scan.
+	indexes   map[string][]contentInfo
+	manifests map[string][]manifestEntry
+}
+
+// Options mirrors kopia.Options for the knobs that apply to the native
+// reader.
+type Options struct {
+	// ConnectTimeout caps how long Connect waits for credentials to
+	// become ready. Defaults to 60s when zero.
+	ConnectTimeout time.Duration
+	// BackendType is the CheckResult.Backend label. Defaults to
+	// backend.TypeKopiaS3; BACKEND_TYPE=kopia-fs sets backend.TypeKopiaFS.
+	BackendType string
+}
+
+// NewClient constructs a native reader. Nothing is read until Connect or
+// the first query.
+func NewClient(store BlobStore, creds kopia.CredentialsSource, logger *slog.Logger, opts Options) *Client {
+	connectTimeout := opts.ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = 60 * time.Second
+	}
+	backendType := opts.BackendType
+	if backendType == "" {
+		backendType = backend.TypeKopiaS3
+	}
+	return &Client{
+		store:          store,
+		backendType:    backendType,
+		creds:          creds,
+		connectTimeout: connectTimeout,
+		logger:         logger,
+		indexes:        map[string][]contentInfo{},
+		manifests:      map[string][]manifestEntry{},
+	}
+}
+
+// NewS3Client is NewClient over an S3Store — the production shape behind
+// BACKEND_TYPE=kopia-s3 with KOPIA_READER=native.
+func NewS3Client(s3 S3Options, creds kopia.CredentialsSource, logger *slog.Logger, opts Options) (*Client, error) {
+	store, err := NewS3Store(s3, creds)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(store, creds, logger, opts), nil
+}
+
+// Connect waits for credentials (same backoff as kopia.Client.Connect)
+// and opens the repository, failing on a wrong password or an
+// unsupported repository format at startup rather than on the first
+// admission request.
+func (c *Client) Connect(ctx context.Context) error {
+	if _, err := kopia.AwaitCredentials(ctx, c.creds, c.connectTimeout, c.logger); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.openLocked(ctx); err != nil {
+		return fmt.Errorf("open kopia repository: %w", err)
+	}
+	c.logger.Info("opened kopia repository (native reader)",
+		"encryption", c.repo.config.Encryption,
+		"hash", c.repo.config.Hash,
+	)
+	return nil
+}
+
+// Reconnect re-opens the repository with freshly loaded credentials after
+// a rotation (see kopia.CredentialsWatcher). openLocked only re-derives
+// keys when the password or format blob changed, and the S3 store signs
+// each request with the current keys, so this mostly proves the new
+// material works; on failure the previously opened repository stays in
+// place.
+func (c *Client) Reconnect(ctx context.Context) error {
+	return c.Connect(ctx)
+}
+
+// HealthCheck re-reads and decrypts the format blob under the current
+// password, bounded to 5s like kopia.Client's status probe. That proves
+// the store is reachable, the S3 credentials sign, and the password still
+// opens the repository.
+func (c *Client) HealthCheck(ctx context.Context) error {
+	const statusTimeout = 5 * time.Second
+	probeCtx, cancel := context.WithTimeout(ctx, statusTimeout)
+	defer cancel()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.openLocked(probeCtx); err != nil {
+		return fmt.Errorf("kopia repository status: %w", err)
+	}
+	return nil
+}
+
+// CheckBackupExists implements the BackendClient contract over the
+// query's candidate sources (kopia.QuerySources). Every source comes out
+// of one manifest scan, so the candidates are filtered from it rather
+// than listed one by one.
+func (c *Client) CheckBackupExists(ctx context.Context, q backend.Query) backend.CheckResult {
+	all, err := c.ListAllSnapshots(ctx)
+	if err != nil {
+		return c.label(kopia.CheckResultFor(q, nil, err))
+	}
+	candidates := kopia.QuerySources(q)
+	var snaps []kopia.SnapshotInfo
+	for _, s := range all {
+		if slices.Contains(candidates, s.Source) {
+			snaps = append(snaps, s)
+		}
+	}
+	return c.label(kopia.CheckResultFor(q, snaps, nil))
+}
+
+// label stamps the reader's backend type on a result.
+func (c *Client) label(r backend.CheckResult) backend.CheckResult {
+	r.Backend = c.backendType
+	return r
+}
+
+// BackendType is the CheckResult.Backend label this reader reports.
+func (c *Client) BackendType() string {
+	return c.backendType
+}
+
+// ListSnapshots returns one source's lineage, oldest first. Unlike the
+// CLI, RetentionReasons are always empty: kopia derives them from the
+// policy at list time and never stores them.
+func (c *Client) ListSnapshots(ctx context.Context, source kopia.SnapshotSource) ([]kopia.SnapshotInfo, error) {
+	all, err := c.ListAllSnapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []kopia.SnapshotInfo
+	for _, s := range all {
+		if s.Source == source {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// SummarizeAllSources implements kopia.SourceSummarizer.
+func (c *Client) SummarizeAllSources(ctx context.Context) (map[string]backend.SnapshotSummary, error) {
+	snaps, err := c.ListAllSnapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := kopia.SummarizeByPVC(snaps)
+	c.logger.Info("snapshot scan complete", "unique_sources", len(out), "snapshots", len(snaps))
+	return out, nil
+}
+
+// ListAllSnapshots returns every live snapshot manifest in the
+// repository, oldest first. Like `kopia snapshot list --all`, it leaves
+// out checkpoints: a source holding only an interrupted upload has no
+// backup to restore from.
+func (c *Client) ListAllSnapshots(ctx context.Context) ([]kopia.SnapshotInfo, error) {
+	return c.listAll(ctx, false)
+}
+
+// ListAllSnapshotsWithCheckpoints is ListAllSnapshots with checkpoints of
+// snapshots still being written (or abandoned) listed too, marked
+// Incomplete — the CLI's --incomplete.
+func (c *Client) ListAllSnapshotsWithCheckpoints(ctx context.Context) ([]kopia.SnapshotInfo, error) {
+	return c.listAll(ctx, true)
+}
+
+func (c *Client) listAll(ctx context.Context, checkpoints bool) ([]kopia.SnapshotInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	repo, err := c.openLocked(ctx)
+	if err != nil {
+		return nil, err
+	}
+	contents, err := c.loadIndexesLocked(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := c.loadManifestsLocked(ctx, repo, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []kopia.SnapshotInfo
+	for id, e := range entries {
+		if e.Labels["type"] != manifestTypeSnapshot {
+			continue
+		}
+		info, err := kopia.DecodeSnapshotManifest(id, e.Data)
+		if err != nil {
+			return nil, fmt.Errorf("decode snapshot manifest %s: %w", id, err)
+		}
+		if info.Incomplete() && !checkpoints {
+			continue
+		}
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].StartTime.Equal(out[j].StartTime) {
+			return out[i].StartTime.Before(out[j].StartTime)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+// openLocked returns the repository for the current format blob and
+// password, re-deriving keys only when either changed.
+func (c *Client) openLocked(ctx context.Context) (*repository, error) {
+	creds, err := c.creds.Load()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := c.store.Get(ctx, formatBlobID, 0, -1)
+	if err != nil {
+		return nil, fmt.Errorf("read format blob: %w", err)
+	}
+	if c.repo != nil && creds.Password == c.password && bytes.Equal(raw, c.formatRaw) {
+		return c.repo, nil
+	}
+	repo, err := openRepository(raw, creds.Password)
+	if err != nil {
+		return nil, err
+	}
+	c.repo, c.formatRaw, c.password = repo, raw, creds.Password
+	return repo, nil
+}
+
+// loadIndexesLocked lists the index blobs, parses any not seen before and
+// merges them. An index blob that vanishes between LIST and GET was
+// compacted away in the meantime; its entries live on in the compacted
+// blob, which the next scan picks up.
+func (c *Client) loadIndexesLocked(ctx context.Context, repo *repository) (map[string]contentInfo, error) {
+	live := map[string]bool{}
+	var all [][]contentInfo
+	for _, prefix := range indexBlobPrefixes {
+		ids, err := c.store.List(ctx, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("list index blobs: %w", err)
+		}
+		for _, id := range ids {
+			live[id] = true
+			ndx, ok := c.indexes[id]
+			if !ok {
+				ndx, err = c.readIndexBlob(ctx, repo, id)
+				if errors.Is(err, ErrBlobNotFound) {
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+				c.indexes[id] = ndx
+			}
+			all = append(all, ndx)
+		}
+	}
+	for id := range c.indexes {
+		if !live[id] {
+			delete(c.indexes, id)
+		}
+	}
+	return mergeIndexes(all), nil
+}
+
+func (c *Client) readIndexBlob(ctx context.Context, repo *repository, id string) ([]contentInfo, error) {
+	raw, err := c.store.Get(ctx, id, 0, -1)
+	if err != nil {
+		return nil, fmt.Errorf("read index blob: %w", err)
+	}
+	iv, err := indexBlobIV(id)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := repo.decrypt(raw, iv)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt index blob %s: %w", id, err)
+	}
+	ndx, err := parseIndexV2(plain)
+	if err != nil {
+		return nil, fmt.Errorf("index blob %s: %w", id, err)
+	}
+	return ndx, nil
+}
+
+// loadManifestsLocked reads every live manifest content and merges the
+// entries they carry.
+func (c *Client) loadManifestsLocked(ctx context.Context, repo *repository, contents map[string]contentInfo) (map[string]manifestEntry, error) {
+	live := map[string]bool{}
+	var batches [][]manifestEntry
+	for id, ci := range contents {
+		if ci.Deleted || id[0] != manifestContentPrefix {
+			continue
+		}
+		live[id] = true
+		batch, ok := c.manifests[id]
+		if !ok {
+			var err error
+			batch, err = c.readManifestContent(ctx, repo, ci)
+			if err != nil {
+				return nil, err
+			}
+			c.manifests[id] = batch
+		}
+		batches = append(batches, batch)
+	}
+	for id := range c.manifests {
+		if !live[id] {
+			delete(c.manifests, id)
+		}
+	}
+	return mergeManifests(batches), nil
+}
+
+func (c *Client) readManifestContent(ctx context.Context, repo *repository, ci contentInfo) ([]manifestEntry, error) {
+	if ci.EncryptionKey != 0 {
+		return nil, fmt.Errorf("content %s: unsupported encryption key ID %d", ci.ID, ci.EncryptionKey)
+	}
+	if len(ci.Hash) < aes.BlockSize {
+		return nil, fmt.Errorf("content %s: hash too short for IV", ci.ID)
+	}
+	raw, err := c.store.Get(ctx, ci.PackBlobID, int64(ci.PackOffset), int64(ci.PackedLength))
+	if err != nil {
+		return nil, fmt.Errorf("read content %s: %w", ci.ID, err)
+	}
+	plain, err := repo.decrypt(raw, ci.Hash[len(ci.Hash)-aes.BlockSize:])
+	if err != nil {
+		return nil, fmt.Errorf("decrypt content %s: %w", ci.ID, err)
+	}
+	data, err := decompress(ci.CompressionID, plain)
+	if err != nil {
+		return nil, fmt.Errorf("content %s: %w", ci.ID, err)
+	}
+	entries, err := parseManifestContent(data)
+	if err != nil {
+		return nil, fmt.Errorf("content %s: %w", ci.ID, err)
+	}
+	return entries, nil
+}
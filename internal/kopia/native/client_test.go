@@ -0,0 +1,491 @@
+package native
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+)
+
+// testdata/repo is a real kopia repository (kopia v0.21.1, `repository
+// create filesystem --flat`, default scrypt / AES256-GCM-HMAC-SHA256 /
+// BLAKE2B-256-128, index v2) holding five snapshots, one of them since
+// deleted:
+//
+//	data-backup@myapp:/data   2026-05-20T03:00Z 5012B "first"
+//	data-backup@myapp:/data   2026-05-21T03:00Z 5017B
+//	cache-backup@myapp:/data  2026-05-19T02:00Z 6B    (deleted)
+//	cache-backup@myapp:/data  2026-05-22T02:00Z 6B
+//	root@builder:/srv         2026-05-18T01:00Z 4B
+//
+// Regenerate with the same layout if the format ever needs to change;
+// the expectations below are the `kopia snapshot list --all --json`
+// output for it.
+const (
+	fixtureDir      = "testdata/repo"
+	fixturePassword = "pvc-plumber-fixture"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// countingStore wraps a BlobStore and counts Gets so
+// tests can assert what the memoisation saves.
+type countingStore struct {
+	BlobStore
+	gets atomic.Int64
+}
+
+func (s *countingStore) Get(ctx context.Context, id string, offset, length int64) ([]byte, error) {
+	s.gets.Add(1)
+	return s.BlobStore.Get(ctx, id, offset, length)
+}
+
+func fixtureClient(t *testing.T, store BlobStore, password string) *Client {
+	t.Helper()
+	if store == nil {
+		store = DirStore{Dir: fixtureDir}
+	}
+	return NewClient(store, kopia.NewStaticCredentialsSource(password, "ak", "sk"), discardLogger(), Options{})
+}
+
+func TestListAllSnapshots_Fixture(t *testing.T) {
+	c := fixtureClient(t, nil, fixturePassword)
+	snaps, err := c.ListAllSnapshots(context.Background())
+	if err != nil {
+		t.Fatalf("ListAllSnapshots: %v", err)
+	}
+
+	want := []struct {
+		id, source, start string
+		size              int64
+	}{
+		{"6164c5d1ebc600a94f45f01edb6dd7a4", "root@builder:/srv", "2026-05-18T01:00:00Z", 4},
+		{"1b26b0c9b702502da29c1a9631f6451f", "data-backup@myapp:/data", "2026-05-20T03:00:00Z", 5012},
+		{"abce948db00c070930b046dd13f5803d", "data-backup@myapp:/data", "2026-05-21T03:00:00Z", 5017},
+		{"a32c303e2a87ec8c08865595d1495d8d", "cache-backup@myapp:/data", "2026-05-22T02:00:00Z", 6},
+	}
+	if len(snaps) != len(want) {
+		t.Fatalf("got %d snapshots, want %d: %+v", len(snaps), len(want), snaps)
+	}
+	for i, w := range want {
+		s := snaps[i]
+		if s.ID != w.id || s.Source.String() != w.source || s.StartTime.Format(time.RFC3339) != w.start || s.TotalSize != w.size {
+			t.Errorf("[%d]: got %s %s %s %d, want %+v", i, s.ID, s.Source, s.StartTime.Format(time.RFC3339), s.TotalSize, w)
+		}
+		if s.EndTime.Before(s.StartTime) || s.Incomplete() {
+			t.Errorf("[%d]: end %s / incomplete %q", i, s.EndTime, s.IncompleteReason)
+		}
+	}
+	if snaps[1].Description != "first" || snaps[1].FileCount != 2 || snaps[1].DirCount != 1 {
+		t.Errorf("manifest fields: %+v", snaps[1])
+	}
+}
+
+func TestListAllSnapshots_DeletedManifestDropped(t *testing.T) {
+	c := fixtureClient(t, nil, fixturePassword)
+	snaps, err := c.ListSnapshots(context.Background(), kopia.SnapshotSource{Host: "myapp", UserName: "cache-backup", Path: "/data"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != 1 || snaps[0].ID != "a32c303e2a87ec8c08865595d1495d8d" {
+		t.Errorf("deleted snapshot 44e171a4… must not be listed: %+v", snaps)
+	}
+}
+
+func TestListAllSnapshots_WrongPassword(t *testing.T) {
+	c := fixtureClient(t, nil, "not-the-password")
+	_, err := c.ListAllSnapshots(context.Background())
+	if !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("got %v, want ErrWrongPassword", err)
+	}
+}
+
+func TestListAllSnapshots_MemoisesBlobs(t *testing.T) {
+	store := &countingStore{BlobStore: DirStore{Dir: fixtureDir}}
+	c := fixtureClient(t, store, fixturePassword)
+	if _, err := c.ListAllSnapshots(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	first := store.gets.Load()
+	if _, err := c.ListAllSnapshots(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// A second scan over an unchanged repository re-reads only the format
+	// blob; every index blob and manifest content is served from memory.
+	if got := store.gets.Load() - first; got != 1 {
+		t.Errorf("second scan did %d GETs, want 1 (format blob only); first scan did %d", got, first)
+	}
+}
+
+func TestCheckBackupExists_Restore(t *testing.T) {
+	c := fixtureClient(t, nil, fixturePassword)
+	r := c.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "data", ""))
+	if !r.Exists || r.Decision != backend.DecisionRestore || !r.Authoritative {
+		t.Fatalf("result: %+v", r)
+	}
+	if r.Backend != backend.TypeKopiaS3 || r.Source != "data-backup@myapp:/data" {
+		t.Errorf("identity: %+v", r)
+	}
+	if r.SnapshotCount != 2 || r.LatestSnapshotSize != 5017 ||
+		!r.LatestSnapshotAt.Equal(time.Date(2026, 5, 21, 3, 0, 0, 0, time.UTC)) {
+		t.Errorf("summary: %+v", r)
+	}
+}
+
+func TestCheckBackupExists_Fresh(t *testing.T) {
+	c := fixtureClient(t, nil, fixturePassword)
+	r := c.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "never-backed-up", ""))
+	if r.Exists || r.Decision != backend.DecisionFresh || !r.Authoritative || r.Error != "" {
+		t.Errorf("result: %+v", r)
+	}
+}
+
+// TestCheckBackupExists_IdentityOverride reads the fixture's legacy
+// lineage through an override naming it, the way an adopted PVC whose
+// annotation points at its old source would.
+func TestCheckBackupExists_IdentityOverride(t *testing.T) {
+	c := fixtureClient(t, nil, fixturePassword)
+	r := c.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "restored", "data-backup"))
+	if !r.Exists || r.SnapshotCount != 2 || r.Source != "data-backup@myapp:/data" || r.Identity != "data-backup" || r.Pvc != "restored" {
+		t.Errorf("result: %+v", r)
+	}
+	r = c.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "data", "elsewhere"))
+	if r.Exists || r.Decision != backend.DecisionFresh || r.Source != "elsewhere@myapp:/data" {
+		t.Errorf("override hides the legacy lineage: %+v", r)
+	}
+}
+
+func TestCheckBackupExists_ErrorIsUnknown(t *testing.T) {
+	c := fixtureClient(t, DirStore{Dir: t.TempDir()}, fixturePassword)
+	r := c.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "data", ""))
+	if r.Decision != backend.DecisionUnknown || r.Authoritative || !strings.Contains(r.Error, "format blob") {
+		t.Errorf("result: %+v", r)
+	}
+}
+
+// TestSummarizeAllSources_KeysByPVC pins the pre-warm keys: each legacy
+// source keys its PVC and, read as a v4 source, a PVC of its own name;
+// root@builder:/srv follows neither convention and is skipped.
+func TestSummarizeAllSources_KeysByPVC(t *testing.T) {
+	c := fixtureClient(t, nil, fixturePassword)
+	got, err := c.SummarizeAllSources(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := make([]string, 0, len(got))
+	for k := range got {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"myapp/cache", "myapp/cache-backup", "myapp/data", "myapp/data-backup"}) {
+		t.Fatalf("keys: %v", keys)
+	}
+	if got["myapp/cache"].Count != 1 || got["myapp/data"].LatestSize != 5017 || got["myapp/data"].Source != "data-backup@myapp:/data" {
+		t.Errorf("summaries: %+v", got)
+	}
+}
+
+// overlayStore serves extra blobs on top of a BlobStore, the way a
+// repository grows new packs and indexes between scans.
+type overlayStore struct {
+	BlobStore
+	extra map[string][]byte
+}
+
+func (s overlayStore) List(ctx context.Context, prefix string) ([]string, error) {
+	ids, err := s.BlobStore.List(ctx, prefix)
+	for id := range s.extra {
+		if strings.HasPrefix(id, prefix) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, err
+}
+
+func (s overlayStore) Get(ctx context.Context, id string, offset, length int64) ([]byte, error) {
+	b, ok := s.extra[id]
+	if !ok {
+		return s.BlobStore.Get(ctx, id, offset, length)
+	}
+	if length < 0 {
+		length = int64(len(b)) - offset
+	}
+	return b[offset : offset+length], nil
+}
+
+// checkpointStore is the fixture plus one manifest content holding a
+// single checkpoint — the manifest kopia writes every few minutes of an
+// upload that has not finished — for partial-backup@myapp:/data, a source
+// with no complete snapshot at all. kopia cannot be run here, so the pack
+// and index blobs are written the way kopia writes them: the content
+// zstd-compressed and sealed under its hash, the index sealed under the
+// hash in its blob ID.
+func checkpointStore(t *testing.T) BlobStore {
+	t.Helper()
+	base := DirStore{Dir: fixtureDir}
+	raw, err := base.Get(context.Background(), formatBlobID, 0, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := openRepository(raw, fixturePassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seal := func(plain, iv []byte) []byte {
+		mac := hmac.New(sha256.New, repo.keySecret)
+		mac.Write(iv)
+		aead, err := repo.newAEAD(mac.Sum(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			t.Fatal(err)
+		}
+		return aead.Seal(nonce, nonce, plain, iv)
+	}
+	random := func() []byte {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	start := time.Date(2026, 5, 23, 4, 0, 0, 0, time.UTC)
+	doc, err := json.Marshal(map[string]any{"entries": []map[string]any{{
+		"id":       "0c4ec4ec4ec4ec4ec4ec4ec4ec4ec4ec",
+		"labels":   map[string]string{"type": "snapshot", "hostname": "myapp", "username": "partial-backup", "path": "/data"},
+		"modified": start.Add(5 * time.Minute),
+		"data": map[string]any{
+			"source":     map[string]string{"host": "myapp", "userName": "partial-backup", "path": "/data"},
+			"startTime":  start,
+			"endTime":    start.Add(5 * time.Minute),
+			"incomplete": "checkpoint",
+			"stats":      map[string]int64{"totalSize": 4096},
+		},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(doc)
+	zw.Close()
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := binary.BigEndian.AppendUint32(nil, 0x1101)
+	plain = enc.EncodeAll(gz.Bytes(), plain)
+	enc.Close()
+
+	hash := random()
+	content := seal(plain, hash)
+	pack := "p" + hex.EncodeToString(random()) + "-s0c4ec4ec4ec4ec4e146"
+	ndx := buildIndexV2(uint32(start.Unix()), pack, []struct {
+		key          []byte
+		ts           uint32
+		deleted      bool
+		offset       uint32
+		orig, packed uint32
+	}{{key: append([]byte{manifestContentPrefix}, hash...), orig: uint32(len(plain)), packed: uint32(len(content))}})
+	iv := random()
+	return overlayStore{BlobStore: base, extra: map[string][]byte{
+		pack: content,
+		"xn0_" + hex.EncodeToString(iv) + "-s0c4ec4ec4ec4ec4e146-c1": seal(ndx, iv),
+	}}
+}
+
+// TestListAllSnapshots_CheckpointOnlySource pins that a lineage holding
+// only a checkpoint is no backup: ListAllSnapshots, CheckBackupExists and
+// the pre-warm summaries leave it out, and only the prune listing sees it.
+func TestListAllSnapshots_CheckpointOnlySource(t *testing.T) {
+	ctx := context.Background()
+	c := fixtureClient(t, checkpointStore(t), fixturePassword)
+	partial := kopia.SnapshotSource{Host: "myapp", UserName: "partial-backup", Path: "/data"}
+
+	all, err := c.ListAllSnapshots(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4 || slices.ContainsFunc(all, kopia.SnapshotInfo.Incomplete) {
+		t.Errorf("ListAllSnapshots lists the checkpoint: %+v", all)
+	}
+
+	with, err := c.ListAllSnapshotsWithCheckpoints(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(with) != 5 || with[4].Source != partial || with[4].IncompleteReason != "checkpoint" {
+		t.Errorf("ListAllSnapshotsWithCheckpoints: %+v", with)
+	}
+
+	r := c.CheckBackupExists(ctx, backend.NewQuery("myapp", "data", "partial-backup"))
+	if r.Exists || r.Decision != backend.DecisionFresh || r.SnapshotCount != 0 || r.Error != "" {
+		t.Errorf("checkpoint-only lineage answered %+v, want fresh", r)
+	}
+
+	sums, err := c.SummarizeAllSources(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sums["myapp/partial-backup"]; ok {
+		t.Errorf("summaries key the checkpoint-only source: %+v", sums)
+	}
+}
+
+func TestConnectAndHealthCheck(t *testing.T) {
+	c := fixtureClient(t, nil, fixturePassword)
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if err := c.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("HealthCheck: %v", err)
+	}
+}
+
+func TestHealthCheck_PasswordRotatedAway(t *testing.T) {
+	dir := t.TempDir()
+	for name, v := range map[string]string{"KOPIA_PASSWORD": fixturePassword, "AWS_ACCESS_KEY_ID": "ak", "AWS_SECRET_ACCESS_KEY": "sk"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(v), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := NewClient(DirStore{Dir: fixtureDir}, kopia.NewDirCredentialsSource(dir), discardLogger(), Options{})
+	if err := c.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("HealthCheck: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "KOPIA_PASSWORD"), []byte("rotated"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.HealthCheck(context.Background()); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("after rotation: got %v, want ErrWrongPassword", err)
+	}
+}
+
+func TestReconnect_WrongPasswordKeepsOpenRepository(t *testing.T) {
+	dir := t.TempDir()
+	for name, v := range map[string]string{"KOPIA_PASSWORD": fixturePassword, "AWS_ACCESS_KEY_ID": "ak", "AWS_SECRET_ACCESS_KEY": "sk"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(v), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := NewClient(DirStore{Dir: fixtureDir}, kopia.NewDirCredentialsSource(dir), discardLogger(), Options{})
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	opened := c.repo
+	if err := os.WriteFile(filepath.Join(dir, "KOPIA_PASSWORD"), []byte("rotated"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reconnect(context.Background()); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("Reconnect: got %v, want ErrWrongPassword", err)
+	}
+	if c.repo != opened || c.password != fixturePassword {
+		t.Error("failed Reconnect replaced the open repository")
+	}
+}
+
+func TestConnect_CredentialsNeverReady(t *testing.T) {
+	c := NewClient(DirStore{Dir: fixtureDir}, kopia.NewDirCredentialsSource(t.TempDir()), discardLogger(),
+		Options{ConnectTimeout: 10 * time.Millisecond})
+	if err := c.Connect(context.Background()); !errors.Is(err, kopia.ErrCredentialsNotReady) {
+		t.Errorf("got %v, want ErrCredentialsNotReady", err)
+	}
+}
+
+func TestDirStore_ListAndRange(t *testing.T) {
+	s := DirStore{Dir: fixtureDir}
+	ids, err := s.List(context.Background(), "xn")
+	if err != nil || len(ids) == 0 {
+		t.Fatalf("List: %v %v", ids, err)
+	}
+	for _, id := range ids {
+		if strings.HasSuffix(id, dirBlobSuffix) || !strings.HasPrefix(id, "xn") {
+			t.Errorf("id %q", id)
+		}
+	}
+	b, err := s.Get(context.Background(), formatBlobID, 4, 6)
+	if err != nil || string(b) != "\"tool\"" {
+		t.Errorf("ranged Get: %q %v", b, err)
+	}
+	if _, err := s.Get(context.Background(), "nope", 0, -1); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("missing blob: %v", err)
+	}
+}
+
+// shardFixture copies the flat fixture into kopia's default sharded
+// filesystem layout: data blobs split 1+3 characters into directories,
+// kopia.* blobs left at the root the way kopia's shard overrides keep them.
+func shardFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	entries, err := os.ReadDir(fixtureDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		b, err := os.ReadFile(filepath.Join(fixtureDir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst := filepath.Join(dir, e.Name())
+		if !strings.HasPrefix(e.Name(), "kopia.") {
+			dst = filepath.Join(dir, e.Name()[:1], e.Name()[1:4], e.Name()[4:])
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dst, b, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestDirStore_ShardedLayout(t *testing.T) {
+	sharded := DirStore{Dir: shardFixture(t)}
+	flat := DirStore{Dir: fixtureDir}
+	for _, prefix := range []string{"", "xn", "q", "kopia."} {
+		got, err := sharded.List(context.Background(), prefix)
+		if err != nil {
+			t.Fatalf("List(%q): %v", prefix, err)
+		}
+		want, _ := flat.List(context.Background(), prefix)
+		slices.Sort(got)
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			t.Errorf("List(%q) = %v, want %v", prefix, got, want)
+		}
+	}
+
+	c := NewClient(sharded, kopia.NewPasswordCredentialsSource(fixturePassword), discardLogger(),
+		Options{BackendType: backend.TypeKopiaFS})
+	r := c.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "data", ""))
+	if !r.Exists || r.SnapshotCount != 2 || r.Backend != backend.TypeKopiaFS {
+		t.Errorf("sharded repository result: %+v", r)
+	}
+}
@@ -0,0 +1,200 @@
+package native
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// formatBlobID is the unencrypted JSON blob at the root of every kopia
+// repository. It names the password KDF and carries the repository
+// config (hash, encryption, master key) encrypted under the password.
+const formatBlobID = "kopia.repository"
+
+// ErrWrongPassword is returned when the format blob does not decrypt
+// under the configured password. Distinguished from transport errors so
+// /readyz logs say which of the two broke.
+var ErrWrongPassword = errors.New("kopia repository password is incorrect")
+
+// formatBlob is kopia's format.KopiaRepositoryJSON.
+type formatBlob struct {
+	UniqueID             []byte `json:"uniqueID"`
+	KeyAlgo              string `json:"keyAlgo"`
+	Encryption           string `json:"encryption"`
+	EncryptedBlockFormat []byte `json:"encryptedBlockFormat"`
+}
+
+// repositoryConfig is the subset of kopia's format.RepositoryConfig the
+// reader needs to decrypt contents.
+type repositoryConfig struct {
+	Hash         string `json:"hash"`
+	Encryption   string `json:"encryption"`
+	ECC          string `json:"ecc"`
+	MasterKey    []byte `json:"masterKey"`
+	Version      int    `json:"version"`
+	IndexVersion int    `json:"indexVersion"`
+}
+
+// Format-blob and content-key derivation constants, from kopia's
+// internal/crypto and repo/encryption packages.
+const (
+	formatKeySize          = 32
+	formatEncryptionAES    = "AES256_GCM"
+	kdfScrypt65536         = "scrypt-65536-8-1"
+	kdfPBKDF2SHA256        = "pbkdf2-sha256-600000"
+	pbkdf2Iterations       = 600000
+	purposeFormatAESKey    = "AES"
+	purposeFormatAuthData  = "CHECKSUM"
+	purposeContentKey      = "encryption"
+	encAES256GCMHMACSHA256 = "AES256-GCM-HMAC-SHA256"
+	encChaCha20HMACSHA256  = "CHACHA20-POLY1305-HMAC-SHA256"
+	indexBlobIVHexLen      = 2 * aes.BlockSize
+)
+
+// repository is an opened kopia repository: the decrypted config plus the
+// per-content AEAD factory derived from its master key.
+type repository struct {
+	config    repositoryConfig
+	keySecret []byte
+	newAEAD   func(key []byte) (cipher.AEAD, error)
+}
+
+// openRepository decrypts the format blob with password. Repositories
+// using ECC or an index format older than v2 are rejected with an error
+// naming the feature: the reader does not implement them, and guessing
+// would turn "unsupported" into "no backups".
+func openRepository(raw []byte, password string) (*repository, error) {
+	var fb formatBlob
+	if err := json.Unmarshal(raw, &fb); err != nil {
+		return nil, fmt.Errorf("invalid format blob: %w", err)
+	}
+	if fb.Encryption != formatEncryptionAES {
+		return nil, fmt.Errorf("unsupported format blob encryption %q", fb.Encryption)
+	}
+	formatKey, err := deriveFormatKey(password, fb.UniqueID, fb.KeyAlgo)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := decryptFormatBlock(fb.EncryptedBlockFormat, formatKey, fb.UniqueID)
+	if err != nil {
+		return nil, err
+	}
+	var wrapper struct {
+		Format repositoryConfig `json:"format"`
+	}
+	if err := json.Unmarshal(plain, &wrapper); err != nil {
+		return nil, fmt.Errorf("invalid repository config: %w", err)
+	}
+	cfg := wrapper.Format
+	if cfg.ECC != "" {
+		return nil, fmt.Errorf("unsupported repository: error correction %q", cfg.ECC)
+	}
+	if cfg.Version < 2 || cfg.IndexVersion < 2 {
+		return nil, fmt.Errorf("unsupported repository: format version %d, index version %d (need 2+)", cfg.Version, cfg.IndexVersion)
+	}
+
+	r := &repository{config: cfg}
+	switch cfg.Encryption {
+	case encAES256GCMHMACSHA256:
+		r.newAEAD = func(key []byte) (cipher.AEAD, error) {
+			blk, err := aes.NewCipher(key)
+			if err != nil {
+				return nil, err
+			}
+			return cipher.NewGCM(blk)
+		}
+	case encChaCha20HMACSHA256:
+		r.newAEAD = chacha20poly1305.New
+	default:
+		return nil, fmt.Errorf("unsupported content encryption %q", cfg.Encryption)
+	}
+	r.keySecret, err = hkdf.Key(sha256.New, cfg.MasterKey, []byte(purposeContentKey), "", 32)
+	if err != nil {
+		return nil, fmt.Errorf("derive content key: %w", err)
+	}
+	return r, nil
+}
+
+// deriveFormatKey stretches the repository password with the KDF named in
+// the format blob, salted with the repository's unique ID.
+func deriveFormatKey(password string, salt []byte, algo string) ([]byte, error) {
+	switch algo {
+	case kdfScrypt65536:
+		return scrypt.Key([]byte(password), salt, 65536, 8, 1, formatKeySize)
+	case kdfPBKDF2SHA256:
+		return pbkdf2.Key(sha256.New, password, salt, pbkdf2Iterations, formatKeySize)
+	default:
+		return nil, fmt.Errorf("unsupported key derivation %q", algo)
+	}
+}
+
+// decryptFormatBlock opens the AES-256-GCM encrypted repository config.
+// A GCM authentication failure here can only mean the password is wrong
+// (or the blob is corrupt), so it maps to ErrWrongPassword.
+func decryptFormatBlock(data, formatKey, uniqueID []byte) ([]byte, error) {
+	aesKey, err := hkdf.Key(sha256.New, formatKey, uniqueID, purposeFormatAESKey, 32)
+	if err != nil {
+		return nil, err
+	}
+	authData, err := hkdf.Key(sha256.New, formatKey, uniqueID, purposeFormatAuthData, 32)
+	if err != nil {
+		return nil, err
+	}
+	blk, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(blk)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("format blob: encrypted block too short")
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], authData)
+	if err != nil {
+		return nil, ErrWrongPassword
+	}
+	return plain, nil
+}
+
+// decrypt opens a content or index blob payload encrypted under iv. The
+// per-payload key is HMAC-SHA256(keySecret, iv); the nonce is the
+// payload's prefix and iv doubles as the additional data.
+func (r *repository) decrypt(payload, iv []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, r.keySecret)
+	mac.Write(iv)
+	aead, err := r.newAEAD(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) < aead.NonceSize() {
+		return nil, errors.New("encrypted payload too short")
+	}
+	return aead.Open(nil, payload[:aead.NonceSize()], payload[aead.NonceSize():], iv)
+}
+
+// indexBlobIV derives the IV of an index blob from its ID: the last 32 hex
+// characters before the first "-" (the blob's content hash).
+func indexBlobIV(id string) ([]byte, error) {
+	s, _, _ := strings.Cut(id, "-")
+	if len(s) < indexBlobIVHexLen {
+		return nil, fmt.Errorf("blob id too short: %s", id)
+	}
+	iv, err := hex.DecodeString(s[len(s)-indexBlobIVHexLen:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid blob id %s: %w", id, err)
+	}
+	return iv, nil
+}
@@ -0,0 +1,167 @@
+package native
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// Index blob prefixes the reader loads. "n" is the pre-epoch (v0) index
+// layout; "xn", "xs" and "xr" are the epoch manager's uncompacted,
+// single-epoch compacted and range-checkpoint blobs. Compaction logs
+// ("m"), epoch markers ("xe") and write markers ("xw") hold no index
+// entries. Reading compacted blobs alongside the uncompacted ones they
+// superseded is harmless: the merge below resolves every content to the
+// same winner kopia itself would pick.
+var indexBlobPrefixes = []string{"n", "xn", "xs", "xr"}
+
+// manifestContentPrefix is the content ID prefix byte of manifest
+// contents (snapshot, policy, …) — the only contents the reader fetches.
+const manifestContentPrefix = 'm'
+
+// Index v2 layout, from kopia's repo/content/index/index_v2.go.
+const (
+	indexV2Version        = 2
+	indexV2HeaderSize     = 17
+	indexV2PackInfoSize   = 5
+	indexV2FormatInfoSize = 6
+	indexV2EntryMinLength = 16
+	indexV2EntryMaxLength = 19
+
+	v2EntryDeletedFlag    = 0x80
+	v2EntryPackOffsetMask = 1<<31 - 1
+)
+
+// contentInfo is one index entry, reduced to what locating and decoding
+// a content needs.
+type contentInfo struct {
+	ID             string // prefix byte + hex hash, kopia's content.ID form
+	Hash           []byte
+	Timestamp      int64
+	Deleted        bool
+	PackBlobID     string
+	PackOffset     uint32
+	PackedLength   uint32
+	CompressionID  uint32
+	EncryptionKey  byte
+	OriginalLength uint32
+}
+
+// parseIndexV2 decodes a decrypted index v2 blob into its entries. Index
+// v1 (pre-0.9 repositories) is rejected.
+func parseIndexV2(data []byte) ([]contentInfo, error) {
+	if len(data) < indexV2HeaderSize {
+		return nil, errors.New("index: truncated header")
+	}
+	if data[0] != indexV2Version {
+		return nil, fmt.Errorf("index: unsupported version %d", data[0])
+	}
+	keySize := int(data[1])
+	entrySize := int(binary.BigEndian.Uint16(data[2:4]))
+	entryCount := int(binary.BigEndian.Uint32(data[4:8]))
+	packCount := int(binary.BigEndian.Uint32(data[8:12]))
+	formatCount := int(data[12])
+	baseTimestamp := int64(binary.BigEndian.Uint32(data[13:17]))
+	if keySize <= 1 || entrySize < indexV2EntryMinLength || entrySize > indexV2EntryMaxLength {
+		return nil, fmt.Errorf("index: invalid header (key %d, entry %d)", keySize, entrySize)
+	}
+
+	stride := keySize + entrySize
+	packsOffset := indexV2HeaderSize + entryCount*stride
+	formatsOffset := packsOffset + packCount*indexV2PackInfoSize
+	if formatsOffset+formatCount*indexV2FormatInfoSize > len(data) {
+		return nil, errors.New("index: truncated body")
+	}
+
+	packs := make([]string, packCount)
+	for i := range packs {
+		rec := data[packsOffset+i*indexV2PackInfoSize:]
+		n := int(rec[0])
+		off := int(binary.BigEndian.Uint32(rec[1:5]))
+		if off+n > len(data) {
+			return nil, errors.New("index: pack name out of range")
+		}
+		packs[i] = string(data[off : off+n])
+	}
+
+	type format struct {
+		compression uint32
+		keyID       byte
+	}
+	formats := make([]format, formatCount)
+	for i := range formats {
+		rec := data[formatsOffset+i*indexV2FormatInfoSize:]
+		formats[i] = format{compression: binary.BigEndian.Uint32(rec[0:4]), keyID: rec[5]}
+	}
+
+	out := make([]contentInfo, 0, entryCount)
+	for i := range entryCount {
+		rec := data[indexV2HeaderSize+i*stride:]
+		key, e := rec[:keySize], rec[keySize:stride]
+
+		ci := contentInfo{
+			Timestamp:      int64(binary.BigEndian.Uint32(e[0:4])) + baseTimestamp,
+			Deleted:        e[4]&v2EntryDeletedFlag != 0,
+			PackOffset:     binary.BigEndian.Uint32(e[4:8]) & v2EntryPackOffsetMask,
+			OriginalLength: uint24(e[8:11]),
+			PackedLength:   uint24(e[11:14]),
+		}
+		packIdx := int(binary.BigEndian.Uint16(e[14:16]))
+		fid := 0
+		if entrySize > 16 {
+			fid = int(e[16])
+		}
+		if entrySize > 17 {
+			packIdx |= int(e[17]) << 16
+		}
+		if entrySize > 18 {
+			ci.OriginalLength |= uint32(e[18]>>4) << 24
+			ci.PackedLength |= uint32(e[18]&0xF) << 24
+		}
+		if packIdx >= len(packs) || fid >= len(formats) {
+			return nil, fmt.Errorf("index: entry %d references missing pack or format", i)
+		}
+		ci.PackBlobID = packs[packIdx]
+		ci.CompressionID = formats[fid].compression
+		ci.EncryptionKey = formats[fid].keyID
+
+		ci.Hash = append([]byte(nil), key[1:]...)
+		ci.ID = hex.EncodeToString(ci.Hash)
+		if key[0] != 0 {
+			ci.ID = string(key[0]) + ci.ID
+		}
+		out = append(out, ci)
+	}
+	return out, nil
+}
+
+func uint24(b []byte) uint32 {
+	return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+}
+
+// mergeIndexes resolves every content ID to the winning entry across all
+// index blobs, with kopia's rule: newer timestamp wins; on a tie a live
+// entry beats a deletion marker; then the greater pack blob ID, so the
+// choice is deterministic.
+func mergeIndexes(indexes [][]contentInfo) map[string]contentInfo {
+	out := make(map[string]contentInfo)
+	for _, ndx := range indexes {
+		for _, ci := range ndx {
+			if cur, ok := out[ci.ID]; !ok || contentNewer(ci, cur) {
+				out[ci.ID] = ci
+			}
+		}
+	}
+	return out
+}
+
+func contentNewer(a, b contentInfo) bool {
+	if a.Timestamp != b.Timestamp {
+		return a.Timestamp > b.Timestamp
+	}
+	if a.Deleted != b.Deleted {
+		return !a.Deleted
+	}
+	return a.PackBlobID > b.PackBlobID
+}
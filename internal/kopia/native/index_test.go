@@ -0,0 +1,142 @@
+package native
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildIndexV2 encodes entries in kopia's index v2 layout with a 19-byte
+// entry (format ID, extended pack ID and high length bits all present) so
+// the optional-byte paths are exercised. keys are prefix byte + 16-byte
+// hash; every entry uses format 0 (zstd-fastest) and pack 0.
+func buildIndexV2(base uint32, pack string, entries []struct {
+	key          []byte
+	ts           uint32
+	deleted      bool
+	offset       uint32
+	orig, packed uint32
+}) []byte {
+	const keySize, entrySize = 17, 19
+	stride := keySize + entrySize
+	packsOff := indexV2HeaderSize + len(entries)*stride
+	formatsOff := packsOff + indexV2PackInfoSize
+	nameOff := formatsOff + indexV2FormatInfoSize
+
+	b := make([]byte, nameOff+len(pack))
+	b[0], b[1] = indexV2Version, keySize
+	binary.BigEndian.PutUint16(b[2:], entrySize)
+	binary.BigEndian.PutUint32(b[4:], uint32(len(entries)))
+	binary.BigEndian.PutUint32(b[8:], 1)
+	b[12] = 1
+	binary.BigEndian.PutUint32(b[13:], base)
+	for i, e := range entries {
+		rec := b[indexV2HeaderSize+i*stride:]
+		copy(rec, e.key)
+		v := rec[keySize:]
+		binary.BigEndian.PutUint32(v[0:], e.ts)
+		binary.BigEndian.PutUint32(v[4:], e.offset)
+		if e.deleted {
+			v[4] |= v2EntryDeletedFlag
+		}
+		v[8], v[9], v[10] = byte(e.orig>>16), byte(e.orig>>8), byte(e.orig)
+		v[11], v[12], v[13] = byte(e.packed>>16), byte(e.packed>>8), byte(e.packed)
+		v[18] = byte(e.orig>>24)<<4 | byte(e.packed>>24)&0xF
+	}
+	b[packsOff] = byte(len(pack))
+	binary.BigEndian.PutUint32(b[packsOff+1:], uint32(nameOff))
+	binary.BigEndian.PutUint32(b[formatsOff:], 0x1101)
+	copy(b[nameOff:], pack)
+	return b
+}
+
+func TestParseIndexV2(t *testing.T) {
+	manifestKey := append([]byte{'m'}, make([]byte, 16)...)
+	manifestKey[16] = 0xab
+	dataKey := make([]byte, 17) // unprefixed content
+	dataKey[1] = 0x01
+
+	raw := buildIndexV2(1_700_000_000, "qdeadbeef", []struct {
+		key          []byte
+		ts           uint32
+		deleted      bool
+		offset       uint32
+		orig, packed uint32
+	}{
+		{key: dataKey, ts: 5, offset: 0, orig: 100, packed: 80},
+		{key: manifestKey, ts: 7, deleted: true, offset: 4096, orig: 1<<24 + 3, packed: 2<<24 + 9},
+	})
+	got, err := parseIndexV2(raw)
+	if err != nil {
+		t.Fatalf("parseIndexV2: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("entries: %d", len(got))
+	}
+	d, m := got[0], got[1]
+	if d.ID != "01000000000000000000000000000000" || d.Timestamp != 1_700_000_005 || d.Deleted ||
+		d.OriginalLength != 100 || d.PackedLength != 80 || d.PackBlobID != "qdeadbeef" || d.CompressionID != 0x1101 {
+		t.Errorf("data entry: %+v", d)
+	}
+	if !strings.HasPrefix(m.ID, "m") || !strings.HasSuffix(m.ID, "ab") || !m.Deleted || m.PackOffset != 4096 ||
+		m.OriginalLength != 1<<24+3 || m.PackedLength != 2<<24+9 {
+		t.Errorf("manifest entry: %+v", m)
+	}
+}
+
+func TestParseIndexV2_RejectsV1AndTruncation(t *testing.T) {
+	if _, err := parseIndexV2([]byte{1, 17, 0, 16, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}); err == nil ||
+		!strings.Contains(err.Error(), "unsupported version 1") {
+		t.Errorf("v1: %v", err)
+	}
+	raw := buildIndexV2(0, "p1", nil)
+	binary.BigEndian.PutUint32(raw[4:], 3) // claims 3 entries, has none
+	if _, err := parseIndexV2(raw); err == nil {
+		t.Error("truncated index must fail")
+	}
+}
+
+func TestMergeIndexes(t *testing.T) {
+	a := contentInfo{ID: "m1", Timestamp: 10, PackBlobID: "p-a"}
+	newerDeleted := contentInfo{ID: "m1", Timestamp: 11, Deleted: true, PackBlobID: "p-b"}
+	tieDeleted := contentInfo{ID: "m2", Timestamp: 10, Deleted: true, PackBlobID: "p-z"}
+	tieLive := contentInfo{ID: "m2", Timestamp: 10, PackBlobID: "p-a"}
+	tieLow := contentInfo{ID: "m3", Timestamp: 10, PackBlobID: "p-a"}
+	tieHigh := contentInfo{ID: "m3", Timestamp: 10, PackBlobID: "p-b"}
+
+	got := mergeIndexes([][]contentInfo{{a, tieDeleted, tieHigh}, {newerDeleted, tieLive, tieLow}})
+	if !got["m1"].Deleted {
+		t.Error("newer timestamp must win, even as a deletion")
+	}
+	if got["m2"].Deleted {
+		t.Error("on a timestamp tie the live entry must win")
+	}
+	if got["m3"].PackBlobID != "p-b" {
+		t.Error("on a full tie the greater pack blob ID must win")
+	}
+}
+
+func TestMergeManifests(t *testing.T) {
+	t0 := time.Date(2026, 5, 20, 0, 0, 0, 0, time.UTC)
+	got := mergeManifests([][]manifestEntry{
+		{{ID: "s1", Modified: t0}, {ID: "s2", Modified: t0}},
+		{{ID: "s1", Modified: t0.Add(time.Minute), Deleted: true}, {ID: "s2", Modified: t0.Add(-time.Minute), Deleted: true}},
+	})
+	if _, ok := got["s1"]; ok {
+		t.Error("s1's newest entry is a tombstone; it must be dropped")
+	}
+	if _, ok := got["s2"]; !ok {
+		t.Error("s2's tombstone is older than its live entry; it must survive")
+	}
+}
+
+func TestIndexBlobIV(t *testing.T) {
+	iv, err := indexBlobIV("xn0_2d731e220a156292e34fce77ccdcdf0f-s595ae2270de79141146-c1")
+	if err != nil || len(iv) != 16 || iv[0] != 0x2d || iv[15] != 0x0f {
+		t.Errorf("iv %x, err %v", iv, err)
+	}
+	if _, err := indexBlobIV("xn0_abc-c1"); err == nil {
+		t.Error("short ID must fail")
+	}
+}
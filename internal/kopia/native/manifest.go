@@ -0,0 +1,122 @@
+package native
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression header IDs from kopia's repo/compression/compression_ids.go.
+// Each compressed content starts with its 4-byte big-endian header ID.
+// Manifests are always written zstd-fastest; the other families are
+// accepted so a repository whose policy compresses everything still reads.
+const (
+	compressionHeaderSize = 4
+
+	compressionGzipFirst    = 0x1000
+	compressionGzipLast     = 0x1002
+	compressionZstdFirst    = 0x1100
+	compressionZstdLast     = 0x1103
+	compressionS2First      = 0x1200
+	compressionS2Last       = 0x1203
+	compressionPgzipFirst   = 0x1300
+	compressionPgzipLast    = 0x1302
+	compressionDeflateFirst = 0x1500
+	compressionDeflateLast  = 0x1502
+)
+
+// decompress undoes content-level compression. id 0 means the content was
+// stored uncompressed.
+func decompress(id uint32, data []byte) ([]byte, error) {
+	if id == 0 {
+		return data, nil
+	}
+	if len(data) < compressionHeaderSize {
+		return nil, errors.New("compressed content too short")
+	}
+	if got := binary.BigEndian.Uint32(data[:compressionHeaderSize]); got != id {
+		return nil, fmt.Errorf("compression header %x does not match index %x", got, id)
+	}
+	body := bytes.NewReader(data[compressionHeaderSize:])
+
+	var r io.Reader
+	switch {
+	case id >= compressionZstdFirst && id <= compressionZstdLast:
+		dec, err := zstd.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer dec.Close()
+		r = dec
+	case id >= compressionGzipFirst && id <= compressionGzipLast,
+		id >= compressionPgzipFirst && id <= compressionPgzipLast:
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		r = gz
+	case id >= compressionS2First && id <= compressionS2Last:
+		r = s2.NewReader(body)
+	case id >= compressionDeflateFirst && id <= compressionDeflateLast:
+		r = flate.NewReader(body)
+	default:
+		return nil, fmt.Errorf("unsupported compression %x", id)
+	}
+	return io.ReadAll(r)
+}
+
+// manifestEntry is one entry of a manifest content — kopia's
+// repo/manifest manifestEntry as serialized.
+type manifestEntry struct {
+	ID       string            `json:"id"`
+	Labels   map[string]string `json:"labels"`
+	Modified time.Time         `json:"modified"`
+	Deleted  bool              `json:"deleted,omitempty"`
+	Data     json.RawMessage   `json:"data"`
+}
+
+// parseManifestContent decodes a decompressed manifest content: a gzip'd
+// JSON document holding a batch of manifest entries.
+func parseManifestContent(data []byte) ([]manifestEntry, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("manifest: %w", err)
+	}
+	var doc struct {
+		Entries []manifestEntry `json:"entries"`
+	}
+	if err := json.NewDecoder(gz).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("manifest: %w", err)
+	}
+	return doc.Entries, nil
+}
+
+// mergeManifests resolves each manifest ID to its newest entry (kopia
+// rewrites an entry as a tombstone on delete and may carry both across
+// contents until compaction) and drops the ones whose newest entry is a
+// tombstone.
+func mergeManifests(batches [][]manifestEntry) map[string]manifestEntry {
+	latest := make(map[string]manifestEntry)
+	for _, batch := range batches {
+		for _, e := range batch {
+			if cur, ok := latest[e.ID]; !ok || e.Modified.After(cur.Modified) {
+				latest[e.ID] = e
+			}
+		}
+	}
+	for id, e := range latest {
+		if e.Deleted {
+			delete(latest, id)
+		}
+	}
+	return latest
+}
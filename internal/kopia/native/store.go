@@ -0,0 +1,225 @@
+package native
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/mitchross/pvc-plumber/internal/kopia"
+)
+
+// ErrBlobNotFound is returned by a BlobStore when the requested blob does
+// not exist. The reader treats it as "repository not initialised" for the
+// format blob and as a concurrent-compaction race for index and pack blobs.
+var ErrBlobNotFound = errors.New("kopia blob not found")
+
+// BlobStore is the read-only slice of kopia's blob storage the native
+// reader needs. Blob IDs are kopia's own (e.g. "kopia.repository",
+// "xn0_…-c1", "q…"); a store maps them onto its backing layout.
+type BlobStore interface {
+	// List returns every blob ID starting with prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Get returns length bytes of the blob starting at offset. A negative
+	// length reads to the end of the blob.
+	Get(ctx context.Context, id string, offset, length int64) ([]byte, error)
+}
+
+// S3Store reads kopia blobs from an S3 bucket laid out the way kopia's s3
+// storage writes them: one object per blob, keyed by the bare blob ID.
+// That is the layout VolSync's kopia mover produces, so the bucket the
+// mover Jobs write to can be read here unchanged.
+type S3Store struct {
+	client *minio.Client
+	bucket string
+}
+
+// S3Options are the static inputs NewS3Store needs. Credentials are not
+// part of it: they come from a kopia.CredentialsSource on every request so
+// a rotated Secret is picked up without a restart, exactly as the CLI
+// client does.
+type S3Options struct {
+	Endpoint   string
+	Bucket     string
+	DisableTLS bool
+}
+
+// NewS3Store constructs an S3Store. No request is made here; the first
+// List or Get surfaces connectivity and credential errors.
+func NewS3Store(opts S3Options, creds kopia.CredentialsSource) (*S3Store, error) {
+	mc, err := minio.New(opts.Endpoint, &minio.Options{
+		Creds:  credentials.New(&credsProvider{src: creds}),
+		Secure: !opts.DisableTLS,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create minio client: %w", err)
+	}
+	return &S3Store{client: mc, bucket: opts.Bucket}, nil
+}
+
+// List implements BlobStore.
+func (s *S3Store) List(ctx context.Context, prefix string) ([]string, error) {
+	var ids []string
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("list %q: %w", prefix, obj.Err)
+		}
+		ids = append(ids, obj.Key)
+	}
+	return ids, nil
+}
+
+// Get implements BlobStore.
+func (s *S3Store) Get(ctx context.Context, id string, offset, length int64) ([]byte, error) {
+	opts := minio.GetObjectOptions{}
+	switch {
+	case length == 0:
+		return []byte{}, nil
+	case length > 0:
+		if err := opts.SetRange(offset, offset+length-1); err != nil {
+			return nil, err
+		}
+	case offset > 0:
+		if err := opts.SetRange(offset, 0); err != nil {
+			return nil, err
+		}
+	}
+	obj, err := s.client.GetObject(ctx, s.bucket, id, opts)
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", id, err)
+	}
+	defer func() { _ = obj.Close() }()
+	b, err := io.ReadAll(obj)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == minio.NoSuchKey {
+			return nil, fmt.Errorf("%w: %s", ErrBlobNotFound, id)
+		}
+		return nil, fmt.Errorf("get %s: %w", id, err)
+	}
+	return b, nil
+}
+
+// credsProvider adapts a kopia.CredentialsSource to minio-go's credential
+// provider. IsExpired always reports true so every signed request re-reads
+// the source — the same "Load on every call" contract the CLI client
+// relies on for ESO rotations.
+type credsProvider struct {
+	src kopia.CredentialsSource
+}
+
+func (p *credsProvider) Retrieve() (credentials.Value, error) {
+	c, err := p.src.Load()
+	if err != nil {
+		return credentials.Value{}, err
+	}
+	return credentials.Value{AccessKeyID: c.AccessKey, SecretAccessKey: c.SecretKey, SignerType: credentials.SignatureV4}, nil
+}
+
+func (p *credsProvider) RetrieveWithCredContext(*credentials.CredContext) (credentials.Value, error) {
+	return p.Retrieve()
+}
+
+func (p *credsProvider) IsExpired() bool { return true }
+
+// DirStore reads kopia blobs from a local directory in kopia's filesystem
+// layout: one file per blob, named by blob ID with kopia's ".f" suffix,
+// either flat (`kopia repository create filesystem --flat`, the test
+// fixture under testdata/) or sharded into nested directories named by
+// leading slices of the ID (the default, e.g. "p15/3e5/6afe….f"). The
+// shard widths are not configured: a repository's `.shards` file, or the
+// pre-`.shards` default, only decides where IDs are split, and both List
+// and Get follow whatever directories exist. That makes it the native
+// reader's store for BACKEND_TYPE=kopia-fs as well as for inspecting a
+// copied repository.
+type DirStore struct {
+	Dir string
+}
+
+// dirBlobSuffix is the extension kopia's filesystem storage appends to
+// every blob file.
+const dirBlobSuffix = ".f"
+
+// maxShardWidth bounds how long a shard directory name Get probes for.
+// kopia's widths are single digits; anything longer is not a shard.
+const maxShardWidth = 8
+
+// List implements BlobStore. Shard directories are only entered when
+// their accumulated name is compatible with prefix, so a prefixed list
+// of a sharded repository does not walk the whole tree.
+func (d DirStore) List(_ context.Context, prefix string) ([]string, error) {
+	var ids []string
+	var walk func(dir, acc string) error
+	walk = func(dir, acc string) error {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				next := acc + e.Name()
+				if strings.HasPrefix(prefix, next) || strings.HasPrefix(next, prefix) {
+					if err := walk(filepath.Join(dir, e.Name()), next); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			name, ok := strings.CutSuffix(e.Name(), dirBlobSuffix)
+			if id := acc + name; ok && strings.HasPrefix(id, prefix) {
+				ids = append(ids, id)
+			}
+		}
+		return nil
+	}
+	if err := walk(d.Dir, ""); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Get implements BlobStore.
+func (d DirStore) Get(_ context.Context, id string, offset, length int64) ([]byte, error) {
+	path, ok := d.locate(d.Dir, id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrBlobNotFound, id)
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrBlobNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if offset > int64(len(b)) || (length >= 0 && offset+length > int64(len(b))) {
+		return nil, fmt.Errorf("blob %s: range [%d,+%d) beyond length %d", id, offset, length, len(b))
+	}
+	if length < 0 {
+		return b[offset:], nil
+	}
+	return b[offset : offset+length], nil
+}
+
+// locate finds the file holding blob rest under dir: the flat name first,
+// then each shard directory named by a leading slice of rest.
+func (d DirStore) locate(dir, rest string) (string, bool) {
+	flat := filepath.Join(dir, rest+dirBlobSuffix)
+	if fi, err := os.Stat(flat); err == nil && !fi.IsDir() {
+		return flat, true
+	}
+	for w := 1; w < len(rest) && w <= maxShardWidth; w++ {
+		sub := filepath.Join(dir, rest[:w])
+		if fi, err := os.Stat(sub); err == nil && fi.IsDir() {
+			if path, ok := d.locate(sub, rest[w:]); ok {
+				return path, true
+			}
+		}
+	}
+	return "", false
+}
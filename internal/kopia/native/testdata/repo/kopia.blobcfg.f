@@ -0,0 +1 @@
+ۈQ=bG|szdx
\ No newline at end of file
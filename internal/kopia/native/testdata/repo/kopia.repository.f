@@ -0,0 +1,9 @@
+{
+  "tool": "https://github.com/kopia/kopia",
+  "buildVersion": "v0-unofficial",
+  "buildInfo": "-(unknown_revision)",
+  "uniqueID": "npG20DV+5NwVHI7mQLc0o0Ff85HYLpqWKbjXVQkjz9w=",
+  "keyAlgo": "scrypt-65536-8-1",
+  "encryption": "AES256_GCM",
+  "encryptedBlockFormat": "IO6/TENNwsgBgDn3eqRRts11yNH49WdYoB/lSurDjbUEzy4N7oS5cp3MJhVKcG7MedibIx+oEi3jBSBimQy2UDbemE0I7TkH2zKbO6WRBYEx2JskDHBIEhO42/YTTssvTpTZV5G7U6YiHnFh5J36jqB8nfBJ7fsdSc0pcmJ+UtiWQYml3si70KjwhXjOyASeDfqHZXF6bWbLmG7dw23IfdneEPcywgTC021lIAm/WppNrLoDNmOTx5bESPdWXcklHfJlrbG9S8FmpZHjq6q+C7kvyHgQfVB9m5pLmCcGB2kn1TkVx2zZ6Y5OjgbHYaJeJf4OzoLO3/OBXSmo6UwoJIr9tVrtLdXdhTE19IxhdMGCoA80PV3CqnjHSf3lD5iRaCyhMYP8bYfRini+6RADhGVBgYi4TQCnSM4BiQoC7DbAYg7Rya4IzwWjOGLvaagvr4QO2MvVTHozNnxKlI0Zn0aKkAT8YjpKhAHr1tGLxCO+WxdkOLKXuijThMDAH8Nz01Tr4SNOQYmxG0yZGV9GF3mVnXgmWhGlkI4uMETYZ/UfGM1AZs/3hn9gY1T+1IGJTTvySPladHPfnx9Lqw3JGJL8lyF590iYHH2WhRE5KWUFAE2YFxFJpxzLbx43fZVTUKUqQpi3ItNlhSAYIxpGpTH4Rx9t0xmUsqjF9AZAkwiTruL4mbRCpG8eij78CAYz4hOXHKvVVnQfGKFa5PpCc0rK5JWra9e3ictvxedpWfC6YMUB63YeROYUtIFSNC5ekQtrk4ElU38djxVj1h0G9BbJeiuX+RpEqeqXBNVmIg=="
+}
@@ -0,0 +1 @@
+/WhNRRIq`8Aχt0ȡy5W2&B[<ha,P	iADC9!ʽd	?$sܨ@D<r;y0 Bi&;#sIk
\ No newline at end of file
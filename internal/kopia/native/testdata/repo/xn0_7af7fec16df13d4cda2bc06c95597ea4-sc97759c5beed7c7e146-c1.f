@@ -0,0 +1,2 @@
+\m4wKErFL`rQP`lRA0dgdG؅],L=x{]XHVƭ`Cެ$MiuSavrg2g6zNd=ڻ
+A@Y	JjXȥm^aUA}1+.Ҧ,rd|k,rcǙ m$W¡9Hє^@rhщ/1#t4f51FKBd
\ No newline at end of file
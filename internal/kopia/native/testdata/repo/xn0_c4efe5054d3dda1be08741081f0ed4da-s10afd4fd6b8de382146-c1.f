@@ -0,0 +1,2 @@
+iqL*W=1s/1TΩ@/Wݟ'lo:z|sRͺ7p
+MhlCl`^á':К뛗jj7x&"O%9;^H+~DTF"
\ No newline at end of file
@@ -0,0 +1,226 @@
+package kopia
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrPruneRefused is returned (wrapped) by Pruner.Prune when any
+// allow-listed source fails a guard. Nothing is deleted in that case.
+var ErrPruneRefused = errors.New("prune refused")
+
+// SnapshotDeleter is a repository that can delete snapshot manifests.
+// Only the CLI *Client is one: the native reader is read-only by
+// construction and the server reader's API user has no delete right.
+// The pruner lists through ListAllSnapshotsWithCheckpoints, so a backup
+// still being written counts towards a lineage's age and an abandoned
+// checkpoint is deleted with the rest of it.
+type SnapshotDeleter interface {
+	SnapshotLister
+	ListAllSnapshotsWithCheckpoints(ctx context.Context) ([]SnapshotInfo, error)
+	DeleteSnapshot(ctx context.Context, id string) error
+}
+
+// ReferenceLister reports the sources live cluster state still points
+// at, each with a human-readable reason ("ReplicationSource myapp/data").
+// Pruner refuses to touch any of them.
+type ReferenceLister interface {
+	ReferencedSources(ctx context.Context) (map[SnapshotSource]string, error)
+}
+
+// DeleteSnapshot deletes one snapshot manifest. Without --delete kopia
+// only prints what it would remove; the contents become unreferenced and
+// are reclaimed by a later full maintenance run, not here.
+func (c *Client) DeleteSnapshot(ctx context.Context, id string) error {
+	if _, err := c.executor.Run(ctx, "kopia", "snapshot", "delete", id, "--delete"); err != nil {
+		return fmt.Errorf("failed to delete snapshot %s: %w", id, err)
+	}
+	return nil
+}
+
+// ParseSnapshotSource parses the "user@host:/path" form SnapshotSource's
+// String prints (and the inventory reports). All three parts are
+// required.
+func ParseSnapshotSource(s string) (SnapshotSource, error) {
+	userHost, path, ok := strings.Cut(s, ":")
+	user, host, ok2 := strings.Cut(userHost, "@")
+	if !ok || !ok2 || user == "" || host == "" || !strings.HasPrefix(path, "/") {
+		return SnapshotSource{}, fmt.Errorf("invalid snapshot source %q (want user@host:/path)", s)
+	}
+	return SnapshotSource{Host: host, UserName: user, Path: path}, nil
+}
+
+// Prune actions reported per source.
+const (
+	PruneDelete      = "delete"       // deleted (or, in a dry run, would be)
+	PruneRefused     = "refused"      // a guard failed; see Reason
+	PruneDeleteError = "delete-error" // a delete call failed mid-run
+)
+
+// PruneOptions are the operator's instructions for one run.
+type PruneOptions struct {
+	// Allow is the explicit list of sources to prune. Nothing outside it
+	// is ever deleted, however orphaned it looks.
+	Allow []SnapshotSource
+	// MinAge is how long a source must have gone without a new snapshot.
+	// Required: a zero MinAge is rejected rather than read as "any age".
+	MinAge time.Duration
+	// Confirm turns the dry run into a real one.
+	Confirm bool
+}
+
+// PruneSource is the verdict for one allow-listed source.
+type PruneSource struct {
+	Source           string    `json:"source"`
+	Action           string    `json:"action"`
+	Reason           string    `json:"reason,omitempty"`
+	SnapshotIDs      []string  `json:"snapshot_ids"`
+	LatestSnapshotAt time.Time `json:"latest_snapshot_at,omitzero"`
+}
+
+// PruneReport is the outcome of Pruner.Prune.
+type PruneReport struct {
+	DryRun  bool          `json:"dry_run"`
+	Sources []PruneSource `json:"sources"`
+	// Deleted lists the manifest IDs actually removed, in order.
+	Deleted []string `json:"deleted"`
+}
+
+// PruneRecord is one journal line: a manifest ID the pruner deleted, or
+// tried to. The journal is the only record of what a lineage held once
+// its manifests are gone, so a record is written per ID, not per source.
+type PruneRecord struct {
+	Time       time.Time `json:"time"`
+	Source     string    `json:"source"`
+	SnapshotID string    `json:"snapshot_id"`
+	StartTime  time.Time `json:"start_time"`
+	TotalSize  int64     `json:"total_size"`
+	Result     string    `json:"result"` // "deleted" | "failed"
+	Error      string    `json:"error,omitempty"`
+}
+
+// Pruner deletes whole orphaned lineages from a shared repository, under
+// guards that all have to pass before anything is removed:
+//
+//   - the source is on the explicit allow-list;
+//   - the source has snapshots at all (a typo is refused, not a no-op);
+//   - no live ReplicationSource, ReplicationDestination or PVC still
+//     references it (References);
+//   - its newest snapshot, including incomplete checkpoints, is older
+//     than MinAge — a lineage that was written to last night, or is
+//     being written to now, is not orphaned, whatever the inventory said
+//     when the allow-list was drawn up.
+//
+// Checkpoints are also in the delete set, so a pruned lineage leaves no
+// manifests behind.
+//
+// One refused source refuses the run: the allow-list is meant to be
+// exactly a set of orphans, and an entry that fails a guard means it
+// was built from stale data.
+type Pruner struct {
+	Repo       SnapshotDeleter
+	References ReferenceLister
+	// Journal receives one JSON PruneRecord per delete attempt. Required
+	// for a confirmed run.
+	Journal io.Writer
+	Now     func() time.Time
+	Logger  *slog.Logger
+}
+
+// Prune plans the run and, with opts.Confirm, executes it. The report
+// is returned alongside any error so a caller can show how far a failed
+// run got.
+func (p *Pruner) Prune(ctx context.Context, opts PruneOptions) (PruneReport, error) {
+	report := PruneReport{DryRun: !opts.Confirm, Sources: []PruneSource{}, Deleted: []string{}}
+	switch {
+	case len(opts.Allow) == 0:
+		return report, errors.New("prune needs at least one allow-listed source")
+	case opts.MinAge <= 0:
+		return report, errors.New("prune needs a positive minimum age")
+	case opts.Confirm && p.Journal == nil:
+		return report, errors.New("a confirmed prune needs a journal")
+	}
+	now := time.Now
+	if p.Now != nil {
+		now = p.Now
+	}
+
+	snaps, err := p.Repo.ListAllSnapshotsWithCheckpoints(ctx)
+	if err != nil {
+		return report, fmt.Errorf("list snapshots: %w", err)
+	}
+	refs, err := p.References.ReferencedSources(ctx)
+	if err != nil {
+		return report, fmt.Errorf("list references: %w", err)
+	}
+	bySource := map[SnapshotSource][]SnapshotInfo{}
+	for _, s := range snaps {
+		bySource[s.Source] = append(bySource[s.Source], s)
+	}
+
+	allow := slices.Clone(opts.Allow)
+	slices.SortFunc(allow, func(a, b SnapshotSource) int { return strings.Compare(a.String(), b.String()) })
+	allow = slices.Compact(allow)
+	refused := 0
+	for _, src := range allow {
+		ps := PruneSource{Source: src.String(), Action: PruneDelete, SnapshotIDs: []string{}}
+		for _, s := range bySource[src] {
+			ps.SnapshotIDs = append(ps.SnapshotIDs, s.ID)
+			if s.StartTime.After(ps.LatestSnapshotAt) {
+				ps.LatestSnapshotAt = s.StartTime
+			}
+		}
+		switch reason, referenced := refs[src]; {
+		case len(ps.SnapshotIDs) == 0:
+			ps.Action, ps.Reason = PruneRefused, "no snapshots in the repository"
+		case referenced:
+			ps.Action, ps.Reason = PruneRefused, "still referenced by "+reason
+		case now().Sub(ps.LatestSnapshotAt) < opts.MinAge:
+			ps.Action, ps.Reason = PruneRefused, fmt.Sprintf("newest snapshot %s is younger than %s",
+				ps.LatestSnapshotAt.UTC().Format(time.RFC3339), opts.MinAge)
+		}
+		if ps.Action == PruneRefused {
+			refused++
+		}
+		report.Sources = append(report.Sources, ps)
+	}
+	if refused > 0 {
+		return report, fmt.Errorf("%w: %d of %d sources failed a guard", ErrPruneRefused, refused, len(allow))
+	}
+	if !opts.Confirm {
+		return report, nil
+	}
+
+	enc := json.NewEncoder(p.Journal)
+	for i, src := range allow {
+		for _, s := range bySource[src] {
+			rec := PruneRecord{Source: src.String(), SnapshotID: s.ID, StartTime: s.StartTime, TotalSize: s.TotalSize, Result: "deleted"}
+			delErr := p.Repo.DeleteSnapshot(ctx, s.ID)
+			if delErr != nil {
+				rec.Result, rec.Error = "failed", delErr.Error()
+			}
+			rec.Time = now()
+			if err := enc.Encode(rec); err != nil {
+				// A delete the journal can't record is a delete nobody
+				// can account for later; stop here.
+				return report, fmt.Errorf("write prune journal: %w", err)
+			}
+			if delErr != nil {
+				report.Sources[i].Action, report.Sources[i].Reason = PruneDeleteError, delErr.Error()
+				return report, delErr
+			}
+			report.Deleted = append(report.Deleted, s.ID)
+			if p.Logger != nil {
+				p.Logger.Info("pruned kopia snapshot", "source", rec.Source, "id", s.ID)
+			}
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,227 @@
+package kopia
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pruneListJSON is a repository holding two orphaned lineages (one with
+// an abandoned checkpoint), one live lineage, one orphan that was written
+// to yesterday, and one with old complete snapshots and a checkpoint
+// written today.
+const pruneListJSON = `[
+  {"id": "o1", "source": {"host": "immich", "userName": "old-backup", "path": "/data"}, "startTime": "2026-03-01T03:00:00Z", "stats": {"totalSize": 10}},
+  {"id": "o2", "source": {"host": "immich", "userName": "old-backup", "path": "/data"}, "startTime": "2026-03-02T03:00:00Z", "stats": {"totalSize": 20}},
+  {"id": "g1", "source": {"host": "gone", "userName": "cache", "path": "/data"}, "startTime": "2026-02-01T03:00:00Z"},
+  {"id": "g2", "source": {"host": "gone", "userName": "cache", "path": "/data"}, "startTime": "2026-02-02T03:00:00Z", "incomplete": "canceled"},
+  {"id": "b1", "source": {"host": "busy", "userName": "stale", "path": "/data"}, "startTime": "2026-03-01T03:00:00Z"},
+  {"id": "b2", "source": {"host": "busy", "userName": "stale", "path": "/data"}, "startTime": "2026-06-03T06:00:00Z", "incomplete": "checkpoint"},
+  {"id": "l1", "source": {"host": "karakeep", "userName": "data", "path": "/data"}, "startTime": "2026-03-01T03:00:00Z"},
+  {"id": "r1", "source": {"host": "recent", "userName": "tmp", "path": "/data"}, "startTime": "2026-06-02T03:00:00Z", "incomplete": "checkpoint"}
+]`
+
+var pruneNow = time.Date(2026, 6, 3, 12, 0, 0, 0, time.UTC)
+
+// pruneExecutor answers `snapshot list --all --incomplete` and records
+// deletes, failing the ones named in failDelete. A listing without
+// --incomplete is an error: it would hide the checkpoints.
+type pruneExecutor struct {
+	deleted    []string
+	failDelete map[string]bool
+}
+
+func (e *pruneExecutor) Run(_ context.Context, _ string, args ...string) ([]byte, error) {
+	if len(args) >= 2 && args[0] == "snapshot" && args[1] == "list" {
+		if !slices.Contains(args, "--incomplete") {
+			return nil, errors.New("snapshot list without --incomplete")
+		}
+		return []byte(pruneListJSON), nil
+	}
+	if len(args) == 4 && args[0] == "snapshot" && args[1] == "delete" && args[3] == "--delete" {
+		if e.failDelete[args[2]] {
+			return nil, errors.New("exit status 1")
+		}
+		e.deleted = append(e.deleted, args[2])
+		return nil, nil
+	}
+	return nil, errors.New("unexpected command: " + strings.Join(args, " "))
+}
+
+type staticRefs map[SnapshotSource]string
+
+func (r staticRefs) ReferencedSources(context.Context) (map[SnapshotSource]string, error) {
+	return r, nil
+}
+
+func newTestPruner(exec *pruneExecutor, journal io.Writer) *Pruner {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return &Pruner{
+		Repo:       NewClientWithExecutor(testS3Config(), testCreds(), logger, exec, Options{}),
+		References: staticRefs{{Host: "karakeep", UserName: "data", Path: "/data"}: "ReplicationSource karakeep/data"},
+		Journal:    journal,
+		Now:        func() time.Time { return pruneNow },
+		Logger:     logger,
+	}
+}
+
+func mustSources(t *testing.T, raw ...string) []SnapshotSource {
+	t.Helper()
+	var out []SnapshotSource
+	for _, r := range raw {
+		s, err := ParseSnapshotSource(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestPrune_DryRunDeletesNothing(t *testing.T) {
+	exec := &pruneExecutor{}
+	report, err := newTestPruner(exec, nil).Prune(context.Background(), PruneOptions{
+		Allow:  mustSources(t, "old-backup@immich:/data", "cache@gone:/data"),
+		MinAge: 30 * 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exec.deleted) != 0 || !report.DryRun || len(report.Deleted) != 0 {
+		t.Fatalf("dry run deleted %v, report %+v", exec.deleted, report)
+	}
+	// Sorted by source; every snapshot of the lineage is listed.
+	if got := report.Sources; len(got) != 2 || got[0].Source != "cache@gone:/data" ||
+		got[1].Action != PruneDelete || !slices.Equal(got[1].SnapshotIDs, []string{"o1", "o2"}) ||
+		got[1].LatestSnapshotAt.Day() != 2 {
+		t.Errorf("sources: %+v", got)
+	}
+}
+
+func TestPrune_ConfirmDeletesAndJournals(t *testing.T) {
+	exec := &pruneExecutor{}
+	var journal bytes.Buffer
+	report, err := newTestPruner(exec, &journal).Prune(context.Background(), PruneOptions{
+		Allow:   mustSources(t, "old-backup@immich:/data", "cache@gone:/data", "old-backup@immich:/data"),
+		MinAge:  30 * 24 * time.Hour,
+		Confirm: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The abandoned checkpoint g2 goes with its lineage.
+	if !slices.Equal(exec.deleted, []string{"g1", "g2", "o1", "o2"}) || !slices.Equal(report.Deleted, exec.deleted) {
+		t.Fatalf("deleted %v, report %v", exec.deleted, report.Deleted)
+	}
+	lines := strings.Split(strings.TrimSpace(journal.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("journal: %q", journal.String())
+	}
+	var rec PruneRecord
+	if err := json.Unmarshal([]byte(lines[3]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.SnapshotID != "o2" || rec.Source != "old-backup@immich:/data" || rec.Result != "deleted" ||
+		rec.TotalSize != 20 || !rec.Time.Equal(pruneNow) {
+		t.Errorf("record: %+v", rec)
+	}
+}
+
+func TestPrune_GuardsRefuseTheWholeRun(t *testing.T) {
+	cases := []struct {
+		source string
+		reason string
+	}{
+		{"data@karakeep:/data", "still referenced by ReplicationSource karakeep/data"},
+		{"tmp@recent:/data", "younger than"},
+		// Old complete snapshots, but a checkpoint written today.
+		{"stale@busy:/data", "newest snapshot 2026-06-03T06:00:00Z is younger than"},
+		{"typo@immich:/data", "no snapshots"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.source, func(t *testing.T) {
+			exec := &pruneExecutor{}
+			var journal bytes.Buffer
+			report, err := newTestPruner(exec, &journal).Prune(context.Background(), PruneOptions{
+				Allow:   mustSources(t, "old-backup@immich:/data", tc.source),
+				MinAge:  30 * 24 * time.Hour,
+				Confirm: true,
+			})
+			if !errors.Is(err, ErrPruneRefused) {
+				t.Fatalf("err = %v", err)
+			}
+			if len(exec.deleted) != 0 || journal.Len() != 0 {
+				t.Errorf("refused run deleted %v", exec.deleted)
+			}
+			for _, s := range report.Sources {
+				if s.Source == tc.source && (s.Action != PruneRefused || !strings.Contains(s.Reason, tc.reason)) {
+					t.Errorf("verdict: %+v", s)
+				}
+			}
+		})
+	}
+}
+
+func TestPrune_DeleteFailureStopsAndIsJournaled(t *testing.T) {
+	exec := &pruneExecutor{failDelete: map[string]bool{"o1": true}}
+	var journal bytes.Buffer
+	report, err := newTestPruner(exec, &journal).Prune(context.Background(), PruneOptions{
+		Allow:   mustSources(t, "old-backup@immich:/data", "cache@gone:/data"),
+		MinAge:  time.Hour,
+		Confirm: true,
+	})
+	if err == nil {
+		t.Fatal("delete failure not returned")
+	}
+	if !slices.Equal(exec.deleted, []string{"g1", "g2"}) || report.Sources[1].Action != PruneDeleteError {
+		t.Errorf("deleted %v, sources %+v", exec.deleted, report.Sources)
+	}
+	if !strings.Contains(journal.String(), `"snapshot_id":"o1"`) || !strings.Contains(journal.String(), `"result":"failed"`) {
+		t.Errorf("journal: %s", journal.String())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestPrune_RequiredOptions(t *testing.T) {
+	allow := mustSources(t, "cache@gone:/data")
+	p := newTestPruner(&pruneExecutor{}, nil)
+	for name, opts := range map[string]PruneOptions{
+		"no allow-list":       {MinAge: time.Hour},
+		"no min age":          {Allow: allow},
+		"confirm w/o journal": {Allow: allow, MinAge: time.Hour, Confirm: true},
+		"negative min age":    {Allow: allow, MinAge: -time.Hour},
+	} {
+		if _, err := p.Prune(context.Background(), opts); err == nil || errors.Is(err, ErrPruneRefused) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+
+	// A journal that can't be written stops the run after the first delete.
+	exec := &pruneExecutor{}
+	p = newTestPruner(exec, failingWriter{})
+	if _, err := p.Prune(context.Background(), PruneOptions{Allow: mustSources(t, "old-backup@immich:/data"), MinAge: time.Hour, Confirm: true}); err == nil || len(exec.deleted) != 1 {
+		t.Errorf("err = %v, deleted %v", err, exec.deleted)
+	}
+}
+
+func TestParseSnapshotSource(t *testing.T) {
+	s, err := ParseSnapshotSource("data-backup@karakeep:/data")
+	if err != nil || s != LegacySource("karakeep", "data") {
+		t.Errorf("got %+v, %v", s, err)
+	}
+	for _, bad := range []string{"", "data@karakeep", "@karakeep:/data", "data@:/data", "data:/data", "data@karakeep:data"} {
+		if _, err := ParseSnapshotSource(bad); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
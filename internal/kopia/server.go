@@ -0,0 +1,365 @@
+package kopia
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+)
+
+// HTTPDoer is the seam between ServerClient and the network — the HTTP
+// counterpart of CommandExecutor. *http.Client satisfies it; tests point
+// one at an httptest.Server.
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// ServerConfig describes how to reach a kopia server's HTTP API.
+type ServerConfig struct {
+	// URL is the server's base URL, e.g. http://127.0.0.1:51515.
+	URL string
+	// Username / Password are the server's basic-auth user (`kopia server
+	// start --server-username/--server-password`).
+	Username string
+	Password string
+	// Timeout bounds every API call. Defaults to 10s.
+	Timeout time.Duration
+	// MaxStaleness bounds how old the server's view of the repository may
+	// be when a query runs. A long-lived kopia server only re-reads index
+	// blobs every 15 minutes on its own, which would hide a snapshot the
+	// mover just wrote; queries first POST /api/v1/refresh when the last
+	// refresh is older than this. Defaults to 30s.
+	MaxStaleness time.Duration
+}
+
+// kopia server API paths and the CSRF plumbing its UI routes require.
+// Snapshot listing is a UI route: it wants the session cookie plus a
+// token the server embeds in the root HTML page, the same dance kopia's
+// own API client does.
+const (
+	serverPathStatus    = "/api/v1/repo/status"
+	serverPathSources   = "/api/v1/sources"
+	serverPathSnapshots = "/api/v1/snapshots"
+	serverPathRefresh   = "/api/v1/refresh"
+	serverCSRFHeader    = "X-Kopia-Csrf-Token"
+	serverSessionCookie = "Kopia-Session-Cookie"
+)
+
+var serverCSRFMeta = regexp.MustCompile(`<meta name="kopia-csrf-token" content="([^"]*)"`)
+
+// ServerClient answers the same queries as Client over a kopia server's
+// HTTP API instead of a `kopia` subprocess per call: one keep-alive
+// connection, no fork, and no KOPIA_CONFIG_PATH session to expire under
+// the webhook. The server is either external (KOPIA_SERVER_URL) or one
+// this process supervises (ManagedServer).
+type ServerClient struct {
+	cfg    ServerConfig
+	base   *url.URL
+	http   HTTPDoer
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu          sync.Mutex
+	csrfToken   string
+	session     *http.Cookie
+	lastRefresh time.Time
+}
+
+// NewServerClient constructs a ServerClient with a pooled *http.Client.
+func NewServerClient(cfg ServerConfig, logger *slog.Logger) (*ServerClient, error) {
+	return NewServerClientWithDoer(cfg, logger, &http.Client{
+		Transport: &http.Transport{
+			MaxIdleConnsPerHost: 4,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	})
+}
+
+// NewServerClientWithDoer constructs a ServerClient over a custom HTTPDoer
+// (for testing).
+func NewServerClientWithDoer(cfg ServerConfig, logger *slog.Logger, doer HTTPDoer) (*ServerClient, error) {
+	base, err := url.Parse(cfg.URL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("invalid kopia server URL %q", cfg.URL)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.MaxStaleness <= 0 {
+		cfg.MaxStaleness = 30 * time.Second
+	}
+	return &ServerClient{cfg: cfg, base: base, http: doer, logger: logger, now: time.Now}, nil
+}
+
+// serverSnapshot is kopia's serverapi.Snapshot. Unlike the CLI's JSON it
+// carries no source (the caller asked for one) and its sizes live under
+// the root directory summary.
+type serverSnapshot struct {
+	ID               string    `json:"id"`
+	Description      string    `json:"description"`
+	StartTime        time.Time `json:"startTime"`
+	EndTime          time.Time `json:"endTime"`
+	IncompleteReason string    `json:"incomplete"`
+	Summary          *struct {
+		Size      int64 `json:"size"`
+		Files     int64 `json:"files"`
+		Dirs      int64 `json:"dirs"`
+		NumFailed int64 `json:"numFailed"`
+	} `json:"summary"`
+	Retention []string `json:"retention"`
+	Pins      []string `json:"pins"`
+}
+
+func (s serverSnapshot) info(source SnapshotSource) SnapshotInfo {
+	info := SnapshotInfo{
+		ID:               s.ID,
+		Source:           source,
+		Description:      s.Description,
+		StartTime:        s.StartTime,
+		EndTime:          s.EndTime,
+		RetentionReasons: s.Retention,
+		Pins:             s.Pins,
+		IncompleteReason: s.IncompleteReason,
+	}
+	if s.Summary != nil {
+		info.TotalSize = s.Summary.Size
+		info.FileCount = s.Summary.Files
+		info.DirCount = s.Summary.Dirs
+		info.ErrorCount = s.Summary.NumFailed
+	}
+	return info
+}
+
+// ListSnapshots returns the full lineage for one source, oldest first.
+// `all=1` disables the server's collapsing of consecutive identical
+// snapshots so counts match `kopia snapshot list`.
+func (c *ServerClient) ListSnapshots(ctx context.Context, source SnapshotSource) ([]SnapshotInfo, error) {
+	if err := c.maybeRefresh(ctx); err != nil {
+		return nil, err
+	}
+	return c.listSnapshots(ctx, source)
+}
+
+func (c *ServerClient) listSnapshots(ctx context.Context, source SnapshotSource) ([]SnapshotInfo, error) {
+	q := url.Values{"userName": {source.UserName}, "host": {source.Host}, "path": {source.Path}, "all": {"1"}}
+	var resp struct {
+		Snapshots []serverSnapshot `json:"snapshots"`
+	}
+	if err := c.call(ctx, http.MethodGet, serverPathSnapshots, q, &resp); err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	out := make([]SnapshotInfo, 0, len(resp.Snapshots))
+	for _, s := range resp.Snapshots {
+		out = append(out, s.info(source))
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].StartTime.Before(out[j].StartTime) })
+	return out, nil
+}
+
+// SummarizeAllSources lists the repository's sources and the lineage of
+// each one that maps to a PVC key (see SummarizeByPVC). One request per
+// source, all on the same connection.
+func (c *ServerClient) SummarizeAllSources(ctx context.Context) (map[string]backend.SnapshotSummary, error) {
+	snaps, err := c.scanSources(ctx, func(s SnapshotSource) bool { return len(PVCKeys(s)) > 0 })
+	if err != nil {
+		return nil, err
+	}
+	out := SummarizeByPVC(snaps)
+	c.logger.Info("snapshot scan complete", "unique_sources", len(out), "snapshots", len(snaps))
+	return out, nil
+}
+
+// ListAllSnapshots lists the lineage of every source the server knows,
+// oldest first — unlike SummarizeAllSources, sources that map to no PVC
+// key are listed too.
+func (c *ServerClient) ListAllSnapshots(ctx context.Context) ([]SnapshotInfo, error) {
+	snaps, err := c.scanSources(ctx, func(SnapshotSource) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(snaps, func(i, j int) bool { return snaps[i].StartTime.Before(snaps[j].StartTime) })
+	return snaps, nil
+}
+
+// scanSources lists the server's sources and the lineage of each one keep
+// accepts.
+func (c *ServerClient) scanSources(ctx context.Context, keep func(SnapshotSource) bool) ([]SnapshotInfo, error) {
+	if err := c.refresh(ctx); err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Sources []struct {
+			Source SnapshotSource `json:"source"`
+		} `json:"sources"`
+	}
+	if err := c.call(ctx, http.MethodGet, serverPathSources, nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to list sources: %w", err)
+	}
+	var snaps []SnapshotInfo
+	for _, s := range resp.Sources {
+		if !keep(s.Source) {
+			continue
+		}
+		lineage, err := c.listSnapshots(ctx, s.Source)
+		if err != nil {
+			return nil, err
+		}
+		snaps = append(snaps, lineage...)
+	}
+	return snaps, nil
+}
+
+// CheckBackupExists implements the BackendClient contract over the
+// server API. Results are identical in shape to Client's.
+func (c *ServerClient) CheckBackupExists(ctx context.Context, q backend.Query) backend.CheckResult {
+	return CheckQuery(ctx, q, c.ListSnapshots)
+}
+
+// HealthCheck asks the server for repository status and fails unless the
+// server reports it is connected to the repository.
+func (c *ServerClient) HealthCheck(ctx context.Context) error {
+	var st struct {
+		Connected bool `json:"connected"`
+	}
+	if err := c.call(ctx, http.MethodGet, serverPathStatus, nil, &st); err != nil {
+		return fmt.Errorf("kopia server status: %w", err)
+	}
+	if !st.Connected {
+		return errors.New("kopia server is not connected to a repository")
+	}
+	return nil
+}
+
+// maybeRefresh refreshes the server's repository view when it is older
+// than MaxStaleness.
+func (c *ServerClient) maybeRefresh(ctx context.Context) error {
+	c.mu.Lock()
+	fresh := c.now().Sub(c.lastRefresh) < c.cfg.MaxStaleness
+	c.mu.Unlock()
+	if fresh {
+		return nil
+	}
+	return c.refresh(ctx)
+}
+
+func (c *ServerClient) refresh(ctx context.Context) error {
+	if err := c.call(ctx, http.MethodPost, serverPathRefresh, nil, nil); err != nil {
+		return fmt.Errorf("refresh kopia server: %w", err)
+	}
+	c.mu.Lock()
+	c.lastRefresh = c.now()
+	c.mu.Unlock()
+	return nil
+}
+
+// errCSRFRejected marks a 401 caused by a missing or stale CSRF token
+// rather than bad credentials; call re-fetches the token once on it.
+var errCSRFRejected = errors.New("kopia server rejected CSRF token")
+
+// call performs one API request and decodes the JSON body into out (when
+// non-nil). A CSRF rejection triggers one token fetch and retry.
+func (c *ServerClient) call(ctx context.Context, method, path string, q url.Values, out any) error {
+	err := c.callOnce(ctx, method, path, q, out)
+	if !errors.Is(err, errCSRFRejected) {
+		return err
+	}
+	if err := c.fetchCSRFToken(ctx); err != nil {
+		return err
+	}
+	return c.callOnce(ctx, method, path, q, out)
+}
+
+func (c *ServerClient) callOnce(ctx context.Context, method, path string, q url.Values, out any) error {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	u := c.base.JoinPath(path)
+	u.RawQuery = q.Encode()
+	var body io.Reader
+	if method == http.MethodPost {
+		body = bytes.NewReader([]byte("{}"))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.cfg.Username, c.cfg.Password)
+	req.Header.Set("Content-Type", "application/json")
+	c.mu.Lock()
+	if c.csrfToken != "" {
+		req.Header.Set(serverCSRFHeader, c.csrfToken)
+	}
+	if c.session != nil {
+		req.AddCookie(c.session)
+	}
+	c.mu.Unlock()
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && bytes.Contains(raw, []byte("CSRF")) {
+		return errCSRFRejected
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(raw))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("%s %s: decode: %w", method, path, err)
+	}
+	return nil
+}
+
+// fetchCSRFToken loads the server's root page, which sets the session
+// cookie and embeds the CSRF token bound to it.
+func (c *ServerClient) fetchCSRFToken(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base.JoinPath("/").String(), nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.cfg.Username, c.cfg.Password)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch kopia server CSRF token: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	m := serverCSRFMeta.FindSubmatch(raw)
+	if m == nil {
+		return fmt.Errorf("kopia server CSRF token not found (%s); start the server with its UI enabled", resp.Status)
+	}
+	var session *http.Cookie
+	for _, ck := range resp.Cookies() {
+		if ck.Name == serverSessionCookie {
+			session = &http.Cookie{Name: ck.Name, Value: ck.Value}
+		}
+	}
+	c.mu.Lock()
+	c.csrfToken, c.session = string(m[1]), session
+	c.mu.Unlock()
+	return nil
+}
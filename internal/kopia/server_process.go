@@ -0,0 +1,245 @@
+package kopia
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// Process is a started long-lived child process.
+type Process interface {
+	// Wait blocks until the process exits.
+	Wait() error
+}
+
+// ProcessStarter starts long-lived processes — the counterpart of
+// CommandExecutor for `kopia server start`, which never returns output.
+// env entries are appended to the parent's environment.
+type ProcessStarter interface {
+	Start(ctx context.Context, env []string, name string, args ...string) (Process, error)
+}
+
+// RealProcessStarter starts processes using os/exec. The process is
+// killed when ctx is cancelled.
+type RealProcessStarter struct{}
+
+func (RealProcessStarter) Start(ctx context.Context, env []string, name string, args ...string) (Process, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// managedServerUsername is the basic-auth user of the supervised server.
+// Nothing but this process ever talks to it.
+const managedServerUsername = "pvc-plumber"
+
+// ManagedServerOptions configures a supervised `kopia server start`.
+type ManagedServerOptions struct {
+	// Address is the loopback host:port the server listens on. Defaults
+	// to 127.0.0.1:51515.
+	Address string
+	// ReadyTimeout caps how long Start waits for the API to answer.
+	// Defaults to 60s.
+	ReadyTimeout time.Duration
+}
+
+// ManagedServer runs `kopia server start` against the repository Client
+// connected (the same KOPIA_CONFIG_PATH) and restarts it if it exits. It
+// listens on loopback only, without TLS or gRPC; the basic-auth password
+// is random per process and passed through the environment so it never
+// shows up in the process list.
+type ManagedServer struct {
+	opts     ManagedServerOptions
+	starter  ProcessStarter
+	logger   *slog.Logger
+	password string
+}
+
+// NewManagedServer constructs a ManagedServer. Nothing runs until Start.
+func NewManagedServer(opts ManagedServerOptions, logger *slog.Logger) (*ManagedServer, error) {
+	return NewManagedServerWithStarter(opts, logger, RealProcessStarter{})
+}
+
+// NewManagedServerWithStarter constructs a ManagedServer with a custom
+// ProcessStarter (for testing).
+func NewManagedServerWithStarter(opts ManagedServerOptions, logger *slog.Logger, starter ProcessStarter) (*ManagedServer, error) {
+	if opts.Address == "" {
+		opts.Address = "127.0.0.1:51515"
+	}
+	if opts.ReadyTimeout <= 0 {
+		opts.ReadyTimeout = 60 * time.Second
+	}
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("generate kopia server password: %w", err)
+	}
+	return &ManagedServer{opts: opts, starter: starter, logger: logger, password: hex.EncodeToString(buf)}, nil
+}
+
+// URL is the base URL the server listens on.
+func (m *ManagedServer) URL() string {
+	return "http://" + m.opts.Address
+}
+
+// ServerConfig returns the ServerConfig a ServerClient needs to reach
+// this server.
+func (m *ManagedServer) ServerConfig() ServerConfig {
+	return ServerConfig{URL: m.URL(), Username: managedServerUsername, Password: m.password}
+}
+
+// serverStartArgs assembles the `kopia server start` argv. The UI stays
+// on: `--no-ui` unregisters the whole /api/v1 surface the snapshot
+// queries use, not just the web pages, so ServerClient does the CSRF
+// handshake instead. The server's own refresh interval is left at
+// kopia's default: ServerClient refreshes on demand before queries.
+func serverStartArgs(address string) []string {
+	return []string{
+		"server", "start",
+		"--address=http://" + address,
+		"--insecure",
+		"--server-username=" + managedServerUsername,
+		"--no-grpc",
+		"--no-persistent-logs",
+	}
+}
+
+// Start launches the server, waits until client answers its status
+// probe, and supervises it in the background until ctx is cancelled: an
+// exit is logged and the server restarted with backoff (1s doubling to
+// 30s). The caller must have run Client.Connect first so the on-disk
+// config exists.
+func (m *ManagedServer) Start(ctx context.Context, client *ServerClient) error {
+	proc, err := m.launch(ctx)
+	if err != nil {
+		return err
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- proc.Wait() }()
+
+	readyCtx, cancel := context.WithTimeout(ctx, m.opts.ReadyTimeout)
+	defer cancel()
+	if err := waitServerReady(readyCtx, client, exited); err != nil {
+		return fmt.Errorf("kopia server did not become ready: %w", err)
+	}
+	m.logger.Info("kopia server ready", "url", m.URL())
+	go m.supervise(ctx, exited)
+	return nil
+}
+
+func (m *ManagedServer) launch(ctx context.Context) (Process, error) {
+	proc, err := m.starter.Start(ctx, []string{"KOPIA_SERVER_PASSWORD=" + m.password}, "kopia", serverStartArgs(m.opts.Address)...)
+	if err != nil {
+		return nil, fmt.Errorf("start kopia server: %w", err)
+	}
+	return proc, nil
+}
+
+// errServerExited is returned by waitServerReady when the process dies
+// before its API ever answers.
+var errServerExited = errors.New("kopia server exited")
+
+// waitServerReady polls HealthCheck every 250ms until it succeeds, the
+// process exits or ctx expires.
+func waitServerReady(ctx context.Context, client *ServerClient, exited <-chan error) error {
+	var last error
+	for {
+		if last = client.HealthCheck(ctx); last == nil {
+			return nil
+		}
+		select {
+		case err := <-exited:
+			return fmt.Errorf("%w: %v", errServerExited, err)
+		case <-ctx.Done():
+			return fmt.Errorf("%w (last error: %v)", ctx.Err(), last)
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+// supervise restarts the server whenever it exits. The backoff resets
+// once a server has stayed up for a minute, so a crash after days of
+// uptime restarts at once rather than after the previous crash loop's
+// ceiling.
+func (m *ManagedServer) supervise(ctx context.Context, exited chan error) {
+	const (
+		minBackoff = time.Second
+		maxBackoff = 30 * time.Second
+		stableRun  = time.Minute
+	)
+	backoff := minBackoff
+	started := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-exited:
+			if ctx.Err() != nil {
+				return
+			}
+			if time.Since(started) >= stableRun {
+				backoff = minBackoff
+			}
+			m.logger.Error("kopia server exited, restarting", "error", err, "backoff", backoff)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+		started = time.Now()
+		proc, err := m.launch(ctx)
+		if err != nil {
+			exited <- err
+			continue
+		}
+		go func() { exited <- proc.Wait() }()
+	}
+}
+
+// ConnectServer returns a ServerClient ready to query. With ext.URL set
+// it talks to that external server and only checks it answers. Otherwise
+// it connects cli (writing the on-disk config the server reads), starts a
+// ManagedServer on managed.Address for the lifetime of ctx, and points
+// the client at it; ext.Timeout still applies.
+func ConnectServer(ctx context.Context, ext ServerConfig, managed ManagedServerOptions, cli *Client, logger *slog.Logger) (*ServerClient, error) {
+	if ext.URL != "" {
+		sc, err := NewServerClient(ext, logger)
+		if err != nil {
+			return nil, err
+		}
+		if err := sc.HealthCheck(ctx); err != nil {
+			return nil, err
+		}
+		return sc, nil
+	}
+
+	if err := cli.Connect(ctx); err != nil {
+		return nil, err
+	}
+	ms, err := NewManagedServer(managed, logger)
+	if err != nil {
+		return nil, err
+	}
+	scCfg := ms.ServerConfig()
+	scCfg.Timeout = ext.Timeout
+	sc, err := NewServerClient(scCfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := ms.Start(ctx, sc); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
@@ -0,0 +1,449 @@
+package kopia
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+)
+
+const (
+	testServerUser     = "reader"
+	testServerPassword = "server-pass"
+	testCSRFToken      = "csrf-tok"
+	testSessionID      = "sess-1"
+)
+
+// fakeKopiaServer is an httptest stand-in for `kopia server start`'s API:
+// basic auth on every route, the UI routes' CSRF token + session cookie
+// when requireCSRF is set, and canned sources / snapshots.
+type fakeKopiaServer struct {
+	requireCSRF bool
+	connected   bool
+	sources     []SnapshotSource
+	// snapshots is keyed by SnapshotSource.String().
+	snapshots map[string][]map[string]any
+	failList  bool
+
+	rootHits    atomic.Int64
+	refreshHits atomic.Int64
+	listHits    atomic.Int64
+}
+
+func (f *fakeKopiaServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if u, p, ok := r.BasicAuth(); !ok || u != testServerUser || p != testServerPassword {
+		http.Error(w, "access denied", http.StatusUnauthorized)
+		return
+	}
+	if r.URL.Path == "/" {
+		f.rootHits.Add(1)
+		http.SetCookie(w, &http.Cookie{Name: serverSessionCookie, Value: testSessionID})
+		_, _ = w.Write([]byte(`<html><head><meta name="kopia-csrf-token" content="` + testCSRFToken + `" /></head></html>`))
+		return
+	}
+	if f.requireCSRF {
+		ck, err := r.Cookie(serverSessionCookie)
+		if err != nil || ck.Value != testSessionID || r.Header.Get(serverCSRFHeader) != testCSRFToken {
+			http.Error(w, "Invalid or missing CSRF token.", http.StatusUnauthorized)
+			return
+		}
+	}
+	switch {
+	case r.URL.Path == serverPathStatus:
+		writeJSON(w, map[string]any{"connected": f.connected})
+	case r.URL.Path == serverPathRefresh && r.Method == http.MethodPost:
+		f.refreshHits.Add(1)
+		writeJSON(w, map[string]any{})
+	case r.URL.Path == serverPathSources:
+		var out []map[string]any
+		for _, s := range f.sources {
+			out = append(out, map[string]any{"source": s, "status": "IDLE"})
+		}
+		writeJSON(w, map[string]any{"sources": out})
+	case r.URL.Path == serverPathSnapshots:
+		f.listHits.Add(1)
+		if f.failList {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		q := r.URL.Query()
+		if q.Get("all") != "1" {
+			http.Error(w, "test server expects all=1", http.StatusBadRequest)
+			return
+		}
+		src := SnapshotSource{UserName: q.Get("userName"), Host: q.Get("host"), Path: q.Get("path")}
+		snaps := f.snapshots[src.String()]
+		if snaps == nil {
+			snaps = []map[string]any{}
+		}
+		writeJSON(w, map[string]any{"snapshots": snaps, "unfilteredCount": len(snaps), "uniqueCount": len(snaps)})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func serverSnap(id, start string, size int64, retention ...string) map[string]any {
+	return map[string]any{
+		"id":        id,
+		"startTime": start,
+		"endTime":   start,
+		"summary":   map[string]any{"size": size, "files": 3, "dirs": 1, "numFailed": 0},
+		"retention": retention,
+	}
+}
+
+func newFakeKopiaServer() *fakeKopiaServer {
+	return &fakeKopiaServer{
+		requireCSRF: true,
+		connected:   true,
+		sources: []SnapshotSource{
+			LegacySource("myapp", "data"),
+			{Host: "builder", UserName: "root", Path: "/srv"},
+		},
+		snapshots: map[string][]map[string]any{
+			LegacySource("myapp", "data").String(): {
+				// Newest first on purpose: the client must sort.
+				serverSnap("bbb", "2026-05-21T03:00:00Z", 5017, "latest-1", "daily-1"),
+				serverSnap("aaa", "2026-05-20T03:00:00Z", 5012, "daily-2"),
+			},
+			"root@builder:/srv": {serverSnap("ccc", "2026-05-18T01:00:00Z", 4)},
+		},
+	}
+}
+
+func testServerClient(t *testing.T, f *fakeKopiaServer) (*ServerClient, *httptest.Server) {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sc, err := NewServerClientWithDoer(ServerConfig{
+		URL:      srv.URL,
+		Username: testServerUser,
+		Password: testServerPassword,
+	}, logger, srv.Client())
+	if err != nil {
+		t.Fatalf("NewServerClientWithDoer: %v", err)
+	}
+	return sc, srv
+}
+
+func TestNewServerClient_InvalidURL(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	for _, u := range []string{"", "127.0.0.1:51515", "://x"} {
+		if _, err := NewServerClient(ServerConfig{URL: u}, logger); err == nil {
+			t.Errorf("URL %q: expected error", u)
+		}
+	}
+}
+
+// TestServerClient_CheckBackupExists_CSRFHandshake pins the UI-route
+// dance: the first query is rejected for want of a CSRF token, the client
+// fetches the token and session cookie from / once, and every later call
+// reuses them.
+func TestServerClient_CheckBackupExists_CSRFHandshake(t *testing.T) {
+	f := newFakeKopiaServer()
+	sc, _ := testServerClient(t, f)
+
+	r := sc.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "data", ""))
+	if !r.Exists || r.Decision != backend.DecisionRestore || !r.Authoritative {
+		t.Fatalf("result: %+v", r)
+	}
+	if r.Backend != backend.TypeKopiaS3 || r.Source != "data-backup@myapp:/data" {
+		t.Errorf("identity: %+v", r)
+	}
+	if r.SnapshotCount != 2 || r.LatestSnapshotSize != 5017 ||
+		!r.LatestSnapshotAt.Equal(time.Date(2026, 5, 21, 3, 0, 0, 0, time.UTC)) {
+		t.Errorf("summary: %+v", r)
+	}
+
+	_ = sc.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "data", ""))
+	if got := f.rootHits.Load(); got != 1 {
+		t.Errorf("CSRF token fetched %d times, want 1", got)
+	}
+}
+
+func TestServerClient_ListSnapshots_MapsServerShape(t *testing.T) {
+	f := newFakeKopiaServer()
+	f.requireCSRF = false
+	sc, _ := testServerClient(t, f)
+
+	snaps, err := sc.ListSnapshots(context.Background(), LegacySource("myapp", "data"))
+	if err != nil {
+		t.Fatalf("ListSnapshots: %v", err)
+	}
+	if len(snaps) != 2 || snaps[0].ID != "aaa" || snaps[1].ID != "bbb" {
+		t.Fatalf("want oldest first [aaa bbb], got %+v", snaps)
+	}
+	s := snaps[1]
+	if s.Source != LegacySource("myapp", "data") || s.TotalSize != 5017 || s.FileCount != 3 || s.DirCount != 1 {
+		t.Errorf("fields: %+v", s)
+	}
+	if !slices.Equal(s.RetentionReasons, []string{"latest-1", "daily-1"}) {
+		t.Errorf("retention: %v", s.RetentionReasons)
+	}
+	if f.rootHits.Load() != 0 {
+		t.Errorf("CSRF token fetched although the server does not check it")
+	}
+}
+
+func TestServerClient_CheckBackupExists_Fresh(t *testing.T) {
+	sc, _ := testServerClient(t, newFakeKopiaServer())
+	r := sc.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "never-backed-up", ""))
+	if r.Exists || r.Decision != backend.DecisionFresh || !r.Authoritative || r.Error != "" {
+		t.Errorf("result: %+v", r)
+	}
+}
+
+func TestServerClient_CheckBackupExists_ServerErrorIsUnknown(t *testing.T) {
+	f := newFakeKopiaServer()
+	f.failList = true
+	sc, _ := testServerClient(t, f)
+	r := sc.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "data", ""))
+	if r.Decision != backend.DecisionUnknown || r.Authoritative || !strings.Contains(r.Error, "500") {
+		t.Errorf("result: %+v", r)
+	}
+}
+
+// TestServerClient_BadCredentialsNoCSRFLoop pins that a plain auth
+// failure surfaces as-is instead of being mistaken for a stale token.
+func TestServerClient_BadCredentialsNoCSRFLoop(t *testing.T) {
+	f := newFakeKopiaServer()
+	sc, _ := testServerClient(t, f)
+	sc.cfg.Password = "wrong"
+	err := sc.HealthCheck(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("got %v, want 401", err)
+	}
+	if f.rootHits.Load() != 0 {
+		t.Errorf("bad credentials triggered a CSRF fetch")
+	}
+}
+
+// TestServerClient_RefreshBoundsStaleness pins the on-demand refresh:
+// queries within MaxStaleness share one POST /api/v1/refresh; the next
+// query after it refreshes again.
+func TestServerClient_RefreshBoundsStaleness(t *testing.T) {
+	f := newFakeKopiaServer()
+	sc, _ := testServerClient(t, f)
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	sc.now = func() time.Time { return now }
+
+	for range 3 {
+		_ = sc.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "data", ""))
+	}
+	if got := f.refreshHits.Load(); got != 1 {
+		t.Fatalf("refreshes = %d, want 1", got)
+	}
+	now = now.Add(31 * time.Second)
+	_ = sc.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "data", ""))
+	if got := f.refreshHits.Load(); got != 2 {
+		t.Errorf("refreshes after MaxStaleness = %d, want 2", got)
+	}
+}
+
+func TestServerClient_SummarizeAllSources(t *testing.T) {
+	f := newFakeKopiaServer()
+	sc, _ := testServerClient(t, f)
+
+	got, err := sc.SummarizeAllSources(context.Background())
+	if err != nil {
+		t.Fatalf("SummarizeAllSources: %v", err)
+	}
+	// data-backup@myapp keys both myapp/data (legacy) and myapp/data-backup
+	// (v4), exactly as the live checks for those PVCs would see it.
+	if len(got) != 2 || got["myapp/data"].Count != 2 || got["myapp/data"].LatestSize != 5017 ||
+		got["myapp/data"].Source != "data-backup@myapp:/data" || got["myapp/data-backup"].Count != 2 {
+		t.Errorf("summaries: %+v", got)
+	}
+	// Sources following neither convention are not even listed; the scan
+	// always refreshes.
+	if f.listHits.Load() != 1 || f.refreshHits.Load() != 1 {
+		t.Errorf("list hits %d, refresh hits %d", f.listHits.Load(), f.refreshHits.Load())
+	}
+}
+
+// TestServerClient_ListAllSnapshots pins the inventory scan: every source
+// is listed, conventional or not, and the result is oldest first.
+func TestServerClient_ListAllSnapshots(t *testing.T) {
+	f := newFakeKopiaServer()
+	sc, _ := testServerClient(t, f)
+
+	snaps, err := sc.ListAllSnapshots(context.Background())
+	if err != nil {
+		t.Fatalf("ListAllSnapshots: %v", err)
+	}
+	var ids []string
+	for _, s := range snaps {
+		ids = append(ids, s.ID)
+	}
+	if !slices.Equal(ids, []string{"ccc", "aaa", "bbb"}) || snaps[0].Source.String() != "root@builder:/srv" {
+		t.Errorf("snapshots: %v", ids)
+	}
+	if f.listHits.Load() != 2 {
+		t.Errorf("list hits %d, want one per source", f.listHits.Load())
+	}
+}
+
+func TestServerClient_HealthCheck(t *testing.T) {
+	f := newFakeKopiaServer()
+	sc, _ := testServerClient(t, f)
+	if err := sc.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("HealthCheck: %v", err)
+	}
+	f.connected = false
+	if err := sc.HealthCheck(context.Background()); err == nil {
+		t.Error("HealthCheck passed on a server without a repository")
+	}
+}
+
+// fakeProcess exits when exit is closed.
+type fakeProcess struct{ exit chan struct{} }
+
+func (p *fakeProcess) Wait() error {
+	<-p.exit
+	return errors.New("signal: killed")
+}
+
+// fakeStarter records every launch and hands out fakeProcesses.
+type fakeStarter struct {
+	mu    sync.Mutex
+	env   []string
+	name  string
+	args  []string
+	procs []*fakeProcess
+	err   error
+}
+
+func (s *fakeStarter) Start(_ context.Context, env []string, name string, args ...string) (Process, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.env, s.name, s.args = env, name, args
+	p := &fakeProcess{exit: make(chan struct{})}
+	s.procs = append(s.procs, p)
+	return p, nil
+}
+
+func (s *fakeStarter) launches() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.procs)
+}
+
+func TestServerStartArgs(t *testing.T) {
+	args := serverStartArgs("127.0.0.1:51599")
+	want := []string{
+		"server", "start",
+		"--address=http://127.0.0.1:51599",
+		"--insecure",
+		"--server-username=pvc-plumber",
+		"--no-grpc",
+		"--no-persistent-logs",
+	}
+	if !slices.Equal(args, want) {
+		t.Errorf("args:\n got %v\nwant %v", args, want)
+	}
+}
+
+// TestManagedServer_StartAndRestart drives the supervisor against an
+// httptest API: the password travels via the environment only, Start
+// returns once the API answers, and an exit triggers a relaunch.
+func TestManagedServer_StartAndRestart(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	starter := &fakeStarter{}
+	ms, err := NewManagedServerWithStarter(ManagedServerOptions{Address: "127.0.0.1:1"}, logger, starter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := newFakeKopiaServer()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Accept the managed credentials in place of the fixture ones.
+		if u, p, _ := r.BasicAuth(); u == managedServerUsername && p == ms.password {
+			r.SetBasicAuth(testServerUser, testServerPassword)
+		}
+		f.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	cfg := ms.ServerConfig()
+	cfg.URL = srv.URL
+	sc, err := NewServerClientWithDoer(cfg, logger, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := ms.Start(ctx, sc); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if starter.name != "kopia" || slices.ContainsFunc(starter.args, func(a string) bool { return strings.Contains(a, ms.password) }) {
+		t.Errorf("password leaked into argv: %v", starter.args)
+	}
+	if !slices.Equal(starter.env, []string{"KOPIA_SERVER_PASSWORD=" + ms.password}) {
+		t.Errorf("env: %v", starter.env)
+	}
+
+	close(starter.procs[0].exit)
+	deadline := time.Now().Add(5 * time.Second)
+	for starter.launches() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("server was not restarted after exiting")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestManagedServer_ExitBeforeReady(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	starter := &fakeStarter{}
+	ms, err := NewManagedServerWithStarter(ManagedServerOptions{Address: "127.0.0.1:1", ReadyTimeout: 5 * time.Second}, logger, starter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nothing listens on port 1, so the probe fails until the process dies.
+	sc, err := NewServerClient(ms.ServerConfig(), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for starter.launches() == 0 {
+			time.Sleep(5 * time.Millisecond)
+		}
+		close(starter.procs[0].exit)
+	}()
+	if err := ms.Start(context.Background(), sc); !errors.Is(err, errServerExited) {
+		t.Errorf("got %v, want errServerExited", err)
+	}
+}
+
+func TestManagedServer_StartFailure(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	starter := &fakeStarter{err: errors.New("exec: \"kopia\": executable file not found in $PATH")}
+	ms, err := NewManagedServerWithStarter(ManagedServerOptions{}, logger, starter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc, _ := NewServerClient(ms.ServerConfig(), logger)
+	if err := ms.Start(context.Background(), sc); err == nil || !strings.Contains(err.Error(), "start kopia server") {
+		t.Errorf("got %v", err)
+	}
+}
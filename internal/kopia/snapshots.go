@@ -0,0 +1,350 @@
+package kopia
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+	"github.com/mitchross/pvc-plumber/internal/v4/naming"
+)
+
+// SnapshotSource is a kopia snapshot source — the (userName, host, path)
+// triple kopia keys every snapshot lineage on. String() renders the
+// `user@host:path` form `kopia snapshot list` accepts as an argument.
+type SnapshotSource struct {
+	Host     string `json:"host"`
+	UserName string `json:"userName"`
+	Path     string `json:"path"`
+}
+
+func (s SnapshotSource) String() string {
+	return s.UserName + "@" + s.Host + ":" + s.Path
+}
+
+// legacySourceSuffix is the username suffix the VolSync kopia mover
+// appends in the legacy convention: source = {pvc}-backup@{namespace}:/data.
+const legacySourceSuffix = "-backup"
+
+// LegacySource returns the source VolSync's kopia mover writes for a PVC
+// under the legacy convention every pre-v4 backup uses.
+func LegacySource(namespace, pvc string) SnapshotSource {
+	return SnapshotSource{Host: namespace, UserName: pvc + legacySourceSuffix, Path: "/data"}
+}
+
+// V4Source returns the source the v4 builder's ReplicationSource writes
+// for an identity: username=<identity.Username>, hostname=<identity.Hostname>.
+// An override carries no hostname, and VolSync's kopia mover then falls
+// back to the namespace, so the host does too.
+func V4Source(namespace string, id naming.KopiaIdentity) SnapshotSource {
+	return SnapshotSource{Host: id.SourceHost(namespace), UserName: id.Username, Path: naming.KopiaDataPath}
+}
+
+// QuerySources returns the lineages a query's backups may live under,
+// v4 first. A PVC on its default identity may have been backed up under
+// either convention (adopted into v4 after years of legacy snapshots), so
+// both are candidates; an explicit identity override only ever names the
+// v4 source.
+func QuerySources(q backend.Query) []SnapshotSource {
+	sources := []SnapshotSource{V4Source(q.Namespace, q.Identity)}
+	if !q.Overridden() {
+		sources = append(sources, LegacySource(q.Namespace, q.PVC))
+	}
+	return sources
+}
+
+// PVCKeys maps a source back to the "namespace/pvc" keys whose default
+// query lists it as a candidate: the legacy key for {pvc}-backup@{namespace}
+// and the v4 key for {pvc}@{namespace}:/data. "data-backup@ns" is both
+// ns/data's legacy lineage and ns/data-backup's v4 one, so it yields two
+// keys; a source following neither convention yields none.
+func PVCKeys(s SnapshotSource) []string {
+	if s.Host == "" || s.UserName == "" {
+		return nil
+	}
+	var keys []string
+	if pvc, ok := strings.CutSuffix(s.UserName, legacySourceSuffix); ok && pvc != "" {
+		keys = append(keys, s.Host+"/"+pvc)
+	}
+	if s.Path == "/data" {
+		keys = append(keys, s.Host+"/"+s.UserName)
+	}
+	return keys
+}
+
+// SnapshotInfo is one kopia snapshot manifest, reduced to the fields
+// consumers reason about: when it ran, how big it was, and why the
+// retention policy is keeping it.
+type SnapshotInfo struct {
+	ID          string         `json:"id"`
+	Source      SnapshotSource `json:"source"`
+	Description string         `json:"description,omitempty"`
+	StartTime   time.Time      `json:"startTime"`
+	EndTime     time.Time      `json:"endTime,omitzero"`
+
+	// TotalSize is the logical size of the snapshotted tree in bytes
+	// (kopia's stats.totalSize, falling back to rootEntry.summ.size on
+	// manifests that predate the stats block).
+	TotalSize  int64 `json:"totalSize"`
+	FileCount  int64 `json:"fileCount"`
+	DirCount   int64 `json:"dirCount"`
+	ErrorCount int64 `json:"errorCount,omitempty"`
+
+	// RetentionReasons is kopia's own explanation of why the snapshot is
+	// still kept ("latest-1", "daily-3", …). Empty when the snapshot is
+	// past every retention bucket and awaiting the next expiry.
+	RetentionReasons []string `json:"retentionReasons,omitempty"`
+	Pins             []string `json:"pins,omitempty"`
+
+	// IncompleteReason is non-empty for a checkpoint of a snapshot that
+	// never finished ("checkpoint", "canceled", …). Listed only when
+	// kopia is asked for incomplete snapshots, but tolerated here so a
+	// partial tree is never mistaken for the latest good backup.
+	IncompleteReason string `json:"incompleteReason,omitempty"`
+}
+
+// Incomplete reports whether the snapshot is an unfinished checkpoint.
+func (s SnapshotInfo) Incomplete() bool {
+	return s.IncompleteReason != ""
+}
+
+// snapshotManifest is the subset of `kopia snapshot list --json` output
+// the client decodes. Field names follow kopia's snapshot.Manifest JSON.
+type snapshotManifest struct {
+	ID               string         `json:"id"`
+	Source           SnapshotSource `json:"source"`
+	Description      string         `json:"description"`
+	StartTime        time.Time      `json:"startTime"`
+	EndTime          time.Time      `json:"endTime"`
+	IncompleteReason string         `json:"incomplete"`
+	Stats            struct {
+		TotalSize  int64 `json:"totalSize"`
+		FileCount  int64 `json:"fileCount"`
+		DirCount   int64 `json:"dirCount"`
+		ErrorCount int64 `json:"errorCount"`
+	} `json:"stats"`
+	RootEntry struct {
+		Summary struct {
+			Size int64 `json:"size"`
+		} `json:"summ"`
+	} `json:"rootEntry"`
+	RetentionReason []string `json:"retentionReason"`
+	Pins            []string `json:"pins"`
+}
+
+func (m snapshotManifest) info() SnapshotInfo {
+	size := m.Stats.TotalSize
+	if size == 0 {
+		size = m.RootEntry.Summary.Size
+	}
+	return SnapshotInfo{
+		ID:               m.ID,
+		Source:           m.Source,
+		Description:      m.Description,
+		StartTime:        m.StartTime,
+		EndTime:          m.EndTime,
+		TotalSize:        size,
+		FileCount:        m.Stats.FileCount,
+		DirCount:         m.Stats.DirCount,
+		ErrorCount:       m.Stats.ErrorCount,
+		RetentionReasons: m.RetentionReason,
+		Pins:             m.Pins,
+		IncompleteReason: m.IncompleteReason,
+	}
+}
+
+// DecodeSnapshotManifest decodes one snapshot manifest payload — the
+// `data` of a kopia manifest entry labelled type=snapshot — into a
+// SnapshotInfo. The payload carries no ID of its own (kopia stores it on
+// the enclosing entry), so the caller supplies it. RetentionReasons stay
+// empty: kopia computes them at list time from the policy, they are never
+// persisted.
+func DecodeSnapshotManifest(id string, data []byte) (SnapshotInfo, error) {
+	var m snapshotManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return SnapshotInfo{}, err
+	}
+	m.ID = id
+	return m.info(), nil
+}
+
+// parseSnapshotList decodes `kopia snapshot list --json` output into
+// SnapshotInfos sorted oldest → newest by StartTime.
+func parseSnapshotList(output []byte) ([]SnapshotInfo, error) {
+	var manifests []snapshotManifest
+	if err := json.Unmarshal(output, &manifests); err != nil {
+		return nil, err
+	}
+	out := make([]SnapshotInfo, 0, len(manifests))
+	for _, m := range manifests {
+		out = append(out, m.info())
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].StartTime.Before(out[j].StartTime) })
+	return out, nil
+}
+
+// Summarize reduces a lineage — or the union of a query's candidate
+// lineages — to the freshness fields carried on backend.CheckResult and in
+// the cache. The latest snapshot is the newest complete one, and Source
+// names the lineage it came from; snapshots holding only checkpoints have
+// a Count but no LatestAt or Source.
+func Summarize(snaps []SnapshotInfo) backend.SnapshotSummary {
+	sum := backend.SnapshotSummary{Count: len(snaps)}
+	for _, s := range snaps {
+		if s.Incomplete() {
+			continue
+		}
+		if sum.LatestAt.IsZero() || s.StartTime.After(sum.LatestAt) {
+			sum.LatestAt = s.StartTime
+			sum.LatestSize = s.TotalSize
+			sum.Source = s.Source.String()
+		}
+	}
+	return sum
+}
+
+// SummarizeByPVC groups snapshots under the "namespace/pvc" keys of the
+// default queries that would find them (see QuerySources) and summarizes
+// each group, so a PVC with both legacy and v4 lineages gets one merged
+// summary. Sources that follow neither convention are skipped. Overridden
+// identities are not keyed here; the cache looks them up live.
+func SummarizeByPVC(snaps []SnapshotInfo) map[string]backend.SnapshotSummary {
+	groups := make(map[string][]SnapshotInfo)
+	for _, s := range snaps {
+		for _, key := range PVCKeys(s.Source) {
+			groups[key] = append(groups[key], s)
+		}
+	}
+	out := make(map[string]backend.SnapshotSummary, len(groups))
+	for key, group := range groups {
+		out[key] = Summarize(group)
+	}
+	return out
+}
+
+// CheckResultFor builds the /exists answer for a query from the snapshots
+// of its candidate sources (or the error listing them). Any snapshot means
+// restore, none means fresh — both authoritative; a listing error is
+// DecisionUnknown and non-authoritative so the webhook fails closed.
+// Source is the lineage holding the latest snapshot, or the v4 source —
+// where the next backup will land — when there is none.
+func CheckResultFor(q backend.Query, snaps []SnapshotInfo, err error) backend.CheckResult {
+	result := q.Result(backend.TypeKopiaS3)
+	result.Source = QuerySources(q)[0].String()
+	if err != nil {
+		result.Decision = backend.DecisionUnknown
+		result.Error = err.Error()
+		return result
+	}
+	result.Exists = len(snaps) > 0
+	result.Decision = backend.DecisionFresh
+	if result.Exists {
+		result.Decision = backend.DecisionRestore
+	}
+	result.Authoritative = true
+	result.ApplySummary(Summarize(snaps))
+	return result
+}
+
+// CheckQuery lists every candidate source of q with list and merges them
+// into one CheckResultFor answer. The first listing error wins: a partial
+// answer could call a PVC fresh when its other lineage holds backups.
+func CheckQuery(ctx context.Context, q backend.Query, list func(context.Context, SnapshotSource) ([]SnapshotInfo, error)) backend.CheckResult {
+	var snaps []SnapshotInfo
+	for _, src := range QuerySources(q) {
+		lineage, err := list(ctx, src)
+		if err != nil {
+			return CheckResultFor(q, nil, err)
+		}
+		snaps = append(snaps, lineage...)
+	}
+	return CheckResultFor(q, snaps, nil)
+}
+
+// SourceSummarizer is what the cache pre-warm and re-warm loops need from
+// a kopia reader: one whole-repository scan summarized per PVC key.
+// Both the CLI-backed Client and the native reader implement it.
+type SourceSummarizer interface {
+	SummarizeAllSources(ctx context.Context) (map[string]backend.SnapshotSummary, error)
+}
+
+// SnapshotLister is a whole-repository scan that keeps every lineage,
+// including sources that follow neither naming convention and so never
+// reach a SourceSummarizer's per-PVC keys. The inventory report needs
+// those: an unrecognized lineage is exactly what it exists to surface.
+// The CLI-backed Client, the server client and the native reader all
+// implement it.
+type SnapshotLister interface {
+	ListAllSnapshots(ctx context.Context) ([]SnapshotInfo, error)
+}
+
+// ListSnapshots returns the full lineage for one source, oldest first.
+// An empty slice (not an error) means the source has no snapshots.
+func (c *Client) ListSnapshots(ctx context.Context, source SnapshotSource) ([]SnapshotInfo, error) {
+	output, err := c.executor.Run(ctx, "kopia", "snapshot", "list", source.String(), "--json")
+	if err != nil {
+		// Check if it's an exit error (command ran but returned non-zero)
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			c.logger.Error("kopia snapshot list failed",
+				"source", source.String(),
+				"error", err,
+				"stderr", string(exitErr.Stderr))
+		}
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	snaps, err := parseSnapshotList(output)
+	if err != nil {
+		c.logger.Error("failed to parse kopia output", "error", err, "output", string(output))
+		return nil, fmt.Errorf("failed to parse kopia output: %w", err)
+	}
+	return snaps, nil
+}
+
+// ListAllSnapshots lists every snapshot in the repository, oldest first,
+// with one `kopia snapshot list --all --json` call. Kopia leaves
+// incomplete checkpoints out of that listing.
+func (c *Client) ListAllSnapshots(ctx context.Context) ([]SnapshotInfo, error) {
+	return c.listAll(ctx)
+}
+
+// ListAllSnapshotsWithCheckpoints is ListAllSnapshots with --incomplete:
+// checkpoints of snapshots still being written (or abandoned) are listed
+// too, marked Incomplete.
+func (c *Client) ListAllSnapshotsWithCheckpoints(ctx context.Context) ([]SnapshotInfo, error) {
+	return c.listAll(ctx, "--incomplete")
+}
+
+func (c *Client) listAll(ctx context.Context, extra ...string) ([]SnapshotInfo, error) {
+	args := append([]string{"snapshot", "list", "--all", "--json"}, extra...)
+	output, err := c.executor.Run(ctx, "kopia", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list all snapshots: %w", err)
+	}
+	snaps, err := parseSnapshotList(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot list: %w", err)
+	}
+	return snaps, nil
+}
+
+// SummarizeAllSources lists every snapshot in the repository (see
+// ListAllSnapshots) and returns a summary per "namespace/pvc" key (see
+// SummarizeByPVC).
+func (c *Client) SummarizeAllSources(ctx context.Context) (map[string]backend.SnapshotSummary, error) {
+	c.logger.Info("listing all kopia snapshots for cache pre-warm")
+
+	snaps, err := c.ListAllSnapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := SummarizeByPVC(snaps)
+	c.logger.Info("snapshot scan complete", "unique_sources", len(out), "snapshots", len(snaps))
+	return out, nil
+}
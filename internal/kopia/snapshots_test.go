@@ -0,0 +1,242 @@
+package kopia
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+	"github.com/mitchross/pvc-plumber/internal/v4/naming"
+)
+
+// lineageJSON is trimmed `kopia snapshot list --json` output for one
+// source: two complete snapshots (deliberately out of order) and a
+// checkpoint newer than both. The older snapshot predates the stats
+// block, so its size comes from rootEntry.summ.
+const lineageJSON = `[
+  {
+    "id": "k2", "source": {"host": "karakeep", "userName": "data-backup", "path": "/data"},
+    "startTime": "2026-06-02T03:00:00Z", "endTime": "2026-06-02T03:01:30Z",
+    "stats": {"totalSize": 2048, "fileCount": 12, "dirCount": 3, "errorCount": 0},
+    "rootEntry": {"summ": {"size": 2048}},
+    "retentionReason": ["latest-1", "daily-1"]
+  },
+  {
+    "id": "k1", "source": {"host": "karakeep", "userName": "data-backup", "path": "/data"},
+    "startTime": "2026-06-01T03:00:00Z", "endTime": "2026-06-01T03:01:00Z",
+    "rootEntry": {"summ": {"size": 1024}},
+    "retentionReason": ["daily-2"]
+  },
+  {
+    "id": "k3", "source": {"host": "karakeep", "userName": "data-backup", "path": "/data"},
+    "startTime": "2026-06-03T03:00:00Z", "incomplete": "checkpoint",
+    "stats": {"totalSize": 99}
+  }
+]`
+
+func snapshotTestClient(out string, err error) (*Client, *mockExecutor) {
+	mock := &mockExecutor{output: []byte(out), err: err}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewClientWithExecutor(testS3Config(), testCreds(), logger, mock, Options{}), mock
+}
+
+func TestListSnapshots_ParsesAndSortsLineage(t *testing.T) {
+	c, mock := snapshotTestClient(lineageJSON, nil)
+	snaps, err := c.ListSnapshots(context.Background(), LegacySource("karakeep", "data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(mock.lastArgs, "data-backup@karakeep:/data") {
+		t.Errorf("argv: %v", mock.lastArgs)
+	}
+	if len(snaps) != 3 || snaps[0].ID != "k1" || snaps[1].ID != "k2" || snaps[2].ID != "k3" {
+		t.Fatalf("want k1,k2,k3 oldest first: %+v", snaps)
+	}
+	if snaps[0].TotalSize != 1024 {
+		t.Errorf("rootEntry.summ.size fallback: got %d", snaps[0].TotalSize)
+	}
+	k2 := snaps[1]
+	if k2.TotalSize != 2048 || k2.FileCount != 12 || k2.DirCount != 3 ||
+		!slices.Equal(k2.RetentionReasons, []string{"latest-1", "daily-1"}) ||
+		!k2.EndTime.Equal(time.Date(2026, 6, 2, 3, 1, 30, 0, time.UTC)) {
+		t.Errorf("k2 fields: %+v", k2)
+	}
+	if !snaps[2].Incomplete() || snaps[1].Incomplete() {
+		t.Error("incomplete marker")
+	}
+}
+
+func TestListSnapshots_Errors(t *testing.T) {
+	c, _ := snapshotTestClient("", errors.New("exit status 1"))
+	if _, err := c.ListSnapshots(context.Background(), LegacySource("a", "b")); err == nil {
+		t.Error("command failure must error")
+	}
+	c, _ = snapshotTestClient("not json", nil)
+	if _, err := c.ListSnapshots(context.Background(), LegacySource("a", "b")); err == nil {
+		t.Error("parse failure must error")
+	}
+}
+
+func TestSummarize_LatestSkipsCheckpoints(t *testing.T) {
+	snaps, err := parseSnapshotList([]byte(lineageJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Summarize(snaps)
+	want := backend.SnapshotSummary{
+		Count:      3,
+		LatestAt:   time.Date(2026, 6, 2, 3, 0, 0, 0, time.UTC),
+		LatestSize: 2048,
+		Source:     "data-backup@karakeep:/data",
+	}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if empty := Summarize(nil); empty != (backend.SnapshotSummary{}) {
+		t.Errorf("empty lineage: %+v", empty)
+	}
+}
+
+func TestCheckBackupExists_CarriesLatestSnapshot(t *testing.T) {
+	c, mock := snapshotTestClient("", nil)
+	mock.bySource = map[string]string{"data-backup@karakeep:/data": lineageJSON}
+	res := c.CheckBackupExists(context.Background(), backend.NewQuery("karakeep", "data", ""))
+	if !res.Exists || res.SnapshotCount != 3 || res.LatestSnapshotSize != 2048 ||
+		!res.LatestSnapshotAt.Equal(time.Date(2026, 6, 2, 3, 0, 0, 0, time.UTC)) {
+		t.Errorf("result: %+v", res)
+	}
+	if res.Source != "data-backup@karakeep:/data" || res.Identity != "karakeep/data" {
+		t.Errorf("source/identity: %+v", res)
+	}
+}
+
+// v4LineageJSON is karakeep/data after adoption into v4 naming: the
+// builder's ReplicationSource writes username=data, hostname=karakeep.
+const v4LineageJSON = `[
+  {
+    "id": "v1", "source": {"host": "karakeep", "userName": "data", "path": "/data"},
+    "startTime": "2026-06-04T03:00:00Z", "stats": {"totalSize": 4096}
+  }
+]`
+
+func TestQuerySources(t *testing.T) {
+	for _, tc := range []struct {
+		q    backend.Query
+		want []string
+	}{
+		{backend.NewQuery("karakeep", "data", ""), []string{"data@karakeep:/data", "data-backup@karakeep:/data"}},
+		{backend.NewQuery("immich-prod", "library", "immich-library"), []string{"immich-library@immich-prod:/data"}},
+	} {
+		var got []string
+		for _, s := range QuerySources(tc.q) {
+			got = append(got, s.String())
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("QuerySources(%+v) = %v, want %v", tc.q, got, tc.want)
+		}
+	}
+}
+
+// V4Source must render exactly the lineage the collision check keys on,
+// or two PVCs could share a kopia source without being flagged.
+func TestV4Source_MatchesIdentityLineage(t *testing.T) {
+	for _, tc := range [][3]string{{"karakeep", "data", ""}, {"immich-prod", "library", "immich-library"}} {
+		id := naming.IdentityFor(tc[0], tc[1], tc[2])
+		if got, want := V4Source(tc[0], id).String(), id.Lineage(tc[0]); got != want {
+			t.Errorf("V4Source(%v) = %q, Lineage = %q", tc, got, want)
+		}
+	}
+}
+
+// TestCheckBackupExists_MergesV4AndLegacy pins that a PVC adopted into v4
+// naming is found under either convention, and the newest snapshot —
+// whichever lineage holds it — decides Source and the freshness fields.
+func TestCheckBackupExists_MergesV4AndLegacy(t *testing.T) {
+	c, mock := snapshotTestClient("", nil)
+	mock.bySource = map[string]string{"data@karakeep:/data": v4LineageJSON}
+	res := c.CheckBackupExists(context.Background(), backend.NewQuery("karakeep", "data", ""))
+	if !res.Exists || res.Decision != backend.DecisionRestore || res.SnapshotCount != 1 || res.Source != "data@karakeep:/data" {
+		t.Errorf("v4 only: %+v", res)
+	}
+	if !slices.Equal(mock.listed, []string{"data@karakeep:/data", "data-backup@karakeep:/data"}) {
+		t.Errorf("listed: %v", mock.listed)
+	}
+
+	mock.bySource["data-backup@karakeep:/data"] = lineageJSON
+	res = c.CheckBackupExists(context.Background(), backend.NewQuery("karakeep", "data", ""))
+	if res.SnapshotCount != 4 || res.LatestSnapshotSize != 4096 || res.Source != "data@karakeep:/data" ||
+		!res.LatestSnapshotAt.Equal(time.Date(2026, 6, 4, 3, 0, 0, 0, time.UTC)) {
+		t.Errorf("merged: %+v", res)
+	}
+}
+
+func TestCheckBackupExists_IdentityOverride(t *testing.T) {
+	c, mock := snapshotTestClient("", nil)
+	mock.bySource = map[string]string{
+		"immich-library@immich-prod:/data": v4LineageJSON,
+		"library-backup@immich-prod:/data": lineageJSON,
+	}
+	res := c.CheckBackupExists(context.Background(), backend.NewQuery("immich-prod", "library", "immich-library"))
+	if !res.Exists || res.SnapshotCount != 1 || res.Identity != "immich-library" || res.Pvc != "library" {
+		t.Errorf("override: %+v", res)
+	}
+	// The override replaces the PVC's own lineages; the legacy one is
+	// never consulted.
+	if !slices.Equal(mock.listed, []string{"immich-library@immich-prod:/data"}) {
+		t.Errorf("listed: %v", mock.listed)
+	}
+}
+
+func TestCheckBackupExists_AnyListingErrorIsUnknown(t *testing.T) {
+	c, mock := snapshotTestClient("", nil)
+	mock.bySource = map[string]string{"data@karakeep:/data": v4LineageJSON, "data-backup@karakeep:/data": "not json"}
+	res := c.CheckBackupExists(context.Background(), backend.NewQuery("karakeep", "data", ""))
+	if res.Exists || res.Decision != backend.DecisionUnknown || res.Authoritative || res.Error == "" {
+		t.Errorf("result: %+v", res)
+	}
+}
+
+func TestSummarizeAllSources_GroupsByPVC(t *testing.T) {
+	all := `[
+	  {"id": "a1", "source": {"host": "ns1", "userName": "db-backup", "path": "/data"}, "startTime": "2026-06-01T00:00:00Z", "stats": {"totalSize": 10}},
+	  {"id": "a2", "source": {"host": "ns1", "userName": "db-backup", "path": "/data"}, "startTime": "2026-06-02T00:00:00Z", "stats": {"totalSize": 20}},
+	  {"id": "a3", "source": {"host": "ns1", "userName": "db", "path": "/data"}, "startTime": "2026-06-03T00:00:00Z", "stats": {"totalSize": 30}},
+	  {"id": "b1", "source": {"host": "ns2", "userName": "cache", "path": "/data"}, "startTime": "2026-06-01T00:00:00Z"},
+	  {"id": "x1", "source": {"host": "laptop", "userName": "root", "path": "/home"}, "startTime": "2026-06-01T00:00:00Z"}
+	]`
+	c, _ := snapshotTestClient(all, nil)
+	got, err := c.SummarizeAllSources(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// db-backup@ns1 is ns1/db's legacy lineage and, read as v4, the
+	// lineage of a PVC named db-backup — the same answer a live check for
+	// either PVC gives.
+	keys := slices.Sorted(maps.Keys(got))
+	if !slices.Equal(keys, []string{"ns1/db", "ns1/db-backup", "ns2/cache"}) {
+		t.Fatalf("keys: %v", keys)
+	}
+	if db := got["ns1/db"]; db.Count != 3 || db.LatestSize != 30 || db.Source != "db@ns1:/data" {
+		t.Errorf("ns1/db merges legacy and v4: %+v", db)
+	}
+	if legacyOnly := got["ns1/db-backup"]; legacyOnly.Count != 2 || legacyOnly.Source != "db-backup@ns1:/data" {
+		t.Errorf("ns1/db-backup: %+v", legacyOnly)
+	}
+
+	// The unconventional laptop lineage keys no PVC, but the full scan
+	// still returns it.
+	snaps, err := c.ListAllSnapshots(context.Background())
+	if err != nil || len(snaps) != 5 {
+		t.Errorf("ListAllSnapshots: %d snapshots, %v", len(snaps), err)
+	}
+
+	sources, err := c.ListAllSources(context.Background())
+	if err != nil || !sources["ns1/db"] || !sources["ns2/cache"] || len(sources) != 3 {
+		t.Errorf("ListAllSources: %v %v", sources, err)
+	}
+}
@@ -3,6 +3,7 @@ package s3
 import (
 	"context"
 	"fmt"
+	"io"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
@@ -12,9 +13,17 @@ import (
 type Client struct {
 	minioClient *minio.Client
 	bucket      string
+	layout      Layout
 }
 
-func NewClient(endpoint, bucket, accessKey, secretKey string, secure bool) (*Client, error) {
+// Options bundles the optional knobs NewClient accepts.
+type Options struct {
+	// Layout decides where an identity's backups live in the bucket.
+	// Defaults to PrefixLayout{}, the "<namespace>/<pvc>/" listing.
+	Layout Layout
+}
+
+func NewClient(endpoint, bucket, accessKey, secretKey string, secure bool, opts Options) (*Client, error) {
 	minioClient, err := minio.New(endpoint, &minio.Options{
 		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
 		Secure: secure,
@@ -23,9 +32,14 @@ func NewClient(endpoint, bucket, accessKey, secretKey string, secure bool) (*Cli
 		return nil, fmt.Errorf("failed to create minio client: %w", err)
 	}
 
+	layout := opts.Layout
+	if layout == nil {
+		layout = PrefixLayout{}
+	}
 	return &Client{
 		minioClient: minioClient,
 		bucket:      bucket,
+		layout:      layout,
 	}, nil
 }
 
@@ -41,51 +55,64 @@ func (c *Client) HealthCheck(ctx context.Context) error {
 	return nil
 }
 
-func (c *Client) CheckBackupExists(ctx context.Context, namespace, pvc string) backend.CheckResult {
-	prefix := fmt.Sprintf("%s/%s/", namespace, pvc)
-
-	opts := minio.ListObjectsOptions{
-		Prefix:  prefix,
-		MaxKeys: 1,
+// CheckBackupExists looks the query's identity up through the configured
+// layout. Namespace and PVC only label the result; the identity decides
+// where to look.
+func (c *Client) CheckBackupExists(ctx context.Context, q backend.Query) backend.CheckResult {
+	found, err := c.layout.Lookup(ctx, c, q.Identity)
+	result := q.Result(backend.TypeS3)
+	result.Source = found.Source
+	if err != nil {
+		result.Decision = backend.DecisionUnknown
+		result.Error = fmt.Sprintf("%s layout: %v", c.layout.Name(), err)
+		return result
 	}
-
-	objectCh := c.minioClient.ListObjects(ctx, c.bucket, opts)
-
-	// Check if any object exists (MaxKeys=1, so at most one result)
-	object, ok := <-objectCh
-	if !ok {
-		// Channel closed with no objects
-		return backend.CheckResult{
-			Exists:        false,
-			Decision:      backend.DecisionFresh,
-			Authoritative: true,
-			Namespace:     namespace,
-			Pvc:           pvc,
-			Backend:       "s3",
-			Source:        prefix,
-		}
+	result.Authoritative = true
+	if !found.Found {
+		result.Decision = backend.DecisionFresh
+		return result
 	}
+	result.Exists = true
+	result.Decision = backend.DecisionRestore
+	result.ApplySummary(found.Summary)
+	return result
+}
 
-	if object.Err != nil {
-		return backend.CheckResult{
-			Exists:        false,
-			Decision:      backend.DecisionUnknown,
-			Authoritative: false,
-			Namespace:     namespace,
-			Pvc:           pvc,
-			Backend:       "s3",
-			Source:        prefix,
-			Error:         fmt.Sprintf("failed to list objects: %v", object.Err),
+// List implements Bucket.
+func (c *Client) List(ctx context.Context, prefix string, limit int) ([]Object, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // stops minio's listing goroutine when we return early
+	opts := minio.ListObjectsOptions{Prefix: prefix, Recursive: true}
+	if limit > 0 {
+		opts.MaxKeys = limit
+	}
+	var out []Object
+	for obj := range c.minioClient.ListObjects(ctx, c.bucket, opts) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", obj.Err)
+		}
+		out = append(out, Object{Key: obj.Key, Size: obj.Size, LastModified: obj.LastModified})
+		if limit > 0 && len(out) >= limit {
+			break
 		}
 	}
+	return out, nil
+}
 
-	return backend.CheckResult{
-		Exists:        true,
-		Decision:      backend.DecisionRestore,
-		Authoritative: true,
-		Namespace:     namespace,
-		Pvc:           pvc,
-		Backend:       "s3",
-		Source:        prefix,
+// Get implements Bucket. minio-go defers the request to the first read,
+// so a missing key surfaces from ReadAll, not GetObject.
+func (c *Client) Get(ctx context.Context, key string) ([]byte, error) {
+	obj, err := c.minioClient.GetObject(ctx, c.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object %s: %w", key, err)
+	}
+	defer func() { _ = obj.Close() }()
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, fmt.Errorf("%s: %w", key, ErrObjectNotFound)
+		}
+		return nil, fmt.Errorf("failed to read object %s: %w", key, err)
 	}
+	return data, nil
 }
@@ -8,7 +8,7 @@ import (
 )
 
 func TestNewClient(t *testing.T) {
-	client, err := NewClient("localhost:9000", "test-bucket", "accesskey", "secretkey", false)
+	client, err := NewClient("localhost:9000", "test-bucket", "accesskey", "secretkey", false, Options{})
 	if err != nil {
 		t.Fatalf("NewClient returned error: %v", err)
 	}
@@ -27,7 +27,7 @@ func TestNewClient(t *testing.T) {
 func TestNewClient_EmptyEndpoint(t *testing.T) {
 	// minio-go validates the endpoint, but empty string is actually accepted
 	// and will fail later when making requests
-	client, err := NewClient("", "test-bucket", "accesskey", "secretkey", false)
+	client, err := NewClient("", "test-bucket", "accesskey", "secretkey", false, Options{})
 	if err != nil {
 		// If minio-go rejects empty endpoint, that's fine
 		return
@@ -41,12 +41,12 @@ func TestCheckBackupExists_Integration(t *testing.T) {
 	// Skip in CI - this test requires a real MinIO/S3 instance
 	t.Skip("Integration test - requires MinIO instance")
 
-	client, err := NewClient("localhost:9000", "test-bucket", "minioadmin", "minioadmin", false)
+	client, err := NewClient("localhost:9000", "test-bucket", "minioadmin", "minioadmin", false, Options{})
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
 
-	result := client.CheckBackupExists(context.Background(), "test-ns", "test-pvc")
+	result := client.CheckBackupExists(context.Background(), backend.NewQuery("test-ns", "test-pvc", ""))
 	// Just verify it doesn't panic - actual result depends on bucket contents
 	t.Logf("Result: exists=%v, namespace=%s, pvc=%s, backend=%s, error=%s",
 		result.Exists, result.Namespace, result.Pvc, result.Backend, result.Error)
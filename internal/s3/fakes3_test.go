@@ -0,0 +1,165 @@
+package s3
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is the slice of the S3 REST API minio-go's ListObjects (v2),
+// GetObject and BucketExists speak, served path-style from memory. Enough
+// to run Client end to end without a MinIO instance.
+type fakeS3 struct {
+	bucket string
+	// pageSize caps keys per ListObjectsV2 page so tests exercise
+	// continuation tokens. Defaults to 1000, S3's own cap.
+	pageSize int
+	// failList makes every listing return 403 AccessDenied (a 5xx would
+	// sit through minio-go's retry backoff).
+	failList bool
+
+	mu      sync.Mutex
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	data     []byte
+	modified time.Time
+}
+
+func newFakeS3(t *testing.T, bucket string) (*fakeS3, *Client) {
+	t.Helper()
+	f := &fakeS3{bucket: bucket, objects: map[string]fakeObject{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	c, err := NewClient(strings.TrimPrefix(srv.URL, "http://"), bucket, "ak", "sk", false, Options{})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return f, c
+}
+
+func (f *fakeS3) put(key, data string, modified time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[key] = fakeObject{data: []byte(data), modified: modified}
+}
+
+type s3Error struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_ = xml.NewEncoder(w).Encode(s3Error{Code: code, Message: code})
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	q := r.URL.Query()
+	switch {
+	case key == "" && q.Has("location"):
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = fmt.Fprint(w, `<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/"></LocationConstraint>`)
+	case key == "" && r.Method == http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+	case key == "" && q.Get("list-type") == "2":
+		f.list(w, q)
+	case key != "" && r.Method == http.MethodGet:
+		obj, ok := f.objects[key]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Header().Set("Last-Modified", obj.modified.UTC().Format(http.TimeFormat))
+		w.Header().Set("ETag", `"fake"`)
+		_, _ = w.Write(obj.data)
+	default:
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+type listContent struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	Size         int64  `xml:"Size"`
+	ETag         string `xml:"ETag"`
+}
+
+type listResult struct {
+	XMLName               xml.Name      `xml:"ListBucketResult"`
+	Name                  string        `xml:"Name"`
+	Prefix                string        `xml:"Prefix"`
+	KeyCount              int           `xml:"KeyCount"`
+	MaxKeys               int           `xml:"MaxKeys"`
+	IsTruncated           bool          `xml:"IsTruncated"`
+	NextContinuationToken string        `xml:"NextContinuationToken,omitempty"`
+	Contents              []listContent `xml:"Contents"`
+}
+
+// list serves ListObjectsV2 in key order. The continuation token is
+// simply the last key of the previous page.
+func (f *fakeS3) list(w http.ResponseWriter, q map[string][]string) {
+	if f.failList {
+		writeS3Error(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+	get := func(k string) string {
+		if v := q[k]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	prefix, after := get("prefix"), get("continuation-token")
+	limit := f.pageSize
+	if limit <= 0 {
+		limit = 1000
+	}
+	if mk, err := strconv.Atoi(get("max-keys")); err == nil && mk > 0 && mk < limit {
+		limit = mk
+	}
+
+	var keys []string
+	for k := range f.objects {
+		if strings.HasPrefix(k, prefix) && k > after {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	res := listResult{Name: f.bucket, Prefix: prefix, MaxKeys: limit}
+	if len(keys) > limit {
+		keys = keys[:limit]
+		res.IsTruncated = true
+		res.NextContinuationToken = keys[len(keys)-1]
+	}
+	for _, k := range keys {
+		o := f.objects[k]
+		res.Contents = append(res.Contents, listContent{
+			Key:          k,
+			LastModified: o.modified.UTC().Format(time.RFC3339),
+			Size:         int64(len(o.data)),
+			ETag:         `"fake"`,
+		})
+	}
+	res.KeyCount = len(res.Contents)
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,179 @@
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+	"github.com/mitchross/pvc-plumber/internal/v4/naming"
+)
+
+// Layout names, the values of S3_LAYOUT.
+const (
+	LayoutPrefix = "prefix"
+	LayoutRestic = "restic"
+	LayoutIndex  = "index"
+)
+
+// DefaultIndexKey is the object the index layout reads when no key is
+// configured.
+const DefaultIndexKey = "pvc-plumber-index.json"
+
+// ErrObjectNotFound is returned by Bucket.Get for a key that does not
+// exist.
+var ErrObjectNotFound = errors.New("object not found")
+
+// Object is one listed key.
+type Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// Bucket is the read surface a Layout needs. The minio-backed Client
+// implements it; layouts never see minio types.
+type Bucket interface {
+	// List returns up to limit objects under prefix (all when limit <= 0).
+	List(ctx context.Context, prefix string, limit int) ([]Object, error)
+	// Get returns the whole object, or ErrObjectNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// Lookup is what a Layout found for one identity.
+type Lookup struct {
+	Found bool
+	// Source is the key or prefix consulted, reported as
+	// CheckResult.Source.
+	Source string
+	// Summary carries snapshot metadata when the layout can see it; zero
+	// when it only knows existence.
+	Summary backend.SnapshotSummary
+}
+
+// Layout maps a backup identity onto the bucket. Implementations differ
+// in where backups live and how much they can tell about them.
+type Layout interface {
+	Name() string
+	Lookup(ctx context.Context, b Bucket, id naming.KopiaIdentity) (Lookup, error)
+}
+
+// LayoutOptions configures NewLayout.
+type LayoutOptions struct {
+	// Name is one of LayoutPrefix (default), LayoutRestic, LayoutIndex.
+	Name string
+	// Prefix is prepended to every key the layout consults, e.g.
+	// "volsync/" when backups share the bucket with other data.
+	Prefix string
+	// IndexKey is the index layout's object, under Prefix. Defaults to
+	// DefaultIndexKey.
+	IndexKey string
+}
+
+// NewLayout returns the layout opts names.
+func NewLayout(opts LayoutOptions) (Layout, error) {
+	switch opts.Name {
+	case "", LayoutPrefix:
+		return PrefixLayout{Prefix: opts.Prefix}, nil
+	case LayoutRestic:
+		return ResticLayout{Prefix: opts.Prefix}, nil
+	case LayoutIndex:
+		key := opts.IndexKey
+		if key == "" {
+			key = DefaultIndexKey
+		}
+		return IndexLayout{Key: opts.Prefix + key}, nil
+	default:
+		return nil, fmt.Errorf("unknown s3 layout %q (must be %q, %q or %q)", opts.Name, LayoutPrefix, LayoutRestic, LayoutIndex)
+	}
+}
+
+// PrefixLayout treats any object under "<prefix><identity>/" as a backup
+// — the pre-layout behaviour, where the identity is "<namespace>/<pvc>"
+// unless overridden. It only knows existence.
+type PrefixLayout struct {
+	Prefix string
+}
+
+func (PrefixLayout) Name() string { return LayoutPrefix }
+
+func (l PrefixLayout) Lookup(ctx context.Context, b Bucket, id naming.KopiaIdentity) (Lookup, error) {
+	prefix := l.Prefix + id.Key() + "/"
+	objs, err := b.List(ctx, prefix, 1)
+	if err != nil {
+		return Lookup{Source: prefix}, err
+	}
+	return Lookup{Found: len(objs) > 0, Source: prefix}, nil
+}
+
+// ResticLayout reads a restic repository per identity at
+// "<prefix><identity>/", the shape VolSync's restic mover writes. Each
+// snapshot is one object under its snapshots/ directory, so the listing
+// yields the snapshot count and, from the newest object's mtime, when
+// the latest one landed. Sizes are encrypted inside the objects and stay
+// unknown.
+type ResticLayout struct {
+	Prefix string
+}
+
+func (ResticLayout) Name() string { return LayoutRestic }
+
+func (l ResticLayout) Lookup(ctx context.Context, b Bucket, id naming.KopiaIdentity) (Lookup, error) {
+	prefix := l.Prefix + id.Key() + "/snapshots/"
+	objs, err := b.List(ctx, prefix, 0)
+	if err != nil {
+		return Lookup{Source: prefix}, err
+	}
+	out := Lookup{Found: len(objs) > 0, Source: prefix}
+	out.Summary.Count = len(objs)
+	for _, o := range objs {
+		if o.LastModified.After(out.Summary.LatestAt) {
+			out.Summary.LatestAt = o.LastModified
+		}
+	}
+	return out, nil
+}
+
+// IndexLayout reads one JSON object a backup hook maintains:
+//
+//	{"identities": {"<identity>": {"count": 3, "latestAt": "…", "latestSize": 123}}}
+//
+// An identity listed there has a backup; count / latestAt / latestSize
+// are optional. A missing index object is an error, not "no backups":
+// the hook may simply not have run yet.
+type IndexLayout struct {
+	Key string
+}
+
+func (IndexLayout) Name() string { return LayoutIndex }
+
+// indexDocument is the IndexLayout object schema.
+type indexDocument struct {
+	Identities map[string]struct {
+		Count      int       `json:"count"`
+		LatestAt   time.Time `json:"latestAt"`
+		LatestSize int64     `json:"latestSize"`
+	} `json:"identities"`
+}
+
+func (l IndexLayout) Lookup(ctx context.Context, b Bucket, id naming.KopiaIdentity) (Lookup, error) {
+	raw, err := b.Get(ctx, l.Key)
+	if err != nil {
+		return Lookup{Source: l.Key}, fmt.Errorf("read index object: %w", err)
+	}
+	var doc indexDocument
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return Lookup{Source: l.Key}, fmt.Errorf("decode index object %s: %w", l.Key, err)
+	}
+	e, ok := doc.Identities[id.Key()]
+	if !ok {
+		return Lookup{Source: l.Key}, nil
+	}
+	return Lookup{
+		Found:   true,
+		Source:  l.Key,
+		Summary: backend.SnapshotSummary{Count: e.Count, LatestAt: e.LatestAt, LatestSize: e.LatestSize},
+	}, nil
+}
@@ -0,0 +1,173 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/backend"
+)
+
+const testBucket = "backups"
+
+var (
+	day1 = time.Date(2026, 5, 20, 3, 0, 0, 0, time.UTC)
+	day2 = time.Date(2026, 5, 21, 3, 0, 0, 0, time.UTC)
+)
+
+func TestNewLayout(t *testing.T) {
+	for _, tc := range []struct {
+		opts LayoutOptions
+		want Layout
+	}{
+		{LayoutOptions{}, PrefixLayout{}},
+		{LayoutOptions{Name: LayoutPrefix, Prefix: "volsync/"}, PrefixLayout{Prefix: "volsync/"}},
+		{LayoutOptions{Name: LayoutRestic, Prefix: "restic/"}, ResticLayout{Prefix: "restic/"}},
+		{LayoutOptions{Name: LayoutIndex, Prefix: "meta/"}, IndexLayout{Key: "meta/" + DefaultIndexKey}},
+		{LayoutOptions{Name: LayoutIndex, IndexKey: "idx.json"}, IndexLayout{Key: "idx.json"}},
+	} {
+		got, err := NewLayout(tc.opts)
+		if err != nil || got != tc.want {
+			t.Errorf("NewLayout(%+v) = %#v, %v; want %#v", tc.opts, got, err, tc.want)
+		}
+	}
+	if _, err := NewLayout(LayoutOptions{Name: "kopia"}); err == nil {
+		t.Error("unknown layout accepted")
+	}
+}
+
+// TestPrefixLayout_DefaultIdentity pins the pre-layout behaviour: any
+// object under "<namespace>/<pvc>/" is a backup; a sibling PVC whose
+// name shares the prefix is not.
+func TestPrefixLayout_DefaultIdentity(t *testing.T) {
+	f, c := newFakeS3(t, testBucket)
+	f.put("myapp/data/chunk-0001", "x", day1)
+	f.put("myapp/data-old/chunk-0001", "x", day1)
+
+	r := c.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "data", ""))
+	if !r.Exists || r.Decision != backend.DecisionRestore || !r.Authoritative || r.Source != "myapp/data/" || r.Backend != backend.TypeS3 {
+		t.Errorf("restore: %+v", r)
+	}
+	if r.SnapshotCount != 0 || !r.LatestSnapshotAt.IsZero() {
+		t.Errorf("prefix layout must not invent snapshot metadata: %+v", r)
+	}
+
+	r = c.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "dat", ""))
+	if r.Exists || r.Decision != backend.DecisionFresh || !r.Authoritative {
+		t.Errorf("fresh: %+v", r)
+	}
+}
+
+func TestPrefixLayout_OverrideIdentityAndRootPrefix(t *testing.T) {
+	f, c := newFakeS3(t, testBucket)
+	c.layout = PrefixLayout{Prefix: "volsync/"}
+	f.put("volsync/immich-library/chunk", "x", day1)
+
+	r := c.CheckBackupExists(context.Background(), backend.NewQuery("immich-prod", "library", "immich-library"))
+	if !r.Exists || r.Source != "volsync/immich-library/" || r.Namespace != "immich-prod" || r.Pvc != "library" {
+		t.Errorf("override: %+v", r)
+	}
+	// Without the override the default identity has nothing.
+	if r := c.CheckBackupExists(context.Background(), backend.NewQuery("immich-prod", "library", "")); r.Exists {
+		t.Errorf("default identity: %+v", r)
+	}
+}
+
+func TestResticLayout_CountsSnapshots(t *testing.T) {
+	f, c := newFakeS3(t, testBucket)
+	c.layout = ResticLayout{}
+	f.pageSize = 2 // three snapshots span two ListObjectsV2 pages
+	f.put("myapp/data/config", "cfg", day1)
+	f.put("myapp/data/keys/k1", "key", day1)
+	f.put("myapp/data/data/00/0011", "pack", day2)
+	f.put("myapp/data/snapshots/aaa", "s", day1)
+	f.put("myapp/data/snapshots/bbb", "s", day2)
+	f.put("myapp/data/snapshots/ccc", "s", day1.Add(-24*time.Hour))
+
+	r := c.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "data", ""))
+	if !r.Exists || r.Decision != backend.DecisionRestore || r.Source != "myapp/data/snapshots/" {
+		t.Fatalf("restore: %+v", r)
+	}
+	if r.SnapshotCount != 3 || !r.LatestSnapshotAt.Equal(day2) || r.LatestSnapshotSize != 0 {
+		t.Errorf("summary: %+v", r)
+	}
+}
+
+// TestResticLayout_InitializedButEmpty pins that a restic repository
+// with no snapshots yet (config + keys only) is fresh.
+func TestResticLayout_InitializedButEmpty(t *testing.T) {
+	f, c := newFakeS3(t, testBucket)
+	c.layout = ResticLayout{}
+	f.put("myapp/data/config", "cfg", day1)
+	f.put("myapp/data/keys/k1", "key", day1)
+
+	r := c.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "data", ""))
+	if r.Exists || r.Decision != backend.DecisionFresh || !r.Authoritative {
+		t.Errorf("result: %+v", r)
+	}
+}
+
+func TestIndexLayout(t *testing.T) {
+	f, c := newFakeS3(t, testBucket)
+	c.layout = IndexLayout{Key: DefaultIndexKey}
+	f.put(DefaultIndexKey, `{"identities": {
+		"myapp/data": {"count": 4, "latestAt": "2026-05-21T03:00:00Z", "latestSize": 5017},
+		"immich-library": {}
+	}}`, day2)
+
+	r := c.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "data", ""))
+	if !r.Exists || r.Source != DefaultIndexKey || r.SnapshotCount != 4 || !r.LatestSnapshotAt.Equal(day2) || r.LatestSnapshotSize != 5017 {
+		t.Errorf("listed identity: %+v", r)
+	}
+	r = c.CheckBackupExists(context.Background(), backend.NewQuery("immich-prod", "library", "immich-library"))
+	if !r.Exists || r.SnapshotCount != 0 {
+		t.Errorf("listed without metadata: %+v", r)
+	}
+	r = c.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "cache", ""))
+	if r.Exists || r.Decision != backend.DecisionFresh || !r.Authoritative {
+		t.Errorf("unlisted identity: %+v", r)
+	}
+}
+
+// TestIndexLayout_MissingOrCorruptIsUnknown pins that an index the hook
+// has not written (or wrote badly) never reads as "no backups".
+func TestIndexLayout_MissingOrCorruptIsUnknown(t *testing.T) {
+	f, c := newFakeS3(t, testBucket)
+	c.layout = IndexLayout{Key: DefaultIndexKey}
+
+	r := c.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "data", ""))
+	if r.Decision != backend.DecisionUnknown || r.Authoritative || !strings.Contains(r.Error, "index layout") {
+		t.Errorf("missing: %+v", r)
+	}
+	if _, err := c.Get(context.Background(), DefaultIndexKey); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("Get missing: %v, want ErrObjectNotFound", err)
+	}
+
+	f.put(DefaultIndexKey, `{"identities": [`, day1)
+	r = c.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "data", ""))
+	if r.Decision != backend.DecisionUnknown || !strings.Contains(r.Error, "decode index object") {
+		t.Errorf("corrupt: %+v", r)
+	}
+}
+
+func TestCheckBackupExists_ListErrorIsUnknown(t *testing.T) {
+	f, c := newFakeS3(t, testBucket)
+	f.failList = true
+	r := c.CheckBackupExists(context.Background(), backend.NewQuery("myapp", "data", ""))
+	if r.Exists || r.Decision != backend.DecisionUnknown || r.Authoritative || r.Source != "myapp/data/" || r.Error == "" {
+		t.Errorf("result: %+v", r)
+	}
+}
+
+func TestHealthCheck_FakeServer(t *testing.T) {
+	_, c := newFakeS3(t, testBucket)
+	if err := c.HealthCheck(context.Background()); err != nil {
+		t.Errorf("HealthCheck: %v", err)
+	}
+	c.bucket = "missing"
+	if err := c.HealthCheck(context.Background()); err == nil {
+		t.Error("HealthCheck passed on a missing bucket")
+	}
+}
@@ -11,24 +11,10 @@ import (
 // (0, false) for tiers that have no freshness gate (disabled, manual,
 // unspecified).
 //
-// Windows are tier-relative with cadence slack:
-//
-//	hourly  → 3h   (3x cadence)
-//	daily   → 48h  (2x cadence)
-//	weekly  → 216h ≈ 9d (1.3x cadence)
-//	manual  → no window (operator-driven cadence)
-//	disabled→ no window (no backup expected)
+// The windows themselves live on labels.Tier.FreshnessWindow so /audit's
+// freshness verdict uses the same numbers.
 func freshnessWindow(tier labels.Tier) (time.Duration, bool) {
-	switch tier {
-	case labels.TierHourly:
-		return 3 * time.Hour, true
-	case labels.TierDaily:
-		return 48 * time.Hour, true
-	case labels.TierWeekly:
-		return 9 * 24 * time.Hour, true
-	default:
-		return 0, false
-	}
+	return tier.FreshnessWindow()
 }
 
 // freshnessBlockers evaluates Inputs.RequireFreshBackup against
@@ -22,6 +22,7 @@ package builder
 
 import (
 	"strconv"
+	"time"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -162,7 +163,9 @@ func BuildRS(in Inputs) *unstructured.Unstructured {
 // manual trigger that fires only when the spec.trigger.manual string
 // changes (the talos repo pins `restore-once`), plus the kopia
 // sourceIdentity needed to locate the right snapshot lineage in the
-// shared repo.
+// shared repo. A point-in-time selection (Spec.RestoreAsOf /
+// RestorePrevious) adds kopia restoreAsOf / previous and a trigger
+// keyed to it (see restoreTrigger).
 func BuildRD(in Inputs) *unstructured.Unstructured {
 	rd := &unstructured.Unstructured{}
 	rd.SetGroupVersionKind(rdGVK)
@@ -196,16 +199,58 @@ func BuildRD(in Inputs) *unstructured.Unstructured {
 		"moverSecurityContext":    moverSecurityContext(in),
 	}
 	_ = identity // already used above
+	if asOf := RestoreAsOf(in.Spec); asOf != "" {
+		kopia["restoreAsOf"] = asOf
+	}
+	if in.Spec.RestorePrevious > 0 {
+		kopia["previous"] = int64(in.Spec.RestorePrevious)
+	}
 
 	rd.Object["spec"] = map[string]interface{}{
 		"trigger": map[string]interface{}{
-			"manual": "restore-once",
+			"manual": restoreTrigger(in.Spec),
 		},
 		"kopia": kopia,
 	}
 	return rd
 }
 
+// restoreTriggerSeed is the RD's spec.trigger.manual when the PVC
+// restores the latest snapshot — the value the talos repo pins.
+const restoreTriggerSeed = "restore-once"
+
+// RestoreAsOf renders Spec.RestoreAsOf the way BuildRD writes it into
+// spec.kopia.restoreAsOf, or "" when unset. Exported so the planner's
+// drift check compares against exactly the string the builder emits.
+func RestoreAsOf(spec labels.Spec) string {
+	if spec.RestoreAsOf.IsZero() {
+		return ""
+	}
+	return spec.RestoreAsOf.UTC().Format(time.RFC3339)
+}
+
+// restoreTrigger derives the RD's manual trigger from the restore
+// selection. VolSync runs an RD once per distinct trigger value, so a
+// static trigger would leave a changed restoreAsOf / previous unused
+// until someone also bumped the trigger by hand; keying the trigger to
+// the selection makes setting (or clearing) the annotations re-run the
+// restore, and the new latestImage is what a recreated PVC populates
+// from. No selection keeps the historical `restore-once`, so existing
+// RDs are not re-triggered by this change.
+func restoreTrigger(spec labels.Spec) string {
+	if !spec.HasRestoreSelection() {
+		return restoreTriggerSeed
+	}
+	t := "restore"
+	if !spec.RestoreAsOf.IsZero() {
+		t += "-as-of-" + spec.RestoreAsOf.UTC().Format("20060102T150405Z")
+	}
+	if spec.RestorePrevious > 0 {
+		t += "-previous-" + strconv.Itoa(int(spec.RestorePrevious))
+	}
+	return t
+}
+
 // commonLabels are stamped onto both RS and RD. These are the
 // operator's identity stamp — the planner uses them to distinguish
 // operator-owned resources from inline-Argo or unmanaged ones.
@@ -3,6 +3,7 @@ package builder
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	kvalidation "k8s.io/apimachinery/pkg/util/validation"
@@ -335,6 +336,54 @@ func TestBuildRD_ManualTriggerOnly(t *testing.T) {
 	}
 }
 
+func TestBuildRD_RestoreSelection(t *testing.T) {
+	asOf := time.Date(2026, 5, 17, 3, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name        string
+		asOf        time.Time
+		previous    int32
+		wantAsOf    string
+		wantTrigger string
+	}{
+		{name: "latest", wantTrigger: "restore-once"},
+		{name: "as-of", asOf: asOf, wantAsOf: "2026-05-17T03:00:00Z", wantTrigger: "restore-as-of-20260517T030000Z"},
+		{name: "previous", previous: 1, wantTrigger: "restore-previous-1"},
+		{name: "both", asOf: asOf, previous: 2, wantAsOf: "2026-05-17T03:00:00Z", wantTrigger: "restore-as-of-20260517T030000Z-previous-2"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			in := baseInputs()
+			in.Spec.RestoreAsOf, in.Spec.RestorePrevious = tc.asOf, tc.previous
+			rd := BuildRD(in)
+
+			gotAsOf, found, _ := unstructured.NestedString(rd.Object, "spec", "kopia", "restoreAsOf")
+			if gotAsOf != tc.wantAsOf || found != (tc.wantAsOf != "") {
+				t.Errorf("restoreAsOf: got %q (found=%v), want %q", gotAsOf, found, tc.wantAsOf)
+			}
+			gotPrev, found, _ := unstructured.NestedInt64(rd.Object, "spec", "kopia", "previous")
+			if gotPrev != int64(tc.previous) || found != (tc.previous > 0) {
+				t.Errorf("previous: got %d (found=%v), want %d", gotPrev, found, tc.previous)
+			}
+			if got, _, _ := unstructured.NestedString(rd.Object, "spec", "trigger", "manual"); got != tc.wantTrigger {
+				t.Errorf("trigger.manual: got %q, want %q", got, tc.wantTrigger)
+			}
+			if RestoreAsOf(in.Spec) != tc.wantAsOf {
+				t.Errorf("RestoreAsOf: got %q, want %q", RestoreAsOf(in.Spec), tc.wantAsOf)
+			}
+		})
+	}
+
+	// The selection is RD-only: the RS keeps backing up as before.
+	in := baseInputs()
+	in.Spec.RestoreAsOf, in.Spec.RestorePrevious = asOf, 1
+	rs := BuildRS(in)
+	for _, f := range []string{"restoreAsOf", "previous"} {
+		if _, found, _ := unstructured.NestedFieldNoCopy(rs.Object, "spec", "kopia", f); found {
+			t.Errorf("RS carries kopia.%s", f)
+		}
+	}
+}
+
 func TestBuildRS_MoverSecurityContextDefaults(t *testing.T) {
 	rs := BuildRS(baseInputs())
 	m, found, err := unstructured.NestedMap(rs.Object, "spec", "kopia", "moverSecurityContext")
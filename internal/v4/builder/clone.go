@@ -0,0 +1,107 @@
+package builder
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/labels"
+)
+
+// Restore-to-clone objects. A clone restores a PVC's backup into a new
+// PVC the owner names, next to — never over — the original
+// (controller.CloneRunner). It is two objects in the target namespace:
+// an RD cloned from the source PVC's live RD, and the target PVC
+// populated from it via dataSourceRef.
+//
+// As for drills, the RD is cloned from the live object so the clone
+// reads exactly the repository and sourceIdentity the PVC's own restore
+// would. Both objects are returned unstructured: they go through
+// executor.Execute, which only takes unstructured ops.
+
+// CloneInputs names one clone.
+type CloneInputs struct {
+	// Namespace / Name are the target PVC's.
+	Namespace string
+	Name      string
+
+	// RDName is "<source pvc>-clone-<id>".
+	RDName string
+
+	// ID identifies the request (AnnotationCloneID) and keys the RD's
+	// manual trigger.
+	ID string
+
+	// SourceNamespace / SourcePVC identify the cloned PVC; recorded in
+	// the clone-source annotation.
+	SourceNamespace string
+	SourcePVC       string
+
+	// AsOf restores the newest snapshot at or before this time. Zero
+	// restores the latest.
+	AsOf time.Time
+
+	// ExpiresAt is when the clone is garbage-collected.
+	ExpiresAt time.Time
+}
+
+// cloneObjectMeta is the metadata shared by a clone's RD and PVC.
+func cloneObjectMeta(in CloneInputs, name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: in.Namespace,
+		Name:      name,
+		Labels: map[string]string{
+			labels.LabelManagedByKey: labels.LabelManagedByValue,
+			labels.LabelClone:        "true",
+		},
+		Annotations: map[string]string{
+			labels.AnnotationCloneSource:    in.SourceNamespace + "/" + in.SourcePVC,
+			labels.AnnotationCloneID:        in.ID,
+			labels.AnnotationCloneExpiresAt: in.ExpiresAt.UTC().Format(time.RFC3339),
+		},
+	}
+}
+
+// BuildCloneRD clones liveRD's spec into the clone's RD. As with
+// BuildDrillRD the trigger is replaced and destinationPVC dropped —
+// VolSync provisions the restore volume the target PVC is populated
+// from — and the source PVC's own point-in-time selection is replaced
+// by the clone's: restoreAsOf when in.AsOf is set, the latest snapshot
+// otherwise.
+func BuildCloneRD(liveRD *unstructured.Unstructured, in CloneInputs) *unstructured.Unstructured {
+	meta := cloneObjectMeta(in, in.RDName)
+	rd := &unstructured.Unstructured{}
+	rd.SetGroupVersionKind(rdGVK)
+	rd.SetNamespace(meta.Namespace)
+	rd.SetName(meta.Name)
+	rd.SetLabels(meta.Labels)
+	rd.SetAnnotations(meta.Annotations)
+
+	spec := restoreRDSpec(liveRD, "clone-"+in.ID)
+	if kopia, ok := spec["kopia"].(map[string]interface{}); ok && !in.AsOf.IsZero() {
+		kopia["restoreAsOf"] = in.AsOf.UTC().Format(time.RFC3339)
+	}
+	rd.Object["spec"] = spec
+	return rd
+}
+
+// BuildClonePVC builds the target PVC populated from cloneRD, shaped
+// like BuildDrillPVC.
+func BuildClonePVC(cloneRD *unstructured.Unstructured, in CloneInputs) (*unstructured.Unstructured, error) {
+	spec, err := restoredPVCSpec(cloneRD)
+	if err != nil {
+		return nil, err
+	}
+	pvc := &corev1.PersistentVolumeClaim{ObjectMeta: cloneObjectMeta(in, in.Name), Spec: spec}
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pvc)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: obj}
+	u.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaim"))
+	delete(u.Object, "status")
+	return u, nil
+}
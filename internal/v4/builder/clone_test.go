@@ -0,0 +1,105 @@
+package builder
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/labels"
+)
+
+func cloneInputs(asOf time.Time) CloneInputs {
+	return CloneInputs{
+		Namespace:       "scratch",
+		Name:            "storage-restore",
+		RDName:          tpvcStorage + "-clone-1a2b3c4d",
+		ID:              "1a2b3c4d",
+		SourceNamespace: tnsOpenWebUI,
+		SourcePVC:       tpvcStorage,
+		AsOf:            asOf,
+		ExpiresAt:       time.Date(2026, 5, 20, 3, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestBuildCloneRD(t *testing.T) {
+	asOf := time.Date(2026, 5, 17, 3, 0, 0, 0, time.UTC)
+	live := liveRD()
+	in := cloneInputs(asOf)
+	rd := BuildCloneRD(live, in)
+
+	if rd.GetNamespace() != "scratch" || rd.GetName() != in.RDName {
+		t.Errorf("meta: %s/%s", rd.GetNamespace(), rd.GetName())
+	}
+	lbls := rd.GetLabels()
+	if lbls[labels.LabelClone] != "true" || lbls[labels.LabelManagedByKey] != labels.LabelManagedByValue {
+		t.Errorf("labels: %v", lbls)
+	}
+	if _, ok := lbls[labels.LabelSourcePVC]; ok {
+		t.Errorf("clone RD carries %s", labels.LabelSourcePVC)
+	}
+	anns := rd.GetAnnotations()
+	if anns[labels.AnnotationCloneSource] != tnsOpenWebUI+"/"+tpvcStorage || anns[labels.AnnotationCloneID] != in.ID ||
+		anns[labels.AnnotationCloneExpiresAt] != "2026-05-20T03:00:00Z" {
+		t.Errorf("annotations: %v", anns)
+	}
+	if got, _, _ := unstructured.NestedString(rd.Object, "spec", "trigger", "manual"); got != "clone-1a2b3c4d" {
+		t.Errorf("trigger: %q", got)
+	}
+	if got, _, _ := unstructured.NestedString(rd.Object, "spec", "kopia", "restoreAsOf"); got != "2026-05-17T03:00:00Z" {
+		t.Errorf("restoreAsOf: %q", got)
+	}
+	// The source PVC's own selection (previous=1 on liveRD) is not the
+	// clone's.
+	if _, ok, _ := unstructured.NestedFieldNoCopy(rd.Object, "spec", "kopia", "previous"); ok {
+		t.Error("kopia.previous not dropped")
+	}
+	for _, f := range []string{"repository", "username", "hostname"} {
+		want, _, _ := unstructured.NestedString(live.Object, "spec", "kopia", f)
+		if got, _, _ := unstructured.NestedString(rd.Object, "spec", "kopia", f); got != want {
+			t.Errorf("kopia.%s: got %q, want %q", f, got, want)
+		}
+	}
+
+	latest := BuildCloneRD(live, cloneInputs(time.Time{}))
+	if _, ok, _ := unstructured.NestedFieldNoCopy(latest.Object, "spec", "kopia", "restoreAsOf"); ok {
+		t.Error("restoreAsOf set without an as-of")
+	}
+}
+
+// The clone PVC carries what the executor's clone rule
+// (executor.IsClonePVC) checks: both labels and an RD data source.
+func TestBuildClonePVC(t *testing.T) {
+	in := cloneInputs(time.Time{})
+	rd := BuildCloneRD(liveRD(), in)
+	pvc, err := BuildClonePVC(rd, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pvc.GroupVersionKind().Kind != "PersistentVolumeClaim" || pvc.GetLabels()[labels.LabelClone] != "true" ||
+		pvc.GetLabels()[labels.LabelManagedByKey] != labels.LabelManagedByValue {
+		t.Errorf("kind %v labels %v", pvc.GroupVersionKind(), pvc.GetLabels())
+	}
+	ref, _, _ := unstructured.NestedStringMap(pvc.Object, "spec", "dataSourceRef")
+	if ref["apiGroup"] != rdGVK.Group || ref["kind"] != rdGVK.Kind {
+		t.Errorf("dataSourceRef: %v", ref)
+	}
+	if pvc.GetNamespace() != "scratch" || pvc.GetName() != "storage-restore" {
+		t.Errorf("meta: %s/%s", pvc.GetNamespace(), pvc.GetName())
+	}
+	if got, _, _ := unstructured.NestedString(pvc.Object, "spec", "dataSourceRef", "name"); got != in.RDName {
+		t.Errorf("dataSourceRef.name: %q", got)
+	}
+	wantCap, _, _ := unstructured.NestedString(rd.Object, "spec", "kopia", "capacity")
+	if got, _, _ := unstructured.NestedString(pvc.Object, "spec", "resources", "requests", "storage"); got != wantCap {
+		t.Errorf("storage: got %q, want %q", got, wantCap)
+	}
+	if _, ok := pvc.Object["status"]; ok {
+		t.Error("status rendered")
+	}
+
+	unstructured.RemoveNestedField(rd.Object, "spec", "kopia", "capacity")
+	if _, err := BuildClonePVC(rd, in); err == nil {
+		t.Error("no error for an RD without capacity")
+	}
+}
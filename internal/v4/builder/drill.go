@@ -0,0 +1,252 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/labels"
+)
+
+// Restore drill objects. A drill restores one PVC's latest backup into
+// a throwaway PVC in a sandbox namespace and runs a verification Job
+// against it (controller.DrillRunner). The three objects built here are
+// that drill: an RD cloned from the PVC's live, operator-owned RD, a PVC
+// populated from it via dataSourceRef, and the Job that mounts the PVC.
+//
+// The RD is cloned from the live object rather than rendered from
+// Inputs on purpose: the drill should restore through exactly the
+// repository, identity and sourceIdentity a real restore of that PVC
+// would use, including any drift the reconciler has not yet repaired.
+// Rendering fresh would prove the builder works, not the backup.
+
+// DrillMountPath is where the verification Job mounts the restored PVC.
+const DrillMountPath = "/data"
+
+// DrillInputs names one drill. Name is shared by the RD, the PVC and the
+// Job and must be a valid DNS label (Job names are also pod label
+// values).
+type DrillInputs struct {
+	SandboxNamespace string
+	Name             string
+
+	// Trigger is the drill RD's spec.trigger.manual — unique per drill,
+	// so status.lastManualSync == Trigger means this drill's restore
+	// finished.
+	Trigger string
+
+	// SourceNamespace / SourcePVC identify the drilled PVC; recorded in
+	// the drill-source annotation.
+	SourceNamespace string
+	SourcePVC       string
+
+	// Verification, from the source PVC's drill annotations.
+	Verify   labels.DrillVerify
+	Manifest string
+	Probe    string
+	Image    string
+
+	// JobDeadline bounds the verification pod's runtime
+	// (activeDeadlineSeconds). Zero leaves it unbounded.
+	JobDeadline int64
+}
+
+// drillObjectMeta is the metadata shared by every drill object.
+func drillObjectMeta(in DrillInputs) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: in.SandboxNamespace,
+		Name:      in.Name,
+		Labels: map[string]string{
+			labels.LabelManagedByKey: labels.LabelManagedByValue,
+			labels.LabelDrill:        "true",
+		},
+		Annotations: map[string]string{
+			labels.AnnotationDrillSource: in.SourceNamespace + "/" + in.SourcePVC,
+		},
+	}
+}
+
+// BuildDrillRD clones liveRD's spec into a sandbox RD. The trigger is
+// replaced with in.Trigger, and spec.kopia.destinationPVC is dropped: it
+// names a PVC in the source namespace, and without it VolSync provisions
+// its own destination from accessModes/capacity, which is what the
+// drill wants. Any restoreAsOf / previous selection is dropped too, so
+// the drill always restores the latest snapshot. Everything else
+// (repository, identity, sourceIdentity, classes, mover security
+// context) is kept verbatim.
+func BuildDrillRD(liveRD *unstructured.Unstructured, in DrillInputs) *unstructured.Unstructured {
+	meta := drillObjectMeta(in)
+	rd := &unstructured.Unstructured{}
+	rd.SetGroupVersionKind(rdGVK)
+	rd.SetNamespace(meta.Namespace)
+	rd.SetName(meta.Name)
+	rd.SetLabels(meta.Labels)
+	rd.SetAnnotations(meta.Annotations)
+
+	rd.Object["spec"] = restoreRDSpec(liveRD, in.Trigger)
+	return rd
+}
+
+// restoreRDSpec deep-copies liveRD's spec for a one-off restore RD
+// (drill or clone): the manual trigger becomes trigger, and
+// destinationPVC plus any restoreAsOf / previous selection are dropped.
+func restoreRDSpec(liveRD *unstructured.Unstructured, trigger string) map[string]interface{} {
+	spec := map[string]interface{}{}
+	if live, ok := liveRD.Object["spec"].(map[string]interface{}); ok {
+		spec = runtime.DeepCopyJSON(live)
+	}
+	spec["trigger"] = map[string]interface{}{"manual": trigger}
+	if kopia, ok := spec["kopia"].(map[string]interface{}); ok {
+		delete(kopia, "destinationPVC")
+		// A point-in-time selection pins the PVC's own restore to an
+		// older snapshot; a drill's question is whether the newest
+		// backup restores, and a clone names its own point in time.
+		delete(kopia, "restoreAsOf")
+		delete(kopia, "previous")
+	}
+	return spec
+}
+
+// BuildDrillPVC builds the sandbox PVC populated from the drill RD. Size,
+// access modes and storage class come from the RD's kopia block, so the
+// restored volume has the shape a real restore would get.
+func BuildDrillPVC(drillRD *unstructured.Unstructured, in DrillInputs) (*corev1.PersistentVolumeClaim, error) {
+	spec, err := restoredPVCSpec(drillRD)
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.PersistentVolumeClaim{ObjectMeta: drillObjectMeta(in), Spec: spec}, nil
+}
+
+// restoredPVCSpec is the spec of a PVC populated from rd through
+// dataSourceRef, sized and classed from rd's kopia block.
+func restoredPVCSpec(rd *unstructured.Unstructured) (corev1.PersistentVolumeClaimSpec, error) {
+	capacity, _, _ := unstructured.NestedString(rd.Object, "spec", "kopia", "capacity")
+	if capacity == "" {
+		return corev1.PersistentVolumeClaimSpec{}, errors.New("RD has no spec.kopia.capacity")
+	}
+	qty, err := resource.ParseQuantity(capacity)
+	if err != nil {
+		return corev1.PersistentVolumeClaimSpec{}, fmt.Errorf("RD spec.kopia.capacity %q: %w", capacity, err)
+	}
+	modes, _, _ := unstructured.NestedStringSlice(rd.Object, "spec", "kopia", "accessModes")
+	if len(modes) == 0 {
+		modes = []string{accessModeRWO}
+	}
+	accessModes := make([]corev1.PersistentVolumeAccessMode, 0, len(modes))
+	for _, m := range modes {
+		accessModes = append(accessModes, corev1.PersistentVolumeAccessMode(m))
+	}
+
+	apiGroup := rdGVK.Group
+	spec := corev1.PersistentVolumeClaimSpec{
+		AccessModes: accessModes,
+		Resources: corev1.VolumeResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceStorage: qty},
+		},
+		DataSourceRef: &corev1.TypedObjectReference{
+			APIGroup: &apiGroup,
+			Kind:     rdGVK.Kind,
+			Name:     rd.GetName(),
+		},
+	}
+	if sc, _, _ := unstructured.NestedString(rd.Object, "spec", "kopia", "storageClassName"); sc != "" {
+		spec.StorageClassName = &sc
+	}
+	return spec, nil
+}
+
+// Verification scripts, run by `sh -c` in the Job. Inputs arrive as
+// environment variables rather than being spliced into the script, so a
+// manifest path or probe command needs no shell quoting here.
+const (
+	drillScriptFileCount = `n=$(find "$DRILL_MOUNT" -type f | wc -l); echo "restored files: $n"; [ "$n" -gt 0 ]`
+	drillScriptChecksum  = `cd "$DRILL_MOUNT" && sha256sum -c -- "$DRILL_MANIFEST"`
+	drillScriptProbe     = `cd "$DRILL_MOUNT" && exec sh -c "$DRILL_PROBE"`
+)
+
+// BuildDrillJob builds the verification Job. It runs once (no retries:
+// a flaky probe should fail the drill, not be retried into a pass) with
+// the RD's mover UID/GID/fsGroup, so it reads the restored files with
+// the same identity the application would. The container is locked down
+// to the restricted Pod Security profile.
+func BuildDrillJob(drillRD *unstructured.Unstructured, in DrillInputs) (*batchv1.Job, error) {
+	var script string
+	env := []corev1.EnvVar{{Name: "DRILL_MOUNT", Value: DrillMountPath}}
+	switch in.Verify {
+	case labels.DrillVerifyFileCount:
+		script = drillScriptFileCount
+	case labels.DrillVerifyChecksumManifest:
+		script = drillScriptChecksum
+		env = append(env, corev1.EnvVar{Name: "DRILL_MANIFEST", Value: in.Manifest})
+	case labels.DrillVerifyProbe:
+		script = drillScriptProbe
+		env = append(env, corev1.EnvVar{Name: "DRILL_PROBE", Value: in.Probe})
+	default:
+		return nil, fmt.Errorf("no verification for drill mode %q", in.Verify)
+	}
+	if in.Image == "" {
+		return nil, errors.New("no verification image")
+	}
+
+	podSC := &corev1.PodSecurityContext{
+		SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	}
+	if v, ok, _ := unstructured.NestedInt64(drillRD.Object, "spec", "kopia", "moverSecurityContext", "runAsUser"); ok {
+		podSC.RunAsUser = &v
+		if v != 0 {
+			nonRoot := true
+			podSC.RunAsNonRoot = &nonRoot
+		}
+	}
+	if v, ok, _ := unstructured.NestedInt64(drillRD.Object, "spec", "kopia", "moverSecurityContext", "runAsGroup"); ok {
+		podSC.RunAsGroup = &v
+	}
+	if v, ok, _ := unstructured.NestedInt64(drillRD.Object, "spec", "kopia", "moverSecurityContext", "fsGroup"); ok {
+		podSC.FSGroup = &v
+	}
+
+	noEscalation := false
+	backoff := int32(0)
+	job := &batchv1.Job{
+		ObjectMeta: drillObjectMeta(in),
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoff,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: drillObjectMeta(in).Labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy:   corev1.RestartPolicyNever,
+					SecurityContext: podSC,
+					Containers: []corev1.Container{{
+						Name:    "verify",
+						Image:   in.Image,
+						Command: []string{"sh", "-c", script},
+						Env:     env,
+						SecurityContext: &corev1.SecurityContext{
+							AllowPrivilegeEscalation: &noEscalation,
+							Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+						},
+						VolumeMounts: []corev1.VolumeMount{{Name: "restored", MountPath: DrillMountPath}},
+					}},
+					Volumes: []corev1.Volume{{
+						Name: "restored",
+						VolumeSource: corev1.VolumeSource{
+							PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: in.Name},
+						},
+					}},
+				},
+			},
+		},
+	}
+	if in.JobDeadline > 0 {
+		deadline := in.JobDeadline
+		job.Spec.ActiveDeadlineSeconds = &deadline
+	}
+	return job, nil
+}
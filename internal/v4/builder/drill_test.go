@@ -0,0 +1,154 @@
+package builder
+
+import (
+	"slices"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/labels"
+)
+
+func drillInputs(verify labels.DrillVerify) DrillInputs {
+	return DrillInputs{
+		SandboxNamespace: "pvc-plumber-drill",
+		Name:             "drill-1a2b3c4d-t0",
+		Trigger:          "drill-1700000000",
+		SourceNamespace:  tnsOpenWebUI,
+		SourcePVC:        tpvcStorage,
+		Verify:           verify,
+		Image:            "busybox:1.37",
+		JobDeadline:      600,
+	}
+}
+
+// liveRD is BuildRD's output as the apiserver returns it: status set, a
+// destinationPVC someone added by hand, a point-in-time selection.
+func liveRD() *unstructured.Unstructured {
+	in := baseInputs()
+	in.Spec.RestorePrevious = 1
+	rd := BuildRD(in)
+	_ = unstructured.SetNestedField(rd.Object, "storage", "spec", "kopia", "destinationPVC")
+	_ = unstructured.SetNestedField(rd.Object, "restore-once", "status", "lastManualSync")
+	rd.SetResourceVersion("42")
+	return rd
+}
+
+func TestBuildDrillRD_ClonesLiveSpecIntoSandbox(t *testing.T) {
+	live := liveRD()
+	in := drillInputs(labels.DrillVerifyFileCount)
+	rd := BuildDrillRD(live, in)
+
+	if rd.GetNamespace() != in.SandboxNamespace || rd.GetName() != in.Name || rd.GetResourceVersion() != "" {
+		t.Errorf("meta: %s/%s rv=%q", rd.GetNamespace(), rd.GetName(), rd.GetResourceVersion())
+	}
+	lbls := rd.GetLabels()
+	if lbls[labels.LabelDrill] != "true" || lbls[labels.LabelManagedByKey] != labels.LabelManagedByValue {
+		t.Errorf("labels: %v", lbls)
+	}
+	// Drill objects must not look like a PVC's real children.
+	if _, ok := lbls[labels.LabelSourcePVC]; ok {
+		t.Errorf("drill RD carries %s", labels.LabelSourcePVC)
+	}
+	if got := rd.GetAnnotations()[labels.AnnotationDrillSource]; got != tnsOpenWebUI+"/"+tpvcStorage {
+		t.Errorf("drill-source: %q", got)
+	}
+	if got, _, _ := unstructured.NestedString(rd.Object, "spec", "trigger", "manual"); got != in.Trigger {
+		t.Errorf("trigger: %q", got)
+	}
+	if _, ok, _ := unstructured.NestedString(rd.Object, "spec", "kopia", "destinationPVC"); ok {
+		t.Error("destinationPVC not dropped")
+	}
+	for _, f := range []string{"restoreAsOf", "previous"} {
+		if _, ok, _ := unstructured.NestedFieldNoCopy(rd.Object, "spec", "kopia", f); ok {
+			t.Errorf("point-in-time kopia.%s not dropped", f)
+		}
+	}
+	if _, ok := rd.Object["status"]; ok {
+		t.Error("status copied")
+	}
+	for _, f := range []string{"repository", "username", "hostname"} {
+		want, _, _ := unstructured.NestedString(live.Object, "spec", "kopia", f)
+		if got, _, _ := unstructured.NestedString(rd.Object, "spec", "kopia", f); got != want {
+			t.Errorf("kopia.%s: got %q, want %q", f, got, want)
+		}
+	}
+	// The clone is deep: editing it leaves the live object alone.
+	_ = unstructured.SetNestedField(rd.Object, "other", "spec", "kopia", "repository")
+	if got, _, _ := unstructured.NestedString(live.Object, "spec", "kopia", "repository"); got != tshareRepo {
+		t.Errorf("live RD mutated: %q", got)
+	}
+	if got, _, _ := unstructured.NestedString(live.Object, "spec", "kopia", "destinationPVC"); got != "storage" {
+		t.Errorf("live RD destinationPVC mutated: %q", got)
+	}
+}
+
+func TestBuildDrillPVC(t *testing.T) {
+	in := drillInputs(labels.DrillVerifyFileCount)
+	rd := BuildDrillRD(liveRD(), in)
+	pvc, err := BuildDrillPVC(rd, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := pvc.Spec.DataSourceRef
+	if ref == nil || ref.APIGroup == nil || *ref.APIGroup != "volsync.backube" || ref.Kind != "ReplicationDestination" || ref.Name != in.Name {
+		t.Errorf("dataSourceRef: %+v", ref)
+	}
+	if q := pvc.Spec.Resources.Requests.Storage(); q.String() != tcap10Gi {
+		t.Errorf("capacity: %s", q)
+	}
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName != tscLonghorn {
+		t.Errorf("storage class: %v", pvc.Spec.StorageClassName)
+	}
+
+	unstructured.RemoveNestedField(rd.Object, "spec", "kopia", "capacity")
+	if _, err := BuildDrillPVC(rd, in); err == nil {
+		t.Error("RD without capacity accepted")
+	}
+}
+
+func TestBuildDrillJob(t *testing.T) {
+	rd := BuildDrillRD(liveRD(), drillInputs(labels.DrillVerifyFileCount))
+	cases := []struct {
+		verify labels.DrillVerify
+		env    string
+	}{
+		{labels.DrillVerifyFileCount, ""},
+		{labels.DrillVerifyChecksumManifest, "DRILL_MANIFEST"},
+		{labels.DrillVerifyProbe, "DRILL_PROBE"},
+	}
+	for _, tc := range cases {
+		in := drillInputs(tc.verify)
+		in.Manifest, in.Probe = "SHA256SUMS", "test -s db.sqlite3"
+		job, err := BuildDrillJob(rd, in)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.verify, err)
+		}
+		pod := job.Spec.Template.Spec
+		if *job.Spec.BackoffLimit != 0 || *job.Spec.ActiveDeadlineSeconds != 600 {
+			t.Errorf("%s: backoff/deadline %d/%d", tc.verify, *job.Spec.BackoffLimit, *job.Spec.ActiveDeadlineSeconds)
+		}
+		if pod.Volumes[0].PersistentVolumeClaim.ClaimName != in.Name {
+			t.Errorf("%s: mounts %q", tc.verify, pod.Volumes[0].PersistentVolumeClaim.ClaimName)
+		}
+		if sc := pod.SecurityContext; *sc.RunAsUser != 568 || *sc.FSGroup != 568 || !*sc.RunAsNonRoot {
+			t.Errorf("%s: pod security context %+v", tc.verify, sc)
+		}
+		var names []string
+		for _, e := range pod.Containers[0].Env {
+			names = append(names, e.Name)
+		}
+		if tc.env != "" && !slices.Contains(names, tc.env) {
+			t.Errorf("%s: env %v lacks %s", tc.verify, names, tc.env)
+		}
+	}
+
+	if _, err := BuildDrillJob(rd, drillInputs(labels.DrillVerifyNone)); err == nil {
+		t.Error("none built a Job")
+	}
+	noImage := drillInputs(labels.DrillVerifyFileCount)
+	noImage.Image = ""
+	if _, err := BuildDrillJob(rd, noImage); err == nil {
+		t.Error("empty image accepted")
+	}
+}
@@ -303,7 +303,7 @@ func Decide(in Input) Output {
 			out.Mutate = false
 			out.DataSourceRef = nil
 			out.ReasonCode = ReasonDeniedDuplicateIdentityStrict
-			out.Message = fmt.Sprintf("strict mode: backup identity %q already in use by %s/%s",
+			out.Message = fmt.Sprintf("strict mode: kopia lineage %q already in use by %s/%s",
 				dup.Identity, dup.Namespace, dup.PVCName)
 			out.Severity = SeverityError
 			return applyAuditOverride(in, out)
@@ -311,7 +311,7 @@ func Decide(in Input) Output {
 		out.Events = append(out.Events, Event{
 			Reason:  "DuplicateBackupIdentity",
 			Type:    eventTypeWarning,
-			Message: fmt.Sprintf("backup identity %q is also in use by %s/%s", dup.Identity, dup.Namespace, dup.PVCName),
+			Message: fmt.Sprintf("kopia lineage %q is also in use by %s/%s", dup.Identity, dup.Namespace, dup.PVCName),
 		})
 		out.MetricsIncrement = append(out.MetricsIncrement, "pvc_plumber_duplicate_identity_total")
 	}
@@ -323,26 +323,59 @@ func Decide(in Input) Output {
 // If the PVC has set pvc-plumber.io/backup-identity, that opaque value is
 // used; otherwise the default <namespace>/<pvc> is constructed.
 func resolveIdentity(in Input) string {
-	if in.LabelSpec.BackupIdentity != "" {
-		return in.LabelSpec.BackupIdentity
+	return IdentityFor(in.Namespace, in.PVCName, in.LabelSpec.BackupIdentity)
+}
+
+// IdentityFor is the identity-resolution rule shared by Decide, the v4
+// planner, and /audit's backup_identity: the explicit
+// pvc-plumber.io/backup-identity override when set, otherwise the
+// default <namespace>/<pvc>. It names the identity; it is not what
+// collisions are detected on (see LineageFor).
+func IdentityFor(namespace, pvcName, override string) string {
+	if override != "" {
+		return override
 	}
-	return in.Namespace + "/" + in.PVCName
+	return namespace + "/" + pvcName
+}
+
+// LineageFor is the collision key shared by Decide, the v4 planner, and
+// the reconciler's identity index: the kopia source the PVC's RS writes
+// to ("<username>@<hostname>:/data", see naming.KopiaIdentity.Lineage).
+// Comparing IdentityFor strings instead gets both directions wrong. The
+// override "b" on ns/x writes b@ns, the same lineage as the default
+// ns/b, yet the strings differ. The override "shared" on ns1/a and on
+// ns2/b writes shared@ns1 and shared@ns2, separate lineages, yet the
+// strings match.
+func LineageFor(namespace, pvcName, override string) string {
+	return naming.IdentityFor(namespace, pvcName, override).Lineage(namespace)
 }
 
 // findDuplicate returns the first matching IdentityRef from
-// in.KnownIdentities whose Identity matches the current PVC's resolved
-// identity. Self-references (same namespace+pvc) are skipped.
+// in.KnownIdentities whose Identity matches the current PVC's lineage
+// (LineageFor). Self-references (same namespace+pvc) are skipped.
 func findDuplicate(in Input) *IdentityRef {
-	id := resolveIdentity(in)
-	for i, candidate := range in.KnownIdentities {
-		if candidate.Namespace == in.Namespace && candidate.PVCName == in.PVCName {
+	lineage := LineageFor(in.Namespace, in.PVCName, in.LabelSpec.BackupIdentity)
+	if dups := DuplicateIdentities(in.Namespace, in.PVCName, lineage, in.KnownIdentities); len(dups) > 0 {
+		return &dups[0]
+	}
+	return nil
+}
+
+// DuplicateIdentities returns every ref in known, other than the PVC
+// itself, whose Identity equals identity — in input order. Exported so
+// the v4 planner applies the same collision rule as Decide without
+// building a full Input.
+func DuplicateIdentities(namespace, pvcName, identity string, known []IdentityRef) []IdentityRef {
+	var out []IdentityRef
+	for _, candidate := range known {
+		if candidate.Namespace == namespace && candidate.PVCName == pvcName {
 			continue
 		}
-		if candidate.Identity == id {
-			return &in.KnownIdentities[i]
+		if candidate.Identity == identity {
+			out = append(out, candidate)
 		}
 	}
-	return nil
+	return out
 }
 
 // applyAuditOverride converts a deny verdict into an audit "would-deny"
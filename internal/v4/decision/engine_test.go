@@ -14,7 +14,7 @@ import (
 const (
 	testPVCData      = "data"
 	testPVCDataDst   = "data-dst"
-	testIdMyappData  = "myapp/data"
+	testIdMyappData  = "data@myapp:/data" // LineageFor("myapp", "data", "")
 	testPVCLibrary   = "library"
 	testPVCLibraryBk = "library-backup"
 	testEvBackupUnk  = "BackupStateUnknown"
@@ -153,7 +153,7 @@ func TestFailureMatrix(t *testing.T) {
 			in: func() Input {
 				i := baseInput(optedIn(labels.TierDaily), resolved(mode.Strict, mode.RestoreStrict), BackupExists, CacheFresh)
 				i.KnownIdentities = []IdentityRef{
-					{Namespace: "other-app", PVCName: "data", Identity: testIdMyappData},
+					{Namespace: "myapp", PVCName: "data-old", Identity: testIdMyappData},
 				}
 				return i
 			}(),
@@ -166,7 +166,7 @@ func TestFailureMatrix(t *testing.T) {
 			in: func() Input {
 				i := baseInput(optedIn(labels.TierDaily), resolved(mode.Permissive, mode.RestorePermissive), BackupExists, CacheFresh)
 				i.KnownIdentities = []IdentityRef{
-					{Namespace: "other-app", PVCName: "data", Identity: testIdMyappData},
+					{Namespace: "myapp", PVCName: "data-old", Identity: testIdMyappData},
 				}
 				return i
 			}(),
@@ -357,7 +357,7 @@ func TestFailureMatrix(t *testing.T) {
 				i := baseInput(spec, resolved(mode.Strict, mode.RestoreStrict), BackupExists, CacheFresh)
 				// Sibling PVC in same ns has a DIFFERENT identity.
 				i.KnownIdentities = []IdentityRef{
-					{Namespace: "myapp", PVCName: "config", Identity: "myapp/config"},
+					{Namespace: "myapp", PVCName: "config", Identity: "config@myapp:/data"},
 				}
 				return i
 			}(),
@@ -530,3 +530,45 @@ func TestDecide_SelfReferenceNotDuplicate(t *testing.T) {
 		t.Errorf("self-reference falsely triggered duplicate-identity deny")
 	}
 }
+
+func TestDuplicateIdentities_AllMatchesExceptSelf(t *testing.T) {
+	known := []IdentityRef{
+		{Namespace: "myapp", PVCName: "data", Identity: "shared"},
+		{Namespace: "b", PVCName: "one", Identity: "shared"},
+		{Namespace: "c", PVCName: "two", Identity: "c/two"},
+		{Namespace: "d", PVCName: "three", Identity: "shared"},
+	}
+	got := DuplicateIdentities("myapp", "data", "shared", known)
+	if len(got) != 2 || got[0].PVCName != "one" || got[1].PVCName != "three" {
+		t.Errorf("got %+v, want b/one and d/three", got)
+	}
+	if IdentityFor("ns", "pvc", "") != "ns/pvc" || IdentityFor("ns", "pvc", "x") != "x" {
+		t.Error("IdentityFor: override / default resolution")
+	}
+}
+
+// Collisions are decided on the kopia source the RS writes, which an
+// IdentityFor string comparison gets wrong in both directions.
+func TestLineageFor_MatchesWhatTheRSWrites(t *testing.T) {
+	cases := []struct {
+		name         string
+		a, b         [3]string // namespace, pvc, override
+		wantCollided bool
+	}{
+		{"override equals another PVC's name", [3]string{"ns", "x", "b"}, [3]string{"ns", "b", ""}, true},
+		{"same override, same namespace", [3]string{"ns", "x", "shared"}, [3]string{"ns", "y", "shared"}, true},
+		{"same override, different namespaces", [3]string{"ns1", "a", "shared"}, [3]string{"ns2", "b", "shared"}, false},
+		{"override spelled like another default key", [3]string{"ns", "x", "ns/b"}, [3]string{"ns", "b", ""}, false},
+		{"same pvc name, different namespaces", [3]string{"ns1", "data", ""}, [3]string{"ns2", "data", ""}, false},
+	}
+	for _, tc := range cases {
+		a := LineageFor(tc.a[0], tc.a[1], tc.a[2])
+		b := LineageFor(tc.b[0], tc.b[1], tc.b[2])
+		if (a == b) != tc.wantCollided {
+			t.Errorf("%s: %q vs %q, want collided=%t", tc.name, a, b, tc.wantCollided)
+		}
+	}
+	if got := LineageFor("ns", "x", "b"); got != "b@ns:/data" {
+		t.Errorf("LineageFor(ns, x, b) = %q", got)
+	}
+}
@@ -68,7 +68,9 @@ func (c CacheFreshness) String() string {
 type IdentityRef struct {
 	Namespace string
 	PVCName   string
-	Identity  string
+	// Identity is the PVC's kopia lineage (LineageFor), not its
+	// IdentityFor label.
+	Identity string
 }
 
 // Config carries the operator-level knobs the decision engine needs.
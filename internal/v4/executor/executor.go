@@ -22,7 +22,11 @@
 //  1. GVK allow-list. The op's resource MUST be a VolSync RS or RD.
 //     Anything else — Secret, ExternalSecret, PVC, webhook config, SA,
 //     Pod — is Refused with reason "forbidden-kind". Defense against a
-//     future planner bug or compromised input.
+//     future planner bug or compromised input. The single exception is
+//     the PVC of a restore-to-clone, which may be created and deleted
+//     (never updated) only when it carries the clone label and is
+//     populated from an RD; see AllowedClonePVCGVK. Every other PVC is
+//     still "forbidden-kind".
 //
 //  2. Ownership re-check on Update/Delete. The executor reads the live
 //     resource and verifies app.kubernetes.io/managed-by=pvc-plumber.
@@ -62,6 +66,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/mitchross/pvc-plumber/internal/v4/labels"
 	"github.com/mitchross/pvc-plumber/internal/v4/mode"
 	"github.com/mitchross/pvc-plumber/internal/v4/planner"
 )
@@ -86,7 +91,9 @@ const (
 
 	// OpRefused: the executor's safety rails rejected the op before
 	// (or instead of) calling the apiserver. Reasons:
-	//   - "forbidden-kind"  — GVK is not RS/RD
+	//   - "forbidden-kind"  — GVK is not RS/RD, or a PVC that is not a
+	//                         restore-to-clone PVC
+	//   - "clone-immutable" — Update of a clone PVC
 	//   - "exists"          — Create returned AlreadyExists; no adoption
 	//   - "not-owned"       — Update/Delete live resource has wrong managed-by
 	//   - "absent"          — Update target doesn't exist
@@ -127,6 +134,23 @@ type OpOutcome struct {
 	// Err carries the apiserver error for OpFailed outcomes. Nil
 	// otherwise.
 	Err error
+
+	// BeforeResourceVersion is the live resourceVersion the executor
+	// observed before writing (Update/Delete). Empty for Create and for
+	// ops that never read live state.
+	BeforeResourceVersion string
+
+	// AfterResourceVersion is the resourceVersion the apiserver returned
+	// for a successful Create/Update. Empty for Delete (the object is
+	// gone) and for every non-succeeded outcome.
+	AfterResourceVersion string
+
+	// Object is the body the op acted on: the desired object sent on
+	// Create/Update, or the live object removed on Delete. nil when the
+	// op never got that far (audit skip, nil-resource, forbidden-kind,
+	// absent). The write journal hashes its spec and keeps it so a
+	// deleted RS/RD can be replayed; it is never surfaced in /audit.
+	Object *unstructured.Unstructured
 }
 
 // Counts aggregates per-status totals across all ops Execute considered.
@@ -219,7 +243,13 @@ func executeOne(ctx context.Context, c client.Client, op planner.PlannedOp) OpOu
 	// if the planner ever emits a Secret/PVC/webhook op (bug or
 	// compromise), the executor refuses before reaching the apiserver.
 	gvk := op.Resource.GroupVersionKind()
-	if !IsAllowedGVK(gvk) {
+	switch {
+	case IsAllowedGVK(gvk):
+	case gvk == AllowedClonePVCGVK:
+		if reason := clonePVCRefusal(op); reason != "" {
+			return makeOutcome(op, OpRefused, reason, nil)
+		}
+	default:
 		return makeOutcome(op, OpRefused, "forbidden-kind", nil)
 	}
 
@@ -235,6 +265,25 @@ func executeOne(ctx context.Context, c client.Client, op planner.PlannedOp) OpOu
 	}
 }
 
+// clonePVCRefusal applies the clone-PVC rule (AllowedClonePVCGVK) to the
+// op as planned; execDelete re-checks the live object. Returns "" when
+// the op may proceed.
+func clonePVCRefusal(op planner.PlannedOp) string {
+	switch op.Kind {
+	case planner.OpCreate:
+		if !IsClonePVC(op.Resource) {
+			return "forbidden-kind"
+		}
+	case planner.OpUpdate:
+		return "clone-immutable"
+	case planner.OpDelete:
+		if op.Resource.GetLabels()[labels.LabelClone] != "true" {
+			return "forbidden-kind"
+		}
+	}
+	return ""
+}
+
 // execCreate attempts to create a fresh RS/RD. The planner's contract
 // (rule 6c) only emits a Create when the live state for that resource
 // is absent, but races can happen between plan and execute — Argo could
@@ -246,12 +295,17 @@ func execCreate(ctx context.Context, c client.Client, op planner.PlannedOp) OpOu
 	desired := op.Resource.DeepCopy()
 	err := c.Create(ctx, desired)
 	if err == nil {
-		return makeOutcome(op, OpSucceeded, "", nil)
+		out := makeOutcome(op, OpSucceeded, "", nil)
+		out.AfterResourceVersion = desired.GetResourceVersion()
+		out.Object = desired
+		return out
 	}
 	if apierrors.IsAlreadyExists(err) {
 		return makeOutcome(op, OpRefused, "exists", nil)
 	}
-	return makeOutcome(op, OpFailed, "create-failed", err)
+	out := makeOutcome(op, OpFailed, "create-failed", err)
+	out.Object = desired
+	return out
 }
 
 // execUpdate read-then-overwrites the live resource with the planner's
@@ -271,7 +325,9 @@ func execUpdate(ctx context.Context, c client.Client, op planner.PlannedOp) OpOu
 	}
 
 	if !IsOperatorOwned(live) {
-		return makeOutcome(op, OpRefused, "not-owned", nil)
+		out := makeOutcome(op, OpRefused, "not-owned", nil)
+		out.BeforeResourceVersion = live.GetResourceVersion()
+		return out
 	}
 
 	// Read-then-overwrite: preserve the live resourceVersion + UID
@@ -281,10 +337,18 @@ func execUpdate(ctx context.Context, c client.Client, op planner.PlannedOp) OpOu
 	desired.SetResourceVersion(live.GetResourceVersion())
 	desired.SetUID(live.GetUID())
 
+	before := live.GetResourceVersion()
 	if err := c.Update(ctx, desired); err != nil {
-		return makeOutcome(op, OpFailed, "update-failed", err)
+		out := makeOutcome(op, OpFailed, "update-failed", err)
+		out.BeforeResourceVersion = before
+		out.Object = desired
+		return out
 	}
-	return makeOutcome(op, OpSucceeded, "", nil)
+	out := makeOutcome(op, OpSucceeded, "", nil)
+	out.BeforeResourceVersion = before
+	out.AfterResourceVersion = desired.GetResourceVersion()
+	out.Object = desired
+	return out
 }
 
 // execDelete removes operator-owned RS/RD when the planner emits a
@@ -303,18 +367,30 @@ func execDelete(ctx context.Context, c client.Client, op planner.PlannedOp) OpOu
 		return makeOutcome(op, OpFailed, "get-failed", err)
 	}
 
-	if !IsOperatorOwned(live) {
-		return makeOutcome(op, OpRefused, "not-owned", nil)
+	if !IsOperatorOwned(live) || (live.GroupVersionKind() == AllowedClonePVCGVK && !IsClonePVC(live)) {
+		out := makeOutcome(op, OpRefused, "not-owned", nil)
+		out.BeforeResourceVersion = live.GetResourceVersion()
+		return out
 	}
 
+	// The live body (not the planner's stub) is what the write journal
+	// keeps for a Delete: it is the last spec the cluster actually ran,
+	// and therefore the one a later replay should recreate.
+	before := live.GetResourceVersion()
+	var out OpOutcome
 	if err := c.Delete(ctx, live); err != nil {
 		if apierrors.IsNotFound(err) {
 			// Raced with another deletion — still success.
-			return makeOutcome(op, OpSucceeded, "already-gone", nil)
+			out = makeOutcome(op, OpSucceeded, "already-gone", nil)
+		} else {
+			out = makeOutcome(op, OpFailed, "delete-failed", err)
 		}
-		return makeOutcome(op, OpFailed, "delete-failed", err)
+	} else {
+		out = makeOutcome(op, OpSucceeded, "", nil)
 	}
-	return makeOutcome(op, OpSucceeded, "", nil)
+	out.BeforeResourceVersion = before
+	out.Object = live
+	return out
 }
 
 // makeOutcome packages a single op + status into an OpOutcome. Safe to
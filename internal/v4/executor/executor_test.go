@@ -584,6 +584,90 @@ func TestExecute_Permissive_ForeignKindWebhook_RefusedForbidden(t *testing.T) {
 	}
 }
 
+// ---- Restore-to-clone PVCs --------------------------------------------------
+
+// clonePVC returns the unstructured target PVC of a restore-to-clone:
+// operator-owned, clone-labeled, populated from the named RD.
+func clonePVC(name, rd string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(executor.AllowedClonePVCGVK)
+	u.SetNamespace(tns)
+	u.SetName(name)
+	u.SetLabels(map[string]string{
+		v4labels.LabelManagedByKey: managedByPVCPlumber,
+		v4labels.LabelClone:        "true",
+	})
+	_ = unstructured.SetNestedStringSlice(u.Object, []string{"ReadWriteOnce"}, "spec", "accessModes")
+	_ = unstructured.SetNestedField(u.Object, "1Gi", "spec", "resources", "requests", "storage")
+	_ = unstructured.SetNestedStringMap(u.Object, map[string]string{
+		"apiGroup": groupVolSync, "kind": "ReplicationDestination", "name": rd,
+	}, "spec", "dataSourceRef")
+	return u
+}
+
+// A clone PVC is the one PVC the executor writes: create and delete go
+// through, update is refused, and the writes that reach the client are
+// exactly the clone's.
+func TestExecute_Permissive_ClonePVC_CreateAndDelete(t *testing.T) {
+	rc, fc := newRecordingClient(t)
+	ctx := context.Background()
+
+	res := executor.Execute(ctx, rc, mode.Permissive, planCreate(clonePVC("data-restore", "data-clone-1")))
+	assertCounts(t, res.Counts, 0, 1, 0, 0)
+
+	res = executor.Execute(ctx, rc, mode.Permissive, planUpdate(clonePVC("data-restore", "data-clone-2")))
+	assertOutcomeStatus(t, res.Attempted[0], executor.OpRefused, "clone-immutable")
+
+	res = executor.Execute(ctx, rc, mode.Permissive, planDelete(clonePVC("data-restore", "data-clone-1")))
+	assertCounts(t, res.Counts, 0, 1, 0, 0)
+	if err := fc.Get(ctx, client.ObjectKey{Namespace: tns, Name: "data-restore"}, &corev1.PersistentVolumeClaim{}); err == nil {
+		t.Error("clone PVC still present after delete")
+	}
+	if len(rc.actions) != 2 || rc.actions[0].Verb != "create" || rc.actions[1].Verb != "delete" {
+		t.Errorf("actions: %+v", rc.actions)
+	}
+}
+
+// Anything short of a full clone PVC stays forbidden-kind, and a delete
+// whose target turns out not to be a clone (a drill PVC, an app PVC a
+// stale op was aimed at) is refused on the live object.
+func TestExecute_Permissive_ClonePVC_Refusals(t *testing.T) {
+	noSource := clonePVC("no-source", "x")
+	unstructured.RemoveNestedField(noSource.Object, "spec", "dataSourceRef")
+	notClone := clonePVC("not-clone", "x")
+	notClone.SetLabels(map[string]string{v4labels.LabelManagedByKey: managedByPVCPlumber})
+	argo := clonePVC("argo", "x")
+	argo.SetLabels(map[string]string{v4labels.LabelManagedByKey: managedByArgoCD, v4labels.LabelClone: "true"})
+
+	rc, fc := newRecordingClient(t, &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Namespace: tns, Name: "app-data",
+		Labels: map[string]string{v4labels.LabelManagedByKey: managedByPVCPlumber, v4labels.LabelDrill: "true"},
+	}})
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name   string
+		plan   planner.Plan
+		reason string
+	}{
+		{"create without dataSourceRef", planCreate(noSource), reasonForbiddenKind},
+		{"create without clone label", planCreate(notClone), reasonForbiddenKind},
+		{"create not operator-owned", planCreate(argo), reasonForbiddenKind},
+		{"delete op without clone label", planDelete(notClone), reasonForbiddenKind},
+		{"delete of a live non-clone PVC", planDelete(clonePVC("app-data", "x")), reasonNotOwned},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			res := executor.Execute(ctx, rc, mode.Permissive, tc.plan)
+			assertOutcomeStatus(t, res.Attempted[0], executor.OpRefused, tc.reason)
+		})
+	}
+	if len(rc.actions) != 0 {
+		t.Errorf("client touched on refused clone PVC ops: %+v", rc.actions)
+	}
+	if err := fc.Get(ctx, client.ObjectKey{Namespace: tns, Name: "app-data"}, &corev1.PersistentVolumeClaim{}); err != nil {
+		t.Errorf("non-clone PVC gone: %v", err)
+	}
+}
+
 // ---- Idempotency (Case 15) -------------------------------------------------
 
 // Case 15: run Execute with 2 create ops against empty cluster → both
@@ -758,6 +842,72 @@ func TestExecute_Permissive_UnknownOpKind_Refused(t *testing.T) {
 // safety.go unit tests
 // =============================================================================
 
+// ---- Journal fields (resourceVersion + applied body) ----------------------
+
+// The write journal reconstructs history from OpOutcome alone, so every
+// succeeded write must carry the resourceVersion transition and the body
+// it acted on. Delete keeps the LIVE body (not the planner stub) because
+// that is what a replay should recreate.
+func TestExecute_Permissive_OutcomesCarryResourceVersionsAndBody(t *testing.T) {
+	liveRS := rsLive(tpvcName, managedByPVCPlumber, tdriftRepo)
+	liveRD := rdLive(tdstName, managedByPVCPlumber, tgoodRepo)
+	rc, fc := newRecordingClient(t, liveRS, liveRD)
+
+	// Read the seeded versions back so the assertions don't depend on
+	// the fake client's numbering scheme.
+	seeded := &unstructured.Unstructured{}
+	seeded.SetGroupVersionKind(rsGVK)
+	if err := fc.Get(context.Background(), client.ObjectKey{Namespace: tns, Name: tpvcName}, seeded); err != nil {
+		t.Fatalf("seed get: %v", err)
+	}
+	seededRV := seeded.GetResourceVersion()
+
+	res := executor.Execute(context.Background(), rc, mode.Permissive, planUpdate(rsDesired(tpvcName, tgoodRepo)))
+	upd := res.Attempted[0]
+	assertOutcomeStatus(t, upd, executor.OpSucceeded, "")
+	if upd.BeforeResourceVersion != seededRV {
+		t.Errorf("update BeforeResourceVersion: got %q, want %q", upd.BeforeResourceVersion, seededRV)
+	}
+	if upd.AfterResourceVersion == "" || upd.AfterResourceVersion == seededRV {
+		t.Errorf("update AfterResourceVersion: got %q, want a new version (before=%q)", upd.AfterResourceVersion, seededRV)
+	}
+	if upd.Object == nil {
+		t.Fatal("update outcome has nil Object")
+	}
+
+	res = executor.Execute(context.Background(), rc, mode.Permissive, planDelete(rdDesired(tdstName, "planner-stub")))
+	del := res.Attempted[0]
+	assertOutcomeStatus(t, del, executor.OpSucceeded, "")
+	if del.BeforeResourceVersion == "" || del.AfterResourceVersion != "" {
+		t.Errorf("delete RVs: before=%q after=%q, want non-empty/empty", del.BeforeResourceVersion, del.AfterResourceVersion)
+	}
+	if del.Object == nil {
+		t.Fatal("delete outcome has nil Object")
+	}
+	gotRepo, _, _ := unstructured.NestedString(del.Object.Object, "spec", "kopia", "repository")
+	if gotRepo != tgoodRepo {
+		t.Errorf("delete Object must be the live body: repo got %q, want %q", gotRepo, tgoodRepo)
+	}
+
+	res = executor.Execute(context.Background(), rc, mode.Permissive, planCreate(rdDesired(tdstName, tgoodRepo)))
+	cr := res.Attempted[0]
+	assertOutcomeStatus(t, cr, executor.OpSucceeded, "")
+	if cr.BeforeResourceVersion != "" || cr.AfterResourceVersion == "" {
+		t.Errorf("create RVs: before=%q after=%q, want empty/non-empty", cr.BeforeResourceVersion, cr.AfterResourceVersion)
+	}
+}
+
+// Audit-mode skips never touch the apiserver, so they carry no body and
+// no versions — the journal relies on this to ignore them.
+func TestExecute_Audit_OutcomesCarryNoBody(t *testing.T) {
+	rc, _ := newRecordingClient(t)
+	res := executor.Execute(context.Background(), rc, mode.Audit, planCreate(rsDesired(tpvcName, tgoodRepo)))
+	out := res.Attempted[0]
+	if out.Object != nil || out.BeforeResourceVersion != "" || out.AfterResourceVersion != "" {
+		t.Errorf("audit skip carried journal fields: %+v", out)
+	}
+}
+
 func TestIsAllowedGVK(t *testing.T) {
 	cases := []struct {
 		gvk  schema.GroupVersionKind
@@ -779,6 +929,33 @@ func TestIsAllowedGVK(t *testing.T) {
 	}
 }
 
+func TestIsClonePVC(t *testing.T) {
+	wrongKind := clonePVC("x", "rd")
+	_ = unstructured.SetNestedField(wrongKind.Object, "VolumeSnapshot", "spec", "dataSourceRef", "kind")
+	rd := rdDesired("x", tgoodRepo)
+	rd.SetLabels(clonePVC("x", "rd").GetLabels())
+	cases := []struct {
+		name string
+		obj  *unstructured.Unstructured
+		want bool
+	}{
+		{"nil", nil, false},
+		{"clone", clonePVC("x", "rd"), true},
+		{"snapshot data source", wrongKind, false},
+		{"clone-labeled RD", rd, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := executor.IsClonePVC(c.obj); got != c.want {
+				t.Errorf("IsClonePVC: got %v, want %v", got, c.want)
+			}
+		})
+	}
+	if executor.IsAllowedGVK(executor.AllowedClonePVCGVK) {
+		t.Error("the clone PVC GVK must not be in the general allow-list")
+	}
+}
+
 func TestIsOperatorOwned(t *testing.T) {
 	cases := []struct {
 		name string
@@ -7,11 +7,13 @@ import (
 	"github.com/mitchross/pvc-plumber/internal/v4/labels"
 )
 
-// Hard allow-list. The executor will mutate exactly two kinds, ever:
-// VolSync ReplicationSource and ReplicationDestination. Anything else
-// — Secrets, ExternalSecrets, PVCs, webhook configurations, ServiceAccounts,
-// Pods — is refused at the executor boundary, even if the planner is
-// buggy or compromised and produces an op carrying a foreign GVK.
+// Hard allow-list. The executor will mutate VolSync ReplicationSource
+// and ReplicationDestination, and — under a much narrower rule, see
+// AllowedClonePVCGVK — the PVC of a restore-to-clone. Anything else —
+// Secrets, ExternalSecrets, application PVCs, webhook configurations,
+// ServiceAccounts, Pods — is refused at the executor boundary, even if
+// the planner is buggy or compromised and produces an op carrying a
+// foreign GVK.
 //
 // The GVK constants are intentionally re-declared here instead of imported
 // from internal/v4/planner. Each package owns its own safety contract:
@@ -24,6 +26,7 @@ const (
 	volsyncVersion = "v1alpha1"
 	kindRS         = "ReplicationSource"
 	kindRD         = "ReplicationDestination"
+	kindPVC        = "PersistentVolumeClaim"
 )
 
 // AllowedRSGVK / AllowedRDGVK are the only GVKs IsAllowedGVK will return
@@ -47,6 +50,41 @@ func IsAllowedGVK(gvk schema.GroupVersionKind) bool {
 	return ok
 }
 
+// AllowedClonePVCGVK is the one kind outside VolSync the executor will
+// write: the target PVC of a restore-to-clone (controller.CloneRunner).
+// It is deliberately NOT in allowedGVKs — IsAllowedGVK stays false for
+// it, and the planner's ops can never reach a PVC. An op on this GVK is
+// accepted only when clonePVCRefusal finds nothing wrong with it:
+//
+//   - Create only of a PVC IsClonePVC accepts: operator-owned,
+//     LabelClone=true, and populated from a VolSync RD through
+//     dataSourceRef. That rules out writing an empty PVC, and any PVC an
+//     application could mount as its own.
+//   - Update never. A clone is immutable once created (dataSourceRef
+//     cannot change anyway); a different request is a different clone.
+//   - Delete only when the live PVC passes IsClonePVC too, on top of the
+//     ownership re-check every Delete gets. A drill PVC is
+//     operator-owned but not a clone, and is refused.
+//
+// An application's PVC carries neither label, so no bug upstream of the
+// executor can turn this into a path for deleting user data.
+var AllowedClonePVCGVK = schema.GroupVersionKind{Version: "v1", Kind: kindPVC}
+
+// IsClonePVC reports whether obj is a restore-to-clone PVC: it carries
+// the managed-by and clone labels and its dataSourceRef names a VolSync
+// ReplicationDestination. Works on typed-as-unstructured PVCs, desired
+// or live. Pure function. Nil-safe: returns false on nil input.
+func IsClonePVC(obj *unstructured.Unstructured) bool {
+	if obj == nil || obj.GroupVersionKind() != AllowedClonePVCGVK {
+		return false
+	}
+	if !IsOperatorOwned(obj) || obj.GetLabels()[labels.LabelClone] != "true" {
+		return false
+	}
+	ref, ok, _ := unstructured.NestedStringMap(obj.Object, "spec", "dataSourceRef")
+	return ok && ref["apiGroup"] == volsyncGroup && ref["kind"] == kindRD && ref["name"] != ""
+}
+
 // IsOperatorOwned reports whether the given live resource carries the
 // canonical pvc-plumber managed-by label. Used by execUpdate and
 // execDelete to gate mutations on ownership: if the live object isn't
@@ -0,0 +1,507 @@
+// Package journal is the append-only record of every cluster write the
+// v4 executor attempted.
+//
+// Before the journal existed, an executor outcome lived in exactly two
+// places: the per-PVC ParityEntry.ExecutionResult (overwritten on the
+// very next reconcile) and the operator's log stream (rotated away by
+// the node within days). Neither answers "which ReplicationSource did
+// the operator delete last Tuesday, and what did it look like?" — the
+// question every post-incident review of a pruned backup chain starts
+// with. The journal keeps one Entry per attempted write with the
+// resourceVersion transition, a hash of the applied spec, and the spec
+// itself, so history can be reconstructed and a deleted RS/RD can be
+// replayed (see Replay).
+//
+// What is journaled:
+//
+//   - every executor outcome that reached the write stage: succeeded,
+//     failed, and refused ops (a refusal is a decision worth keeping —
+//     "not-owned" on a Delete is exactly the evidence an incident
+//     review wants).
+//   - replays, with Trigger="replay".
+//   - orphaned-RS reaps, with Trigger="orphan-reap".
+//   - restore-drill writes in the drill sandbox namespace, with
+//     Trigger="restore-drill".
+//   - restore-to-clone creates and expiries, with
+//     Trigger="restore-clone".
+//   - on-demand backup trigger switches on an RS, with
+//     Trigger="backup-on-demand".
+//   - pre/post backup hooks — execs into application pods and scales of
+//     application workloads — with Trigger="backup-hook".
+//
+// What is NOT journaled:
+//
+//   - OpSkipped outcomes. Audit mode re-plans every PVC on every
+//     resync and skips every op; journaling those would bury the real
+//     writes under thousands of no-op lines per day and would make an
+//     audit-mode pod write to its sinks, which it must not do.
+//
+// Sinks are pluggable (Sink). Delivery is best-effort and never fails
+// the caller: a sink error is logged, counted, and dropped. Once Start
+// runs (the operator registers the Journal with the manager), Append
+// does not block on the sinks either — the ConfigMap sink is an
+// apiserver round-trip per entry, and reconcile workers must not queue
+// up behind it. Entries go onto a bounded queue (QueueCapacity) drained
+// in order by one goroutine; when the queue is full the entry is
+// dropped from the sinks and counted (Dropped). Before Start and after
+// it returns, Append writes synchronously, which is what the CLIs want:
+// their journal entry is on disk or in the ConfigMap before they exit.
+//
+// The journal also keeps a small in-memory ring of recent entries so
+// /journal has something to serve when no durable sink is readable.
+// Every entry reaches the ring, dropped or not.
+package journal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/executor"
+)
+
+// Trigger values recorded on Entry.Trigger.
+const (
+	// TriggerReconcile marks writes issued by the v4 reconciler's
+	// executor pass.
+	TriggerReconcile = "reconcile"
+
+	// TriggerReplay marks writes issued by Replay.
+	TriggerReplay = "replay"
+
+	// TriggerOrphanReap marks deletes issued by the orphaned-child
+	// reaper (controller.OrphanReaper).
+	TriggerOrphanReap = "orphan-reap"
+
+	// TriggerRestoreDrill marks the creates and deletes the restore
+	// drill (controller.DrillRunner) issues in its sandbox namespace.
+	// They bypass the executor, whose allow-list is RS/RD only, so the
+	// drill builds these entries itself.
+	TriggerRestoreDrill = "restore-drill"
+
+	// TriggerRestoreClone marks the creates and TTL deletes of
+	// restore-to-clone RDs and PVCs (controller.CloneRunner). Unlike
+	// drill writes they go through the executor.
+	TriggerRestoreClone = "restore-clone"
+
+	// TriggerBackupOnDemand marks the RS trigger updates of an
+	// on-demand backup (controller.OnDemandBackup): the switch to a
+	// one-shot manual trigger and the restore of the original trigger.
+	TriggerBackupOnDemand = "backup-on-demand"
+
+	// TriggerBackupHook marks what a backup hook (controller.HookRunner)
+	// did to an application: Op "exec" into a Pod, Op "scale" of a
+	// Deployment or StatefulSet. These are the only journaled actions on
+	// objects the operator does not own; Replay never acts on them.
+	TriggerBackupHook = "backup-hook"
+)
+
+// DefaultRecentCapacity is the size of the in-memory ring used when no
+// readable durable sink is configured. Large enough to cover several
+// resync cycles of a busy permissive cluster; small enough that the
+// retained specs are noise next to the informer caches.
+const DefaultRecentCapacity = 512
+
+// QueueCapacity bounds the entries waiting for the sinks while Start
+// runs. A permissive resync writes a handful of entries per changed
+// PVC, so only sinks stalled for minutes fill it.
+const QueueCapacity = 1024
+
+// Entry is one journaled write attempt. The JSON shape is the on-disk
+// and on-the-wire contract for every sink and for /journal; add fields,
+// never rename them.
+type Entry struct {
+	// Time is when the journal recorded the outcome (UTC).
+	Time time.Time `json:"time"`
+
+	// Trigger is what issued the write (TriggerReconcile | TriggerReplay).
+	Trigger string `json:"trigger"`
+
+	// Op is the executor op kind: "create" | "update" | "delete". Backup
+	// hooks add "exec" and "scale" (TriggerBackupHook).
+	Op string `json:"op"`
+
+	// GVK is the canonical "group/version/Kind" string, identical to
+	// the one /audit reports in PlannedOps and ExecutionResult.
+	GVK       string `json:"gvk"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	// BeforeResourceVersion / AfterResourceVersion bracket the write.
+	// Create has no before; Delete has no after.
+	BeforeResourceVersion string `json:"before_resource_version,omitempty"`
+	AfterResourceVersion  string `json:"after_resource_version,omitempty"`
+
+	// SpecHash is "sha256:<hex>" over the JSON encoding of the applied
+	// spec (the live spec for Delete). Lets a reader spot "same spec
+	// rewritten" vs "spec actually changed" without diffing Spec.
+	SpecHash string `json:"spec_hash,omitempty"`
+
+	// Status / Reason / Error mirror executor.OpOutcome. Error is the
+	// apiserver error string for failed ops.
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+	Error  string `json:"error,omitempty"`
+
+	// Labels, Annotations and Spec are the body the op acted on. They
+	// are what Replay recreates from, so they are kept verbatim.
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Spec        map[string]any    `json:"spec,omitempty"`
+}
+
+// Kind returns the Kind segment of GVK ("ReplicationSource" for
+// "volsync.backube/v1alpha1/ReplicationSource").
+func (e Entry) Kind() string {
+	if i := strings.LastIndex(e.GVK, "/"); i >= 0 {
+		return e.GVK[i+1:]
+	}
+	return e.GVK
+}
+
+// Filter narrows a Query. Zero-valued fields match everything.
+type Filter struct {
+	Namespace string
+	Name      string
+	// Kind matches Entry.Kind() exactly ("ReplicationSource").
+	Kind   string
+	Op     string
+	Status string
+	// Since drops entries recorded before this instant.
+	Since time.Time
+	// Limit caps the number of entries returned (newest first).
+	// Zero or negative means no cap.
+	Limit int
+}
+
+// Matches reports whether e satisfies every non-zero field of f (Limit
+// is applied by the caller, not here).
+func (f Filter) Matches(e Entry) bool {
+	if f.Namespace != "" && e.Namespace != f.Namespace {
+		return false
+	}
+	if f.Name != "" && e.Name != f.Name {
+		return false
+	}
+	if f.Kind != "" && e.Kind() != f.Kind {
+		return false
+	}
+	if f.Op != "" && e.Op != f.Op {
+		return false
+	}
+	if f.Status != "" && e.Status != f.Status {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	return true
+}
+
+// Sink receives journal entries. Implementations must be safe for
+// concurrent use; Write is called from reconcile goroutines.
+type Sink interface {
+	Write(ctx context.Context, e Entry) error
+}
+
+// Reader is implemented by sinks that can read their own history back
+// (the rotating file and the ConfigMap). Query prefers the first
+// configured Reader over the in-memory ring because it survives pod
+// restarts.
+type Reader interface {
+	Read(ctx context.Context) ([]Entry, error)
+}
+
+// Journal fans entries out to its sinks and answers queries.
+//
+// A nil *Journal is valid and inert: RecordResult / Append are no-ops
+// and Query returns nothing. That lets the reconciler call it
+// unconditionally, the same way it treats a zero ResyncInterval.
+type Journal struct {
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	sinks  []Sink
+	recent []Entry
+	cap    int
+
+	// queue is non-nil while Start runs; Append sends on it under mu,
+	// so Start can swap it out without racing a send.
+	queue chan queued
+
+	writeErrors atomic.Int64
+	dropped     atomic.Int64
+
+	// now is injected for deterministic tests. nil → time.Now.
+	now func() time.Time
+}
+
+// New constructs a Journal over the given sinks. logger may be nil.
+func New(logger *slog.Logger, sinks ...Sink) *Journal {
+	return &Journal{
+		logger: logger,
+		sinks:  append([]Sink(nil), sinks...),
+		cap:    DefaultRecentCapacity,
+	}
+}
+
+// AddSink attaches another sink after construction. Used by the
+// operator binary for the ConfigMap sink, whose client only exists once
+// the controller-runtime manager is up.
+func (j *Journal) AddSink(s Sink) {
+	if j == nil || s == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.sinks = append(j.sinks, s)
+}
+
+// SetNow overrides the clock. Tests only.
+func (j *Journal) SetNow(now func() time.Time) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.now = now
+}
+
+// WriteErrors returns the number of sink writes that failed since
+// startup. Sink failures never surface to the reconciler, so this
+// counter (and the accompanying Warn log) is the only signal.
+func (j *Journal) WriteErrors() int64 {
+	if j == nil {
+		return 0
+	}
+	return j.writeErrors.Load()
+}
+
+// Dropped returns the number of entries that never reached the sinks
+// because the delivery queue was full. They are still in the in-memory
+// ring.
+func (j *Journal) Dropped() int64 {
+	if j == nil {
+		return 0
+	}
+	return j.dropped.Load()
+}
+
+// queued is an entry waiting for the sinks, with the context it was
+// appended under (cancellation stripped: the caller has moved on).
+type queued struct {
+	ctx context.Context
+	e   Entry
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every
+// replica journals its own writes.
+func (j *Journal) NeedLeaderElection() bool { return false }
+
+// Start implements manager.Runnable: it switches Append to queued
+// delivery and drains the queue into the sinks until ctx is cancelled.
+// On the way out it writes whatever is still queued and switches Append
+// back to synchronous delivery, so nothing appended during shutdown is
+// lost.
+func (j *Journal) Start(ctx context.Context) error {
+	q := make(chan queued, QueueCapacity)
+	j.mu.Lock()
+	j.queue = q
+	j.mu.Unlock()
+
+	for {
+		select {
+		case item := <-q:
+			j.deliver(item.ctx, item.e)
+		case <-ctx.Done():
+			j.mu.Lock()
+			j.queue = nil
+			j.mu.Unlock()
+			for {
+				select {
+				case item := <-q:
+					j.deliver(item.ctx, item.e)
+				default:
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// RecordResult journals every non-skipped outcome in res under the
+// given trigger.
+func (j *Journal) RecordResult(ctx context.Context, trigger string, res executor.Result) {
+	if j == nil {
+		return
+	}
+	for _, out := range res.Attempted {
+		if out.Status == executor.OpSkipped {
+			continue
+		}
+		j.Append(ctx, EntryFromOutcome(trigger, out))
+	}
+}
+
+// Append stamps e with the journal clock (when e.Time is zero), keeps
+// it in the in-memory ring, and hands it to the sinks: queued while
+// Start runs, written inline otherwise.
+func (j *Journal) Append(ctx context.Context, e Entry) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	if e.Time.IsZero() {
+		now := time.Now
+		if j.now != nil {
+			now = j.now
+		}
+		e.Time = now().UTC()
+	}
+	j.recent = append(j.recent, e)
+	if over := len(j.recent) - j.cap; over > 0 {
+		j.recent = append([]Entry(nil), j.recent[over:]...)
+	}
+	if j.queue != nil {
+		if len(j.sinks) > 0 {
+			select {
+			case j.queue <- queued{ctx: context.WithoutCancel(ctx), e: e}:
+			default:
+				n := j.dropped.Add(1)
+				if j.logger != nil {
+					j.logger.Warn("journal queue full; entry dropped from the sinks",
+						"dropped_total", n,
+						"op", e.Op,
+						"gvk", e.GVK,
+						"namespace", e.Namespace,
+						"name", e.Name,
+					)
+				}
+			}
+		}
+		j.mu.Unlock()
+		return
+	}
+	j.mu.Unlock()
+	j.deliver(ctx, e)
+}
+
+// deliver writes e to every sink, counting and logging failures.
+func (j *Journal) deliver(ctx context.Context, e Entry) {
+	j.mu.Lock()
+	sinks := append([]Sink(nil), j.sinks...)
+	j.mu.Unlock()
+
+	for _, s := range sinks {
+		if err := s.Write(ctx, e); err != nil {
+			j.writeErrors.Add(1)
+			if j.logger != nil {
+				j.logger.Warn("journal sink write failed",
+					"error", err,
+					"op", e.Op,
+					"gvk", e.GVK,
+					"namespace", e.Namespace,
+					"name", e.Name,
+				)
+			}
+		}
+	}
+}
+
+// Query returns entries matching f, newest first. History comes from
+// the first sink that implements Reader; when none does, the in-memory
+// ring is used instead. While Start runs, the newest entries may still
+// be queued and not yet in the Reader.
+func (j *Journal) Query(ctx context.Context, f Filter) ([]Entry, error) {
+	if j == nil {
+		return nil, nil
+	}
+	j.mu.Lock()
+	var reader Reader
+	for _, s := range j.sinks {
+		if r, ok := s.(Reader); ok {
+			reader = r
+			break
+		}
+	}
+	recent := append([]Entry(nil), j.recent...)
+	j.mu.Unlock()
+
+	all := recent
+	if reader != nil {
+		read, err := reader.Read(ctx)
+		if err != nil {
+			return nil, err
+		}
+		all = read
+	}
+	return Select(all, f), nil
+}
+
+// Select applies f to entries and returns the matches newest first.
+// Exported so sinks read outside a Journal (the CLI's direct file /
+// ConfigMap access) filter identically.
+func Select(entries []Entry, f Filter) []Entry {
+	out := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		if f.Matches(e) {
+			out = append(out, e)
+		}
+	}
+	sort.SliceStable(out, func(a, b int) bool { return out[a].Time.After(out[b].Time) })
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	return out
+}
+
+// EntryFromOutcome converts one executor outcome into an Entry. Time is
+// left zero so Append stamps it.
+func EntryFromOutcome(trigger string, out executor.OpOutcome) Entry {
+	e := Entry{
+		Trigger:               trigger,
+		Op:                    out.Kind,
+		GVK:                   out.GVK,
+		Namespace:             out.Namespace,
+		Name:                  out.Name,
+		BeforeResourceVersion: out.BeforeResourceVersion,
+		AfterResourceVersion:  out.AfterResourceVersion,
+		Status:                string(out.Status),
+		Reason:                out.Reason,
+	}
+	if out.Err != nil {
+		e.Error = out.Err.Error()
+	}
+	if out.Object != nil {
+		e.Labels = out.Object.GetLabels()
+		e.Annotations = out.Object.GetAnnotations()
+		if spec, ok := out.Object.Object["spec"].(map[string]any); ok {
+			e.Spec = spec
+			e.SpecHash = SpecHash(spec)
+		}
+	}
+	return e
+}
+
+// SpecHash returns "sha256:<hex>" over the JSON encoding of spec.
+// encoding/json sorts map keys, so the hash is stable for equal specs
+// regardless of map iteration order. Returns "" for a nil spec or one
+// that cannot be encoded.
+func SpecHash(spec map[string]any) string {
+	if spec == nil {
+		return ""
+	}
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(raw)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,192 @@
+package journal_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/executor"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+	v4labels "github.com/mitchross/pvc-plumber/internal/v4/labels"
+)
+
+const (
+	tns      = "myapp"
+	tpvc     = "data"
+	tdst     = "data-dst"
+	rsGVKStr = "volsync.backube/v1alpha1/ReplicationSource"
+	rdGVKStr = "volsync.backube/v1alpha1/ReplicationDestination"
+	tRepo    = "volsync-kopia-repository"
+)
+
+var (
+	rsGVK = schema.GroupVersionKind{Group: "volsync.backube", Version: "v1alpha1", Kind: "ReplicationSource"}
+	rdGVK = schema.GroupVersionKind{Group: "volsync.backube", Version: "v1alpha1", Kind: "ReplicationDestination"}
+
+	fixedTime = time.Date(2026, 6, 2, 9, 0, 0, 0, time.UTC)
+)
+
+// ownedBody returns an operator-owned RS/RD body with a kopia repository
+// spec, the same minimum shape the executor tests use.
+func ownedBody(gvk schema.GroupVersionKind, name, repo string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	u.SetNamespace(tns)
+	u.SetName(name)
+	u.SetLabels(map[string]string{v4labels.LabelManagedByKey: v4labels.LabelManagedByValue})
+	_ = unstructured.SetNestedField(u.Object, repo, "spec", "kopia", "repository")
+	return u
+}
+
+// memSink is an in-package Sink + Reader used to observe fan-out.
+type memSink struct {
+	entries []journal.Entry
+	err     error
+}
+
+func (m *memSink) Write(_ context.Context, e journal.Entry) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.entries = append(m.entries, e)
+	return nil
+}
+
+func (m *memSink) Read(_ context.Context) ([]journal.Entry, error) {
+	return append([]journal.Entry(nil), m.entries...), nil
+}
+
+// clock returns a deterministic clock that advances one minute per call
+// so newest-first ordering is observable.
+func clock() func() time.Time {
+	t := fixedTime
+	return func() time.Time {
+		t = t.Add(time.Minute)
+		return t
+	}
+}
+
+func TestRecordResult_SkipsAuditSkipsAndJournalsEverythingElse(t *testing.T) {
+	sink := &memSink{}
+	j := journal.New(nil, sink)
+	j.SetNow(clock())
+
+	res := executor.Result{Attempted: []executor.OpOutcome{
+		{Kind: "create", GVK: rsGVKStr, Namespace: tns, Name: tpvc, Status: executor.OpSkipped, Reason: "mode=audit"},
+		{Kind: "create", GVK: rsGVKStr, Namespace: tns, Name: tpvc, Status: executor.OpSucceeded,
+			AfterResourceVersion: "7", Object: ownedBody(rsGVK, tpvc, tRepo)},
+		{Kind: "update", GVK: rdGVKStr, Namespace: tns, Name: tdst, Status: executor.OpRefused, Reason: "not-owned",
+			BeforeResourceVersion: "3"},
+		{Kind: "delete", GVK: rdGVKStr, Namespace: tns, Name: tdst, Status: executor.OpFailed, Reason: "delete-failed",
+			Err: errors.New("etcdserver: request timed out")},
+	}}
+	j.RecordResult(context.Background(), journal.TriggerReconcile, res)
+
+	if len(sink.entries) != 3 {
+		t.Fatalf("journaled %d entries, want 3 (skip dropped): %+v", len(sink.entries), sink.entries)
+	}
+	created := sink.entries[0]
+	if created.Op != "create" || created.Status != "succeeded" || created.AfterResourceVersion != "7" {
+		t.Errorf("create entry: %+v", created)
+	}
+	if created.Trigger != journal.TriggerReconcile {
+		t.Errorf("trigger: got %q", created.Trigger)
+	}
+	if created.SpecHash == "" || created.Spec == nil {
+		t.Errorf("create entry missing spec/hash: %+v", created)
+	}
+	if created.Time.IsZero() {
+		t.Error("entry time not stamped")
+	}
+	if got := sink.entries[2].Error; got != "etcdserver: request timed out" {
+		t.Errorf("failed entry error: got %q", got)
+	}
+}
+
+func TestQuery_FiltersNewestFirstAndLimits(t *testing.T) {
+	sink := &memSink{}
+	j := journal.New(nil, sink)
+	j.SetNow(clock())
+	ctx := context.Background()
+
+	j.Append(ctx, journal.Entry{Op: "create", GVK: rsGVKStr, Namespace: tns, Name: tpvc, Status: "succeeded"})
+	j.Append(ctx, journal.Entry{Op: "create", GVK: rdGVKStr, Namespace: tns, Name: tdst, Status: "succeeded"})
+	j.Append(ctx, journal.Entry{Op: "delete", GVK: rsGVKStr, Namespace: tns, Name: tpvc, Status: "succeeded"})
+	j.Append(ctx, journal.Entry{Op: "create", GVK: rsGVKStr, Namespace: "other", Name: tpvc, Status: "succeeded"})
+
+	got, err := j.Query(ctx, journal.Filter{Namespace: tns, Kind: "ReplicationSource"})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(got) != 2 || got[0].Op != "delete" || got[1].Op != "create" {
+		t.Fatalf("want [delete create] newest first, got %+v", got)
+	}
+
+	got, _ = j.Query(ctx, journal.Filter{Limit: 1})
+	if len(got) != 1 || got[0].Namespace != "other" {
+		t.Errorf("Limit=1 should return the newest entry, got %+v", got)
+	}
+
+	got, _ = j.Query(ctx, journal.Filter{Since: fixedTime.Add(3 * time.Minute)})
+	if len(got) != 2 {
+		t.Errorf("Since filter: got %d entries, want 2", len(got))
+	}
+}
+
+// Without a Reader sink the in-memory ring answers queries — the stdout
+// sink alone must not leave /journal empty.
+func TestQuery_FallsBackToRecentRingWithoutReader(t *testing.T) {
+	j := journal.New(nil, journal.NewWriterSink(discard{}))
+	j.Append(context.Background(), journal.Entry{Op: "create", GVK: rsGVKStr, Namespace: tns, Name: tpvc})
+	got, err := j.Query(context.Background(), journal.Filter{})
+	if err != nil || len(got) != 1 {
+		t.Fatalf("ring fallback: got %v, %v", got, err)
+	}
+}
+
+func TestAppend_SinkErrorIsCountedNotPropagated(t *testing.T) {
+	bad := &memSink{err: errors.New("disk full")}
+	good := &memSink{}
+	j := journal.New(nil, bad, good)
+	j.Append(context.Background(), journal.Entry{Op: "create"})
+	if j.WriteErrors() != 1 {
+		t.Errorf("WriteErrors: got %d, want 1", j.WriteErrors())
+	}
+	if len(good.entries) != 1 {
+		t.Error("a failing sink must not stop delivery to the others")
+	}
+}
+
+func TestNilJournal_IsInert(t *testing.T) {
+	var j *journal.Journal
+	j.RecordResult(context.Background(), journal.TriggerReconcile, executor.Result{
+		Attempted: []executor.OpOutcome{{Status: executor.OpSucceeded}},
+	})
+	j.AddSink(&memSink{})
+	if got, err := j.Query(context.Background(), journal.Filter{}); got != nil || err != nil {
+		t.Errorf("nil journal Query: got %v, %v", got, err)
+	}
+}
+
+func TestSpecHash_StableAndSensitive(t *testing.T) {
+	a := map[string]any{"sourcePVC": tpvc, "kopia": map[string]any{"repository": tRepo}}
+	b := map[string]any{"kopia": map[string]any{"repository": tRepo}, "sourcePVC": tpvc}
+	if journal.SpecHash(a) != journal.SpecHash(b) {
+		t.Error("hash must not depend on map construction order")
+	}
+	c := map[string]any{"sourcePVC": tpvc, "kopia": map[string]any{"repository": "other"}}
+	if journal.SpecHash(a) == journal.SpecHash(c) {
+		t.Error("hash must change when the spec changes")
+	}
+	if journal.SpecHash(nil) != "" {
+		t.Error("nil spec must hash to empty")
+	}
+}
+
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
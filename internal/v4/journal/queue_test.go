@@ -0,0 +1,114 @@
+package journal
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gateSink blocks every Write until gate is closed, signalling entered
+// the first time one starts.
+type gateSink struct {
+	gate    chan struct{}
+	entered chan struct{}
+	once    sync.Once
+
+	mu      sync.Mutex
+	entries []Entry
+}
+
+func (s *gateSink) Write(_ context.Context, e Entry) error {
+	s.once.Do(func() { close(s.entered) })
+	<-s.gate
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func (s *gateSink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+func waitQueued(t *testing.T, j *Journal) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		j.mu.Lock()
+		active := j.queue != nil
+		j.mu.Unlock()
+		if active {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("Start never switched to queued delivery")
+}
+
+// While Start runs a stalled sink neither blocks Append nor grows
+// memory without bound: the queue fills, then entries are dropped and
+// counted. Shutdown delivers what was queued and goes back to inline
+// writes.
+func TestStart_StalledSinkDoesNotBlockAppend(t *testing.T) {
+	sink := &gateSink{gate: make(chan struct{}), entered: make(chan struct{})}
+	j := New(nil, sink)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() { stopped <- j.Start(ctx) }()
+	waitQueued(t, j)
+
+	j.Append(context.Background(), Entry{Op: "create"})
+	<-sink.entered // the drain goroutine now holds one entry in Write
+	for range QueueCapacity + 2 {
+		j.Append(context.Background(), Entry{Op: "update"})
+	}
+	if got := j.Dropped(); got != 2 {
+		t.Errorf("Dropped = %d, want 2", got)
+	}
+
+	close(sink.gate)
+	cancel()
+	if err := <-stopped; err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if got := sink.count(); got != QueueCapacity+1 {
+		t.Errorf("delivered %d entries, want %d", got, QueueCapacity+1)
+	}
+
+	j.Append(context.Background(), Entry{Op: "delete"})
+	if got := sink.count(); got != QueueCapacity+2 {
+		t.Errorf("after Start returned, Append must write inline: %d entries", got)
+	}
+}
+
+// Reading the whole retained history must not hold up writers.
+func TestFileSink_ReadDoesNotTakeWriteLock(t *testing.T) {
+	s, err := NewFileSink(filepath.Join(t.TempDir(), "journal.jsonl"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }()
+	if err := s.Write(context.Background(), Entry{Op: "create"}); err != nil {
+		t.Fatal(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	done := make(chan []Entry)
+	go func() {
+		entries, _ := s.Read(context.Background())
+		done <- entries
+	}()
+	select {
+	case entries := <-done:
+		if len(entries) != 1 {
+			t.Errorf("Read: %d entries, want 1", len(entries))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Read waited for the write lock")
+	}
+}
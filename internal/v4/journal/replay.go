@@ -0,0 +1,101 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/executor"
+	"github.com/mitchross/pvc-plumber/internal/v4/mode"
+	"github.com/mitchross/pvc-plumber/internal/v4/planner"
+)
+
+// Replay errors. Callers (the CLI) map these to refusals rather than
+// infrastructure failures.
+var (
+	// ErrNoJournaledSpec means no entry for the target carries a spec
+	// to recreate from.
+	ErrNoJournaledSpec = errors.New("no journaled spec for target")
+
+	// ErrNotOperatorOwned means the journaled body lacks the pvc-plumber
+	// managed-by label. Replay only recreates what the operator itself
+	// owned; recreating an Argo-owned inline RS/RD would be adoption.
+	ErrNotOperatorOwned = errors.New("journaled body is not operator-owned")
+)
+
+// Target names one RS/RD to replay. Kind is "ReplicationSource" or
+// "ReplicationDestination".
+type Target struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// Reconstruct returns the object Replay would create for t: the newest
+// succeeded entry for t that carries a spec, rebuilt as a fresh
+// unstructured body (no resourceVersion, UID, or status). Failed,
+// refused and skipped entries are passed over: their spec never reached
+// the cluster, so a rejected update must not shadow the spec that was
+// actually running. Pure; entries may come from any sink.
+func Reconstruct(entries []Entry, t Target) (*unstructured.Unstructured, Entry, error) {
+	matches := Select(entries, Filter{Namespace: t.Namespace, Name: t.Name, Kind: t.Kind, Status: string(executor.OpSucceeded)})
+	for _, e := range matches {
+		if e.Spec == nil {
+			continue
+		}
+		gvk, err := parseGVK(e.GVK)
+		if err != nil {
+			return nil, e, err
+		}
+		u := &unstructured.Unstructured{Object: map[string]any{}}
+		u.SetGroupVersionKind(gvk)
+		u.SetNamespace(e.Namespace)
+		u.SetName(e.Name)
+		u.SetLabels(e.Labels)
+		u.SetAnnotations(e.Annotations)
+		u.Object["spec"] = e.Spec
+		if !executor.IsOperatorOwned(u) {
+			return nil, e, ErrNotOperatorOwned
+		}
+		return u, e, nil
+	}
+	return nil, Entry{}, fmt.Errorf("%w: %s %s/%s", ErrNoJournaledSpec, t.Kind, t.Namespace, t.Name)
+}
+
+// Replay recreates a deleted RS/RD from its newest succeeded journaled spec.
+//
+// The create goes through executor.Execute like any planner op, so the
+// executor's rails still apply: the GVK allow-list, and no adoption —
+// if the object already exists the outcome is Refused("exists") and
+// nothing is written. In audit mode the op is Skipped. The outcome is
+// journaled with Trigger=TriggerReplay; j may be nil when the caller
+// does not want that.
+func Replay(ctx context.Context, c client.Client, m mode.Mode, j *Journal, entries []Entry, t Target) (executor.OpOutcome, error) {
+	obj, _, err := Reconstruct(entries, t)
+	if err != nil {
+		return executor.OpOutcome{}, err
+	}
+	res := executor.Execute(ctx, c, m, planner.Plan{
+		Ops: []planner.PlannedOp{{Kind: planner.OpCreate, Resource: obj}},
+	})
+	j.RecordResult(ctx, TriggerReplay, res)
+	return res.Attempted[0], nil
+}
+
+// parseGVK inverts executor's "group/version/Kind" rendering.
+func parseGVK(s string) (schema.GroupVersionKind, error) {
+	i := strings.LastIndex(s, "/")
+	if i <= 0 || i == len(s)-1 {
+		return schema.GroupVersionKind{}, fmt.Errorf("malformed journal gvk %q", s)
+	}
+	gv, err := schema.ParseGroupVersion(s[:i])
+	if err != nil {
+		return schema.GroupVersionKind{}, fmt.Errorf("malformed journal gvk %q: %w", s, err)
+	}
+	return gv.WithKind(s[i+1:]), nil
+}
@@ -0,0 +1,144 @@
+package journal_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/executor"
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+	v4labels "github.com/mitchross/pvc-plumber/internal/v4/labels"
+	"github.com/mitchross/pvc-plumber/internal/v4/mode"
+	"github.com/mitchross/pvc-plumber/internal/v4/planner"
+)
+
+var rsTarget = journal.Target{Kind: "ReplicationSource", Namespace: tns, Name: tpvc}
+
+// deleteThroughExecutor seeds an operator-owned RS, deletes it via the
+// executor, and journals the outcome — the exact path a tier=disabled
+// tear-down takes in permissive mode.
+func deleteThroughExecutor(t *testing.T, repo string) (client.Client, *journal.Journal, *memSink) {
+	t.Helper()
+	fc := fake.NewClientBuilder().WithObjects(ownedBody(rsGVK, tpvc, repo)).Build()
+	sink := &memSink{}
+	j := journal.New(nil, sink)
+	j.SetNow(clock())
+
+	res := executor.Execute(context.Background(), fc, mode.Permissive, planner.Plan{
+		Ops: []planner.PlannedOp{{Kind: planner.OpDelete, Resource: ownedBody(rsGVK, tpvc, "planner-stub")}},
+	})
+	j.RecordResult(context.Background(), journal.TriggerReconcile, res)
+	return fc, j, sink
+}
+
+func TestReplay_RecreatesDeletedRSFromJournaledLiveSpec(t *testing.T) {
+	fc, j, sink := deleteThroughExecutor(t, tRepo)
+	ctx := context.Background()
+
+	out, err := journal.Replay(ctx, fc, mode.Permissive, j, sink.entries, rsTarget)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if out.Status != executor.OpSucceeded {
+		t.Fatalf("replay outcome: %+v", out)
+	}
+
+	got := &unstructured.Unstructured{}
+	got.SetGroupVersionKind(rsGVK)
+	if err := fc.Get(ctx, client.ObjectKey{Namespace: tns, Name: tpvc}, got); err != nil {
+		t.Fatalf("RS not recreated: %v", err)
+	}
+	repo, _, _ := unstructured.NestedString(got.Object, "spec", "kopia", "repository")
+	if repo != tRepo {
+		t.Errorf("replayed repo: got %q, want the live spec %q (not the planner stub)", repo, tRepo)
+	}
+
+	last := sink.entries[len(sink.entries)-1]
+	if last.Trigger != journal.TriggerReplay || last.Op != "create" {
+		t.Errorf("replay not journaled as trigger=replay create: %+v", last)
+	}
+	if last.SpecHash != sink.entries[0].SpecHash {
+		t.Errorf("replayed spec hash %q differs from deleted spec hash %q", last.SpecHash, sink.entries[0].SpecHash)
+	}
+}
+
+// Replay is not adoption: if the object came back in the meantime the
+// executor's "exists" rail refuses and nothing is overwritten.
+func TestReplay_ExistingObjectRefused(t *testing.T) {
+	fc, j, sink := deleteThroughExecutor(t, tRepo)
+	ctx := context.Background()
+	if err := fc.Create(ctx, ownedBody(rsGVK, tpvc, "recreated-by-someone-else")); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := journal.Replay(ctx, fc, mode.Permissive, j, sink.entries, rsTarget)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if out.Status != executor.OpRefused || out.Reason != "exists" {
+		t.Errorf("want refused/exists, got %+v", out)
+	}
+}
+
+func TestReplay_AuditModeSkips(t *testing.T) {
+	fc, j, sink := deleteThroughExecutor(t, tRepo)
+	out, err := journal.Replay(context.Background(), fc, mode.Audit, j, sink.entries, rsTarget)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if out.Status != executor.OpSkipped {
+		t.Errorf("audit replay must skip, got %+v", out)
+	}
+}
+
+func TestReconstruct_Errors(t *testing.T) {
+	if _, _, err := journal.Reconstruct(nil, rsTarget); !errors.Is(err, journal.ErrNoJournaledSpec) {
+		t.Errorf("empty journal: got %v, want ErrNoJournaledSpec", err)
+	}
+
+	foreign := []journal.Entry{{
+		Op: "delete", GVK: rsGVKStr, Namespace: tns, Name: tpvc, Status: string(executor.OpSucceeded),
+		Labels: map[string]string{"app.kubernetes.io/managed-by": "argocd"},
+		Spec:   map[string]any{"sourcePVC": tpvc},
+	}}
+	if _, _, err := journal.Reconstruct(foreign, rsTarget); !errors.Is(err, journal.ErrNotOperatorOwned) {
+		t.Errorf("argocd body: got %v, want ErrNotOperatorOwned", err)
+	}
+}
+
+// A newer update the apiserver rejected never ran in the cluster; replay
+// must recreate from the older succeeded entry underneath it.
+func TestReconstruct_SkipsUnsucceededEntries(t *testing.T) {
+	owned := map[string]string{v4labels.LabelManagedByKey: v4labels.LabelManagedByValue}
+	entry := func(minute int, status executor.OpStatus, repo string) journal.Entry {
+		return journal.Entry{
+			Time: fixedTime.Add(time.Duration(minute) * time.Minute),
+			Op:   "update", GVK: rsGVKStr, Namespace: tns, Name: tpvc, Status: string(status),
+			Labels: owned,
+			Spec:   map[string]any{"kopia": map[string]any{"repository": repo}},
+		}
+	}
+	entries := []journal.Entry{
+		entry(1, executor.OpSucceeded, tRepo),
+		entry(2, executor.OpFailed, "rejected-repo"),
+		entry(3, executor.OpRefused, "refused-repo"),
+	}
+
+	obj, from, err := journal.Reconstruct(entries, rsTarget)
+	if err != nil {
+		t.Fatalf("Reconstruct: %v", err)
+	}
+	repo, _, _ := unstructured.NestedString(obj.Object, "spec", "kopia", "repository")
+	if repo != tRepo || !from.Time.Equal(entries[0].Time) {
+		t.Errorf("reconstructed %q from %+v, want %q from the succeeded entry", repo, from, tRepo)
+	}
+
+	if _, _, err := journal.Reconstruct(entries[1:], rsTarget); !errors.Is(err, journal.ErrNoJournaledSpec) {
+		t.Errorf("only unsucceeded entries: got %v, want ErrNoJournaledSpec", err)
+	}
+}
@@ -0,0 +1,365 @@
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/labels"
+)
+
+// Defaults for the durable sinks. Chosen so a permissive cluster with a
+// few hundred managed PVCs keeps weeks of history in the file sink and
+// days in the ConfigMap sink, while the ConfigMap stays comfortably
+// under the apiserver's 1 MiB object limit (one RS entry with spec is
+// roughly 1-2 KiB of JSON).
+const (
+	DefaultFileMaxBytes        int64 = 10 << 20
+	DefaultFileMaxFiles              = 5
+	DefaultConfigMapMaxEntries       = 200
+
+	// ConfigMapDataKey is the ConfigMap data key holding the JSON-lines
+	// journal.
+	ConfigMapDataKey = "journal.jsonl"
+)
+
+// =============================================================================
+// WriterSink (stdout)
+// =============================================================================
+
+// WriterSink writes one JSON line per entry to an io.Writer. Used for
+// stdout so the journal rides the pod's normal log shipping; it cannot
+// read its history back, so it never answers Query.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink wraps w. NewStdoutSink is the production constructor.
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// NewStdoutSink writes to os.Stdout.
+func NewStdoutSink() *WriterSink {
+	return NewWriterSink(os.Stdout)
+}
+
+// Write implements Sink.
+func (s *WriterSink) Write(_ context.Context, e Entry) error {
+	line, err := encodeLine(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(line)
+	return err
+}
+
+// =============================================================================
+// FileSink (rotating JSON-lines)
+// =============================================================================
+
+// FileSink appends JSON lines to Path and rotates when the file would
+// grow past MaxBytes: Path → Path.1 → … → Path.<MaxFiles>, oldest
+// dropped. Rotation is size-based only; the journal is low-volume
+// enough that time-based rotation would just produce empty files.
+type FileSink struct {
+	path     string
+	maxBytes int64
+	maxFiles int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+
+	// rotations counts rotate calls, so Read can tell that the files
+	// shifted under it.
+	rotations atomic.Int64
+}
+
+// NewFileSink opens (creating if needed) path for appending. maxBytes
+// <= 0 and maxFiles <= 0 fall back to the package defaults.
+func NewFileSink(path string, maxBytes int64, maxFiles int) (*FileSink, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultFileMaxBytes
+	}
+	if maxFiles <= 0 {
+		maxFiles = DefaultFileMaxFiles
+	}
+	s := &FileSink{path: path, maxBytes: maxBytes, maxFiles: maxFiles}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return fmt.Errorf("open journal file: %w", err)
+	}
+	st, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("stat journal file: %w", err)
+	}
+	s.f = f
+	s.size = st.Size()
+	return nil
+}
+
+// Write implements Sink.
+func (s *FileSink) Write(_ context.Context, e Entry) error {
+	line, err := encodeLine(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return errors.New("journal file sink is closed")
+	}
+	if s.size > 0 && s.size+int64(len(line)) > s.maxBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.f.Write(line)
+	s.size += int64(n)
+	return err
+}
+
+// rotate shifts Path.N → Path.N+1 (dropping the oldest) and reopens a
+// fresh Path. Caller holds s.mu.
+func (s *FileSink) rotate() error {
+	if err := s.f.Close(); err != nil {
+		return fmt.Errorf("close journal file for rotation: %w", err)
+	}
+	s.f = nil
+	s.rotations.Add(1)
+	_ = os.Remove(s.rotated(s.maxFiles))
+	for i := s.maxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(s.rotated(i), s.rotated(i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("rotate journal file: %w", err)
+		}
+	}
+	if err := os.Rename(s.path, s.rotated(1)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("rotate journal file: %w", err)
+	}
+	return s.open()
+}
+
+func (s *FileSink) rotated(i int) string {
+	return fmt.Sprintf("%s.%d", s.path, i)
+}
+
+// fileReadAttempts bounds how often Read starts over when a rotation
+// lands mid-read.
+const fileReadAttempts = 3
+
+// Read implements Reader: every retained file, oldest first.
+// Malformed lines (a torn write from a crash mid-append, or a line
+// still being appended) are skipped.
+//
+// Read does not take the write lock: reading every retained file can
+// take a while (MaxBytes × MaxFiles, 50 MiB by default), and writers
+// must not wait for it. An append racing the read is harmless. A
+// rotation is not — the files shift and an entry could be read twice
+// or missed — so Read starts over when one lands mid-read, and returns
+// its last attempt if rotations keep landing.
+func (s *FileSink) Read(_ context.Context) ([]Entry, error) {
+	var out []Entry
+	for range fileReadAttempts {
+		before := s.rotations.Load()
+		entries, err := ReadFiles(s.path, s.maxFiles)
+		if err != nil {
+			return nil, err
+		}
+		out = entries
+		if s.rotations.Load() == before {
+			break
+		}
+	}
+	return out, nil
+}
+
+// Close flushes and closes the active file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+// ReadFiles reads path and its rotated siblings (path.1 … path.maxFiles)
+// without opening anything for writing. The CLI uses it to read a
+// journal copied out of the pod.
+func ReadFiles(path string, maxFiles int) ([]Entry, error) {
+	if maxFiles <= 0 {
+		maxFiles = DefaultFileMaxFiles
+	}
+	var out []Entry
+	for i := maxFiles; i >= 0; i-- {
+		p := path
+		if i > 0 {
+			p = fmt.Sprintf("%s.%d", path, i)
+		}
+		raw, err := os.ReadFile(p)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("read journal file %s: %w", p, err)
+		}
+		out = append(out, decodeLines(raw)...)
+	}
+	return out, nil
+}
+
+// =============================================================================
+// ConfigMapSink (capped, in-cluster)
+// =============================================================================
+
+// ConfigMapSink keeps the newest MaxEntries entries as JSON lines under
+// ConfigMapDataKey in a single ConfigMap. It survives pod rescheduling
+// without a PersistentVolume, which the file sink does not.
+//
+// The ConfigMap is the operator's own bookkeeping object, not a planner
+// op: it never flows through executor.Execute and so is not subject to
+// the RS/RD GVK allow-list. It is written through whatever client the
+// caller passes — the operator binary passes an auditclient-wrapped one
+// so even this path is inert in audit mode.
+type ConfigMapSink struct {
+	c          client.Client
+	key        types.NamespacedName
+	maxEntries int
+
+	mu sync.Mutex
+}
+
+// NewConfigMapSink targets namespace/name. maxEntries <= 0 falls back
+// to DefaultConfigMapMaxEntries. c should be an uncached client: a
+// cached Get would start a cluster-wide ConfigMap informer for one
+// object.
+func NewConfigMapSink(c client.Client, namespace, name string, maxEntries int) *ConfigMapSink {
+	if maxEntries <= 0 {
+		maxEntries = DefaultConfigMapMaxEntries
+	}
+	return &ConfigMapSink{
+		c:          c,
+		key:        types.NamespacedName{Namespace: namespace, Name: name},
+		maxEntries: maxEntries,
+	}
+}
+
+// Write implements Sink. Read-modify-write with conflict retry; the
+// oldest entries are trimmed once the cap is reached.
+func (s *ConfigMapSink) Write(ctx context.Context, e Entry) error {
+	line, err := encodeLine(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm := &corev1.ConfigMap{}
+		err := s.c.Get(ctx, s.key, cm)
+		if apierrors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: s.key.Namespace,
+					Name:      s.key.Name,
+					Labels:    map[string]string{labels.LabelManagedByKey: labels.LabelManagedByValue},
+				},
+				Data: map[string]string{ConfigMapDataKey: string(line)},
+			}
+			return s.c.Create(ctx, cm)
+		}
+		if err != nil {
+			return fmt.Errorf("get journal configmap: %w", err)
+		}
+		lines := splitLines(cm.Data[ConfigMapDataKey])
+		lines = append(lines, strings.TrimSuffix(string(line), "\n"))
+		if over := len(lines) - s.maxEntries; over > 0 {
+			lines = lines[over:]
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[ConfigMapDataKey] = strings.Join(lines, "\n") + "\n"
+		return s.c.Update(ctx, cm)
+	})
+}
+
+// Read implements Reader. A missing ConfigMap is an empty journal.
+func (s *ConfigMapSink) Read(ctx context.Context) ([]Entry, error) {
+	cm := &corev1.ConfigMap{}
+	if err := s.c.Get(ctx, s.key, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get journal configmap: %w", err)
+	}
+	return decodeLines([]byte(cm.Data[ConfigMapDataKey])), nil
+}
+
+// =============================================================================
+// Encoding helpers
+// =============================================================================
+
+func encodeLine(e Entry) ([]byte, error) {
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("encode journal entry: %w", err)
+	}
+	return append(raw, '\n'), nil
+}
+
+func decodeLines(raw []byte) []Entry {
+	var out []Entry
+	sc := bufio.NewScanner(bytes.NewReader(raw))
+	sc.Buffer(make([]byte, 0, 64*1024), 4<<20)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func splitLines(s string) []string {
+	var out []string
+	for _, l := range strings.Split(s, "\n") {
+		if strings.TrimSpace(l) != "" {
+			out = append(out, l)
+		}
+	}
+	return out
+}
@@ -0,0 +1,125 @@
+package journal_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/mitchross/pvc-plumber/internal/v4/journal"
+)
+
+func TestWriterSink_OneJSONLinePerEntry(t *testing.T) {
+	var buf bytes.Buffer
+	s := journal.NewWriterSink(&buf)
+	ctx := context.Background()
+	_ = s.Write(ctx, journal.Entry{Op: "create", Name: "a"})
+	_ = s.Write(ctx, journal.Entry{Op: "delete", Name: "b"})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %q", len(lines), buf.String())
+	}
+	var e journal.Entry
+	if err := json.Unmarshal([]byte(lines[1]), &e); err != nil || e.Op != "delete" {
+		t.Errorf("line 2 did not round-trip: %v %+v", err, e)
+	}
+}
+
+func TestFileSink_RotatesAndReadsAcrossFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	// Tiny cap so every second entry forces a rotation; keep 2 rotated
+	// files so the oldest entries fall off.
+	s, err := journal.NewFileSink(path, 200, 2)
+	if err != nil {
+		t.Fatalf("NewFileSink: %v", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	ctx := context.Background()
+	for i := range 8 {
+		if err := s.Write(ctx, journal.Entry{Op: "create", Namespace: tns, Name: fmt.Sprintf("rs-%d", i)}); err != nil {
+			t.Fatalf("Write %d: %v", i, err)
+		}
+	}
+
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Errorf("expected %s.1 after rotation: %v", path, err)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("rotation must cap at maxFiles=2, found %s.3 (err=%v)", path, err)
+	}
+
+	got, err := s.Read(ctx)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(got) == 0 || len(got) >= 8 {
+		t.Fatalf("Read returned %d entries; want some but not all (oldest rotated away)", len(got))
+	}
+	if last := got[len(got)-1].Name; last != "rs-7" {
+		t.Errorf("newest entry should be read last (oldest-first), got %q", last)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i-1].Name >= got[i].Name {
+			t.Errorf("entries out of order across files: %q before %q", got[i-1].Name, got[i].Name)
+		}
+	}
+}
+
+func TestFileSink_SkipsTornLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	if err := os.WriteFile(path, []byte(`{"op":"create","name":"ok"}`+"\n"+`{"op":"upd`+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := journal.ReadFiles(path, 1)
+	if err != nil {
+		t.Fatalf("ReadFiles: %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "ok" {
+		t.Errorf("torn trailing line should be skipped, got %+v", got)
+	}
+}
+
+func TestConfigMapSink_CreatesThenCapsOldestFirst(t *testing.T) {
+	fc := fake.NewClientBuilder().Build()
+	s := journal.NewConfigMapSink(fc, "pvc-plumber", "pvc-plumber-journal", 3)
+	ctx := context.Background()
+	for i := range 5 {
+		if err := s.Write(ctx, journal.Entry{Op: "create", Name: fmt.Sprintf("rs-%d", i)}); err != nil {
+			t.Fatalf("Write %d: %v", i, err)
+		}
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := fc.Get(ctx, types.NamespacedName{Namespace: "pvc-plumber", Name: "pvc-plumber-journal"}, cm); err != nil {
+		t.Fatalf("configmap not created: %v", err)
+	}
+	if cm.Labels["app.kubernetes.io/managed-by"] != "pvc-plumber" {
+		t.Errorf("configmap must carry the managed-by label, got %v", cm.Labels)
+	}
+
+	got, err := s.Read(ctx)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(got) != 3 || got[0].Name != "rs-2" || got[2].Name != "rs-4" {
+		t.Errorf("cap=3 should keep rs-2..rs-4, got %+v", got)
+	}
+}
+
+func TestConfigMapSink_MissingConfigMapIsEmptyJournal(t *testing.T) {
+	s := journal.NewConfigMapSink(fake.NewClientBuilder().Build(), "ns", "absent", 0)
+	got, err := s.Read(context.Background())
+	if err != nil || len(got) != 0 {
+		t.Errorf("got %v, %v; want empty, nil", got, err)
+	}
+}
@@ -11,6 +11,13 @@
 // from anywhere — controllers, webhooks, CLI tools, tests.
 package labels
 
+import "strings"
+
+// KeyPrefix is the domain shared by every v4 label and annotation key.
+// Namespace-level watches filter on it so a new namespace-scoped
+// pvc-plumber.io/* knob re-enqueues PVCs without touching the watch.
+const KeyPrefix = "pvc-plumber.io/"
+
 // New v4 namespaced labels (used by reconciler AND webhook objectSelector).
 const (
 	// LabelEnabled is the **only** opt-in signal honored by future admission
@@ -108,6 +115,78 @@ const (
 	// until the PVC has been Bound for at least this duration. Default is
 	// operator config (recommended 2h). Format: time.ParseDuration.
 	AnnotationMinBackupAge = "pvc-plumber.io/min-backup-age"
+
+	// AnnotationRestoreAsOf pins the PVC's restore to the newest snapshot
+	// taken at or before this RFC3339 time (VolSync's kopia restoreAsOf).
+	// Unset restores the latest snapshot.
+	AnnotationRestoreAsOf = "pvc-plumber.io/restore-as-of"
+
+	// AnnotationRestorePrevious skips N snapshots back from the one the
+	// restore would otherwise pick — the latest, or the newest at or
+	// before AnnotationRestoreAsOf when both are set (VolSync's kopia
+	// previous). "1" is "the snapshot before the latest".
+	AnnotationRestorePrevious = "pvc-plumber.io/restore-previous"
+
+	// AnnotationDrillVerify picks how the restore drill verifies this
+	// PVC's restored copy: file-count (the default), checksum-manifest,
+	// probe, or none to opt the PVC out of drills entirely. See
+	// DrillVerify.
+	AnnotationDrillVerify = "pvc-plumber.io/drill-verify"
+
+	// AnnotationDrillManifest is the path, relative to the volume root,
+	// of a `sha256sum` manifest the application keeps on its volume.
+	// checksum-manifest drills run `sha256sum -c` against it. Setting it
+	// without AnnotationDrillVerify implies checksum-manifest.
+	AnnotationDrillManifest = "pvc-plumber.io/drill-manifest"
+
+	// AnnotationDrillProbe is an app-provided shell command run by probe
+	// drills with the restored volume mounted at $DRILL_MOUNT (working
+	// directory). Exit 0 passes. Setting it without
+	// AnnotationDrillVerify implies probe.
+	AnnotationDrillProbe = "pvc-plumber.io/drill-probe"
+
+	// AnnotationDrillImage overrides the verification Job's image for
+	// this PVC — typically the application's own image, so a probe can
+	// use its tooling. Empty falls back to the operator default.
+	AnnotationDrillImage = "pvc-plumber.io/drill-image"
+
+	// AnnotationCloneTo requests a restore-to-clone: the PVC's backup is
+	// restored into a new PVC "[<namespace>/]<name>" (namespace defaults
+	// to the PVC's own) and the original is left alone. See
+	// controller.CloneRunner.
+	AnnotationCloneTo = "pvc-plumber.io/clone-to"
+
+	// AnnotationCloneAsOf restores the clone from the newest snapshot
+	// taken at or before this RFC3339 time. Unset clones the latest.
+	AnnotationCloneAsOf = "pvc-plumber.io/clone-as-of"
+
+	// AnnotationCloneTTL is how long the clone lives before the operator
+	// deletes it (time.ParseDuration, e.g. "72h"). Unset falls back to
+	// the operator default.
+	AnnotationCloneTTL = "pvc-plumber.io/clone-ttl"
+
+	// AnnotationPreBackupExec is a command run in the application before
+	// each scheduled backup, to quiesce it: "<container>:<command>". The
+	// command runs in that container of every running pod that mounts
+	// the PVC, through /bin/sh -c — so the image needs a shell — unless it
+	// is a JSON array (`app:["redis-cli","SAVE"]`), which runs as is. See
+	// controller.HookRunner.
+	AnnotationPreBackupExec = "pvc-plumber.io/pre-backup-exec"
+
+	// AnnotationPostBackupExec is AnnotationPreBackupExec's counterpart,
+	// run once the backup is over — also when the pre-hook or the sync
+	// failed. Same format.
+	AnnotationPostBackupExec = "pvc-plumber.io/post-backup-exec"
+
+	// AnnotationPreBackupScaleToZero names a workload in the PVC's
+	// namespace, "deployment/<name>" or "statefulset/<name>", to scale
+	// to zero before each scheduled backup and back to its replica count
+	// afterwards. The workload's pod template must mount the PVC.
+	AnnotationPreBackupScaleToZero = "pvc-plumber.io/pre-backup-scale-to-zero"
+
+	// AnnotationBackupHookTimeout bounds each of the PVC's hooks (Go
+	// duration, e.g. "2m"). Unset falls back to the operator default.
+	AnnotationBackupHookTimeout = "pvc-plumber.io/backup-hook-timeout"
 )
 
 // Legacy keys retained for inventory + back-compat reads. These MUST NOT be
@@ -153,6 +232,56 @@ const (
 	LabelSourceNamespace = "pvc-plumber.io/source-namespace"
 	LabelSourcePVC       = "pvc-plumber.io/source-pvc"
 	LabelTierOnChild     = "pvc-plumber.io/tier"
+
+	// LabelDrill marks the RD, PVC and Job a restore drill creates in its
+	// sandbox namespace (value "true"). Drill objects deliberately carry
+	// neither LabelSourceNamespace nor LabelSourcePVC: the orphan sweep
+	// and the reconciler match on those, and a drill RD must never be
+	// mistaken for a PVC's real restore pointer. The drilled PVC is
+	// recorded in AnnotationDrillSource instead.
+	LabelDrill = "pvc-plumber.io/drill"
+
+	// AnnotationDrillSource is "<namespace>/<pvc>" of the PVC a drill
+	// object restores. An annotation because the value contains '/'.
+	AnnotationDrillSource = "pvc-plumber.io/drill-source"
+
+	// LabelClone marks the RD and PVC of a restore-to-clone (value
+	// "true"). Like drill objects, clones carry neither
+	// LabelSourceNamespace nor LabelSourcePVC — a clone RD is not the
+	// source PVC's restore pointer and must never be reconciled as one.
+	// It is also the only label under which the executor accepts a PVC
+	// write at all (executor.IsClonePVC).
+	LabelClone = "pvc-plumber.io/clone"
+
+	// AnnotationCloneSource is "<namespace>/<pvc>" of the PVC a clone
+	// restores.
+	AnnotationCloneSource = "pvc-plumber.io/clone-source"
+
+	// AnnotationCloneID is the clone's request id, shared by its RD and
+	// PVC. It changes whenever the request (source, target, as-of)
+	// does.
+	AnnotationCloneID = "pvc-plumber.io/clone-id"
+
+	// AnnotationCloneExpiresAt is the RFC3339 time after which the
+	// operator deletes the clone's RD and PVC.
+	AnnotationCloneExpiresAt = "pvc-plumber.io/clone-expires-at"
+
+	// AnnotationBackupOnDemandUntil is set on an operator-owned RS while
+	// an on-demand backup (controller.OnDemandBackup) holds its trigger
+	// on a one-shot manual value. The RFC3339 value is the hold's
+	// deadline: before it, a second on-demand request is refused; after
+	// it, the reconciler treats a scheduled RS left without its schedule
+	// as drift and renders the schedule back. The annotation is what
+	// keeps a crash mid-backup from silently ending scheduled backups.
+	AnnotationBackupOnDemandUntil = "pvc-plumber.io/backup-on-demand-until"
+
+	// AnnotationBackupHookReplicas is set on an application Deployment
+	// or StatefulSet while a pre-backup-scale-to-zero hook holds it at
+	// zero. The value is the replica count to scale back to. It lives on
+	// the workload rather than in operator memory so an operator that
+	// dies mid-backup still scales the application back up on its next
+	// pass.
+	AnnotationBackupHookReplicas = "pvc-plumber.io/backup-hook-replicas"
 )
 
 // NamespacePrivilegedMoversLabel is the label that the operator and the
@@ -181,3 +310,30 @@ const NamespaceManagedLabel = "pvc-plumber.io/managed-namespace"
 func NamespaceManaged(nsLabels map[string]string) bool {
 	return nsLabels[NamespaceManagedLabel] == "true"
 }
+
+// NamespaceBackupSoftQuotaAnnotation sets a namespace's backup soft
+// quota, a resource quantity ("200Gi"). The usage report (package
+// inventory) compares it with the logical size of the namespace's latest
+// snapshots and, when it is exceeded, adds a warning note to every /audit
+// entry in the namespace. Soft: nothing is blocked or deleted.
+const NamespaceBackupSoftQuotaAnnotation = "pvc-plumber.io/backup-soft-quota"
+
+// OwnKeysEqual reports whether a and b agree on every KeyPrefix key,
+// ignoring all other keys. Pure; nil-safe.
+func OwnKeysEqual(a, b map[string]string) bool {
+	for k, v := range a {
+		if strings.HasPrefix(k, KeyPrefix) {
+			if bv, ok := b[k]; !ok || bv != v {
+				return false
+			}
+		}
+	}
+	for k := range b {
+		if strings.HasPrefix(k, KeyPrefix) {
+			if _, ok := a[k]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -1,11 +1,16 @@
 package labels
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
+	"path"
 	"strconv"
 	"strings"
 	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 // String forms of Tier. Private constants so the linter doesn't flag
@@ -56,6 +61,32 @@ func (t Tier) String() string {
 	}
 }
 
+// FreshnessWindow returns the maximum age the latest backup may have
+// before a PVC on this tier counts as overdue. Returns (0, false) for
+// tiers with no cadence to measure against (manual, disabled,
+// unspecified).
+//
+// Windows are tier-relative with cadence slack:
+//
+//	hourly  → 3h   (3x cadence)
+//	daily   → 48h  (2x cadence)
+//	weekly  → 216h ≈ 9d (1.3x cadence)
+//
+// Shared by the adopt freshness gate and the /audit freshness verdict
+// so the two can never disagree about what "overdue" means.
+func (t Tier) FreshnessWindow() (time.Duration, bool) {
+	switch t {
+	case TierHourly:
+		return 3 * time.Hour, true
+	case TierDaily:
+		return 48 * time.Hour, true
+	case TierWeekly:
+		return 9 * 24 * time.Hour, true
+	default:
+		return 0, false
+	}
+}
+
 // parseTier accepts "hourly", "daily", "weekly", "manual", or "disabled"
 // (case-insensitive). Empty string returns TierUnspecified, nil error.
 // Any other value returns an error.
@@ -172,10 +203,61 @@ type Spec struct {
 	MinBackupAge    time.Duration
 	MinBackupAgeSet bool
 
+	// Point-in-time restore selection, rendered into the RD's kopia
+	// block. Zero RestoreAsOf and zero RestorePrevious select the latest
+	// snapshot, which is also what an unset annotation means.
+	RestoreAsOf     time.Time
+	RestorePrevious int32
+
+	// Restore drill verification (AnnotationDrillVerify and friends).
+	// DrillVerify is always resolved: an unset annotation becomes
+	// file-count, or the mode implied by a manifest / probe annotation.
+	DrillVerify   DrillVerify
+	DrillManifest string
+	DrillProbe    string
+	DrillImage    string
+
+	// Restore-to-clone request (AnnotationCloneTo and friends).
+	// CloneName is empty when no clone is requested. CloneNamespace is
+	// empty when the annotation names no namespace, which means the
+	// PVC's own. Zero CloneTTL means the operator default.
+	CloneNamespace string
+	CloneName      string
+	CloneAsOf      time.Time
+	CloneTTL       time.Duration
+
+	// Pre/post backup hooks (AnnotationPreBackupExec and friends). Zero
+	// values mean no such hook; zero BackupHookTimeout means the
+	// operator default.
+	PreBackupExec     ExecHook
+	PostBackupExec    ExecHook
+	PreBackupScale    ScaleTarget
+	BackupHookTimeout time.Duration
+
 	// Accumulated parse errors (one per malformed key). Non-nil slice if any.
 	Errors []error
 }
 
+// DrillVerify is how the restore drill checks a restored volume.
+type DrillVerify string
+
+const (
+	// DrillVerifyFileCount passes when the restored volume holds at
+	// least one regular file. Catches the empty-restore failure (wrong
+	// identity, empty snapshot) without knowing anything about the app.
+	DrillVerifyFileCount DrillVerify = "file-count"
+
+	// DrillVerifyChecksumManifest runs `sha256sum -c` against
+	// Spec.DrillManifest on the restored volume.
+	DrillVerifyChecksumManifest DrillVerify = "checksum-manifest"
+
+	// DrillVerifyProbe runs Spec.DrillProbe.
+	DrillVerifyProbe DrillVerify = "probe"
+
+	// DrillVerifyNone excludes the PVC from restore drills.
+	DrillVerifyNone DrillVerify = "none"
+)
+
 // Parse builds a Spec from the metadata.labels and metadata.annotations of a
 // PVC. Both maps may be nil. The returned Spec is always usable; any parse
 // failures are appended to Spec.Errors and the field falls back to its zero
@@ -311,9 +393,93 @@ func Parse(pvcLabels, pvcAnnotations map[string]string) Spec {
 		}
 	}
 
+	parseRestoreSelection(&s, pvcAnnotations)
+	parseDrill(&s, pvcAnnotations)
+	parseClone(&s, pvcAnnotations)
+	parseBackupHooks(&s, pvcAnnotations)
+
 	return s
 }
 
+// HasRestoreSelection reports whether the PVC pins its restore to
+// something other than the latest snapshot.
+func (s Spec) HasRestoreSelection() bool {
+	return !s.RestoreAsOf.IsZero() || s.RestorePrevious > 0
+}
+
+// parseRestoreSelection reads the point-in-time restore annotations. A
+// malformed value records an error and selects nothing, so the PVC goes
+// to needs-human-review rather than silently restoring the latest
+// snapshot when the owner asked for an older one.
+func parseRestoreSelection(s *Spec, pvcAnnotations map[string]string) {
+	if v := strings.TrimSpace(pvcAnnotations[AnnotationRestoreAsOf]); v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err != nil {
+			s.Errors = append(s.Errors, fmt.Errorf("%s: %q is not an RFC3339 time (e.g. 2026-05-17T03:00:00Z)", AnnotationRestoreAsOf, v))
+		} else {
+			s.RestoreAsOf = t.UTC()
+		}
+	}
+	if v := strings.TrimSpace(pvcAnnotations[AnnotationRestorePrevious]); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 32); err != nil || n < 0 {
+			s.Errors = append(s.Errors, fmt.Errorf("%s: %q must be a non-negative integer", AnnotationRestorePrevious, v))
+		} else {
+			s.RestorePrevious = int32(n)
+		}
+	}
+}
+
+// parseDrill resolves the restore-drill annotations. A malformed value
+// records an error and leaves DrillVerify at none, so a typo'd drill
+// config surfaces on /audit instead of drilling with a check the owner
+// did not ask for.
+func parseDrill(s *Spec, pvcAnnotations map[string]string) {
+	s.DrillManifest = strings.TrimSpace(pvcAnnotations[AnnotationDrillManifest])
+	s.DrillProbe = strings.TrimSpace(pvcAnnotations[AnnotationDrillProbe])
+	s.DrillImage = strings.TrimSpace(pvcAnnotations[AnnotationDrillImage])
+
+	raw := strings.ToLower(strings.TrimSpace(pvcAnnotations[AnnotationDrillVerify]))
+	var v DrillVerify
+	switch {
+	case raw != "":
+		v = DrillVerify(raw)
+	case s.DrillManifest != "" && s.DrillProbe != "":
+		s.DrillVerify = DrillVerifyNone
+		s.Errors = append(s.Errors, fmt.Errorf("%s and %s are both set: choose one with %s",
+			AnnotationDrillManifest, AnnotationDrillProbe, AnnotationDrillVerify))
+		return
+	case s.DrillManifest != "":
+		v = DrillVerifyChecksumManifest
+	case s.DrillProbe != "":
+		v = DrillVerifyProbe
+	default:
+		v = DrillVerifyFileCount
+	}
+
+	var err error
+	switch v {
+	case DrillVerifyFileCount, DrillVerifyNone:
+	case DrillVerifyChecksumManifest:
+		switch {
+		case s.DrillManifest == "":
+			err = fmt.Errorf("%s=%s requires %s", AnnotationDrillVerify, v, AnnotationDrillManifest)
+		case path.IsAbs(s.DrillManifest) || !fs.ValidPath(path.Clean(s.DrillManifest)):
+			err = fmt.Errorf("%s: %q must be a relative path inside the volume", AnnotationDrillManifest, s.DrillManifest)
+		}
+	case DrillVerifyProbe:
+		if s.DrillProbe == "" {
+			err = fmt.Errorf("%s=%s requires %s", AnnotationDrillVerify, v, AnnotationDrillProbe)
+		}
+	default:
+		err = fmt.Errorf("%s: invalid value %q (expected file-count|checksum-manifest|probe|none)", AnnotationDrillVerify, raw)
+	}
+	if err != nil {
+		s.DrillVerify = DrillVerifyNone
+		s.Errors = append(s.Errors, err)
+		return
+	}
+	s.DrillVerify = v
+}
+
 // parseUserID parses a UID/GID/fsGroup annotation value. Range is the POSIX
 // reasonable range [0, 2^31-1]. Empty values return an error so the caller
 // can distinguish "unset" (no annotation key) from "set to empty" (typo).
@@ -339,3 +505,183 @@ func parseUserID(raw string) (int64, error) {
 func NamespaceHasPrivilegedMovers(nsLabels map[string]string) bool {
 	return strings.EqualFold(strings.TrimSpace(nsLabels[NamespacePrivilegedMoversLabel]), "true")
 }
+
+// HasClone reports whether the PVC requests a restore-to-clone.
+func (s Spec) HasClone() bool {
+	return s.CloneName != ""
+}
+
+// parseClone reads the restore-to-clone annotations. A malformed
+// request records an error and requests nothing: a clone restored from
+// the wrong point in time, or into a PVC the owner did not name, is
+// worse than none.
+func parseClone(s *Spec, pvcAnnotations map[string]string) {
+	raw := strings.TrimSpace(pvcAnnotations[AnnotationCloneTo])
+	if raw == "" {
+		for _, k := range []string{AnnotationCloneAsOf, AnnotationCloneTTL} {
+			if strings.TrimSpace(pvcAnnotations[k]) != "" {
+				s.Errors = append(s.Errors, fmt.Errorf("%s is set without %s", k, AnnotationCloneTo))
+			}
+		}
+		return
+	}
+	ns, name, qualified := strings.Cut(raw, "/")
+	if !qualified {
+		ns, name = "", raw
+	}
+	var errs []error
+	if qualified && len(validation.IsDNS1123Label(ns)) > 0 {
+		errs = append(errs, fmt.Errorf("%s: %q is not a valid namespace", AnnotationCloneTo, ns))
+	}
+	if len(validation.IsDNS1123Subdomain(name)) > 0 {
+		errs = append(errs, fmt.Errorf("%s: %q is not a valid PVC name", AnnotationCloneTo, name))
+	}
+	var asOf time.Time
+	if v := strings.TrimSpace(pvcAnnotations[AnnotationCloneAsOf]); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %q is not an RFC3339 time (e.g. 2026-05-17T03:00:00Z)", AnnotationCloneAsOf, v))
+		}
+		asOf = t.UTC()
+	}
+	var ttl time.Duration
+	if v := strings.TrimSpace(pvcAnnotations[AnnotationCloneTTL]); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("%s: %q must be a positive duration (e.g. 72h)", AnnotationCloneTTL, v))
+		}
+		ttl = d
+	}
+	if len(errs) > 0 {
+		s.Errors = append(s.Errors, errs...)
+		return
+	}
+	s.CloneNamespace, s.CloneName, s.CloneAsOf, s.CloneTTL = ns, name, asOf, ttl
+}
+
+// ExecHook is a command to run in a container of the pods mounting the
+// PVC. Command is a shell command line, run through /bin/sh -c; Argv is
+// an exec-form command run as is, for images without a shell. Exactly
+// one is set.
+type ExecHook struct {
+	Container string
+	Command   string
+	Argv      []string
+}
+
+// IsZero reports whether no exec hook is configured.
+func (h ExecHook) IsZero() bool { return h.Command == "" && len(h.Argv) == 0 }
+
+// Exec is the command line the hook runs in the container.
+func (h ExecHook) Exec() []string {
+	if len(h.Argv) > 0 {
+		return h.Argv
+	}
+	return []string{"/bin/sh", "-c", h.Command}
+}
+
+// ScaleTarget is a workload a pre-backup hook scales to zero. Kind is
+// "deployment" or "statefulset".
+type ScaleTarget struct {
+	Kind string
+	Name string
+}
+
+// IsZero reports whether no workload is named.
+func (t ScaleTarget) IsZero() bool { return t.Name == "" }
+
+// String renders the target as written in the annotation.
+func (t ScaleTarget) String() string { return t.Kind + "/" + t.Name }
+
+// Workload kinds AnnotationPreBackupScaleToZero accepts.
+const (
+	ScaleKindDeployment  = "deployment"
+	ScaleKindStatefulSet = "statefulset"
+)
+
+// HasBackupHooks reports whether the PVC configures any backup hook.
+func (s Spec) HasBackupHooks() bool {
+	return !s.PreBackupExec.IsZero() || !s.PostBackupExec.IsZero() || !s.PreBackupScale.IsZero()
+}
+
+// parseBackupHooks reads the backup hook annotations. Any malformed
+// value records an error and configures no hook at all: running a
+// pre-hook whose post-hook did not parse would leave the application
+// quiesced, and running only the post-hook would unquiesce something
+// nobody quiesced.
+func parseBackupHooks(s *Spec, pvcAnnotations map[string]string) {
+	var errs []error
+	pre, err := parseExecHook(AnnotationPreBackupExec, pvcAnnotations)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	post, err := parseExecHook(AnnotationPostBackupExec, pvcAnnotations)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	var scale ScaleTarget
+	if v := strings.TrimSpace(pvcAnnotations[AnnotationPreBackupScaleToZero]); v != "" {
+		kind, name, _ := strings.Cut(v, "/")
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		name = strings.TrimSpace(name)
+		switch {
+		case kind != ScaleKindDeployment && kind != ScaleKindStatefulSet:
+			errs = append(errs, fmt.Errorf("%s: %q must be deployment/<name> or statefulset/<name>", AnnotationPreBackupScaleToZero, v))
+		case len(validation.IsDNS1123Subdomain(name)) > 0:
+			errs = append(errs, fmt.Errorf("%s: %q is not a valid workload name", AnnotationPreBackupScaleToZero, name))
+		default:
+			scale = ScaleTarget{Kind: kind, Name: name}
+		}
+	}
+	// With the workload at zero there is no pod left to run the
+	// post-hook in.
+	if !scale.IsZero() && !post.IsZero() {
+		errs = append(errs, fmt.Errorf("%s cannot be combined with %s: no pod is running to exec into after the backup",
+			AnnotationPostBackupExec, AnnotationPreBackupScaleToZero))
+	}
+
+	var timeout time.Duration
+	if v := strings.TrimSpace(pvcAnnotations[AnnotationBackupHookTimeout]); v != "" {
+		d, err := time.ParseDuration(v)
+		switch {
+		case err != nil || d <= 0:
+			errs = append(errs, fmt.Errorf("%s: %q must be a positive duration (e.g. 2m)", AnnotationBackupHookTimeout, v))
+		case pre.IsZero() && post.IsZero() && scale.IsZero() && len(errs) == 0:
+			errs = append(errs, fmt.Errorf("%s is set without a backup hook", AnnotationBackupHookTimeout))
+		}
+		timeout = d
+	}
+	if len(errs) > 0 {
+		s.Errors = append(s.Errors, errs...)
+		return
+	}
+	s.PreBackupExec, s.PostBackupExec, s.PreBackupScale, s.BackupHookTimeout = pre, post, scale, timeout
+}
+
+// parseExecHook parses "<container>:<command>" under key. A command
+// that starts with "[" is the exec form, a JSON array of strings
+// (`app:["redis-cli","SAVE"]`); anything else is a shell command line.
+// Unset returns the zero hook.
+func parseExecHook(key string, pvcAnnotations map[string]string) (ExecHook, error) {
+	v := strings.TrimSpace(pvcAnnotations[key])
+	if v == "" {
+		return ExecHook{}, nil
+	}
+	container, command, ok := strings.Cut(v, ":")
+	container, command = strings.TrimSpace(container), strings.TrimSpace(command)
+	switch {
+	case !ok || command == "":
+		return ExecHook{}, fmt.Errorf("%s: %q must be <container>:<command>", key, v)
+	case len(validation.IsDNS1123Label(container)) > 0:
+		return ExecHook{}, fmt.Errorf("%s: %q is not a valid container name", key, container)
+	}
+	if !strings.HasPrefix(command, "[") {
+		return ExecHook{Container: container, Command: command}, nil
+	}
+	var argv []string
+	if err := json.Unmarshal([]byte(command), &argv); err != nil || len(argv) == 0 || argv[0] == "" {
+		return ExecHook{}, fmt.Errorf("%s: %q must be a non-empty JSON array of strings", key, command)
+	}
+	return ExecHook{Container: container, Argv: argv}, nil
+}
@@ -1,6 +1,7 @@
 package labels
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -330,6 +331,183 @@ func TestParse_MinBackupAge(t *testing.T) {
 	}
 }
 
+func TestParse_Drill(t *testing.T) {
+	cases := []struct {
+		name    string
+		anns    map[string]string
+		want    DrillVerify
+		wantErr bool
+	}{
+		{name: "unset → file-count", want: DrillVerifyFileCount},
+		{name: "opt out", anns: map[string]string{AnnotationDrillVerify: " None "}, want: DrillVerifyNone},
+		{name: "manifest implies checksum", anns: map[string]string{AnnotationDrillManifest: "meta/SHA256SUMS"}, want: DrillVerifyChecksumManifest},
+		{name: "probe implies probe", anns: map[string]string{AnnotationDrillProbe: "test -s db.sqlite3"}, want: DrillVerifyProbe},
+		{name: "explicit mode wins over implication", anns: map[string]string{
+			AnnotationDrillVerify: "file-count", AnnotationDrillProbe: "true"}, want: DrillVerifyFileCount},
+		{name: "manifest and probe without a mode", anns: map[string]string{
+			AnnotationDrillManifest: "SUMS", AnnotationDrillProbe: "true"}, want: DrillVerifyNone, wantErr: true},
+		{name: "checksum without manifest", anns: map[string]string{AnnotationDrillVerify: "checksum-manifest"}, want: DrillVerifyNone, wantErr: true},
+		{name: "absolute manifest", anns: map[string]string{AnnotationDrillManifest: "/etc/passwd"}, want: DrillVerifyNone, wantErr: true},
+		{name: "manifest escaping the volume", anns: map[string]string{AnnotationDrillManifest: "a/../../SUMS"}, want: DrillVerifyNone, wantErr: true},
+		{name: "probe without command", anns: map[string]string{AnnotationDrillVerify: "probe"}, want: DrillVerifyNone, wantErr: true},
+		{name: "unknown mode", anns: map[string]string{AnnotationDrillVerify: "md5"}, want: DrillVerifyNone, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := Parse(nil, tc.anns)
+			if s.DrillVerify != tc.want {
+				t.Errorf("DrillVerify: got %q, want %q", s.DrillVerify, tc.want)
+			}
+			if (len(s.Errors) > 0) != tc.wantErr {
+				t.Errorf("Errors: got %v, wantErr=%v", s.Errors, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestParse_RestoreSelection(t *testing.T) {
+	asOf := time.Date(2026, 5, 17, 3, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name     string
+		anns     map[string]string
+		asOf     time.Time
+		previous int32
+		wantErr  bool
+	}{
+		{name: "unset selects latest"},
+		{name: "as-of", anns: map[string]string{AnnotationRestoreAsOf: "2026-05-17T03:00:00Z"}, asOf: asOf},
+		{name: "as-of with offset is normalized to UTC", anns: map[string]string{AnnotationRestoreAsOf: " 2026-05-17T05:00:00+02:00 "}, asOf: asOf},
+		{name: "previous", anns: map[string]string{AnnotationRestorePrevious: "1"}, previous: 1},
+		{name: "both", anns: map[string]string{AnnotationRestoreAsOf: "2026-05-17T03:00:00Z", AnnotationRestorePrevious: "2"}, asOf: asOf, previous: 2},
+		{name: "previous zero is latest", anns: map[string]string{AnnotationRestorePrevious: "0"}},
+		{name: "date without time", anns: map[string]string{AnnotationRestoreAsOf: "2026-05-17"}, wantErr: true},
+		{name: "yesterday", anns: map[string]string{AnnotationRestoreAsOf: "yesterday 03:00"}, wantErr: true},
+		{name: "negative previous", anns: map[string]string{AnnotationRestorePrevious: "-1"}, wantErr: true},
+		{name: "non-numeric previous", anns: map[string]string{AnnotationRestorePrevious: "one"}, wantErr: true},
+		{name: "previous overflows int32", anns: map[string]string{AnnotationRestorePrevious: "4294967296"}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := Parse(nil, tc.anns)
+			if !s.RestoreAsOf.Equal(tc.asOf) || s.RestorePrevious != tc.previous {
+				t.Errorf("got as-of=%v previous=%d, want %v / %d", s.RestoreAsOf, s.RestorePrevious, tc.asOf, tc.previous)
+			}
+			if (len(s.Errors) > 0) != tc.wantErr {
+				t.Errorf("Errors: got %v, wantErr=%v", s.Errors, tc.wantErr)
+			}
+			if got, want := s.HasRestoreSelection(), !tc.asOf.IsZero() || tc.previous > 0; got != want {
+				t.Errorf("HasRestoreSelection: got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParse_Clone(t *testing.T) {
+	asOf := time.Date(2026, 5, 17, 3, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name    string
+		anns    map[string]string
+		ns      string
+		target  string
+		asOf    time.Time
+		ttl     time.Duration
+		wantErr bool
+	}{
+		{name: "unset requests nothing"},
+		{name: "same namespace", anns: map[string]string{AnnotationCloneTo: " data-restore "}, target: "data-restore"},
+		{name: "other namespace", anns: map[string]string{AnnotationCloneTo: "scratch/data-restore"}, ns: "scratch", target: "data-restore"},
+		{
+			name:   "as-of and ttl",
+			anns:   map[string]string{AnnotationCloneTo: "data-restore", AnnotationCloneAsOf: "2026-05-17T05:00:00+02:00", AnnotationCloneTTL: "72h"},
+			target: "data-restore", asOf: asOf, ttl: 72 * time.Hour,
+		},
+		{name: "invalid name", anns: map[string]string{AnnotationCloneTo: "Data_Restore"}, wantErr: true},
+		{name: "invalid namespace", anns: map[string]string{AnnotationCloneTo: "a/b/c"}, wantErr: true},
+		{name: "empty name", anns: map[string]string{AnnotationCloneTo: "scratch/"}, wantErr: true},
+		{name: "bad as-of", anns: map[string]string{AnnotationCloneTo: "x", AnnotationCloneAsOf: "2026-05-17"}, wantErr: true},
+		{name: "zero ttl", anns: map[string]string{AnnotationCloneTo: "x", AnnotationCloneTTL: "0s"}, wantErr: true},
+		{name: "ttl without target", anns: map[string]string{AnnotationCloneTTL: "1h"}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := Parse(nil, tc.anns)
+			if s.CloneNamespace != tc.ns || s.CloneName != tc.target || !s.CloneAsOf.Equal(tc.asOf) || s.CloneTTL != tc.ttl {
+				t.Errorf("got %q/%q as-of=%v ttl=%v", s.CloneNamespace, s.CloneName, s.CloneAsOf, s.CloneTTL)
+			}
+			if (len(s.Errors) > 0) != tc.wantErr {
+				t.Errorf("Errors: got %v, wantErr=%v", s.Errors, tc.wantErr)
+			}
+			if s.HasClone() != (tc.target != "") {
+				t.Errorf("HasClone: got %v", s.HasClone())
+			}
+		})
+	}
+}
+
+func TestParse_BackupHooks(t *testing.T) {
+	cases := []struct {
+		name    string
+		anns    map[string]string
+		pre     ExecHook
+		post    ExecHook
+		scale   ScaleTarget
+		timeout time.Duration
+		wantErr bool
+	}{
+		{name: "unset configures nothing"},
+		{
+			name: "exec pair",
+			anns: map[string]string{
+				AnnotationPreBackupExec:     "app: sqlite3 /data/app.db 'PRAGMA wal_checkpoint(TRUNCATE)'",
+				AnnotationPostBackupExec:    " app :touch /tmp/resumed",
+				AnnotationBackupHookTimeout: "90s",
+			},
+			pre:     ExecHook{Container: "app", Command: "sqlite3 /data/app.db 'PRAGMA wal_checkpoint(TRUNCATE)'"},
+			post:    ExecHook{Container: "app", Command: "touch /tmp/resumed"},
+			timeout: 90 * time.Second,
+		},
+		{
+			name:  "scale with a pre exec",
+			anns:  map[string]string{AnnotationPreBackupScaleToZero: "Deployment/web", AnnotationPreBackupExec: "web:sync"},
+			pre:   ExecHook{Container: "web", Command: "sync"},
+			scale: ScaleTarget{Kind: ScaleKindDeployment, Name: "web"},
+		},
+		{
+			name: "exec form",
+			anns: map[string]string{AnnotationPreBackupExec: `app: ["redis-cli", "SAVE"]`},
+			pre:  ExecHook{Container: "app", Argv: []string{"redis-cli", "SAVE"}},
+		},
+		{name: "exec form not an array of strings", anns: map[string]string{AnnotationPreBackupExec: `app:["redis-cli", 1]`}, wantErr: true},
+		{name: "exec form empty", anns: map[string]string{AnnotationPreBackupExec: "app:[]"}, wantErr: true},
+		{name: "statefulset", anns: map[string]string{AnnotationPreBackupScaleToZero: "statefulset/db"}, scale: ScaleTarget{Kind: ScaleKindStatefulSet, Name: "db"}},
+		{name: "no container", anns: map[string]string{AnnotationPreBackupExec: "sync"}, wantErr: true},
+		{name: "empty command", anns: map[string]string{AnnotationPreBackupExec: "app:  "}, wantErr: true},
+		{name: "bad container", anns: map[string]string{AnnotationPreBackupExec: "App_1:sync"}, wantErr: true},
+		{name: "bad kind", anns: map[string]string{AnnotationPreBackupScaleToZero: "daemonset/agent"}, wantErr: true},
+		{name: "bad workload name", anns: map[string]string{AnnotationPreBackupScaleToZero: "deployment/"}, wantErr: true},
+		{name: "scale with post exec", anns: map[string]string{AnnotationPreBackupScaleToZero: "deployment/web", AnnotationPostBackupExec: "web:true"}, wantErr: true},
+		// One malformed hook disables its well-formed partner too.
+		{name: "bad post disables pre", anns: map[string]string{AnnotationPreBackupExec: "app:lock", AnnotationPostBackupExec: "unlock"}, wantErr: true},
+		{name: "bad timeout", anns: map[string]string{AnnotationPreBackupExec: "app:sync", AnnotationBackupHookTimeout: "0s"}, wantErr: true},
+		{name: "timeout without hook", anns: map[string]string{AnnotationBackupHookTimeout: "1m"}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := Parse(nil, tc.anns)
+			if !reflect.DeepEqual(s.PreBackupExec, tc.pre) || !reflect.DeepEqual(s.PostBackupExec, tc.post) ||
+				s.PreBackupScale != tc.scale || s.BackupHookTimeout != tc.timeout {
+				t.Errorf("got pre=%+v post=%+v scale=%+v timeout=%v", s.PreBackupExec, s.PostBackupExec, s.PreBackupScale, s.BackupHookTimeout)
+			}
+			if (len(s.Errors) > 0) != tc.wantErr {
+				t.Errorf("Errors: got %v, wantErr=%v", s.Errors, tc.wantErr)
+			}
+			if want := !tc.pre.IsZero() || !tc.post.IsZero() || !tc.scale.IsZero(); s.HasBackupHooks() != want {
+				t.Errorf("HasBackupHooks: got %v, want %v", s.HasBackupHooks(), want)
+			}
+		})
+	}
+}
+
 func TestParse_FreeFormAnnotations(t *testing.T) {
 	anns := map[string]string{
 		AnnotationMode:           "  strict ",
@@ -562,3 +740,44 @@ func int64PtrEq(a, b *int64) bool {
 	}
 	return *a == *b
 }
+
+func TestOwnKeysEqual(t *testing.T) {
+	base := map[string]string{NamespaceManagedLabel: "true", "team": "a"}
+	cases := []struct {
+		name string
+		b    map[string]string
+		want bool
+	}{
+		{"identical", map[string]string{NamespaceManagedLabel: "true", "team": "a"}, true},
+		{"foreign key differs", map[string]string{NamespaceManagedLabel: "true", "team": "b"}, true},
+		{"own value differs", map[string]string{NamespaceManagedLabel: "false", "team": "a"}, false},
+		{"own key removed", map[string]string{"team": "a"}, false},
+		{"own key added", map[string]string{NamespaceManagedLabel: "true", KeyPrefix + "new": "x"}, false},
+	}
+	for _, tc := range cases {
+		if got := OwnKeysEqual(base, tc.b); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+	if !OwnKeysEqual(nil, map[string]string{"team": "a"}) {
+		t.Error("nil vs foreign-only must be equal")
+	}
+}
+
+func TestTierFreshnessWindow(t *testing.T) {
+	cases := map[Tier]time.Duration{
+		TierHourly: 3 * time.Hour,
+		TierDaily:  48 * time.Hour,
+		TierWeekly: 9 * 24 * time.Hour,
+	}
+	for tier, want := range cases {
+		if got, ok := tier.FreshnessWindow(); !ok || got != want {
+			t.Errorf("%s: got (%s, %v), want %s", tier, got, ok, want)
+		}
+	}
+	for _, tier := range []Tier{TierManual, TierDisabled, TierUnspecified} {
+		if _, ok := tier.FreshnessWindow(); ok {
+			t.Errorf("%s: must have no window", tier)
+		}
+	}
+}
@@ -175,3 +175,41 @@ func IdentityFor(namespace, pvcName, backupIdentityOverride string) KopiaIdentit
 	}
 	return KopiaIdentity{Username: pvcName, Hostname: namespace}
 }
+
+// Key is the identity's single-string form: "<hostname>/<username>" for
+// the default convention (so "<namespace>/<pvc>"), or the bare override.
+// It equals decision.IdentityFor for the same inputs, which is what
+// /audit reports as backup_identity, so one identity reads the same
+// everywhere.
+//
+// Key is a label, not a lineage: the override "b" in namespace ns and
+// the default ns/b have different keys but write the same kopia source.
+// Compare Lineage values to find PVCs that share snapshots.
+func (k KopiaIdentity) Key() string {
+	if k.Hostname == "" {
+		return k.Username
+	}
+	return k.Hostname + "/" + k.Username
+}
+
+// KopiaDataPath is the source path every v4 RS snapshots: the mover
+// mounts the PVC at /data.
+const KopiaDataPath = "/data"
+
+// SourceHost is the kopia hostname the RS's snapshots are recorded
+// under. An override leaves Hostname blank and VolSync's kopia mover
+// then falls back to the namespace, so the host does too.
+func (k KopiaIdentity) SourceHost(namespace string) string {
+	if k.Hostname == "" {
+		return namespace
+	}
+	return k.Hostname
+}
+
+// Lineage is the kopia source the RS writes to, in kopia's
+// "<username>@<hostname>:<path>" form — the same string
+// kopia.V4Source(namespace, k).String() renders. Two PVCs share a
+// snapshot lineage exactly when their Lineage values are equal.
+func (k KopiaIdentity) Lineage(namespace string) string {
+	return k.Username + "@" + k.SourceHost(namespace) + ":" + KopiaDataPath
+}
@@ -164,6 +164,7 @@ func TestIdentityFor(t *testing.T) {
 		override string
 		wantUser string
 		wantHost string
+		wantLine string
 	}{
 		{
 			name:     "default identity (ns/pvc convention)",
@@ -171,6 +172,7 @@ func TestIdentityFor(t *testing.T) {
 			pvc:      testPVCStorage,
 			wantUser: "storage",
 			wantHost: "open-webui",
+			wantLine: "storage@open-webui:/data",
 		},
 		{
 			name:     "override pins to opaque identity",
@@ -179,6 +181,7 @@ func TestIdentityFor(t *testing.T) {
 			override: "immich-library",
 			wantUser: "immich-library",
 			wantHost: "",
+			wantLine: "immich-library@immich-prod:/data",
 		},
 		{
 			name:     "empty override is treated as unset",
@@ -187,6 +190,7 @@ func TestIdentityFor(t *testing.T) {
 			override: "",
 			wantUser: "config",
 			wantHost: "jellyfin",
+			wantLine: "config@jellyfin:/data",
 		},
 	}
 	for _, tc := range cases {
@@ -198,6 +202,17 @@ func TestIdentityFor(t *testing.T) {
 			if id.Hostname != tc.wantHost {
 				t.Errorf("Hostname: got %q, want %q", id.Hostname, tc.wantHost)
 			}
+			// Key must match decision.IdentityFor's string form.
+			wantKey := tc.ns + "/" + tc.pvc
+			if tc.override != "" {
+				wantKey = tc.override
+			}
+			if got := id.Key(); got != wantKey {
+				t.Errorf("Key: got %q, want %q", got, wantKey)
+			}
+			if got := id.Lineage(tc.ns); got != tc.wantLine {
+				t.Errorf("Lineage: got %q, want %q", got, tc.wantLine)
+			}
 		})
 	}
 }
@@ -26,6 +26,8 @@
 //  3. Spec.Errors non-empty                        → NeedsHumanReview
 //  4. no opt-in (no enabled, no manage, no legacy) → SkippedNotOptedIn
 //  5. manage-volsync=true but enabled=false        → SkippedNotOptedIn + blocker
+//     5a. resolved backup identity shared with another
+//     known PVC (KnownIdentities)                  → NeedsHumanReview (zero ops)
 //     5b. write-eligible BUT namespace not managed     → SkippedNamespaceNotManaged
 //     (NamespaceManaged=false; suppresses ALL writes incl. tier=disabled)
 //  6. write-eligible (Enabled + ManageVolSync) AND namespace managed:
@@ -44,6 +46,8 @@
 //     d. unmanaged drifts                          → NeedsHumanReview
 //     e. operator-owned matches                    → AlreadyMatches
 //     f. operator-owned drifts                     → AlreadyMatches + note (can't update without gate)
+//  8. any AlreadyMatches from 6/7 whose live RS/RD
+//     mover is failing                             → MoverFailing + note (zero ops)
 //
 // The planner only ever produces operations on
 // ReplicationSource / ReplicationDestination kinds. A paranoia test in
@@ -54,11 +58,14 @@ package planner
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/mitchross/pvc-plumber/internal/v4/builder"
+	"github.com/mitchross/pvc-plumber/internal/v4/decision"
 	"github.com/mitchross/pvc-plumber/internal/v4/labels"
 	"github.com/mitchross/pvc-plumber/internal/v4/naming"
 )
@@ -88,6 +95,10 @@ const (
 	// ops. This is the namespace write gate (v4.0.1) that makes a DRY
 	// cluster-wide RS/RD write ClusterRoleBinding safe.
 	ActionSkippedNamespaceNotManaged ActionKind = "skipped-namespace-not-managed"
+	// ActionMoverFailing: the verdict would be AlreadyMatches, but the
+	// live RS or RD reports its VolSync mover failing
+	// (CurrentState.RSMoverFailure / RDMoverFailure). Zero ops.
+	ActionMoverFailing ActionKind = "mover-failing"
 )
 
 // OwnerClassification mirrors controller.OwnerClassification.
@@ -121,11 +132,32 @@ type CurrentState struct {
 	RSRepository string
 	RSSourcePVC  string
 	RSSchedule   string // optional; only populated if reconciler captured it
+	// RSOnDemandExpired is true when the live RS still carries an
+	// on-demand backup hold (labels.AnnotationBackupOnDemandUntil) whose
+	// deadline has passed — the backup that switched its trigger to
+	// manual never switched it back.
+	RSOnDemandExpired bool
+	// RSManualTrigger is the live spec.trigger.manual ("" when absent).
+	// Repairing a manual-tier RS's expired hold keeps it: a changed
+	// manual string fires a sync nobody asked for.
+	RSManualTrigger string
 
 	RDPresent    bool
 	RDName       string
 	RDManagedBy  string
 	RDRepository string
+	// RDRestoreAsOf / RDRestorePrevious are the live RD's
+	// spec.kopia.restoreAsOf / previous ("" / 0 when absent), compared
+	// against the PVC's point-in-time restore annotations.
+	RDRestoreAsOf     string
+	RDRestorePrevious int64
+
+	// RSMoverFailure / RDMoverFailure are non-empty when the live object's
+	// VolSync mover is failing; the value is a one-line reason for the
+	// note. Derived by the reconciler from .status — the planner does not
+	// read status itself.
+	RSMoverFailure string
+	RDMoverFailure string
 }
 
 // =============================================================================
@@ -198,6 +230,14 @@ type Inputs struct {
 	// unaffected (they never write regardless of this flag).
 	NamespaceManaged bool
 
+	// KnownIdentities is the reconciler's view of every other opted-in
+	// PVC's kopia lineage (see controller.IdentityIndex). Any entry,
+	// other than this PVC itself, whose Identity equals this PVC's
+	// decision.LineageFor is a collision: two PVCs would write
+	// snapshots into one kopia lineage and a restore would pull back the
+	// wrong data. Nil means "no other PVCs known", never "skip the check".
+	KnownIdentities []decision.IdentityRef
+
 	// Naming + shared-resource references.
 	NamingStrategy    naming.Strategy
 	DefaultRepoSecret string
@@ -278,6 +318,23 @@ func PlanFor(in Inputs) Plan {
 	// expected state and branch on write eligibility.
 	writeEligible := in.Spec.Enabled && in.Spec.ManageVolSync
 
+	// Duplicate backup identity. Two opted-in PVCs whose RSes write the
+	// same kopia source (decision.LineageFor — e.g. an override "b" in
+	// namespace ns next to the PVC ns/b) would interleave snapshots in
+	// one lineage; whichever restores next gets the other's data.
+	// Neither side is "right", so both go to needs-human-review with
+	// zero ops — ahead of the namespace gate so the collision is visible
+	// even while the namespace is closed, and ahead of every write
+	// branch so no RS/RD is created, repaired, or deleted for either PVC
+	// until the annotation is fixed.
+	lineage := decision.LineageFor(in.Namespace, in.PVCName, in.Spec.BackupIdentity)
+	if dups := decision.DuplicateIdentities(in.Namespace, in.PVCName, lineage, in.KnownIdentities); len(dups) > 0 {
+		return Plan{
+			Action:   ActionNeedsHumanReview,
+			Blockers: []string{duplicateIdentityBlocker(in.Namespace+"/"+in.PVCName, lineage, dups)},
+		}
+	}
+
 	// Namespace write gate (v4.0.1). A PVC may be fully opted in (both
 	// fuse labels + valid tier) yet live in a namespace that has NOT been
 	// opted in to operator management. In that case the operator must NOT
@@ -313,10 +370,32 @@ func PlanFor(in Inputs) Plan {
 		plan.Notes = append(plan.Notes,
 			"no pvc-plumber.io/tier label; defaulting to daily cadence — set the label explicitly")
 	}
+	plan = applyMoverFailure(plan, in.Current)
 	plan.Notes = append(plan.Notes, inertAnnotationNotes(in)...)
 	return plan
 }
 
+// applyMoverFailure turns an AlreadyMatches verdict into MoverFailing
+// when a live RS/RD reports its mover failing. Only AlreadyMatches is
+// rewritten: every other verdict already demands attention for a
+// configuration reason, and that reason must stay the headline (a
+// would-update may well be the fix for the failure). Ops are untouched —
+// AlreadyMatches never carries any.
+func applyMoverFailure(plan Plan, cur CurrentState) Plan {
+	if plan.Action != ActionAlreadyMatches {
+		return plan
+	}
+	if cur.RSPresent && cur.RSMoverFailure != "" {
+		plan.Action = ActionMoverFailing
+		plan.Notes = append(plan.Notes, "ReplicationSource "+cur.RSName+" mover failing: "+cur.RSMoverFailure)
+	}
+	if cur.RDPresent && cur.RDMoverFailure != "" {
+		plan.Action = ActionMoverFailing
+		plan.Notes = append(plan.Notes, "ReplicationDestination "+cur.RDName+" mover failing: "+cur.RDMoverFailure)
+	}
+	return plan
+}
+
 // inertAnnotationNotes discloses annotations the parser recognizes but
 // the v4 permissive reconciler does not enforce (their consumers —
 // sourcegate, decision engine, admission webhooks — only wire under
@@ -394,6 +473,20 @@ func planWriteEligible(in Inputs) Plan {
 		}
 		// Both children present. Check for spec drift.
 		if !shapeMatches(in) {
+			if manualHoldOnly(in) {
+				return Plan{
+					Action: ActionWouldUpdate,
+					Ops:    manualHoldRepairOps(in),
+					Notes:  []string{"expired on-demand backup hold on a manual-tier RS; removing the hold, keeping the live manual trigger"},
+				}
+			}
+			if restoreSelectionOnly(in) {
+				return Plan{
+					Action: ActionWouldUpdate,
+					Ops:    rdUpdateOps(in),
+					Notes:  []string{"point-in-time restore selection changed; updating the RD only"},
+				}
+			}
 			return Plan{
 				Action: ActionWouldUpdate,
 				Ops:    updateOps(in),
@@ -550,6 +643,19 @@ func writeGateBlocker(in Inputs) string {
 	}
 }
 
+// duplicateIdentityBlocker names every PVC sharing the identity, self
+// first then the others sorted, so both sides of a collision carry the
+// same list and an operator grepping /audit for either name finds it.
+func duplicateIdentityBlocker(self, lineage string, dups []decision.IdentityRef) string {
+	others := make([]string, 0, len(dups))
+	for _, d := range dups {
+		others = append(others, d.Namespace+"/"+d.PVCName)
+	}
+	sort.Strings(others)
+	return fmt.Sprintf("kopia lineage %q is written by %s and %s; snapshots would interleave — give all but one PVC a distinct %s annotation",
+		lineage, self, strings.Join(others, ", "), labels.AnnotationBackupIdentity)
+}
+
 // =============================================================================
 // Shape matching + Op construction
 // =============================================================================
@@ -558,7 +664,10 @@ func writeGateBlocker(in Inputs) string {
 // what the builder would produce for this PVC. Intentionally
 // conservative: missing RS/RD never matches; differing repository or
 // sourcePVC never matches. Schedule drift is only checked when
-// RSSchedule is non-empty (caller controls whether to populate it).
+// RSSchedule is non-empty (caller controls whether to populate it),
+// except that an RS abandoned by an on-demand backup (RSOnDemandExpired)
+// never matches: for a scheduled tier the update renders its schedule
+// back, for the manual tier it drops the hold (manualHoldRepairOps).
 func shapeMatches(in Inputs) bool {
 	if !in.Current.RSPresent || !in.Current.RDPresent {
 		return false
@@ -594,8 +703,10 @@ func shapeMatches(in Inputs) bool {
 			// leftover cron schedule is drift. This matters because
 			// ScheduleFor's manual fallback equals the daily cron, so a
 			// daily→manual flip would otherwise read as "matching" and
-			// never be repaired (2026-06-09 review).
-			if in.Current.RSSchedule != "" {
+			// never be repaired (2026-06-09 review). An expired
+			// on-demand hold is drift too: until it is removed every
+			// further on-demand backup of the RS is refused.
+			if in.Current.RSSchedule != "" || in.Current.RSOnDemandExpired {
 				return false
 			}
 		} else if in.Current.RSSchedule != "" {
@@ -603,6 +714,20 @@ func shapeMatches(in Inputs) bool {
 			if in.Current.RSSchedule != expectedSchedule {
 				return false
 			}
+		} else if in.Current.RSOnDemandExpired {
+			// An empty schedule is otherwise tolerated, but here it is
+			// the one-shot manual trigger of an on-demand backup that
+			// died before restoring the schedule.
+			return false
+		}
+		// Point-in-time restore selection: setting, changing or clearing
+		// the restore-as-of / restore-previous annotations must reach
+		// the RD (whose trigger is keyed to the selection, so the update
+		// re-runs the restore).
+		if in.Current.RDPresent &&
+			(in.Current.RDRestoreAsOf != builder.RestoreAsOf(in.Spec) ||
+				in.Current.RDRestorePrevious != int64(in.Spec.RestorePrevious)) {
+			return false
 		}
 	}
 	return true
@@ -632,15 +757,63 @@ func createOpsForMissing(in Inputs) []PlannedOp {
 	return ops
 }
 
+// manualHoldRepairOps returns a single RS update for a manual-tier RS
+// whose only drift is an expired on-demand hold. The builder's RS
+// carries no hold annotation, and the live manual trigger is kept in
+// place of the builder's seed, so the update removes the hold without
+// firing a sync.
+func manualHoldRepairOps(in Inputs) []PlannedOp {
+	return []PlannedOp{{Kind: OpUpdate, Resource: buildRSKeepingTrigger(in)}}
+}
+
+// buildRSKeepingTrigger renders the RS, keeping a manual-tier RS's live
+// spec.trigger.manual in place of the builder's seed. VolSync syncs
+// whenever that string changes, and on the manual tier a human owns it:
+// resetting a patched value to the seed would fire a sync nobody asked
+// for.
+func buildRSKeepingTrigger(in Inputs) *unstructured.Unstructured {
+	rs := builder.BuildRS(toBuilderInputs(in))
+	if in.Spec.Tier == labels.TierManual && in.Current.RSManualTrigger != "" {
+		_ = unstructured.SetNestedField(rs.Object, in.Current.RSManualTrigger, "spec", "trigger", "manual")
+	}
+	return rs
+}
+
+// manualHoldOnly reports whether the only drift on a manual-tier RS is an
+// expired on-demand hold.
+func manualHoldOnly(in Inputs) bool {
+	if in.Spec.Tier != labels.TierManual || !in.Current.RSOnDemandExpired {
+		return false
+	}
+	cleared := in
+	cleared.Current.RSOnDemandExpired = false
+	return shapeMatches(cleared)
+}
+
+// restoreSelectionOnly reports whether the only drift is the RD's
+// point-in-time restore selection.
+func restoreSelectionOnly(in Inputs) bool {
+	cleared := in
+	cleared.Current.RDRestoreAsOf = builder.RestoreAsOf(in.Spec)
+	cleared.Current.RDRestorePrevious = int64(in.Spec.RestorePrevious)
+	return shapeMatches(cleared)
+}
+
+// rdUpdateOps returns a single RD update, for drift the RS does not
+// share. Re-rendering the RS alongside would be a no-op at best.
+func rdUpdateOps(in Inputs) []PlannedOp {
+	return []PlannedOp{{Kind: OpUpdate, Resource: builder.BuildRD(toBuilderInputs(in))}}
+}
+
 // updateOps returns the full update plan for both RS and RD. The
 // executor is responsible for choosing the apply strategy
 // (server-side apply, patch, etc.); the planner only carries the
-// desired-state object.
+// desired-state object. A manual-tier RS keeps its live trigger (see
+// buildRSKeepingTrigger).
 func updateOps(in Inputs) []PlannedOp {
-	bin := toBuilderInputs(in)
 	return []PlannedOp{
-		{Kind: OpUpdate, Resource: builder.BuildRS(bin)},
-		{Kind: OpUpdate, Resource: builder.BuildRD(bin)},
+		{Kind: OpUpdate, Resource: buildRSKeepingTrigger(in)},
+		{Kind: OpUpdate, Resource: builder.BuildRD(toBuilderInputs(in))},
 	}
 }
 
@@ -10,6 +10,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/mitchross/pvc-plumber/internal/v4/builder"
+	"github.com/mitchross/pvc-plumber/internal/v4/decision"
 	"github.com/mitchross/pvc-plumber/internal/v4/labels"
 	"github.com/mitchross/pvc-plumber/internal/v4/naming"
 )
@@ -232,6 +233,73 @@ func TestPlanFor_ManageOnlyWithoutEnabled_SkippedWithBlocker(t *testing.T) {
 	}
 }
 
+// =============================================================================
+// Rule 5a: duplicate backup identity → NeedsHumanReview, zero ops
+// =============================================================================
+
+func TestPlanFor_DuplicateIdentity_NeedsHumanReviewNamesBoth(t *testing.T) {
+	in := withEnabledManage()
+	in.Spec.BackupIdentity = "shared"
+	lineage := "shared@" + tns + ":/data"
+	// Operator-owned drifted pair present: without the collision rule
+	// this would be WouldUpdate. The collision must win.
+	in.Owner = OwnerPVCPlumber
+	in.Current = CurrentState{RSPresent: true, RSName: tpvc, RSRepository: tdrifted, RDPresent: true, RDName: tpvc + "-dst", RDRepository: tshared}
+	in.KnownIdentities = []decision.IdentityRef{
+		{Namespace: tns, PVCName: tpvc, Identity: lineage}, // self
+		{Namespace: tns, PVCName: "other", Identity: lineage},
+		{Namespace: "unrelated", PVCName: "x", Identity: "x@unrelated:/data"},
+	}
+	got := PlanFor(in)
+	if got.Action != ActionNeedsHumanReview {
+		t.Fatalf("Action: got %q, want %q", got.Action, ActionNeedsHumanReview)
+	}
+	if len(got.Ops) != 0 {
+		t.Errorf("Ops: got %d, want 0", len(got.Ops))
+	}
+	if len(got.Blockers) != 1 || !containsAll(got.Blockers[0], tns+"/"+tpvc, tns+"/other", lineage, labels.AnnotationBackupIdentity) {
+		t.Errorf("blocker must name the identity and both PVCs: %v", got.Blockers)
+	}
+}
+
+func TestPlanFor_DuplicateIdentity_OverrideCollidesWithDefault(t *testing.T) {
+	// An override equal to another PVC's name in the same namespace: the
+	// RS writes <override>@<namespace>, that PVC's default lineage.
+	in := withEnabledManage()
+	in.Spec.BackupIdentity = "other"
+	in.KnownIdentities = []decision.IdentityRef{{Namespace: tns, PVCName: "other", Identity: decision.LineageFor(tns, "other", "")}}
+	if got := PlanFor(in); got.Action != ActionNeedsHumanReview {
+		t.Errorf("Action: got %q, want %q", got.Action, ActionNeedsHumanReview)
+	}
+}
+
+// The same override in another namespace is another lineage: the mover
+// records it under that namespace's hostname.
+func TestPlanFor_DuplicateIdentity_SameOverrideOtherNamespaceIsNotCollision(t *testing.T) {
+	in := withEnabledManage()
+	in.Spec.BackupIdentity = "shared"
+	in.KnownIdentities = []decision.IdentityRef{{Namespace: "elsewhere", PVCName: "b", Identity: decision.LineageFor("elsewhere", "b", "shared")}}
+	if got := PlanFor(in); got.Action != ActionWouldCreate {
+		t.Errorf("Action: got %q, want %q", got.Action, ActionWouldCreate)
+	}
+}
+
+func TestPlanFor_DuplicateIdentity_SelfOnlyIsNotCollision(t *testing.T) {
+	in := withEnabledManage()
+	in.KnownIdentities = []decision.IdentityRef{{Namespace: tns, PVCName: tpvc, Identity: decision.LineageFor(tns, tpvc, "")}}
+	if got := PlanFor(in); got.Action != ActionWouldCreate {
+		t.Errorf("Action: got %q, want %q", got.Action, ActionWouldCreate)
+	}
+}
+
+func TestPlanFor_DuplicateIdentity_NotOptedInIgnored(t *testing.T) {
+	in := baseInputs()
+	in.KnownIdentities = []decision.IdentityRef{{Namespace: tns, PVCName: "data", Identity: decision.LineageFor(tns, tpvc, "")}}
+	if got := PlanFor(in); got.Action != ActionSkippedNotOptedIn {
+		t.Errorf("Action: got %q, want %q", got.Action, ActionSkippedNotOptedIn)
+	}
+}
+
 // =============================================================================
 // Rule 6: write-eligible (Enabled + ManageVolSync)
 // =============================================================================
@@ -868,6 +936,148 @@ func TestPlanFor_ManualTier_NoCron_AlreadyMatches(t *testing.T) {
 	}
 }
 
+// An on-demand backup switches a scheduled RS to a one-shot manual
+// trigger and back. If it died in between, the expired hold makes the
+// schedule-less RS drift; a live hold does not. A manual-tier RS with an
+// expired hold is drift too (see TestPlanFor_ManualTier_ExpiredHold).
+func TestPlanFor_OnDemandHold(t *testing.T) {
+	cases := []struct {
+		name    string
+		tier    labels.Tier
+		expired bool
+		want    ActionKind
+	}{
+		{name: "scheduled, hold expired", tier: labels.TierDaily, expired: true, want: ActionWouldUpdate},
+		{name: "scheduled, hold live", tier: labels.TierDaily, want: ActionAlreadyMatches},
+		{name: "manual, hold live", tier: labels.TierManual, want: ActionAlreadyMatches},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			in := withEnabledManage()
+			in.Owner = OwnerPVCPlumber
+			in.Current = matchingCurrent(in, "pvc-plumber")
+			in.Spec.Tier = tc.tier
+			in.Current.RSSchedule = ""
+			in.Current.RSOnDemandExpired = tc.expired
+
+			plan := PlanFor(in)
+			if plan.Action != tc.want {
+				t.Fatalf("Action: got %q, want %q", plan.Action, tc.want)
+			}
+		})
+	}
+}
+
+// A manual-tier RS whose on-demand backup died keeps its expired hold,
+// and every later on-demand backup is refused while it is there. The
+// repair is a single RS update that drops the hold and keeps the live
+// manual trigger, so no sync fires.
+func TestPlanFor_ManualTier_ExpiredHold(t *testing.T) {
+	in := withEnabledManage()
+	in.Owner = OwnerPVCPlumber
+	in.Spec.Tier = labels.TierManual
+	in.Current = matchingCurrent(in, "pvc-plumber")
+	in.Current.RSSchedule = ""
+	in.Current.RSOnDemandExpired = true
+	in.Current.RSManualTrigger = "on-demand-20260517-030000"
+
+	plan := PlanFor(in)
+	if plan.Action != ActionWouldUpdate {
+		t.Fatalf("Action: got %q, want %q", plan.Action, ActionWouldUpdate)
+	}
+	if len(plan.Ops) != 1 || plan.Ops[0].Kind != OpUpdate || plan.Ops[0].Resource.GetKind() != kindRS {
+		t.Fatalf("Ops: got %+v, want one RS update", plan.Ops)
+	}
+	rs := plan.Ops[0].Resource
+	if got, _, _ := unstructured.NestedString(rs.Object, "spec", "trigger", "manual"); got != in.Current.RSManualTrigger {
+		t.Errorf("trigger.manual: got %q, want the live %q", got, in.Current.RSManualTrigger)
+	}
+	if _, ok := rs.GetAnnotations()[labels.AnnotationBackupOnDemandUntil]; ok {
+		t.Error("repair keeps the hold annotation")
+	}
+}
+
+// Setting, changing or clearing the point-in-time restore annotations
+// is RD drift on an operator-owned pair; an RD already carrying the
+// selection matches. Inline-argo RDs are never compared on it.
+func TestPlanFor_RestoreSelectionDrift(t *testing.T) {
+	asOf := time.Date(2026, 5, 17, 3, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name         string
+		owner        OwnerClassification
+		managedBy    string
+		specAsOf     time.Time
+		specPrevious int32
+		liveAsOf     string
+		livePrevious int64
+		want         ActionKind
+	}{
+		{name: "annotation added", owner: OwnerPVCPlumber, managedBy: "pvc-plumber", specAsOf: asOf, want: ActionWouldUpdate},
+		{name: "previous added", owner: OwnerPVCPlumber, managedBy: "pvc-plumber", specPrevious: 1, want: ActionWouldUpdate},
+		{name: "as-of changed", owner: OwnerPVCPlumber, managedBy: "pvc-plumber", specAsOf: asOf, liveAsOf: "2026-05-16T03:00:00Z", want: ActionWouldUpdate},
+		{name: "annotations cleared", owner: OwnerPVCPlumber, managedBy: "pvc-plumber", liveAsOf: "2026-05-17T03:00:00Z", livePrevious: 1, want: ActionWouldUpdate},
+		{name: "already selected", owner: OwnerPVCPlumber, managedBy: "pvc-plumber", specAsOf: asOf, specPrevious: 1, liveAsOf: "2026-05-17T03:00:00Z", livePrevious: 1, want: ActionAlreadyMatches},
+		{name: "inline-argo not compared", owner: OwnerInlineArgo, managedBy: "argocd", specAsOf: asOf, want: ActionAlreadyMatches},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			in := withEnabledManage()
+			in.Owner = tc.owner
+			in.Current = matchingCurrent(in, tc.managedBy)
+			in.Spec.RestoreAsOf, in.Spec.RestorePrevious = tc.specAsOf, tc.specPrevious
+			in.Current.RDRestoreAsOf, in.Current.RDRestorePrevious = tc.liveAsOf, tc.livePrevious
+
+			plan := PlanFor(in)
+			if plan.Action != tc.want {
+				t.Fatalf("Action: got %q, want %q", plan.Action, tc.want)
+			}
+		})
+	}
+}
+
+// A restore-selection change touches only the RD. Re-rendering the RS
+// with it would reset a manual-tier RS's human-patched trigger.manual to
+// the builder's seed and fire a sync nobody asked for.
+func TestPlanFor_RestoreSelectionDrift_UpdatesRDOnly(t *testing.T) {
+	in := withEnabledManage()
+	in.Owner = OwnerPVCPlumber
+	in.Spec.Tier = labels.TierManual
+	in.Current = matchingCurrent(in, "pvc-plumber")
+	in.Current.RSSchedule = ""
+	in.Current.RSManualTrigger = "backup-2026-05-17"
+	in.Spec.RestoreAsOf = time.Date(2026, 5, 17, 3, 0, 0, 0, time.UTC)
+
+	plan := PlanFor(in)
+	if plan.Action != ActionWouldUpdate {
+		t.Fatalf("Action: got %q, want %q", plan.Action, ActionWouldUpdate)
+	}
+	if len(plan.Ops) != 1 || plan.Ops[0].Kind != OpUpdate || plan.Ops[0].Resource.GetKind() != kindRD {
+		t.Fatalf("Ops: got %+v, want one RD update", plan.Ops)
+	}
+}
+
+// When the RS drifts too, both children are updated, and a manual-tier
+// RS still keeps its live trigger.
+func TestPlanFor_ManualTier_UpdateKeepsLiveTrigger(t *testing.T) {
+	in := withEnabledManage()
+	in.Owner = OwnerPVCPlumber
+	in.Spec.Tier = labels.TierManual
+	in.Current = matchingCurrent(in, "pvc-plumber")
+	in.Current.RSSchedule = ""
+	in.Current.RSManualTrigger = "backup-2026-05-17"
+	in.Current.RSRepository = "old-repo"
+	in.Spec.RestoreAsOf = time.Date(2026, 5, 17, 3, 0, 0, 0, time.UTC)
+
+	plan := PlanFor(in)
+	if plan.Action != ActionWouldUpdate || len(plan.Ops) != 2 {
+		t.Fatalf("plan: got %q %+v, want an RS and an RD update", plan.Action, plan.Ops)
+	}
+	rs := plan.Ops[0].Resource
+	if got, _, _ := unstructured.NestedString(rs.Object, "spec", "trigger", "manual"); got != in.Current.RSManualTrigger {
+		t.Errorf("trigger.manual: got %q, want the live %q", got, in.Current.RSManualTrigger)
+	}
+}
+
 // A write-eligible PVC with no tier label gets the daily default — but
 // /audit must SAY so (2026-06-09 review: silent defaults are traps).
 func TestPlanFor_UnspecifiedTier_NotesDefault(t *testing.T) {
@@ -937,3 +1147,62 @@ func TestPlanFor_NoV5Annotations_NoInertNotes(t *testing.T) {
 		}
 	}
 }
+
+// =============================================================================
+// Rule 8: mover failing on an otherwise-matching RS/RD
+// =============================================================================
+
+func TestPlanFor_MatchingButRSMoverFailing_MoverFailing(t *testing.T) {
+	in := withEnabledManage()
+	in.Owner = OwnerPVCPlumber
+	in.Current = matchingCurrent(in, "pvc-plumber")
+	in.Current.RSMoverFailure = "repository not initialized"
+	got := PlanFor(in)
+	if got.Action != ActionMoverFailing {
+		t.Fatalf("Action: got %q, want %q", got.Action, ActionMoverFailing)
+	}
+	if len(got.Ops) != 0 {
+		t.Errorf("mover-failing must plan zero ops, got %d", len(got.Ops))
+	}
+	found := false
+	for _, n := range got.Notes {
+		if strings.Contains(n, "ReplicationSource "+tpvc) && strings.Contains(n, "repository not initialized") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("notes must name the RS and the reason: %v", got.Notes)
+	}
+}
+
+func TestPlanFor_LegacyMatchingButRDMoverFailing_MoverFailing(t *testing.T) {
+	in := withLegacyOnly()
+	in.Owner = OwnerInlineArgo
+	in.Current = matchingCurrent(in, "argocd")
+	in.Current.RDMoverFailure = "latest mover result: Failed"
+	if got := PlanFor(in); got.Action != ActionMoverFailing {
+		t.Errorf("Action: got %q, want %q", got.Action, ActionMoverFailing)
+	}
+}
+
+func TestPlanFor_DriftWinsOverMoverFailing(t *testing.T) {
+	in := withEnabledManage()
+	in.Owner = OwnerPVCPlumber
+	in.Current = driftedCurrent(in, "pvc-plumber")
+	in.Current.RSMoverFailure = "repository not initialized"
+	got := PlanFor(in)
+	if got.Action != ActionWouldUpdate {
+		t.Errorf("Action: got %q, want %q (drift stays the headline)", got.Action, ActionWouldUpdate)
+	}
+}
+
+func TestPlanFor_MoverFailureOnAbsentObjectIgnored(t *testing.T) {
+	in := withEnabledManage()
+	in.Owner = OwnerPVCPlumber
+	in.Current = matchingCurrent(in, "pvc-plumber")
+	in.Current.RDPresent = false
+	in.Current.RDMoverFailure = "stale"
+	if got := PlanFor(in); got.Action == ActionMoverFailing {
+		t.Errorf("failure on an absent RD must not be reported: %+v", got)
+	}
+}
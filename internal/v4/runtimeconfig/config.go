@@ -17,6 +17,9 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"github.com/mitchross/pvc-plumber/internal/v4/mode"
 )
@@ -38,6 +41,103 @@ const (
 	EnvDefaultFSGroup       = "PVC_PLUMBER_DEFAULT_FSGROUP"
 )
 
+// Env var names for the executor write journal. PVC_PLUMBER_JOURNAL_SINKS
+// is a comma-separated subset of {file, stdout, configmap}; unset means
+// the journal only keeps its in-memory ring (still served on /journal,
+// lost on restart). The remaining knobs only matter when their sink is
+// selected.
+const (
+	EnvJournalSinks               = "PVC_PLUMBER_JOURNAL_SINKS"
+	EnvJournalFile                = "PVC_PLUMBER_JOURNAL_FILE"
+	EnvJournalFileMaxBytes        = "PVC_PLUMBER_JOURNAL_FILE_MAX_BYTES"
+	EnvJournalFileMaxFiles        = "PVC_PLUMBER_JOURNAL_FILE_MAX_FILES"
+	EnvJournalConfigMap           = "PVC_PLUMBER_JOURNAL_CONFIGMAP"
+	EnvJournalConfigMapMaxEntries = "PVC_PLUMBER_JOURNAL_CONFIGMAP_MAX_ENTRIES"
+)
+
+// Env var names for the orphaned-child sweep (controller.OrphanReaper).
+// Detection always runs in v4 modes; PVC_PLUMBER_ORPHAN_REAP=true
+// additionally deletes orphaned ReplicationSources once they have been
+// orphaned for PVC_PLUMBER_ORPHAN_GRACE. Durations use Go syntax
+// ("168h", "30m"); unset means the controller package default.
+const (
+	EnvOrphanReap         = "PVC_PLUMBER_ORPHAN_REAP"
+	EnvOrphanGrace        = "PVC_PLUMBER_ORPHAN_GRACE"
+	EnvOrphanScanInterval = "PVC_PLUMBER_ORPHAN_SCAN_INTERVAL"
+)
+
+// EnvInventory enables GET /audit/inventory, the kopia-lineage vs PVC
+// cross-reference (package inventory). Off by default: it is the one v4
+// endpoint that reads the backup repository, so it needs the kopia
+// settings (BACKEND_TYPE=kopia-s3 or kopia-fs and their KOPIA_* vars)
+// that v4 modes otherwise never load.
+const EnvInventory = "PVC_PLUMBER_INVENTORY"
+
+// EnvUsage enables the backup usage report: GET /audit/usage, its
+// gauges at /audit/usage/metrics, and soft-quota notes on /audit
+// entries (inventory.UsageTracker). Off by default for the same reason
+// as EnvInventory; when on, the repository is scanned every
+// RE_WARM_INTERVAL rather than per request.
+const EnvUsage = "PVC_PLUMBER_USAGE"
+
+// Env var names for the restore drill (controller.DrillRunner). Setting
+// PVC_PLUMBER_DRILL_NAMESPACE turns drills on: the sandbox namespace is
+// the only place the drill writes, and it needs the kopia repository
+// Secret the drilled RDs reference. Durations use Go syntax; unset means
+// the controller package default. The image runs the verification Job.
+const (
+	EnvDrillNamespace = "PVC_PLUMBER_DRILL_NAMESPACE"
+	EnvDrillInterval  = "PVC_PLUMBER_DRILL_INTERVAL"
+	EnvDrillTimeout   = "PVC_PLUMBER_DRILL_TIMEOUT"
+	EnvDrillImage     = "PVC_PLUMBER_DRILL_IMAGE"
+)
+
+// Env var names for restore-to-clone (controller.CloneRunner). The
+// runner always runs in v4 modes; these only tune it. The TTL applies to
+// clones whose source PVC sets no pvc-plumber.io/clone-ttl. Durations
+// use Go syntax; unset means the controller package default.
+const (
+	EnvCloneDefaultTTL   = "PVC_PLUMBER_CLONE_DEFAULT_TTL"
+	EnvCloneScanInterval = "PVC_PLUMBER_CLONE_SCAN_INTERVAL"
+)
+
+// Env var names for on-demand backups (POST /backup/{namespace}/{pvc},
+// controller.OnDemandBackup). The endpoint is mounted only when the
+// token file is set: it is the one write route on the v4 HTTP server,
+// and an unauthenticated write route is never an option. The file is
+// normally a mounted Secret key. The timeout bounds the wait for the
+// sync; unset means the controller package default.
+const (
+	EnvBackupAPITokenFile = "PVC_PLUMBER_BACKUP_API_TOKEN_FILE"
+	EnvBackupTimeout      = "PVC_PLUMBER_BACKUP_TIMEOUT"
+)
+
+// Env var names for pre/post backup hooks (controller.HookRunner).
+// PVC_PLUMBER_BACKUP_HOOKS=true turns them on. Off by default: a hook
+// execs into application pods and scales application workloads, which
+// needs RBAC the operator otherwise never asks for. The timeout bounds
+// one hook, the lead is how long before the RS's nextSyncTime the
+// pre-hooks start, and the max hold bounds how long the application
+// stays quiesced waiting for the sync. Durations use Go syntax; unset
+// means the controller package default.
+const (
+	EnvBackupHooks       = "PVC_PLUMBER_BACKUP_HOOKS"
+	EnvBackupHookTimeout = "PVC_PLUMBER_BACKUP_HOOK_TIMEOUT"
+	EnvBackupHookLead    = "PVC_PLUMBER_BACKUP_HOOK_LEAD"
+	EnvBackupHookMaxHold = "PVC_PLUMBER_BACKUP_HOOK_MAX_HOLD"
+)
+
+// Journal sink names accepted in PVC_PLUMBER_JOURNAL_SINKS.
+const (
+	JournalSinkFile      = "file"
+	JournalSinkStdout    = "stdout"
+	JournalSinkConfigMap = "configmap"
+)
+
+// DefaultJournalFile is the file sink path when PVC_PLUMBER_JOURNAL_FILE
+// is unset. Mount a volume there for the journal to survive restarts.
+const DefaultJournalFile = "/var/lib/pvc-plumber/journal.jsonl"
+
 // Config is the resolved runtime configuration. Add fields here as the
 // operator gains more env-driven knobs; Phase 2.5 added Mode and Patch
 // 6.8a added the six v4 write-mode defaults.
@@ -76,6 +176,54 @@ type Config struct {
 	DefaultUID           *int64
 	DefaultGID           *int64
 	DefaultFSGroup       *int64
+
+	// Executor write journal. JournalSinks holds the validated, de-
+	// duplicated sink names in the order given; invalid names are
+	// dropped with a warning. Zero-valued size knobs mean "use the
+	// journal package default". JournalConfigMap is "namespace/name";
+	// the configmap sink is dropped (with a warning) when it is not.
+	JournalSinks               []string
+	JournalFile                string
+	JournalFileMaxBytes        int64
+	JournalFileMaxFiles        int
+	JournalConfigMap           string
+	JournalConfigMapMaxEntries int
+
+	// Orphaned-child sweep. OrphanReap defaults to false (report only).
+	// Zero durations mean "use the controller package default".
+	OrphanReap         bool
+	OrphanGrace        time.Duration
+	OrphanScanInterval time.Duration
+
+	// Inventory mounts /audit/inventory. Defaults to false.
+	Inventory bool
+
+	// Usage runs the backup usage tracker. Defaults to false.
+	Usage bool
+
+	// Restore drill. An empty DrillNamespace disables drills; zero
+	// durations and an empty image mean the controller package default.
+	DrillNamespace string
+	DrillInterval  time.Duration
+	DrillTimeout   time.Duration
+	DrillImage     string
+
+	// Restore-to-clone. Zero durations mean the controller package
+	// default.
+	CloneDefaultTTL   time.Duration
+	CloneScanInterval time.Duration
+
+	// On-demand backup API. An empty token file leaves POST /backup
+	// unmounted; a zero timeout means the controller package default.
+	BackupAPITokenFile string
+	BackupTimeout      time.Duration
+
+	// Pre/post backup hooks. BackupHooks defaults to false; zero
+	// durations mean the controller package default.
+	BackupHooks       bool
+	BackupHookTimeout time.Duration
+	BackupHookLead    time.Duration
+	BackupHookMaxHold time.Duration
 }
 
 // ModeSource classifies where the effective Mode came from.
@@ -183,6 +331,13 @@ func Load() (Config, error) {
 		cfg.DefaultFSGroup = v
 	}
 
+	errs = append(errs, loadJournalConfig(&cfg)...)
+	errs = append(errs, loadOrphanConfig(&cfg)...)
+	errs = append(errs, loadDrillConfig(&cfg)...)
+	errs = append(errs, loadCloneConfig(&cfg)...)
+	errs = append(errs, loadBackupConfig(&cfg)...)
+	errs = append(errs, loadBackupHookConfig(&cfg)...)
+
 	switch len(errs) {
 	case 0:
 		return cfg, nil
@@ -217,6 +372,233 @@ func parseNonNegInt64Env(key string) (*int64, error) {
 	return &v, nil
 }
 
+// loadJournalConfig fills the journal fields of cfg. Like the rest of
+// Load it never fails: a bad value degrades to the default (or drops
+// the offending sink) and is reported as a warning.
+func loadJournalConfig(cfg *Config) []error {
+	var errs []error
+
+	seen := map[string]bool{}
+	for _, raw := range strings.Split(os.Getenv(EnvJournalSinks), ",") {
+		name := strings.ToLower(strings.TrimSpace(raw))
+		if name == "" || seen[name] {
+			continue
+		}
+		switch name {
+		case JournalSinkFile, JournalSinkStdout, JournalSinkConfigMap:
+			seen[name] = true
+			cfg.JournalSinks = append(cfg.JournalSinks, name)
+		default:
+			errs = append(errs, fmt.Errorf("invalid %s entry %q: must be one of %s, %s, %s (ignored)",
+				EnvJournalSinks, raw, JournalSinkFile, JournalSinkStdout, JournalSinkConfigMap))
+		}
+	}
+
+	cfg.JournalFile = strings.TrimSpace(os.Getenv(EnvJournalFile))
+	if cfg.JournalFile == "" {
+		cfg.JournalFile = DefaultJournalFile
+	}
+	if v, err := parseNonNegInt64Env(EnvJournalFileMaxBytes); err != nil {
+		errs = append(errs, err)
+	} else if v != nil {
+		cfg.JournalFileMaxBytes = *v
+	}
+	if v, err := parseNonNegInt64Env(EnvJournalFileMaxFiles); err != nil {
+		errs = append(errs, err)
+	} else if v != nil {
+		cfg.JournalFileMaxFiles = int(*v)
+	}
+	if v, err := parseNonNegInt64Env(EnvJournalConfigMapMaxEntries); err != nil {
+		errs = append(errs, err)
+	} else if v != nil {
+		cfg.JournalConfigMapMaxEntries = int(*v)
+	}
+
+	cfg.JournalConfigMap = strings.TrimSpace(os.Getenv(EnvJournalConfigMap))
+	if seen[JournalSinkConfigMap] {
+		if _, _, ok := SplitNamespacedName(cfg.JournalConfigMap); !ok {
+			errs = append(errs, fmt.Errorf("invalid %s=%q: must be namespace/name when %s includes %s (configmap sink disabled)",
+				EnvJournalConfigMap, cfg.JournalConfigMap, EnvJournalSinks, JournalSinkConfigMap))
+			kept := cfg.JournalSinks[:0]
+			for _, s := range cfg.JournalSinks {
+				if s != JournalSinkConfigMap {
+					kept = append(kept, s)
+				}
+			}
+			cfg.JournalSinks = kept
+		}
+	}
+	return errs
+}
+
+// loadOrphanConfig fills the orphan-sweep fields of cfg, and the
+// inventory switch that reports the repository-side orphans. A malformed
+// boolean leaves its feature off; a malformed duration falls back to the
+// default. Both are reported as warnings.
+func loadOrphanConfig(cfg *Config) []error {
+	var errs []error
+	if raw := strings.TrimSpace(os.Getenv(EnvOrphanReap)); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s=%q: not a boolean (reaping disabled)", EnvOrphanReap, raw))
+		} else {
+			cfg.OrphanReap = v
+		}
+	}
+	if v, err := parsePositiveDurationEnv(EnvOrphanGrace); err != nil {
+		errs = append(errs, err)
+	} else {
+		cfg.OrphanGrace = v
+	}
+	if v, err := parsePositiveDurationEnv(EnvOrphanScanInterval); err != nil {
+		errs = append(errs, err)
+	} else {
+		cfg.OrphanScanInterval = v
+	}
+	if raw := strings.TrimSpace(os.Getenv(EnvInventory)); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s=%q: not a boolean (inventory disabled)", EnvInventory, raw))
+		} else {
+			cfg.Inventory = v
+		}
+	}
+	if raw := strings.TrimSpace(os.Getenv(EnvUsage)); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s=%q: not a boolean (usage report disabled)", EnvUsage, raw))
+		} else {
+			cfg.Usage = v
+		}
+	}
+	return errs
+}
+
+// loadDrillConfig fills the restore-drill fields of cfg. A namespace
+// that is not a valid name leaves drills off; a malformed duration falls
+// back to the default. Both are reported as warnings.
+func loadDrillConfig(cfg *Config) []error {
+	var errs []error
+	if ns := strings.TrimSpace(os.Getenv(EnvDrillNamespace)); ns != "" {
+		if msgs := validation.IsDNS1123Label(ns); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid %s=%q: %s (restore drills disabled)", EnvDrillNamespace, ns, strings.Join(msgs, "; ")))
+		} else {
+			cfg.DrillNamespace = ns
+		}
+	}
+	if v, err := parsePositiveDurationEnv(EnvDrillInterval); err != nil {
+		errs = append(errs, err)
+	} else {
+		cfg.DrillInterval = v
+	}
+	if v, err := parsePositiveDurationEnv(EnvDrillTimeout); err != nil {
+		errs = append(errs, err)
+	} else {
+		cfg.DrillTimeout = v
+	}
+	cfg.DrillImage = strings.TrimSpace(os.Getenv(EnvDrillImage))
+	return errs
+}
+
+// loadCloneConfig fills the restore-to-clone fields of cfg. A malformed
+// duration falls back to the default and is reported as a warning.
+func loadCloneConfig(cfg *Config) []error {
+	var errs []error
+	if v, err := parsePositiveDurationEnv(EnvCloneDefaultTTL); err != nil {
+		errs = append(errs, err)
+	} else {
+		cfg.CloneDefaultTTL = v
+	}
+	if v, err := parsePositiveDurationEnv(EnvCloneScanInterval); err != nil {
+		errs = append(errs, err)
+	} else {
+		cfg.CloneScanInterval = v
+	}
+	return errs
+}
+
+// loadBackupConfig fills the on-demand backup fields of cfg. The token
+// file is not opened here — the endpoint reads it per request, so a
+// Secret mounted after startup, or rotated, still works. A malformed
+// timeout falls back to the default and is reported as a warning.
+func loadBackupConfig(cfg *Config) []error {
+	var errs []error
+	cfg.BackupAPITokenFile = strings.TrimSpace(os.Getenv(EnvBackupAPITokenFile))
+	if v, err := parsePositiveDurationEnv(EnvBackupTimeout); err != nil {
+		errs = append(errs, err)
+	} else {
+		cfg.BackupTimeout = v
+	}
+	return errs
+}
+
+// loadBackupHookConfig fills the backup hook fields of cfg. A malformed
+// switch leaves hooks off and a malformed duration falls back to the
+// default; both are reported as warnings.
+func loadBackupHookConfig(cfg *Config) []error {
+	var errs []error
+	if raw := strings.TrimSpace(os.Getenv(EnvBackupHooks)); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s=%q: not a boolean (backup hooks disabled)", EnvBackupHooks, raw))
+		} else {
+			cfg.BackupHooks = v
+		}
+	}
+	for _, d := range []struct {
+		key string
+		dst *time.Duration
+	}{
+		{EnvBackupHookTimeout, &cfg.BackupHookTimeout},
+		{EnvBackupHookLead, &cfg.BackupHookLead},
+		{EnvBackupHookMaxHold, &cfg.BackupHookMaxHold},
+	} {
+		if v, err := parsePositiveDurationEnv(d.key); err != nil {
+			errs = append(errs, err)
+		} else {
+			*d.dst = v
+		}
+	}
+	return errs
+}
+
+// parsePositiveDurationEnv returns 0 for an unset variable and an error
+// for anything that is not a positive Go duration.
+func parsePositiveDurationEnv(key string) (time.Duration, error) {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s=%q: not a valid duration: %w (using default)", key, raw, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid %s=%q: must be positive (using default)", key, raw)
+	}
+	return d, nil
+}
+
+// SplitNamespacedName splits "namespace/name". ok is false unless both
+// halves are non-empty.
+func SplitNamespacedName(s string) (namespace, name string, ok bool) {
+	namespace, name, found := strings.Cut(s, "/")
+	if !found || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", false
+	}
+	return namespace, name, true
+}
+
+// HasJournalSink reports whether name is among the configured sinks.
+func (c Config) HasJournalSink(name string) bool {
+	for _, s := range c.JournalSinks {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
 // RequireV4WriteDefaults enforces the Patch 6.8a contract: in
 // permissive mode, all six PVC_PLUMBER_DEFAULT_* env vars must be set
 // to non-empty / non-zero values before the operator binary will start.
@@ -3,6 +3,7 @@ package runtimeconfig
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/mitchross/pvc-plumber/internal/v4/mode"
 )
@@ -600,3 +601,254 @@ func TestRequireV4WriteDefaults_EnforceStrictNoError(t *testing.T) {
 // int64Ptr is a small test helper because Go has no &literal for
 // numeric types.
 func int64Ptr(v int64) *int64 { return &v }
+
+// TestLoad_JournalConfig covers the executor write journal knobs: sink
+// list parsing (case-insensitive, de-duplicated, unknown names dropped
+// with a warning), the file default, and the configmap sink being
+// dropped when PVC_PLUMBER_JOURNAL_CONFIGMAP is not namespace/name.
+func TestLoad_JournalConfig(t *testing.T) {
+	cases := []struct {
+		name      string
+		sinks     string
+		configMap string
+		wantSinks []string
+		wantErr   bool
+	}{
+		{name: "unset → ring only", wantSinks: nil},
+		{name: "file + stdout", sinks: "file, STDOUT,file", wantSinks: []string{JournalSinkFile, JournalSinkStdout}},
+		{name: "configmap with target", sinks: "configmap", configMap: "pvc-plumber/pvc-plumber-journal", wantSinks: []string{JournalSinkConfigMap}},
+		{name: "configmap without target dropped", sinks: "stdout,configmap", wantSinks: []string{JournalSinkStdout}, wantErr: true},
+		{name: "unknown sink dropped", sinks: "syslog,stdout", wantSinks: []string{JournalSinkStdout}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(EnvKey, "")
+			unsetDefaultsFixture(t)
+			t.Setenv(EnvJournalSinks, tc.sinks)
+			t.Setenv(EnvJournalConfigMap, tc.configMap)
+			t.Setenv(EnvJournalFile, "")
+
+			cfg, err := Load()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("err: got %v, wantErr=%v", err, tc.wantErr)
+			}
+			if len(cfg.JournalSinks) != len(tc.wantSinks) {
+				t.Fatalf("JournalSinks: got %v, want %v", cfg.JournalSinks, tc.wantSinks)
+			}
+			for i := range tc.wantSinks {
+				if cfg.JournalSinks[i] != tc.wantSinks[i] {
+					t.Errorf("JournalSinks[%d]: got %q, want %q", i, cfg.JournalSinks[i], tc.wantSinks[i])
+				}
+			}
+			if cfg.JournalFile != DefaultJournalFile {
+				t.Errorf("JournalFile: got %q, want default %q", cfg.JournalFile, DefaultJournalFile)
+			}
+		})
+	}
+}
+
+func TestLoad_OrphanConfig(t *testing.T) {
+	cases := []struct {
+		name                string
+		reap, grace, every  string
+		wantReap            bool
+		wantGrace, wantTick time.Duration
+		wantErr             bool
+	}{
+		{name: "unset → report only, package defaults"},
+		{name: "reap with grace", reap: "true", grace: "72h", every: "5m", wantReap: true, wantGrace: 72 * time.Hour, wantTick: 5 * time.Minute},
+		{name: "malformed reap stays off", reap: "yes please", wantErr: true},
+		{name: "negative grace → default", reap: "true", grace: "-1h", wantReap: true, wantErr: true},
+		{name: "garbage interval → default", every: "often", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(EnvKey, "")
+			unsetDefaultsFixture(t)
+			t.Setenv(EnvJournalSinks, "")
+			t.Setenv(EnvOrphanReap, tc.reap)
+			t.Setenv(EnvOrphanGrace, tc.grace)
+			t.Setenv(EnvOrphanScanInterval, tc.every)
+
+			cfg, err := Load()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("err: got %v, wantErr=%v", err, tc.wantErr)
+			}
+			if cfg.OrphanReap != tc.wantReap || cfg.OrphanGrace != tc.wantGrace || cfg.OrphanScanInterval != tc.wantTick {
+				t.Errorf("got reap=%v grace=%v interval=%v, want %v %v %v",
+					cfg.OrphanReap, cfg.OrphanGrace, cfg.OrphanScanInterval, tc.wantReap, tc.wantGrace, tc.wantTick)
+			}
+		})
+	}
+}
+
+func TestLoad_Inventory(t *testing.T) {
+	for raw, want := range map[string]struct{ on, err bool }{
+		"": {}, "true": {on: true}, "false": {}, "sometimes": {err: true},
+	} {
+		t.Setenv(EnvKey, "")
+		unsetDefaultsFixture(t)
+		t.Setenv(EnvJournalSinks, "")
+		t.Setenv(EnvInventory, raw)
+		cfg, err := Load()
+		if cfg.Inventory != want.on || (err != nil) != want.err {
+			t.Errorf("%s=%q: Inventory=%v err=%v, want %v err=%v", EnvInventory, raw, cfg.Inventory, err, want.on, want.err)
+		}
+	}
+}
+
+func TestLoad_Usage(t *testing.T) {
+	for raw, want := range map[string]struct{ on, err bool }{
+		"": {}, "true": {on: true}, "0": {}, "yes": {err: true},
+	} {
+		t.Setenv(EnvKey, "")
+		unsetDefaultsFixture(t)
+		t.Setenv(EnvJournalSinks, "")
+		t.Setenv(EnvUsage, raw)
+		cfg, err := Load()
+		if cfg.Usage != want.on || (err != nil) != want.err {
+			t.Errorf("%s=%q: Usage=%v err=%v, want %v err=%v", EnvUsage, raw, cfg.Usage, err, want.on, want.err)
+		}
+	}
+}
+
+func TestLoad_DrillConfig(t *testing.T) {
+	cases := []struct {
+		name, ns, every, timeout string
+		wantNS                   string
+		wantEvery, wantTimeout   time.Duration
+		wantErr                  bool
+	}{
+		{name: "unset → drills off"},
+		{name: "enabled", ns: "pvc-plumber-drill", every: "12h", timeout: "90m", wantNS: "pvc-plumber-drill", wantEvery: 12 * time.Hour, wantTimeout: 90 * time.Minute},
+		{name: "invalid namespace stays off", ns: "Drill_Sandbox", wantErr: true},
+		{name: "garbage timeout → default", ns: "drill", timeout: "soon", wantNS: "drill", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(EnvKey, "")
+			unsetDefaultsFixture(t)
+			t.Setenv(EnvJournalSinks, "")
+			t.Setenv(EnvDrillNamespace, tc.ns)
+			t.Setenv(EnvDrillInterval, tc.every)
+			t.Setenv(EnvDrillTimeout, tc.timeout)
+
+			cfg, err := Load()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("err: got %v, wantErr=%v", err, tc.wantErr)
+			}
+			if cfg.DrillNamespace != tc.wantNS || cfg.DrillInterval != tc.wantEvery || cfg.DrillTimeout != tc.wantTimeout {
+				t.Errorf("got ns=%q interval=%v timeout=%v, want %q %v %v",
+					cfg.DrillNamespace, cfg.DrillInterval, cfg.DrillTimeout, tc.wantNS, tc.wantEvery, tc.wantTimeout)
+			}
+		})
+	}
+}
+
+func TestLoad_CloneConfig(t *testing.T) {
+	cases := []struct {
+		name, ttl, every  string
+		wantTTL, wantTick time.Duration
+		wantErr           bool
+	}{
+		{name: "unset → package defaults"},
+		{name: "set", ttl: "72h", every: "30s", wantTTL: 72 * time.Hour, wantTick: 30 * time.Second},
+		{name: "zero ttl → default", ttl: "0s", wantErr: true},
+		{name: "garbage interval → default", ttl: "1h", every: "often", wantTTL: time.Hour, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(EnvKey, "")
+			unsetDefaultsFixture(t)
+			t.Setenv(EnvJournalSinks, "")
+			t.Setenv(EnvCloneDefaultTTL, tc.ttl)
+			t.Setenv(EnvCloneScanInterval, tc.every)
+
+			cfg, err := Load()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("err: got %v, wantErr=%v", err, tc.wantErr)
+			}
+			if cfg.CloneDefaultTTL != tc.wantTTL || cfg.CloneScanInterval != tc.wantTick {
+				t.Errorf("got ttl=%v interval=%v, want %v %v", cfg.CloneDefaultTTL, cfg.CloneScanInterval, tc.wantTTL, tc.wantTick)
+			}
+		})
+	}
+}
+
+func TestLoad_BackupConfig(t *testing.T) {
+	cases := []struct {
+		name, file, timeout string
+		wantFile            string
+		wantTimeout         time.Duration
+		wantErr             bool
+	}{
+		{name: "unset → endpoint off, package default"},
+		{name: "set", file: " /var/run/secrets/backup/token ", timeout: "2h", wantFile: "/var/run/secrets/backup/token", wantTimeout: 2 * time.Hour},
+		{name: "garbage timeout → default", file: "/token", timeout: "soon", wantFile: "/token", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(EnvKey, "")
+			unsetDefaultsFixture(t)
+			t.Setenv(EnvJournalSinks, "")
+			t.Setenv(EnvBackupAPITokenFile, tc.file)
+			t.Setenv(EnvBackupTimeout, tc.timeout)
+
+			cfg, err := Load()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("err: got %v, wantErr=%v", err, tc.wantErr)
+			}
+			if cfg.BackupAPITokenFile != tc.wantFile || cfg.BackupTimeout != tc.wantTimeout {
+				t.Errorf("got file=%q timeout=%v, want %q %v", cfg.BackupAPITokenFile, cfg.BackupTimeout, tc.wantFile, tc.wantTimeout)
+			}
+		})
+	}
+}
+
+func TestLoad_BackupHookConfig(t *testing.T) {
+	cases := []struct {
+		name, enable, timeout, lead, hold string
+		want                              Config
+		wantErr                           bool
+	}{
+		{name: "unset → off, package defaults"},
+		{
+			name: "set", enable: "true", timeout: "90s", lead: "5m", hold: "2h",
+			want: Config{BackupHooks: true, BackupHookTimeout: 90 * time.Second, BackupHookLead: 5 * time.Minute, BackupHookMaxHold: 2 * time.Hour},
+		},
+		{name: "garbage switch → off", enable: "sometimes", wantErr: true},
+		{name: "negative lead → default", enable: "1", lead: "-1m", want: Config{BackupHooks: true}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(EnvKey, "")
+			unsetDefaultsFixture(t)
+			t.Setenv(EnvJournalSinks, "")
+			t.Setenv(EnvBackupHooks, tc.enable)
+			t.Setenv(EnvBackupHookTimeout, tc.timeout)
+			t.Setenv(EnvBackupHookLead, tc.lead)
+			t.Setenv(EnvBackupHookMaxHold, tc.hold)
+
+			cfg, err := Load()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("err: got %v, wantErr=%v", err, tc.wantErr)
+			}
+			if cfg.BackupHooks != tc.want.BackupHooks || cfg.BackupHookTimeout != tc.want.BackupHookTimeout ||
+				cfg.BackupHookLead != tc.want.BackupHookLead || cfg.BackupHookMaxHold != tc.want.BackupHookMaxHold {
+				t.Errorf("got hooks=%v timeout=%v lead=%v hold=%v, want %v %v %v %v",
+					cfg.BackupHooks, cfg.BackupHookTimeout, cfg.BackupHookLead, cfg.BackupHookMaxHold,
+					tc.want.BackupHooks, tc.want.BackupHookTimeout, tc.want.BackupHookLead, tc.want.BackupHookMaxHold)
+			}
+		})
+	}
+}
+
+func TestSplitNamespacedName(t *testing.T) {
+	for in, want := range map[string]bool{
+		"ns/name": true, "": false, "ns/": false, "/name": false, "name": false, "a/b/c": false,
+	} {
+		if _, _, ok := SplitNamespacedName(in); ok != want {
+			t.Errorf("SplitNamespacedName(%q) ok=%v, want %v", in, ok, want)
+		}
+	}
+}
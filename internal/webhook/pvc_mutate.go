@@ -18,6 +18,7 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -25,13 +26,14 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	"github.com/mitchross/pvc-plumber/internal/backend"
+	"github.com/mitchross/pvc-plumber/internal/v4/labels"
 )
 
 // kopiaClient is the narrow surface PVC handlers need from the Kopia client.
 // Defining it here lets tests inject a fake without spinning up a real Kopia
 // repository or wrestling with the concrete `*kopia.Client` constructor.
 type kopiaClient interface {
-	CheckBackupExists(ctx context.Context, namespace, pvc string) backend.CheckResult
+	CheckBackupExists(ctx context.Context, q backend.Query) backend.CheckResult
 }
 
 // Backup label values that opt a PVC into VolSync backup. Anything outside
@@ -71,6 +73,15 @@ func hasBackupLabel(pvc *corev1.PersistentVolumeClaim) bool {
 	return v == backupLabelHour || v == backupLabelDay
 }
 
+// backupQuery is the backup lookup for a PVC: its default identity, or
+// the pvc-plumber.io/backup-identity override the v4 builder renders onto
+// the ReplicationSource. Whitespace is trimmed the way labels.Parse does,
+// so both sides agree on the identity.
+func backupQuery(pvc *corev1.PersistentVolumeClaim) backend.Query {
+	override := strings.TrimSpace(pvc.Annotations[labels.AnnotationBackupIdentity])
+	return backend.NewQuery(pvc.Namespace, pvc.Name, override)
+}
+
 // PVCMutator is the mutating admission handler for PersistentVolumeClaim
 // CREATE requests. It mirrors Kyverno rule 2 (`add-datasource-if-backup-exists`):
 // when pvc-plumber reports an authoritative restore decision, it injects
@@ -132,7 +143,7 @@ func (h *PVCMutator) Handle(ctx context.Context, req admission.Request) admissio
 		return admission.Allowed("")
 	}
 
-	result := h.Kopia.CheckBackupExists(ctx, pvc.Namespace, pvc.Name)
+	result := h.Kopia.CheckBackupExists(ctx, backupQuery(pvc))
 
 	// Fail-OPEN: anything short of an authoritative restore decision admits
 	// the PVC unchanged. The validator runs a second independent Kopia check
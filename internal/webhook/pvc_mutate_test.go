@@ -16,6 +16,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	"github.com/mitchross/pvc-plumber/internal/backend"
+	"github.com/mitchross/pvc-plumber/internal/v4/labels"
 )
 
 // fakeKopia implements kopiaClient for table-driven tests. It returns a
@@ -23,11 +24,11 @@ import (
 // the handler did or did not consult the backend.
 type fakeKopia struct {
 	result backend.CheckResult
-	calls  []string // namespace/pvc keys, in call order
+	calls  []string // Query.Key values, in call order
 }
 
-func (f *fakeKopia) CheckBackupExists(_ context.Context, ns, pvc string) backend.CheckResult {
-	f.calls = append(f.calls, ns+"/"+pvc)
+func (f *fakeKopia) CheckBackupExists(_ context.Context, q backend.Query) backend.CheckResult {
+	f.calls = append(f.calls, q.Key())
 	return f.result
 }
 
@@ -160,6 +161,24 @@ func TestPVCMutate_FreshDecision_NoPatch(t *testing.T) {
 	}
 }
 
+// TestPVCMutate_BackupIdentityOverride_ChecksOverride pins that the
+// pvc-plumber.io/backup-identity annotation, not the PVC name, decides
+// which backups the restore check looks for.
+func TestPVCMutate_BackupIdentityOverride_ChecksOverride(t *testing.T) {
+	pvc := backupPVC("media")
+	pvc.Annotations = map[string]string{labels.AnnotationBackupIdentity: " media-library "}
+	kopia := &fakeKopia{result: backend.CheckResult{Decision: backend.DecisionFresh, Authoritative: true}}
+	mut := &PVCMutator{Decoder: newDecoder(t), Kopia: kopia}
+
+	if resp := mut.Handle(context.Background(), pvcRequest(t, pvc)); !resp.Allowed {
+		t.Fatalf("expected allowed, got %v", resp.Result)
+	}
+	want := "media/" + testBackupPVCName + "#media-library"
+	if len(kopia.calls) != 1 || kopia.calls[0] != want {
+		t.Errorf("calls = %v, want [%s]", kopia.calls, want)
+	}
+}
+
 func TestPVCMutate_KopiaError_AllowsFailOpen(t *testing.T) {
 	// Fail-OPEN is the whole point of the mutator — error must NOT deny.
 	// The validator is the fail-closed companion.
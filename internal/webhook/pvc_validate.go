@@ -127,7 +127,7 @@ func (h *PVCValidator) Handle(ctx context.Context, req admission.Request) admiss
 		return admission.Allowed("")
 	}
 
-	result := h.Kopia.CheckBackupExists(ctx, pvc.Namespace, pvc.Name)
+	result := h.Kopia.CheckBackupExists(ctx, backupQuery(pvc))
 
 	// Rule 1 fail-closed: any flavor of "we don't know" denies. Note
 	// `Decision == DecisionUnknown` is a deliberate triple-check alongside
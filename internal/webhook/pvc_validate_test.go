@@ -9,6 +9,7 @@ import (
 	"k8s.io/utils/ptr"
 
 	"github.com/mitchross/pvc-plumber/internal/backend"
+	"github.com/mitchross/pvc-plumber/internal/v4/labels"
 )
 
 // withRestoreDataSourceRef returns the PVC with the dataSourceRef shape that
@@ -38,6 +39,20 @@ func TestPVCValidate_Unknown_Denies(t *testing.T) {
 	}
 }
 
+func TestPVCValidate_BackupIdentityOverride_ChecksOverride(t *testing.T) {
+	pvc := backupPVC("media")
+	pvc.Annotations = map[string]string{labels.AnnotationBackupIdentity: "media-library"}
+	kopia := &fakeKopia{result: backend.CheckResult{Decision: backend.DecisionFresh, Authoritative: true}}
+	val := &PVCValidator{Decoder: newDecoder(t), Kopia: kopia}
+
+	if resp := val.Handle(context.Background(), pvcRequest(t, pvc)); !resp.Allowed {
+		t.Fatalf("expected allowed on fresh decision, got %v", resp.Result)
+	}
+	if want := "media/" + testBackupPVCName + "#media-library"; len(kopia.calls) != 1 || kopia.calls[0] != want {
+		t.Errorf("calls = %v, want [%s]", kopia.calls, want)
+	}
+}
+
 func TestPVCValidate_Error_Denies(t *testing.T) {
 	kopia := &fakeKopia{result: backend.CheckResult{
 		Decision:      backend.DecisionRestore, // even with restore decision, presence of Error denies